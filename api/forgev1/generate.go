@@ -0,0 +1,10 @@
+// Package forgev1 holds the gRPC API of `forge serve`: the service definition in forge.proto and
+// the Go code generated from it, with the client other Go services use to start, list, follow and
+// cancel runs:
+//
+//	conn, err := grpc.NewClient("localhost:9090", grpc.WithTransportCredentials(insecure.NewCredentials()))
+//	client := forgev1.NewForgeServiceClient(conn)
+//	run, err := client.StartRun(ctx, &forgev1.StartRunRequest{Workflow: "ci.yaml"})
+package forgev1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative forge.proto
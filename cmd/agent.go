@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"runtime"
+	"slices"
+	"syscall"
+
+	"github.com/andre-koe/forge/internal/agent"
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/andre-koe/forge/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// agentLabels returns the labels of an agent: the given ones plus its name, OS and architecture
+func agentLabels(name string, labels []string) []string {
+	all := slices.Clone(labels)
+	for _, label := range []string{name, runtime.GOOS, runtime.GOARCH} {
+		if !slices.Contains(all, label) {
+			all = append(all, label)
+		}
+	}
+	return all
+}
+
+// runAgent runs the stages the server at join sends until ctx is done
+func runAgent(ctx context.Context, join, name, dir string, labels []string, out io.Writer, newRunner runner.Factory) error {
+	if u, err := url.Parse(join); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: --join must be the http(s) URL of a forge server, got %q", agentErr, join)
+	}
+	a := &agent.Agent{
+		Client:     server.NewClient(join),
+		Name:       name,
+		Labels:     agentLabels(name, labels),
+		Root:       dir,
+		NewRunner:  newRunner,
+		RunOptions: runBaseOptions,
+		Out:        out,
+	}
+	if err := a.Run(ctx); err != nil {
+		return fmt.Errorf("%w: %v", agentErr, err)
+	}
+	return nil
+}
+
+func makeAgentCmd(newRunner runner.Factory) *cobra.Command {
+	var join, name, dir string
+	var labels []string
+
+	cmd := &cobra.Command{
+		Use:   "agent --join <server>",
+		Short: "Run the stages a forge server sends to this machine",
+		Long: `Join a forge server as an agent and run the stages it sends, until interrupted.
+
+Stages with runs_on: {agent: [labels]} are sent to an idle agent that has all of the
+labels; an agent's name, OS and architecture count as labels too. The agent runs the
+stage from its own copy of the workflow below --dir, at the same path relative to it as
+on the server, records it in its own run history and streams its output back to the run:
+
+  forge agent --join http://ci.example.com:8080 --label gpu --dir /srv/checkout`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return runAgent(ctx, join, name, dir, labels, cmd.OutOrStdout(), newRunner)
+		},
+	}
+	hostname, _ := os.Hostname()
+	cmd.Flags().StringVar(&join, "join", "", "URL of the forge server to join, e.g. http://ci.example.com:8080")
+	cmd.Flags().StringVar(&name, "name", hostname, "name of the agent")
+	cmd.Flags().StringSliceVar(&labels, "label", nil, "label stages can select the agent by (repeatable)")
+	cmd.Flags().StringVar(&dir, "dir", ".", "directory containing the agent's copy of the server's workflows")
+	_ = cmd.MarkFlagRequired("join")
+	_ = cmd.MarkFlagDirname("dir")
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(makeAgentCmd(runner.New))
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/andre-koe/forge/internal/server"
+)
+
+func TestAgentLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels []string
+		want   []string
+	}{
+		{"no labels", nil, []string{"gpu-1", runtime.GOOS, runtime.GOARCH}},
+		{"given labels first", []string{"gpu", "cuda"}, []string{"gpu", "cuda", "gpu-1", runtime.GOOS, runtime.GOARCH}},
+		{"no duplicates", []string{runtime.GOOS, "gpu-1"}, []string{runtime.GOOS, "gpu-1", runtime.GOARCH}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := agentLabels("gpu-1", tt.labels); !slices.Equal(got, tt.want) {
+				t.Errorf("agentLabels() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunAgent(t *testing.T) {
+	for _, join := range []string{"", "ci.example.com:8080", "ftp://ci.example.com"} {
+		if err := runAgent(context.Background(), join, "gpu-1", t.TempDir(), nil, new(syncBuffer), runner.New); !errors.Is(err, agentErr) {
+			t.Errorf("runAgent(%q) error = %v, want %v", join, err, agentErr)
+		}
+	}
+
+	s := server.New(context.Background(), t.TempDir(), runner.New)
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	out := new(syncBuffer)
+	done := make(chan error)
+	go func() { done <- runAgent(ctx, ts.URL, "gpu-1", t.TempDir(), []string{"gpu"}, out, runner.New) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.Agents()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	agents := s.Agents()
+	if len(agents) != 1 || agents[0].Name != "gpu-1" || !slices.Contains(agents[0].Labels, "gpu") || !slices.Contains(agents[0].Labels, runtime.GOOS) {
+		t.Errorf("agents = %+v, want gpu-1 with its labels", agents)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("runAgent() error: %v", err)
+	}
+	if len(s.Agents()) != 0 {
+		t.Errorf("agents after stopping = %+v, want none", s.Agents())
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/andre-koe/forge/internal/active"
+	"github.com/andre-koe/forge/internal/artifact"
+	"github.com/andre-koe/forge/internal/cache"
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	planNotFoundErr = errors.New("plan file not found")
+	planInvalidErr  = errors.New("invalid plan file")
+)
+
+func runApply(planPath string, out io.Writer, newRunner runner.Factory, opts ...runner.Option) error {
+	if err := CheckFilePathExistAndIsNotEmpty(planPath); err != nil {
+		if errors.Is(err, workflowNotFoundErr) {
+			return planNotFoundErr
+		}
+		return err
+	}
+
+	p, err := runner.ReadPlan(planPath)
+	if err != nil {
+		return fmt.Errorf("%w: %v", planInvalidErr, err)
+	}
+
+	if err := p.Verify(); err != nil {
+		return err
+	}
+
+	r, err := newRunner(p.Workflow, slices.Concat(p.Options(), []runner.Option{runner.WithOut(out), runner.WithHistory(history.ForWorkflow(p.Workflow)), runner.WithArtifacts(artifact.ForWorkflow(p.Workflow)),
+		runner.WithCache(cache.ForWorkflow(p.Workflow)), runner.WithActive(active.ForWorkflow(p.Workflow))}, opts)...)
+	if err != nil {
+		return fmt.Errorf("%w: %v", runnerCreationErr, err)
+	}
+
+	if err := r.Run(); err != nil {
+		return fmt.Errorf("%w: %v", workflowExecutionErr, err)
+	}
+
+	return nil
+}
+
+func makeApplyCmd(newRunner runner.Factory) *cobra.Command {
+	return &cobra.Command{
+		Use:   "apply [plan]",
+		Short: "Execute a plan created with 'forge plan'",
+		Long: `Execute exactly the execution plan stored in a plan file, with the variables,
+profile and env files it was created with. The run is refused if the workflow file the
+plan was created from, a file it includes or one of its env files has changed since.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return runApply(args[0], cmd.OutOrStdout(), newRunner, runner.WithContext(ctx))
+		},
+	}
+}
+
+var applyCmd = makeApplyCmd(runner.New)
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+}
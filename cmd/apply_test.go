@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+func writeTestPlan(t *testing.T) (workflowPath, planPath string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	workflowPath = filepath.Join(tmpDir, "workflow.yml")
+	planPath = filepath.Join(tmpDir, "plan.json")
+	if err := os.WriteFile(workflowPath, []byte(planTestWorkflow), 0644); err != nil {
+		t.Fatalf("failed to create test workflow: %v", err)
+	}
+	if err := runPlan(workflowPath, planPath, new(bytes.Buffer), dsl.LoadWorkflowFromFile); err != nil {
+		t.Fatalf("runPlan() failed: %v", err)
+	}
+	return workflowPath, planPath
+}
+
+func TestRunApply(t *testing.T) {
+	_, planPath := writeTestPlan(t)
+
+	var calls [][]string
+	newRunner := func(path string, opts ...runner.Option) (runner.Interface, error) {
+		return runner.NewRunner(path, append(opts, runner.WithRunCmd(func(argv []string) error {
+			calls = append(calls, argv)
+			return nil
+		}))...)
+	}
+
+	if err := runApply(planPath, new(bytes.Buffer), newRunner); err != nil {
+		t.Fatalf("runApply() unexpected error = %v", err)
+	}
+	if len(calls) != 1 || !slices.Equal(calls[0], []string{"echo", "hello"}) {
+		t.Errorf("expected planned command to run, got %v", calls)
+	}
+}
+
+func TestRunApply_PlanVars(t *testing.T) {
+	dir := t.TempDir()
+	workflowPath := filepath.Join(dir, "workflow.yml")
+	planPath := filepath.Join(dir, "plan.json")
+	envPath := filepath.Join(dir, "prod.env")
+	workflow := `name: release
+vars:
+  tag: dev
+stages:
+  - name: publish
+    steps:
+      - name: push
+        type: exec
+        run: ["docker", "push", "app:${{ vars.tag }}", "${{ env.REGION }}"]
+`
+	if err := os.WriteFile(workflowPath, []byte(workflow), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(envPath, []byte("REGION=eu\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := makePlanCmd(dsl.LoadWorkflowFromFile)
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetArgs([]string{workflowPath, "--var", "tag=v1.2.3", "--env-file", envPath, "-o", planPath})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("plan Execute() error: %v", err)
+	}
+
+	var calls [][]string
+	newRunner := func(path string, opts ...runner.Option) (runner.Interface, error) {
+		return runner.NewRunner(path, append(opts, runner.WithRunCmd(func(argv []string) error {
+			calls = append(calls, argv)
+			return nil
+		}))...)
+	}
+	if err := runApply(planPath, new(bytes.Buffer), newRunner); err != nil {
+		t.Fatalf("runApply() error: %v", err)
+	}
+	if len(calls) != 1 || !slices.Equal(calls[0], []string{"docker", "push", "app:v1.2.3", "eu"}) {
+		t.Errorf("commands = %v, want the planned variables and env file", calls)
+	}
+
+	if err := os.WriteFile(envPath, []byte("REGION=us\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runApply(planPath, new(bytes.Buffer), newRunner); !errors.Is(err, runner.ErrPlanStale) {
+		t.Errorf("runApply() after the env file changed = %v, want %v", err, runner.ErrPlanStale)
+	}
+}
+
+func TestRunApply_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T) string
+		wantErr error
+	}{
+		{
+			name:    "plan not found",
+			setup:   func(t *testing.T) string { return "/non/existent/plan.json" },
+			wantErr: planNotFoundErr,
+		},
+		{
+			name: "invalid plan",
+			setup: func(t *testing.T) string {
+				path := filepath.Join(t.TempDir(), "plan.json")
+				if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+					t.Fatalf("failed to write plan: %v", err)
+				}
+				return path
+			},
+			wantErr: planInvalidErr,
+		},
+		{
+			name: "workflow changed since plan",
+			setup: func(t *testing.T) string {
+				workflowPath, planPath := writeTestPlan(t)
+				if err := os.WriteFile(workflowPath, []byte(planTestWorkflow+"\n# edited\n"), 0644); err != nil {
+					t.Fatalf("failed to modify workflow: %v", err)
+				}
+				return planPath
+			},
+			wantErr: runner.ErrPlanStale,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runApply(tt.setup(t), new(bytes.Buffer), runner.New)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("runApply() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunApply_RunFails(t *testing.T) {
+	_, planPath := writeTestPlan(t)
+	fail := runner.WithRunCmd(func([]string) error { return errors.New("exit status 3") })
+	err := runApply(planPath, new(bytes.Buffer), runner.New, runner.WithHistory(nil), fail)
+	if !errors.Is(err, workflowExecutionErr) || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("runApply() error = %v, want %v with its cause", err, workflowExecutionErr)
+	}
+}
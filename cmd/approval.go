@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/spf13/cobra"
+)
+
+// approvalSecretEnv holds the key approval tokens are signed with
+const approvalSecretEnv = "FORGE_APPROVAL_SECRET"
+
+// approvers returns who decides about the approval steps of a run: the holders of tokens and,
+// with terminal, whoever answers the question on in
+func approvers(tokens []string, terminal bool, in io.Reader, out io.Writer) ([]runner.Approver, error) {
+	var list []runner.Approver
+	if len(tokens) > 0 {
+		secret := os.Getenv(approvalSecretEnv)
+		if secret == "" {
+			return nil, fmt.Errorf("%w: %s is not set", invalidApprovalTokenErr, approvalSecretEnv)
+		}
+		list = append(list, runner.TokenApprover([]byte(secret), tokens, time.Now))
+	}
+	if terminal {
+		list = append(list, runner.TerminalApprover(in, out))
+	}
+	return list, nil
+}
+
+// runApprovalToken prints a token approving the approval step "stage.step" of the workflow for
+// the given time, on behalf of by
+func runApprovalToken(workflow, step, by string, expires time.Duration, out io.Writer, load func(string) (*dsl.Workflow, error)) error {
+	secret := os.Getenv(approvalSecretEnv)
+	if secret == "" {
+		return fmt.Errorf("%w: %s is not set", approvalTokenErr, approvalSecretEnv)
+	}
+	if expires <= 0 {
+		return fmt.Errorf("%w: --expires must be positive", approvalTokenErr)
+	}
+	if err := CheckFilePathExistAndIsNotEmpty(workflow); err != nil {
+		return err
+	}
+	wf, err := load(workflow)
+	if err != nil {
+		return fmt.Errorf("%w: %v", workflowLoadErr, err)
+	}
+
+	stageName, stepName, _ := strings.Cut(step, ".")
+	found := false
+	for _, stage := range wf.Stages {
+		for _, s := range stage.Steps {
+			if stage.Name == stageName && s.Name == stepName && s.Type == dsl.StepTypeApproval {
+				found = true
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: %s has no approval step %q", approvalTokenErr, workflow, step)
+	}
+
+	fmt.Fprintln(out, runner.SignApprovalToken([]byte(secret), wf.Name, step, by, time.Now().Add(expires)))
+	return nil
+}
+
+func makeApprovalTokenCmd(load func(string) (*dsl.Workflow, error)) *cobra.Command {
+	var expires time.Duration
+	var by string
+	cmd := &cobra.Command{
+		Use:   "approval-token <workflow.yml> <stage.step>",
+		Short: "Create a token that approves an approval step",
+		Long: `Create a token that approves the approval step "stage.step" of a workflow until it
+expires, e.g. for a release manager to hand to a CI job:
+
+  forge run deploy.yml --approval-token "$(forge approval-token deploy.yml prod.gate)"
+
+Tokens are signed with the key in FORGE_APPROVAL_SECRET, which 'forge run' needs to
+check them, as does 'forge serve' for runs approved or denied over its API, e.g.
+
+  curl -X POST -H "Authorization: Bearer $TOKEN" http://localhost:8080/runs/<id>/approve
+
+The run's log records the approval as by the token's --by, the current user by default.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runApprovalToken(args[0], args[1], by, expires, cmd.OutOrStdout(), load)
+		},
+	}
+	cmd.Flags().DurationVar(&expires, "expires", time.Hour, "how long the token is valid")
+	cmd.Flags().StringVar(&by, "by", currentUser(), "who approves with the token, as the run's log records it")
+	return cmd
+}
+
+// currentUser returns the name of the user running forge, empty if it is unknown
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func init() {
+	rootCmd.AddCommand(makeApprovalTokenCmd(dsl.LoadWorkflowFromFile))
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+const approvalTestWorkflow = `name: release
+stages:
+  - name: prod
+    steps:
+      - name: gate
+        type: approval
+      - name: deploy
+        type: exec
+        run: ["true"]
+`
+
+func TestRunApprovalToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deploy.yaml")
+	if err := os.WriteFile(path, []byte(approvalTestWorkflow), 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	t.Setenv(approvalSecretEnv, "")
+	if err := runApprovalToken(path, "prod.gate", "alice", time.Hour, &bytes.Buffer{}, dsl.LoadWorkflowFromFile); !errors.Is(err, approvalTokenErr) {
+		t.Errorf("runApprovalToken() without secret error = %v, want %v", err, approvalTokenErr)
+	}
+
+	t.Setenv(approvalSecretEnv, "s3cret")
+	for _, step := range []string{"prod.deploy", "prod.missing", "gate"} {
+		if err := runApprovalToken(path, step, "alice", time.Hour, &bytes.Buffer{}, dsl.LoadWorkflowFromFile); !errors.Is(err, approvalTokenErr) {
+			t.Errorf("runApprovalToken(%q) error = %v, want %v", step, err, approvalTokenErr)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := runApprovalToken(path, "prod.gate", "alice", time.Hour, &out, dsl.LoadWorkflowFromFile); err != nil {
+		t.Fatalf("runApprovalToken() error: %v", err)
+	}
+	token := strings.TrimSpace(out.String())
+
+	// The token lets a run pass the gate
+	approvers, err := approvers([]string{token}, false, nil, nil)
+	if err != nil {
+		t.Fatalf("approvers() error: %v", err)
+	}
+	var run bytes.Buffer
+	if err := runRun(path, &run, runner.New, runner.WithHistory(nil), runner.WithApprovers(approvers...)); err != nil {
+		t.Fatalf("runRun() error: %v\n%s", err, run.String())
+	}
+	if !strings.Contains(run.String(), "Approved by alice") {
+		t.Errorf("output missing the approval:\n%s", run.String())
+	}
+}
+
+func TestApprovers(t *testing.T) {
+	t.Setenv(approvalSecretEnv, "")
+	if _, err := approvers([]string{"token"}, false, nil, nil); !errors.Is(err, invalidApprovalTokenErr) {
+		t.Errorf("approvers() without secret error = %v, want %v", err, invalidApprovalTokenErr)
+	}
+	list, err := approvers(nil, true, strings.NewReader("y\n"), &bytes.Buffer{})
+	if err != nil || len(list) != 1 {
+		t.Errorf("approvers() on a terminal = %d approvers, %v, want 1", len(list), err)
+	}
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/andre-koe/forge/internal/artifact"
+	"github.com/andre-koe/forge/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+// artifactStore returns the store next to the workflow file, or in the current directory
+func artifactStore(workflow string) *artifact.Store {
+	if workflow == "" {
+		return artifact.NewStore(artifact.DirName)
+	}
+	return artifact.ForWorkflow(workflow)
+}
+
+func runArtifactsList(store *artifact.Store, runID string, out io.Writer) error {
+	list, err := store.List(runID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", artifactsErr, err)
+	}
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STAGE\tSIZE\tPATH")
+	for _, a := range list {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", a.Stage, snapshot.FormatBytes(a.Size), a.Path)
+	}
+	return tw.Flush()
+}
+
+// runArtifactsDownload copies the artifacts of one stage into dir, or those of all stages into dir/<stage>
+func runArtifactsDownload(store *artifact.Store, runID, stage, dir string, out io.Writer) error {
+	stages := []string{stage}
+	if stage == "" {
+		list, err := store.List(runID)
+		if err != nil {
+			return fmt.Errorf("%w: %v", artifactsErr, err)
+		}
+		stages = nil
+		for _, a := range list {
+			if len(stages) == 0 || stages[len(stages)-1] != a.Stage {
+				stages = append(stages, a.Stage)
+			}
+		}
+	}
+	for _, name := range stages {
+		dst := dir
+		if stage == "" {
+			dst = filepath.Join(dir, name)
+		}
+		stats, err := store.Restore(runID, name, dst)
+		if err != nil {
+			return fmt.Errorf("%w: %v", artifactsErr, err)
+		}
+		fmt.Fprintf(out, "Downloaded artifacts of %s to %s: %s\n", name, dst, stats)
+	}
+	return nil
+}
+
+func makeArtifactsCmd() *cobra.Command {
+	var workflow string
+	cmd := &cobra.Command{
+		Use:   "artifacts",
+		Short: "List and download the artifacts kept by workflow runs",
+		Long: `Stages declare artifacts: path globs that are kept in .forge/artifacts/<run-id>/ next
+to the workflow file after the stage ran. Use the run id printed in the run history.`,
+	}
+	cmd.PersistentFlags().StringVarP(&workflow, "workflow", "w", "", "workflow file whose artifacts to read (default: the current directory)")
+	_ = cmd.MarkPersistentFlagFilename("workflow", "yaml", "yml")
+
+	list := &cobra.Command{
+		Use:   "list <run-id>",
+		Short: "List the artifacts of a run",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runArtifactsList(artifactStore(workflow), args[0], cmd.OutOrStdout())
+		},
+	}
+
+	var stage, output string
+	download := &cobra.Command{
+		Use:   "download <run-id>",
+		Short: "Copy the artifacts of a run into a directory",
+		Long: `Copy the artifacts of a run into the output directory. With --stage, the artifacts of that
+stage are copied directly into it; otherwise each stage gets a subdirectory.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runArtifactsDownload(artifactStore(workflow), args[0], stage, output, cmd.OutOrStdout())
+		},
+	}
+	download.Flags().StringVar(&stage, "stage", "", "only download the artifacts of this stage")
+	download.Flags().StringVarP(&output, "output", "o", ".", "directory to copy the artifacts into")
+	_ = download.MarkFlagDirname("output")
+
+	cmd.AddCommand(list, download)
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(makeArtifactsCmd())
+}
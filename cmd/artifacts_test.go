@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/artifact"
+)
+
+func TestArtifactsCmds(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for name, content := range map[string]string{"dist/app": "binary", "coverage.out": "cover"} {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := artifact.NewStore(filepath.Join(t.TempDir(), "artifacts"))
+	if _, err := store.Collect("run-1", "build", "", []string{"dist"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Collect("run-1", "test", "", []string{"coverage.out"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runArtifactsList(store, "run-1", &out); err != nil {
+		t.Fatalf("runArtifactsList() error: %v", err)
+	}
+	for _, want := range []string{"STAGE", "build  6 B   dist/app", "test   5 B   coverage.out"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("list output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	dir := t.TempDir()
+	if err := runArtifactsDownload(store, "run-1", "", dir, &out); err != nil {
+		t.Fatalf("runArtifactsDownload() error: %v", err)
+	}
+	for _, name := range []string{"build/dist/app", "test/coverage.out"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("download of all stages: %v", err)
+		}
+	}
+
+	dir = t.TempDir()
+	if err := runArtifactsDownload(store, "run-1", "build", dir, &out); err != nil {
+		t.Fatalf("runArtifactsDownload() error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dist/app")); err != nil {
+		t.Errorf("download of one stage: %v", err)
+	}
+
+	if err := runArtifactsList(store, "run-2", &out); !errors.Is(err, artifactsErr) {
+		t.Errorf("list of an unknown run error = %v, want artifactsErr", err)
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/spf13/cobra"
+)
+
+// benchSamples collects the durations of the measured runs, per step path in the order the steps first ran
+type benchSamples struct {
+	order []string
+	steps map[string][]time.Duration
+	total []time.Duration
+}
+
+func (s *benchSamples) add(rec *history.Record, total time.Duration) {
+	s.total = append(s.total, total)
+	if rec == nil {
+		return
+	}
+	for _, step := range rec.Steps {
+		if step.Status != history.StatusSuccess {
+			continue
+		}
+		if _, ok := s.steps[step.Path]; !ok {
+			s.order = append(s.order, step.Path)
+		}
+		s.steps[step.Path] = append(s.steps[step.Path], step.Duration)
+	}
+}
+
+// percentile returns the nearest-rank p-th percentile of the sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(i-1, 0), len(sorted)-1)]
+}
+
+// writeBenchRow writes the minimum, median and 95th percentile of durations
+func writeBenchRow(w io.Writer, name string, durations []time.Duration) {
+	sorted := slices.Sorted(slices.Values(durations))
+	fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", name, len(sorted),
+		sorted[0].Round(time.Millisecond), percentile(sorted, 50).Round(time.Millisecond), percentile(sorted, 95).Round(time.Millisecond))
+}
+
+// runBench runs the workflow warmup times without measuring it, then runs times, and prints the
+// minimum, median and 95th percentile duration of every step and of the whole run. Runs are not
+// recorded in the history and bypass the step cache; the first failure ends the benchmark.
+func runBench(workflow string, out io.Writer, newRunner runner.Factory, runs, warmup int, opts ...runner.Option) error {
+	base, err := runBaseOptions(workflow)
+	if err != nil {
+		return err
+	}
+
+	samples := &benchSamples{steps: make(map[string][]time.Duration)}
+	for i := 1; i <= warmup+runs; i++ {
+		label := fmt.Sprintf("Run %d/%d", i-warmup, runs)
+		if i <= warmup {
+			label = fmt.Sprintf("Warmup %d/%d", i, warmup)
+		}
+
+		var log bytes.Buffer
+		iterOpts := slices.Concat(base, opts, []runner.Option{
+			runner.WithOut(&log), runner.WithProcessOutput(&log, &log), runner.WithHistory(nil), runner.WithCache(nil),
+		})
+		r, err := newRunner(workflow, iterOpts...)
+		if err != nil {
+			return runnerCreationErr
+		}
+
+		fmt.Fprintf(out, "%s: ", label)
+		start := time.Now()
+		if err := r.Run(); err != nil {
+			fmt.Fprintf(out, "FAILED: %v\n\n--- Output of %s ---\n%s", err, label, log.String())
+			return fmt.Errorf("%w: %s: %v", workflowExecutionErr, label, err)
+		}
+		elapsed := time.Since(start)
+		fmt.Fprintf(out, "ok (%s)\n", elapsed.Round(time.Millisecond))
+		if i > warmup {
+			samples.add(r.Results().Record, elapsed)
+		}
+	}
+
+	fmt.Fprintln(out)
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STEP\tRUNS\tMIN\tMEDIAN\tP95")
+	for _, path := range samples.order {
+		writeBenchRow(tw, path, samples.steps[path])
+	}
+	writeBenchRow(tw, "total", samples.total)
+	return tw.Flush()
+}
+
+func makeBenchCmd(newRunner runner.Factory) *cobra.Command {
+	var runs, warmup int
+	var stages, vars, varFiles, envFiles []string
+
+	cmd := &cobra.Command{
+		Use:   "bench [workflow]",
+		Short: "Measure how long a workflow and its steps take",
+		Long: `Run a workflow repeatedly and report the minimum, median and 95th percentile
+duration of every step and of the whole run.
+
+The first --warmup runs fill caches of the tools involved and are not measured. Runs
+are not recorded in the run history and do not skip cached steps; the first failing run
+ends the benchmark and prints its output.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeWorkflowFiles,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if runs < 1 || warmup < 0 {
+				return fmt.Errorf("%w: --runs must be at least 1 and --warmup at least 0", invalidBenchErr)
+			}
+			values, err := parseVars(vars, varFiles)
+			if err != nil {
+				return err
+			}
+			return runBench(args[0], cmd.OutOrStdout(), newRunner, runs, warmup,
+				runner.WithStages(stages...), runner.WithVars(values), runner.WithEnvFiles(envFiles...), runner.WithContext(cmd.Context()))
+		},
+	}
+	cmd.Flags().IntVarP(&runs, "runs", "n", 10, "number of measured runs")
+	cmd.Flags().IntVar(&warmup, "warmup", 1, "number of runs before measuring")
+	cmd.Flags().StringSliceVar(&stages, "stage", nil, "run only the named stages (repeatable)")
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "override a workflow variable, e.g. tag=v1.2.3 (repeatable)")
+	cmd.Flags().StringSliceVar(&varFiles, "var-file", nil, "override workflow variables from a YAML file (repeatable, --var wins)")
+	cmd.Flags().StringSliceVar(&envFiles, "env-file", nil, "load KEY=VALUE pairs from a dotenv file (repeatable)")
+	_ = cmd.RegisterFlagCompletionFunc("stage", completeStages)
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(makeBenchCmd(runner.New))
+}
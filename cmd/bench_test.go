@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{p: 0, want: 1},
+		{p: 50, want: 5},
+		{p: 95, want: 10},
+		{p: 100, want: 10},
+	}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile(nil) = %v, want 0", got)
+	}
+}
+
+func TestRunBench(t *testing.T) {
+	tests := []struct {
+		name       string
+		failOn     int
+		wantErr    error
+		wantCalls  int
+		wantOutput []string
+	}{
+		{
+			name:       "reports steps and total",
+			wantCalls:  4,
+			wantOutput: []string{"Warmup 1/1: ok", "Run 3/3: ok", "STEP", "MEDIAN", "P95", "build/hello  3", "total"},
+		},
+		{
+			name:       "failure ends the benchmark",
+			failOn:     2,
+			wantErr:    workflowExecutionErr,
+			wantCalls:  2,
+			wantOutput: []string{"Run 1/3: FAILED", "--- Output of Run 1/3 ---"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "workflow.yaml")
+			if err := os.WriteFile(path, []byte(sourceTestWorkflow), 0o644); err != nil {
+				t.Fatalf("WriteFile() error: %v", err)
+			}
+
+			calls := 0
+			runCmd := func(argv []string) error {
+				calls++
+				if calls == tt.failOn {
+					return errors.New("broken")
+				}
+				return nil
+			}
+
+			out := new(bytes.Buffer)
+			err := runBench(path, out, runner.New, 3, 1, runner.WithRunCmd(runCmd))
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("runBench() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(path), ".forge", "runs")); !os.IsNotExist(err) {
+				t.Errorf("benchmark runs were recorded in the history")
+			}
+		})
+	}
+}
+
+func TestMakeBenchCmd_InvalidRuns(t *testing.T) {
+	cmd := makeBenchCmd(runner.New)
+	cmd.SetArgs([]string{"workflow.yaml", "--runs", "0"})
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	if err := cmd.Execute(); !errors.Is(err, invalidBenchErr) {
+		t.Fatalf("Execute() error = %v, want %v", err, invalidBenchErr)
+	}
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/andre-koe/forge/internal/cache"
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/spf13/cobra"
+)
+
+// cacheStore returns the store next to the workflow file, or in the current directory
+func cacheStore(workflow string) *cache.Store {
+	if workflow == "" {
+		return cache.NewStore(cache.DirName)
+	}
+	return cache.ForWorkflow(workflow)
+}
+
+func runCacheClear(store *cache.Store, out io.Writer) error {
+	if err := store.Clear(); err != nil {
+		return fmt.Errorf("%w: %v", cacheClearErr, err)
+	}
+	fmt.Fprintf(out, "Cleared step cache in %s\n", store.Dir)
+	return nil
+}
+
+func makeCacheCmd() *cobra.Command {
+	var workflow string
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the step cache",
+		Long: `Steps with a cache: are skipped when their key_files and definition are unchanged since
+their last successful run. The keys are kept in .forge/cache/ next to the workflow file.`,
+	}
+	cmd.PersistentFlags().StringVarP(&workflow, "workflow", "w", "", "workflow file whose cache to use (default: the current directory)")
+	_ = cmd.MarkPersistentFlagFilename("workflow", dsl.FileExtensions...)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "clear",
+		Short: "Forget all cached steps so they run again",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCacheClear(cacheStore(workflow), cmd.OutOrStdout())
+		},
+	})
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(makeCacheCmd())
+}
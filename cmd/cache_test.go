@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/cache"
+)
+
+func TestRunCacheClear(t *testing.T) {
+	store := cache.NewStore(filepath.Join(t.TempDir(), "cache"))
+	if err := store.Put("ci/build/deps", "abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runCacheClear(store, &out); err != nil {
+		t.Fatalf("runCacheClear() error: %v", err)
+	}
+	if store.Hit("ci/build/deps", "abc") {
+		t.Error("cache still hits after clear")
+	}
+	if !strings.Contains(out.String(), "Cleared step cache") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
+
+func TestCacheStore(t *testing.T) {
+	if got := cacheStore("").Dir; got != cache.DirName {
+		t.Errorf("cacheStore(\"\") = %s, want %s", got, cache.DirName)
+	}
+	if got, want := cacheStore(filepath.Join("project", "ci.yaml")).Dir, filepath.Join("project", cache.DirName); got != want {
+		t.Errorf("cacheStore() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/andre-koe/forge/internal/active"
+	"github.com/spf13/cobra"
+)
+
+// runCancel asks the forge process executing a run to cancel it: runs of a server through its API,
+// other runs with SIGTERM
+func runCancel(ctx context.Context, reg *active.Registry, id string, client *http.Client, out io.Writer) error {
+	e, err := reg.Get(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", cancelErr, err)
+	}
+	if err := e.Cancel(ctx, client); err != nil {
+		return fmt.Errorf("%w: %v", cancelErr, err)
+	}
+	if e.CancelURL != "" {
+		fmt.Fprintf(out, "Cancellation of run %s requested from %s\n", id, e.CancelURL)
+		return nil
+	}
+	fmt.Fprintf(out, "Cancellation of run %s requested from forge process %d\n", id, e.PID)
+	return nil
+}
+
+func makeCancelCmd() *cobra.Command {
+	var workflow string
+	cmd := &cobra.Command{
+		Use:   "cancel <run-id>",
+		Short: "Cancel a run in progress",
+		Long: `Cancel a run shown by 'forge status'. The forge process executing it receives SIGTERM,
+stops before the next step, sends SIGTERM to the process group of the running step
+(SIGKILL after a grace period) and still runs always and finally hooks. Runs started
+through 'forge serve' are cancelled through the server's API.
+
+Cancelling a run of 'forge watch' or 'forge schedule' stops that process as well.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCancel(cmd.Context(), activeRegistry(workflow), args[0], http.DefaultClient, cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().StringVarP(&workflow, "workflow", "w", "", "workflow file whose runs to search (default: the current directory)")
+	_ = cmd.MarkFlagFilename("workflow", "yaml", "yml")
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(makeCancelCmd())
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/active"
+)
+
+func TestRunCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cancellation uses signals")
+	}
+	reg := active.NewRegistry(filepath.Join(t.TempDir(), "active"))
+
+	// A sleeping process stands in for the forge process executing the run
+	proc := exec.Command("sleep", "30")
+	if err := proc.Start(); err != nil {
+		t.Skip("cannot start sleep:", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- proc.Wait() }()
+	if err := reg.Add(active.Entry{ID: "local", Workflow: "ci.yaml", PID: proc.Process.Pid, StartedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	var deleted string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		deleted = req.Method + " " + req.URL.Path
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	if err := reg.Add(active.Entry{ID: "served", Workflow: "ci.yaml", PID: os.Getpid(), StartedAt: time.Now(), CancelURL: srv.URL + "/runs/served"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runCancel(context.Background(), reg, "local", srv.Client(), &out); err != nil {
+		t.Fatalf("runCancel() error: %v", err)
+	}
+	select {
+	case err := <-exited:
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Errorf("process exited with %v, want a signal", err)
+		}
+	case <-time.After(5 * time.Second):
+		proc.Process.Kill()
+		t.Fatal("process did not receive SIGTERM")
+	}
+
+	if err := runCancel(context.Background(), reg, "served", srv.Client(), &out); err != nil {
+		t.Fatalf("runCancel() error: %v", err)
+	}
+	if deleted != "DELETE /runs/served" {
+		t.Errorf("server received %q, want DELETE /runs/served", deleted)
+	}
+
+	if err := runCancel(context.Background(), reg, "missing", srv.Client(), &out); !errors.Is(err, cancelErr) {
+		t.Errorf("runCancel() error = %v, want %v", err, cancelErr)
+	}
+}
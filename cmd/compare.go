@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/andre-koe/forge/internal/report"
+	"github.com/spf13/cobra"
+)
+
+// formatChange formats a duration difference with its sign and its share of the old duration
+func formatChange(old, diff time.Duration) string {
+	s := diff.Round(time.Millisecond).String()
+	if diff >= 0 {
+		s = "+" + s
+	}
+	if old > 0 {
+		s += fmt.Sprintf(", %+.0f%%", 100*float64(diff)/float64(old))
+	}
+	return s
+}
+
+// runCompare prints how the steps of the run in newPath differ from the run in oldPath. With
+// failOnRegression a slower or newly failing step makes it return an error.
+func runCompare(oldPath, newPath string, out io.Writer, threshold report.Threshold, all, failOnRegression bool) error {
+	old, err := report.Read(oldPath)
+	if err != nil {
+		return fmt.Errorf("%w: %v", compareErr, err)
+	}
+	cur, err := report.Read(newPath)
+	if err != nil {
+		return fmt.Errorf("%w: %v", compareErr, err)
+	}
+
+	fmt.Fprintf(out, "Comparing %s (%s, %s) with %s (%s, %s)\n\n",
+		oldPath, old.Status, old.Duration.Round(time.Millisecond), newPath, cur.Status, cur.Duration.Round(time.Millisecond))
+
+	deltas := report.Compare(old, cur, threshold)
+	counts := make(map[report.DeltaKind]int)
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, d := range deltas {
+		counts[d.Kind]++
+		switch d.Kind {
+		case report.DeltaAdded:
+			fmt.Fprintf(tw, "+ %s\t\t%s\tadded\n", d.Path, d.New.Duration.Round(time.Millisecond))
+		case report.DeltaRemoved:
+			fmt.Fprintf(tw, "- %s\t%s\t\tremoved\n", d.Path, d.Old.Duration.Round(time.Millisecond))
+		case report.DeltaFailing:
+			fmt.Fprintf(tw, "✗ %s\t\t\tnewly failing: %s\n", d.Path, d.New.Error)
+		case report.DeltaFixed:
+			fmt.Fprintf(tw, "✓ %s\t\t\tfixed\n", d.Path)
+		case report.DeltaSlower, report.DeltaFaster:
+			fmt.Fprintf(tw, "~ %s\t%s\t%s\t%s (%s)\n", d.Path, d.Old.Duration.Round(time.Millisecond),
+				d.New.Duration.Round(time.Millisecond), d.Kind, formatChange(d.Old.Duration, d.Change()))
+		case report.DeltaUnchanged:
+			if all {
+				fmt.Fprintf(tw, "  %s\t%s\t%s\t\n", d.Path, d.Old.Duration.Round(time.Millisecond), d.New.Duration.Round(time.Millisecond))
+			}
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if counts[report.DeltaUnchanged] == len(deltas) && !all {
+		fmt.Fprintln(out, "No step changes")
+	}
+
+	fmt.Fprintf(out, "\nTotal: %s -> %s (%s)\n", old.Duration.Round(time.Millisecond), cur.Duration.Round(time.Millisecond),
+		formatChange(old.Duration, cur.Duration-old.Duration))
+	fmt.Fprintf(out, "%d slower, %d faster, %d newly failing, %d fixed, %d added, %d removed\n",
+		counts[report.DeltaSlower], counts[report.DeltaFaster], counts[report.DeltaFailing],
+		counts[report.DeltaFixed], counts[report.DeltaAdded], counts[report.DeltaRemoved])
+
+	if regressions := counts[report.DeltaSlower] + counts[report.DeltaFailing]; failOnRegression && regressions > 0 {
+		return fmt.Errorf("%w: %d step(s) slower or newly failing", regressionErr, regressions)
+	}
+	return nil
+}
+
+func makeCompareCmd() *cobra.Command {
+	var threshold float64
+	var minDelta time.Duration
+	var all, failOnRegression bool
+
+	cmd := &cobra.Command{
+		Use:   "compare <old.json> <new.json>",
+		Short: "Compare two JSON run reports",
+		Long: `Compare two run reports written with 'forge run --report json=run.json': steps that
+got slower or faster, newly failing and fixed steps, and steps that were added or removed.
+
+A step's duration only counts as changed when it differs by at least --min-delta and by
+more than --threshold percent. --fail-on-regression exits with an error when a step got
+slower or started failing, e.g. to flag a pull request in CI.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			t := report.Threshold{Ratio: threshold / 100, Min: minDelta}
+			return runCompare(args[0], args[1], cmd.OutOrStdout(), t, all, failOnRegression)
+		},
+	}
+	cmd.Flags().Float64Var(&threshold, "threshold", 10, "percentage by which a step's duration must change to be reported")
+	cmd.Flags().DurationVar(&minDelta, "min-delta", 100*time.Millisecond, "smallest duration change to be reported")
+	cmd.Flags().BoolVar(&all, "all", false, "also list unchanged steps")
+	cmd.Flags().BoolVar(&failOnRegression, "fail-on-regression", false, "exit with an error when a step got slower or started failing")
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(makeCompareCmd())
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/report"
+)
+
+func writeTestReport(t *testing.T, name string, status history.Status, steps ...history.StepRecord) string {
+	t.Helper()
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	var total time.Duration
+	for _, s := range steps {
+		total += s.Duration
+	}
+	rec := &history.Record{Name: "ci", StartedAt: start, FinishedAt: start.Add(total), Status: status, Steps: steps}
+	path := filepath.Join(t.TempDir(), name)
+	if err := report.New(rec).WriteFile(report.Spec{Format: "json", Path: path}); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	return path
+}
+
+func TestRunCompare(t *testing.T) {
+	old := writeTestReport(t, "old.json", history.StatusSuccess,
+		history.StepRecord{Path: "build/compile", Status: history.StatusSuccess, Duration: time.Second},
+		history.StepRecord{Path: "build/lint", Status: history.StatusSuccess, Duration: time.Second},
+		history.StepRecord{Path: "test/unit", Status: history.StatusSuccess, Duration: time.Second},
+	)
+	slower := writeTestReport(t, "new.json", history.StatusFailed,
+		history.StepRecord{Path: "build/compile", Status: history.StatusSuccess, Duration: 3 * time.Second},
+		history.StepRecord{Path: "build/lint", Status: history.StatusFailed, Duration: time.Second, Error: "exit status 1"},
+		history.StepRecord{Path: "test/race", Status: history.StatusSuccess, Duration: time.Second},
+	)
+
+	tests := []struct {
+		name             string
+		newPath          string
+		failOnRegression bool
+		wantErr          error
+		wantOutput       []string
+	}{
+		{
+			name:    "regressions",
+			newPath: slower,
+			wantOutput: []string{
+				"~ build/compile", "slower (+2s, +200%)",
+				"✗ build/lint", "newly failing: exit status 1",
+				"+ test/race", "- test/unit",
+				"Total: 3s -> 5s (+2s, +67%)",
+				"1 slower, 0 faster, 1 newly failing, 0 fixed, 1 added, 1 removed",
+			},
+		},
+		{name: "fail on regression", newPath: slower, failOnRegression: true, wantErr: regressionErr},
+		{name: "identical", newPath: old, failOnRegression: true, wantOutput: []string{"No step changes", "Total: 3s -> 3s (+0s, +0%)"}},
+		{name: "missing report", newPath: filepath.Join(t.TempDir(), "missing.json"), wantErr: compareErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			err := runCompare(old, tt.newPath, out, report.Threshold{Ratio: 0.1, Min: 100 * time.Millisecond}, false, tt.failOnRegression)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("runCompare() error = %v, want %v", err, tt.wantErr)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
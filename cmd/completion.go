@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/spf13/cobra"
+)
+
+func makeCompletionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Generate the shell completion script",
+		Long: `Generate the completion script for the given shell. Besides commands and flags it
+completes workflow files and, for --stage and --skip-step, the stages and steps of the workflow.
+
+  bash:       source <(forge completion bash)
+  zsh:        forge completion zsh > "${fpath[1]}/_forge"
+  fish:       forge completion fish > ~/.config/fish/completions/forge.fish
+  powershell: forge completion powershell | Out-String | Invoke-Expression`,
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, out := cmd.Root(), cmd.OutOrStdout()
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(out, true)
+			case "zsh":
+				return root.GenZshCompletion(out)
+			case "fish":
+				return root.GenFishCompletion(out, true)
+			default:
+				return root.GenPowerShellCompletionWithDesc(out)
+			}
+		},
+	}
+}
+
+// completeWorkflowFiles completes YAML, JSON and TOML files for workflow arguments
+func completeWorkflowFiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return dsl.FileExtensions, cobra.ShellCompDirectiveFilterFileExt
+}
+
+// completionWorkflow loads the workflow named on the command line or, failing that, the one
+// forge init creates in the current directory
+func completionWorkflow(args []string) *dsl.Workflow {
+	candidates := []string{defaultFileName}
+	if len(args) > 0 {
+		candidates = []string{args[0]}
+	} else if matches := workflowFiles(history.WorkflowsDir); len(matches) == 1 {
+		candidates = append(candidates, matches[0])
+	}
+
+	for _, path := range candidates {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if wf, err := dsl.DecodeWorkflow(data, path); err == nil {
+			return wf
+		}
+	}
+	return nil
+}
+
+// workflowFiles lists the workflow files in dir
+func workflowFiles(dir string) []string {
+	var files []string
+	for _, ext := range dsl.FileExtensions {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*."+ext))
+		files = append(files, matches...)
+	}
+	return files
+}
+
+// completeStages completes the stage names of the workflow
+func completeStages(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	wf := completionWorkflow(args)
+	if wf == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, stage := range wf.Stages {
+		if strings.HasPrefix(stage.Name, toComplete) {
+			names = append(names, completionWithDesc(stage.Name, stage.Description))
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSteps completes "stage.step" ids of the workflow
+func completeSteps(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	wf := completionWorkflow(args)
+	if wf == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var ids []string
+	for _, stage := range wf.Stages {
+		for _, step := range stage.Steps {
+			id := stage.Name + "." + step.Name
+			if strings.HasPrefix(id, toComplete) {
+				ids = append(ids, completionWithDesc(id, step.Description))
+			}
+			for _, sub := range step.Steps {
+				if subID := id + "." + sub.Name; strings.HasPrefix(subID, toComplete) {
+					ids = append(ids, completionWithDesc(subID, sub.Description))
+				}
+			}
+		}
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completionWithDesc(choice, desc string) string {
+	if desc == "" {
+		return choice
+	}
+	return cobra.CompletionWithDesc(choice, desc)
+}
+
+func init() {
+	rootCmd.AddCommand(makeCompletionCmd())
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/spf13/cobra"
+)
+
+const completionTestWorkflow = `name: completion
+stages:
+  - name: build
+    description: Compile everything
+    steps:
+      - name: compile
+        type: exec
+        run: ["make"]
+  - name: deploy
+    steps:
+      - name: push
+        type: exec
+        run: ["push"]
+`
+
+func TestCompletionCmd(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		t.Run(shell, func(t *testing.T) {
+			root := &cobra.Command{Use: "forge"}
+			root.AddCommand(makeCompletionCmd())
+			out := new(bytes.Buffer)
+			root.SetOut(out)
+			root.SetArgs([]string{"completion", shell})
+
+			if err := root.Execute(); err != nil {
+				t.Fatalf("Execute() error: %v", err)
+			}
+			if !strings.Contains(out.String(), "forge") {
+				t.Errorf("completion script for %s does not mention forge", shell)
+			}
+		})
+	}
+
+	root := &cobra.Command{Use: "forge"}
+	root.AddCommand(makeCompletionCmd())
+	root.SetOut(new(bytes.Buffer))
+	root.SetErr(new(bytes.Buffer))
+	root.SetArgs([]string{"completion", "tcsh"})
+	if err := root.Execute(); err == nil {
+		t.Error("Execute() accepted an unsupported shell")
+	}
+}
+
+func TestRunCmd_DynamicCompletion(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile(defaultFileName, []byte(completionTestWorkflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+		not  []string
+	}{
+		{
+			name: "stages of default workflow",
+			args: []string{"run", "--stage", ""},
+			want: []string{"build\tCompile everything", "deploy"},
+		},
+		{
+			name: "steps with prefix",
+			args: []string{"run", defaultFileName, "--skip-step", "dep"},
+			want: []string{"deploy.push"},
+			not:  []string{"build.compile"},
+		},
+		{
+			name: "workflow files",
+			args: []string{"run", ""},
+			want: []string{"yaml", "yml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := &cobra.Command{Use: "forge"}
+			root.AddCommand(makeRunCmd(runner.New))
+			out := new(bytes.Buffer)
+			root.SetOut(out)
+			root.SetArgs(append([]string{cobra.ShellCompRequestCmd}, tt.args...))
+
+			if err := root.Execute(); err != nil {
+				t.Fatalf("Execute() error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want+"\n") {
+					t.Errorf("completions missing %q:\n%s", want, out.String())
+				}
+			}
+			for _, not := range tt.not {
+				if strings.Contains(out.String(), not) {
+					t.Errorf("completions contain %q:\n%s", not, out.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+package cmd
+
+import "github.com/andre-koe/forge/internal/config"
+
+// loadConfig returns the user defaults from the config file and FORGE_* environment variables
+var loadConfig = config.Load
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/config"
+	"github.com/andre-koe/forge/internal/runner/runnertest"
+)
+
+func TestRunCmd_ConfigPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	workflow := filepath.Join(dir, "workflow.yaml")
+	if err := os.WriteFile(workflow, []byte(sourceTestWorkflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("timezone: Asia/Tokyo\nhistory: false\nenv_files: [ci.env]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		args        []string
+		wantZone    string
+		wantHistory bool
+		// wantEnvFiles defaults to the config file's
+		wantEnvFiles []string
+		wantErr      error
+	}{
+		{name: "config file", wantZone: "Asia/Tokyo", wantHistory: false},
+		{name: "env over config", env: map[string]string{"FORGE_TIMEZONE": "Europe/Berlin", "FORGE_HISTORY": "true", "FORGE_ENV_FILES": "a.env,b.env"}, wantZone: "Europe/Berlin", wantHistory: true, wantEnvFiles: []string{"a.env", "b.env"}},
+		{name: "flag over env", env: map[string]string{"FORGE_TIMEZONE": "Europe/Berlin"}, args: []string{"--timezone", "UTC", "--env-file", "local.env"}, wantZone: "UTC", wantEnvFiles: []string{"local.env"}},
+		{name: "invalid env", env: map[string]string{"FORGE_JOBS": "lots"}, wantErr: configErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(config.PathEnv, cfgPath)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			runners := &runnertest.Factory{}
+			cmd := makeRunCmd(runners.New)
+			cmd.SetOut(new(bytes.Buffer))
+			cmd.SetErr(new(bytes.Buffer))
+			cmd.SetArgs(append([]string{workflow}, tt.args...))
+
+			err := cmd.Execute()
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Execute() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			got := runners.Runners()[0].Config
+			if got.Location == nil || got.Location.String() != tt.wantZone {
+				t.Errorf("Location = %v, want %s", got.Location, tt.wantZone)
+			}
+			if (got.History != nil) != tt.wantHistory {
+				t.Errorf("History enabled = %v, want %v", got.History != nil, tt.wantHistory)
+			}
+			want := tt.wantEnvFiles
+			if want == nil {
+				want = []string{"ci.env"}
+			}
+			if !slices.Equal(got.EnvFiles, want) {
+				t.Errorf("EnvFiles = %q, want %q", got.EnvFiles, want)
+			}
+		})
+	}
+}
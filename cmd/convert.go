@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/andre-koe/forge/internal/convert"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sourceNotFoundErr = errors.New("source file not found")
+	conversionErr     = errors.New("conversion failed")
+)
+
+func runConvert(source, from, output string, out, errOut io.Writer) error {
+	if source == "" {
+		return workflowEmptyPathErr
+	}
+	if _, err := os.Stat(source); os.IsNotExist(err) {
+		return sourceNotFoundErr
+	}
+
+	format := convert.Format(from)
+	if format == "" {
+		detected, err := convert.DetectFormat(source)
+		if err != nil {
+			return fmt.Errorf("%w: %v", conversionErr, err)
+		}
+		format = detected
+	}
+
+	res, err := convert.File(source, format)
+	if err != nil {
+		return fmt.Errorf("%w: %v", conversionErr, err)
+	}
+
+	for _, w := range res.Warnings {
+		fmt.Fprintf(errOut, "Warning: %s\n", w)
+	}
+
+	data, err := res.Marshal()
+	if err != nil {
+		return fmt.Errorf("%w: %v", conversionErr, err)
+	}
+
+	if output == "" {
+		_, err := out.Write(data)
+		return err
+	}
+
+	if _, err := os.Stat(output); err == nil {
+		return errFileExists
+	}
+	if err := os.WriteFile(output, data, 0644); err != nil {
+		return errWriteFailed
+	}
+
+	fmt.Fprintf(out, "Converted %s (%s) to %s\n", source, format, output)
+	return nil
+}
+
+func makeConvertCmd() *cobra.Command {
+	var from, output string
+
+	cmd := &cobra.Command{
+		Use:   "convert [file]",
+		Short: "Convert a pipeline definition from another tool into a forge workflow",
+		Long: `Convert a pipeline definition from another tool into a forge workflow.
+The source format is detected from the file name unless --from is given.
+Constructs without a forge equivalent are reported as warnings.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConvert(args[0], from, output, cmd.OutOrStdout(), cmd.ErrOrStderr())
+		},
+	}
+	cmd.Flags().StringVar(&from, "from", "", fmt.Sprintf("source format %v", convert.Formats()))
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write the workflow to this file instead of stdout")
+	return cmd
+}
+
+var convertCmd = makeConvertCmd()
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+const gitlabTestPipeline = `stages: [build]
+compile:
+  stage: build
+  image: golang
+  script: [go build ./...]
+`
+
+func TestRunConvert(t *testing.T) {
+	tmpDir := t.TempDir()
+	source := filepath.Join(tmpDir, ".gitlab-ci.yml")
+	if err := os.WriteFile(source, []byte(gitlabTestPipeline), 0644); err != nil {
+		t.Fatalf("failed to create pipeline: %v", err)
+	}
+	existing := filepath.Join(tmpDir, "existing.yaml")
+	if err := os.WriteFile(existing, []byte("x"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		source  string
+		from    string
+		output  string
+		wantErr error
+		wantOut string
+	}{
+		{name: "detected format to stdout", source: source, wantOut: "name: compile"},
+		{name: "explicit format to file", source: source, from: "gitlab-ci", output: filepath.Join(tmpDir, "workflow.yaml"), wantOut: "Converted"},
+		{name: "unknown format", source: source, from: "jenkins", wantErr: conversionErr},
+		{name: "source not found", source: "/non/existent/.gitlab-ci.yml", wantErr: sourceNotFoundErr},
+		{name: "output exists", source: source, output: existing, wantErr: errFileExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+			err := runConvert(tt.source, tt.from, tt.output, out, errOut)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("runConvert() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runConvert() unexpected error = %v", err)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output missing %q, got:\n%s", tt.wantOut, out.String())
+			}
+			if !strings.Contains(errOut.String(), "'image' is not supported") {
+				t.Errorf("expected warning about image, got:\n%s", errOut.String())
+			}
+			if tt.output != "" {
+				if _, err := dsl.LoadWorkflowFromFile(tt.output); err != nil {
+					t.Errorf("converted workflow does not load: %v", err)
+				}
+			}
+		})
+	}
+}
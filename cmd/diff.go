@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/spf13/cobra"
+)
+
+// diffValue formats a setting of a workflow diff, or "(unset)"
+func diffValue(v any) string {
+	if v == nil {
+		return "(unset)"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// runDiff prints the semantic differences between two workflow files
+func runDiff(oldPath, newPath string, out io.Writer, load func(string) (*dsl.Workflow, error)) error {
+	var wfs [2]*dsl.Workflow
+	for i, path := range []string{oldPath, newPath} {
+		if err := CheckFilePathExistAndIsNotEmpty(path); err != nil {
+			return err
+		}
+		wf, err := load(path)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", workflowLoadErr, path, err)
+		}
+		wfs[i] = wf
+	}
+
+	diffs := dsl.Diff(wfs[0], wfs[1])
+	if len(diffs) == 0 {
+		fmt.Fprintln(out, "No differences")
+		return nil
+	}
+	fmt.Fprintf(out, "--- %s\n+++ %s\n", oldPath, newPath)
+	for _, d := range diffs {
+		switch d.Kind {
+		case dsl.DiffAdded:
+			fmt.Fprintf(out, "+ %s %s\n", d.Unit, d.Path)
+		case dsl.DiffRemoved:
+			fmt.Fprintf(out, "- %s %s\n", d.Unit, d.Path)
+		case dsl.DiffMoved:
+			fmt.Fprintf(out, "~ %s %s moved from position %v to %v\n", d.Unit, d.Path, d.Old, d.New)
+		case dsl.DiffChanged:
+			name := d.Unit
+			if d.Path != "" {
+				name += " " + d.Path
+			}
+			fmt.Fprintf(out, "~ %s: %s %s -> %s\n", name, d.Field, diffValue(d.Old), diffValue(d.New))
+		}
+	}
+	return nil
+}
+
+func makeDiffCmd(load func(string) (*dsl.Workflow, error)) *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff <old.yml> <new.yml>",
+		Short: "Show how two workflows differ, stage by stage and step by step",
+		Long: `Compare two workflow files after they have been loaded, like 'forge explain', and
+list the differences by stage and step instead of by line: added, removed and reordered
+stages and steps, and changed settings such as a step's commands or one of its
+environment variables. Stages and steps are matched by name.`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeWorkflowFiles,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDiff(args[0], args[1], cmd.OutOrStdout(), load)
+		},
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(makeDiffCmd(dsl.LoadWorkflowFromFile))
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunDiff(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.yaml")
+	changed := filepath.Join(dir, "new.yaml")
+	if err := os.WriteFile(old, []byte(sourceTestWorkflow), 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	newWorkflow := strings.Replace(sourceTestWorkflow, `run: ["echo", "hi"]`, "run: [\"echo\", \"hello\"]\n      - name: bye\n        type: exec\n        run: [\"echo\", \"bye\"]", 1)
+	if err := os.WriteFile(changed, []byte(newWorkflow), 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		old, new   string
+		wantErr    error
+		wantOutput []string
+	}{
+		{
+			name: "changed workflow",
+			old:  old, new: changed,
+			wantOutput: []string{"--- " + old, "+++ " + changed, "+ step build/bye", `~ step build/hello: run ["echo","hi"] -> ["echo","hello"]`},
+		},
+		{name: "identical workflows", old: old, new: old, wantOutput: []string{"No differences"}},
+		{name: "missing workflow", old: old, new: filepath.Join(dir, "missing.yaml"), wantErr: workflowNotFoundErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			err := runDiff(tt.old, tt.new, out, dsl.LoadWorkflowFromFile)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("runDiff() error = %v, want %v", err, tt.wantErr)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+var (
+	docsFormatErr = errors.New("unknown docs format")
+	docsWriteErr  = errors.New("failed to write documentation")
+)
+
+// runDocs writes the CLI reference for root in the given format to dir and, if requested,
+// the DSL reference as dsl.md
+func runDocs(root *cobra.Command, format, dir string, withDSL bool, out io.Writer) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("%w: %v", docsWriteErr, err)
+	}
+
+	root.DisableAutoGenTag = true
+	var err error
+	switch format {
+	case "markdown":
+		err = doc.GenMarkdownTree(root, dir)
+	case "man":
+		err = doc.GenManTree(root, &doc.GenManHeader{Title: "FORGE", Section: "1", Source: "forge"}, dir)
+	default:
+		return fmt.Errorf("%w: %s (use markdown or man)", docsFormatErr, format)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", docsWriteErr, err)
+	}
+	fmt.Fprintf(out, "CLI reference (%s) written to %s\n", format, dir)
+
+	if withDSL {
+		path := filepath.Join(dir, "dsl.md")
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("%w: %v", docsWriteErr, err)
+		}
+		defer f.Close()
+		if err := dsl.WriteReference(f); err != nil {
+			return fmt.Errorf("%w: %v", docsWriteErr, err)
+		}
+		fmt.Fprintf(out, "DSL reference written to %s\n", path)
+	}
+	return nil
+}
+
+func makeDocsCmd() *cobra.Command {
+	var format, dir string
+	var withDSL bool
+
+	cmd := &cobra.Command{
+		Use:    "docs",
+		Short:  "Generate man pages or Markdown reference documentation",
+		Long:   `Generate the CLI reference from the command tree, for packaging. --dsl adds a reference of the workflow DSL.`,
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDocs(cmd.Root(), format, dir, withDSL, cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "markdown", "output format: markdown or man")
+	cmd.Flags().StringVarP(&dir, "dir", "d", "docs/cli", "directory to write the documentation to")
+	cmd.Flags().BoolVar(&withDSL, "dsl", false, "also write the workflow DSL reference (dsl.md)")
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(makeDocsCmd())
+}
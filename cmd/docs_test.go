@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func docsTestRoot() *cobra.Command {
+	root := &cobra.Command{Use: "forge", Short: "test root"}
+	root.AddCommand(&cobra.Command{Use: "run", Short: "run a workflow", Run: func(*cobra.Command, []string) {}})
+	return root
+}
+
+func TestRunDocs(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		withDSL   bool
+		wantFiles []string
+		wantErr   error
+	}{
+		{name: "markdown", format: "markdown", wantFiles: []string{"forge.md", "forge_run.md"}},
+		{name: "man with dsl", format: "man", withDSL: true, wantFiles: []string{"forge.1", "forge-run.1", "dsl.md"}},
+		{name: "unknown format", format: "pdf", wantErr: docsFormatErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "docs")
+			err := runDocs(docsTestRoot(), tt.format, dir, tt.withDSL, new(bytes.Buffer))
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("runDocs() error = %v, want %v", err, tt.wantErr)
+			}
+			for _, name := range tt.wantFiles {
+				info, err := os.Stat(filepath.Join(dir, name))
+				if err != nil || info.Size() == 0 {
+					t.Errorf("expected non-empty %s: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDocsCmd_Hidden(t *testing.T) {
+	if !makeDocsCmd().Hidden {
+		t.Error("docs command should be hidden")
+	}
+}
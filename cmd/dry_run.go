@@ -4,38 +4,167 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"time"
 
+	"github.com/andre-koe/forge/internal/history"
 	"github.com/andre-koe/forge/internal/runner"
 	"github.com/spf13/cobra"
 )
 
-func runDryRun(workflow string, out io.Writer, newRunner func(string, ...runner.Option) (*runner.Runner, error)) error {
+var dryRunFormatErr = errors.New("unknown dry-run format")
+
+// runDryRunPlan writes the resolved execution plan of the workflow as JSON
+func runDryRunPlan(workflow string, out io.Writer, newRunner func(string, ...runner.Option) (*runner.Runner, error), opts ...runner.Option) error {
+	if err := CheckFilePathExistAndIsNotEmpty(workflow); err != nil {
+		return err
+	}
+
+	r, err := newRunner(workflow, append([]runner.Option{runner.WithOut(io.Discard)}, opts...)...)
+	if err != nil {
+		return runnerCreationErr
+	}
+
+	p, err := r.ResolvePlan()
+	if err != nil {
+		return fmt.Errorf("%w: %v", workflowExecutionErr, err)
+	}
+	return p.Write(out)
+}
+
+func runDryRun(workflow string, out io.Writer, newRunner func(string, ...runner.Option) (*runner.Runner, error), opts ...runner.Option) error {
 	if err := CheckFilePathExistAndIsNotEmpty(workflow); err != nil {
 		return err
 	}
 
-	r, err := newRunner(workflow, runner.WithOut(out))
+	r, err := newRunner(workflow, append([]runner.Option{runner.WithOut(out), runner.WithMode(runner.ModeDryRun)}, opts...)...)
+	if err != nil {
+		return runnerCreationErr
+	}
+
+	if err := r.Run(); err != nil {
+		return fmt.Errorf("%w: %v", workflowExecutionErr, err)
+	}
+	return nil
+}
+
+// runCompareLast prints how the planned steps differ from the last recorded run of the workflow
+func runCompareLast(workflow string, out io.Writer, newRunner func(string, ...runner.Option) (*runner.Runner, error), opts ...runner.Option) error {
+	r, err := newRunner(workflow, append([]runner.Option{runner.WithOut(out)}, opts...)...)
 	if err != nil {
 		return runnerCreationErr
 	}
 
-	if err := r.DryRun(); err != nil {
-		return workflowExecutionErr
+	wf, err := r.LoadWorkflow(workflow)
+	if err != nil {
+		return fmt.Errorf("%w: %v", workflowLoadErr, err)
+	}
+
+	last, err := history.ForWorkflow(workflow).Last(wf.Name)
+	if errors.Is(err, history.ErrNoRuns) {
+		fmt.Fprintf(out, "\n[DRY-RUN] No recorded run of %s to compare with\n", wf.Name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", historyReadErr, err)
+	}
+
+	fmt.Fprintf(out, "\n[DRY-RUN] Comparing with last run %s (%s, %s)\n", last.ID, last.Status, last.StartedAt.Format(time.RFC3339))
+	changes := history.Diff(last.Steps, runner.PlannedSteps(wf))
+	if len(changes) == 0 {
+		fmt.Fprintf(out, "[DRY-RUN]   No changes\n")
+		return nil
+	}
+
+	for _, c := range changes {
+		switch c.Kind {
+		case history.ChangeAdded:
+			fmt.Fprintf(out, "[DRY-RUN]   + %s (%s) %v\n", c.Path, c.New.Type, c.New.Commands)
+		case history.ChangeRemoved:
+			fmt.Fprintf(out, "[DRY-RUN]   - %s (%s) %v\n", c.Path, c.Old.Type, c.Old.Commands)
+		case history.ChangeChanged:
+			fmt.Fprintf(out, "[DRY-RUN]   ~ %s (%s) %v -> (%s) %v\n", c.Path, c.Old.Type, c.Old.Commands, c.New.Type, c.New.Commands)
+		}
+	}
+	if last.Status == history.StatusFailed {
+		fmt.Fprintf(out, "[DRY-RUN]   Note: the last run failed, steps after the failure were never executed\n")
 	}
 	return nil
 }
 
 func makeDryRunCmd(newRunner func(string, ...runner.Option) (*runner.Runner, error)) *cobra.Command {
-	return &cobra.Command{
+	var compareLast, strictWarnings bool
+	var format, profile, templateValues string
+	var envFiles, vars, varFiles []string
+
+	cmd := &cobra.Command{
 		Use:   "dry-run [workflow]",
 		Short: "Simulate the execution of a workflow without making any changes",
-		Long:  `Simulate the execution of a workflow defined in your forge configuration file without making any changes.`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Simulate the execution of a workflow defined in your forge configuration file without making any changes.
+
+Steps marked dry_run: execute run for real, for read-only commands such as terraform
+plan, and fail the dry run when they fail; steps marked dry_run: skip are left out.
+
+--format json prints the resolved execution plan instead: the stages in execution order with
+their conditions, and each step's final command, directory, environment, timeout and
+whether it would be skipped. It has the shape of the plan written by 'forge plan'.
+
+--profile prod shows the resolved values of the workflow's prod profile; the values of
+its secrets are masked. --template-values values.yaml renders the workflow through
+text/template first, as forge run does.
+
+Warnings about the workflow, see 'forge validate', are printed before the plan;
+--strict-warnings fails the dry run on them.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeWorkflowFiles,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runDryRun(args[0], cmd.OutOrStdout(), newRunner)
+			values, err := parseVars(vars, varFiles)
+			if err != nil {
+				return err
+			}
+			opts := []runner.Option{runner.WithEnvFiles(envFiles...), runner.WithVars(values), runner.WithProfile(profile), runner.WithStrictWarnings(strictWarnings)}
+			templateVals, err := readTemplateValues(templateValues)
+			if err != nil {
+				return err
+			}
+			if templateVals != nil {
+				sourceOpts, err := workflowSourceOptions(args[0], "", templateVals, cmd.InOrStdin(), workflowHTTPClient)
+				if err != nil {
+					return err
+				}
+				opts = append(opts, sourceOpts...)
+			}
+			switch {
+			case format == "json" && compareLast:
+				return fmt.Errorf("%w: --compare-last needs --format text", dryRunFormatErr)
+			case format == "json":
+				return runDryRunPlan(args[0], cmd.OutOrStdout(), newRunner, opts...)
+			case format != "text":
+				return fmt.Errorf("%w: %s (use text or json)", dryRunFormatErr, format)
+			}
+			if err := runDryRun(args[0], cmd.OutOrStdout(), newRunner, opts...); err != nil {
+				return err
+			}
+			if compareLast {
+				return runCompareLast(args[0], cmd.OutOrStdout(), newRunner, opts...)
+			}
+			return nil
 		},
 	}
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "override a workflow variable, e.g. tag=v1.2.3 (repeatable)")
+	cmd.Flags().StringSliceVar(&varFiles, "var-file", nil, "override workflow variables from a YAML file (repeatable, --var wins)")
+	cmd.Flags().StringSliceVar(&envFiles, "env-file", nil, "load KEY=VALUE pairs from a dotenv file for interpolation (repeatable)")
+	cmd.Flags().StringVar(&profile, "profile", "", "apply the overrides of the named workflow profile, e.g. prod")
+	cmd.Flags().StringVar(&templateValues, "template-values", "", "render the workflow through text/template with the values of this YAML file")
+	cmd.Flags().StringVar(&format, "format", "text", "output format: text or json")
+	cmd.Flags().BoolVar(&compareLast, "compare-last", false, "compare the planned commands with the last recorded run")
+	cmd.Flags().BoolVar(&strictWarnings, "strict-warnings", false, "fail on workflow warnings as on errors")
+	_ = cmd.MarkFlagFilename("env-file")
+	_ = cmd.MarkFlagFilename("var-file", "yaml", "yml")
+	_ = cmd.MarkFlagFilename("template-values", "yaml", "yml")
+	return cmd
 }
 
 var (
@@ -2,13 +2,17 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/andre-koe/forge/internal/dsl"
 	"github.com/andre-koe/forge/internal/runner"
+	"github.com/andre-koe/forge/internal/runner/runnertest"
 )
 
 func TestRunDryRun(t *testing.T) {
@@ -311,6 +315,86 @@ stages:
 	}
 }
 
+func TestDryRunCmd_EnvFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	workflowPath := filepath.Join(tmpDir, "workflow.yml")
+	envPath := filepath.Join(tmpDir, ".env")
+	workflowContent := []byte(`name: env
+stages:
+  - name: deploy
+    steps:
+      - name: push
+        type: exec
+        run: ["deploy", "--region", "${{ env.REGION }}"]
+`)
+	if err := os.WriteFile(workflowPath, workflowContent, 0644); err != nil {
+		t.Fatalf("failed to create test workflow: %v", err)
+	}
+	if err := os.WriteFile(envPath, []byte("REGION=eu-west-1\n"), 0644); err != nil {
+		t.Fatalf("failed to create env file: %v", err)
+	}
+
+	cmd := makeDryRunCmd(runner.NewRunner)
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs([]string{workflowPath, "--env-file", envPath})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Would execute command: [deploy --region eu-west-1]") {
+		t.Errorf("dry-run output does not show the interpolated command:\n%s", out.String())
+	}
+}
+
+func TestDryRunCmd_FormatJSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	workflowPath := filepath.Join(tmpDir, "workflow.yml")
+	workflowContent := []byte(`name: plan
+vars:
+  tag: dev
+stages:
+  - name: deploy
+    steps:
+      - name: push
+        type: exec
+        run: ["docker", "push", "app:${{ vars.tag }}"]
+`)
+	if err := os.WriteFile(workflowPath, workflowContent, 0644); err != nil {
+		t.Fatalf("failed to create test workflow: %v", err)
+	}
+
+	cmd := makeDryRunCmd(runner.NewRunner)
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs([]string{workflowPath, "--format", "json", "--var", "tag=v2"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	var p runner.Plan
+	if err := json.Unmarshal(out.Bytes(), &p); err != nil {
+		t.Fatalf("output is not a plan: %v\n%s", err, out)
+	}
+	if len(p.Stages) != 1 || !reflect.DeepEqual(p.Stages[0].Steps[0].Argv, []string{"docker", "push", "app:v2"}) {
+		t.Errorf("plan stages = %+v, want the interpolated command", p.Stages)
+	}
+
+	for _, args := range [][]string{
+		{workflowPath, "--format", "yaml"},
+		{workflowPath, "--format", "json", "--compare-last"},
+	} {
+		cmd := makeDryRunCmd(runner.NewRunner)
+		cmd.SetOut(new(bytes.Buffer))
+		cmd.SetErr(new(bytes.Buffer))
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); !errors.Is(err, dryRunFormatErr) {
+			t.Errorf("Execute(%q) error = %v, want %v", args, err, dryRunFormatErr)
+		}
+	}
+}
+
 func TestRunDryRun_ComparisonWithRun(t *testing.T) {
 	// Verify that dry-run and run use the same error types and validation
 	tmpDir := t.TempDir()
@@ -334,7 +418,8 @@ stages:
 	}
 
 	// Both should return the same error type for runner creation failure
-	errRun := runRun(workflowPath, out, mockNewRunner)
+	failing := &runnertest.Factory{Err: errors.New("creation error")}
+	errRun := runRun(workflowPath, out, failing.New)
 	errDryRun := runDryRun(workflowPath, out, mockNewRunner)
 
 	if !errors.Is(errRun, runnerCreationErr) {
@@ -346,7 +431,7 @@ stages:
 	}
 
 	// Both should handle empty paths the same way
-	errRunEmpty := runRun("", out, mockNewRunner)
+	errRunEmpty := runRun("", out, failing.New)
 	errDryRunEmpty := runDryRun("", out, mockNewRunner)
 
 	if !errors.Is(errRunEmpty, workflowEmptyPathErr) {
@@ -357,3 +442,126 @@ stages:
 		t.Errorf("runDryRun(\"\") error = %v, want workflowEmptyPathErr", errDryRunEmpty)
 	}
 }
+
+func TestRunCompareLast(t *testing.T) {
+	tmpDir := t.TempDir()
+	workflowPath := filepath.Join(tmpDir, "workflow.yml")
+	if err := os.WriteFile(workflowPath, []byte(planTestWorkflow), 0644); err != nil {
+		t.Fatalf("failed to create test workflow: %v", err)
+	}
+
+	out := new(bytes.Buffer)
+	if err := runCompareLast(workflowPath, out, runner.NewRunner); err != nil {
+		t.Fatalf("runCompareLast() unexpected error = %v", err)
+	}
+	if !strings.Contains(out.String(), "No recorded run") {
+		t.Errorf("expected note about missing history, got:\n%s", out.String())
+	}
+
+	if err := runRun(workflowPath, new(bytes.Buffer), runner.New); err != nil {
+		t.Fatalf("runRun() failed: %v", err)
+	}
+
+	out.Reset()
+	if err := runCompareLast(workflowPath, out, runner.NewRunner); err != nil {
+		t.Fatalf("runCompareLast() unexpected error = %v", err)
+	}
+	if !strings.Contains(out.String(), "No changes") {
+		t.Errorf("expected no changes after identical run, got:\n%s", out.String())
+	}
+
+	changed := strings.Replace(planTestWorkflow, `["echo", "hello"]`, `["echo", "bye"]`, 1)
+	if err := os.WriteFile(workflowPath, []byte(changed), 0644); err != nil {
+		t.Fatalf("failed to modify workflow: %v", err)
+	}
+
+	out.Reset()
+	if err := runCompareLast(workflowPath, out, runner.NewRunner); err != nil {
+		t.Fatalf("runCompareLast() unexpected error = %v", err)
+	}
+	if !strings.Contains(out.String(), "~ test-stage/hello (exec) [[echo hello]] -> (exec) [[echo bye]]") {
+		t.Errorf("expected changed command, got:\n%s", out.String())
+	}
+}
+
+func TestDryRunCmd_TemplateValues(t *testing.T) {
+	tmpDir := t.TempDir()
+	workflowPath := filepath.Join(tmpDir, "workflow.yml")
+	valuesPath := filepath.Join(tmpDir, "values.yaml")
+	workflowContent := []byte(`name: services
+vars:
+  tag: dev
+stages:
+{{- range .services }}
+  - name: build-{{ . }}
+    steps:
+      - name: build
+        type: exec
+        run: ["docker", "build", "-t", "{{ . }}:${{ vars.tag }}", "services/{{ . }}"]
+{{- end }}
+`)
+	if err := os.WriteFile(workflowPath, workflowContent, 0644); err != nil {
+		t.Fatalf("failed to create test workflow: %v", err)
+	}
+	if err := os.WriteFile(valuesPath, []byte("services: [api, web]\n"), 0644); err != nil {
+		t.Fatalf("failed to create values file: %v", err)
+	}
+
+	cmd := makeDryRunCmd(runner.NewRunner)
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs([]string{workflowPath, "--template-values", valuesPath})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	for _, want := range []string{"Would execute command: [docker build -t api:dev services/api]", "Would execute command: [docker build -t web:dev services/web]"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("dry-run output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	if err := os.WriteFile(valuesPath, []byte("other: [api]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd = makeDryRunCmd(runner.NewRunner)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs([]string{workflowPath, "--template-values", valuesPath})
+	if err := cmd.Execute(); !errors.Is(err, workflowTemplateErr) {
+		t.Errorf("Execute() error = %v, want %v", err, workflowTemplateErr)
+	}
+}
+
+func TestDryRunCmd_StrictWarnings(t *testing.T) {
+	workflowPath := filepath.Join(t.TempDir(), "workflow.yml")
+	content := []byte("name: build\nstages:\n  - name: build\n    steps:\n      - name: compile\n        type: exec\n        run: [\"./build.sh\"]\n")
+	if err := os.WriteFile(workflowPath, content, 0644); err != nil {
+		t.Fatalf("failed to create test workflow: %v", err)
+	}
+
+	for _, strict := range []bool{false, true} {
+		cmd := makeDryRunCmd(runner.NewRunner)
+		out := new(bytes.Buffer)
+		cmd.SetOut(out)
+		cmd.SetErr(out)
+		args := []string{workflowPath}
+		if strict {
+			args = append(args, "--strict-warnings")
+		}
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		if strict {
+			if err == nil || !strings.Contains(err.Error(), "workflow has warnings") {
+				t.Errorf("Execute() with --strict-warnings error = %v, want the warnings", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Execute() error: %v", err)
+		}
+		if want := "[DRY-RUN] Warning: step build.compile runs ./build.sh"; !strings.Contains(out.String(), want) {
+			t.Errorf("dry-run output missing %q:\n%s", want, out.String())
+		}
+	}
+}
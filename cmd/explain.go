@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/expr"
+	"github.com/andre-koe/forge/internal/runner"
+	yaml "github.com/goccy/go-yaml"
+	"github.com/spf13/cobra"
+)
+
+// explanation is what forge explain prints: the loaded workflow, and its stages as a run with the
+// given variables, profile and env files would execute them
+type explanation struct {
+	Workflow *dsl.Workflow      `yaml:"workflow"`
+	Vars     map[string]string  `yaml:"vars,omitempty"`
+	Profile  string             `yaml:"profile,omitempty"`
+	EnvFiles []string           `yaml:"env_files,omitempty"`
+	Stages   []runner.PlanStage `yaml:"stages"`
+}
+
+func runExplain(workflow string, out io.Writer, load func(string) (*dsl.Workflow, error), opts ...runner.Option) error {
+	if err := CheckFilePathExistAndIsNotEmpty(workflow); err != nil {
+		return err
+	}
+
+	r, err := runner.NewRunner(workflow, append([]runner.Option{runner.WithOut(io.Discard), runner.WithLoadWorkflow(load)}, opts...)...)
+	if err != nil {
+		return fmt.Errorf("%w: %v", runnerCreationErr, err)
+	}
+	p, err := r.ResolvePlan()
+	if err != nil {
+		return fmt.Errorf("%w: %v", workflowLoadErr, err)
+	}
+
+	b, err := yaml.MarshalWithOptions(explanation{Workflow: p.Resolved, Vars: p.Vars, Profile: p.Profile, EnvFiles: p.EnvFiles, Stages: p.Stages}, yaml.IndentSequence(true))
+	if err != nil {
+		return fmt.Errorf("%w: %v", workflowLoadErr, err)
+	}
+
+	fmt.Fprintf(out, "# Resolved workflow: %s\n", workflow)
+	fmt.Fprint(out, string(b))
+	return nil
+}
+
+// runExplainFunctions lists the functions available in ${{ }} expressions
+func runExplainFunctions(out io.Writer) error {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, f := range expr.Functions() {
+		fmt.Fprintf(tw, "%s\t%s\n", f.Signature, f.Description)
+	}
+	return tw.Flush()
+}
+
+func makeExplainCmd(load func(string) (*dsl.Workflow, error)) *cobra.Command {
+	var functions bool
+	var profile string
+	var envFiles, vars, varFiles []string
+
+	cmd := &cobra.Command{
+		Use:   "explain [workflow]",
+		Short: "Print the fully-resolved workflow the runner would execute",
+		Long: `Print the workflow after it has been loaded, validated and resolved, followed by
+its stages as a run would execute them: in execution order, with each step's command
+interpolated and its directory, environment and timeout. This makes it useful for
+debugging defaults, includes, interpolation and expansion.
+
+--var, --var-file, --profile and --env-file resolve ${{ vars.* }} and ${{ env.* }}
+as forge run does with the same flags.
+
+--functions lists the functions available in ${{ }} expressions instead, e.g.
+${{ default(env.STAGE, "dev") }} or ${{ hash("go.sum") }}.`,
+		Args:              cobra.RangeArgs(0, 1),
+		ValidArgsFunction: completeWorkflowFiles,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if functions {
+				return runExplainFunctions(cmd.OutOrStdout())
+			}
+			if len(args) == 0 {
+				return workflowEmptyPathErr
+			}
+			values, err := parseVars(vars, varFiles)
+			if err != nil {
+				return err
+			}
+			return runExplain(args[0], cmd.OutOrStdout(), load, runner.WithEnvFiles(envFiles...), runner.WithVars(values), runner.WithProfile(profile))
+		},
+	}
+	cmd.Flags().BoolVar(&functions, "functions", false, "list the functions of ${{ }} expressions")
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "override a workflow variable, e.g. tag=v1.2.3 (repeatable)")
+	cmd.Flags().StringSliceVar(&varFiles, "var-file", nil, "override workflow variables from a YAML file (repeatable, --var wins)")
+	cmd.Flags().StringSliceVar(&envFiles, "env-file", nil, "load KEY=VALUE pairs from a dotenv file for interpolation (repeatable)")
+	cmd.Flags().StringVar(&profile, "profile", "", "apply the overrides of the named workflow profile, e.g. prod")
+	_ = cmd.MarkFlagFilename("env-file")
+	_ = cmd.MarkFlagFilename("var-file", "yaml", "yml")
+	return cmd
+}
+
+var explainCmd = makeExplainCmd(dsl.LoadWorkflowFromFile)
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+}
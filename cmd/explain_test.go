@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunExplain(t *testing.T) {
+	tmpDir := t.TempDir()
+	validWorkflow := filepath.Join(tmpDir, "workflow.yml")
+	workflowContent := []byte(`name: test-workflow
+description: Test
+stages:
+  - name: test-stage
+    steps:
+      - name: hello
+        type: exec
+        run: ["echo", "hello"]
+`)
+	if err := os.WriteFile(validWorkflow, workflowContent, 0644); err != nil {
+		t.Fatalf("failed to create test workflow: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		workflow string
+		load     func(string) (*dsl.Workflow, error)
+		wantErr  error
+		wantOut  []string
+	}{
+		{
+			name:     "successful explain",
+			workflow: validWorkflow,
+			load:     dsl.LoadWorkflowFromFile,
+			wantOut:  []string{"# Resolved workflow:", "name: test-workflow", "- name: hello"},
+		},
+		{
+			name:     "empty workflow path",
+			workflow: "",
+			wantErr:  workflowEmptyPathErr,
+		},
+		{
+			name:     "workflow file not found",
+			workflow: "/non/existent/workflow.yml",
+			wantErr:  workflowNotFoundErr,
+		},
+		{
+			name:     "load fails",
+			workflow: validWorkflow,
+			load: func(string) (*dsl.Workflow, error) {
+				return nil, errors.New("mock load error")
+			},
+			wantErr: workflowLoadErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			err := runExplain(tt.workflow, out, tt.load)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("runExplain() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runExplain() unexpected error = %v", err)
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q, got:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestMakeExplainCmd_Vars(t *testing.T) {
+	dir := t.TempDir()
+	workflow := filepath.Join(dir, "workflow.yml")
+	content := `name: release
+vars:
+  tag: dev
+  registry: localhost:5000
+profiles:
+  prod:
+    vars:
+      registry: registry.example.com
+stages:
+  - name: publish
+    steps:
+      - name: push
+        type: exec
+        run: ["docker", "push", "${{ vars.registry }}/app:${{ vars.tag }}", "${{ env.REGION }}"]
+`
+	if err := os.WriteFile(workflow, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	envFile := filepath.Join(dir, "prod.env")
+	if err := os.WriteFile(envFile, []byte("REGION=eu-west-1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := makeExplainCmd(dsl.LoadWorkflowFromFile)
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{workflow, "--var", "tag=v1.2.3", "--profile", "prod", "--env-file", envFile})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	for _, want := range []string{"tag: v1.2.3", "profile: prod", "- registry.example.com/app:v1.2.3", "- eu-west-1"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestExplainCmd_Properties(t *testing.T) {
+	cmd := makeExplainCmd(dsl.LoadWorkflowFromFile)
+
+	if cmd.Use != "explain [workflow]" {
+		t.Errorf("expected Use to be 'explain [workflow]', got %q", cmd.Use)
+	}
+	if cmd.Short == "" {
+		t.Error("expected Short description to be non-empty")
+	}
+	if cmd.Args == nil {
+		t.Error("expected Args validator to be set")
+	}
+}
+
+func TestMakeExplainCmd_Functions(t *testing.T) {
+	cmd := makeExplainCmd(dsl.LoadWorkflowFromFile)
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{"--functions"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	for _, want := range []string{"env(name)", "default(value, fallback)", "now([layout])", "uuid()"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	cmd = makeExplainCmd(dsl.LoadWorkflowFromFile)
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	cmd.SetArgs(nil)
+	if err := cmd.Execute(); !errors.Is(err, workflowEmptyPathErr) {
+		t.Errorf("Execute() without workflow error = %v, want %v", err, workflowEmptyPathErr)
+	}
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/andre-koe/forge/internal/convert"
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportTargetErr = errors.New("unsupported export target")
+	exportErr       = errors.New("export failed")
+)
+
+func runExport(workflow, to, output string, invokeForge bool, out, errOut io.Writer, load func(string) (*dsl.Workflow, error)) error {
+	if err := CheckFilePathExistAndIsNotEmpty(workflow); err != nil {
+		return err
+	}
+	if convert.Target(to) != convert.TargetGitHubActions {
+		return fmt.Errorf("%w: %q (supported: %v)", exportTargetErr, to, convert.Targets())
+	}
+
+	wf, err := load(workflow)
+	if err != nil {
+		return fmt.Errorf("%w: %v", workflowLoadErr, err)
+	}
+
+	res, err := convert.GitHubActions(wf, workflow, invokeForge)
+	if err != nil {
+		return fmt.Errorf("%w: %v", exportErr, err)
+	}
+	for _, w := range res.Warnings {
+		fmt.Fprintf(errOut, "Warning: %s\n", w)
+	}
+
+	if output == "" {
+		_, err := out.Write(res.Data)
+		return err
+	}
+	if _, err := os.Stat(output); err == nil {
+		return errFileExists
+	}
+	if err := os.WriteFile(output, res.Data, 0644); err != nil {
+		return errWriteFailed
+	}
+
+	fmt.Fprintf(out, "Exported %s to %s (%s)\n", workflow, output, to)
+	return nil
+}
+
+func makeExportCmd(load func(string) (*dsl.Workflow, error)) *cobra.Command {
+	var to, output string
+	var invokeForge bool
+
+	cmd := &cobra.Command{
+		Use:   "export [workflow]",
+		Short: "Export a workflow to another CI system",
+		Long: `Export a forge workflow as a pipeline definition for another CI system,
+so a single workflow file can serve both local runs and hosted CI.
+By default each stage becomes a job running the equivalent commands; with
+--invoke-forge the generated pipeline installs forge and runs the workflow itself.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeWorkflowFiles,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExport(args[0], to, output, invokeForge, cmd.OutOrStdout(), cmd.ErrOrStderr(), load)
+		},
+	}
+	cmd.Flags().StringVar(&to, "to", string(convert.TargetGitHubActions), fmt.Sprintf("export target %v", convert.Targets()))
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write the pipeline to this file instead of stdout")
+	cmd.Flags().BoolVar(&invokeForge, "invoke-forge", false, "generate a pipeline that runs the workflow with forge instead of translating each step")
+	return cmd
+}
+
+var exportCmd = makeExportCmd(dsl.LoadWorkflowFromFile)
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+}
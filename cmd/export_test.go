@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunExport(t *testing.T) {
+	tmpDir := t.TempDir()
+	workflowPath := filepath.Join(tmpDir, "workflow.yml")
+	if err := os.WriteFile(workflowPath, []byte(planTestWorkflow), 0644); err != nil {
+		t.Fatalf("failed to create test workflow: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		to          string
+		output      string
+		invokeForge bool
+		wantErr     error
+		wantOut     string
+	}{
+		{name: "github actions to stdout", to: "github-actions", wantOut: "run: echo hello"},
+		{name: "invoke forge", to: "github-actions", invokeForge: true, wantOut: "forge run"},
+		{name: "to file", to: "github-actions", output: filepath.Join(tmpDir, "ci.yml"), wantOut: "Exported"},
+		{name: "unsupported target", to: "jenkins", wantErr: exportTargetErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			err := runExport(workflowPath, tt.to, tt.output, tt.invokeForge, out, new(bytes.Buffer), dsl.LoadWorkflowFromFile)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("runExport() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runExport() unexpected error = %v", err)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output missing %q, got:\n%s", tt.wantOut, out.String())
+			}
+		})
+	}
+}
@@ -8,29 +8,80 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
 	"github.com/spf13/cobra"
 )
 
 const defaultFileName string = "workflow.yaml"
 
 var (
-	errFileExists  = errors.New("file already exists")
-	errWriteFailed = errors.New("failed to write template file")
+	errFileExists      = errors.New("file already exists")
+	errWriteFailed     = errors.New("failed to write template file")
+	errUnknownTemplate = errors.New("unknown template")
+	errInitTarget      = errors.New("give the target either as argument or with --output")
 )
 
+// initOptions configures forge init
+type initOptions struct {
+	Template string
+	// Force overwrites an existing file
+	Force bool
+	// ForgeDir writes the workflow into the .forge/workflows directory of the target directory
+	ForgeDir bool
+}
+
 func runWriteTemplate(fileName string, out io.Writer) error {
-	if fileName == "" {
-		fileName = defaultFileName
+	return runInit(fileName, initOptions{Template: dsl.DefaultTemplate}, out)
+}
+
+func runListTemplates(out io.Writer) error {
+	for _, t := range dsl.Templates() {
+		fmt.Fprintf(out, "%-14s %s\n", t.Name, t.Description)
 	}
+	return nil
+}
+
+// initPath resolves where forge init writes: target may be empty, a file or a directory
+func initPath(target string, opts initOptions) string {
+	if opts.ForgeDir {
+		if target == "" {
+			target = "."
+		}
+		return filepath.Join(target, history.WorkflowsDir, opts.Template+".yaml")
+	}
+	if target == "" {
+		return defaultFileName
+	}
+	if strings.HasSuffix(target, "/") || strings.HasSuffix(target, string(filepath.Separator)) {
+		return filepath.Join(target, defaultFileName)
+	}
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		return filepath.Join(target, defaultFileName)
+	}
+	return target
+}
+
+func runInit(target string, opts initOptions, out io.Writer) error {
+	if !slices.ContainsFunc(dsl.Templates(), func(t dsl.Template) bool { return t.Name == opts.Template }) {
+		return fmt.Errorf("%w: %s (see forge init --list-templates)", errUnknownTemplate, opts.Template)
+	}
+
+	fileName := initPath(target, opts)
 
 	// Check if file already exists
-	if _, err := os.Stat(fileName); err == nil {
+	if info, err := os.Stat(fileName); err == nil && (!opts.Force || info.IsDir()) {
 		return errFileExists
 	}
 
-	err := dsl.WriteTemplate(fileName)
+	if err := os.MkdirAll(filepath.Dir(fileName), 0o755); err != nil {
+		return errWriteFailed
+	}
+	err := dsl.WriteNamedTemplate(fileName, opts.Template)
 	if err != nil {
 		return errWriteFailed
 	}
@@ -40,19 +91,38 @@ func runWriteTemplate(fileName string, out io.Writer) error {
 }
 
 func makeInitCmd() *cobra.Command {
-	return &cobra.Command{
+	var output string
+	var opts initOptions
+	var listTemplates bool
+
+	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "Initialize a new Forge project",
 		Long: `Initialize a new Forge project by creating a template workflow configuration file.
-If a file with the specified name already exists, it will not be overwritten.`,
+If a file with the specified name already exists, it will not be overwritten unless --force is given.
+The target may be a file or a directory; --forge-dir writes to .forge/workflows/<template>.yaml
+for projects with several workflows.
+Use --template to start from a common use case; --list-templates shows them all.`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) == 0 {
-				return runWriteTemplate("", cmd.OutOrStdout())
+			if listTemplates {
+				return runListTemplates(cmd.OutOrStdout())
+			}
+			if len(args) == 1 {
+				if output != "" {
+					return errInitTarget
+				}
+				output = args[0]
 			}
-			return runWriteTemplate(args[0], cmd.OutOrStdout())
+			return runInit(output, opts, cmd.OutOrStdout())
 		},
 	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file or directory to write the workflow to")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "overwrite an existing file")
+	cmd.Flags().BoolVar(&opts.ForgeDir, "forge-dir", false, "write into the .forge/workflows directory")
+	cmd.Flags().StringVar(&opts.Template, "template", dsl.DefaultTemplate, "template to start from, see --list-templates")
+	cmd.Flags().BoolVar(&listTemplates, "list-templates", false, "list the available templates")
+	return cmd
 }
 
 var initCmd = makeInitCmd()
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
 )
 
 func TestRunWriteTemplate(t *testing.T) {
@@ -321,3 +324,105 @@ func TestRunWriteTemplate_PermissionError(t *testing.T) {
 		t.Errorf("runWriteTemplate() error = %v, want errWriteFailed", err)
 	}
 }
+
+func TestRunInit_Templates(t *testing.T) {
+	for _, tpl := range dsl.Templates() {
+		t.Run(tpl.Name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "workflow.yaml")
+			if err := runInit(path, initOptions{Template: tpl.Name}, new(bytes.Buffer)); err != nil {
+				t.Fatalf("runInit() error: %v", err)
+			}
+			if _, err := dsl.LoadWorkflowFromFile(path); err != nil {
+				t.Errorf("template %s does not load: %v", tpl.Name, err)
+			}
+		})
+	}
+
+	t.Run("unknown template", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "workflow.yaml")
+		err := runInit(path, initOptions{Template: "cobol-ci"}, new(bytes.Buffer))
+		if !errors.Is(err, errUnknownTemplate) {
+			t.Errorf("runInit() error = %v, want %v", err, errUnknownTemplate)
+		}
+		if _, statErr := os.Stat(path); !os.IsNotExist(statErr) {
+			t.Error("runInit() wrote a file for an unknown template")
+		}
+	})
+}
+
+func TestMakeInitCmd_ListTemplates(t *testing.T) {
+	cmd := makeInitCmd()
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{"--list-templates"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	for _, name := range []string{"hello", "go-ci", "docker-build", "deploy", "monorepo"} {
+		if !bytes.Contains(out.Bytes(), []byte(name)) {
+			t.Errorf("--list-templates output missing %q:\n%s", name, out.String())
+		}
+	}
+}
+
+func TestRunInit_Targets(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		opts     initOptions
+		existing string
+		wantPath string
+		wantErr  error
+	}{
+		{name: "existing directory", target: "ci", existing: "ci/", wantPath: "ci/workflow.yaml"},
+		{name: "new directory", target: "new/", wantPath: "new/workflow.yaml"},
+		{name: "nested file", target: "a/b/flow.yml", wantPath: "a/b/flow.yml"},
+		{name: "existing file refused", target: "flow.yml", existing: "flow.yml", wantErr: errFileExists},
+		{name: "existing file forced", target: "flow.yml", existing: "flow.yml", opts: initOptions{Force: true}, wantPath: "flow.yml"},
+		{name: "forge dir", opts: initOptions{ForgeDir: true, Template: "go-ci"}, wantPath: ".forge/workflows/go-ci.yaml"},
+		{name: "forge dir in target", target: "svc", opts: initOptions{ForgeDir: true}, wantPath: "svc/.forge/workflows/hello.yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			if strings.HasSuffix(tt.existing, "/") {
+				if err := os.MkdirAll(tt.existing, 0o755); err != nil {
+					t.Fatal(err)
+				}
+			} else if tt.existing != "" {
+				if err := os.WriteFile(tt.existing, []byte("existing"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.opts.Template == "" {
+				tt.opts.Template = dsl.DefaultTemplate
+			}
+
+			err := runInit(tt.target, tt.opts, new(bytes.Buffer))
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("runInit() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if _, err := dsl.LoadWorkflowFromFile(filepath.FromSlash(tt.wantPath)); err != nil {
+				t.Errorf("runInit() did not write a valid workflow to %s: %v", tt.wantPath, err)
+			}
+		})
+	}
+}
+
+func TestMakeInitCmd_OutputAndArgument(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	cmd := makeInitCmd()
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	cmd.SetArgs([]string{"a.yaml", "-o", "b.yaml"})
+
+	if err := cmd.Execute(); !errors.Is(err, errInitTarget) {
+		t.Errorf("Execute() error = %v, want %v", err, errInitTarget)
+	}
+}
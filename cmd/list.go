@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/spf13/cobra"
+)
+
+// runList prints the stages, steps and hooks of the workflow with their descriptions
+func runList(workflow string, out io.Writer, load func(string) (*dsl.Workflow, error)) error {
+	if err := CheckFilePathExistAndIsNotEmpty(workflow); err != nil {
+		return err
+	}
+	wf, err := load(workflow)
+	if err != nil {
+		return fmt.Errorf("%w: %v", workflowLoadErr, err)
+	}
+
+	fmt.Fprintln(out, wf.Name)
+	if wf.Description != "" {
+		fmt.Fprintf(out, "%s\n", wf.Description)
+	}
+	fmt.Fprintln(out)
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	listHooks := func(indent string, hooks map[string][]dsl.Step) {
+		for _, kind := range dsl.HookKinds {
+			for _, step := range hooks[kind] {
+				fmt.Fprintf(tw, "%s%s: %s\t%s\n", indent, kind, step.Name, step.Description)
+			}
+		}
+	}
+	for _, i := range wf.StageOrder() {
+		stage := wf.Stages[i]
+		name := stage.Name
+		if stage.Finally {
+			name += " (finally)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", name, stage.Description)
+		for _, step := range stage.Steps {
+			fmt.Fprintf(tw, "  %s\t%s\n", step.Name, step.Description)
+			for _, sub := range step.Steps {
+				fmt.Fprintf(tw, "    %s\t%s\n", sub.Name, sub.Description)
+			}
+		}
+		listHooks("  ", stage.Hooks())
+	}
+	listHooks("", wf.Hooks())
+	return tw.Flush()
+}
+
+func makeListCmd(load func(string) (*dsl.Workflow, error)) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list [workflow]",
+		Short: "List the stages and steps of a workflow with their descriptions",
+		Long: `List the stages of a workflow in the order they run, each with its steps and
+hooks, next to their description: so it is clear what a step does without reading
+its command.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeWorkflowFiles,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runList(args[0], cmd.OutOrStdout(), load)
+		},
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(makeListCmd(dsl.LoadWorkflowFromFile))
+}
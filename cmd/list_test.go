@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "workflow.yaml")
+	content := `name: release
+description: Ship a release
+stages:
+  - name: cleanup
+    finally: true
+    steps:
+      - name: prune
+        type: exec
+        run: ["docker", "system", "prune"]
+  - name: build
+    description: Compile and package the service
+    steps:
+      - name: compile
+        description: Builds the static binary
+        type: exec
+        run: ["go", "build"]
+    on_failure:
+      - name: notify
+        description: Tell the team
+        type: exec
+        run: ["notify"]
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	out := new(bytes.Buffer)
+	if err := runList(path, out, dsl.LoadWorkflowFromFile); err != nil {
+		t.Fatalf("runList() error: %v", err)
+	}
+	want := `release
+Ship a release
+
+build                 Compile and package the service
+  compile             Builds the static binary
+  on_failure: notify  Tell the team
+cleanup (finally)
+  prune
+`
+	lines := strings.Split(out.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	if got := strings.Join(lines, "\n"); got != want {
+		t.Errorf("runList() output =\n%s\nwant\n%s", got, want)
+	}
+
+	if err := runList(filepath.Join(t.TempDir(), "missing.yaml"), out, dsl.LoadWorkflowFromFile); !errors.Is(err, workflowNotFoundErr) {
+		t.Errorf("runList() error = %v, want %v", err, workflowNotFoundErr)
+	}
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// historyStore returns the store next to the workflow file, or in the current directory
+func historyStore(workflow string) *history.Store {
+	if workflow == "" {
+		return history.NewStore(history.DirName)
+	}
+	return history.ForWorkflow(workflow)
+}
+
+// runLogs prints the output recorded for a run in the local history
+func runLogs(store *history.Store, id string, out io.Writer) error {
+	rec, err := store.Get(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", historyReadErr, err)
+	}
+	recorded := false
+	for _, step := range rec.Steps {
+		name := step.Step
+		if step.Group != "" {
+			name = step.Group + " / " + step.Step
+		}
+		fmt.Fprintf(out, "=== %s / %s (%s) ===\n", step.Stage, name, step.Status)
+		for _, line := range step.OutputHead {
+			fmt.Fprintln(out, line)
+		}
+		if step.OutputOmitted > 0 {
+			fmt.Fprintf(out, "... %d bytes of output omitted ...\n", step.OutputOmitted)
+		}
+		for _, line := range step.OutputTail {
+			fmt.Fprintln(out, line)
+		}
+		recorded = recorded || len(step.OutputTail) > 0
+	}
+	if !recorded {
+		fmt.Fprintf(out, "No output was recorded for run %s; runs with --report keep the last lines of each step\n", id)
+	}
+	return nil
+}
+
+// runRemoteLogs prints the output of a run of a forge server, following it until the run ends when follow is set
+func runRemoteLogs(ctx context.Context, client *server.Client, id string, follow bool, out io.Writer) error {
+	if !follow {
+		if err := client.Logs(ctx, id, out); err != nil {
+			return fmt.Errorf("%w: %v", remoteLogsErr, err)
+		}
+		return nil
+	}
+	err := client.Follow(ctx, id, func(ev server.Event) error {
+		switch ev.Type {
+		case "output":
+			var text string
+			if err := json.Unmarshal(ev.Data, &text); err != nil {
+				return err
+			}
+			_, err := io.WriteString(out, text)
+			return err
+		case "end":
+			var run server.Run
+			if err := json.Unmarshal(ev.Data, &run); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "\nRun %s %s\n", run.ID, run.Status)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", remoteLogsErr, err)
+	}
+	return nil
+}
+
+func makeLogsCmd() *cobra.Command {
+	var workflow, remote string
+	var follow bool
+
+	cmd := &cobra.Command{
+		Use:   "logs <run-id>",
+		Short: "Show the output of a run",
+		Long: `Show the output of a run. Local runs keep the last lines of each step's output in
+the run history when they write a --report.
+
+With --remote the output of a run started through 'forge serve' is fetched from that
+server; --follow streams it live until the run ends.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if remote != "" {
+				return runRemoteLogs(cmd.Context(), server.NewClient(remote), args[0], follow, cmd.OutOrStdout())
+			}
+			if follow {
+				return fmt.Errorf("%w: --follow needs --remote", remoteLogsErr)
+			}
+			return runLogs(historyStore(workflow), args[0], cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().StringVarP(&workflow, "workflow", "w", "", "workflow file whose run history to read (default: the current directory)")
+	cmd.Flags().StringVar(&remote, "remote", "", "address of a forge server, e.g. http://localhost:8080")
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "with --remote, stream the output until the run ends")
+	_ = cmd.MarkFlagFilename("workflow", dsl.FileExtensions...)
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(makeLogsCmd())
+}
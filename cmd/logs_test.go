@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/andre-koe/forge/internal/server"
+)
+
+func TestRunLogs(t *testing.T) {
+	store := history.NewStore(t.TempDir())
+	rec := &history.Record{ID: "20260101-100000-abcdef", Workflow: "ci", Steps: []history.StepRecord{
+		{Stage: "build", Step: "compile", Status: history.StatusSuccess, OutputTail: []string{"ok", "done"}},
+		{Stage: "build", Step: "test", Status: history.StatusFailed, OutputHead: []string{"=== RUN TestA"}, OutputOmitted: 2048, OutputTail: []string{"FAIL"}},
+		{Stage: "build", Step: "vet", Status: history.StatusSkipped},
+	}}
+	if err := store.Save(rec); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runLogs(store, rec.ID, &out); err != nil {
+		t.Fatalf("runLogs() error: %v", err)
+	}
+	want := "=== build / compile (success) ===\nok\ndone\n=== build / test (failed) ===\n=== RUN TestA\n... 2048 bytes of output omitted ...\nFAIL\n=== build / vet (skipped) ===\n"
+	if out.String() != want {
+		t.Errorf("runLogs() output = %q, want %q", out.String(), want)
+	}
+
+	if err := runLogs(store, "missing", &out); !errors.Is(err, historyReadErr) {
+		t.Errorf("runLogs() error = %v, want %v", err, historyReadErr)
+	}
+}
+
+func TestRunRemoteLogs(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "ci.yaml"), []byte(sourceTestWorkflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	newRunner := func(workflow string, opts ...runner.Option) (runner.Interface, error) {
+		return runner.NewRunner(workflow, append(opts, runner.WithHistory(nil), runner.WithRunCmd(func([]string) error { return nil }))...)
+	}
+	s := server.New(context.Background(), root, newRunner)
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	run, err := s.Start(server.RunRequest{Workflow: "ci.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runRemoteLogs(context.Background(), server.NewClient(ts.URL), run.ID, true, &out); err != nil {
+		t.Fatalf("runRemoteLogs() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "STEP 1.1: hello (exec)") || !strings.HasSuffix(out.String(), "Run "+run.ID+" succeeded\n") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	if err := runRemoteLogs(context.Background(), server.NewClient(ts.URL), "nope", false, &out); !errors.Is(err, remoteLogsErr) {
+		t.Errorf("runRemoteLogs() error = %v, want %v", err, remoteLogsErr)
+	}
+}
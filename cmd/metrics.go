@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/metrics"
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+// metricsJob is the Pushgateway job name forge pushes under
+const metricsJob = "forge"
+
+// metricsHTTPClient is used to push metrics to the Pushgateway
+var metricsHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// metricsPushOptions returns the runner option that pushes the metrics of every run to the Pushgateway
+// at pushURL, grouped by workflow name. A failed push only prints a warning.
+func metricsPushOptions(pushURL string, out io.Writer) ([]runner.Option, error) {
+	if pushURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(pushURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q: expected an http(s) URL", invalidMetricsURLErr, pushURL)
+	}
+
+	push := func(rec *history.Record) {
+		c := metrics.New()
+		c.Observe(rec)
+		if err := c.Push(metricsHTTPClient, pushURL, metricsJob, map[string]string{"workflow": rec.Name}); err != nil {
+			fmt.Fprintf(out, "Warning: failed to push metrics: %v\n", err)
+		}
+	}
+	return []runner.Option{runner.WithOnFinish(push)}, nil
+}
+
+// observeRuns returns newRunner with the runs of its runners observed by c, and counted as active
+// while they run
+func observeRuns(c *metrics.Collector, newRunner runner.Factory) runner.Factory {
+	return func(path string, opts ...runner.Option) (runner.Interface, error) {
+		r, err := newRunner(path, append(opts, runner.WithOnFinish(c.Observe))...)
+		if err != nil {
+			return nil, err
+		}
+		return observedRunner{Interface: r, c: c}, nil
+	}
+}
+
+// observedRunner counts the runs of a runner as active
+type observedRunner struct {
+	runner.Interface
+	c *metrics.Collector
+}
+
+func (r observedRunner) Run() error {
+	r.c.RunStarted()
+	defer r.c.RunEnded()
+	return r.Interface.Run()
+}
+
+// serveMetrics serves the metrics of the runs of newRunner on /metrics at ln until ctx is done, and
+// returns the factory observing them
+func serveMetrics(ctx context.Context, ln net.Listener, newRunner runner.Factory, out io.Writer) runner.Factory {
+	c := metrics.New()
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", c)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	fmt.Fprintf(out, "Serving metrics on http://%s/metrics\n", ln.Addr())
+	go func() { _ = srv.Serve(ln) }()
+	context.AfterFunc(ctx, func() { srv.Close() })
+	return observeRuns(c, newRunner)
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+func TestRunCmd_MetricsPush(t *testing.T) {
+	workflow := filepath.Join(t.TempDir(), "workflow.yaml")
+	if err := os.WriteFile(workflow, []byte(sourceTestWorkflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var gotPath, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+	}))
+	defer srv.Close()
+
+	cmd := makeRunCmd(runner.New)
+	cmd.SetOut(new(strings.Builder))
+	cmd.SetErr(new(strings.Builder))
+	cmd.SetArgs([]string{workflow, "--no-history", "--metrics-push-url", srv.URL})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if gotPath != "/metrics/job/forge/workflow/remote" {
+		t.Errorf("pushed to %q", gotPath)
+	}
+	if !strings.Contains(gotBody, `forge_runs_total{workflow="remote",status="success"} 1`) {
+		t.Errorf("pushed body:\n%s", gotBody)
+	}
+}
+
+func TestMetricsPushOptions_InvalidURL(t *testing.T) {
+	for _, u := range []string{"pushgateway:9091", "ftp://host", "http://"} {
+		if _, err := metricsPushOptions(u, io.Discard); !errors.Is(err, invalidMetricsURLErr) {
+			t.Errorf("metricsPushOptions(%q) error = %v, want invalidMetricsURLErr", u, err)
+		}
+	}
+}
+
+func TestServeMetrics(t *testing.T) {
+	workflow := filepath.Join(t.TempDir(), "workflow.yaml")
+	if err := os.WriteFile(workflow, []byte(sourceTestWorkflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	url := "http://" + ln.Addr().String() + "/metrics"
+	scrape := func() string {
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	var during string
+	newRunner := serveMetrics(ctx, ln, func(path string, opts ...runner.Option) (runner.Interface, error) {
+		r, err := runner.NewRunner(path, append(opts, runner.WithHistory(nil))...)
+		if err != nil {
+			return nil, err
+		}
+		r.RunCmd = func([]string) error {
+			during = scrape()
+			return nil
+		}
+		return r, nil
+	}, io.Discard)
+	r, err := newRunner(workflow, runner.WithOut(io.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if !strings.Contains(during, "forge_active_runs 1\n") {
+		t.Errorf("metrics during the run:\n%s", during)
+	}
+	after := scrape()
+	for _, want := range []string{"forge_active_runs 0\n", `forge_runs_total{workflow="remote",status="success"} 1`} {
+		if !strings.Contains(after, want) {
+			t.Errorf("metrics after the run missing %q:\n%s", want, after)
+		}
+	}
+}
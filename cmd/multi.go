@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+var (
+	workflowPatternErr = errors.New("no workflow matches pattern")
+	multiWorkflowErr   = errors.New("option requires a single workflow")
+)
+
+// expandWorkflowArgs resolves glob patterns in the workflow arguments, keeping their order and
+// dropping duplicates. Stdin and URLs are passed through unchanged.
+func expandWorkflowArgs(args []string) ([]string, error) {
+	var workflows []string
+	for _, arg := range args {
+		if isVirtualWorkflow(arg) || !strings.ContainsAny(arg, "*?[") {
+			if !slices.Contains(workflows, arg) {
+				workflows = append(workflows, arg)
+			}
+			continue
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", workflowPatternErr, arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%w: %s", workflowPatternErr, arg)
+		}
+		for _, m := range matches {
+			if !slices.Contains(workflows, m) {
+				workflows = append(workflows, m)
+			}
+		}
+	}
+	return workflows, nil
+}
+
+type workflowResult struct {
+	Workflow string
+	Duration time.Duration
+	Err      error
+}
+
+// runWorkflows runs several workflows, one after another or concurrently, and prints one summary.
+// In parallel mode at most jobs workflows (all if jobs is 0) run at once and each workflow's output
+// is buffered and printed when all have finished.
+func runWorkflows(workflows []string, out io.Writer, newRunner runner.Factory, parallel bool, jobs int, opts ...runner.Option) error {
+	results := make([]workflowResult, len(workflows))
+	logs := make([]bytes.Buffer, len(workflows))
+
+	run := func(i int, w io.Writer, extra ...runner.Option) {
+		start := time.Now()
+		err := runOne(workflows[i], w, newRunner, slices.Concat(opts, extra)...)
+		results[i] = workflowResult{Workflow: workflows[i], Duration: time.Since(start), Err: err}
+	}
+
+	if parallel {
+		if jobs <= 0 {
+			jobs = len(workflows)
+		}
+		sem := make(chan struct{}, jobs)
+		var wg sync.WaitGroup
+		for i := range workflows {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				run(i, &logs[i], runner.WithProcessOutput(&logs[i], &logs[i]), runner.WithTerminalStatus(nil))
+			}()
+		}
+		wg.Wait()
+		for i := range workflows {
+			fmt.Fprintf(out, "\n##### %s #####\n%s", workflows[i], logs[i].String())
+		}
+	} else {
+		for i := range workflows {
+			fmt.Fprintf(out, "\n##### %s #####\n", workflows[i])
+			run(i, out)
+		}
+	}
+
+	return printWorkflowSummary(out, results)
+}
+
+// runOne runs a single workflow like runRun but returns the underlying error for the summary
+func runOne(workflow string, out io.Writer, newRunner runner.Factory, opts ...runner.Option) error {
+	base, err := runBaseOptions(workflow)
+	if err != nil {
+		return err
+	}
+
+	r, err := newRunner(workflow, slices.Concat(base, []runner.Option{runner.WithOut(out)}, opts)...)
+	if err != nil {
+		return runnerCreationErr
+	}
+	return r.Run()
+}
+
+func printWorkflowSummary(out io.Writer, results []workflowResult) error {
+	failed := 0
+	fmt.Fprintf(out, "\n=== SUMMARY ===\n")
+	for _, res := range results {
+		d := res.Duration.Round(time.Millisecond)
+		if res.Err != nil {
+			failed++
+			fmt.Fprintf(out, "✗ %s (%s): %v\n", res.Workflow, d, res.Err)
+			continue
+		}
+		fmt.Fprintf(out, "✓ %s (%s)\n", res.Workflow, d)
+	}
+	fmt.Fprintf(out, "%d workflow(s): %d succeeded, %d failed\n", len(results), len(results)-failed, failed)
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d workflow(s) failed", workflowExecutionErr, failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+func TestExpandWorkflowArgs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"api.yml", "web.yml", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o644); err != nil {
+			t.Fatalf("WriteFile() error: %v", err)
+		}
+	}
+	api, web := filepath.Join(dir, "api.yml"), filepath.Join(dir, "web.yml")
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr error
+	}{
+		{name: "plain path", args: []string{api}, want: []string{api}},
+		{name: "glob", args: []string{filepath.Join(dir, "*.yml")}, want: []string{api, web}},
+		{name: "duplicates dropped", args: []string{web, filepath.Join(dir, "*.yml")}, want: []string{web, api}},
+		{name: "stdin passed through", args: []string{"-"}, want: []string{"-"}},
+		{name: "no match", args: []string{filepath.Join(dir, "*.yaml")}, wantErr: workflowPatternErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandWorkflowArgs(tt.args)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("expandWorkflowArgs() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandWorkflowArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunWorkflows(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, command string) string {
+		path := filepath.Join(dir, name)
+		content := "name: " + name + "\nstages:\n  - name: build\n    steps:\n      - name: run\n        type: exec\n        run: [\"" + command + "\"]\n"
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile() error: %v", err)
+		}
+		return path
+	}
+	ok1, ok2, bad := write("a.yml", "ok"), write("b.yml", "ok"), write("c.yml", "fail")
+
+	tests := []struct {
+		name       string
+		workflows  []string
+		parallel   bool
+		jobs       int
+		wantErr    error
+		wantOutput []string
+	}{
+		{
+			name:       "sequential success",
+			workflows:  []string{ok1, ok2},
+			wantOutput: []string{"✓ " + ok1, "✓ " + ok2, "2 workflow(s): 2 succeeded, 0 failed"},
+		},
+		{
+			name:       "parallel with failure",
+			workflows:  []string{ok1, bad, ok2},
+			parallel:   true,
+			jobs:       2,
+			wantErr:    workflowExecutionErr,
+			wantOutput: []string{"##### " + bad + " #####", "✗ " + bad, "3 workflow(s): 2 succeeded, 1 failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var calls []string
+			runCmd := func(argv []string) error {
+				mu.Lock()
+				defer mu.Unlock()
+				calls = append(calls, argv[0])
+				if argv[0] == "fail" {
+					return errors.New("boom")
+				}
+				return nil
+			}
+
+			out := new(bytes.Buffer)
+			err := runWorkflows(tt.workflows, out, runner.New, tt.parallel, tt.jobs,
+				runner.WithRunCmd(runCmd), runner.WithHistory(nil))
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("runWorkflows() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(calls) != len(tt.workflows) {
+				t.Errorf("ran %d workflow(s), want %d", len(calls), len(tt.workflows))
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/spf13/cobra"
+)
+
+var errPlanWriteFailed = errors.New("failed to write plan file")
+
+func runPlan(workflow, output string, out io.Writer, load func(string) (*dsl.Workflow, error), opts ...runner.Option) error {
+	if err := CheckFilePathExistAndIsNotEmpty(workflow); err != nil {
+		return err
+	}
+
+	p, err := runner.NewPlan(workflow, load, opts...)
+	if err != nil {
+		return fmt.Errorf("%w: %v", workflowLoadErr, err)
+	}
+
+	if output == "" {
+		return p.Write(out)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return errPlanWriteFailed
+	}
+	defer f.Close()
+
+	if err := p.Write(f); err != nil {
+		return errPlanWriteFailed
+	}
+
+	fmt.Fprintf(out, "Plan written to %s (%d stages)\n", output, len(p.Resolved.Stages))
+	fmt.Fprintf(out, "Run 'forge apply %s' to execute it.\n", output)
+	return nil
+}
+
+func makePlanCmd(load func(string) (*dsl.Workflow, error)) *cobra.Command {
+	var output, profile string
+	var envFiles, vars, varFiles []string
+
+	cmd := &cobra.Command{
+		Use:   "plan [workflow]",
+		Short: "Write the fully-resolved execution plan of a workflow",
+		Long: `Resolve a workflow and serialize the exact execution plan as JSON.
+The plan can be reviewed and later executed with 'forge apply', which refuses to
+run if the workflow file, a file it includes or one of its env files changed in the
+meantime.
+
+--var, --var-file, --profile and --env-file resolve the plan as forge run does with the
+same flags; 'forge apply' runs it with them.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeWorkflowFiles,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			values, err := parseVars(vars, varFiles)
+			if err != nil {
+				return err
+			}
+			return runPlan(args[0], output, cmd.OutOrStdout(), load, runner.WithVars(values), runner.WithProfile(profile), runner.WithEnvFiles(envFiles...))
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write the plan to this file instead of stdout")
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "override a workflow variable, e.g. tag=v1.2.3 (repeatable)")
+	cmd.Flags().StringSliceVar(&varFiles, "var-file", nil, "override workflow variables from a YAML file (repeatable, --var wins)")
+	cmd.Flags().StringVar(&profile, "profile", "", "apply the overrides of the named workflow profile, e.g. prod")
+	cmd.Flags().StringSliceVar(&envFiles, "env-file", nil, "load KEY=VALUE pairs from a dotenv file into the steps' environment (repeatable)")
+	_ = cmd.MarkFlagFilename("env-file")
+	_ = cmd.MarkFlagFilename("var-file", "yaml", "yml")
+	return cmd
+}
+
+var planCmd = makePlanCmd(dsl.LoadWorkflowFromFile)
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+const planTestWorkflow = `name: test-workflow
+stages:
+  - name: test-stage
+    steps:
+      - name: hello
+        type: exec
+        run: ["echo", "hello"]
+`
+
+func TestRunPlan(t *testing.T) {
+	tmpDir := t.TempDir()
+	workflowPath := filepath.Join(tmpDir, "workflow.yml")
+	if err := os.WriteFile(workflowPath, []byte(planTestWorkflow), 0644); err != nil {
+		t.Fatalf("failed to create test workflow: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		workflow string
+		output   string
+		load     func(string) (*dsl.Workflow, error)
+		wantErr  error
+		wantOut  string
+	}{
+		{
+			name:     "plan to stdout",
+			workflow: workflowPath,
+			load:     dsl.LoadWorkflowFromFile,
+			wantOut:  `"sha256"`,
+		},
+		{
+			name:     "plan to file",
+			workflow: workflowPath,
+			output:   filepath.Join(tmpDir, "plan.json"),
+			load:     dsl.LoadWorkflowFromFile,
+			wantOut:  "Plan written to",
+		},
+		{
+			name:     "workflow not found",
+			workflow: "/non/existent/workflow.yml",
+			wantErr:  workflowNotFoundErr,
+		},
+		{
+			name:     "load fails",
+			workflow: workflowPath,
+			load: func(string) (*dsl.Workflow, error) {
+				return nil, errors.New("mock load error")
+			},
+			wantErr: workflowLoadErr,
+		},
+		{
+			name:     "unwritable output",
+			workflow: workflowPath,
+			output:   filepath.Join(tmpDir, "missing", "plan.json"),
+			load:     dsl.LoadWorkflowFromFile,
+			wantErr:  errPlanWriteFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			err := runPlan(tt.workflow, tt.output, out, tt.load)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("runPlan() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runPlan() unexpected error = %v", err)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output missing %q, got:\n%s", tt.wantOut, out.String())
+			}
+		})
+	}
+}
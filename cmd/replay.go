@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/spf13/cobra"
+)
+
+// parseTolerate returns the kinds of drift named in names
+func parseTolerate(names []string) ([]history.DriftKind, error) {
+	var kinds []history.DriftKind
+	for _, name := range names {
+		kind := history.DriftKind(strings.TrimSpace(name))
+		if !slices.Contains(history.DriftKinds, kind) {
+			return nil, fmt.Errorf("%w: unknown drift %q, use one of %v", invalidTolerateErr, name, history.DriftKinds)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+// runReplay executes the commands of a recorded run again
+func runReplay(ctx context.Context, store *history.Store, id string, tolerate []history.DriftKind, out io.Writer) error {
+	rec, err := store.Get(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", historyReadErr, err)
+	}
+	if err := runner.Replay(ctx, rec, tolerate, out); err != nil {
+		return fmt.Errorf("%w: %v", replayErr, err)
+	}
+	return nil
+}
+
+func makeReplayCmd() *cobra.Command {
+	var workflow string
+	var tolerate []string
+
+	cmd := &cobra.Command{
+		Use:   "replay <run-id>",
+		Short: "Execute the commands of a recorded run again",
+		Long: `Execute exactly the commands a recorded run executed again, with the arguments,
+directories and environment they had, e.g. to reproduce a failure. Only runs started
+with 'forge run --replayable' record them; their records hold the values of variables
+and environment the commands were given.
+
+The replay is rejected when the host differs from the one the run was recorded on:
+its os, arch, host name, forge version or the files the run's programs resolve to.
+--tolerate host,forge (or replay_tolerate in the config file / FORGE_REPLAY_TOLERATE)
+accepts differences of the named kinds.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("tolerate") {
+				cfg, err := loadConfig()
+				if err != nil {
+					return fmt.Errorf("%w: %v", configErr, err)
+				}
+				if cfg.ReplayTolerate != "" {
+					tolerate = strings.Split(cfg.ReplayTolerate, ",")
+				}
+			}
+			kinds, err := parseTolerate(tolerate)
+			if err != nil {
+				return err
+			}
+			return runReplay(cmd.Context(), historyStore(workflow), args[0], kinds, cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().StringVarP(&workflow, "workflow", "w", "", "workflow file whose run history to read (default: the current directory)")
+	cmd.Flags().StringSliceVar(&tolerate, "tolerate", nil, "kinds of environment drift to accept: os, arch, host, forge, tools")
+	_ = cmd.MarkFlagFilename("workflow", dsl.FileExtensions...)
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(makeReplayCmd())
+}
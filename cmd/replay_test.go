@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func TestParseTolerate(t *testing.T) {
+	kinds, err := parseTolerate([]string{"host", " forge"})
+	if err != nil || !reflect.DeepEqual(kinds, []history.DriftKind{history.DriftHost, history.DriftForge}) {
+		t.Errorf("parseTolerate() = %v, %v", kinds, err)
+	}
+	if _, err := parseTolerate([]string{"weather"}); !errors.Is(err, invalidTolerateErr) {
+		t.Errorf("parseTolerate(weather) error = %v, want %v", err, invalidTolerateErr)
+	}
+}
+
+func TestRunReplay(t *testing.T) {
+	store := history.NewStore(t.TempDir())
+	if err := store.Save(&history.Record{ID: "20260101-100000-abcdef", Steps: []history.StepRecord{{Stage: "build", Step: "make", Commands: [][]string{{"make"}}}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runReplay(context.Background(), store, "missing", nil, &out); !errors.Is(err, historyReadErr) {
+		t.Errorf("runReplay(missing) error = %v, want %v", err, historyReadErr)
+	}
+	if err := runReplay(context.Background(), store, "20260101-100000-abcdef", nil, &out); !errors.Is(err, replayErr) {
+		t.Errorf("runReplay() of a run without replay data error = %v, want %v", err, replayErr)
+	}
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/report"
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+// reportTailLines is the number of output lines kept per step for --report
+const reportTailLines = 20
+
+// reportOptions returns the runner options that write the --report files after every run
+func reportOptions(specs []string, out io.Writer) ([]runner.Option, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	parsed := make([]report.Spec, 0, len(specs))
+	for _, s := range specs {
+		spec, err := report.ParseSpec(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", invalidReportErr, err)
+		}
+		parsed = append(parsed, spec)
+	}
+
+	writeReports := func(rec *history.Record) {
+		rep := report.New(rec)
+		for _, spec := range parsed {
+			if err := rep.WriteFile(spec); err != nil {
+				fmt.Fprintf(out, "Warning: failed to write %s report: %v\n", spec.Format, err)
+				continue
+			}
+			fmt.Fprintf(out, "Report written to %s\n", spec.Path)
+		}
+	}
+	return []runner.Option{runner.WithOutputTail(reportTailLines), runner.WithOnFinish(writeReports)}, nil
+}
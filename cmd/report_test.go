@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+func TestRunCmd_Report(t *testing.T) {
+	dir := t.TempDir()
+	workflow := filepath.Join(dir, "workflow.yaml")
+	if err := os.WriteFile(workflow, []byte(sourceTestWorkflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reportPath := filepath.Join(dir, "run.json")
+
+	cmd := makeRunCmd(runner.New)
+	out := new(strings.Builder)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs([]string{workflow, "--no-history", "--report", "json=" + reportPath})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Report written to "+reportPath) {
+		t.Errorf("output does not mention the report:\n%s", out.String())
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("report not written: %v", err)
+	}
+	var rep struct {
+		Name  string `json:"name"`
+		Steps []struct {
+			Status     string   `json:"status"`
+			OutputTail []string `json:"output_tail"`
+		} `json:"steps"`
+	}
+	if err := json.Unmarshal(data, &rep); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	if rep.Name != "remote" || len(rep.Steps) != 1 || rep.Steps[0].Status != "success" || strings.Join(rep.Steps[0].OutputTail, "\n") != "hi" {
+		t.Errorf("report = %+v", rep)
+	}
+}
+
+func TestRunCmd_InvalidReport(t *testing.T) {
+	workflow := filepath.Join(t.TempDir(), "workflow.yaml")
+	if err := os.WriteFile(workflow, []byte(sourceTestWorkflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := makeRunCmd(runner.New)
+	cmd.SetOut(new(strings.Builder))
+	cmd.SetErr(new(strings.Builder))
+	cmd.SetArgs([]string{workflow, "--report", "xml=run.xml"})
+	if err := cmd.Execute(); !errors.Is(err, invalidReportErr) {
+		t.Errorf("Execute() error = %v, want invalidReportErr", err)
+	}
+}
@@ -6,6 +6,9 @@ package cmd
 import (
 	"os"
 
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/include"
+	"github.com/andre-koe/forge/internal/preprocess"
 	"github.com/spf13/cobra"
 )
 
@@ -28,6 +31,11 @@ func Execute() {
 }
 
 func init() {
+	// Remote includes are fetched into ~/.cache/forge and pinned in <workflow>.lock
+	dsl.SetIncludeFetcher(include.NewFetcher().Fetch)
+	// .cue and .jsonnet workflows are evaluated with the cue and jsonnet binaries
+	dsl.SetPreprocessor(preprocess.NewEvaluator().Evaluate)
+
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
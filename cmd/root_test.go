@@ -21,7 +21,7 @@ func TestRootCmd_Structure(t *testing.T) {
 }
 
 func TestRootCmd_SubcommandsRegistered(t *testing.T) {
-	expectedSubcommands := []string{"run", "dry-run", "init", "version"}
+	expectedSubcommands := []string{"run", "dry-run", "init", "version", "explain", "plan", "apply", "convert", "export", "completion"}
 
 	for _, name := range expectedSubcommands {
 		found := false
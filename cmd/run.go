@@ -1,42 +1,469 @@
 package cmd
 
 import (
+	"fmt"
 	"io"
+	"os"
+	"os/signal"
+	"path"
+	"slices"
+	"syscall"
+	"time"
 
+	"github.com/andre-koe/forge/internal/active"
+	"github.com/andre-koe/forge/internal/ansi"
+	"github.com/andre-koe/forge/internal/artifact"
+	"github.com/andre-koe/forge/internal/cache"
+	"github.com/andre-koe/forge/internal/config"
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/logfile"
 	"github.com/andre-koe/forge/internal/runner"
+	"github.com/andre-koe/forge/internal/tui"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
-func runRun(workflow string, out io.Writer, newRunner func(string, ...runner.Option) (*runner.Runner, error)) error {
+// runBaseOptions checks the workflow argument and returns the options every run of it uses
+func runBaseOptions(workflow string) ([]runner.Option, error) {
+	if isVirtualWorkflow(workflow) {
+		return []runner.Option{
+			runner.WithHistory(history.NewStore(history.DirName)),
+			runner.WithArtifacts(artifact.NewStore(artifact.DirName)),
+			runner.WithCache(cache.NewStore(cache.DirName)),
+			runner.WithActive(active.NewRegistry(active.DirName)),
+		}, nil
+	}
 	if err := CheckFilePathExistAndIsNotEmpty(workflow); err != nil {
+		return nil, err
+	}
+	return []runner.Option{
+		runner.WithHistory(history.ForWorkflow(workflow)),
+		runner.WithArtifacts(artifact.ForWorkflow(workflow)),
+		runner.WithCache(cache.ForWorkflow(workflow)),
+		runner.WithActive(active.ForWorkflow(workflow)),
+	}, nil
+}
+
+func runRun(workflow string, out io.Writer, newRunner runner.Factory, opts ...runner.Option) error {
+	base, err := runBaseOptions(workflow)
+	if err != nil {
 		return err
 	}
 
-	r, err := newRunner(workflow, runner.WithOut(out))
+	base = append(base, runner.WithOut(out))
+	r, err := newRunner(workflow, append(base, opts...)...)
 	if err != nil {
 		return runnerCreationErr
 	}
 
 	if err := r.Run(); err != nil {
-		return workflowExecutionErr
+		return fmt.Errorf("%w: %v", workflowExecutionErr, err)
 	}
 
 	return nil
 }
 
-func makeRunCmd(newRunner func(string, ...runner.Option) (*runner.Runner, error)) *cobra.Command {
-	return &cobra.Command{
+func makeRunCmd(newRunner runner.Factory) *cobra.Command {
+	var timezone, chaos, sum, metricsURL, changesBase, workdir, logFile, profile, templateValues string
+	var noHistory, noCache, force, replayable, annotations, untilFailure, parallel, title, interactive, dryRun, step, dashboard, progress, stripANSI bool
+	var stages, skipSteps, envFiles, envs, vars, varFiles, reports, policies, approvalTokens []string
+	var limits soakLimits
+	var jobs, logKeep, outputLimit int
+	var logMaxAge time.Duration
+
+	cmd := &cobra.Command{
 		Use:   "run [workflow]",
 		Short: "Execute a defined workflow",
-		Long:  `Execute a workflow defined in your forge configuration file.`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Execute a workflow defined in your forge configuration file.
+The workflow can also be read from stdin with "-" or fetched from an https:// URL;
+use --sha256 to pin its content. With --template-values values.yaml the workflow is
+rendered through Go's text/template with the values as dot before it is parsed.
+
+With --until-failure the workflow (or the stages selected with --stage) is run
+repeatedly until an iteration fails or --max-iterations/--max-duration is reached.
+
+Workflow variables declared in vars: can be overridden with --var key=value and
+--var-file vars.yaml and are referenced as ${{ vars.NAME }}.
+--profile prod applies the workflow's prod profile: its vars below --var and its env
+and secrets below --env.
+Variables from --env-file (and the workflow's env_file) are exported to every step
+and can be referenced in commands as ${{ env.NAME }}; --env KEY=VALUE sets a variable
+below them. --workdir starts the steps' commands in another directory.
+--interactive runs every exec step in a pseudo-terminal, like steps with tty: true,
+for commands such as ssh, sudo or installers that prompt on a terminal.
+
+--step pauses before every step, shows its command, directory and environment and
+asks whether to continue, skip the step or abort the run.
+
+Escape sequences such as colors are removed from the output when it is not a terminal, with
+--strip-ansi, or with no_color in the config file, NO_COLOR or FORGE_NO_COLOR, which also turn
+off the spinner and terminal title; log files, reports and the run history never contain them.
+
+--log-file logs/forge.log also writes the whole output of the run to a file named after
+the start time, e.g. logs/forge-20250102-150405.log; --log-keep and --log-max-age remove
+older log files.
+
+On a terminal, outside CI, the running step shows a spinner with its elapsed time (a
+countdown for sleep steps); --progress=false turns it off.
+
+--tui shows the run as a live dashboard: stages and steps with their elapsed times, the
+output of the running step and a summary at the end.
+
+--dry-run prints what the run would do without running anything, like forge dry-run,
+so wrappers only need to add one flag.
+
+Steps with a cache: are skipped when their key_files and definition are unchanged
+since their last successful run; --no-cache runs them anyway. Steps with an
+idempotency_key are skipped once a step with the same key succeeded in a recorded run;
+--force runs them anyway.
+
+--replayable records every command as executed, after interpolation and with the
+environment forge gave it, so 'forge replay <run-id>' can repeat the run exactly. The
+record then holds the values of variables and environment variables the commands saw.
+
+Stages with changes: only run when a file matching one of their globs changed since
+the workflow's changes_base (HEAD by default, override with --changes-base); stages
+selected with --stage always run.
+
+--report json=run.json writes a machine-readable report of the run (timings, step
+status, the last lines of each step's output and host information); --report
+html=report.html renders the same as a standalone page. Of steps printing more than
+--output-limit bytes only the beginning and end of the output are kept, with a note of
+how much was left out.
+
+Several workflows, or glob patterns such as 'workflows/*.yml', run one after another
+(or concurrently with --parallel) and are reported in one summary.
+
+--policy policies/ evaluates Open Policy Agent Rego policies in package forge against
+the workflow before it runs, using the opa binary; every message of their deny rule
+fails the run.
+
+Approval steps ask on the terminal whether to continue; --approval-token passes a token
+from 'forge approval-token' instead, checked with the key in FORGE_APPROVAL_SECRET.
+Without a decision within their timeout they are denied.
+
+Defaults for --timezone, --jobs, --no-history, --terminal-title, --github-annotations,
+--policy, --env-file, --log-file, --log-keep and --log-max-age can be set in
+~/.config/forge/config.yaml or FORGE_* environment variables; flags win over the
+environment, which wins over the config file.`,
+		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runRun(args[0], cmd.OutOrStdout(), newRunner)
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("%w: %v", configErr, err)
+			}
+			flags := cmd.Flags()
+			if !flags.Changed("timezone") && cfg.Timezone != "" {
+				timezone = cfg.Timezone
+			}
+			if !flags.Changed("jobs") && cfg.Jobs > 0 {
+				jobs = cfg.Jobs
+			}
+			if !flags.Changed("no-history") && cfg.History != nil {
+				noHistory = !*cfg.History
+			}
+			if !flags.Changed("terminal-title") {
+				title = config.Bool(cfg.TerminalTitle, title) && !cfg.NoColor
+			}
+			if !flags.Changed("strip-ansi") && cfg.NoColor {
+				stripANSI = true
+			}
+			if !flags.Changed("progress") && cfg.NoColor {
+				progress = false
+			}
+			if !flags.Changed("github-annotations") {
+				annotations = config.Bool(cfg.GitHubAnnotations, annotations)
+			}
+			if !flags.Changed("policy") && cfg.Policy != "" {
+				policies = []string{cfg.Policy}
+			}
+			if !flags.Changed("env-file") && len(cfg.EnvFiles) > 0 {
+				envFiles = cfg.EnvFiles
+			}
+			if !flags.Changed("log-file") && cfg.LogFile != "" {
+				logFile = cfg.LogFile
+			}
+			if !flags.Changed("log-keep") && cfg.LogKeep != nil {
+				logKeep = *cfg.LogKeep
+			}
+			if !flags.Changed("log-max-age") && cfg.LogMaxAge != "" {
+				if logMaxAge, err = time.ParseDuration(cfg.LogMaxAge); err != nil {
+					return fmt.Errorf("%w: log_max_age: %v", configErr, err)
+				}
+			}
+
+			if dryRun && untilFailure {
+				return fmt.Errorf("%w: cannot be combined with --until-failure", invalidDryRunErr)
+			}
+			if dashboard && (step || interactive || dryRun) {
+				return fmt.Errorf("%w: cannot be combined with --step, --interactive or --dry-run", invalidTUIErr)
+			}
+			if dashboard && !isTerminal(os.Stdout) {
+				return fmt.Errorf("%w: standard output is not a terminal", invalidTUIErr)
+			}
+
+			workflows, err := expandWorkflowArgs(args)
+			if err != nil {
+				return err
+			}
+			if len(workflows) > 1 {
+				for _, w := range workflows {
+					if isVirtualWorkflow(w) {
+						return fmt.Errorf("%w: reading from stdin or a URL", multiWorkflowErr)
+					}
+				}
+				if sum != "" {
+					return fmt.Errorf("%w: --sha256", multiWorkflowErr)
+				}
+				if templateValues != "" {
+					return fmt.Errorf("%w: --template-values", multiWorkflowErr)
+				}
+				if untilFailure {
+					return fmt.Errorf("%w: --until-failure", multiWorkflowErr)
+				}
+				if len(reports) > 0 {
+					return fmt.Errorf("%w: --report", multiWorkflowErr)
+				}
+				if interactive && parallel {
+					return fmt.Errorf("%w: --interactive with --parallel", multiWorkflowErr)
+				}
+				if step && parallel {
+					return fmt.Errorf("%w: --step with --parallel", multiWorkflowErr)
+				}
+				if dashboard {
+					return fmt.Errorf("%w: --tui", multiWorkflowErr)
+				}
+			}
+
+			for _, w := range workflows {
+				bin, err := checkForgeVersion(w)
+				if err != nil {
+					return err
+				}
+				if bin != "" {
+					if len(workflows) > 1 {
+						return fmt.Errorf("%w: %s needs %s; run it on its own", forgeVersionErr, w, bin)
+					}
+					return reexecForge(bin, os.Args[1:], cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr())
+				}
+			}
+
+			out := cmd.OutOrStdout()
+			if stripANSI {
+				out = ansi.NewWriter(out)
+			}
+			var logOut io.Writer
+			if logFile != "" {
+				f, err := logfile.Create(logFile, time.Now(), logfile.Retention{Keep: logKeep, MaxAge: logMaxAge})
+				if err != nil {
+					return fmt.Errorf("%w: %v", logFileErr, err)
+				}
+				defer f.Close()
+				logOut = ansi.NewWriter(f)
+				out = io.MultiWriter(out, logOut)
+			}
+
+			// An interrupt stops the run before its next step; hooks such as always still run
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			opts := []runner.Option{runner.WithGitHubAnnotations(annotations), runner.WithContext(ctx)}
+			values, err := readTemplateValues(templateValues)
+			if err != nil {
+				return err
+			}
+			if isVirtualWorkflow(workflows[0]) || sum != "" || values != nil {
+				sourceOpts, err := workflowSourceOptions(workflows[0], sum, values, cmd.InOrStdin(), workflowHTTPClient)
+				if err != nil {
+					return err
+				}
+				opts = append(opts, sourceOpts...)
+			}
+			if title {
+				opts = append(opts, runner.WithTerminalStatus(os.Stdout))
+			}
+			if progress && !dashboard && !parallel {
+				opts = append(opts, runner.WithProgress(os.Stdout))
+			}
+			if len(stages) > 0 {
+				opts = append(opts, runner.WithStages(stages...))
+			}
+			if len(skipSteps) > 0 {
+				for _, p := range skipSteps {
+					if _, err := path.Match(p, ""); err != nil {
+						return fmt.Errorf("%w: %q: %v", invalidSkipStepErr, p, err)
+					}
+				}
+				opts = append(opts, runner.WithSkipSteps(skipSteps...))
+			}
+			if len(vars) > 0 || len(varFiles) > 0 {
+				values, err := parseVars(vars, varFiles)
+				if err != nil {
+					return err
+				}
+				opts = append(opts, runner.WithVars(values))
+			}
+			if profile != "" {
+				opts = append(opts, runner.WithProfile(profile))
+			}
+			reportOpts, err := reportOptions(reports, out)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, reportOpts...)
+			metricsOpts, err := metricsPushOptions(metricsURL, out)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, metricsOpts...)
+			if len(envFiles) > 0 {
+				opts = append(opts, runner.WithEnvFiles(envFiles...))
+			}
+			if len(envs) > 0 {
+				env, err := parseEnv(envs)
+				if err != nil {
+					return err
+				}
+				opts = append(opts, runner.WithEnv(env))
+			}
+			if workdir != "" {
+				if fi, err := os.Stat(workdir); err != nil || !fi.IsDir() {
+					return fmt.Errorf("%w: %s is not a directory", invalidWorkdirErr, workdir)
+				}
+				opts = append(opts, runner.WithWorkdir(workdir))
+			}
+			if interactive {
+				opts = append(opts, runner.WithInteractive(true))
+			}
+			if dryRun {
+				opts = append(opts, runner.WithMode(runner.ModeDryRun))
+			}
+			if step {
+				if slices.Contains(workflows, "-") {
+					return fmt.Errorf("%w: the answers are read from stdin, which holds the workflow", invalidStepErr)
+				}
+				opts = append(opts, runner.WithStepPrompt(runner.TerminalStepPrompt(cmd.InOrStdin(), out)))
+			}
+			terminal := isTerminal(os.Stdin) && !slices.Contains(workflows, "-") && !dashboard
+			approvers, err := approvers(approvalTokens, terminal, cmd.InOrStdin(), out)
+			if err != nil {
+				return err
+			}
+			if len(approvers) > 0 {
+				opts = append(opts, runner.WithApprovers(approvers...))
+			}
+			if len(policies) > 0 {
+				for _, p := range policies {
+					if _, err := os.Stat(p); err != nil {
+						return fmt.Errorf("%w: %v", invalidPolicyErr, err)
+					}
+				}
+				opts = append(opts, runner.WithPolicies(policies...))
+			}
+			if noHistory {
+				opts = append(opts, runner.WithHistory(nil))
+			}
+			if noCache {
+				opts = append(opts, runner.WithCache(nil))
+			}
+			if force {
+				opts = append(opts, runner.WithForce(true))
+			}
+			if replayable {
+				opts = append(opts, runner.WithReplayable(true))
+			}
+			if outputLimit > 0 {
+				opts = append(opts, runner.WithOutputLimit(outputLimit))
+			}
+			if changesBase != "" {
+				opts = append(opts, runner.WithChangesBase(changesBase))
+			}
+			if timezone != "" {
+				loc, err := time.LoadLocation(timezone)
+				if err != nil {
+					return fmt.Errorf("%w: %v", invalidTimezoneErr, err)
+				}
+				opts = append(opts, runner.WithTimezone(loc))
+			}
+			if chaos != "" {
+				rules, err := runner.ParseChaos(chaos)
+				if err != nil {
+					return fmt.Errorf("%w: %v", invalidChaosErr, err)
+				}
+				fmt.Fprintf(out, "Chaos mode enabled: %s\n", chaos)
+				opts = append(opts, runner.WithChaos(rules))
+			}
+			if len(workflows) > 1 {
+				return runWorkflows(workflows, out, newRunner, parallel, jobs, opts...)
+			}
+			if dashboard {
+				d := tui.New(cmd.OutOrStdout())
+				if width, height, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+					d.Width, d.OutputLines = width, max(3, height/3)
+				}
+				opts = append(opts, d.Options()...)
+				if logOut != nil {
+					both := io.MultiWriter(d, logOut)
+					opts = append(opts, runner.WithOut(both), runner.WithProcessOutput(both, both))
+				}
+				d.Start()
+				defer d.Stop()
+			}
+			if untilFailure {
+				return runUntilFailure(workflows[0], out, newRunner, limits, opts...)
+			}
+			return runRun(workflows[0], out, newRunner, opts...)
 		},
 	}
+	cmd.Flags().StringVar(&sum, "sha256", "", "expected SHA-256 digest of the workflow file")
+	cmd.Flags().StringVar(&templateValues, "template-values", "", "render the workflow through text/template with the values of this YAML file")
+	cmd.Flags().StringVar(&chaos, "chaos", "", `inject failures/delays into matching steps, e.g. "fail-step=deploy.push:0.3,delay=test.*:5s"`)
+	cmd.Flags().BoolVar(&annotations, "github-annotations", os.Getenv("GITHUB_ACTIONS") == "true", "print problem matcher findings as GitHub Actions annotations")
+	cmd.Flags().BoolVar(&title, "terminal-title", isTerminal(os.Stdout), "show the current step and progress in the terminal title and tab")
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "override a workflow variable, e.g. tag=v1.2.3 (repeatable)")
+	cmd.Flags().StringSliceVar(&varFiles, "var-file", nil, "override workflow variables from a YAML file (repeatable, --var wins)")
+	cmd.Flags().StringVar(&profile, "profile", "", "apply the overrides of the named workflow profile, e.g. prod")
+	cmd.Flags().StringSliceVar(&envFiles, "env-file", nil, "load KEY=VALUE pairs from a dotenv file into the steps' environment (repeatable)")
+	cmd.Flags().StringArrayVar(&envs, "env", nil, "set an environment variable for the steps, e.g. REGION=eu (repeatable)")
+	cmd.Flags().StringVar(&workdir, "workdir", "", "directory the steps' commands run in")
+	cmd.Flags().StringArrayVar(&policies, "policy", nil, "Rego policy file or directory the workflow has to pass before it runs (repeatable)")
+	cmd.Flags().BoolVar(&interactive, "interactive", false, "run the commands of exec steps in a pseudo-terminal")
+	cmd.Flags().StringArrayVar(&approvalTokens, "approval-token", nil, "token from 'forge approval-token' that approves an approval step (repeatable)")
+	cmd.Flags().BoolVar(&step, "step", false, "pause before every step and ask whether to continue, skip it or abort")
+	cmd.Flags().StringVar(&logFile, "log-file", "", "also write the run's output to this file, with the start time inserted into its name")
+	cmd.Flags().IntVar(&logKeep, "log-keep", 10, "with --log-file, keep this many log files (0 = all)")
+	cmd.Flags().DurationVar(&logMaxAge, "log-max-age", 0, "with --log-file, remove log files older than this (0 = never)")
+	cmd.Flags().BoolVar(&stripANSI, "strip-ansi", !isTerminal(os.Stdout), "remove escape sequences such as colors from the output")
+	cmd.Flags().BoolVar(&progress, "progress", isTerminal(os.Stdout) && os.Getenv("CI") == "", "show a spinner with the elapsed time of the running step")
+	cmd.Flags().BoolVar(&dashboard, "tui", false, "show the run as a live terminal dashboard")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print what the run would do without running anything")
+	cmd.Flags().StringArrayVar(&reports, "report", nil, "write a run report as format=path with format json or html, e.g. html=report.html (repeatable)")
+	cmd.Flags().IntVar(&outputLimit, "output-limit", runner.DefaultOutputLimit, "bytes of output kept per step for reports and history, split between its beginning and end")
+	cmd.Flags().StringVar(&metricsURL, "metrics-push-url", "", "push run metrics to this Prometheus Pushgateway after the run")
+	cmd.Flags().BoolVar(&noHistory, "no-history", false, "do not record this run in "+history.DirName)
+	cmd.Flags().BoolVar(&noCache, "no-cache", false, "run steps with a cache even if their inputs are unchanged")
+	cmd.Flags().BoolVar(&replayable, "replayable", false, "record the commands as executed, with their environment, for 'forge replay'")
+	cmd.Flags().BoolVar(&force, "force", false, "run steps whose idempotency key already succeeded in a recorded run")
+	cmd.Flags().StringVar(&changesBase, "changes-base", "", "git ref that stages with changes filters are compared against (overrides the workflow setting)")
+	cmd.Flags().StringSliceVar(&stages, "stage", nil, "run only the named stages (repeatable)")
+	cmd.Flags().BoolVar(&parallel, "parallel", false, "run several workflows concurrently")
+	cmd.Flags().IntVar(&jobs, "jobs", 0, "with --parallel, run at most this many workflows at once (0 = all)")
+	cmd.Flags().StringSliceVar(&skipSteps, "skip-step", nil, `skip steps matching a "stage.step" pattern, e.g. "deploy.*" (repeatable)`)
+	cmd.Flags().BoolVar(&untilFailure, "until-failure", false, "run repeatedly until an iteration fails")
+	cmd.Flags().IntVar(&limits.MaxIterations, "max-iterations", 0, "with --until-failure, stop after this many iterations (0 = no limit)")
+	cmd.Flags().DurationVar(&limits.MaxDuration, "max-duration", 0, "with --until-failure, stop starting new iterations after this long (0 = no limit)")
+	cmd.Flags().StringVar(&timezone, "timezone", "", "timezone for displayed timestamps, e.g. UTC (overrides the workflow setting)")
+	cmd.ValidArgsFunction = completeWorkflowFiles
+	_ = cmd.MarkFlagFilename("env-file")
+	_ = cmd.MarkFlagDirname("workdir")
+	_ = cmd.MarkFlagFilename("policy", "rego")
+	_ = cmd.MarkFlagFilename("var-file", "yaml", "yml")
+	_ = cmd.MarkFlagFilename("template-values", "yaml", "yml")
+	_ = cmd.RegisterFlagCompletionFunc("stage", completeStages)
+	_ = cmd.RegisterFlagCompletionFunc("skip-step", completeSteps)
+	return cmd
 }
 
-var runCmd = makeRunCmd(runner.NewRunner)
+var runCmd = makeRunCmd(runner.New)
 
 func init() {
 	rootCmd.AddCommand(runCmd)
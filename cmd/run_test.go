@@ -5,8 +5,12 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/andre-koe/forge/internal/config"
 	"github.com/andre-koe/forge/internal/dsl"
 	"github.com/andre-koe/forge/internal/runner"
 )
@@ -31,14 +35,14 @@ stages:
 	tests := []struct {
 		name        string
 		workflow    string
-		newRunner   func(string, ...runner.Option) (*runner.Runner, error)
+		newRunner   runner.Factory
 		wantErr     error
 		wantErrType bool
 	}{
 		{
 			name:     "successful run",
 			workflow: validWorkflow,
-			newRunner: func(path string, opts ...runner.Option) (*runner.Runner, error) {
+			newRunner: func(path string, opts ...runner.Option) (runner.Interface, error) {
 				// Use the real NewRunner to get proper defaults
 				return runner.NewRunner(path, opts...)
 			},
@@ -61,7 +65,7 @@ stages:
 		{
 			name:     "runner creation fails",
 			workflow: validWorkflow,
-			newRunner: func(path string, opts ...runner.Option) (*runner.Runner, error) {
+			newRunner: func(path string, opts ...runner.Option) (runner.Interface, error) {
 				return nil, errors.New("mock runner creation error")
 			},
 			wantErr:     runnerCreationErr,
@@ -70,7 +74,7 @@ stages:
 		{
 			name:     "workflow execution fails",
 			workflow: validWorkflow,
-			newRunner: func(path string, opts ...runner.Option) (*runner.Runner, error) {
+			newRunner: func(path string, opts ...runner.Option) (runner.Interface, error) {
 				// Create a runner with a mock that returns an error
 				mockLoad := func(path string) (*dsl.Workflow, error) {
 					return nil, errors.New("mock workflow execution error")
@@ -110,24 +114,24 @@ func TestMakeRunCmd(t *testing.T) {
 	tests := []struct {
 		name      string
 		args      []string
-		newRunner func(string, ...runner.Option) (*runner.Runner, error)
+		newRunner runner.Factory
 		wantErr   error
 	}{
 		{
 			name: "no arguments provided",
 			args: []string{},
-			newRunner: func(path string, opts ...runner.Option) (*runner.Runner, error) {
+			newRunner: func(path string, opts ...runner.Option) (runner.Interface, error) {
 				return runner.NewRunner(path, opts...)
 			},
 			wantErr: nil, // cobra handles this with its own error
 		},
 		{
-			name: "too many arguments",
+			name: "several missing workflows",
 			args: []string{"workflow1.yml", "workflow2.yml"},
-			newRunner: func(path string, opts ...runner.Option) (*runner.Runner, error) {
+			newRunner: func(path string, opts ...runner.Option) (runner.Interface, error) {
 				return runner.NewRunner(path, opts...)
 			},
-			wantErr: nil, // cobra handles this with its own error
+			wantErr: workflowExecutionErr,
 		},
 	}
 
@@ -145,6 +149,9 @@ func TestMakeRunCmd(t *testing.T) {
 			if len(tt.args) != 1 && err == nil {
 				t.Error("Execute() expected error for invalid args, got nil")
 			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Execute() error = %v, want %v", err, tt.wantErr)
+			}
 		})
 	}
 }
@@ -169,7 +176,7 @@ stages:
 	out := new(bytes.Buffer)
 
 	// Create a mock runner that simulates a command failure
-	mockNewRunner := func(path string, opts ...runner.Option) (*runner.Runner, error) {
+	mockNewRunner := func(path string, opts ...runner.Option) (runner.Interface, error) {
 		mockRunCmd := func(argv []string) error {
 			if len(argv) > 0 && argv[0] == "false" {
 				return errors.New("command failed with exit code 1")
@@ -202,7 +209,7 @@ func TestRunRun_WorkflowLoadError(t *testing.T) {
 	out := new(bytes.Buffer)
 
 	// Use the real NewRunner, which will fail to parse the YAML
-	err := runRun(workflowPath, out, runner.NewRunner)
+	err := runRun(workflowPath, out, runner.New)
 
 	if err == nil {
 		t.Fatal("runRun() expected error for invalid YAML, got nil")
@@ -231,7 +238,7 @@ stages:
 	}
 
 	runCalled := false
-	mockNewRunner := func(path string, opts ...runner.Option) (*runner.Runner, error) {
+	mockNewRunner := func(path string, opts ...runner.Option) (runner.Interface, error) {
 		if path != workflowPath {
 			t.Errorf("expected workflow path %q, got %q", workflowPath, path)
 		}
@@ -255,7 +262,7 @@ stages:
 }
 
 func TestRunCmd_Properties(t *testing.T) {
-	cmd := makeRunCmd(func(path string, opts ...runner.Option) (*runner.Runner, error) {
+	cmd := makeRunCmd(func(path string, opts ...runner.Option) (runner.Interface, error) {
 		return &runner.Runner{}, nil
 	})
 
@@ -277,3 +284,305 @@ func TestRunCmd_Properties(t *testing.T) {
 		t.Error("expected Args validator to be set")
 	}
 }
+
+func TestMakeRunCmd_InvalidTimezone(t *testing.T) {
+	tmpDir := t.TempDir()
+	workflowPath := filepath.Join(tmpDir, "workflow.yml")
+	if err := os.WriteFile(workflowPath, []byte(planTestWorkflow), 0644); err != nil {
+		t.Fatalf("failed to create test workflow: %v", err)
+	}
+
+	cmd := makeRunCmd(runner.New)
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs([]string{"--timezone", "Nowhere/Nothing", workflowPath})
+
+	if err := cmd.Execute(); !errors.Is(err, invalidTimezoneErr) {
+		t.Errorf("Execute() error = %v, want %v", err, invalidTimezoneErr)
+	}
+}
+
+func TestMakeRunCmd_InvalidChaos(t *testing.T) {
+	tmpDir := t.TempDir()
+	workflowPath := filepath.Join(tmpDir, "workflow.yml")
+	if err := os.WriteFile(workflowPath, []byte(planTestWorkflow), 0644); err != nil {
+		t.Fatalf("failed to create test workflow: %v", err)
+	}
+
+	cmd := makeRunCmd(runner.New)
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs([]string{"--chaos", "explode=everything", workflowPath})
+
+	if err := cmd.Execute(); !errors.Is(err, invalidChaosErr) {
+		t.Errorf("Execute() error = %v, want %v", err, invalidChaosErr)
+	}
+}
+
+func TestMakeRunCmd_EnvAndWorkdir(t *testing.T) {
+	tmpDir := t.TempDir()
+	workdir := filepath.Join(tmpDir, "app")
+	if err := os.Mkdir(workdir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(tmpDir, "workflow.yml")
+	workflow := `name: ctx
+stages:
+  - name: show
+    steps:
+      - name: where
+        type: exec
+        run: ["sh", "-c", "echo $REGION $(basename $PWD)"]
+`
+	if err := os.WriteFile(workflowPath, []byte(workflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := makeRunCmd(runner.New)
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs([]string{"--no-history", "--env", "REGION=eu", "--workdir", workdir, workflowPath})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v\n%s", err, out)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("[show/where] eu app\n")) {
+		t.Errorf("output = %q, want the command run with the variable in the workdir", out)
+	}
+
+	for _, args := range [][]string{
+		{"--env", "REGION", workflowPath},
+		{"--workdir", filepath.Join(tmpDir, "missing"), workflowPath},
+	} {
+		cmd := makeRunCmd(runner.New)
+		cmd.SetOut(new(bytes.Buffer))
+		cmd.SetErr(new(bytes.Buffer))
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); !errors.Is(err, invalidEnvErr) && !errors.Is(err, invalidWorkdirErr) {
+			t.Errorf("Execute(%q) error = %v, want an invalid flag error", args, err)
+		}
+	}
+}
+
+func TestMakeRunCmd_InteractiveParallel(t *testing.T) {
+	cmd := makeRunCmd(runner.New)
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	cmd.SetArgs([]string{"--interactive", "--parallel", "a.yml", "b.yml"})
+	if err := cmd.Execute(); !errors.Is(err, multiWorkflowErr) {
+		t.Errorf("Execute() error = %v, want %v", err, multiWorkflowErr)
+	}
+}
+
+func TestMakeRunCmd_Policy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake opa is a shell script")
+	}
+	tmpDir := t.TempDir()
+	opa := filepath.Join(tmpDir, "bin", "opa")
+	if err := os.MkdirAll(filepath.Dir(opa), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\ncat >/dev/null\necho '{\"result\":[{\"expressions\":[{\"value\":[\"deploy stages must have an approval step\"]}]}]}'\n"
+	if err := os.WriteFile(opa, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", filepath.Dir(opa)+string(os.PathListSeparator)+os.Getenv("PATH"))
+	policyDir := filepath.Join(tmpDir, "policies")
+	if err := os.Mkdir(policyDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	workflowPath := filepath.Join(tmpDir, "workflow.yml")
+	if err := os.WriteFile(workflowPath, []byte(sourceTestWorkflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := makeRunCmd(runner.New)
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs([]string{"--no-history", "--policy", policyDir, workflowPath})
+	err := cmd.Execute()
+	if !errors.Is(err, workflowExecutionErr) || !strings.Contains(err.Error(), "deploy stages must have an approval step") {
+		t.Errorf("Execute() error = %v, want the policy message", err)
+	}
+
+	cmd = makeRunCmd(runner.New)
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	cmd.SetArgs([]string{"--policy", filepath.Join(tmpDir, "missing"), workflowPath})
+	if err := cmd.Execute(); !errors.Is(err, invalidPolicyErr) {
+		t.Errorf("Execute() error = %v, want %v", err, invalidPolicyErr)
+	}
+}
+
+func TestMakeRunCmd_DryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	marker := filepath.Join(tmpDir, "marker")
+	workflowPath := filepath.Join(tmpDir, "workflow.yml")
+	workflow := "name: touch\nstages:\n  - name: build\n    steps:\n      - name: touch\n        type: exec\n        run: [\"touch\", \"" + filepath.ToSlash(marker) + "\"]\n"
+	if err := os.WriteFile(workflowPath, []byte(workflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := makeRunCmd(runner.New)
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs([]string{"--no-history", "--dry-run", workflowPath})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "[DRY-RUN] STEP 1.1: touch (exec)") {
+		t.Errorf("output does not show the simulated step:\n%s", out.String())
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("the step ran during --dry-run: %v", err)
+	}
+
+	cmd = makeRunCmd(runner.New)
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	cmd.SetArgs([]string{"--dry-run", "--until-failure", workflowPath})
+	if err := cmd.Execute(); !errors.Is(err, invalidDryRunErr) {
+		t.Errorf("Execute() error = %v, want %v", err, invalidDryRunErr)
+	}
+}
+
+func TestMakeRunCmd_Step(t *testing.T) {
+	tmpDir := t.TempDir()
+	marker := filepath.Join(tmpDir, "marker")
+	workflowPath := filepath.Join(tmpDir, "workflow.yml")
+	workflow := "name: touch\nstages:\n  - name: build\n    steps:\n      - name: touch\n        type: exec\n        run: [\"touch\", \"" + filepath.ToSlash(marker) + "\"]\n"
+	if err := os.WriteFile(workflowPath, []byte(workflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := makeRunCmd(runner.New)
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetIn(strings.NewReader("s\n"))
+	cmd.SetArgs([]string{"--no-history", "--step", workflowPath})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "command: touch "+marker) || !strings.Contains(out.String(), "STEP 1.1: touch (skipped)") {
+		t.Errorf("output does not show the prompt and the skipped step:\n%s", out.String())
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("the skipped step ran: %v", err)
+	}
+
+	cmd = makeRunCmd(runner.New)
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	cmd.SetIn(strings.NewReader(sourceTestWorkflow))
+	cmd.SetArgs([]string{"--step", "-"})
+	if err := cmd.Execute(); !errors.Is(err, invalidStepErr) {
+		t.Errorf("Execute() error = %v, want %v", err, invalidStepErr)
+	}
+}
+
+func TestMakeRunCmd_TUI(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "with --step", args: []string{"--tui", "--step", "workflow.yml"}},
+		{name: "without a terminal", args: []string{"--tui", "workflow.yml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := makeRunCmd(runner.New)
+			cmd.SetOut(new(bytes.Buffer))
+			cmd.SetErr(new(bytes.Buffer))
+			cmd.SetArgs(tt.args)
+			if err := cmd.Execute(); !errors.Is(err, invalidTUIErr) {
+				t.Errorf("Execute() error = %v, want %v", err, invalidTUIErr)
+			}
+		})
+	}
+}
+
+func TestMakeRunCmd_LogFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	workflowPath := filepath.Join(tmpDir, "workflow.yml")
+	if err := os.WriteFile(workflowPath, []byte(sourceTestWorkflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	logDir := filepath.Join(tmpDir, "logs")
+	if err := os.MkdirAll(logDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(logDir, "forge-20200101-000000.log")
+	if err := os.WriteFile(stale, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := makeRunCmd(runner.New)
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs([]string{"--no-history", "--log-file", filepath.Join(logDir, "forge.log"), "--log-keep", "1", workflowPath})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+
+	logs, err := filepath.Glob(filepath.Join(logDir, "forge-*.log"))
+	if err != nil || len(logs) != 1 || logs[0] == stale {
+		t.Fatalf("log files = %v, %v; want only the new one", logs, err)
+	}
+	data, err := os.ReadFile(logs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != out.String() || !strings.Contains(string(data), "[build/hello] hi") {
+		t.Errorf("log file = %q, want the console output %q", data, out.String())
+	}
+}
+
+func TestMakeRunCmd_StripANSI(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses printf from sh")
+	}
+	tmpDir := t.TempDir()
+	workflowPath := filepath.Join(tmpDir, "workflow.yml")
+	workflow := "name: color\nstages:\n  - name: build\n    steps:\n      - name: color\n        type: exec\n        run: [\"sh\", \"-c\", \"printf '\\\\033[31mred\\\\033[0m\\\\n'\"]\n"
+	if err := os.WriteFile(workflowPath, []byte(workflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, strip := range []bool{true, false} {
+		cmd := makeRunCmd(runner.New)
+		out := new(bytes.Buffer)
+		cmd.SetOut(out)
+		cmd.SetErr(out)
+		cmd.SetArgs([]string{"--no-history", "--strip-ansi=" + strconv.FormatBool(strip), workflowPath})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("Execute() error: %v", err)
+		}
+		if got := strings.Contains(out.String(), "\x1b["); got == strip {
+			t.Errorf("--strip-ansi=%v: output = %q", strip, out.String())
+		}
+		if !strings.Contains(out.String(), "red") {
+			t.Errorf("--strip-ansi=%v: output lost the text: %q", strip, out.String())
+		}
+	}
+	// --strip-ansi wins over NO_COLOR
+	t.Setenv(config.PathEnv, filepath.Join(tmpDir, "missing.yaml"))
+	t.Setenv("NO_COLOR", "1")
+	cmd := makeRunCmd(runner.New)
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs([]string{"--no-history", "--strip-ansi=false", workflowPath})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "\x1b[") {
+		t.Errorf("--strip-ansi=false with NO_COLOR: output = %q, want the colors", out.String())
+	}
+}
@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/andre-koe/forge/internal/cron"
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/spf13/cobra"
+)
+
+// overlapPolicy decides what happens when a run is due while the previous one is still in progress
+type overlapPolicy string
+
+const (
+	overlapSkip   overlapPolicy = "skip"
+	overlapQueue  overlapPolicy = "queue"
+	overlapCancel overlapPolicy = "cancel"
+)
+
+var overlapPolicies = []overlapPolicy{overlapSkip, overlapQueue, overlapCancel}
+
+// cronTicks sends the activation times of sched in loc until ctx is done or the schedule no longer fires
+func cronTicks(ctx context.Context, sched *cron.Schedule, loc *time.Location) <-chan time.Time {
+	ticks := make(chan time.Time)
+	go func() {
+		defer close(ticks)
+		for {
+			next := sched.Next(time.Now().In(loc))
+			if next.IsZero() {
+				return
+			}
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+			select {
+			case ticks <- next:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ticks
+}
+
+// runSchedule runs the workflow for every tick until ticks is closed or ctx is done, waiting for the
+// runs in progress or queued before returning. Ticks during a run are handled according to policy; queued
+// ticks collapse into a single run.
+func runSchedule(ctx context.Context, workflow string, out io.Writer, newRunner runner.Factory, ticks <-chan time.Time, policy overlapPolicy, opts ...runner.Option) error {
+	base, err := runBaseOptions(workflow)
+	if err != nil {
+		return err
+	}
+
+	var (
+		cancel   context.CancelFunc
+		done     chan struct{}
+		queued   bool
+		queuedAt time.Time
+	)
+	start := func(at time.Time) {
+		runCtx, c := context.WithCancel(ctx)
+		cancel, done = c, make(chan struct{})
+		fmt.Fprintf(out, "\n--- Scheduled run due at %s ---\n", at.Format(time.RFC3339))
+		go func(done chan struct{}) {
+			defer close(done)
+			r, err := newRunner(workflow, slices.Concat(base, opts, []runner.Option{runner.WithOut(out), runner.WithContext(runCtx)})...)
+			if err != nil {
+				fmt.Fprintf(out, "\n%v: %v\n", runnerCreationErr, err)
+				return
+			}
+			switch err := r.Run(); {
+			case errors.Is(err, runner.ErrCancelled):
+				fmt.Fprintln(out, "\nScheduled run cancelled")
+			case err != nil:
+				fmt.Fprintf(out, "\nScheduled run failed: %v\n", err)
+			default:
+				fmt.Fprintln(out, "\nScheduled run succeeded")
+			}
+		}(done)
+	}
+
+	for {
+		select {
+		case at, ok := <-ticks:
+			switch {
+			case !ok && done == nil:
+				return nil
+			case !ok:
+				// Finish the run in progress and a queued one first
+				ticks = nil
+			case done == nil:
+				start(at)
+			case policy == overlapSkip:
+				fmt.Fprintf(out, "Skipping run due at %s: the previous run is still in progress\n", at.Format(time.RFC3339))
+			case policy == overlapQueue:
+				fmt.Fprintf(out, "Queueing run due at %s until the previous run finishes\n", at.Format(time.RFC3339))
+				queued, queuedAt = true, at
+			case policy == overlapCancel:
+				fmt.Fprintf(out, "Cancelling the previous run for the run due at %s\n", at.Format(time.RFC3339))
+				cancel()
+				queued, queuedAt = true, at
+			}
+		case <-done:
+			cancel()
+			done = nil
+			switch {
+			case queued:
+				queued = false
+				start(queuedAt)
+			case ticks == nil:
+				return nil
+			}
+		case <-ctx.Done():
+			if done != nil {
+				<-done
+			}
+			return nil
+		}
+	}
+}
+
+// scheduleLocation returns the zone the schedule of a workflow is evaluated in: timezone, from
+// --timezone or the config file, or else the workflow's own, as for the timestamps of its runs
+func scheduleLocation(wf *dsl.Workflow, timezone string) (*time.Location, error) {
+	if timezone != "" {
+		return time.LoadLocation(timezone)
+	}
+	return wf.Location()
+}
+
+func makeScheduleCmd(newRunner runner.Factory) *cobra.Command {
+	var expr, overlap, metricsAddr, timezone string
+	var stages []string
+
+	cmd := &cobra.Command{
+		Use:   "schedule [workflow]",
+		Short: "Run a workflow on a cron schedule",
+		Long: `Run a workflow on the cron schedule given by its schedule: field or --cron until
+interrupted. Schedules use the five cron fields (minute hour day-of-month month
+day-of-week) or @hourly, @daily, @weekly, @monthly and @yearly, evaluated in the
+zone the runs show their timestamps in: --timezone, the timezone of the config file or
+FORGE_TIMEZONE, or the workflow's timezone.
+
+--overlap decides what happens when a run is due while the previous one is still in
+progress: skip it (default), queue it until the previous run finishes, or cancel the
+previous run. Every run is recorded in the run history. With --metrics-addr the
+metrics of the runs are served on /metrics in the Prometheus text format.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workflow := args[0]
+			policy := overlapPolicy(overlap)
+			if !slices.Contains(overlapPolicies, policy) {
+				return fmt.Errorf("%w: --overlap must be one of skip, queue or cancel, got %q", scheduleErr, overlap)
+			}
+			if isVirtualWorkflow(workflow) {
+				return fmt.Errorf("%w: a workflow read from stdin or a URL", scheduleErr)
+			}
+			if err := CheckFilePathExistAndIsNotEmpty(workflow); err != nil {
+				return err
+			}
+			wf, err := dsl.LoadWorkflowFromFile(workflow)
+			if err != nil {
+				return fmt.Errorf("%w: %v", workflowLoadErr, err)
+			}
+			if expr == "" {
+				expr = wf.Schedule
+			}
+			if expr == "" {
+				return fmt.Errorf("%w: %s has no schedule; set schedule: or use --cron", scheduleErr, workflow)
+			}
+			sched, err := cron.Parse(expr)
+			if err != nil {
+				return fmt.Errorf("%w: %v", scheduleErr, err)
+			}
+			if !cmd.Flags().Changed("timezone") {
+				cfg, err := loadConfig()
+				if err != nil {
+					return fmt.Errorf("%w: %v", configErr, err)
+				}
+				timezone = cfg.Timezone
+			}
+			loc, err := scheduleLocation(wf, timezone)
+			if err != nil {
+				return fmt.Errorf("%w: %v", invalidTimezoneErr, err)
+			}
+			var opts []runner.Option
+			if timezone != "" {
+				opts = append(opts, runner.WithTimezone(loc))
+			}
+			next := sched.Next(time.Now().In(loc))
+			if next.IsZero() {
+				return fmt.Errorf("%w: %q never fires", scheduleErr, expr)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			fmt.Fprintf(cmd.OutOrStdout(), "Scheduling %s on %q (overlap: %s); next run at %s\n", workflow, expr, policy, next.Format(time.RFC3339))
+			if len(stages) > 0 {
+				opts = append(opts, runner.WithStages(stages...))
+			}
+			newRunner := newRunner
+			if metricsAddr != "" {
+				ln, err := net.Listen("tcp", metricsAddr)
+				if err != nil {
+					return fmt.Errorf("%w: %v", scheduleErr, err)
+				}
+				newRunner = serveMetrics(ctx, ln, newRunner, cmd.OutOrStdout())
+			}
+			return runSchedule(ctx, workflow, cmd.OutOrStdout(), newRunner, cronTicks(ctx, sched, loc), policy, opts...)
+		},
+	}
+	cmd.Flags().StringVar(&expr, "cron", "", `cron expression overriding the workflow's schedule, e.g. "*/30 * * * *"`)
+	cmd.Flags().StringVar(&overlap, "overlap", string(overlapSkip), "when a run is due during the previous one: skip, queue or cancel")
+	cmd.Flags().StringSliceVar(&stages, "stage", nil, "run only the named stages (repeatable)")
+	cmd.Flags().StringVar(&timezone, "timezone", "", "timezone to evaluate the schedule and show timestamps in, e.g. UTC (overrides the workflow setting)")
+	cmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "address to serve Prometheus metrics of the runs on /metrics, e.g. 127.0.0.1:9100; none by default")
+	cmd.ValidArgsFunction = completeWorkflowFiles
+	_ = cmd.RegisterFlagCompletionFunc("stage", completeStages)
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(makeScheduleCmd(runner.New))
+}
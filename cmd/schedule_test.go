@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/andre-koe/forge/internal/runner/runnertest"
+)
+
+func TestRunSchedule(t *testing.T) {
+	tests := []struct {
+		policy     overlapPolicy
+		wantRuns   int
+		wantOutput []string
+	}{
+		{policy: overlapSkip, wantRuns: 1, wantOutput: []string{"Skipping run due at 2026-01-01T10:01:00Z", "Scheduled run succeeded"}},
+		{policy: overlapQueue, wantRuns: 2, wantOutput: []string{"Queueing run due at 2026-01-01T10:01:00Z", "--- Scheduled run due at 2026-01-01T10:01:00Z ---", "Scheduled run succeeded"}},
+		{policy: overlapCancel, wantRuns: 2, wantOutput: []string{"Cancelling the previous run", "Scheduled run cancelled", "--- Scheduled run due at 2026-01-01T10:01:00Z ---"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "workflow.yaml")
+			if err := os.WriteFile(path, []byte(sourceTestWorkflow), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			// The first run blocks until it is released or cancelled
+			var mu sync.Mutex
+			runs := 0
+			started, release := make(chan struct{}), make(chan struct{})
+			runners := &runnertest.Factory{Configure: func(r *runnertest.Runner) {
+				r.RunFunc = func(r *runnertest.Runner, _ bool) error {
+					mu.Lock()
+					runs++
+					n := runs
+					mu.Unlock()
+					if n == 1 {
+						close(started)
+						select {
+						case <-release:
+						case <-r.Config.Context.Done():
+							return fmt.Errorf("%w: %v", runner.ErrCancelled, r.Config.Context.Err())
+						}
+					}
+					return nil
+				}
+			}}
+
+			ticks := make(chan time.Time)
+			out := new(syncBuffer)
+			done := make(chan error)
+			go func() {
+				done <- runSchedule(context.Background(), path, out, runners.New, ticks, tt.policy, runner.WithHistory(nil))
+			}()
+
+			ticks <- time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+			<-started
+			ticks <- time.Date(2026, 1, 1, 10, 1, 0, 0, time.UTC)
+			if tt.policy != overlapCancel {
+				close(release)
+			}
+			close(ticks)
+			if err := <-done; err != nil {
+				t.Fatalf("runSchedule() error: %v", err)
+			}
+
+			if runs != tt.wantRuns {
+				t.Errorf("runs = %d, want %d", runs, tt.wantRuns)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRunSchedule_MissingWorkflow(t *testing.T) {
+	err := runSchedule(context.Background(), "does-not-exist.yaml", new(bytes.Buffer), runner.New, nil, overlapSkip)
+	if !errors.Is(err, workflowNotFoundErr) {
+		t.Errorf("runSchedule() error = %v, want %v", err, workflowNotFoundErr)
+	}
+}
+
+func TestScheduleLocation(t *testing.T) {
+	tests := []struct {
+		name, workflowZone, timezone, want string
+		wantErr                            bool
+	}{
+		{name: "workflow zone", workflowZone: "UTC", want: "UTC"},
+		{name: "timezone wins", workflowZone: "Nowhere/Nothing", timezone: "UTC", want: "UTC"},
+		{name: "invalid timezone", workflowZone: "UTC", timezone: "Nowhere/Nothing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := scheduleLocation(&dsl.Workflow{Timezone: tt.workflowZone}, tt.timezone)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("scheduleLocation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && loc.String() != tt.want {
+				t.Errorf("scheduleLocation() = %s, want %s", loc, tt.want)
+			}
+		})
+	}
+}
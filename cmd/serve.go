@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/andre-koe/forge/internal/metrics"
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/andre-koe/forge/internal/server"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+// serveShutdownTimeout bounds how long open requests, such as followed logs, may delay shutdown
+const serveShutdownTimeout = 5 * time.Second
+
+// runServe serves the REST API on ln, and the gRPC API on grpcLn unless it is nil, until ctx is done,
+// then cancels the runs in progress and waits for them
+func runServe(ctx context.Context, ln, grpcLn net.Listener, root string, out io.Writer, newRunner runner.Factory, opts ...server.Option) error {
+	opts = append([]server.Option{server.WithRunOptions(runBaseOptions), server.WithBaseURL("http://" + ln.Addr().String())}, opts...)
+	c := metrics.New()
+	s := server.New(ctx, root, observeRuns(c, newRunner), opts...)
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", c)
+	mux.Handle("/", s.Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	fmt.Fprintf(out, "Serving workflows in %s on http://%s\n", root, ln.Addr())
+	errc := make(chan error, 2)
+	go func() { errc <- srv.Serve(ln) }()
+	var gs *grpc.Server
+	if grpcLn != nil {
+		gs = grpc.NewServer()
+		s.RegisterGRPC(gs)
+		fmt.Fprintf(out, "Serving the gRPC API on %s\n", grpcLn.Addr())
+		go func() { errc <- gs.Serve(grpcLn) }()
+	}
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("%w: %v", serveErr, err)
+	case <-ctx.Done():
+	}
+	fmt.Fprintln(out, "Shutting down, cancelling runs in progress")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	if gs != nil {
+		stopGRPC(shutdownCtx, gs)
+	}
+	s.Wait()
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", serveErr, err)
+	}
+	return nil
+}
+
+// stopGRPC stops gs once its open calls, such as followed logs, ended or ctx is done
+func stopGRPC(ctx context.Context, gs *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		gs.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		gs.Stop()
+	}
+}
+
+func makeServeCmd(newRunner runner.Factory) *cobra.Command {
+	var addr, grpcAddr, dir string
+	var maxRuns, maxRunsPerWorkflow, maxQueued int
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Trigger and follow workflow runs over HTTP",
+		Long: `Serve a REST API for running the workflows below --dir:
+
+  POST   /runs               start a run, e.g. {"workflow": "ci.yaml", "inputs": {"tag": "v1"}, "stages": ["build"], "priority": 1}
+  GET    /runs               list the runs started by this server
+  GET    /runs/{id}          show the status of a run, its queue position and the approval step it waits for
+  GET    /runs/{id}/logs     stream the output of a run until it finishes
+  POST   /runs/{id}/approve  approve the approval step the run waits for, see below
+  POST   /runs/{id}/deny     deny it, failing the step
+  DELETE /runs/{id}          cancel a run
+  GET    /agents             list the agents that joined with forge agent --join
+  GET    /metrics            metrics of the server's runs in the Prometheus text format
+
+With --grpc-addr the same runs can be started, listed, followed and cancelled over gRPC,
+with the ForgeService of api/forgev1/forge.proto and its generated Go client.
+
+Inputs override the workflow's vars. Stages with runs_on: {agent: [labels]} are sent to
+an agent with those labels. With --max-runs or --max-runs-per-workflow, runs beyond the
+limits wait in a queue, higher priorities first and otherwise in the order they were
+requested; --max-queued answers 503 once that many runs wait. Runs are recorded in the
+workflow's run history and cancelled when the server is interrupted.
+
+Approving or denying a run takes a token from 'forge approval-token' for the step in an
+"Authorization: Bearer" header, signed with the key in FORGE_APPROVAL_SECRET; without it
+set, runs cannot be decided over the API. The rest of the API has no authentication;
+bind it to a trusted interface.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if maxRuns < 0 || maxRunsPerWorkflow < 0 || maxQueued < 0 {
+				return fmt.Errorf("%w: --max-runs, --max-runs-per-workflow and --max-queued must not be negative", serveErr)
+			}
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("%w: %v", serveErr, err)
+			}
+			var grpcLn net.Listener
+			if grpcAddr != "" {
+				if grpcLn, err = net.Listen("tcp", grpcAddr); err != nil {
+					ln.Close()
+					return fmt.Errorf("%w: %v", serveErr, err)
+				}
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return runServe(ctx, ln, grpcLn, dir, cmd.OutOrStdout(), newRunner,
+				server.WithMaxRuns(maxRuns), server.WithMaxRunsPerWorkflow(maxRunsPerWorkflow), server.WithMaxQueued(maxQueued),
+				server.WithApprovalSecret([]byte(os.Getenv(approvalSecretEnv))))
+		},
+	}
+	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:8080", "address to listen on")
+	cmd.Flags().StringVar(&grpcAddr, "grpc-addr", "", "address to serve the gRPC API on, e.g. 127.0.0.1:9090; none by default")
+	cmd.Flags().StringVar(&dir, "dir", ".", "directory containing the workflows that can be run")
+	cmd.Flags().IntVar(&maxRuns, "max-runs", 0, "maximum number of runs in progress at once, 0 for no limit")
+	cmd.Flags().IntVar(&maxRunsPerWorkflow, "max-runs-per-workflow", 0, "maximum number of runs of the same workflow in progress at once, 0 for no limit")
+	cmd.Flags().IntVar(&maxQueued, "max-queued", 0, "maximum number of runs waiting in the queue, 0 for no limit")
+	_ = cmd.MarkFlagDirname("dir")
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(makeServeCmd(runner.New))
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/api/forgev1"
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/andre-koe/forge/internal/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestRunServe(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "ci.yaml"), []byte(sourceTestWorkflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Runs block until the server shuts down
+	newRunner := func(workflow string, opts ...runner.Option) (runner.Interface, error) {
+		r, err := runner.NewRunner(workflow, append(opts, runner.WithHistory(nil))...)
+		if err != nil {
+			return nil, err
+		}
+		r.RunCmd = func(argv []string) error {
+			<-r.Context.Done()
+			return r.Context.Err()
+		}
+		return r, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	out := new(syncBuffer)
+	done := make(chan error)
+	go func() { done <- runServe(ctx, ln, grpcLn, root, out, newRunner) }()
+
+	base := "http://" + ln.Addr().String()
+	resp, err := http.Post(base+"/runs", "application/json", strings.NewReader(`{"workflow": "ci.yaml"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var run server.Run
+	_ = json.NewDecoder(resp.Body).Decode(&run)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || run.Status != server.StatusRunning {
+		t.Fatalf("POST /runs = %d %+v", resp.StatusCode, run)
+	}
+
+	resp, err = http.Post(base+"/runs", "application/json", strings.NewReader(`{"workflow": "missing.yaml"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("POST /runs for a missing workflow = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	conn, err := grpc.NewClient(grpcLn.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := forgev1.NewForgeServiceClient(conn)
+	if grpcRun, err := client.StartRun(ctx, &forgev1.StartRunRequest{Workflow: "ci.yaml"}); err != nil || grpcRun.Status != forgev1.RunStatus_RUN_STATUS_RUNNING {
+		t.Fatalf("StartRun() over gRPC = %v, %v", grpcRun, err)
+	}
+	if list, err := client.ListRuns(ctx, &forgev1.ListRunsRequest{}); err != nil || len(list.Runs) != 2 {
+		t.Errorf("ListRuns() over gRPC = %v, %v, want the runs started over both APIs", list, err)
+	}
+
+	resp, err = http.Get(base + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(metrics), "forge_active_runs 2\n") {
+		t.Errorf("GET /metrics = %d, want the runs in progress:\n%s", resp.StatusCode, metrics)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("runServe() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "cancelling runs in progress") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
@@ -6,10 +6,46 @@ import (
 )
 
 var (
-	workflowEmptyPathErr = errors.New("workflow path cannot be empty")
-	workflowNotFoundErr  = errors.New("workflow file not found")
-	runnerCreationErr    = errors.New("failed to create runner")
-	workflowExecutionErr = errors.New("workflow execution failed")
+	workflowEmptyPathErr    = errors.New("workflow path cannot be empty")
+	workflowNotFoundErr     = errors.New("workflow file not found")
+	workflowLoadErr         = errors.New("failed to load workflow")
+	runnerCreationErr       = errors.New("failed to create runner")
+	workflowExecutionErr    = errors.New("workflow execution failed")
+	invalidTimezoneErr      = errors.New("invalid timezone")
+	invalidChaosErr         = errors.New("invalid chaos specification")
+	historyReadErr          = errors.New("failed to read run history")
+	invalidSkipStepErr      = errors.New("invalid --skip-step pattern")
+	configErr               = errors.New("invalid forge configuration")
+	invalidVarErr           = errors.New("invalid --var")
+	varFileErr              = errors.New("failed to read var file")
+	invalidEnvErr           = errors.New("invalid --env")
+	invalidWorkdirErr       = errors.New("invalid --workdir")
+	invalidPolicyErr        = errors.New("invalid --policy")
+	invalidDryRunErr        = errors.New("invalid --dry-run")
+	invalidStepErr          = errors.New("invalid --step")
+	invalidTUIErr           = errors.New("invalid --tui")
+	logFileErr              = errors.New("cannot open log file")
+	invalidBenchErr         = errors.New("invalid benchmark")
+	compareErr              = errors.New("cannot compare reports")
+	regressionErr           = errors.New("run regressed")
+	invalidReportErr        = errors.New("invalid --report")
+	invalidMetricsURLErr    = errors.New("invalid --metrics-push-url")
+	artifactsErr            = errors.New("failed to read artifacts")
+	cacheClearErr           = errors.New("failed to clear the step cache")
+	watchErr                = errors.New("cannot watch workflow")
+	scheduleErr             = errors.New("cannot schedule workflow")
+	serveErr                = errors.New("server failed")
+	agentErr                = errors.New("agent failed")
+	remoteLogsErr           = errors.New("failed to read remote logs")
+	activeRunsErr           = errors.New("failed to read runs in progress")
+	cancelErr               = errors.New("failed to cancel run")
+	invalidApprovalTokenErr = errors.New("invalid --approval-token")
+	approvalTokenErr        = errors.New("cannot create approval token")
+	invalidTolerateErr      = errors.New("invalid --tolerate")
+	replayErr               = errors.New("cannot replay run")
+	fixturesErr             = errors.New("cannot read test fixtures")
+	workflowTestErr         = errors.New("workflow tests failed")
+	workflowInvalidErr      = errors.New("invalid workflow")
 )
 
 func CheckFilePathExistAndIsNotEmpty(path string) error {
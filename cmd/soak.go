@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+// soakLimits bounds a --until-failure run; zero values mean no limit
+type soakLimits struct {
+	MaxIterations int
+	MaxDuration   time.Duration
+}
+
+func (l soakLimits) reached(iterations int, elapsed time.Duration) bool {
+	return (l.MaxIterations > 0 && iterations >= l.MaxIterations) ||
+		(l.MaxDuration > 0 && elapsed >= l.MaxDuration)
+}
+
+// runUntilFailure runs the workflow at least once and repeats until an iteration fails or a limit is reached.
+// Each iteration's output is buffered and only printed for the failing iteration.
+func runUntilFailure(workflow string, out io.Writer, newRunner runner.Factory, limits soakLimits, opts ...runner.Option) error {
+	base, err := runBaseOptions(workflow)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	iterations := 0
+	for {
+		iterations++
+
+		var log bytes.Buffer
+		iterOpts := slices.Concat(base, opts, []runner.Option{runner.WithOut(&log), runner.WithProcessOutput(&log, &log)})
+		r, err := newRunner(workflow, iterOpts...)
+		if err != nil {
+			return runnerCreationErr
+		}
+
+		fmt.Fprintf(out, "Iteration %d: ", iterations)
+		iterStart := time.Now()
+		if err := r.Run(); err != nil {
+			fmt.Fprintf(out, "FAILED after %s", time.Since(iterStart).Round(time.Millisecond))
+			if res := r.Results(); res.Record != nil && res.Saved {
+				fmt.Fprintf(out, " (run %s)", res.Record.ID)
+			}
+			fmt.Fprintf(out, ": %v\n\n--- Output of iteration %d ---\n%s", err, iterations, log.String())
+			return fmt.Errorf("%w: iteration %d: %v", workflowExecutionErr, iterations, err)
+		}
+		fmt.Fprintf(out, "ok (%s)\n", time.Since(iterStart).Round(time.Millisecond))
+
+		if limits.reached(iterations, time.Since(start)) {
+			break
+		}
+	}
+
+	fmt.Fprintf(out, "\nNo failure in %d iteration(s) over %s\n", iterations, time.Since(start).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+func TestRunUntilFailure(t *testing.T) {
+	tests := []struct {
+		name           string
+		failOn         int
+		limits         soakLimits
+		wantErr        error
+		wantIterations int
+		wantOutput     []string
+	}{
+		{
+			name:           "stops at first failure",
+			failOn:         3,
+			limits:         soakLimits{MaxIterations: 10},
+			wantErr:        workflowExecutionErr,
+			wantIterations: 3,
+			wantOutput:     []string{"Iteration 3: FAILED", "--- Output of iteration 3 ---", "step 'hello'"},
+		},
+		{
+			name:           "max iterations reached",
+			limits:         soakLimits{MaxIterations: 4},
+			wantIterations: 4,
+			wantOutput:     []string{"Iteration 4: ok", "No failure in 4 iteration(s)"},
+		},
+		{
+			name:           "max duration reached",
+			limits:         soakLimits{MaxDuration: time.Nanosecond},
+			wantIterations: 1,
+			wantOutput:     []string{"No failure in 1 iteration(s)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "workflow.yaml")
+			if err := os.WriteFile(path, []byte(sourceTestWorkflow), 0o644); err != nil {
+				t.Fatalf("WriteFile() error: %v", err)
+			}
+
+			iterations := 0
+			runCmd := func(argv []string) error {
+				iterations++
+				if iterations == tt.failOn {
+					return errors.New("flaky")
+				}
+				return nil
+			}
+
+			out := new(bytes.Buffer)
+			err := runUntilFailure(path, out, runner.New, tt.limits,
+				runner.WithRunCmd(runCmd), runner.WithHistory(nil))
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("runUntilFailure() error = %v, want %v", err, tt.wantErr)
+			}
+			if iterations != tt.wantIterations {
+				t.Errorf("iterations = %d, want %d", iterations, tt.wantIterations)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRunUntilFailure_MissingWorkflow(t *testing.T) {
+	err := runUntilFailure("does-not-exist.yaml", new(bytes.Buffer), runner.New, soakLimits{MaxIterations: 1})
+	if !errors.Is(err, workflowNotFoundErr) {
+		t.Errorf("runUntilFailure() error = %v, want %v", err, workflowNotFoundErr)
+	}
+}
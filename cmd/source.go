@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+// stdinWorkflow is the workflow argument that reads the workflow from stdin
+const stdinWorkflow = "-"
+
+// maxRemoteWorkflowSize limits how much is read from stdin or a URL
+const maxRemoteWorkflowSize = 10 << 20
+
+var (
+	workflowFetchErr    = errors.New("failed to fetch workflow")
+	workflowChecksumErr = errors.New("workflow checksum mismatch")
+	workflowTemplateErr = errors.New("failed to render workflow template")
+)
+
+var workflowHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// readTemplateValues reads the --template-values file, nil when none is given
+func readTemplateValues(path string) (map[string]any, error) {
+	if path == "" {
+		return nil, nil
+	}
+	values, err := dsl.ReadTemplateValues(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", workflowTemplateErr, err)
+	}
+	return values, nil
+}
+
+// isVirtualWorkflow reports whether the workflow argument is not a local file
+func isVirtualWorkflow(arg string) bool {
+	return arg == stdinWorkflow || isWorkflowURL(arg)
+}
+
+func isWorkflowURL(arg string) bool {
+	return strings.HasPrefix(arg, "https://") || strings.HasPrefix(arg, "http://")
+}
+
+// readWorkflowSource reads a workflow from stdin ("-"), an HTTPS URL or a local file and,
+// if sum is set, verifies its SHA-256 digest
+func readWorkflowSource(arg, sum string, stdin io.Reader, client *http.Client) ([]byte, error) {
+	var data []byte
+	var err error
+
+	switch {
+	case arg == stdinWorkflow:
+		data, err = io.ReadAll(io.LimitReader(stdin, maxRemoteWorkflowSize))
+	case strings.HasPrefix(arg, "http://"):
+		return nil, fmt.Errorf("%w: only https URLs are supported", workflowFetchErr)
+	case isWorkflowURL(arg):
+		data, err = fetchWorkflow(arg, client)
+	default:
+		data, err = os.ReadFile(arg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", workflowFetchErr, err)
+	}
+
+	if sum != "" {
+		digest := sha256.Sum256(data)
+		if got := hex.EncodeToString(digest[:]); !strings.EqualFold(got, sum) {
+			return nil, fmt.Errorf("%w: expected %s, got %s", workflowChecksumErr, sum, got)
+		}
+	}
+	return data, nil
+}
+
+func fetchWorkflow(url string, client *http.Client) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxRemoteWorkflowSize))
+}
+
+// workflowSourceOptions returns runner options that load the workflow from data read
+// from stdin or a URL instead of the file system, rendered with the template values when
+// they are not nil
+func workflowSourceOptions(arg, sum string, values map[string]any, stdin io.Reader, client *http.Client) ([]runner.Option, error) {
+	data, err := readWorkflowSource(arg, sum, stdin, client)
+	if err != nil {
+		return nil, err
+	}
+	if values != nil {
+		if data, err = dsl.RenderWorkflow(data, arg, values); err != nil {
+			return nil, fmt.Errorf("%w: %v", workflowTemplateErr, err)
+		}
+	}
+
+	load := func(string) (*dsl.Workflow, error) {
+		return dsl.LoadWorkflow(data, arg)
+	}
+	return []runner.Option{runner.WithLoadWorkflow(load)}, nil
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+const sourceTestWorkflow = `name: remote
+stages:
+  - name: build
+    steps:
+      - name: hello
+        type: exec
+        run: ["echo", "hi"]
+`
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestReadWorkflowSource(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/workflow.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(sourceTestWorkflow))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		arg     string
+		sum     string
+		stdin   string
+		wantErr error
+	}{
+		{name: "stdin", arg: "-", stdin: sourceTestWorkflow},
+		{name: "stdin with matching checksum", arg: "-", stdin: sourceTestWorkflow, sum: sha256Hex(sourceTestWorkflow)},
+		{name: "stdin with wrong checksum", arg: "-", stdin: sourceTestWorkflow, sum: sha256Hex("other"), wantErr: workflowChecksumErr},
+		{name: "https url", arg: srv.URL + "/workflow.yaml"},
+		{name: "https url with matching checksum", arg: srv.URL + "/workflow.yaml", sum: strings.ToUpper(sha256Hex(sourceTestWorkflow))},
+		{name: "https url not found", arg: srv.URL + "/missing.yaml", wantErr: workflowFetchErr},
+		{name: "plain http rejected", arg: "http://example.com/workflow.yaml", wantErr: workflowFetchErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := readWorkflowSource(tt.arg, tt.sum, strings.NewReader(tt.stdin), srv.Client())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("readWorkflowSource() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readWorkflowSource() unexpected error: %v", err)
+			}
+			if string(data) != sourceTestWorkflow {
+				t.Errorf("readWorkflowSource() = %q, want %q", data, sourceTestWorkflow)
+			}
+		})
+	}
+}
+
+func TestWorkflowSourceOptions(t *testing.T) {
+	opts, err := workflowSourceOptions("-", "", nil, strings.NewReader(sourceTestWorkflow), http.DefaultClient)
+	if err != nil {
+		t.Fatalf("workflowSourceOptions() unexpected error: %v", err)
+	}
+
+	r := &runner.Runner{}
+	for _, opt := range opts {
+		opt(r)
+	}
+	wf, err := r.LoadWorkflow("-")
+	if err != nil {
+		t.Fatalf("LoadWorkflow() unexpected error: %v", err)
+	}
+	if wf.Name != "remote" {
+		t.Errorf("wf.Name = %q, want %q", wf.Name, "remote")
+	}
+}
+
+func TestRunCmd_Stdin(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	cmd := makeRunCmd(runner.New)
+	out := new(strings.Builder)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetIn(strings.NewReader(sourceTestWorkflow))
+	cmd.SetArgs([]string{"-", "--no-history"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Workflow execution completed") {
+		t.Errorf("output = %q, want completion message", out.String())
+	}
+}
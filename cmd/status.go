@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/andre-koe/forge/internal/active"
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/spf13/cobra"
+)
+
+// activeRegistry returns the registry next to the workflow file, or in the current directory
+func activeRegistry(workflow string) *active.Registry {
+	if workflow == "" {
+		return active.NewRegistry(active.DirName)
+	}
+	return active.ForWorkflow(workflow)
+}
+
+func runStatus(reg *active.Registry, out io.Writer, now time.Time) error {
+	entries, err := reg.List()
+	if err != nil {
+		return fmt.Errorf("%w: %v", activeRunsErr, err)
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(out, "No runs in progress")
+		return nil
+	}
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RUN ID\tWORKFLOW\tPID\tRUNNING FOR\tCONCURRENCY GROUP\tMANAGED BY")
+	for _, e := range entries {
+		managedBy := "-"
+		if e.CancelURL != "" {
+			managedBy = "forge serve"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\n", e.ID, e.Workflow, e.PID, now.Sub(e.StartedAt).Round(time.Second), cmp.Or(e.Group, "-"), managedBy)
+	}
+	return tw.Flush()
+}
+
+func makeStatusCmd() *cobra.Command {
+	var workflow string
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the runs in progress",
+		Long: `Show the runs of forge run, watch, schedule and serve that are in progress. Runs register
+themselves in .forge/active/ next to the workflow file while they execute.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatus(activeRegistry(workflow), cmd.OutOrStdout(), time.Now())
+		},
+	}
+	cmd.Flags().StringVarP(&workflow, "workflow", "w", "", "workflow file whose runs to show (default: the current directory)")
+	_ = cmd.MarkFlagFilename("workflow", dsl.FileExtensions...)
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(makeStatusCmd())
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/active"
+)
+
+func TestRunStatus(t *testing.T) {
+	reg := active.NewRegistry(filepath.Join(t.TempDir(), "active"))
+	var out bytes.Buffer
+	if err := runStatus(reg, &out, time.Now()); err != nil {
+		t.Fatalf("runStatus() error: %v", err)
+	}
+	if out.String() != "No runs in progress\n" {
+		t.Errorf("runStatus() on an empty registry = %q", out.String())
+	}
+
+	now := time.Now()
+	for _, e := range []active.Entry{
+		{ID: "run-1", Workflow: "ci.yaml", PID: os.Getpid(), StartedAt: now.Add(-90 * time.Second)},
+		{ID: "run-2", Workflow: "deploy.yaml", PID: os.Getpid(), StartedAt: now.Add(-time.Second), CancelURL: "http://localhost:8080/runs/run-2", Group: "deploy-prod"},
+	} {
+		if err := reg.Add(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out.Reset()
+	if err := runStatus(reg, &out, now); err != nil {
+		t.Fatalf("runStatus() error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "run-1") || !strings.Contains(lines[1], "1m30s") || !strings.HasSuffix(lines[2], "forge serve") || !strings.Contains(lines[2], "deploy-prod") {
+		t.Errorf("unexpected status:\n%s", out.String())
+	}
+}
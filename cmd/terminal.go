@@ -0,0 +1,12 @@
+package cmd
+
+import "os"
+
+// isTerminal reports whether f is an interactive terminal that understands escape sequences
+func isTerminal(f *os.File) bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/andre-koe/forge/internal/wftest"
+	"github.com/spf13/cobra"
+)
+
+// runTest runs the test cases of fixtures against the workflow and prints their outcome; the
+// output of a run is shown when its case failed, or always when verbose
+func runTest(workflow, fixtures string, verbose bool, out io.Writer, newRunner runner.Factory) error {
+	if err := CheckFilePathExistAndIsNotEmpty(workflow); err != nil {
+		return err
+	}
+	if fixtures == "" {
+		fixtures = wftest.FixturesPath(workflow)
+	}
+	suite, err := wftest.Load(fixtures)
+	if err != nil {
+		return fmt.Errorf("%w: %v", fixturesErr, err)
+	}
+	results, err := suite.Run(workflow, newRunner)
+	if err != nil {
+		return fmt.Errorf("%w: %v", workflowTestErr, err)
+	}
+
+	failed := 0
+	for _, res := range results {
+		status := "PASS"
+		if !res.Passed() {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(out, "%s  %s\n", status, res.Name)
+		for _, f := range res.Failures {
+			fmt.Fprintf(out, "      %s\n", f)
+		}
+		if verbose || !res.Passed() {
+			for _, line := range strings.Split(strings.TrimRight(res.Output, "\n"), "\n") {
+				fmt.Fprintf(out, "      | %s\n", line)
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d failed", workflowTestErr, failed, len(results))
+	}
+	fmt.Fprintf(out, "ok  %d test(s) passed\n", len(results))
+	return nil
+}
+
+func makeTestCmd(newRunner runner.Factory) *cobra.Command {
+	var fixtures string
+	var verbose bool
+
+	cmd := &cobra.Command{
+		Use:   "test <workflow.yml>",
+		Short: "Test a workflow against fixtures without executing its commands",
+		Long: `Run a workflow once for every test case of its fixtures file, deploy.test.yml for
+deploy.yml unless --fixtures names another. The commands of the run are not executed:
+each has to match the next command a test case lists, after interpolation, and gets
+its canned output and exit code. Waits pass at once, notifications are not sent and
+approval steps are approved.
+
+  tests:
+    - name: release publishes the tag
+      vars: {tag: v1.2.0}
+      commands:
+        - run: [git, describe]
+          stdout: v1.2.0
+        - run: [publish, v1.2.0]
+      skipped: [build.notify]
+      status: success
+
+A case fails when the run executes other commands, leaves listed ones out, runs a
+step listed in skipped, lacks the step outputs listed in outputs or ends with
+another status.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTest(args[0], fixtures, verbose, cmd.OutOrStdout(), newRunner)
+		},
+	}
+	cmd.Flags().StringVar(&fixtures, "fixtures", "", "fixtures file (default: <workflow>.test.yml next to the workflow)")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "show the output of passing runs too")
+	_ = cmd.MarkFlagFilename("fixtures", "yaml", "yml")
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(makeTestCmd(runner.New))
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+func TestRunTest(t *testing.T) {
+	dir := t.TempDir()
+	workflow := filepath.Join(dir, "build.yml")
+	content := `name: build
+stages:
+  - name: build
+    steps:
+      - name: compile
+        type: exec
+        run: ["make", "all"]
+`
+	if err := os.WriteFile(workflow, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	passing := "tests:\n  - name: compiles\n    commands:\n      - run: [make, all]\n        stdout: done\n"
+	if err := os.WriteFile(filepath.Join(dir, "build.test.yml"), []byte(passing), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	failing := filepath.Join(dir, "failing.yml")
+	if err := os.WriteFile(failing, []byte("tests:\n  - name: compiles\n  - name: breaks\n    commands:\n      - run: [make, all]\n        exit_code: 2\n    status: failed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		workflow string
+		fixtures string
+		verbose  bool
+		wantErr  error
+		wantOut  []string
+	}{
+		{name: "companion fixtures", workflow: workflow, wantOut: []string{"PASS  compiles\n", "ok  1 test(s) passed"}},
+		{name: "verbose", workflow: workflow, verbose: true, wantOut: []string{"PASS  compiles\n", "      | [build/compile] done"}},
+		{
+			name: "failing case", workflow: workflow, fixtures: failing, wantErr: workflowTestErr,
+			wantOut: []string{"FAIL  compiles\n", `unexpected command ["make" "all"]`, "      | STEP 1.1: compile (exec)", "PASS  breaks\n"},
+		},
+		{name: "missing fixtures", workflow: workflow, fixtures: filepath.Join(dir, "missing.yml"), wantErr: fixturesErr},
+		{name: "missing workflow", workflow: filepath.Join(dir, "missing.yml"), wantErr: workflowNotFoundErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := runTest(tt.workflow, tt.fixtures, tt.verbose, &out, runner.New)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("runTest() error = %v, want %v", err, tt.wantErr)
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/pkg/version"
+)
+
+// reexecEnv is set for a forge started from the toolcache so it never re-executes again
+const reexecEnv = "FORGE_REEXEC"
+
+var forgeVersionErr = errors.New("unsupported forge version")
+
+// checkForgeVersion verifies that the running forge satisfies the workflow's requires.forge.
+// If it does not but $FORGE_TOOLCACHE holds a matching forge, the path of that binary is returned.
+func checkForgeVersion(workflow string) (string, error) {
+	if isVirtualWorkflow(workflow) {
+		return "", nil
+	}
+	// Unreadable or invalid workflows are reported when they are loaded
+	data, err := os.ReadFile(workflow)
+	if err != nil {
+		return "", nil
+	}
+	constraint, err := dsl.RequiredForge(data, workflow)
+	if err != nil || constraint == "" {
+		return "", nil
+	}
+	if ok, err := version.Satisfies(constraint); err != nil || ok {
+		return "", nil
+	}
+
+	verErr := &dsl.VersionError{Required: constraint, Running: version.Version}
+	dir := os.Getenv(dsl.ToolcacheEnv)
+	if dir == "" || os.Getenv(reexecEnv) != "" {
+		return "", fmt.Errorf("%w: %v", forgeVersionErr, verErr)
+	}
+	bin, err := findToolcacheForge(dir, constraint)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v (%v)", forgeVersionErr, verErr, err)
+	}
+	return bin, nil
+}
+
+// findToolcacheForge returns the newest forge in dir, laid out as <dir>/<version>/forge,
+// that satisfies the constraint
+func findToolcacheForge(dir, constraint string) (string, error) {
+	c, err := version.ParseConstraint(constraint)
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	name := "forge"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+
+	var best string
+	var bestVersion version.Semver
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		v, err := version.Parse(e.Name())
+		if err != nil || !c.Check(v) || (best != "" && v.Compare(bestVersion) <= 0) {
+			continue
+		}
+		bin := filepath.Join(dir, e.Name(), name)
+		if info, err := os.Stat(bin); err != nil || info.IsDir() {
+			continue
+		}
+		best, bestVersion = bin, v
+	}
+
+	if best == "" {
+		return "", fmt.Errorf("no forge matching %s in %s", constraint, dir)
+	}
+	return best, nil
+}
+
+// reexecForge runs the given forge binary with args, passing the standard streams through
+func reexecForge(bin string, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fmt.Fprintf(stdout, "Re-executing with %s\n", bin)
+
+	cmd := exec.Command(bin, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, stdout, stderr
+	cmd.Env = append(os.Environ(), reexecEnv+"=1")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %v", workflowExecutionErr, err)
+	}
+	return nil
+}
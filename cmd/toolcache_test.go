@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/pkg/version"
+)
+
+// writeToolcache creates <dir>/<version>/forge scripts that print their version and arguments
+func writeToolcache(t *testing.T, versions ...string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("toolcache scripts need a POSIX shell")
+	}
+	dir := t.TempDir()
+	for _, v := range versions {
+		if err := os.MkdirAll(filepath.Join(dir, v), 0o755); err != nil {
+			t.Fatalf("MkdirAll() error: %v", err)
+		}
+		script := "#!/bin/sh\necho forge " + v + " \"$@\" $FORGE_REEXEC\n"
+		if err := os.WriteFile(filepath.Join(dir, v, "forge"), []byte(script), 0o755); err != nil {
+			t.Fatalf("WriteFile() error: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestFindToolcacheForge(t *testing.T) {
+	dir := writeToolcache(t, "0.4.0", "0.5.1", "v0.6.0", "1.0.0")
+	if err := os.MkdirAll(filepath.Join(dir, "0.7.0"), 0o755); err != nil {
+		t.Fatalf("MkdirAll() error: %v", err)
+	}
+
+	tests := []struct {
+		constraint string
+		want       string
+		wantErr    bool
+	}{
+		{constraint: ">=0.5, <1.0", want: "v0.6.0"},
+		{constraint: "0.4", want: "0.4.0"},
+		{constraint: ">=0.7, <1.0", wantErr: true},
+		{constraint: ">=2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			got, err := findToolcacheForge(dir, tt.constraint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findToolcacheForge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != filepath.Join(dir, tt.want, "forge") {
+				t.Errorf("findToolcacheForge() = %q, want version %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckForgeVersion(t *testing.T) {
+	orig := version.Version
+	version.Version = "v0.4.0"
+	defer func() { version.Version = orig }()
+
+	cache := writeToolcache(t, "0.5.0")
+	workflow := filepath.Join(t.TempDir(), "workflow.yaml")
+	write := func(constraint string) {
+		content := "name: pinned\nrequires:\n  forge: \"" + constraint + "\"\nstages: []\n"
+		if err := os.WriteFile(workflow, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile() error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		constraint string
+		toolcache  string
+		reexec     string
+		wantBin    string
+		wantErr    error
+	}{
+		{name: "satisfied", constraint: ">=0.4"},
+		{name: "unsatisfied without toolcache", constraint: ">=0.5", wantErr: forgeVersionErr},
+		{name: "unsatisfied with toolcache", constraint: ">=0.5", toolcache: cache, wantBin: filepath.Join(cache, "0.5.0", "forge")},
+		{name: "no match in toolcache", constraint: ">=0.6", toolcache: cache, wantErr: forgeVersionErr},
+		{name: "already re-executed", constraint: ">=0.5", toolcache: cache, reexec: "1", wantErr: forgeVersionErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(dsl.ToolcacheEnv, tt.toolcache)
+			t.Setenv(reexecEnv, tt.reexec)
+			write(tt.constraint)
+
+			bin, err := checkForgeVersion(workflow)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("checkForgeVersion() error = %v, want %v", err, tt.wantErr)
+			}
+			if bin != tt.wantBin {
+				t.Errorf("checkForgeVersion() = %q, want %q", bin, tt.wantBin)
+			}
+		})
+	}
+}
+
+func TestReexecForge(t *testing.T) {
+	dir := writeToolcache(t, "0.5.0")
+
+	out := new(bytes.Buffer)
+	err := reexecForge(filepath.Join(dir, "0.5.0", "forge"), []string{"run", "w.yml"}, nil, out, out)
+	if err != nil {
+		t.Fatalf("reexecForge() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "forge 0.5.0 run w.yml 1") {
+		t.Errorf("output = %q, want re-executed forge with args and %s set", out.String(), reexecEnv)
+	}
+}
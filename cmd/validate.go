@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/spf13/cobra"
+)
+
+// runValidate loads and validates each workflow and prints its warnings; with strict, warnings
+// fail the validation like errors
+func runValidate(workflows []string, out io.Writer, load func(string) (*dsl.Workflow, error), strict bool) error {
+	failed := 0
+	for _, workflow := range workflows {
+		wf, err := loadChecked(workflow, load)
+		if err != nil {
+			fmt.Fprintf(out, "✗ %s: %v\n", workflow, err)
+			failed++
+			continue
+		}
+		warnings := wf.Warnings()
+		for _, w := range warnings {
+			fmt.Fprintf(out, "Warning: %s: %s\n", workflow, w)
+		}
+		if strict && len(warnings) > 0 {
+			fmt.Fprintf(out, "✗ %s: %d warning(s) with --strict-warnings\n", workflow, len(warnings))
+			failed++
+			continue
+		}
+		fmt.Fprintf(out, "✓ %s is valid\n", workflow)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d workflow(s)", workflowInvalidErr, failed, len(workflows))
+	}
+	return nil
+}
+
+// loadChecked loads the workflow after checking that its file exists
+func loadChecked(workflow string, load func(string) (*dsl.Workflow, error)) (*dsl.Workflow, error) {
+	if err := CheckFilePathExistAndIsNotEmpty(workflow); err != nil {
+		return nil, err
+	}
+	return load(workflow)
+}
+
+func makeValidateCmd(load func(string) (*dsl.Workflow, error)) *cobra.Command {
+	var strict bool
+	cmd := &cobra.Command{
+		Use:   "validate [workflow...]",
+		Short: "Check workflows for errors and warnings without running them",
+		Long: `Load and validate workflows without running them. Errors such as unknown keys or
+duplicate stage names make a workflow invalid; warnings are findings that are likely
+mistakes, such as very long sleeps, exec steps without a timeout and commands run by a
+path relative to the directory forge is started in.
+
+--strict-warnings treats warnings as errors, e.g. in CI; forge dry-run accepts it as well.`,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeWorkflowFiles,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runValidate(args, cmd.OutOrStdout(), load, strict)
+		},
+	}
+	cmd.Flags().BoolVar(&strict, "strict-warnings", false, "fail on warnings as on errors")
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(makeValidateCmd(dsl.LoadWorkflowFromFile))
+}
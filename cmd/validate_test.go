@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunValidate(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"clean.yml":     "name: clean\ndefaults:\n  timeout: 5m\nstages:\n  - name: build\n    steps:\n      - name: compile\n        type: exec\n        run: [go, build]\n",
+		"warning.yml":   "name: warning\nstages:\n  - name: wait\n    steps:\n      - name: soak\n        type: sleep\n        seconds: 7200\n",
+		"duplicate.yml": "name: duplicate\nstages:\n  - name: build\n    steps:\n      - name: a\n        type: sleep\n        seconds: 1\n  - name: build\n    steps:\n      - name: b\n        type: sleep\n        seconds: 1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	tests := []struct {
+		name      string
+		workflows []string
+		strict    bool
+		wantOut   []string
+		wantErr   bool
+	}{
+		{name: "valid", workflows: []string{path("clean.yml")}, wantOut: []string{"✓ " + path("clean.yml") + " is valid"}},
+		{name: "warnings", workflows: []string{path("warning.yml")}, wantOut: []string{"Warning: " + path("warning.yml") + ": step wait.soak sleeps for 2h0m0s", "is valid"}},
+		{name: "strict", workflows: []string{path("clean.yml"), path("warning.yml")}, strict: true, wantOut: []string{"✗ " + path("warning.yml") + ": 1 warning(s) with --strict-warnings"}, wantErr: true},
+		{name: "invalid", workflows: []string{path("duplicate.yml"), path("missing.yml")}, wantOut: []string{"name is already used by another stage", "✗ " + path("missing.yml")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := runValidate(tt.workflows, &out, dsl.LoadWorkflowFromFile, tt.strict)
+			if got := errors.Is(err, workflowInvalidErr); got != tt.wantErr {
+				t.Fatalf("runValidate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
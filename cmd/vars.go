@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// parseVars merges the --var-file files and --var key=value assignments in order; assignments win over files
+func parseVars(assignments, files []string) (map[string]string, error) {
+	vars := make(map[string]string)
+	for _, f := range files {
+		fileVars, err := dsl.ReadVarFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", varFileErr, err)
+		}
+		maps.Copy(vars, fileVars)
+	}
+	for _, a := range assignments {
+		name, value, ok := strings.Cut(a, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q: expected key=value", invalidVarErr, a)
+		}
+		if err := dsl.ValidateVarName(name); err != nil {
+			return nil, fmt.Errorf("%w: %v", invalidVarErr, err)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// parseEnv parses --env KEY=VALUE assignments; later assignments win
+func parseEnv(assignments []string) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, a := range assignments {
+		name, value, ok := strings.Cut(a, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%w: %q: expected KEY=VALUE", invalidEnvErr, a)
+		}
+		env[name] = value
+	}
+	return env, nil
+}
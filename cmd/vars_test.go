@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+func TestParseVars(t *testing.T) {
+	dir := t.TempDir()
+	varFile := filepath.Join(dir, "vars.yaml")
+	if err := os.WriteFile(varFile, []byte("image: app\ntag: latest\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		assignments []string
+		files       []string
+		want        map[string]string
+		wantErr     error
+	}{
+		{
+			name:        "assignments",
+			assignments: []string{"tag=v1", "flags=a=b,c"},
+			want:        map[string]string{"tag": "v1", "flags": "a=b,c"},
+		},
+		{
+			name:        "assignments win over files",
+			assignments: []string{"tag=v2"},
+			files:       []string{varFile},
+			want:        map[string]string{"image": "app", "tag": "v2"},
+		},
+		{name: "missing value", assignments: []string{"tag"}, wantErr: invalidVarErr},
+		{name: "invalid name", assignments: []string{"image-tag=x"}, wantErr: invalidVarErr},
+		{name: "missing file", files: []string{filepath.Join(dir, "missing.yaml")}, wantErr: varFileErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseVars(tt.assignments, tt.files)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("parseVars() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseVars() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseVars() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEnv(t *testing.T) {
+	got, err := parseEnv([]string{"REGION=eu", "FLAGS=a=b", "REGION=us", "EMPTY="})
+	if err != nil {
+		t.Fatalf("parseEnv() error: %v", err)
+	}
+	if want := map[string]string{"REGION": "us", "FLAGS": "a=b", "EMPTY": ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseEnv() = %v, want %v", got, want)
+	}
+	for _, a := range []string{"REGION", "=eu"} {
+		if _, err := parseEnv([]string{a}); !errors.Is(err, invalidEnvErr) {
+			t.Errorf("parseEnv(%q) error = %v, want %v", a, err, invalidEnvErr)
+		}
+	}
+}
+
+func TestDryRunCmd_Vars(t *testing.T) {
+	workflowPath := filepath.Join(t.TempDir(), "workflow.yml")
+	workflowContent := []byte(`name: vars
+vars:
+  tag: latest
+stages:
+  - name: build
+    steps:
+      - name: image
+        type: exec
+        run: ["docker", "build", "-t", "app:${{ vars.tag }}"]
+`)
+	if err := os.WriteFile(workflowPath, workflowContent, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := makeDryRunCmd(runner.NewRunner)
+	out := new(bytes.Buffer)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs([]string{workflowPath, "--var", "tag=v1.2.3"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Would execute command: [docker build -t app:v1.2.3]") {
+		t.Errorf("dry-run output does not use the --var override:\n%s", out.String())
+	}
+
+	cmd.SetArgs([]string{workflowPath, "--var", "tga=v1"})
+	if err := cmd.Execute(); !errors.Is(err, workflowExecutionErr) {
+		t.Errorf("Execute() with an undeclared variable error = %v, want workflowExecutionErr", err)
+	}
+}
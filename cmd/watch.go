@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/andre-koe/forge/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+// runWatch runs the workflow once and again for every batch of changed files until changes is
+// closed or ctx is done. A new batch cancels the run still in flight.
+func runWatch(ctx context.Context, workflow string, out io.Writer, newRunner runner.Factory, changes <-chan []string, opts ...runner.Option) error {
+	base, err := runBaseOptions(workflow)
+	if err != nil {
+		return err
+	}
+
+	var files []string
+	for {
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func(files []string) {
+			defer close(done)
+			runOpts := slices.Concat(base, opts, []runner.Option{runner.WithOut(out), runner.WithContext(runCtx), runner.WithChangedFiles(files)})
+			r, err := newRunner(workflow, runOpts...)
+			if err != nil {
+				fmt.Fprintf(out, "\n%v: %v\n", runnerCreationErr, err)
+			} else if err := r.Run(); errors.Is(err, runner.ErrCancelled) {
+				fmt.Fprintln(out, "\nRun cancelled")
+				return
+			} else if err != nil {
+				fmt.Fprintf(out, "\nRun failed: %v\n", err)
+			} else {
+				fmt.Fprintln(out, "\nRun succeeded")
+			}
+			fmt.Fprintln(out, "Watching for changes...")
+		}(files)
+
+		var ok bool
+		select {
+		case files, ok = <-changes:
+		case <-ctx.Done():
+		}
+		cancel()
+		<-done
+		if !ok {
+			return nil
+		}
+		fmt.Fprintf(out, "\nChanged: %s\n", strings.Join(files, ", "))
+	}
+}
+
+func makeWatchCmd(newRunner runner.Factory) *cobra.Command {
+	var paths, stages []string
+	var debounce time.Duration
+	var metricsAddr string
+
+	cmd := &cobra.Command{
+		Use:   "watch [workflow]",
+		Short: "Re-run a workflow whenever files change",
+		Long: `Run a workflow, then watch the files below the workflow's directory and run it
+again whenever they change. Changes within --debounce are batched into one run, and
+a change while a run is in progress cancels it before starting the next.
+
+--path limits the watched files to glob patterns relative to the workflow's directory
+('**' matches any directories). Stages with changes: filters only re-run when one of
+the changed files matches them, so only the affected stages of a monorepo rebuild.
+.git and .forge are never watched. With --metrics-addr the metrics of the runs are
+served on /metrics in the Prometheus text format.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workflow := args[0]
+			if isVirtualWorkflow(workflow) {
+				return fmt.Errorf("%w: a workflow read from stdin or a URL", watchErr)
+			}
+			w, err := watch.New(filepath.Dir(workflow), paths, debounce)
+			if err != nil {
+				return fmt.Errorf("%w: %v", watchErr, err)
+			}
+			defer w.Close()
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			changes := make(chan []string)
+			watchDone := make(chan error, 1)
+			go func() {
+				watchDone <- w.Run(ctx, changes)
+				close(changes)
+			}()
+
+			var opts []runner.Option
+			if len(stages) > 0 {
+				opts = append(opts, runner.WithStages(stages...))
+			}
+			newRunner := newRunner
+			if metricsAddr != "" {
+				ln, err := net.Listen("tcp", metricsAddr)
+				if err != nil {
+					return fmt.Errorf("%w: %v", watchErr, err)
+				}
+				newRunner = serveMetrics(ctx, ln, newRunner, cmd.OutOrStdout())
+			}
+			if err := runWatch(ctx, workflow, cmd.OutOrStdout(), newRunner, changes, opts...); err != nil {
+				return err
+			}
+			if err := <-watchDone; err != nil {
+				return fmt.Errorf("%w: %v", watchErr, err)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(&paths, "path", nil, "watch only files matching this glob, e.g. 'src/**' (repeatable, default: all files)")
+	cmd.Flags().DurationVar(&debounce, "debounce", watch.DefaultDebounce, "wait this long for further changes before re-running")
+	cmd.Flags().StringSliceVar(&stages, "stage", nil, "run only the named stages (repeatable)")
+	cmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "address to serve Prometheus metrics of the runs on /metrics, e.g. 127.0.0.1:9100; none by default")
+	cmd.ValidArgsFunction = completeWorkflowFiles
+	_ = cmd.RegisterFlagCompletionFunc("stage", completeStages)
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(makeWatchCmd(runner.New))
+}
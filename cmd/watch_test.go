@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+// syncBuffer is written by the run goroutines of runWatch
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRunWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "workflow.yaml")
+	if err := os.WriteFile(path, []byte(sourceTestWorkflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first run blocks until the change cancels it; the second one runs to completion
+	var mu sync.Mutex
+	runs := 0
+	started, ran := make(chan struct{}), make(chan struct{})
+	newRunner := func(workflow string, opts ...runner.Option) (runner.Interface, error) {
+		r, err := runner.NewRunner(workflow, opts...)
+		if err != nil {
+			return nil, err
+		}
+		r.RunCmd = func(argv []string) error {
+			mu.Lock()
+			runs++
+			n := runs
+			mu.Unlock()
+			if n == 1 {
+				close(started)
+				<-r.Context.Done()
+				return r.Context.Err()
+			}
+			close(ran)
+			return nil
+		}
+		return r, nil
+	}
+
+	changes := make(chan []string)
+	out := new(syncBuffer)
+	done := make(chan error)
+	go func() { done <- runWatch(context.Background(), path, out, newRunner, changes, runner.WithHistory(nil)) }()
+
+	<-started
+	changes <- []string{"main.go", "util.go"}
+	<-ran
+	close(changes)
+	if err := <-done; err != nil {
+		t.Fatalf("runWatch() error: %v", err)
+	}
+
+	if runs != 2 {
+		t.Errorf("runs = %d, want 2", runs)
+	}
+	for _, want := range []string{"Run cancelled", "Changed: main.go, util.go", "Run succeeded", "Watching for changes"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunWatch_MissingWorkflow(t *testing.T) {
+	err := runWatch(context.Background(), "does-not-exist.yaml", new(bytes.Buffer), runner.New, nil)
+	if !errors.Is(err, workflowNotFoundErr) {
+		t.Errorf("runWatch() error = %v, want %v", err, workflowNotFoundErr)
+	}
+}
@@ -0,0 +1,148 @@
+// Package active tracks the runs in progress so they can be listed and cancelled from
+// another forge process.
+package active
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/andre-koe/forge/internal/history"
+)
+
+// DirName is the directory, relative to the workflow file, where runs in progress are registered
+const DirName = ".forge/active"
+
+// ErrNotFound is returned for runs that are not in progress
+var ErrNotFound = errors.New("no run in progress")
+
+// Entry describes a run in progress
+type Entry struct {
+	ID       string `json:"id"`
+	Workflow string `json:"workflow"`
+	// PID is the forge process executing the run
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	// CancelURL is set for runs of a forge server, which are cancelled with a DELETE request instead of a signal
+	CancelURL string `json:"cancel_url,omitempty"`
+	// Group is the concurrency group of the run, whose runs do not overlap
+	Group string `json:"group,omitempty"`
+}
+
+// Cancel asks the forge process executing the run to cancel it: runs of a server through its
+// API, other runs with SIGTERM
+func (e *Entry) Cancel(ctx context.Context, client *http.Client) error {
+	if e.CancelURL == "" {
+		return Terminate(e.PID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, e.CancelURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("%s: %s", e.CancelURL, resp.Status)
+	}
+	return nil
+}
+
+// Registry keeps one file per run in progress
+type Registry struct {
+	Dir string
+}
+
+func NewRegistry(dir string) *Registry {
+	return &Registry{Dir: dir}
+}
+
+// ForWorkflow returns the registry next to the run history of the given workflow file
+func ForWorkflow(workflow string) *Registry {
+	return NewRegistry(filepath.Join(filepath.Dir(history.ForWorkflow(workflow).Dir), filepath.Base(DirName)))
+}
+
+// Add registers a run
+func (r *Registry) Add(e Entry) error {
+	if err := os.MkdirAll(r.Dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path(e.ID), data, 0o644)
+}
+
+// Remove unregisters a run
+func (r *Registry) Remove(id string) error {
+	if err := os.Remove(r.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Get returns the run with the given ID if it is still in progress
+func (r *Registry) Get(id string) (*Entry, error) {
+	if strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	data, err := os.ReadFile(r.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("%s: %w", r.path(id), err)
+	}
+	if !Alive(e.PID) {
+		// The process exited without unregistering, e.g. because it was killed
+		_ = r.Remove(id)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return &e, nil
+}
+
+// List returns the runs in progress, oldest first; entries of processes that no longer exist are removed
+func (r *Registry) List() ([]*Entry, error) {
+	files, err := os.ReadDir(r.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for _, f := range files {
+		id, ok := strings.CutSuffix(f.Name(), ".json")
+		if !ok {
+			continue
+		}
+		e, err := r.Get(id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	slices.SortFunc(entries, func(a, b *Entry) int { return a.StartedAt.Compare(b.StartedAt) })
+	return entries, nil
+}
+
+func (r *Registry) path(id string) string {
+	return filepath.Join(r.Dir, id+".json")
+}
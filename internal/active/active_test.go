@@ -0,0 +1,87 @@
+package active
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(filepath.Join(t.TempDir(), "active"))
+	if entries, err := r.List(); err != nil || len(entries) != 0 {
+		t.Fatalf("List() on an empty registry = %v, %v", entries, err)
+	}
+
+	// A process that has exited stands in for a forge process killed without cleaning up
+	dead := exec.Command("true")
+	if err := dead.Run(); err != nil {
+		t.Skip("cannot start a process:", err)
+	}
+
+	now := time.Now()
+	for _, e := range []Entry{
+		{ID: "b", Workflow: "ci.yaml", PID: os.Getpid(), StartedAt: now},
+		{ID: "a", Workflow: "ci.yaml", PID: os.Getpid(), StartedAt: now.Add(-time.Minute)},
+		{ID: "stale", Workflow: "ci.yaml", PID: dead.Process.Pid, StartedAt: now},
+	} {
+		if err := r.Add(e); err != nil {
+			t.Fatalf("Add() error: %v", err)
+		}
+	}
+
+	entries, err := r.List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != "a" || entries[1].ID != "b" {
+		t.Errorf("List() = %+v, want a and b", entries)
+	}
+	if _, err := os.Stat(r.path("stale")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("List() kept the entry of an exited process")
+	}
+
+	if err := r.Remove("a"); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+	if _, err := r.Get("a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a removed run error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := r.Get("../b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() with a path error = %v, want %v", err, ErrNotFound)
+	}
+	if e, err := r.Get("b"); err != nil || e.Workflow != "ci.yaml" {
+		t.Errorf("Get() = %+v, %v", e, err)
+	}
+}
+
+func TestEntry_Cancel(t *testing.T) {
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if r.URL.Path != "/runs/run-1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	e := &Entry{ID: "run-1", CancelURL: srv.URL + "/runs/run-1"}
+	if err := e.Cancel(context.Background(), srv.Client()); err != nil {
+		t.Errorf("Cancel() error: %v", err)
+	}
+	e = &Entry{ID: "run-2", CancelURL: srv.URL + "/runs/run-2"}
+	if err := e.Cancel(context.Background(), srv.Client()); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Cancel() of an unknown run error = %v, want 404", err)
+	}
+	if len(methods) != 2 || methods[0] != http.MethodDelete {
+		t.Errorf("requests = %v, want two DELETE", methods)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !unix
+
+package active
+
+import "os"
+
+// Alive reports whether a process with the given PID exists
+func Alive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
+
+// Terminate stops the process; without signals this platform cannot cancel cooperatively
+func Terminate(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
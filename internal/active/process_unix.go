@@ -0,0 +1,19 @@
+//go:build unix
+
+package active
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Alive reports whether a process with the given PID exists
+func Alive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// Terminate asks the process to stop; forge cancels its run and the steps' processes on SIGTERM
+func Terminate(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
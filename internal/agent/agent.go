@@ -0,0 +1,179 @@
+// Package agent runs the stages a forge server sends to it: an agent joins the server, waits
+// for stages whose runs_on labels it has, runs them from its own copy of the workflows and
+// streams their output back.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/andre-koe/forge/internal/server"
+)
+
+const (
+	// pollWait is how long one request for work waits for a stage
+	pollWait = 30 * time.Second
+	// heartbeat is how often an agent tells the server it still runs a stage
+	heartbeat = 5 * time.Second
+	// retryDelay is how long an agent waits after the server could not be reached
+	retryDelay = 5 * time.Second
+)
+
+// Agent runs stages for a forge server
+type Agent struct {
+	Client *server.Client
+	Name   string
+	Labels []string
+	// Root is the directory holding the agent's copy of the server's workflows
+	Root      string
+	NewRunner runner.Factory
+	// RunOptions returns the runner options used for every stage of a workflow, e.g. its history store
+	RunOptions func(workflow string) ([]runner.Option, error)
+	// Out receives what the agent does, not the output of the stages
+	Out io.Writer
+}
+
+// Run joins the server and runs the stages it sends until ctx is done, then leaves the server
+func (a *Agent) Run(ctx context.Context) error {
+	id, err := a.join(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		leaveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), retryDelay)
+		defer cancel()
+		_ = a.Client.Leave(leaveCtx, id)
+	}()
+
+	for ctx.Err() == nil {
+		as, err := a.Client.NextAssignment(ctx, id, pollWait)
+		switch {
+		case ctx.Err() != nil:
+		case errors.Is(err, server.ErrAgentNotFound):
+			// The server restarted or lost track of the agent
+			if id, err = a.join(ctx); err != nil {
+				return err
+			}
+		case err != nil:
+			fmt.Fprintf(a.Out, "Warning: asking %s for work failed, retrying: %v\n", a.Client.BaseURL, err)
+			sleep(ctx, retryDelay)
+		case as != nil:
+			a.run(ctx, id, as)
+		}
+	}
+	return nil
+}
+
+// join registers the agent with the server
+func (a *Agent) join(ctx context.Context) (string, error) {
+	agent, err := a.Client.Join(ctx, server.AgentRequest{Name: a.Name, Labels: a.Labels})
+	if err != nil {
+		return "", fmt.Errorf("joining %s: %w", a.Client.BaseURL, err)
+	}
+	fmt.Fprintf(a.Out, "Joined %s as agent %s with labels %v\n", a.Client.BaseURL, a.Name, a.Labels)
+	return agent.ID, nil
+}
+
+// run runs the stage of an assignment, streaming its output to the server, and reports the outcome
+func (a *Agent) run(ctx context.Context, id string, as *server.Assignment) {
+	runID := history.NewID(time.Now())
+	fmt.Fprintf(a.Out, "Running stage '%s' of %s for run %s as run %s\n", as.Stage, as.Workflow, as.RunID, runID)
+	err := a.runStage(ctx, id, as, runID)
+	result := server.AssignmentResult{}
+	if err != nil {
+		result.Error = err.Error()
+		fmt.Fprintf(a.Out, "Stage '%s' of run %s failed: %v\n", as.Stage, as.RunID, err)
+	} else {
+		fmt.Fprintf(a.Out, "Stage '%s' of run %s succeeded\n", as.Stage, as.RunID)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	if err := a.Client.Finish(ctx, id, as.ID, result); err != nil && !errors.Is(err, server.ErrAgentNotFound) {
+		fmt.Fprintf(a.Out, "Warning: failed to report the outcome of run %s: %v\n", as.RunID, err)
+	}
+}
+
+func (a *Agent) runStage(ctx context.Context, id string, as *server.Assignment, runID string) error {
+	if !filepath.IsLocal(filepath.FromSlash(as.Workflow)) {
+		return fmt.Errorf("workflow %q is not inside the agent's directory", as.Workflow)
+	}
+	workflow := filepath.Join(a.Root, filepath.FromSlash(as.Workflow))
+	opts, err := a.RunOptions(workflow)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pr, pw := io.Pipe()
+	sent := make(chan error, 1)
+	go func() {
+		err := a.Client.SendLogs(ctx, id, as.ID, pr)
+		// Keep the stage running if the server stops reading its output
+		_, _ = io.Copy(io.Discard, pr)
+		sent <- err
+	}()
+	go a.watch(ctx, cancel, id, as.ID)
+
+	fmt.Fprintf(pw, "Recording the stage as run %s of agent %s\n", runID, a.Name)
+	opts = append(opts,
+		runner.WithOut(pw),
+		runner.WithProcessOutput(pw, pw),
+		runner.WithContext(ctx),
+		runner.WithRunID(runID),
+		runner.WithStages(as.Stage),
+		runner.WithOnAgent(),
+	)
+	if len(as.Vars) > 0 {
+		opts = append(opts, runner.WithVars(as.Vars))
+	}
+	if as.Profile != "" {
+		opts = append(opts, runner.WithProfile(as.Profile))
+	}
+	r, err := a.NewRunner(workflow, opts...)
+	if err == nil {
+		err = r.Run()
+	}
+	if err != nil {
+		fmt.Fprintf(pw, "Error: %v\n", err)
+	}
+	pw.Close()
+	if sendErr := <-sent; sendErr != nil && ctx.Err() == nil {
+		fmt.Fprintf(a.Out, "Warning: failed to send the output of run %s: %v\n", as.RunID, sendErr)
+	}
+	return err
+}
+
+// watch tells the server that the agent still runs the assignment and cancels the stage once
+// the server no longer waits for it
+func (a *Agent) watch(ctx context.Context, cancel context.CancelFunc, id, assignment string) {
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.Client.Assignment(ctx, id, assignment); errors.Is(err, server.ErrAgentNotFound) {
+				fmt.Fprintf(a.Out, "Cancelling the stage, the server no longer waits for it\n")
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
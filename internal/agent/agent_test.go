@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/runner"
+	"github.com/andre-koe/forge/internal/server"
+)
+
+// recorder returns a runner factory for a workflow whose second stage runs on a gpu agent, and
+// the commands its runners ran
+func recorder() (runner.Factory, func() []string) {
+	var mu sync.Mutex
+	var calls []string
+	newRunner := func(workflow string, opts ...runner.Option) (runner.Interface, error) {
+		r, err := runner.NewRunner(workflow, opts...)
+		if err != nil {
+			return nil, err
+		}
+		r.LoadWorkflow = func(string) (*dsl.Workflow, error) {
+			return &dsl.Workflow{Name: "ml", Vars: map[string]string{"model": "small"}, Stages: []dsl.Stage{
+				{Name: "build", Steps: []dsl.Step{{Name: "compile", Type: dsl.StepTypeExec, Run: dsl.Command{"make"}}}},
+				{Name: "train", RunsOn: &dsl.RunsOn{Agent: []string{"gpu"}}, Steps: []dsl.Step{
+					{Name: "fit", Type: dsl.StepTypeExec, Run: dsl.Command{"train", "${{ vars.model }}"}},
+				}},
+			}}, nil
+		}
+		r.RunCmd = func(argv []string) error {
+			mu.Lock()
+			calls = append(calls, strings.Join(argv, " "))
+			mu.Unlock()
+			return nil
+		}
+		return r, nil
+	}
+	return newRunner, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(calls)
+	}
+}
+
+func TestAgent_Run(t *testing.T) {
+	newRunner, calls := recorder()
+	s := server.New(context.Background(), t.TempDir(), newRunner)
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a := &Agent{
+		Client:     server.NewClient(ts.URL),
+		Name:       "gpu-1",
+		Labels:     []string{"gpu"},
+		Root:       t.TempDir(),
+		NewRunner:  newRunner,
+		RunOptions: func(string) ([]runner.Option, error) { return nil, nil },
+		Out:        io.Discard,
+	}
+	stopped := make(chan error, 1)
+	go func() { stopped <- a.Run(ctx) }()
+	for len(s.Agents()) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	run, err := s.Start(server.RunRequest{Workflow: "ml.yaml", Inputs: map[string]string{"model": "large"}})
+	if err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	s.Wait()
+	if got, _ := s.Get(run.ID); got.Status != server.StatusSucceeded {
+		t.Errorf("run status = %s (%s), want succeeded", got.Status, got.Error)
+	}
+	if got, want := calls(), []string{"make", "train large"}; !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+	var logs strings.Builder
+	if err := server.NewClient(ts.URL).Logs(context.Background(), run.ID, &logs); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Running stage 'train' on agent gpu-1\n", "STEP 2.1: fit (exec)\n", "=== STAGE 2 COMPLETED ==="} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs missing %q:\n%s", want, logs.String())
+		}
+	}
+
+	cancel()
+	if err := <-stopped; err != nil {
+		t.Errorf("Run() error: %v", err)
+	}
+	if agents := s.Agents(); len(agents) != 0 {
+		t.Errorf("agents after the agent stopped = %+v, want none", agents)
+	}
+}
+
+func TestAgent_RunStage_OutsideRoot(t *testing.T) {
+	newRunner, calls := recorder()
+	a := &Agent{Client: server.NewClient("http://127.0.0.1:0"), Root: t.TempDir(), NewRunner: newRunner, Out: io.Discard}
+	err := a.runStage(context.Background(), "agent", &server.Assignment{Workflow: "../ml.yaml", Stage: "train"}, "run")
+	if err == nil || !strings.Contains(err.Error(), "is not inside the agent's directory") {
+		t.Errorf("runStage() error = %v, want the workflow refused", err)
+	}
+	if len(calls()) != 0 {
+		t.Errorf("commands = %q, want none", calls())
+	}
+}
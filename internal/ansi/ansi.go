@@ -0,0 +1,121 @@
+// Package ansi removes terminal escape sequences from the output of child processes, so that
+// colors and cursor movement do not end up in log files, reports and run history.
+package ansi
+
+import (
+	"io"
+	"strings"
+)
+
+const esc = 0x1b
+
+// state is where a Writer is inside an escape sequence
+type state int
+
+const (
+	text state = iota
+	escape
+	intermediate
+	csi
+	str
+	strEscape
+)
+
+// Writer copies text to an underlying writer without escape sequences; sequences split
+// across writes are removed as well
+type Writer struct {
+	w     io.Writer
+	state state
+	buf   []byte
+}
+
+// NewWriter returns a Writer writing to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (a *Writer) Write(p []byte) (int, error) {
+	a.buf = a.strip(a.buf[:0], p)
+	if len(a.buf) == 0 {
+		return len(p), nil
+	}
+	if _, err := a.w.Write(a.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// strip appends the text of p outside escape sequences to dst
+func (a *Writer) strip(dst, p []byte) []byte {
+	for _, c := range p {
+		switch a.state {
+		case text:
+			if c == esc {
+				a.state = escape
+				continue
+			}
+			dst = append(dst, c)
+		case escape:
+			switch {
+			case c == '[':
+				a.state = csi
+			case c == ']' || c == 'P' || c == 'X' || c == '^' || c == '_':
+				// OSC, DCS, SOS, PM and APC strings end with BEL or ESC \
+				a.state = str
+			case c >= 0x20 && c <= 0x2f:
+				a.state = intermediate
+			default:
+				a.state = text
+			}
+		case intermediate:
+			if c < 0x20 || c > 0x2f {
+				a.state = text
+			}
+		case csi:
+			if c >= 0x40 && c <= 0x7e {
+				a.state = text
+			}
+		case str:
+			switch c {
+			case 0x07:
+				a.state = text
+			case esc:
+				a.state = strEscape
+			}
+		case strEscape:
+			a.state = text
+			if c != '\\' {
+				a.state = str
+			}
+		}
+	}
+	return dst
+}
+
+// Strip returns s without escape sequences
+func Strip(s string) string {
+	if !strings.ContainsRune(s, esc) {
+		return s
+	}
+	return string(new(Writer).strip(nil, []byte(s)))
+}
+
+// Sanitize returns s without escape sequences and control characters other than tab and
+// newline, with invalid UTF-8 replaced by U+FFFD, ready for JSON, HTML or XML documents
+func Sanitize(s string) string {
+	s = strings.ToValidUTF8(Strip(s), "�")
+	return strings.Map(func(r rune) rune {
+		if (r < 0x20 && r != '\t' && r != '\n') || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// SanitizeLines applies Sanitize to every line
+func SanitizeLines(lines []string) []string {
+	for i, line := range lines {
+		lines[i] = Sanitize(line)
+	}
+	return lines
+}
@@ -0,0 +1,59 @@
+package ansi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStrip(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "ok\tdone\n", want: "ok\tdone\n"},
+		{name: "colors", in: "\x1b[1;31mFAIL\x1b[0m TestA", want: "FAIL TestA"},
+		{name: "cursor movement", in: "50%\x1b[2K\x1b[1G100%", want: "50%100%"},
+		{name: "title with BEL", in: "\x1b]0;building\x07done", want: "done"},
+		{name: "hyperlink with ST", in: "\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\", want: "link"},
+		{name: "charset selection", in: "\x1b(Bbox", want: "box"},
+		{name: "keypad mode", in: "\x1b=menu", want: "menu"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Strip(tt.in); got != tt.want {
+				t.Errorf("Strip(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	for _, chunk := range []string{"\x1b[3", "2mok\x1b", "[0m\n\x1b]0;ti", "tle\x07next\n"} {
+		if n, err := w.Write([]byte(chunk)); err != nil || n != len(chunk) {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+	if got, want := out.String(), "ok\nnext\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "\x1b[32mok\x1b[0m", want: "ok"},
+		{in: "bad \xff byte", want: "bad � byte"},
+		{in: "bell\x07 and\x00 nul\r", want: "bell and nul"},
+		{in: "tab\tand\nnewline", want: "tab\tand\nnewline"},
+	}
+	for _, tt := range tests {
+		if got := Sanitize(tt.in); got != tt.want {
+			t.Errorf("Sanitize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Package artifact keeps files produced by a stage in a per-run directory so later stages,
+// or later runs, can restore them.
+package artifact
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/snapshot"
+)
+
+// DirName is the directory, relative to the workflow file, where artifacts are kept
+const DirName = ".forge/artifacts"
+
+// ErrNotFound is returned when a run or stage has no stored artifacts
+var ErrNotFound = errors.New("no artifacts")
+
+// Artifact is a file stored for a stage of a run
+type Artifact struct {
+	Stage string
+	// Path is the slash-separated path relative to the working directory of the run
+	Path string
+	Size int64
+}
+
+// Store keeps artifacts in <Dir>/<run-id>/<stage>/<path>
+type Store struct {
+	Dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// ForWorkflow returns the store next to the run history of the given workflow file
+func ForWorkflow(workflow string) *Store {
+	return NewStore(filepath.Join(filepath.Dir(history.ForWorkflow(workflow).Dir), filepath.Base(DirName)))
+}
+
+// ValidatePattern reports patterns that are not relative globs inside the working directory
+func ValidatePattern(pattern string) error {
+	if filepath.IsAbs(pattern) || !filepath.IsLocal(filepath.Clean(pattern)) {
+		return fmt.Errorf("artifact %q must be a relative path inside the working directory", pattern)
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("artifact %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// Collect copies the files and directories matching the glob patterns, relative to src (forge's
+// working directory when empty), into the artifacts of the stage in the given run. Patterns
+// that match nothing are ignored.
+func (s *Store) Collect(runID, stage, src string, patterns []string) (snapshot.Stats, error) {
+	dir, err := s.stageDir(runID, stage)
+	if err != nil {
+		return snapshot.Stats{}, err
+	}
+	var total snapshot.Stats
+	for _, pattern := range patterns {
+		if err := ValidatePattern(pattern); err != nil {
+			return total, err
+		}
+		matches, err := filepath.Glob(filepath.Join(src, pattern))
+		if err != nil {
+			return total, err
+		}
+		for _, m := range matches {
+			rel, err := filepath.Rel(cmp.Or(src, "."), m)
+			if err != nil {
+				return total, err
+			}
+			stats, err := snapshot.Copy(m, filepath.Join(dir, rel))
+			if err != nil {
+				return total, fmt.Errorf("artifact %s: %w", rel, err)
+			}
+			total.Files += stats.Files
+			total.Bytes += stats.Bytes
+			total.Cloned += stats.Cloned
+		}
+	}
+	return total, nil
+}
+
+// Restore copies the artifacts of the stage in the given run into dst, replacing existing files
+func (s *Store) Restore(runID, stage, dst string) (snapshot.Stats, error) {
+	dir, err := s.stageDir(runID, stage)
+	if err != nil {
+		return snapshot.Stats{}, err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		if os.IsNotExist(err) {
+			return snapshot.Stats{}, fmt.Errorf("stage %s of run %s: %w", stage, runID, ErrNotFound)
+		}
+		return snapshot.Stats{}, err
+	}
+	return snapshot.Copy(dir, dst)
+}
+
+// Find returns runID if it stored artifacts for the stage, otherwise the most recent run that did
+func (s *Store) Find(stage, runID string) (string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	var runs []string
+	for _, e := range entries {
+		if e.IsDir() {
+			runs = append(runs, e.Name())
+		}
+	}
+	// Run IDs start with their UTC start time, so the newest sorts first after reversing
+	slices.Sort(runs)
+	slices.Reverse(runs)
+	for _, id := range append([]string{runID}, runs...) {
+		dir, err := s.stageDir(id, stage)
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(dir); err == nil {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("stage %s: %w", stage, ErrNotFound)
+}
+
+// List returns the artifacts of a run ordered by stage and path
+func (s *Store) List(runID string) ([]Artifact, error) {
+	dir, err := s.runDir(runID)
+	if err != nil {
+		return nil, err
+	}
+	var artifacts []Artifact
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return fmt.Errorf("run %s: %w", runID, ErrNotFound)
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		escaped, file, _ := strings.Cut(filepath.ToSlash(rel), "/")
+		stage, err := url.PathUnescape(escaped)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, Artifact{Stage: stage, Path: file, Size: info.Size()})
+		return nil
+	})
+	slices.SortFunc(artifacts, func(a, b Artifact) int {
+		return cmp.Or(cmp.Compare(a.Stage, b.Stage), cmp.Compare(a.Path, b.Path))
+	})
+	return artifacts, err
+}
+
+func (s *Store) runDir(runID string) (string, error) {
+	if runID == "" || runID == "." || runID == ".." || strings.ContainsAny(runID, `/\`) {
+		return "", fmt.Errorf("invalid run id %q", runID)
+	}
+	return filepath.Join(s.Dir, runID), nil
+}
+
+// stageDir escapes the stage name so any name maps to a single directory
+func (s *Store) stageDir(runID, stage string) (string, error) {
+	dir, err := s.runDir(runID)
+	if err != nil {
+		return "", err
+	}
+	escaped := url.PathEscape(stage)
+	if escaped == "" || escaped == "." || escaped == ".." {
+		return "", fmt.Errorf("invalid stage name %q", stage)
+	}
+	return filepath.Join(dir, escaped), nil
+}
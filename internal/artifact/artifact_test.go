@@ -0,0 +1,113 @@
+package artifact
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatalf("MkdirAll() error: %v", err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile() error: %v", err)
+		}
+	}
+}
+
+func TestStore_CollectListRestore(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeFiles(t, map[string]string{"bin/app": "binary", "bin/tool": "tool", "coverage.out": "cover", "README.md": "docs"})
+	s := NewStore(filepath.Join(t.TempDir(), "artifacts"))
+
+	stats, err := s.Collect("run-1", "build & lint", "", []string{"bin", "*.out", "missing/*"})
+	if err != nil {
+		t.Fatalf("Collect() error: %v", err)
+	}
+	if stats.Files != 3 {
+		t.Errorf("Collect() stored %d files, want 3", stats.Files)
+	}
+
+	got, err := s.List("run-1")
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	want := []Artifact{
+		{Stage: "build & lint", Path: "bin/app", Size: 6},
+		{Stage: "build & lint", Path: "bin/tool", Size: 4},
+		{Stage: "build & lint", Path: "coverage.out", Size: 5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %+v, want %+v", got, want)
+	}
+
+	if err := os.RemoveAll("bin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Restore("run-1", "build & lint", "."); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	if data, err := os.ReadFile("bin/app"); err != nil || string(data) != "binary" {
+		t.Errorf("restored bin/app = %q, %v", data, err)
+	}
+
+	if _, err := s.List("run-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("List() of an unknown run error = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Restore("run-1", "test", "."); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Restore() of an unknown stage error = %v, want ErrNotFound", err)
+	}
+	if _, err := s.List("../run-1"); err == nil {
+		t.Error("List() accepted a run id with a path separator")
+	}
+}
+
+func TestStore_Find(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeFiles(t, map[string]string{"out.txt": "x"})
+	s := NewStore(filepath.Join(t.TempDir(), "artifacts"))
+	for _, run := range []string{"20240101-000000-aaaaaa", "20240102-000000-bbbbbb"} {
+		if _, err := s.Collect(run, "build", "", []string{"out.txt"}); err != nil {
+			t.Fatalf("Collect() error: %v", err)
+		}
+	}
+	if _, err := s.Collect("20240103-000000-cccccc", "test", "", []string{"out.txt"}); err != nil {
+		t.Fatalf("Collect() error: %v", err)
+	}
+
+	tests := []struct {
+		stage, run, want string
+		wantErr          bool
+	}{
+		{stage: "build", run: "20240101-000000-aaaaaa", want: "20240101-000000-aaaaaa"},
+		{stage: "build", run: "20240104-000000-dddddd", want: "20240102-000000-bbbbbb"},
+		{stage: "deploy", run: "20240104-000000-dddddd", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := s.Find(tt.stage, tt.run)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Find(%s, %s) = %q, %v; want %q", tt.stage, tt.run, got, err, tt.want)
+		}
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	for pattern, wantErr := range map[string]bool{
+		"dist":         false,
+		"bin/*":        false,
+		"./out/report": false,
+		"/etc/passwd":  true,
+		"../secrets":   true,
+		"a/../../b":    true,
+		"[":            true,
+	} {
+		if err := ValidatePattern(pattern); (err != nil) != wantErr {
+			t.Errorf("ValidatePattern(%q) error = %v, wantErr %v", pattern, err, wantErr)
+		}
+	}
+}
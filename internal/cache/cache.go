@@ -0,0 +1,124 @@
+// Package cache remembers the input hashes of successful steps so unchanged steps can be skipped.
+package cache
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/history"
+)
+
+// DirName is the directory, relative to the workflow file, where cache keys are kept
+const DirName = ".forge/cache"
+
+// Store keeps one key file per cached step
+type Store struct {
+	Dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// ForWorkflow returns the store next to the run history of the given workflow file
+func ForWorkflow(workflow string) *Store {
+	return NewStore(filepath.Join(filepath.Dir(history.ForWorkflow(workflow).Dir), filepath.Base(DirName)))
+}
+
+// Key hashes the JSON encoding of def together with the paths and contents of the files
+// matching the glob patterns; directories are hashed recursively. Relative patterns are resolved
+// against dir, forge's working directory when empty, and a pattern matching nothing is an error.
+func Key(def any, dir string, patterns []string) (string, error) {
+	h := sha256.New()
+	data, err := json.Marshal(def)
+	if err != nil {
+		return "", err
+	}
+	h.Write(data)
+
+	var files []string
+	for _, pattern := range patterns {
+		abs := pattern
+		if !filepath.IsAbs(pattern) {
+			abs = filepath.Join(dir, pattern)
+		}
+		matches, err := filepath.Glob(abs)
+		if err != nil {
+			return "", fmt.Errorf("key file %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return "", fmt.Errorf("key file %q matches no files", pattern)
+		}
+		for _, m := range matches {
+			err := filepath.WalkDir(m, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.Type().IsRegular() {
+					files = append(files, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	slices.Sort(files)
+	for _, name := range slices.Compact(files) {
+		// Names are hashed relative to dir so the key does not depend on where forge runs
+		rel, err := filepath.Rel(cmp.Or(dir, "."), name)
+		if err != nil {
+			rel = name
+		}
+		fmt.Fprintf(h, "\x00%s\x00", filepath.ToSlash(rel))
+		if err := hashFile(h, name); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(w io.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Hit reports whether key is the stored key of the step with the given id
+func (s *Store) Hit(id, key string) bool {
+	data, err := os.ReadFile(s.path(id))
+	return err == nil && strings.TrimSpace(string(data)) == key
+}
+
+// Put stores key as the key of the last successful run of the step with the given id
+func (s *Store) Put(id, key string) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(id), []byte(key+"\n"), 0o644)
+}
+
+// Clear removes all stored keys
+func (s *Store) Clear() error {
+	return os.RemoveAll(s.Dir)
+}
+
+// path maps a step id, which may contain any characters, to a file name
+func (s *Store) path(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:16]))
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll("src/sub", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.sum", "v1")
+	write("src/sub/main.go", "package main")
+
+	patterns := []string{"go.sum", "src", "*.sum"}
+	key := func(def any) string {
+		t.Helper()
+		k, err := Key(def, "", patterns)
+		if err != nil {
+			t.Fatalf("Key() error: %v", err)
+		}
+		return k
+	}
+
+	base := key([]string{"go", "build"})
+	if again := key([]string{"go", "build"}); again != base {
+		t.Error("Key() is not stable")
+	}
+	if key([]string{"go", "vet"}) == base {
+		t.Error("Key() ignores the step definition")
+	}
+	write("src/sub/main.go", "package main // changed")
+	if key([]string{"go", "build"}) == base {
+		t.Error("Key() ignores files in matched directories")
+	}
+
+	if _, err := Key(nil, "", []string{"["}); err == nil {
+		t.Error("Key() accepted a malformed pattern")
+	}
+	if _, err := Key(nil, "", []string{"go.sum", "yarn.lock"}); err == nil || !strings.Contains(err.Error(), `key file "yarn.lock" matches no files`) {
+		t.Errorf("Key() with a pattern matching nothing error = %v", err)
+	}
+}
+
+func TestKey_Dir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.sum"), []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	base, err := Key("build", dir, []string{"go.sum"})
+	if err != nil {
+		t.Fatalf("Key() error: %v", err)
+	}
+	// The key files are found, and the key is the same, whatever forge's working directory
+	t.Chdir(t.TempDir())
+	if again, err := Key("build", dir, []string{"go.sum"}); err != nil || again != base {
+		t.Errorf("Key() from another directory = %q, %v, want %q", again, err, base)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.sum"), []byte("v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := Key("build", dir, []string{"go.sum"}); err != nil || changed == base {
+		t.Errorf("Key() after changing the key file = %q, %v, want a new key", changed, err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "cache"))
+	if s.Hit("ci/build/compile", "abc") {
+		t.Error("Hit() on an empty store")
+	}
+	if err := s.Put("ci/build/compile", "abc"); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	if !s.Hit("ci/build/compile", "abc") || s.Hit("ci/build/compile", "def") || s.Hit("ci/build/test", "abc") {
+		t.Error("Hit() does not match the stored key of the step")
+	}
+	if err := s.Clear(); err != nil {
+		t.Fatalf("Clear() error: %v", err)
+	}
+	if s.Hit("ci/build/compile", "abc") {
+		t.Error("Hit() after Clear()")
+	}
+}
@@ -0,0 +1,45 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	select {
+	case got := <-f.After(0):
+		if !got.Equal(start) {
+			t.Errorf("After(0) = %s, want %s", got, start)
+		}
+	default:
+		t.Error("After(0) did not fire immediately")
+	}
+
+	late, early := f.After(10*time.Second), f.After(5*time.Second)
+	done := make(chan struct{})
+	go func() {
+		f.Sleep(time.Minute)
+		close(done)
+	}()
+	f.BlockUntil(3)
+
+	f.Advance(7 * time.Second)
+	if got := <-early; !got.Equal(start.Add(7 * time.Second)) {
+		t.Errorf("After(5s) fired with %s", got)
+	}
+	select {
+	case <-late:
+		t.Fatal("After(10s) fired after 7s")
+	default:
+	}
+
+	f.Advance(time.Hour)
+	<-late
+	<-done
+	if got := f.Now(); !got.Equal(start.Add(time.Hour + 7*time.Second)) {
+		t.Errorf("Now() = %s", got)
+	}
+}
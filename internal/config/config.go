@@ -0,0 +1,159 @@
+// Package config loads user defaults from ~/.config/forge/config.yaml and FORGE_* environment
+// variables. Command-line flags take precedence over the environment, which takes precedence
+// over the file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	yaml "github.com/goccy/go-yaml"
+)
+
+// PathEnv overrides the location of the config file
+const PathEnv = "FORGE_CONFIG"
+
+// Config holds user defaults; nil pointers and zero values mean "not set"
+type Config struct {
+	// Timezone for displayed timestamps
+	Timezone string `yaml:"timezone,omitempty"`
+	// Jobs limits how many workflows run at once with --parallel, 0 means no limit
+	Jobs int `yaml:"jobs,omitempty"`
+	// NoColor disables colors and terminal escape sequences
+	NoColor bool `yaml:"no_color,omitempty"`
+	// History records runs in .forge/runs
+	History *bool `yaml:"history,omitempty"`
+	// TerminalTitle shows progress in the terminal title
+	TerminalTitle *bool `yaml:"terminal_title,omitempty"`
+	// GitHubAnnotations prints problem matcher findings as GitHub annotations
+	GitHubAnnotations *bool `yaml:"github_annotations,omitempty"`
+	// Policy is a Rego file or directory of policies every run has to pass
+	Policy string `yaml:"policy,omitempty"`
+	// LogFile receives a timestamped copy of the output of every run
+	LogFile string `yaml:"log_file,omitempty"`
+	// LogKeep is the number of log files kept, 0 keeps all
+	LogKeep *int `yaml:"log_keep,omitempty"`
+	// LogMaxAge removes older log files, e.g. 168h
+	LogMaxAge string `yaml:"log_max_age,omitempty"`
+	// ReplayTolerate lists the kinds of environment drift forge replay accepts, e.g. "host,forge"
+	ReplayTolerate string `yaml:"replay_tolerate,omitempty"`
+	// EnvFiles are dotenv files loaded into the steps' environment of every run
+	EnvFiles []string `yaml:"env_files,omitempty"`
+}
+
+// Path returns the config file location: $FORGE_CONFIG or <user config dir>/forge/config.yaml
+func Path() (string, error) {
+	if p := os.Getenv(PathEnv); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "forge", "config.yaml"), nil
+}
+
+// Load reads the config file, if there is one, and applies FORGE_* environment variables on top
+func Load() (*Config, error) {
+	cfg := &Config{}
+	if path, err := Path(); err == nil {
+		if cfg, err = LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.ApplyEnv(os.Getenv); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadFile reads a config file; a missing file yields an empty config
+func LoadFile(path string) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.UnmarshalWithOptions(data, cfg, yaml.Strict()); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ApplyEnv overrides settings with the FORGE_* variables returned by getenv. NO_COLOR is honored
+// as well, see https://no-color.org.
+func (c *Config) ApplyEnv(getenv func(string) string) error {
+	if v := getenv("FORGE_TIMEZONE"); v != "" {
+		c.Timezone = v
+	}
+	if v := getenv("FORGE_POLICY"); v != "" {
+		c.Policy = v
+	}
+	if v := getenv("FORGE_LOG_FILE"); v != "" {
+		c.LogFile = v
+	}
+	if v := getenv("FORGE_LOG_MAX_AGE"); v != "" {
+		c.LogMaxAge = v
+	}
+	if v := getenv("FORGE_REPLAY_TOLERATE"); v != "" {
+		c.ReplayTolerate = v
+	}
+	if v := getenv("FORGE_ENV_FILES"); v != "" {
+		c.EnvFiles = strings.Split(v, ",")
+	}
+	if v := getenv("FORGE_LOG_KEEP"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("FORGE_LOG_KEEP: %q is not a non-negative number", v)
+		}
+		c.LogKeep = &n
+	}
+	if v := getenv("FORGE_JOBS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("FORGE_JOBS: %q is not a non-negative number", v)
+		}
+		c.Jobs = n
+	}
+	if getenv("NO_COLOR") != "" {
+		c.NoColor = true
+	}
+	if v := getenv("FORGE_NO_COLOR"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("FORGE_NO_COLOR: %w", err)
+		}
+		c.NoColor = b
+	}
+	for name, dst := range map[string]**bool{
+		"FORGE_HISTORY":            &c.History,
+		"FORGE_TERMINAL_TITLE":     &c.TerminalTitle,
+		"FORGE_GITHUB_ANNOTATIONS": &c.GitHubAnnotations,
+	} {
+		v := getenv(name)
+		if v == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*dst = &b
+	}
+	return nil
+}
+
+// Bool returns *b, or def if the setting is not set
+func Bool(b *bool, def bool) bool {
+	if b == nil {
+		return def
+	}
+	return *b
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	if cfg, err := LoadFile(filepath.Join(dir, "missing.yaml")); err != nil || !reflect.DeepEqual(*cfg, Config{}) {
+		t.Errorf("LoadFile(missing) = %+v, %v; want empty config", cfg, err)
+	}
+
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("timezone: UTC\njobs: 4\nhistory: false\nlog_keep: 0\nenv_files: [ci.env]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error: %v", err)
+	}
+	if cfg.Timezone != "UTC" || cfg.Jobs != 4 || Bool(cfg.History, true) || cfg.LogKeep == nil || *cfg.LogKeep != 0 || !slices.Equal(cfg.EnvFiles, []string{"ci.env"}) {
+		t.Errorf("LoadFile() = %+v", cfg)
+	}
+
+	if err := os.WriteFile(path, []byte("colour: never\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Error("LoadFile() accepted an unknown key")
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		check   func(*Config) bool
+		wantErr bool
+	}{
+		{
+			name:  "env overrides file values",
+			env:   map[string]string{"FORGE_TIMEZONE": "Europe/Berlin", "FORGE_JOBS": "2"},
+			check: func(c *Config) bool { return c.Timezone == "Europe/Berlin" && c.Jobs == 2 },
+		},
+		{
+			name:  "policy",
+			env:   map[string]string{"FORGE_POLICY": "/etc/forge/policies"},
+			check: func(c *Config) bool { return c.Policy == "/etc/forge/policies" },
+		},
+		{
+			name:  "booleans",
+			env:   map[string]string{"FORGE_HISTORY": "true", "FORGE_TERMINAL_TITLE": "0"},
+			check: func(c *Config) bool { return Bool(c.History, false) && !Bool(c.TerminalTitle, true) },
+		},
+		{
+			name:  "NO_COLOR",
+			env:   map[string]string{"NO_COLOR": "1"},
+			check: func(c *Config) bool { return c.NoColor },
+		},
+		{
+			name:  "FORGE_NO_COLOR wins over NO_COLOR",
+			env:   map[string]string{"NO_COLOR": "1", "FORGE_NO_COLOR": "false"},
+			check: func(c *Config) bool { return !c.NoColor },
+		},
+		{
+			name:  "log file",
+			env:   map[string]string{"FORGE_LOG_FILE": "logs/forge.log", "FORGE_LOG_KEEP": "5", "FORGE_LOG_MAX_AGE": "168h"},
+			check: func(c *Config) bool { return c.LogFile == "logs/forge.log" && *c.LogKeep == 5 && c.LogMaxAge == "168h" },
+		},
+		{
+			name:  "keep all log files",
+			env:   map[string]string{"FORGE_LOG_KEEP": "0"},
+			check: func(c *Config) bool { return c.LogKeep != nil && *c.LogKeep == 0 },
+		},
+		{
+			name:  "replay tolerate",
+			env:   map[string]string{"FORGE_REPLAY_TOLERATE": "host,forge"},
+			check: func(c *Config) bool { return c.ReplayTolerate == "host,forge" },
+		},
+		{
+			name:  "env files",
+			env:   map[string]string{"FORGE_ENV_FILES": "ci.env,secrets.env"},
+			check: func(c *Config) bool { return slices.Equal(c.EnvFiles, []string{"ci.env", "secrets.env"}) },
+		},
+		{name: "invalid log keep", env: map[string]string{"FORGE_LOG_KEEP": "-1"}, wantErr: true},
+		{name: "invalid jobs", env: map[string]string{"FORGE_JOBS": "many"}, wantErr: true},
+		{name: "invalid bool", env: map[string]string{"FORGE_HISTORY": "maybe"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := true
+			cfg := &Config{Timezone: "UTC", Jobs: 8, History: &history}
+			err := cfg.ApplyEnv(func(k string) string { return tt.env[k] })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !tt.check(cfg) {
+				t.Errorf("ApplyEnv() = %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("timezone: UTC\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(PathEnv, path)
+	t.Setenv("FORGE_JOBS", "3")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.Timezone != "UTC" || cfg.Jobs != 3 {
+		t.Errorf("Load() = %+v", cfg)
+	}
+}
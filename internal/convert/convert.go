@@ -0,0 +1,93 @@
+// Package convert translates pipeline definitions between other tools and the forge DSL.
+package convert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+type Format string
+
+const (
+	FormatGitLabCI Format = "gitlab-ci"
+	FormatMakefile Format = "makefile"
+	FormatTaskfile Format = "taskfile"
+)
+
+// Result holds a converted workflow together with notes about constructs that could not be mapped
+type Result struct {
+	Workflow *dsl.Workflow
+	Warnings []string
+}
+
+// Formats lists all supported source formats
+func Formats() []Format {
+	return []Format{FormatGitLabCI, FormatMakefile, FormatTaskfile}
+}
+
+// DetectFormat guesses the source format from the file name
+func DetectFormat(path string) (Format, error) {
+	switch filepath.Base(path) {
+	case ".gitlab-ci.yml", ".gitlab-ci.yaml":
+		return FormatGitLabCI, nil
+	case "Makefile", "makefile", "GNUmakefile":
+		return FormatMakefile, nil
+	case "Taskfile.yml", "Taskfile.yaml", "taskfile.yml", "taskfile.yaml":
+		return FormatTaskfile, nil
+	}
+	return "", fmt.Errorf("cannot detect source format of %s, use --from", path)
+}
+
+// File converts the pipeline definition at path from the given format
+func File(path string, format Format) (*Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *Result
+	switch format {
+	case FormatGitLabCI:
+		res, err = GitLabCI(data)
+	case FormatMakefile:
+		res, err = Makefile(data)
+	case FormatTaskfile:
+		res, err = Taskfile(data)
+	default:
+		return nil, fmt.Errorf("unsupported source format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := res.Workflow.Validate(); err != nil {
+		return nil, fmt.Errorf("converted workflow is invalid: %w", err)
+	}
+	return res, nil
+}
+
+// Marshal renders the converted workflow as YAML
+func (r *Result) Marshal() ([]byte, error) {
+	return r.Workflow.MarshalYAML()
+}
+
+// shellSteps turns script lines into exec steps run through sh -c
+func shellSteps(prefix string, lines []string) []dsl.Step {
+	steps := make([]dsl.Step, 0, len(lines))
+	for i, line := range lines {
+		name := prefix
+		if len(lines) > 1 {
+			name = prefix + "-" + strconv.Itoa(i+1)
+		}
+		steps = append(steps, dsl.Step{
+			Name: name,
+			Type: dsl.StepTypeExec,
+			Run:  []string{"sh", "-c", line},
+		})
+	}
+	return steps
+}
@@ -0,0 +1,157 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/shell"
+	yaml "github.com/goccy/go-yaml"
+)
+
+type Target string
+
+const (
+	TargetGitHubActions Target = "github-actions"
+)
+
+// Targets lists all supported export targets
+func Targets() []Target {
+	return []Target{TargetGitHubActions}
+}
+
+var ghJobIDInvalid = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+type ghWorkflow struct {
+	Name string        `yaml:"name"`
+	On   []string      `yaml:"on"`
+	Jobs yaml.MapSlice `yaml:"jobs"`
+}
+
+type ghJob struct {
+	Name   string   `yaml:"name"`
+	RunsOn string   `yaml:"runs-on"`
+	Needs  []string `yaml:"needs,omitempty"`
+	If     string   `yaml:"if,omitempty"`
+	Steps  []ghStep `yaml:"steps"`
+}
+
+type ghStep struct {
+	Name string            `yaml:"name,omitempty"`
+	Uses string            `yaml:"uses,omitempty"`
+	With map[string]string `yaml:"with,omitempty"`
+	Run  string            `yaml:"run,omitempty"`
+}
+
+var (
+	ghCheckout = ghStep{Uses: "actions/checkout@v4"}
+	ghSetupGo  = ghStep{Uses: "actions/setup-go@v5", With: map[string]string{"go-version": "stable"}}
+)
+
+// Export holds a generated pipeline definition together with notes about lossy translations
+type Export struct {
+	Data     []byte
+	Warnings []string
+}
+
+// GitHubActions renders a GitHub Actions workflow for wf. By default every stage becomes a job
+// running the equivalent commands, chained with `needs` to keep forge's sequential order.
+// With invokeForge a single job installs forge and runs the workflow file at workflowPath.
+func GitHubActions(wf *dsl.Workflow, workflowPath string, invokeForge bool) (*Export, error) {
+	out := ghWorkflow{
+		Name: wf.Name,
+		On:   []string{"push", "pull_request", "workflow_dispatch"},
+	}
+	res := &Export{}
+
+	if invokeForge {
+		out.Jobs = yaml.MapSlice{{Key: "forge", Value: ghJob{
+			Name:   wf.Name,
+			RunsOn: "ubuntu-latest",
+			Steps: []ghStep{
+				ghCheckout,
+				ghSetupGo,
+				{Name: "Install forge", Run: "go install github.com/andre-koe/forge/cmd/forge@latest"},
+				{Name: "Run workflow", Run: shell.Join([]string{"forge", "run", workflowPath})},
+			},
+		}}}
+	} else {
+		var previous string
+		seen := make(map[string]bool)
+		for _, i := range wf.StageOrder() {
+			stage := wf.Stages[i]
+			id := ghJobID(stage.Name, seen)
+			job := ghJob{Name: stage.Name, RunsOn: "ubuntu-latest", Steps: []ghStep{ghCheckout}}
+			if previous != "" {
+				job.Needs = []string{previous}
+			}
+			if stage.Finally {
+				job.If = "always()"
+			}
+			var steps []dsl.Step
+			for _, step := range stage.Steps {
+				if step.Type == dsl.StepTypeGroup {
+					// The steps of a group become steps of the job
+					steps = append(steps, step.GroupSteps()...)
+				} else {
+					steps = append(steps, step)
+				}
+			}
+			for _, step := range steps {
+				s, warning := ghStepFor(&step)
+				if warning != "" {
+					res.Warnings = append(res.Warnings, fmt.Sprintf("stage %s, step %s: %s", stage.Name, step.Name, warning))
+				}
+				if step.Type == dsl.StepTypeGoTest && len(job.Steps) == 1 {
+					job.Steps = append(job.Steps, ghSetupGo)
+				}
+				job.Steps = append(job.Steps, s)
+			}
+			out.Jobs = append(out.Jobs, yaml.MapItem{Key: id, Value: job})
+			previous = id
+		}
+	}
+
+	data, err := yaml.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+	res.Data = append([]byte("# Generated by forge export from "+workflowPath+"\n"), data...)
+	return res, nil
+}
+
+func ghStepFor(step *dsl.Step) (ghStep, string) {
+	s := ghStep{Name: step.Name}
+	switch step.Type {
+	case dsl.StepTypeExec:
+		s.Run = shell.Join(step.Run)
+		if step.Run.IsLine() {
+			s.Run = step.Run[0]
+		}
+	case dsl.StepTypeSleep:
+		s.Run = "sleep " + strconv.Itoa(step.Seconds)
+	case dsl.StepTypeGoTest:
+		s.Run = shell.Join(append(append([]string{"go", "test"}, step.Args...), "./..."))
+		return s, "diff-aware test selection is not available in GitHub Actions, all packages are tested"
+	default:
+		s.Run = "forge run # unsupported step type " + string(step.Type)
+		return s, fmt.Sprintf("step type %s cannot be exported", step.Type)
+	}
+	return s, ""
+}
+
+// ghJobID derives a unique, valid job identifier from a stage name
+func ghJobID(name string, seen map[string]bool) string {
+	id := strings.Trim(ghJobIDInvalid.ReplaceAllString(name, "-"), "-")
+	if id == "" || (id[0] >= '0' && id[0] <= '9') || id[0] == '-' {
+		id = "stage-" + id
+	}
+	base := id
+	for i := 2; seen[id]; i++ {
+		id = base + "-" + strconv.Itoa(i)
+	}
+	seen[id] = true
+	return id
+}
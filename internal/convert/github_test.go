@@ -0,0 +1,123 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	yaml "github.com/goccy/go-yaml"
+)
+
+func TestGitHubActions(t *testing.T) {
+	wf := &dsl.Workflow{
+		Name: "ci",
+		Stages: []dsl.Stage{
+			{Name: "build & lint", Steps: []dsl.Step{
+				{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"go", "build", "./..."}},
+				{Name: "greet", Type: dsl.StepTypeExec, Run: []string{"sh", "-c", "echo it's done"}},
+			}},
+			{Name: "test", Steps: []dsl.Step{
+				{Name: "affected", Type: dsl.StepTypeGoTest, Args: []string{"-race"}},
+				{Name: "pause", Type: dsl.StepTypeSleep, Seconds: 3},
+			}},
+		},
+	}
+
+	res, err := GitHubActions(wf, "ci.yaml", false)
+	if err != nil {
+		t.Fatalf("GitHubActions() error = %v", err)
+	}
+
+	var parsed struct {
+		Jobs map[string]struct {
+			Needs []string `yaml:"needs"`
+			Steps []struct {
+				Uses string `yaml:"uses"`
+				Run  string `yaml:"run"`
+			} `yaml:"steps"`
+		} `yaml:"jobs"`
+	}
+	if err := yaml.Unmarshal(res.Data, &parsed); err != nil {
+		t.Fatalf("generated YAML does not parse: %v\n%s", err, res.Data)
+	}
+
+	build, ok := parsed.Jobs["build-lint"]
+	if !ok {
+		t.Fatalf("expected sanitized job id build-lint, got:\n%s", res.Data)
+	}
+	if build.Steps[0].Uses != "actions/checkout@v4" {
+		t.Errorf("first step should check out the repository, got %+v", build.Steps[0])
+	}
+	if build.Steps[1].Run != "go build ./..." {
+		t.Errorf("compile run = %q", build.Steps[1].Run)
+	}
+	if want := `sh -c 'echo it'"'"'s done'`; build.Steps[2].Run != want {
+		t.Errorf("greet run = %q, want %q", build.Steps[2].Run, want)
+	}
+
+	test := parsed.Jobs["test"]
+	if len(test.Needs) != 1 || test.Needs[0] != "build-lint" {
+		t.Errorf("test needs = %v, want [build-lint]", test.Needs)
+	}
+	runs := []string{}
+	for _, s := range test.Steps {
+		runs = append(runs, s.Run)
+	}
+	if got := strings.Join(runs, "|"); !strings.Contains(got, "go test -race ./...") || !strings.Contains(got, "sleep 3") {
+		t.Errorf("unexpected test job steps: %v", runs)
+	}
+	if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0], "affected") {
+		t.Errorf("expected one warning about go-test, got %v", res.Warnings)
+	}
+}
+
+func TestGitHubActions_InvokeForge(t *testing.T) {
+	wf := &dsl.Workflow{Name: "ci", Stages: []dsl.Stage{{Name: "build"}}}
+
+	res, err := GitHubActions(wf, "workflows/ci.yaml", true)
+	if err != nil {
+		t.Fatalf("GitHubActions() error = %v", err)
+	}
+	if !strings.Contains(string(res.Data), "run: forge run workflows/ci.yaml") {
+		t.Errorf("expected forge invocation, got:\n%s", res.Data)
+	}
+}
+
+func TestGhJobID(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, tt := range []struct{ name, want string }{
+		{"build", "build"},
+		{"build", "build-2"},
+		{"1st stage", "stage-1st-stage"},
+		{"!!!", "stage-"},
+	} {
+		if got := ghJobID(tt.name, seen); got != tt.want {
+			t.Errorf("ghJobID(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGitHubActions_FinallyStage(t *testing.T) {
+	wf := &dsl.Workflow{Name: "ci", Stages: []dsl.Stage{
+		{Name: "teardown", Finally: true, Steps: []dsl.Step{{Name: "down", Type: dsl.StepTypeExec, Run: []string{"docker", "compose", "down"}}}},
+		{Name: "test", Steps: []dsl.Step{{Name: "unit", Type: dsl.StepTypeExec, Run: []string{"go", "test", "./..."}}}},
+	}}
+
+	res, err := GitHubActions(wf, "ci.yaml", false)
+	if err != nil {
+		t.Fatalf("GitHubActions() error = %v", err)
+	}
+	var parsed struct {
+		Jobs map[string]struct {
+			Needs []string `yaml:"needs"`
+			If    string   `yaml:"if"`
+		} `yaml:"jobs"`
+	}
+	if err := yaml.Unmarshal(res.Data, &parsed); err != nil {
+		t.Fatalf("generated YAML does not parse: %v\n%s", err, res.Data)
+	}
+	teardown := parsed.Jobs["teardown"]
+	if teardown.If != "always()" || len(teardown.Needs) != 1 || teardown.Needs[0] != "test" {
+		t.Errorf("teardown job = %+v, want it to run always() after test", teardown)
+	}
+}
@@ -0,0 +1,169 @@
+package convert
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	yaml "github.com/goccy/go-yaml"
+)
+
+// gitlabDefaultStages is the stage list GitLab uses when a pipeline does not declare one
+var gitlabDefaultStages = []string{".pre", "build", "test", "deploy", ".post"}
+
+// gitlabKeywords are top-level keys that are not jobs
+var gitlabKeywords = map[string]bool{
+	"image": true, "services": true, "stages": true, "types": true, "before_script": true,
+	"after_script": true, "variables": true, "cache": true, "include": true, "default": true,
+	"workflow": true,
+}
+
+// gitlabIgnoredJobKeys are job settings that have no forge equivalent yet
+var gitlabIgnoredJobKeys = []string{"image", "services", "variables", "cache", "artifacts", "rules", "only", "except", "needs", "extends", "when", "tags"}
+
+type gitlabJob struct {
+	Stage        string         `yaml:"stage"`
+	Script       scriptLines    `yaml:"script"`
+	BeforeScript *scriptLines   `yaml:"before_script"`
+	AfterScript  *scriptLines   `yaml:"after_script"`
+	Extra        map[string]any `yaml:",inline"`
+}
+
+type gitlabDefaults struct {
+	BeforeScript scriptLines `yaml:"before_script"`
+	AfterScript  scriptLines `yaml:"after_script"`
+}
+
+// scriptLines accepts both a single string and a list of strings
+type scriptLines []string
+
+func (s *scriptLines) UnmarshalYAML(unmarshal func(any) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*s = scriptLines{single}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// GitLabCI converts a .gitlab-ci.yml document into a workflow with one stage per GitLab stage.
+// Every script line becomes an exec step; before_script and after_script lines (job-level or
+// from the default block) become steps surrounding the job's script.
+func GitLabCI(data []byte) (*Result, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid GitLab CI file: %w", err)
+	}
+
+	res := &Result{Workflow: &dsl.Workflow{
+		Name:        "converted-from-gitlab-ci",
+		Description: "Converted from .gitlab-ci.yml by forge convert",
+	}}
+
+	stages := gitlabDefaultStages
+	var defaults gitlabDefaults
+	var jobNames []string
+	jobs := make(map[string]gitlabJob)
+
+	for _, item := range doc {
+		key := fmt.Sprint(item.Key)
+		raw, err := yaml.Marshal(item.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case key == "stages":
+			if err := yaml.Unmarshal(raw, &stages); err != nil {
+				return nil, fmt.Errorf("invalid stages: %w", err)
+			}
+		case key == "default":
+			if err := yaml.Unmarshal(raw, &defaults); err != nil {
+				return nil, fmt.Errorf("invalid default block: %w", err)
+			}
+		case key == "before_script":
+			if err := yaml.Unmarshal(raw, &defaults.BeforeScript); err != nil {
+				return nil, fmt.Errorf("invalid before_script: %w", err)
+			}
+		case key == "after_script":
+			if err := yaml.Unmarshal(raw, &defaults.AfterScript); err != nil {
+				return nil, fmt.Errorf("invalid after_script: %w", err)
+			}
+		case gitlabKeywords[key]:
+			res.Warnings = append(res.Warnings, fmt.Sprintf("top-level '%s' is not supported and was ignored", key))
+		case strings.HasPrefix(key, "."):
+			res.Warnings = append(res.Warnings, fmt.Sprintf("hidden job '%s' was ignored", key))
+		default:
+			var job gitlabJob
+			if err := yaml.Unmarshal(raw, &job); err != nil {
+				return nil, fmt.Errorf("job %s: %w", key, err)
+			}
+			if job.Stage == "" {
+				job.Stage = "test"
+			}
+			if len(job.Script) == 0 {
+				res.Warnings = append(res.Warnings, fmt.Sprintf("job %s has no script and was ignored", key))
+				continue
+			}
+			jobs[key] = job
+			jobNames = append(jobNames, key)
+		}
+	}
+
+	for _, stageName := range stages {
+		stage := dsl.Stage{Name: stageName}
+		for _, name := range jobNames {
+			job := jobs[name]
+			if job.Stage != stageName {
+				continue
+			}
+			stage.Steps = append(stage.Steps, gitlabJobSteps(name, job, defaults)...)
+			res.Warnings = append(res.Warnings, gitlabIgnoredKeys(name, job)...)
+		}
+		if len(stage.Steps) > 0 {
+			res.Workflow.Stages = append(res.Workflow.Stages, stage)
+		}
+	}
+
+	for _, name := range jobNames {
+		if !slices.Contains(stages, jobs[name].Stage) {
+			return nil, fmt.Errorf("job %s uses undeclared stage %q", name, jobs[name].Stage)
+		}
+	}
+
+	return res, nil
+}
+
+func gitlabJobSteps(name string, job gitlabJob, defaults gitlabDefaults) []dsl.Step {
+	before, after := defaults.BeforeScript, defaults.AfterScript
+	if job.BeforeScript != nil {
+		before = *job.BeforeScript
+	}
+	if job.AfterScript != nil {
+		after = *job.AfterScript
+	}
+
+	var steps []dsl.Step
+	steps = append(steps, shellSteps(name+"-before", before)...)
+	steps = append(steps, shellSteps(name, job.Script)...)
+	steps = append(steps, shellSteps(name+"-after", after)...)
+	return steps
+}
+
+func gitlabIgnoredKeys(name string, job gitlabJob) []string {
+	var warnings []string
+	for _, key := range gitlabIgnoredJobKeys {
+		if _, ok := job.Extra[key]; ok {
+			warnings = append(warnings, fmt.Sprintf("job %s: '%s' is not supported and was ignored", name, key))
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
@@ -0,0 +1,134 @@
+package convert
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGitLabCI(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantStages   []string
+		wantSteps    map[string][]string
+		wantWarnings []string
+		wantErr      bool
+	}{
+		{
+			name: "stages, scripts and default hooks",
+			input: `stages: [build, test]
+default:
+  before_script:
+    - echo setup
+build:
+  stage: build
+  script: make build
+unit:
+  script:
+    - go vet ./...
+    - go test ./...
+  after_script: [echo done]
+`,
+			wantStages: []string{"build", "test"},
+			wantSteps: map[string][]string{
+				"build": {"build-before", "build"},
+				"test":  {"unit-before", "unit-1", "unit-2", "unit-after"},
+			},
+		},
+		{
+			name: "job before_script overrides default",
+			input: `before_script: [echo global]
+lint:
+  stage: build
+  before_script: []
+  script: [golangci-lint run]
+`,
+			wantStages: []string{"build"},
+			wantSteps:  map[string][]string{"build": {"lint"}},
+		},
+		{
+			name: "unsupported constructs produce warnings",
+			input: `variables:
+  FOO: bar
+.template:
+  script: [echo hidden]
+deploy:
+  stage: deploy
+  image: alpine
+  script: [./deploy.sh]
+trigger-job:
+  stage: deploy
+  trigger: other/project
+`,
+			wantStages: []string{"deploy"},
+			wantSteps:  map[string][]string{"deploy": {"deploy"}},
+			wantWarnings: []string{
+				"top-level 'variables'",
+				"hidden job '.template'",
+				"job deploy: 'image'",
+				"job trigger-job has no script",
+			},
+		},
+		{
+			name: "undeclared stage",
+			input: `stages: [build]
+deploy:
+  stage: deploy
+  script: [./deploy.sh]
+`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid yaml",
+			input:   "stages: [",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := GitLabCI([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GitLabCI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var stages []string
+			for _, stage := range res.Workflow.Stages {
+				stages = append(stages, stage.Name)
+				var steps []string
+				for _, step := range stage.Steps {
+					steps = append(steps, step.Name)
+					if step.Run[0] != "sh" || step.Run[1] != "-c" {
+						t.Errorf("step %s should run through sh -c, got %v", step.Name, step.Run)
+					}
+				}
+				if !slices.Equal(steps, tt.wantSteps[stage.Name]) {
+					t.Errorf("stage %s steps = %v, want %v", stage.Name, steps, tt.wantSteps[stage.Name])
+				}
+			}
+			if !slices.Equal(stages, tt.wantStages) {
+				t.Errorf("stages = %v, want %v", stages, tt.wantStages)
+			}
+
+			warnings := strings.Join(res.Warnings, "\n")
+			for _, want := range tt.wantWarnings {
+				if !strings.Contains(warnings, want) {
+					t.Errorf("warnings missing %q, got:\n%s", want, warnings)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	if f, err := DetectFormat("project/.gitlab-ci.yml"); err != nil || f != FormatGitLabCI {
+		t.Errorf("DetectFormat() = %q, %v, want %q", f, err, FormatGitLabCI)
+	}
+	if _, err := DetectFormat("pipeline.txt"); err == nil {
+		t.Error("DetectFormat() expected error for unknown file")
+	}
+}
@@ -0,0 +1,100 @@
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// makeRuleRe matches "target [target...]: [deps]" but not variable assignments
+var makeRuleRe = regexp.MustCompile(`^([^:=#\s][^:=#]*?)\s*::?\s*([^=]*)$`)
+
+// makeAssignRe matches variable assignments such as "GO := go" or "VERSION ?= dev"
+var makeAssignRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*\s*(:{1,3}|\?|\+|!)?=`)
+
+// Makefile converts make targets into stages with one exec step per recipe line.
+// Prerequisites become depends_on edges. Special targets (.PHONY, ...) and pattern
+// rules are ignored; recipes referencing make variables are reported since forge
+// does not expand them.
+func Makefile(data []byte) (*Result, error) {
+	res := &Result{Workflow: &dsl.Workflow{
+		Name:        "converted-from-makefile",
+		Description: "Converted from Makefile by forge convert",
+	}}
+
+	var targets []target
+	var current *target
+	var pending string
+	seen := make(map[string]bool)
+	usesVars := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := pending + scanner.Text()
+		pending = ""
+		if strings.HasSuffix(line, "\\") {
+			pending = strings.TrimSuffix(line, "\\")
+			continue
+		}
+
+		if strings.HasPrefix(line, "\t") {
+			if current == nil {
+				continue
+			}
+			cmd := strings.TrimLeft(strings.TrimSpace(line), "@-+")
+			cmd = strings.TrimSpace(cmd)
+			if cmd == "" || strings.HasPrefix(cmd, "#") {
+				continue
+			}
+			if (strings.Contains(cmd, "$(") || strings.Contains(cmd, "${")) && !usesVars[current.Name] {
+				usesVars[current.Name] = true
+				res.Warnings = append(res.Warnings, fmt.Sprintf("target %s: recipe uses make variables, which are not expanded", current.Name))
+			}
+			current.Commands = append(current.Commands, strings.ReplaceAll(cmd, "$$", "$"))
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		current = nil
+		if makeAssignRe.MatchString(trimmed) {
+			continue
+		}
+		m := makeRuleRe.FindStringSubmatch(trimmed)
+		if m == nil {
+			continue
+		}
+
+		deps, _, _ := strings.Cut(m[2], ";")
+		for _, name := range strings.Fields(m[1]) {
+			if strings.HasPrefix(name, ".") || strings.Contains(name, "%") {
+				continue
+			}
+			if seen[name] {
+				res.Warnings = append(res.Warnings, fmt.Sprintf("target %s is defined more than once, only the first definition was used", name))
+				continue
+			}
+			seen[name] = true
+			targets = append(targets, target{Name: name, Deps: strings.Fields(deps)})
+			current = &targets[len(targets)-1]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	stages, warnings, err := stagesFromTargets(targets)
+	if err != nil {
+		return nil, err
+	}
+	res.Workflow.Stages = stages
+	res.Warnings = append(res.Warnings, warnings...)
+	return res, nil
+}
@@ -0,0 +1,97 @@
+package convert
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func stageSummary(res *Result) (names []string, deps map[string][]string, cmds map[string][]string) {
+	deps = make(map[string][]string)
+	cmds = make(map[string][]string)
+	for _, stage := range res.Workflow.Stages {
+		names = append(names, stage.Name)
+		deps[stage.Name] = stage.DependsOn
+		for _, step := range stage.Steps {
+			cmds[stage.Name] = append(cmds[stage.Name], step.Run[2])
+		}
+	}
+	return names, deps, cmds
+}
+
+func TestMakefile(t *testing.T) {
+	input := "GO := go\n" +
+		"VERSION ?= dev\n" +
+		".PHONY: all build test\n" +
+		"\n" +
+		"all: test\n" +
+		"\n" +
+		"## test: run tests\n" +
+		"test: build\n" +
+		"\t@$(GO) test ./...\n" +
+		"\n" +
+		"build: generate\n" +
+		"\t-go build \\\n" +
+		"\t  ./...\n" +
+		"\techo $$HOME\n" +
+		"\n" +
+		"%.o: %.c\n" +
+		"\tcc -c $<\n"
+
+	res, err := Makefile([]byte(input))
+	if err != nil {
+		t.Fatalf("Makefile() error = %v", err)
+	}
+
+	names, deps, cmds := stageSummary(res)
+	if want := []string{"build", "test"}; !slices.Equal(names, want) {
+		t.Errorf("stages = %v, want %v", names, want)
+	}
+	if want := []string{"build"}; !slices.Equal(deps["test"], want) {
+		t.Errorf("test depends_on = %v, want %v", deps["test"], want)
+	}
+	if want := []string{"go build \t  ./...", "echo $HOME"}; !slices.Equal(cmds["build"], want) {
+		t.Errorf("build commands = %q, want %q", cmds["build"], want)
+	}
+	if want := []string{"$(GO) test ./..."}; !slices.Equal(cmds["test"], want) {
+		t.Errorf("test commands = %q, want %q", cmds["test"], want)
+	}
+
+	warnings := strings.Join(res.Warnings, "\n")
+	for _, want := range []string{
+		"target test: recipe uses make variables",
+		"target all has no commands",
+		"dependency 'generate' is not a known target",
+	} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("warnings missing %q, got:\n%s", want, warnings)
+		}
+	}
+}
+
+func TestMakefile_FoldsTargetsWithoutCommands(t *testing.T) {
+	input := "ci: check\n\techo ci\n\ncheck: fmt vet\n\nfmt:\n\tgo fmt ./...\n\nvet:\n\tgo vet ./...\n"
+
+	res, err := Makefile([]byte(input))
+	if err != nil {
+		t.Fatalf("Makefile() error = %v", err)
+	}
+
+	names, deps, _ := stageSummary(res)
+	if want := []string{"fmt", "vet", "ci"}; !slices.Equal(names, want) {
+		t.Errorf("stages = %v, want %v", names, want)
+	}
+	if want := []string{"fmt", "vet"}; !slices.Equal(deps["ci"], want) {
+		t.Errorf("ci depends_on = %v, want %v", deps["ci"], want)
+	}
+	if err := res.Workflow.Validate(); err != nil {
+		t.Errorf("converted workflow is invalid: %v", err)
+	}
+}
+
+func TestMakefile_Cycle(t *testing.T) {
+	input := "a: b\n\techo a\nb: a\n\techo b\n"
+	if _, err := Makefile([]byte(input)); err == nil {
+		t.Error("Makefile() expected error for dependency cycle")
+	}
+}
@@ -0,0 +1,97 @@
+package convert
+
+import (
+	"fmt"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// target is a named unit of work with dependencies, shared by the Makefile and Taskfile importers
+type target struct {
+	Name        string
+	Description string
+	Deps        []string
+	Commands    []string
+}
+
+// stagesFromTargets creates one stage per target, ordered so that dependencies come first.
+// Targets without commands produce no stage; edges through them are rewritten to their
+// own dependencies so that ordering constraints are preserved.
+func stagesFromTargets(targets []target) ([]dsl.Stage, []string, error) {
+	byName := make(map[string]target, len(targets))
+	for _, t := range targets {
+		byName[t.Name] = t
+	}
+
+	var warnings []string
+	var order []string
+	state := make(map[string]int) // 0 = unvisited, 1 = visiting, 2 = done
+
+	var visit func(name string, chain []string) error
+	visit = func(name string, chain []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("dependency cycle: %v -> %s", chain, name)
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		for _, dep := range byName[name].Deps {
+			if _, ok := byName[dep]; !ok {
+				continue
+			}
+			if err := visit(dep, append(chain, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		order = append(order, name)
+		return nil
+	}
+
+	for _, t := range targets {
+		if err := visit(t.Name, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// effectiveDeps resolves dependencies through targets that have no commands
+	var effectiveDeps func(name string, seen map[string]bool) []string
+	effectiveDeps = func(name string, seen map[string]bool) []string {
+		var deps []string
+		for _, dep := range byName[name].Deps {
+			t, ok := byName[dep]
+			if !ok || seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			if len(t.Commands) == 0 {
+				deps = append(deps, effectiveDeps(dep, seen)...)
+				continue
+			}
+			deps = append(deps, dep)
+		}
+		return deps
+	}
+
+	var stages []dsl.Stage
+	for _, name := range order {
+		t := byName[name]
+		for _, dep := range t.Deps {
+			if _, ok := byName[dep]; !ok {
+				warnings = append(warnings, fmt.Sprintf("target %s: dependency '%s' is not a known target and was ignored", name, dep))
+			}
+		}
+		if len(t.Commands) == 0 {
+			warnings = append(warnings, fmt.Sprintf("target %s has no commands and was folded into its dependents", name))
+			continue
+		}
+		stages = append(stages, dsl.Stage{
+			Name:        name,
+			Description: t.Description,
+			DependsOn:   effectiveDeps(name, make(map[string]bool)),
+			Steps:       shellSteps(name, t.Commands),
+		})
+	}
+	return stages, warnings, nil
+}
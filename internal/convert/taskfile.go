@@ -0,0 +1,95 @@
+package convert
+
+import (
+	"fmt"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	yaml "github.com/goccy/go-yaml"
+)
+
+type taskfileDoc struct {
+	Tasks yaml.MapSlice `yaml:"tasks"`
+}
+
+type taskfileTask struct {
+	Desc string        `yaml:"desc"`
+	Deps []taskfileRef `yaml:"deps"`
+	Cmds []taskfileCmd `yaml:"cmds"`
+}
+
+// taskfileRef accepts both "name" and {task: name} dependency forms
+type taskfileRef struct {
+	Task string `yaml:"task"`
+}
+
+func (r *taskfileRef) UnmarshalYAML(unmarshal func(any) error) error {
+	if err := unmarshal(&r.Task); err == nil {
+		return nil
+	}
+	type plain taskfileRef
+	return unmarshal((*plain)(r))
+}
+
+// taskfileCmd accepts both a command string and {cmd: ...} or {task: ...} entries
+type taskfileCmd struct {
+	Cmd  string `yaml:"cmd"`
+	Task string `yaml:"task"`
+}
+
+func (c *taskfileCmd) UnmarshalYAML(unmarshal func(any) error) error {
+	if err := unmarshal(&c.Cmd); err == nil {
+		return nil
+	}
+	type plain taskfileCmd
+	return unmarshal((*plain)(c))
+}
+
+// Taskfile converts go-task Taskfile tasks into stages. Task deps become depends_on
+// edges and `desc` becomes the stage description. Calls to other tasks inside cmds
+// are reported since forge has no equivalent yet.
+func Taskfile(data []byte) (*Result, error) {
+	var doc taskfileDoc
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid Taskfile: %w", err)
+	}
+
+	res := &Result{Workflow: &dsl.Workflow{
+		Name:        "converted-from-taskfile",
+		Description: "Converted from Taskfile by forge convert",
+	}}
+
+	var targets []target
+	for _, item := range doc.Tasks {
+		name := fmt.Sprint(item.Key)
+		raw, err := yaml.Marshal(item.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		var task taskfileTask
+		if err := yaml.Unmarshal(raw, &task); err != nil {
+			return nil, fmt.Errorf("task %s: %w", name, err)
+		}
+
+		t := target{Name: name, Description: task.Desc}
+		for _, dep := range task.Deps {
+			t.Deps = append(t.Deps, dep.Task)
+		}
+		for _, cmd := range task.Cmds {
+			if cmd.Task != "" {
+				res.Warnings = append(res.Warnings, fmt.Sprintf("task %s: call to task '%s' is not supported and was ignored", name, cmd.Task))
+				continue
+			}
+			t.Commands = append(t.Commands, cmd.Cmd)
+		}
+		targets = append(targets, t)
+	}
+
+	stages, warnings, err := stagesFromTargets(targets)
+	if err != nil {
+		return nil, err
+	}
+	res.Workflow.Stages = stages
+	res.Warnings = append(res.Warnings, warnings...)
+	return res, nil
+}
@@ -0,0 +1,65 @@
+package convert
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTaskfile(t *testing.T) {
+	input := `version: '3'
+tasks:
+  test:
+    desc: Run the tests
+    deps: [build]
+    cmds:
+      - go test ./...
+      - task: lint
+  build:
+    deps:
+      - task: generate
+    cmds:
+      - cmd: go build ./...
+  generate:
+    cmds: [go generate ./...]
+`
+
+	res, err := Taskfile([]byte(input))
+	if err != nil {
+		t.Fatalf("Taskfile() error = %v", err)
+	}
+
+	names, deps, cmds := stageSummary(res)
+	if want := []string{"generate", "build", "test"}; !slices.Equal(names, want) {
+		t.Errorf("stages = %v, want %v", names, want)
+	}
+	if want := []string{"generate"}; !slices.Equal(deps["build"], want) {
+		t.Errorf("build depends_on = %v, want %v", deps["build"], want)
+	}
+	if want := []string{"go test ./..."}; !slices.Equal(cmds["test"], want) {
+		t.Errorf("test commands = %q, want %q", cmds["test"], want)
+	}
+	if res.Workflow.Stages[2].Description != "Run the tests" {
+		t.Errorf("description = %q, want %q", res.Workflow.Stages[2].Description, "Run the tests")
+	}
+	if !strings.Contains(strings.Join(res.Warnings, "\n"), "call to task 'lint'") {
+		t.Errorf("expected warning about task call, got %v", res.Warnings)
+	}
+	if err := res.Workflow.Validate(); err != nil {
+		t.Errorf("converted workflow is invalid: %v", err)
+	}
+}
+
+func TestDetectFormat_Targets(t *testing.T) {
+	tests := map[string]Format{
+		"Makefile":          FormatMakefile,
+		"sub/GNUmakefile":   FormatMakefile,
+		"Taskfile.yml":      FormatTaskfile,
+		"dir/Taskfile.yaml": FormatTaskfile,
+	}
+	for path, want := range tests {
+		if got, err := DetectFormat(path); err != nil || got != want {
+			t.Errorf("DetectFormat(%q) = %q, %v, want %q", path, got, err, want)
+		}
+	}
+}
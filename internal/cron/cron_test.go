@@ -0,0 +1,56 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// Friday, 2026-01-02 10:17
+	from := time.Date(2026, 1, 2, 10, 17, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 1, 2, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 1, 2, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17 * * mon-fri", time.Date(2026, 1, 2, 11, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2026, 1, 3, 2, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1,15 mar *", time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: the 13th or any Monday
+		{"0 0 13 * 1", time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", tt.expr, err)
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next() = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestSchedule_Next_HalfHourZone(t *testing.T) {
+	kolkata := time.FixedZone("IST", 5*60*60+30*60)
+	s, err := Parse("0 11 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2026, 1, 2, 10, 17, 0, 0, kolkata)
+	if got, want := s.Next(from), time.Date(2026, 1, 2, 11, 0, 0, 0, kolkata); !got.Equal(want) {
+		t.Errorf("Next() = %v, want %v", got, want)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *", "@often"} {
+		if _, err := Parse(expr); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Parse(%q) error = %v, want %v", expr, err, ErrSyntax)
+		}
+	}
+}
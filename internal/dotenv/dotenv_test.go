@@ -0,0 +1,80 @@
+package dotenv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "plain pairs, comments and blank lines",
+			input: "# database\nDB_HOST=localhost\n\nDB_PORT = 5432\n",
+			want:  map[string]string{"DB_HOST": "localhost", "DB_PORT": "5432"},
+		},
+		{
+			name:  "export prefix",
+			input: "export TOKEN=abc\n",
+			want:  map[string]string{"TOKEN": "abc"},
+		},
+		{
+			name:  "inline comment on unquoted value",
+			input: "MODE=debug # verbose\nURL=http://x/#frag\n",
+			want:  map[string]string{"MODE": "debug", "URL": "http://x/#frag"},
+		},
+		{
+			name:  "quoted values",
+			input: `SINGLE='a $b \n'` + "\n" + `DOUBLE="line\nnext \"q\"" # note` + "\n" + "EMPTY=\n",
+			want:  map[string]string{"SINGLE": `a $b \n`, "DOUBLE": "line\nnext \"q\"", "EMPTY": ""},
+		},
+		{
+			name:  "later assignment wins",
+			input: "A=1\nA=2\n",
+			want:  map[string]string{"A": "2"},
+		},
+		{name: "missing equals", input: "JUSTAKEY\n", wantErr: true},
+		{name: "invalid key", input: "1A=x\n", wantErr: true},
+		{name: "unterminated quote", input: `A="open` + "\n", wantErr: true},
+		{name: "text after quoted value", input: `A="x" y` + "\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.input))
+			if tt.wantErr {
+				if !errors.Is(err, ErrSyntax) {
+					t.Fatalf("Parse() error = %v, want ErrSyntax", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("A=1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadFile(path); err != nil || got["A"] != "1" {
+		t.Errorf("ReadFile() = %v, %v", got, err)
+	}
+	if _, err := ReadFile(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadFile(missing) error = %v, want ErrNotExist", err)
+	}
+}
@@ -0,0 +1,153 @@
+package dsl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrShellSyntax is returned by SplitCommand for command lines that only a shell can run
+var ErrShellSyntax = errors.New("needs a shell")
+
+// Command is the run of an exec step: a list of arguments, or one command line, written as a
+// plain string or a list of one element, that is split into arguments like a shell splits words
+type Command []string
+
+// UnmarshalYAML accepts a plain string as a command line
+func (c *Command) UnmarshalYAML(unmarshal func(any) error) error {
+	var v any
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	if line, ok := v.(string); ok {
+		*c = Command{line}
+		return nil
+	}
+	var args []string
+	if err := unmarshal(&args); err != nil {
+		return err
+	}
+	*c = args
+	return nil
+}
+
+// MarshalYAML writes a command line as a plain string
+func (c Command) MarshalYAML() (any, error) {
+	if c.IsLine() {
+		return c[0], nil
+	}
+	return []string(c), nil
+}
+
+// IsLine reports whether the command is a command line to be split rather than a list of arguments
+func (c Command) IsLine() bool {
+	return len(c) == 1 && strings.ContainsAny(c[0], " \t\n")
+}
+
+// Argv returns the arguments of an exec step's run, splitting a command line; with ExpandGlobs
+// the line may contain the patterns the runner expands. Validation ensures the command lines of
+// steps run without a shell split.
+func (s *Step) Argv() []string {
+	if !s.Run.IsLine() {
+		return s.Run
+	}
+	args, _ := splitCommand(s.Run[0], s.ExpandGlobs)
+	return args
+}
+
+// SplitCommand splits a command line into arguments at unquoted whitespace, like a shell: single
+// quotes keep everything literally, double quotes and backslashes escape as in sh and ${{ }}
+// expressions are kept whole. Pipes, redirections, variables, globs and the like return
+// ErrShellSyntax, as they would not do what they do in a shell.
+func SplitCommand(line string) ([]string, error) {
+	return splitCommand(line, false)
+}
+
+// splitCommand is SplitCommand that, with globs, keeps glob patterns and ~ for expand_globs
+func splitCommand(line string, globs bool) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord := false
+	emit := func() {
+		if inWord {
+			args = append(args, word.String())
+		}
+		word.Reset()
+		inWord = false
+	}
+	// expression copies a ${{ }} expression starting at i and returns the index after it
+	expression := func(i int) int {
+		end := strings.Index(line[i:], "}}")
+		if end < 0 {
+			end = len(line) - i - 2
+		}
+		word.WriteString(line[i : i+end+2])
+		return i + end + 2
+	}
+
+	for i := 0; i < len(line); {
+		ch := line[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n':
+			emit()
+			i++
+		case strings.HasPrefix(line[i:], "${{"):
+			inWord = true
+			i = expression(i)
+		case ch == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated ' in %q", line)
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			inWord = true
+			i += end + 2
+		case ch == '"':
+			inWord = true
+			i++
+			for {
+				if i >= len(line) {
+					return nil, fmt.Errorf("unterminated \" in %q", line)
+				}
+				c := line[i]
+				if c == '"' {
+					i++
+					break
+				}
+				switch {
+				case strings.HasPrefix(line[i:], "${{"):
+					i = expression(i)
+					continue
+				case c == '\\' && i+1 < len(line) && strings.IndexByte("\"\\$`\n", line[i+1]) >= 0:
+					if line[i+1] != '\n' {
+						word.WriteByte(line[i+1])
+					}
+					i += 2
+					continue
+				case c == '$' || c == '`':
+					return nil, fmt.Errorf("%w for %q", ErrShellSyntax, string(c))
+				}
+				word.WriteByte(c)
+				i++
+			}
+		case ch == '\\':
+			if i+1 < len(line) && line[i+1] != '\n' {
+				word.WriteByte(line[i+1])
+				inWord = true
+			}
+			i += 2
+		case globs && (strings.IndexByte("*?[", ch) >= 0 || !inWord && ch == '~'):
+			word.WriteByte(ch)
+			inWord = true
+			i++
+		case strings.IndexByte("|&;<>()$`*?[", ch) >= 0, !inWord && (ch == '~' || ch == '#'):
+			return nil, fmt.Errorf("%w for %q", ErrShellSyntax, string(ch))
+		default:
+			word.WriteByte(ch)
+			inWord = true
+			i++
+		}
+	}
+	emit()
+	return args, nil
+}
@@ -0,0 +1,88 @@
+package dsl
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	yaml "github.com/goccy/go-yaml"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr string
+	}{
+		{line: "go test ./...", want: []string{"go", "test", "./..."}},
+		{line: "  git   commit -m 'fix the  build' ", want: []string{"git", "commit", "-m", "fix the  build"}},
+		{line: `echo "say \"hi\"" it\'s ''`, want: []string{"echo", `say "hi"`, "it's", ""}},
+		{line: `go build -ldflags "-X main.version=${{ vars.tag }}"`, want: []string{"go", "build", "-ldflags", "-X main.version=${{ vars.tag }}"}},
+		{line: "docker push app:${{ vars.tag }} ${{ env.REGISTRY }}", want: []string{"docker", "push", "app:${{ vars.tag }}", "${{ env.REGISTRY }}"}},
+		{line: "printf 'a|b; $HOME *'", want: []string{"printf", "a|b; $HOME *"}},
+		{line: "path/to/a~b x#y", want: []string{"path/to/a~b", "x#y"}},
+		{line: "make | tee build.log", wantErr: `needs a shell for "|"`},
+		{line: "make && make install", wantErr: `needs a shell for "&"`},
+		{line: "echo $HOME", wantErr: `needs a shell for "$"`},
+		{line: `echo "$(date)"`, wantErr: `needs a shell for "$"`},
+		{line: "rm dist/*", wantErr: `needs a shell for "*"`},
+		{line: "ls ~/src", wantErr: `needs a shell for "~"`},
+		{line: "echo 'open", wantErr: "unterminated '"},
+		{line: `echo "open`, wantErr: `unterminated "`},
+	}
+	for _, tt := range tests {
+		got, err := SplitCommand(tt.line)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("SplitCommand(%q) error = %v, want %q", tt.line, err, tt.wantErr)
+			}
+			if strings.HasPrefix(tt.wantErr, "needs a shell") != errors.Is(err, ErrShellSyntax) {
+				t.Errorf("SplitCommand(%q) error = %v, errors.Is(ErrShellSyntax) mismatch", tt.line, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitCommand(%q) = %q, %v, want %q", tt.line, got, err, tt.want)
+		}
+	}
+}
+
+func TestCommand_YAML(t *testing.T) {
+	tests := []struct {
+		yaml     string
+		want     Command
+		wantArgv []string
+		wantYAML string
+	}{
+		{yaml: "run: go test ./...\n", want: Command{"go test ./..."}, wantArgv: []string{"go", "test", "./..."}, wantYAML: "run: go test ./...\n"},
+		{yaml: "run: [go, test, ./...]\n", want: Command{"go", "test", "./..."}, wantArgv: []string{"go", "test", "./..."}, wantYAML: "run: [go, test, ./...]\n"},
+		{yaml: "run: [\"go vet\"]\n", want: Command{"go vet"}, wantArgv: []string{"go", "vet"}, wantYAML: "run: go vet\n"},
+		{yaml: "run: make\n", want: Command{"make"}, wantArgv: []string{"make"}, wantYAML: "run: [make]\n"},
+	}
+	for _, tt := range tests {
+		var step struct {
+			Run Command `yaml:"run,flow"`
+		}
+		if err := yaml.Unmarshal([]byte(tt.yaml), &step); err != nil {
+			t.Fatalf("Unmarshal(%q) error: %v", tt.yaml, err)
+		}
+		if !reflect.DeepEqual(step.Run, tt.want) {
+			t.Errorf("Unmarshal(%q) = %q, want %q", tt.yaml, step.Run, tt.want)
+		}
+		if got := (&Step{Run: step.Run}).Argv(); !reflect.DeepEqual(got, tt.wantArgv) {
+			t.Errorf("Argv() of %q = %q, want %q", tt.yaml, got, tt.wantArgv)
+		}
+		data, err := yaml.Marshal(step)
+		if err != nil || string(data) != tt.wantYAML {
+			t.Errorf("Marshal() = %q, %v, want %q", data, err, tt.wantYAML)
+		}
+	}
+
+	var step struct {
+		Run Command `yaml:"run"`
+	}
+	if err := yaml.Unmarshal([]byte("run: {go: test}\n"), &step); err == nil {
+		t.Error("Unmarshal() of a mapping succeeded, want an error")
+	}
+}
@@ -0,0 +1,33 @@
+package dsl
+
+// Concurrency keeps runs from overlapping: of the runs in progress with the same group, from any
+// workflow in the directory, one runs at a time and the others queue behind it in the order they
+// started
+type Concurrency struct {
+	// Group names the runs that must not overlap, e.g. "deploy-${{ vars.env }}"
+	Group string `yaml:"group" json:"group"`
+	// CancelInProgress makes a new run cancel the runs of its group in progress, queued ones
+	// included, instead of waiting for them
+	CancelInProgress bool `yaml:"cancel_in_progress,omitempty" json:"cancel_in_progress,omitempty"`
+}
+
+// concurrency has the fields of Concurrency without its methods, so decoding it does not recurse
+type concurrency Concurrency
+
+// UnmarshalYAML accepts a plain string as the group of runs that queue behind each other
+func (c *Concurrency) UnmarshalYAML(unmarshal func(any) error) error {
+	var group string
+	if err := unmarshal(&group); err == nil {
+		*c = Concurrency{Group: group}
+		return nil
+	}
+	return unmarshal((*concurrency)(c))
+}
+
+// MarshalYAML writes a group without cancel_in_progress as a plain string
+func (c Concurrency) MarshalYAML() (any, error) {
+	if !c.CancelInProgress {
+		return c.Group, nil
+	}
+	return concurrency(c), nil
+}
@@ -0,0 +1,64 @@
+package dsl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	yaml "github.com/goccy/go-yaml"
+)
+
+func TestConcurrency_YAML(t *testing.T) {
+	tests := []struct {
+		yaml     string
+		want     Concurrency
+		wantYAML string
+	}{
+		{yaml: "concurrency: deploy\n", want: Concurrency{Group: "deploy"}, wantYAML: "concurrency: deploy\n"},
+		{yaml: "concurrency: {group: deploy}\n", want: Concurrency{Group: "deploy"}, wantYAML: "concurrency: deploy\n"},
+		{
+			yaml:     "concurrency: {group: deploy, cancel_in_progress: true}\n",
+			want:     Concurrency{Group: "deploy", CancelInProgress: true},
+			wantYAML: "concurrency:\n  group: deploy\n  cancel_in_progress: true\n",
+		},
+	}
+	for _, tt := range tests {
+		var wf struct {
+			Concurrency *Concurrency `yaml:"concurrency"`
+		}
+		if err := yaml.Unmarshal([]byte(tt.yaml), &wf); err != nil {
+			t.Fatalf("Unmarshal(%q) error: %v", tt.yaml, err)
+		}
+		if wf.Concurrency == nil || !reflect.DeepEqual(*wf.Concurrency, tt.want) {
+			t.Errorf("Unmarshal(%q) = %+v, want %+v", tt.yaml, wf.Concurrency, tt.want)
+		}
+		data, err := yaml.Marshal(wf)
+		if err != nil || string(data) != tt.wantYAML {
+			t.Errorf("Marshal() = %q, %v, want %q", data, err, tt.wantYAML)
+		}
+	}
+}
+
+func TestValidateConcurrency(t *testing.T) {
+	for _, tt := range []struct {
+		concurrency *Concurrency
+		wantErr     string
+	}{
+		{concurrency: &Concurrency{Group: "deploy-${{ vars.env }}", CancelInProgress: true}},
+		{concurrency: &Concurrency{Group: " "}, wantErr: "concurrency: group is required"},
+	} {
+		wf := Workflow{
+			Name:        "deploy",
+			Concurrency: tt.concurrency,
+			Vars:        map[string]string{"env": "prod"},
+			Stages:      []Stage{{Name: "deploy", Steps: []Step{{Name: "push", Type: StepTypeExec, Run: Command{"push"}}}}},
+		}
+		err := wf.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("Validate() of %+v error: %v", tt.concurrency, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("Validate() of %+v error = %v, want %q", tt.concurrency, err, tt.wantErr)
+		}
+	}
+}
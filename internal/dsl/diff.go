@@ -0,0 +1,185 @@
+package dsl
+
+import (
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+type DiffKind string
+
+const (
+	DiffAdded   DiffKind = "added"
+	DiffRemoved DiffKind = "removed"
+	DiffMoved   DiffKind = "moved"
+	DiffChanged DiffKind = "changed"
+)
+
+// Difference is one semantic difference between two workflows
+type Difference struct {
+	Kind DiffKind
+	// Unit is what differs: "workflow", "stage" or "step"
+	Unit string
+	// Path locates the stage or step, e.g. "build" or "build/test"; hook steps are found under
+	// their kind, e.g. "build/on_failure/notify" or "always/cleanup"
+	Path string
+	// Field is the changed setting of a DiffChanged difference, e.g. "run" or "env.GOFLAGS"
+	Field string
+	// Old and New are the values of Field, nil when it is not set on one side; for DiffMoved
+	// they are the positions among the stages or steps both workflows have
+	Old, New any
+}
+
+// Diff compares two workflows stage by stage and step by step, matching them by name. It reports
+// added, removed and reordered stages and steps and every setting that changed, such as a step's
+// commands or a single environment variable.
+func Diff(a, b *Workflow) []Difference {
+	var diffs []Difference
+	diffs = diffFields(diffs, "workflow", "", a, b, "stages", "on_success", "on_failure", "always")
+	diffs = diffList(diffs, "stage", "", a.Stages, b.Stages, func(s Stage) string { return s.Name }, func(diffs []Difference, path string, x, y *Stage) []Difference {
+		diffs = diffFields(diffs, "stage", path, x, y, "steps", "on_success", "on_failure", "always")
+		diffs = diffSteps(diffs, path, x.Steps, y.Steps)
+		return diffHooks(diffs, path, x.Hooks(), y.Hooks())
+	})
+	return diffHooks(diffs, "", a.Hooks(), b.Hooks())
+}
+
+func diffHooks(diffs []Difference, path string, a, b map[string][]Step) []Difference {
+	for _, kind := range HookKinds {
+		diffs = diffSteps(diffs, joinDiffPath(path, kind), a[kind], b[kind])
+	}
+	return diffs
+}
+
+func diffSteps(diffs []Difference, path string, a, b []Step) []Difference {
+	return diffList(diffs, "step", path, a, b, func(s Step) string { return s.Name }, func(diffs []Difference, path string, x, y *Step) []Difference {
+		diffs = diffFields(diffs, "step", path, x, y, "steps")
+		return diffSteps(diffs, path, x.Steps, y.Steps)
+	})
+}
+
+// diffList matches the elements of a and b by name: elements of b missing in a are added, those of a
+// missing in b removed, and common elements outside their longest common ordering moved. Common
+// elements are compared with same.
+func diffList[T any](diffs []Difference, unit, path string, a, b []T, name func(T) string, same func([]Difference, string, *T, *T) []Difference) []Difference {
+	inA := make(map[string]int, len(a))
+	for i, x := range a {
+		inA[name(x)] = i
+	}
+	inB := make(map[string]int, len(b))
+	for i, y := range b {
+		inB[name(y)] = i
+	}
+
+	var commonA, commonB []string
+	for _, x := range a {
+		if _, ok := inB[name(x)]; ok {
+			commonA = append(commonA, name(x))
+		} else {
+			diffs = append(diffs, Difference{Kind: DiffRemoved, Unit: unit, Path: joinDiffPath(path, name(x))})
+		}
+	}
+	for _, y := range b {
+		if _, ok := inA[name(y)]; ok {
+			commonB = append(commonB, name(y))
+		} else {
+			diffs = append(diffs, Difference{Kind: DiffAdded, Unit: unit, Path: joinDiffPath(path, name(y))})
+		}
+	}
+
+	inOrder := longestCommonOrder(commonA, commonB)
+	for j, n := range commonB {
+		if !inOrder[n] {
+			diffs = append(diffs, Difference{Kind: DiffMoved, Unit: unit, Path: joinDiffPath(path, n), Old: slices.Index(commonA, n) + 1, New: j + 1})
+		}
+	}
+	for _, n := range commonB {
+		diffs = same(diffs, joinDiffPath(path, n), &a[inA[n]], &b[inB[n]])
+	}
+	return diffs
+}
+
+// longestCommonOrder returns the names of a longest subsequence common to a and b, which hold the same names
+func longestCommonOrder(a, b []string) map[string]bool {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	common := make(map[string]bool)
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			common[a[i]] = true
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return common
+}
+
+// diffFields compares the settings of a and b by their JSON names, leaving out the nested ones in
+// skip. Settings holding maps, such as env, are compared key by key.
+func diffFields(diffs []Difference, unit, path string, a, b any, skip ...string) []Difference {
+	x, y := fieldValues(a), fieldValues(b)
+	for _, key := range skip {
+		delete(x, key)
+		delete(y, key)
+	}
+	return diffValues(diffs, unit, path, "", x, y)
+}
+
+func diffValues(diffs []Difference, unit, path, prefix string, x, y map[string]any) []Difference {
+	keys := slices.Collect(maps.Keys(x))
+	for k := range y {
+		if _, ok := x[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		old, new := x[k], y[k]
+		if reflect.DeepEqual(old, new) {
+			continue
+		}
+		oldMap, oldIsMap := old.(map[string]any)
+		newMap, newIsMap := new.(map[string]any)
+		if (oldIsMap || old == nil) && (newIsMap || new == nil) && prefix == "" {
+			diffs = diffValues(diffs, unit, path, k+".", oldMap, newMap)
+			continue
+		}
+		diffs = append(diffs, Difference{Kind: DiffChanged, Unit: unit, Path: path, Field: prefix + k, Old: old, New: new})
+	}
+	return diffs
+}
+
+// fieldValues returns the settings of v, a workflow, stage or step, as decoded JSON values
+func fieldValues(v any) map[string]any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]any
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
+
+func joinDiffPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "/" + name
+}
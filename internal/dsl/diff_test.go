@@ -0,0 +1,59 @@
+package dsl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := &Workflow{
+		Name: "ci",
+		Stages: []Stage{
+			{Name: "build", Steps: []Step{
+				{Name: "compile", Type: StepTypeExec, Run: []string{"go", "build"}, Env: map[string]string{"CGO_ENABLED": "0", "GOOS": "linux"}},
+				{Name: "lint", Type: StepTypeExec, Run: []string{"golangci-lint", "run"}},
+				{Name: "vet", Type: StepTypeExec, Run: []string{"go", "vet"}},
+			}},
+			{Name: "test", Steps: []Step{{Name: "unit", Type: StepTypeExec, Run: []string{"go", "test"}}}},
+		},
+		Always: []Step{{Name: "cleanup", Type: StepTypeExec, Run: []string{"rm", "-rf", "tmp"}}},
+	}
+	b := &Workflow{
+		Name:     "ci",
+		Timezone: "UTC",
+		Stages: []Stage{
+			{Name: "build", Steps: []Step{
+				{Name: "vet", Type: StepTypeExec, Run: []string{"go", "vet"}},
+				{Name: "compile", Type: StepTypeExec, Run: []string{"go", "build", "-race"}, Env: map[string]string{"CGO_ENABLED": "1", "GOFLAGS": "-mod=mod"}},
+			}},
+			{Name: "test", Steps: []Step{{Name: "unit", Type: StepTypeExec, Run: []string{"go", "test"}}}},
+			{Name: "deploy", Steps: []Step{{Name: "push", Type: StepTypeExec, Run: []string{"docker", "push"}}}},
+		},
+	}
+
+	want := []Difference{
+		{Kind: DiffChanged, Unit: "workflow", Field: "timezone", New: "UTC"},
+		{Kind: DiffAdded, Unit: "stage", Path: "deploy"},
+		{Kind: DiffRemoved, Unit: "step", Path: "build/lint"},
+		{Kind: DiffMoved, Unit: "step", Path: "build/compile", Old: 1, New: 2},
+		{Kind: DiffChanged, Unit: "step", Path: "build/compile", Field: "env.CGO_ENABLED", Old: "0", New: "1"},
+		{Kind: DiffChanged, Unit: "step", Path: "build/compile", Field: "env.GOFLAGS", New: "-mod=mod"},
+		{Kind: DiffChanged, Unit: "step", Path: "build/compile", Field: "env.GOOS", Old: "linux"},
+		{Kind: DiffChanged, Unit: "step", Path: "build/compile", Field: "run", Old: []any{"go", "build"}, New: []any{"go", "build", "-race"}},
+		{Kind: DiffRemoved, Unit: "step", Path: "always/cleanup"},
+	}
+	if got := Diff(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() =\n%+v\nwant\n%+v", got, want)
+	}
+	if got := Diff(a, a); len(got) != 0 {
+		t.Errorf("Diff() of identical workflows = %+v, want none", got)
+	}
+}
+
+func TestLongestCommonOrder(t *testing.T) {
+	got := longestCommonOrder([]string{"a", "b", "c", "d"}, []string{"b", "c", "a", "d"})
+	want := map[string]bool{"b": true, "c": true, "d": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("longestCommonOrder() = %v, want %v", got, want)
+	}
+}
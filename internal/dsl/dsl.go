@@ -1,10 +1,17 @@
 package dsl
 
 import (
+	"cmp"
 	"fmt"
+	"maps"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/andre-koe/forge/internal/matcher"
+	"github.com/andre-koe/forge/internal/notify"
+	"github.com/andre-koe/forge/internal/preflight"
+	"github.com/andre-koe/forge/pkg/version"
 	yaml "github.com/goccy/go-yaml"
 )
 
@@ -13,45 +20,481 @@ type StepType string
 const (
 	StepTypeExec  StepType = "exec"
 	StepTypeSleep StepType = "sleep"
+	// StepTypeGoTest runs `go test` only for packages affected by changes since Base
+	StepTypeGoTest StepType = "go-test"
+	// StepTypeSnapshot copies the directory at Path aside so a later restore step can put it back
+	StepTypeSnapshot StepType = "snapshot"
+	// StepTypeRestore puts back the directory saved by the snapshot step named in Snapshot
+	StepTypeRestore StepType = "restore"
+	// StepTypeSlack posts the message configured in Slack
+	StepTypeSlack StepType = "slack"
+	// StepTypeApproval waits until someone approves the run to continue, see Approval
+	StepTypeApproval StepType = "approval"
+	// StepTypeAssert runs a command and checks its exit code and output, see Assert
+	StepTypeAssert StepType = "assert"
+	// StepTypeGroup runs its Steps in order as one unit, see GroupSteps
+	StepTypeGroup StepType = "group"
 )
 
 // Workflow and Step definitions for YAML parsing
 type Workflow struct {
-	Name        string  `yaml:"name"`
-	Description string  `yaml:"description,omitempty"`
-	Stages      []Stage `yaml:"stages"`
+	// SchemaVersion is the version of the workflow format, see CurrentSchemaVersion; files
+	// without one are of version 1
+	SchemaVersion int    `yaml:"schema_version,omitempty" json:"schema_version,omitempty"`
+	Name          string `yaml:"name" json:"name"`
+	Description   string `yaml:"description,omitempty" json:"description,omitempty"`
+	Timezone      string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+	// Schedule is a cron expression on which `forge schedule` runs the workflow
+	Schedule string `yaml:"schedule,omitempty" json:"schedule,omitempty"`
+	// Concurrency keeps runs of the same group from overlapping
+	Concurrency *Concurrency `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+	// Requires lists the tools, and the forge version, checked before the run starts
+	Requires *Requires `yaml:"requires,omitempty" json:"requires,omitempty"`
+	// Include pulls stages, variables and problem matchers from other files, local or remote
+	Include []Include `yaml:"include,omitempty" json:"include,omitempty"`
+	// Vars declares workflow variables with their defaults, referenced as ${{ vars.NAME }}
+	Vars map[string]string `yaml:"vars,omitempty" json:"vars,omitempty"`
+	// Profiles holds named overrides such as dev, staging and prod, selected with --profile
+	Profiles map[string]Profile `yaml:"profiles,omitempty" json:"profiles,omitempty"`
+	// EnvFile names a dotenv file whose variables are exported to every step, relative to the working directory
+	EnvFile string `yaml:"env_file,omitempty" json:"env_file,omitempty"`
+	// Workdir is the directory commands run in, relative to the workflow file; stages and steps override it
+	Workdir string `yaml:"workdir,omitempty" json:"workdir,omitempty"`
+	// Defaults holds settings for every step that does not set them itself
+	Defaults *Defaults `yaml:"defaults,omitempty" json:"defaults,omitempty"`
+	// ChangesBase is the git ref stages with changes filters are compared against, HEAD when empty
+	ChangesBase string `yaml:"changes_base,omitempty" json:"changes_base,omitempty"`
+	// ProblemMatchers defines custom matchers that steps can reference by name in addition to the built-ins
+	ProblemMatchers []matcher.Definition `yaml:"problem_matchers,omitempty" json:"problem_matchers,omitempty"`
+	// Notifications sends run lifecycle events to external services
+	Notifications *Notifications `yaml:"notifications,omitempty" json:"notifications,omitempty"`
+	// StepHooks are commands run around every exec step
+	StepHooks *StepHooks `yaml:"step_hooks,omitempty" json:"step_hooks,omitempty"`
+	Stages    []Stage    `yaml:"stages" json:"stages"`
+	// OnSuccess, OnFailure and Always are hook steps run after the stages, depending on the outcome
+	OnSuccess []Step `yaml:"on_success,omitempty" json:"on_success,omitempty"`
+	OnFailure []Step `yaml:"on_failure,omitempty" json:"on_failure,omitempty"`
+	Always    []Step `yaml:"always,omitempty" json:"always,omitempty"`
+
+	// included are the includes merged into the workflow when it was loaded
+	included []Include
+}
+
+// Defaults are the settings steps fall back to; stages and steps override them
+type Defaults struct {
+	// Shell runs the run of exec steps as a script, see Step.Shell
+	Shell string `yaml:"shell,omitempty" json:"shell,omitempty"`
+	// Workdir is the directory commands run in, like the workflow's workdir
+	Workdir string `yaml:"workdir,omitempty" json:"workdir,omitempty"`
+	// Timeout bounds each command a step runs, e.g. "15m"
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// Retries is how often a failed step is run again; approval steps are not retried
+	Retries int `yaml:"retries,omitempty" json:"retries,omitempty"`
+	// Env sets environment variables for every step, below the env: of stages and steps
+	Env map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+}
+
+// Profile overrides variables and sets environment variables in the runs it is selected for
+type Profile struct {
+	// Vars overrides the defaults of variables declared in the workflow's vars
+	Vars map[string]string `yaml:"vars,omitempty" json:"vars,omitempty"`
+	// Env sets environment variables for every step; values may use ${{ }} expressions
+	Env map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	// Secrets are set like Env, but dry runs and plans do not show their values, e.g.
+	// "${{ env.PROD_DB_PASSWORD }}"
+	Secrets map[string]string `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+}
+
+// Requires declares what must be available before a run starts
+type Requires struct {
+	// Tools are executables that must be on PATH, optionally with a version constraint, e.g. "kubectl>=1.28"
+	Tools []string `yaml:"tools,omitempty" json:"tools,omitempty"`
+	// Forge constrains the forge versions allowed to run the workflow, e.g. ">=0.5, <1.0"
+	Forge string `yaml:"forge,omitempty" json:"forge,omitempty"`
+}
+
+// ForgeConstraint returns the required forge version of requires.forge
+func (w *Workflow) ForgeConstraint() string {
+	if w.Requires == nil {
+		return ""
+	}
+	return w.Requires.Forge
+}
+
+// RequiredTools returns the parsed tool requirements of the workflow
+func (w *Workflow) RequiredTools() ([]preflight.Tool, error) {
+	if w.Requires == nil {
+		return nil, nil
+	}
+	tools := make([]preflight.Tool, 0, len(w.Requires.Tools))
+	for _, spec := range w.Requires.Tools {
+		t, err := preflight.ParseTool(spec)
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, t)
+	}
+	return tools, nil
+}
+
+// Notifications configures where run lifecycle events are delivered
+type Notifications struct {
+	Webhooks []notify.Webhook `yaml:"webhooks,omitempty" json:"webhooks,omitempty"`
+	Slack    []notify.Slack   `yaml:"slack,omitempty" json:"slack,omitempty"`
+}
+
+// StageOrder returns the indexes of the stages in execution order: the regular stages, then the finally stages
+func (w *Workflow) StageOrder() []int {
+	order := make([]int, 0, len(w.Stages))
+	for _, finally := range []bool{false, true} {
+		for i, stage := range w.Stages {
+			if stage.Finally == finally {
+				order = append(order, i)
+			}
+		}
+	}
+	return order
+}
+
+// Location returns the time zone used for displayed timestamps, defaulting to the local zone
+func (w *Workflow) Location() (*time.Location, error) {
+	if w.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(w.Timezone)
 }
 
 type Stage struct {
-	Name        string `yaml:"name"`
-	Description string `yaml:"description,omitempty"`
-	Steps       []Step `yaml:"steps"`
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// DependsOn lists stages that must complete before this one; they have to be declared earlier
+	DependsOn []string `yaml:"depends_on,omitempty,flow" json:"depends_on,omitempty"`
+	// Finally makes the stage run after all other stages, even when one of them failed
+	Finally bool `yaml:"finally,omitempty" json:"finally,omitempty"`
+	// OnError decides what a failure of the stage does to the run, OnErrorAbort when empty
+	OnError OnError `yaml:"on_error,omitempty" json:"on_error,omitempty"`
+	// MaxStageRetries is how often a stage with OnErrorRetry is run again after failing, 1 when zero
+	MaxStageRetries int `yaml:"max_stage_retries,omitempty" json:"max_stage_retries,omitempty"`
+	// Workdir is the directory the stage's commands run in, relative to the workflow file
+	Workdir string `yaml:"workdir,omitempty" json:"workdir,omitempty"`
+	// Changes are path globs; when set, the stage only runs if one of the changed files matches
+	Changes []string `yaml:"changes,omitempty" json:"changes,omitempty"`
+	// Env sets environment variables for the stage's steps; values may use ${{ }} expressions
+	Env map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	// CleanEnv starts the processes of the stage's steps from an empty environment plus Env
+	CleanEnv bool `yaml:"clean_env,omitempty" json:"clean_env,omitempty"`
+	// Platforms limits the stage to operating systems and architectures, e.g. "linux" or "darwin/arm64"
+	Platforms []string `yaml:"platforms,omitempty,flow" json:"platforms,omitempty"`
+	// RunsOn sends the stage elsewhere to run, e.g. to an agent
+	RunsOn *RunsOn `yaml:"runs_on,omitempty" json:"runs_on,omitempty"`
+	// ScheduleWindow limits when the stage may run, e.g. "Mon-Fri 09:00-17:00 Europe/Berlin"
+	ScheduleWindow string `yaml:"schedule_window,omitempty" json:"schedule_window,omitempty"`
+	// OutsideWindow decides what happens when the stage is due outside ScheduleWindow, WindowFail when empty
+	OutsideWindow WindowPolicy `yaml:"outside_window,omitempty" json:"outside_window,omitempty"`
+	// Throttle paces the stage's steps, e.g. for APIs with rate limits
+	Throttle *Throttle `yaml:"throttle,omitempty" json:"throttle,omitempty"`
+	// Restore names stages whose artifacts are copied into the working directory before the steps run
+	Restore []string `yaml:"restore,omitempty" json:"restore,omitempty"`
+	Steps   []Step   `yaml:"steps" json:"steps"`
+	// Artifacts are path globs, relative to the working directory, kept after the stage's steps ran
+	Artifacts []string `yaml:"artifacts,omitempty" json:"artifacts,omitempty"`
+	// OnSuccess, OnFailure and Always are hook steps run after the stage's steps, depending on the outcome
+	OnSuccess []Step `yaml:"on_success,omitempty" json:"on_success,omitempty"`
+	OnFailure []Step `yaml:"on_failure,omitempty" json:"on_failure,omitempty"`
+	Always    []Step `yaml:"always,omitempty" json:"always,omitempty"`
+}
+
+// OnError is the failure strategy of a stage
+type OnError string
+
+const (
+	// OnErrorAbort fails the run when the stage fails
+	OnErrorAbort OnError = "abort"
+	// OnErrorContinue reports the failure and goes on with the next stage without failing the run
+	OnErrorContinue OnError = "continue"
+	// OnErrorRetry runs the whole stage again, up to MaxStageRetries times, before failing the run
+	OnErrorRetry OnError = "retry"
+)
+
+// Throttle limits how fast the steps of a stage start
+type Throttle struct {
+	// Steps is how many steps may start within Per, 1 when zero
+	Steps int `yaml:"steps,omitempty" json:"steps,omitempty"`
+	// Per is the interval Steps applies to, e.g. "10s"
+	Per string `yaml:"per,omitempty" json:"per,omitempty"`
+	// Jitter is the upper bound of a random delay added before every step but the first, e.g. "2s"
+	Jitter string `yaml:"jitter,omitempty" json:"jitter,omitempty"`
+}
+
+// Limit returns how many steps may start per interval and the interval and jitter as durations,
+// zero when unset or invalid
+func (t *Throttle) Limit() (steps int, per, jitter time.Duration) {
+	per, _ = time.ParseDuration(t.Per)
+	jitter, _ = time.ParseDuration(t.Jitter)
+	return max(t.Steps, 1), per, jitter
+}
+
+// WindowPolicy is what a stage due outside its schedule window does
+type WindowPolicy string
+
+const (
+	// WindowWait waits until the window opens, then runs the stage
+	WindowWait WindowPolicy = "wait"
+	// WindowSkip skips the stage and goes on with the next one
+	WindowSkip WindowPolicy = "skip"
+	// WindowFail fails the stage
+	WindowFail WindowPolicy = "fail"
+)
+
+// DryRunMode is what a dry run does with a step
+type DryRunMode string
+
+const (
+	// DryRunSimulate prints what the step would do
+	DryRunSimulate DryRunMode = "simulate"
+	// DryRunExecute runs the step, for read-only commands such as terraform plan
+	DryRunExecute DryRunMode = "execute"
+	// DryRunSkip leaves the step out
+	DryRunSkip DryRunMode = "skip"
+)
+
+// StageRetries returns how often the stage is run again after failing
+func (s *Stage) StageRetries() int {
+	if s.OnError != OnErrorRetry {
+		return 0
+	}
+	return max(s.MaxStageRetries, 1)
+}
+
+// ShellNone in a step's shell runs its command without the default shell
+const ShellNone = "none"
+
+// StepShell returns the shell the run of an exec step is passed to, empty when it runs directly
+func (w *Workflow) StepShell(s *Step) string {
+	shell := s.Shell
+	if shell == "" && w.Defaults != nil {
+		shell = w.Defaults.Shell
+	}
+	if shell == ShellNone || s.Type != StepTypeExec {
+		return ""
+	}
+	return shell
+}
+
+// StepTimeout returns the timeout of each command of the step, zero when neither the step nor
+// the defaults set one; for a group it bounds the group as a whole and has no default.
+// Validation ensures timeouts parse.
+func (w *Workflow) StepTimeout(s *Step) time.Duration {
+	timeout := s.Timeout
+	if timeout == "" && w.Defaults != nil && s.Type != StepTypeGroup {
+		timeout = w.Defaults.Timeout
+	}
+	d, _ := time.ParseDuration(timeout)
+	return d
+}
+
+// StepRetries returns how often the step is run again after failing; the default applies to the
+// steps of a group rather than the group
+func (w *Workflow) StepRetries(s *Step) int {
+	switch {
+	case s.Retries != nil:
+		return *s.Retries
+	case w.Defaults == nil || s.Type == StepTypeApproval || s.Type == StepTypeGroup:
+		return 0
+	}
+	return w.Defaults.Retries
+}
+
+// GroupSteps returns the steps of a group step as they run: without env, clean_env or workdir of
+// their own they take the group's, and their env adds to the group's
+func (s *Step) GroupSteps() []Step {
+	steps := make([]Step, len(s.Steps))
+	for i, sub := range s.Steps {
+		if len(s.Env) > 0 {
+			env := maps.Clone(s.Env)
+			maps.Copy(env, sub.Env)
+			sub.Env = env
+		}
+		sub.CleanEnv = sub.CleanEnv || s.CleanEnv
+		sub.Workdir = cmp.Or(sub.Workdir, s.Workdir)
+		steps[i] = sub
+	}
+	return steps
+}
+
+// Redirected reports whether the step sets stdin, stdin_file, stdout_file or stderr_file
+func (s *Step) Redirected() bool {
+	return s.Stdin != "" || s.StdinFile != "" || s.StdoutFile != "" || s.StderrFile != ""
+}
+
+// Hook kinds, named after their YAML keys
+const (
+	HookOnSuccess = "on_success"
+	HookOnFailure = "on_failure"
+	HookAlways    = "always"
+)
+
+// Hooks returns the hook steps of the stage by kind
+func (s *Stage) Hooks() map[string][]Step {
+	return map[string][]Step{HookOnSuccess: s.OnSuccess, HookOnFailure: s.OnFailure, HookAlways: s.Always}
+}
+
+// Hooks returns the workflow-level hook steps by kind
+func (w *Workflow) Hooks() map[string][]Step {
+	return map[string][]Step{HookOnSuccess: w.OnSuccess, HookOnFailure: w.OnFailure, HookAlways: w.Always}
 }
 
+// HookKinds lists the hook kinds in the order they run: on_success or on_failure, then always
+var HookKinds = []string{HookOnSuccess, HookOnFailure, HookAlways}
+
 type Step struct {
-	Name        string   `yaml:"name"`
-	Description string   `yaml:"description,omitempty"`
-	Type        StepType `yaml:"type"`
-	Run         []string `yaml:"run,omitempty"`
-	Seconds     int      `yaml:"seconds,omitempty"`
+	Name string `yaml:"name" json:"name"`
+	// ID names the step for steps.<id>.status and steps.<id>.output expressions; unique in the workflow
+	ID          string   `yaml:"id,omitempty" json:"id,omitempty"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Type        StepType `yaml:"type" json:"type"`
+	// When is a condition expression; the step is skipped unless it is true
+	When string `yaml:"when,omitempty" json:"when,omitempty"`
+	// Run is the command of an exec step, a list of arguments or a command line, see Command
+	Run Command `yaml:"run,omitempty,flow" json:"run,omitempty"`
+	// Before and After are commands an exec step runs before and after run; After runs even if the step failed
+	Before [][]string `yaml:"before,omitempty,flow" json:"before,omitempty"`
+	After  [][]string `yaml:"after,omitempty,flow" json:"after,omitempty"`
+	// TTY runs the commands of an exec step in a pseudo-terminal, for programs that need one
+	TTY bool `yaml:"tty,omitempty" json:"tty,omitempty"`
+	// User and Group run the commands of an exec step as another user or group, by name or id (Unix only)
+	User    string   `yaml:"user,omitempty" json:"user,omitempty"`
+	Group   string   `yaml:"group,omitempty" json:"group,omitempty"`
+	Seconds int      `yaml:"seconds,omitempty" json:"seconds,omitempty"`
+	Base    string   `yaml:"base,omitempty" json:"base,omitempty"`
+	Args    []string `yaml:"args,omitempty,flow" json:"args,omitempty"`
+	// Workdir is the directory the step's commands run in, relative to the workflow file
+	Workdir string `yaml:"workdir,omitempty" json:"workdir,omitempty"`
+	// Shell runs the run of an exec step as a script: its words are joined with spaces and
+	// passed to the shell with -c, e.g. "bash" or "bash -eo pipefail"; ShellNone runs it
+	// directly despite a default shell
+	Shell string `yaml:"shell,omitempty" json:"shell,omitempty"`
+	// ExpandGlobs expands ~ and glob patterns such as dist/*.tar.gz in the arguments of an exec
+	// step's run before it runs, as a shell would
+	ExpandGlobs bool `yaml:"expand_globs,omitempty" json:"expand_globs,omitempty"`
+	// Stdin is text passed to the standard input of an exec step's run, StdinFile a file it reads
+	// it from; StdoutFile and StderrFile are files its output is written to instead of the run
+	// output. Files are relative to the step's directory.
+	Stdin      string `yaml:"stdin,omitempty" json:"stdin,omitempty"`
+	StdinFile  string `yaml:"stdin_file,omitempty" json:"stdin_file,omitempty"`
+	StdoutFile string `yaml:"stdout_file,omitempty" json:"stdout_file,omitempty"`
+	StderrFile string `yaml:"stderr_file,omitempty" json:"stderr_file,omitempty"`
+	// Timeout bounds each command the step runs, e.g. "15m"; 10 minutes when neither the step
+	// nor the defaults set one
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// Retries is how often the step is run again after failing; zero overrides a default
+	Retries *int `yaml:"retries,omitempty" json:"retries,omitempty"`
+	// Env sets environment variables for the step's processes, overriding its stage's Env
+	Env map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	// CleanEnv starts the step's processes from an empty environment plus the declared variables
+	CleanEnv bool `yaml:"clean_env,omitempty" json:"clean_env,omitempty"`
+	// Platforms limits the step to operating systems and architectures, e.g. "linux" or "darwin/arm64"
+	Platforms []string `yaml:"platforms,omitempty,flow" json:"platforms,omitempty"`
+	// Matchers names the problem matchers applied to the step's output
+	Matchers []string `yaml:"matchers,omitempty,flow" json:"matchers,omitempty"`
+	// Path is the directory a snapshot step saves
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// Snapshot names the snapshot step a restore step restores
+	Snapshot string `yaml:"snapshot,omitempty" json:"snapshot,omitempty"`
+	// RestoreOnFailure makes a snapshot step restore its directory automatically if the run fails
+	RestoreOnFailure bool `yaml:"restore_on_failure,omitempty" json:"restore_on_failure,omitempty"`
+	// Ports requests TCP ports by name, either "auto" for a free port or a fixed number
+	Ports map[string]string `yaml:"ports,omitempty" json:"ports,omitempty"`
+	// Slack is the message a slack step posts
+	Slack *notify.Slack `yaml:"slack,omitempty" json:"slack,omitempty"`
+	// Approval configures an approval step
+	Approval *Approval `yaml:"approval,omitempty" json:"approval,omitempty"`
+	// Assert is the command and expectations of an assert step
+	Assert *Assert `yaml:"assert,omitempty" json:"assert,omitempty"`
+	// Cache skips the step when its inputs are unchanged since its last successful run
+	Cache *Cache `yaml:"cache,omitempty" json:"cache,omitempty"`
+	// IdempotencyKey skips the step once a recorded run succeeded with the same key, e.g.
+	// "create-bucket-${{ vars.bucket }}"
+	IdempotencyKey string `yaml:"idempotency_key,omitempty" json:"idempotency_key,omitempty"`
+	// DryRun is what dry runs do with the step, DryRunSimulate when empty
+	DryRun DryRunMode `yaml:"dry_run,omitempty" json:"dry_run,omitempty"`
+	// With configures steps of a type provided by an executor outside the built-in ones
+	With map[string]any `yaml:"with,omitempty" json:"with,omitempty"`
+	// Steps are the steps of a group step; timeout and retries of the group apply to all of them
+	Steps []Step `yaml:"steps,omitempty" json:"steps,omitempty"`
+}
+
+// Approval configures the wait of an approval step
+type Approval struct {
+	// Message is shown to the approvers, e.g. "Deploy ${{ vars.tag }} to production?"
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+	// Timeout is how long to wait for a decision, e.g. "30m", before the step is denied
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// Assert declares the command an assert step runs and what its result has to be
+type Assert struct {
+	Command []string `yaml:"command,flow" json:"command"`
+	// ExpectExitCode is the exit code the command has to end with
+	ExpectExitCode int `yaml:"expect_exit_code,omitempty" json:"expect_exit_code,omitempty"`
+	// ExpectStdoutContains are strings the standard output has to contain
+	ExpectStdoutContains []string `yaml:"expect_stdout_contains,omitempty" json:"expect_stdout_contains,omitempty"`
+	// ExpectStdoutMatches are regular expressions the standard output has to match
+	ExpectStdoutMatches []string `yaml:"expect_stdout_matches,omitempty" json:"expect_stdout_matches,omitempty"`
+	// ExpectStderrContains are strings the standard error has to contain
+	ExpectStderrContains []string `yaml:"expect_stderr_contains,omitempty" json:"expect_stderr_contains,omitempty"`
+}
+
+// StepHooks declares commands run around every exec step, outside the step's own before and after
+type StepHooks struct {
+	Before [][]string `yaml:"before,omitempty,flow" json:"before,omitempty"`
+	After  [][]string `yaml:"after,omitempty,flow" json:"after,omitempty"`
 }
 
-// LoadWorkflowFromFile loads a Workflow from a YAML file
+// Cache declares the inputs of a cached step
+type Cache struct {
+	// KeyFiles are path globs whose contents, together with the step definition, form the cache key
+	KeyFiles []string `yaml:"key_files,flow" json:"key_files"`
+}
+
+// PortAuto requests a free port chosen by forge
+const PortAuto = "auto"
+
+// LoadWorkflowFromFile loads a Workflow from a YAML, JSON or TOML file, or from a CUE or Jsonnet
+// file evaluated by the preprocessor
 func LoadWorkflowFromFile(filename string) (*Workflow, error) {
+	if format := DetectFormat(filename, nil); format.preprocessed() {
+		data, err := evaluate(format, filename)
+		if err != nil {
+			return nil, err
+		}
+		return loadWorkflow(data, filename, FormatJSON)
+	}
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
+	return LoadWorkflow(data, filename)
+}
+
+// LoadWorkflow parses and validates a Workflow from YAML, JSON or TOML data; name identifies the
+// source in messages and its extension tells the format, see DetectFormat
+func LoadWorkflow(data []byte, name string) (*Workflow, error) {
+	return loadWorkflow(data, name, DetectFormat(name, data))
+}
 
+func loadWorkflow(data []byte, name string, format Format) (*Workflow, error) {
 	// Normalize tabs to spaces to avoid YAML parsing issues
-	if strings.Contains(string(data), "\t") {
-		fmt.Printf("Warning: Tabs detected in %s, normalizing to spaces for YAML parsing\n", filename)
+	if format == FormatYAML && strings.Contains(string(data), "\t") {
+		fmt.Printf("Warning: Tabs detected in %s, normalizing to spaces for YAML parsing\n", name)
 	}
 
-	data = normalizeTabs(data)
+	wf, err := decodeWorkflow(data, format)
+	if err != nil {
+		return nil, err
+	}
 
-	var wf Workflow
-	if err := yaml.Unmarshal(data, &wf); err != nil {
+	if err := wf.resolveIncludes(name); err != nil {
 		return nil, err
 	}
 
@@ -59,16 +502,99 @@ func LoadWorkflowFromFile(filename string) (*Workflow, error) {
 		return nil, fmt.Errorf("workflow validation failed: %w", err)
 	}
 
+	if err := wf.CheckForgeVersion(); err != nil {
+		return nil, err
+	}
+
+	return wf, nil
+}
+
+// ParseWorkflow decodes YAML workflow data without validating it or printing warnings; data of
+// an older schema version is migrated first
+func ParseWorkflow(data []byte) (*Workflow, error) {
+	data, err := migrate(normalizeTabs(data))
+	if err != nil {
+		return nil, err
+	}
+	var wf Workflow
+	if err := yaml.Unmarshal(data, &wf); err != nil {
+		return nil, err
+	}
 	return &wf, nil
 }
 
-// WriteTemplate creates a sample workflow template file
-func WriteTemplate(filename string) error {
-	tpl, err := minimalWorkflowExample()
+// ReadVarFile reads a YAML mapping of variable names to scalar values, as passed with --var-file
+func ReadVarFile(filename string) (map[string]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var vars map[string]string
+	if err := yaml.Unmarshal(normalizeTabs(data), &vars); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	for name := range vars {
+		if err := ValidateVarName(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+	return vars, nil
+}
+
+// VersionError reports that the running forge does not satisfy a workflow's requires.forge
+type VersionError struct {
+	Required string
+	Running  string
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("workflow requires forge %s but this is forge %s; upgrade forge or put a matching binary in $%s",
+		e.Required, e.Running, ToolcacheEnv)
+}
+
+// ToolcacheEnv names the directory holding other forge versions as <dir>/<version>/forge
+const ToolcacheEnv = "FORGE_TOOLCACHE"
+
+// CheckForgeVersion returns a *VersionError if the running forge does not satisfy ForgeConstraint
+func (w *Workflow) CheckForgeVersion() error {
+	required := w.ForgeConstraint()
+	if required == "" {
+		return nil
+	}
+	ok, err := version.Satisfies(required)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filename, []byte(tpl), 0644)
+	if !ok {
+		return &VersionError{Required: required, Running: version.Version}
+	}
+	return nil
+}
+
+// RequiredForge returns the requires.forge constraint of workflow data in the format of name,
+// migrated from an older schema version, without validating the rest
+func RequiredForge(data []byte, name string) (string, error) {
+	var wf struct {
+		Requires struct {
+			Forge string `yaml:"forge"`
+		} `yaml:"requires"`
+	}
+	data, err := toYAML(data, DetectFormat(name, data))
+	if err != nil {
+		return "", err
+	}
+	if data, err = migrate(normalizeTabs(data)); err != nil {
+		return "", err
+	}
+	if err := yaml.Unmarshal(data, &wf); err != nil {
+		return "", err
+	}
+	return wf.Requires.Forge, nil
+}
+
+// WriteTemplate creates a workflow file from the default template
+func WriteTemplate(filename string) error {
+	return WriteNamedTemplate(filename, DefaultTemplate)
 }
 
 // Normalize tabs to spaces in file content to improve ux and avoid yaml parsing issues
@@ -76,45 +602,3 @@ func normalizeTabs(data []byte) []byte {
 	normalized := strings.ReplaceAll(string(data), "\t", "  ")
 	return []byte(normalized)
 }
-
-// MinimalWorkflowExample returns a minimal example workflow in YAML format
-func minimalWorkflowExample() (string, error) {
-	wf := Workflow{
-		Name:        "example-forge-workflow",
-		Description: "Generated by forge init",
-		Stages: []Stage{
-			{
-				Name:        "hello-stage",
-				Description: "A very simple Stage with two steps",
-				Steps: []Step{
-					{
-						Name: "hello",
-						Type: StepTypeExec,
-						Run:  []string{"echo", "Hello from Forge"},
-					},
-					{
-						Name:    "pause",
-						Type:    StepTypeSleep,
-						Seconds: 1,
-					},
-				},
-			},
-			{
-				Name:        "goodbye-stage",
-				Description: "A second Stage to say goodbye",
-				Steps: []Step{
-					{
-						Name: "goodbye",
-						Type: StepTypeExec,
-						Run:  []string{"echo", "Goodbye from Forge"},
-					},
-				},
-			},
-		},
-	}
-	b, err := yaml.Marshal(&wf)
-	if err != nil {
-		return "", fmt.Errorf("Failed to generate a minimal workflow example: %w", err)
-	}
-	return "# Example Workflow YAML\n" + string(b), nil
-}
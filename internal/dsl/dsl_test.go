@@ -1,10 +1,15 @@
 package dsl
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"testing"
+	"time"
 
+	"github.com/andre-koe/forge/pkg/version"
 	yaml "github.com/goccy/go-yaml"
 )
 
@@ -315,3 +320,170 @@ stages:
 		})
 	}
 }
+
+func TestCheckForgeVersion(t *testing.T) {
+	orig := version.Version
+	version.Version = "v0.5.2"
+	defer func() { version.Version = orig }()
+
+	tests := []struct {
+		name     string
+		requires string
+		wantErr  bool
+	}{
+		{name: "no constraint", requires: ""},
+		{name: "satisfied", requires: ">=0.5"},
+		{name: "too old", requires: ">=0.6", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := Workflow{Name: "pinned", Requires: &Requires{Forge: tt.requires}}
+			err := wf.CheckForgeVersion()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckForgeVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var verErr *VersionError
+			if tt.wantErr && !errors.As(err, &verErr) {
+				t.Errorf("CheckForgeVersion() error = %T, want *VersionError", err)
+			}
+		})
+	}
+}
+
+func TestRequiredForge(t *testing.T) {
+	got, err := RequiredForge([]byte("name: x\nrequires:\n  forge: \">=0.5\"\nstages: oops\n"), "ci.yaml")
+	if err != nil {
+		t.Fatalf("RequiredForge() error: %v", err)
+	}
+	if got != ">=0.5" {
+		t.Errorf("RequiredForge() = %q, want %q", got, ">=0.5")
+	}
+
+	got, err = RequiredForge([]byte("name: x\nstages: []\n"), "ci.yaml")
+	if err != nil || got != "" {
+		t.Errorf("RequiredForge() without requires = %q, %v, want none", got, err)
+	}
+
+	got, err = RequiredForge([]byte("name = \"x\"\n[requires]\nforge = \">=0.7\"\n"), "ci.toml")
+	if err != nil {
+		t.Fatalf("RequiredForge() error: %v", err)
+	}
+	if got != ">=0.7" {
+		t.Errorf("RequiredForge() = %q, want %q", got, ">=0.7")
+	}
+}
+
+func TestReadVarFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vars.yaml")
+	if err := os.WriteFile(path, []byte("image: app\nreplicas: 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	vars, err := ReadVarFile(path)
+	if err != nil {
+		t.Fatalf("ReadVarFile() error: %v", err)
+	}
+	if vars["image"] != "app" || vars["replicas"] != "3" {
+		t.Errorf("ReadVarFile() = %v", vars)
+	}
+
+	for _, content := range []string{"image-tag: x\n", "list: [1, 2]\n"} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadVarFile(path); err == nil {
+			t.Errorf("ReadVarFile(%q) succeeded, want error", content)
+		}
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	for _, tpl := range Templates() {
+		t.Run(tpl.Name, func(t *testing.T) {
+			out, err := RenderTemplate(tpl.Name)
+			if err != nil {
+				t.Fatalf("RenderTemplate() error: %v", err)
+			}
+			wf, err := LoadWorkflow([]byte(out), tpl.Name)
+			if err != nil {
+				t.Fatalf("template %s is invalid: %v", tpl.Name, err)
+			}
+			if wf.SchemaVersion != CurrentSchemaVersion() {
+				t.Errorf("template %s has schema_version %d, want %d", tpl.Name, wf.SchemaVersion, CurrentSchemaVersion())
+			}
+		})
+	}
+
+	if _, err := RenderTemplate("nope"); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("RenderTemplate(nope) error = %v, want %v", err, ErrUnknownTemplate)
+	}
+}
+
+func TestWorkflow_StageOrder(t *testing.T) {
+	wf := Workflow{Stages: []Stage{
+		{Name: "cleanup", Finally: true},
+		{Name: "build"},
+		{Name: "report", Finally: true},
+		{Name: "test"},
+	}}
+	if got, want := wf.StageOrder(), []int{1, 3, 0, 2}; !slices.Equal(got, want) {
+		t.Errorf("StageOrder() = %v, want %v", got, want)
+	}
+}
+
+func TestWorkflow_StepDefaults(t *testing.T) {
+	zero := 0
+	wf := &Workflow{Defaults: &Defaults{Shell: "bash", Timeout: "15m", Retries: 2}}
+	tests := []struct {
+		name        string
+		step        Step
+		wantShell   string
+		wantTimeout time.Duration
+		wantRetries int
+	}{
+		{name: "defaults", step: Step{Type: StepTypeExec}, wantShell: "bash", wantTimeout: 15 * time.Minute, wantRetries: 2},
+		{name: "overrides", step: Step{Type: StepTypeExec, Shell: ShellNone, Timeout: "1h", Retries: &zero}, wantTimeout: time.Hour},
+		{name: "approval", step: Step{Type: StepTypeApproval}, wantTimeout: 15 * time.Minute},
+		{name: "assert", step: Step{Type: StepTypeAssert}, wantTimeout: 15 * time.Minute, wantRetries: 2},
+		{name: "group", step: Step{Type: StepTypeGroup}},
+		{name: "group with timeout and retries", step: Step{Type: StepTypeGroup, Timeout: "1h", Retries: &zero}, wantTimeout: time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wf.StepShell(&tt.step); got != tt.wantShell {
+				t.Errorf("StepShell() = %q, want %q", got, tt.wantShell)
+			}
+			if got := wf.StepTimeout(&tt.step); got != tt.wantTimeout {
+				t.Errorf("StepTimeout() = %s, want %s", got, tt.wantTimeout)
+			}
+			if got := wf.StepRetries(&tt.step); got != tt.wantRetries {
+				t.Errorf("StepRetries() = %d, want %d", got, tt.wantRetries)
+			}
+		})
+	}
+	if got := (&Workflow{}).StepTimeout(&Step{}); got != 0 {
+		t.Errorf("StepTimeout() without defaults = %s, want 0", got)
+	}
+}
+
+func TestStep_GroupSteps(t *testing.T) {
+	group := Step{Name: "ci", Type: StepTypeGroup, Workdir: "app", Env: map[string]string{"CI": "1", "MODE": "fast"}, CleanEnv: true, Steps: []Step{
+		{Name: "build", Type: StepTypeExec, Run: Command{"make"}},
+		{Name: "docs", Type: StepTypeExec, Run: Command{"make", "docs"}, Workdir: "docs", Env: map[string]string{"MODE": "full"}},
+	}}
+	steps := group.GroupSteps()
+	want := []Step{
+		{Name: "build", Type: StepTypeExec, Run: Command{"make"}, Workdir: "app", Env: map[string]string{"CI": "1", "MODE": "fast"}, CleanEnv: true},
+		{Name: "docs", Type: StepTypeExec, Run: Command{"make", "docs"}, Workdir: "docs", Env: map[string]string{"CI": "1", "MODE": "full"}, CleanEnv: true},
+	}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("GroupSteps() = %+v, want %+v", steps, want)
+	}
+	if group.Steps[0].Env != nil || group.Steps[1].Env["CI"] != "" {
+		t.Errorf("GroupSteps() changed the group's steps: %+v", group.Steps)
+	}
+	if steps := (&Step{Type: StepTypeExec}).GroupSteps(); len(steps) != 0 {
+		t.Errorf("GroupSteps() of an exec step = %+v, want none", steps)
+	}
+}
@@ -0,0 +1,121 @@
+package dsl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	yaml "github.com/goccy/go-yaml"
+)
+
+// Format is the syntax a workflow file is written in
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+	FormatTOML Format = "toml"
+	// FormatCUE and FormatJsonnet are evaluated to JSON by the preprocessor, see SetPreprocessor
+	FormatCUE     Format = "cue"
+	FormatJsonnet Format = "jsonnet"
+)
+
+// FileExtensions lists the extensions of workflow files, without the dot
+var FileExtensions = []string{"yaml", "yml", "json", "toml", "cue", "jsonnet"}
+
+// Preprocessor evaluates the workflow file at path, written in a programmable format such as
+// CUE, to JSON
+type Preprocessor func(format Format, path string) ([]byte, error)
+
+// preprocessor evaluates CUE and Jsonnet workflows; they fail to load without one
+var preprocessor Preprocessor
+
+// SetPreprocessor sets how CUE and Jsonnet workflow files are evaluated
+func SetPreprocessor(p Preprocessor) {
+	preprocessor = p
+}
+
+// preprocessed reports whether workflows of the format are evaluated by the preprocessor
+func (f Format) preprocessed() bool {
+	return f == FormatCUE || f == FormatJsonnet
+}
+
+// tomlStart matches the first line of TOML documents: a table header or a key = value pair
+var tomlStart = regexp.MustCompile(`^(\[\[?\s*[A-Za-z0-9_."-]+\s*\]\]?|[A-Za-z0-9_"-]+\s*=)`)
+
+// DetectFormat returns the format of workflow data by the extension of name, or else by its
+// first line: JSON starts with {, TOML with a table header or a key = value pair
+func DetectFormat(name string, data []byte) Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	case ".cue":
+		return FormatCUE
+	case ".jsonnet":
+		return FormatJsonnet
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "{"):
+			return FormatJSON
+		case tomlStart.MatchString(line):
+			return FormatTOML
+		}
+		break
+	}
+	return FormatYAML
+}
+
+// toYAML converts workflow data of the given format to YAML. JSON is YAML already and is
+// only checked, so that syntax errors read like JSON ones.
+func toYAML(data []byte, format Format) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		if err := json.Unmarshal(data, new(any)); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return data, nil
+	case FormatTOML:
+		var doc map[string]any
+		if _, err := toml.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+			return nil, fmt.Errorf("invalid TOML: %w", err)
+		}
+		return yaml.Marshal(doc)
+	case FormatCUE, FormatJsonnet:
+		return nil, fmt.Errorf("%s workflows are evaluated from files, they cannot be read from stdin, URLs or includes", format)
+	}
+	return data, nil
+}
+
+// evaluate runs the preprocessor on the workflow file at path
+func evaluate(format Format, path string) ([]byte, error) {
+	if preprocessor == nil {
+		return nil, fmt.Errorf("%s: no preprocessor for %s workflows", path, format)
+	}
+	return preprocessor(format, path)
+}
+
+// DecodeWorkflow decodes workflow data in the format of name, see DetectFormat, without
+// validating it or printing warnings
+func DecodeWorkflow(data []byte, name string) (*Workflow, error) {
+	return decodeWorkflow(data, DetectFormat(name, data))
+}
+
+func decodeWorkflow(data []byte, format Format) (*Workflow, error) {
+	data, err := toYAML(data, format)
+	if err != nil {
+		return nil, err
+	}
+	return ParseWorkflow(data)
+}
@@ -0,0 +1,138 @@
+package dsl
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Format
+	}{
+		{name: "ci.json", want: FormatJSON},
+		{name: "ci.TOML", want: FormatTOML},
+		{name: "ci.yml", data: "{\"name\": \"ci\"}", want: FormatYAML},
+		{name: "-", data: "\n  {\"name\": \"ci\"}", want: FormatJSON},
+		{name: "-", data: "# generated\nname = \"ci\"\n", want: FormatTOML},
+		{name: "https://example.com/ci", data: "[[stages]]\nname = \"build\"\n", want: FormatTOML},
+		{name: "-", data: "name: ci\nstages: []\n", want: FormatYAML},
+		{name: "-", data: "- a=b\n", want: FormatYAML},
+		{name: "-", data: "", want: FormatYAML},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat(tt.name, []byte(tt.data)); got != tt.want {
+			t.Errorf("DetectFormat(%q, %q) = %s, want %s", tt.name, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestLoadWorkflowFromFile_Formats(t *testing.T) {
+	const yamlWorkflow = `name: ci
+vars:
+  tag: dev
+stages:
+  - name: build
+    steps:
+      - name: compile
+        type: exec
+        run: [go, build, ./...]
+      - name: wait
+        type: sleep
+        seconds: 2
+`
+	const jsonWorkflow = `{
+	"name": "ci",
+	"vars": {"tag": "dev"},
+	"stages": [
+		{"name": "build", "steps": [
+			{"name": "compile", "type": "exec", "run": ["go", "build", "./..."]},
+			{"name": "wait", "type": "sleep", "seconds": 2}
+		]}
+	]
+}
+`
+	const tomlWorkflow = `name = "ci"
+
+[vars]
+tag = "dev"
+
+[[stages]]
+name = "build"
+
+[[stages.steps]]
+name = "compile"
+type = "exec"
+run = ["go", "build", "./..."]
+
+[[stages.steps]]
+name = "wait"
+type = "sleep"
+seconds = 2
+`
+	dir := t.TempDir()
+	load := func(name, content string) *Workflow {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		wf, err := LoadWorkflowFromFile(path)
+		if err != nil {
+			t.Fatalf("LoadWorkflowFromFile(%s) error: %v", name, err)
+		}
+		return wf
+	}
+
+	want := load("ci.yaml", yamlWorkflow)
+	for name, content := range map[string]string{"ci.json": jsonWorkflow, "ci.toml": tomlWorkflow} {
+		if got := load(name, content); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %+v, want %+v", name, got, want)
+		}
+	}
+
+	if _, err := LoadWorkflow([]byte(tomlWorkflow), "-"); err != nil {
+		t.Errorf("LoadWorkflow() of sniffed TOML error: %v", err)
+	}
+	for name, content := range map[string]string{"ci.json": `{"name": "ci",}`, "ci.toml": "name = ci\n"} {
+		if _, err := LoadWorkflow([]byte(content), name); err == nil || !strings.Contains(err.Error(), "invalid "+strings.ToUpper(filepath.Ext(name)[1:])) {
+			t.Errorf("LoadWorkflow(%s) error = %v, want a syntax error", name, err)
+		}
+	}
+}
+
+func TestLoadWorkflowFromFile_Preprocessed(t *testing.T) {
+	prev := preprocessor
+	defer func() { preprocessor = prev }()
+	var evaluated []string
+	SetPreprocessor(func(format Format, path string) ([]byte, error) {
+		evaluated = append(evaluated, string(format)+" "+path)
+		return []byte(`{"name": "ci", "stages": [{"name": "build", "steps": [{"name": "make", "type": "exec", "run": ["make"]}]}]}`), nil
+	})
+
+	wf, err := LoadWorkflowFromFile("ci.cue")
+	if err != nil {
+		t.Fatalf("LoadWorkflowFromFile() error: %v", err)
+	}
+	if wf.Name != "ci" || len(wf.Stages) != 1 {
+		t.Errorf("LoadWorkflowFromFile() = %+v, want the evaluated workflow", wf)
+	}
+	if _, err := LoadWorkflowFromFile("ci.jsonnet"); err != nil {
+		t.Fatalf("LoadWorkflowFromFile() error: %v", err)
+	}
+	if want := []string{"cue ci.cue", "jsonnet ci.jsonnet"}; !reflect.DeepEqual(evaluated, want) {
+		t.Errorf("evaluated %q, want %q", evaluated, want)
+	}
+
+	if _, err := LoadWorkflow([]byte("{}"), "https://example.com/ci.cue"); err == nil || !strings.Contains(err.Error(), "cue workflows are evaluated from files") {
+		t.Errorf("LoadWorkflow() error = %v, want CUE data refused", err)
+	}
+	preprocessor = nil
+	if _, err := LoadWorkflowFromFile("ci.cue"); err == nil || !strings.Contains(err.Error(), "no preprocessor for cue workflows") {
+		t.Errorf("LoadWorkflowFromFile() error = %v, want no preprocessor", err)
+	}
+}
@@ -0,0 +1,167 @@
+package dsl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/matcher"
+)
+
+// Include pulls the stages, variables and problem matchers of another workflow file into a workflow.
+// Exactly one of a local Path, a URL or a Git repository is the source.
+type Include struct {
+	// Path is a file relative to the including workflow, or the file inside the Git repository
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// URL is an https URL of the file; SHA256 is required with it
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+	// Git is the URL of a repository containing the file at Path, checked out at Ref
+	Git string `yaml:"git,omitempty" json:"git,omitempty"`
+	Ref string `yaml:"ref,omitempty" json:"ref,omitempty"`
+	// SHA256 is the expected hex digest of the file's content
+	SHA256 string `yaml:"sha256,omitempty" json:"sha256,omitempty"`
+}
+
+// Remote reports whether the include is fetched from a URL or Git repository
+func (i *Include) Remote() bool {
+	return i.URL != "" || i.Git != ""
+}
+
+// String identifies the source in messages and lock files
+func (i *Include) String() string {
+	switch {
+	case i.URL != "":
+		return i.URL
+	case i.Git != "":
+		return i.Git + "@" + i.Ref + ":" + i.Path
+	default:
+		return i.Path
+	}
+}
+
+// Validate validates an include
+func (i *Include) Validate() error {
+	sources := 0
+	for _, s := range []string{i.URL, i.Git} {
+		if s != "" {
+			sources++
+		}
+	}
+	if i.Git == "" && i.Path != "" {
+		sources++
+	}
+	switch {
+	case sources != 1:
+		return errors.New("include requires exactly one of 'path', 'url' or 'git'")
+	case i.URL != "" && !strings.HasPrefix(i.URL, "https://"):
+		return fmt.Errorf("include %s: only https URLs are supported", i.URL)
+	case i.URL != "" && i.SHA256 == "":
+		return fmt.Errorf("include %s: 'sha256' is required for URLs", i.URL)
+	case i.Git != "" && (i.Ref == "" || i.Path == ""):
+		return fmt.Errorf("include %s: 'ref' and 'path' are required for git", i.Git)
+	case strings.HasPrefix(i.Git, "-") || strings.HasPrefix(i.Ref, "-"):
+		return fmt.Errorf("include %s: 'git' and 'ref' must not start with '-'", i)
+	case i.SHA256 != "" && !isSHA256(i.SHA256):
+		return fmt.Errorf("include %s: 'sha256' must be 64 hex digits", i)
+	}
+	return nil
+}
+
+func isSHA256(s string) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == sha256.Size
+}
+
+// CheckSHA256 verifies data against the include's digest when it has one
+func (i *Include) CheckSHA256(data []byte) error {
+	if i.SHA256 == "" {
+		return nil
+	}
+	digest := sha256.Sum256(data)
+	if got := hex.EncodeToString(digest[:]); !strings.EqualFold(got, i.SHA256) {
+		return fmt.Errorf("include %s: checksum mismatch: expected %s, got %s", i, i.SHA256, got)
+	}
+	return nil
+}
+
+// IncludeFetcher returns the content of a remote include of the named workflow
+type IncludeFetcher func(workflow string, inc Include) ([]byte, error)
+
+// includeFetcher fetches remote includes; workflows with remote includes fail to load without one
+var includeFetcher IncludeFetcher
+
+// SetIncludeFetcher sets how includes from URLs and Git repositories are fetched
+func SetIncludeFetcher(f IncludeFetcher) {
+	includeFetcher = f
+}
+
+// resolveIncludes merges the included files into the workflow loaded from name: their stages
+// run before the workflow's own, and the workflow's variables and matchers win over theirs
+func (w *Workflow) resolveIncludes(name string) error {
+	if len(w.Include) == 0 {
+		return nil
+	}
+	base := "."
+	if name != "-" && !strings.Contains(name, "://") {
+		base = filepath.Dir(name)
+	}
+
+	var stages []Stage
+	var matchers []matcher.Definition
+	vars := make(map[string]string)
+	for _, inc := range w.Include {
+		if err := inc.Validate(); err != nil {
+			return err
+		}
+		data, err := readInclude(name, base, inc)
+		if err != nil {
+			return fmt.Errorf("include %s: %w", &inc, err)
+		}
+		if err := inc.CheckSHA256(data); err != nil {
+			return err
+		}
+		part, err := DecodeWorkflow(data, inc.String())
+		if err != nil {
+			return fmt.Errorf("include %s: %w", &inc, err)
+		}
+		if len(part.Include) > 0 {
+			return fmt.Errorf("include %s: included files cannot include others", &inc)
+		}
+		stages = append(stages, part.Stages...)
+		matchers = append(matchers, part.ProblemMatchers...)
+		maps.Copy(vars, part.Vars)
+	}
+
+	w.Stages = append(stages, w.Stages...)
+	w.ProblemMatchers = append(matchers, w.ProblemMatchers...)
+	if len(vars) > 0 {
+		maps.Copy(vars, w.Vars)
+		w.Vars = vars
+	}
+	w.included, w.Include = w.Include, nil
+	return nil
+}
+
+// Included returns the includes merged into the workflow when it was loaded from a file
+func (w *Workflow) Included() []Include {
+	return w.included
+}
+
+func readInclude(name, base string, inc Include) ([]byte, error) {
+	if !inc.Remote() {
+		path := inc.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(base, path)
+		}
+		return os.ReadFile(path)
+	}
+	if includeFetcher == nil {
+		return nil, errors.New("remote includes are not supported here")
+	}
+	return includeFetcher(name, inc)
+}
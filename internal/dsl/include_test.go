@@ -0,0 +1,137 @@
+package dsl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const includedFragment = `vars:
+  go: "1.22"
+  region: eu
+stages:
+  - name: lint
+    steps:
+      - name: vet
+        type: exec
+        run: ["go", "vet", "./..."]
+`
+
+func TestLoadWorkflow_Include(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "shared"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "shared", "lint.yaml"), []byte(includedFragment), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(includedFragment))
+	digest := hex.EncodeToString(sum[:])
+
+	workflow := func(include string) string {
+		return `name: ci
+include:
+` + include + `
+vars:
+  region: us
+stages:
+  - name: build
+    depends_on: [lint]
+    steps:
+      - name: compile
+        type: exec
+        run: ["go", "build"]
+`
+	}
+	load := func(t *testing.T, include string) (*Workflow, error) {
+		t.Helper()
+		path := filepath.Join(dir, "ci.yaml")
+		if err := os.WriteFile(path, []byte(workflow(include)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return LoadWorkflowFromFile(path)
+	}
+
+	wf, err := load(t, "  - path: shared/lint.yaml\n    sha256: "+digest)
+	if err != nil {
+		t.Fatalf("LoadWorkflowFromFile() error: %v", err)
+	}
+	var names []string
+	for _, s := range wf.Stages {
+		names = append(names, s.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"lint", "build"}) {
+		t.Errorf("stages = %v, want the included stage first", names)
+	}
+	if want := map[string]string{"go": "1.22", "region": "us"}; !reflect.DeepEqual(wf.Vars, want) {
+		t.Errorf("vars = %v, want %v", wf.Vars, want)
+	}
+	if wf.Include != nil {
+		t.Error("includes are kept after resolving them")
+	}
+
+	tests := []struct {
+		name, include, wantErr string
+	}{
+		{"checksum mismatch", "  - path: shared/lint.yaml\n    sha256: " + strings.Repeat("ab", 32), "checksum mismatch"},
+		{"missing file", "  - path: shared/missing.yaml", "no such file"},
+		{"url without sha256", "  - url: https://example.com/lint.yaml", "'sha256' is required"},
+		{"plain http", "  - url: http://example.com/lint.yaml\n    sha256: " + digest, "only https"},
+		{"git without ref", "  - git: https://example.com/pipelines.git\n    path: lint.yaml", "'ref' and 'path'"},
+		{"git option as repository", "  - git: --upload-pack=touch /tmp/pwned\n    ref: main\n    path: lint.yaml", "must not start with '-'"},
+		{"git option as ref", "  - git: https://example.com/pipelines.git\n    ref: --upload-pack=id\n    path: lint.yaml", "must not start with '-'"},
+		{"two sources", "  - path: shared/lint.yaml\n    url: https://example.com/lint.yaml", "exactly one"},
+		{"no fetcher", "  - url: https://example.com/lint.yaml\n    sha256: " + digest, "not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := load(t, tt.include); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadWorkflowFromFile() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadWorkflow_RemoteInclude(t *testing.T) {
+	t.Cleanup(func() { SetIncludeFetcher(nil) })
+	var fetched []string
+	SetIncludeFetcher(func(workflow string, inc Include) ([]byte, error) {
+		fetched = append(fetched, workflow+" "+inc.String())
+		if inc.Ref == "missing" {
+			return nil, errors.New("unknown ref")
+		}
+		return []byte(includedFragment), nil
+	})
+
+	data := `name: ci
+include:
+  - git: https://example.com/pipelines.git
+    ref: v1
+    path: go/lint.yaml
+stages:
+  - name: build
+    steps:
+      - name: compile
+        type: exec
+        run: ["go", "build"]
+`
+	wf, err := LoadWorkflow([]byte(data), "ci.yaml")
+	if err != nil {
+		t.Fatalf("LoadWorkflow() error: %v", err)
+	}
+	if len(wf.Stages) != 2 || wf.Stages[0].Name != "lint" {
+		t.Errorf("stages = %+v, want the fetched stage first", wf.Stages)
+	}
+	if want := []string{"ci.yaml https://example.com/pipelines.git@v1:go/lint.yaml"}; !reflect.DeepEqual(fetched, want) {
+		t.Errorf("fetched = %q, want %q", fetched, want)
+	}
+
+	if _, err := LoadWorkflow([]byte(strings.Replace(data, "ref: v1", "ref: missing", 1)), "ci.yaml"); err == nil || !strings.Contains(err.Error(), "unknown ref") {
+		t.Errorf("LoadWorkflow() error = %v, want the fetcher's error", err)
+	}
+}
@@ -0,0 +1,38 @@
+package dsl
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// knownOS and knownArch are the GOOS and GOARCH values a platforms entry may name
+var (
+	knownOS   = []string{"aix", "android", "darwin", "dragonfly", "freebsd", "illumos", "ios", "js", "linux", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows"}
+	knownArch = []string{"386", "amd64", "arm", "arm64", "loong64", "mips", "mips64", "mips64le", "mipsle", "ppc64", "ppc64le", "riscv64", "s390x", "wasm"}
+)
+
+// validatePlatforms checks that every entry names an OS, an architecture or an OS/architecture pair
+func validatePlatforms(platforms []string) error {
+	for _, p := range platforms {
+		goos, goarch, pair := strings.Cut(p, "/")
+		switch {
+		case pair && slices.Contains(knownOS, goos) && slices.Contains(knownArch, goarch):
+		case !pair && (slices.Contains(knownOS, p) || slices.Contains(knownArch, p)):
+		default:
+			return fmt.Errorf("unknown platform %q: use an OS like linux, an architecture like arm64, or both like darwin/arm64", p)
+		}
+	}
+	return nil
+}
+
+// MatchPlatform reports whether a stage or step limited to platforms runs on goos/goarch;
+// an empty list matches every platform
+func MatchPlatform(platforms []string, goos, goarch string) bool {
+	if len(platforms) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(platforms, func(p string) bool {
+		return p == goos || p == goarch || p == goos+"/"+goarch
+	})
+}
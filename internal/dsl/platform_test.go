@@ -0,0 +1,43 @@
+package dsl
+
+import "testing"
+
+func TestValidatePlatforms(t *testing.T) {
+	tests := []struct {
+		name      string
+		platforms []string
+		wantErr   bool
+	}{
+		{name: "none"},
+		{name: "os and arch", platforms: []string{"linux", "arm64", "darwin/arm64"}},
+		{name: "unknown os", platforms: []string{"macos"}, wantErr: true},
+		{name: "unknown arch in pair", platforms: []string{"linux/x86"}, wantErr: true},
+		{name: "arch as os", platforms: []string{"amd64/linux"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePlatforms(tt.platforms); (err != nil) != tt.wantErr {
+				t.Errorf("validatePlatforms(%v) error = %v, wantErr %v", tt.platforms, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatchPlatform(t *testing.T) {
+	tests := []struct {
+		platforms []string
+		want      bool
+	}{
+		{platforms: nil, want: true},
+		{platforms: []string{"linux"}, want: true},
+		{platforms: []string{"darwin", "windows"}, want: false},
+		{platforms: []string{"arm64"}, want: true},
+		{platforms: []string{"linux/amd64"}, want: false},
+		{platforms: []string{"linux/arm64"}, want: true},
+	}
+	for _, tt := range tests {
+		if got := MatchPlatform(tt.platforms, "linux", "arm64"); got != tt.want {
+			t.Errorf("MatchPlatform(%v, linux, arm64) = %v, want %v", tt.platforms, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package dsl
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/matcher"
+	"github.com/andre-koe/forge/internal/notify"
+)
+
+// fieldDocs describes every YAML key of the DSL, keyed by "<Type>.<key>"
+var fieldDocs = map[string]string{
+	"Workflow.schema_version":   "Version of the workflow format, `1` when unset. Files of an older version are migrated when loaded; newer ones fail with an upgrade hint.",
+	"Workflow.name":             "Name of the workflow.",
+	"Workflow.description":      "Free-form description.",
+	"Workflow.timezone":         "IANA time zone for displayed timestamps, e.g. `UTC`. Defaults to the local zone.",
+	"Workflow.schedule":         "Cron expression (`minute hour day month weekday`, or `@daily`, `@hourly`, ...) on which `forge schedule` runs the workflow, evaluated in `timezone`.",
+	"Workflow.concurrency":      "Group, e.g. `deploy-${{ vars.env }}`, of runs that must not overlap: a run waits for the runs of its group in progress, from any workflow in the directory, to finish. Either the group or `{group: ..., cancel_in_progress: true}`.",
+	"Workflow.requires":         "Tools and forge version checked before the run starts.",
+	"Workflow.include":          "Files whose stages run before the workflow's own and whose `vars` and `problem_matchers` apply unless the workflow redefines them.",
+	"Workflow.vars":             "Variables and their default values, referenced as `${{ vars.NAME }}`; override with `--var` and `--var-file`.",
+	"Workflow.profiles":         "Named overrides such as `dev`, `staging` and `prod`, selected with `forge run --profile prod`.",
+	"Workflow.workdir":          "Directory, relative to the workflow file, that commands run in unless a stage or step sets its own. Defaults to the directory forge runs in (or `--workdir`).",
+	"Workflow.defaults":         "Settings for every step that does not set them itself: `shell`, `workdir`, `timeout`, `retries` and `env`.",
+	"Workflow.changes_base":     "Git ref that stages with `changes` are compared against, e.g. `origin/main`. Defaults to `HEAD` (uncommitted changes).",
+	"Workflow.env_file":         "Dotenv file (`KEY=VALUE` lines) whose variables are exported to all steps and available as `${{ env.KEY }}`.",
+	"Workflow.problem_matchers": "Custom problem matchers that steps can reference by name.",
+	"Workflow.notifications":    "Where run lifecycle events are delivered.",
+	"Workflow.stages":           "Stages, executed in order.",
+	"Workflow.on_success":       "Steps run after all stages succeeded.",
+	"Workflow.on_failure":       "Steps run after a stage failed or the run was cancelled.",
+	"Workflow.step_hooks":       "Commands run around every exec step: `before` ahead of the step's own `before`, `after` following its own `after`.",
+	"Workflow.always":           "Steps run at the end of every run, after `on_success`/`on_failure`, even if it failed or was cancelled; use them for cleanup.",
+
+	"Concurrency.group":              "Name of the group; `${{ vars.NAME }}` and `${{ env.NAME }}` are expanded.",
+	"Concurrency.cancel_in_progress": "Cancel the runs of the group in progress, queued ones included, instead of waiting for them; the newest run wins.",
+
+	"Stage.name":              "Name of the stage.",
+	"Stage.description":       "What the stage does, at most 200 characters; shown below its header in the run output, in `forge list` and in reports.",
+	"Stage.depends_on":        "Stages that must complete first; they have to be declared earlier.",
+	"Stage.finally":           "Run the stage after all other stages, even if one failed or the run was cancelled; its failure does not replace an earlier error.",
+	"Stage.on_error":          "What a failure of the stage does: `abort` (default) fails the run, `continue` reports it and runs the next stages, `retry` runs the whole stage again.",
+	"Stage.max_stage_retries": "With `on_error: retry`, how often the stage is run again after failing. Defaults to 1.",
+	"Stage.steps":             "Steps, executed in order.",
+	"Stage.artifacts":         "Path globs, relative to the stage's workdir, kept in `.forge/artifacts/<run-id>/` after the steps ran, even if one failed.",
+	"Stage.workdir":           "Directory, relative to the workflow file, that the stage's commands run in; overrides the workflow's `workdir`.",
+	"Stage.env":               "Environment variables for the processes of the stage's steps; values may use `${{ }}` expressions.",
+	"Stage.clean_env":         "Start the processes of every step in the stage from an empty environment plus the declared variables, see `clean_env` on steps.",
+	"Stage.platforms":         "Operating systems (`linux`), architectures (`arm64`) or both (`darwin/arm64`) the stage runs on; elsewhere its steps are reported as skipped.",
+	"Stage.runs_on":           "Where the stage runs instead of forge's own process, see RunsOn; a plain string is the `ssh` URL, or `kubernetes`.",
+	"Stage.throttle":          "Paces the steps, e.g. `{steps: 1, per: 10s, jitter: 2s}` for APIs with rate limits.",
+	"Stage.schedule_window":   "When the stage may run: `[days] HH:MM-HH:MM [timezone]`, e.g. `Mon-Fri 09:00-17:00 Europe/Berlin`; without a timezone the workflow's `timezone` applies.",
+	"Stage.outside_window":    "What the stage does when it is due outside `schedule_window`: `fail` (default), `skip` or `wait` until the window opens.",
+	"Stage.changes":           "Path globs (`**` matches any directories) relative to the working directory; the stage is skipped unless a file changed since `changes_base` matches.",
+	"Stage.restore":           "Stages whose artifacts are copied into the stage's workdir before the steps run; from this run, or the latest run that kept them.",
+	"Stage.on_success":        "Steps run after the stage's steps succeeded.",
+	"Stage.on_failure":        "Steps run after a step of the stage failed or the run was cancelled.",
+	"Stage.always":            "Steps run after the stage's steps whatever the outcome, after `on_success`/`on_failure`.",
+
+	"Step.name":               "Name of the step.",
+	"Step.id":                 "Identifier other steps use to reference this one as `steps.<id>.status` (`success`, `failed`, `skipped`, `cached`, empty before it ran) and `steps.<id>.output` (its standard output, trimmed); unique in the workflow.",
+	"Step.description":        "What the step does, at most 200 characters; shown below its header in the run output, in `forge list` and in reports.",
+	"Step.type":               "Step type, see below.",
+	"Step.when":               "Condition for running the step, e.g. `steps.deploy.status == 'failed'` or `${{ env.CI }}`; expressions compare with `==`/`!=` and combine with `&&`, `||` and `!`. The step is skipped unless it is true (anything but empty, `false` or `0`).",
+	"Step.run":                "`exec`: command and arguments, or one command line such as `go test ./...` that is split into them like a shell splits words (pipes, variables and globs need `shell`); `${{ env.NAME }}` expands environment variables and `${{ vars.NAME }}` workflow variables.",
+	"Step.user":               "`exec`: run the command as this user, by name or numeric id, e.g. to drop root privileges; its primary and supplementary groups apply too (Unix only).",
+	"Step.group":              "`exec`: run the command with this group, by name or numeric id (Unix only).",
+	"Step.tty":                "`exec`: run the command in a pseudo-terminal, for programs such as `ssh`, `sudo` or `docker run -it` that need one; its stderr is merged into stdout.",
+	"Step.seconds":            "`sleep`: how long to sleep.",
+	"Step.base":               "`go-test`: git revision to diff against, default `origin/main`.",
+	"Step.args":               "`go-test`: extra `go test` flags.",
+	"Step.env":                "Environment variables for the step's processes, overriding the stage's `env`; values may use `${{ }}` expressions.",
+	"Step.clean_env":          "Start the step's processes from an empty environment plus `env` (and `--env`, env files and ports) instead of inheriting forge's; pass variables through with e.g. `PATH: ${{ env.PATH }}`.",
+	"Step.platforms":          "Operating systems (`linux`), architectures (`arm64`) or both (`darwin/arm64`) the step runs on; elsewhere it is reported as skipped.",
+	"Step.shell":              "Shell that runs an exec step's `run` as a script, e.g. `bash` or `bash -eo pipefail`: the words are joined with spaces and passed with `-c`. `none` runs the command directly despite a default shell.",
+	"Step.expand_globs":       "`exec`: expand `~` and glob patterns such as `dist/*.tar.gz` in the arguments of `run` before it runs, relative to the step's directory; a pattern matching no files fails the step. Not with `shell`, which expands globs itself.",
+	"Step.stdin":              "`exec`: text passed to the standard input of `run`, e.g. a here-document; `${{ }}` expressions are expanded.",
+	"Step.stdin_file":         "`exec`: file, relative to the step's directory, that `run` reads its standard input from. Not with `stdin`.",
+	"Step.stdout_file":        "`exec`: file, relative to the step's directory, that the standard output of `run` is written to instead of the run output; it is truncated first.",
+	"Step.stderr_file":        "`exec`: file that the standard error of `run` is written to, like `stdout_file`; naming the same file as `stdout_file` writes both to it.",
+	"Step.timeout":            "Bounds each command the step runs, e.g. `15m`. Defaults to `defaults.timeout`, else 10 minutes.",
+	"Step.retries":            "How often the step is run again after failing; `0` overrides `defaults.retries`. Cancelled steps are not retried.",
+	"Step.workdir":            "Directory, relative to the workflow file, that the step's commands run in; overrides the stage's `workdir`.",
+	"Step.matchers":           "Problem matchers applied to the step's output.",
+	"Step.path":               "`snapshot`: directory to save.",
+	"Step.snapshot":           "`restore`: name of the snapshot step to restore.",
+	"Step.restore_on_failure": "`snapshot`: restore the directory automatically if the run fails.",
+	"Step.slack":              "`slack`: the message to post.",
+	"Step.before":             "Commands run before `run`, each a list of arguments; the step fails without running `run` if one fails.",
+	"Step.after":              "Commands run after `run`, even if the step failed or the run was cancelled.",
+	"Step.ports":              "TCP ports by name: `auto` or a fixed number, exported as `FORGE_PORT_<NAME>`.",
+	"Step.approval":           "Message and timeout of an approval step.",
+	"Step.assert":             "Command and expectations of an assert step.",
+	"Step.cache":              "Skip the step when its definition and key files are unchanged since its last successful run.",
+	"Step.dry_run":            "What `forge dry-run` does with the step: `simulate` (default) prints what it would do, `execute` runs it, for read-only commands such as `terraform plan`, and `skip` leaves it out. `execute` is only accepted on exec steps.",
+	"Step.idempotency_key":    "Key, e.g. `create-bucket-${{ vars.bucket }}`, that skips the step once a step with the same key succeeded in a run recorded in the history; `forge run --force` runs it anyway.",
+	"Step.with":               "Settings of a plugin step type; string values may use `${{ env.NAME }}` and `${{ vars.NAME }}`.",
+	"Step.steps":              "`group`: steps run in order as one unit; they take the group's `env`, `clean_env` and `workdir` unless they set their own. The group's `timeout` bounds all of them and its `retries` run them all again.",
+
+	"Requires.tools": "Executables that must be on `PATH`, optionally with a version constraint read from `--version`, e.g. `kubectl>=1.28`.",
+	"Requires.forge": "Version constraint for forge, e.g. `>=0.5, <1.0`.",
+
+	"Include.path":   "File relative to the workflow, or the file inside the `git` repository.",
+	"Include.url":    "`https://` URL of the file; requires `sha256`.",
+	"Include.git":    "Repository URL; the file at `path` is read at `ref`.",
+	"Include.ref":    "Branch, tag or commit of the `git` repository; the commit it resolved to is pinned in `<workflow>.lock`.",
+	"Include.sha256": "Expected SHA-256 digest of the file.",
+
+	"Defaults.shell":                "Shell for exec steps without a `shell` of their own, see the step's `shell`.",
+	"Defaults.workdir":              "Directory commands run in unless a stage or step sets its own, like the workflow's `workdir` (set one of them).",
+	"Defaults.timeout":              "Timeout of each command of steps without a `timeout` of their own, e.g. `15m`.",
+	"Defaults.retries":              "How often failed steps without `retries` of their own are run again; approval steps are never retried.",
+	"Defaults.env":                  "Environment variables for every step, overridden by the `env` of stages and steps; values may use `${{ }}` expressions.",
+	"Profile.vars":                  "Values for variables declared in `vars`; `--var` still wins.",
+	"Profile.env":                   "Environment variables for every step; values may use `${{ }}` expressions and `--env` wins.",
+	"Profile.secrets":               "Environment variables like `env` whose values dry runs and plans do not show, e.g. `${{ env.PROD_DB_PASSWORD }}`.",
+	"RunsOn.agent":                  "Labels an agent needs to run the stage, e.g. `[linux, gpu]`; `forge serve` sends the whole stage to an idle `forge agent --join` with all of them, the agent's name, OS and architecture counting as labels, and streams its output back. Other runs of the workflow fail such stages.",
+	"RunsOn.ssh":                    "`ssh://[user@]host[:port][/dir]` the commands of the stage's exec, assert and go_test steps and hooks run on, with `ssh` in batch mode: the step's environment is passed along, relative workdirs are below `dir` (the login directory when omitted), redirects and the step's output stay on this machine, and globs are not expanded. The workflow itself does not change. Other steps run here.",
+	"RunsOn.kubernetes":             "Pod the commands of the stage's exec, assert and go_test steps and hooks run in, through `kubectl exec` and `sh -c` with the step's environment passed along; a plain `kubernetes` uses the defaults. forge starts the pod with `kubectl run` when the stage starts and deletes it when the stage is done, failed or cancelled; the step's output is streamed back, while artifacts and redirects stay on this machine.",
+	"Kubernetes.image":              "Container image of the pod, e.g. `golang:1.24`; it needs `sh`. Defaults to `alpine:3`.",
+	"Kubernetes.namespace":          "Namespace of the pod, the one of the current kubectl context when omitted.",
+	"Kubernetes.resources":          "CPU and memory the pod requests and is limited to.",
+	"PodResources.cpu":              "CPU in Kubernetes units, e.g. `500m` or `2`.",
+	"PodResources.memory":           "Memory in Kubernetes units, e.g. `512Mi` or `4Gi`.",
+	"Throttle.steps":                "How many steps may start within `per`. Defaults to 1.",
+	"Throttle.per":                  "Interval the step limit applies to, e.g. `10s`.",
+	"Throttle.jitter":               "Upper bound of a random delay before every step but the first, e.g. `2s`.",
+	"Approval.message":              "Question shown to approvers, e.g. `Deploy ${{ vars.tag }} to production?`.",
+	"Approval.timeout":              "How long to wait for a decision, e.g. `30m`, before the step is denied. Defaults to 1h.",
+	"Assert.command":                "Command to run, e.g. `[curl, -s, https://example.com/healthz]`; may use `${{ }}` expressions.",
+	"Assert.expect_exit_code":       "Exit code the command has to end with. Defaults to 0.",
+	"Assert.expect_stdout_contains": "Strings the standard output has to contain; may use `${{ }}` expressions.",
+	"Assert.expect_stdout_matches":  "Regular expressions (Go syntax) the standard output has to match, e.g. `\"status\":\\s*\"ok\"`.",
+	"Assert.expect_stderr_contains": "Strings the standard error has to contain; may use `${{ }}` expressions.",
+	"StepHooks.before":              "Commands run before every exec step, each a list of arguments.",
+	"StepHooks.after":               "Commands run after every exec step, even if it failed.",
+	"Cache.key_files":               "Path globs relative to the step's workdir (directories are hashed recursively) that make up the cache key, e.g. `go.sum`; a glob matching no files runs the step uncached with a warning.",
+
+	"Definition.name":     "Name steps use to reference the matcher.",
+	"Definition.pattern":  "Regular expression with the named groups `file`, `line`, `column`, `severity` and `message`.",
+	"Definition.severity": "Severity when the pattern has no `severity` group: `error`, `warning` or `notice`.",
+
+	"Notifications.webhooks": "URLs that receive run events as JSON `POST` requests.",
+	"Notifications.slack":    "Slack messages posted when the run ends.",
+
+	"Webhook.url":     "Endpoint to post events to; may use `${{ env.NAME }}`.",
+	"Webhook.events":  "Events to deliver: `run_started`, `step_failed`, `run_finished`. Defaults to all.",
+	"Webhook.secret":  "Key for the `X-Forge-Signature-256: sha256=<hmac>` header; use `${{ env.NAME }}` rather than a literal.",
+	"Webhook.retries": "Additional delivery attempts with exponential backoff. Defaults to 3.",
+
+	"Slack.webhook_url": "Incoming webhook URL, e.g. `${{ env.SLACK_WEBHOOK_URL }}`.",
+	"Slack.token":       "Bot token used with `channel` instead of a webhook, e.g. `${{ env.SLACK_BOT_TOKEN }}`.",
+	"Slack.channel":     "Channel to post to with a bot token.",
+	"Slack.on":          "In `notifications`: `success` and/or `failure`. Defaults to both.",
+	"Slack.message":     "Go template with `.Workflow`, `.RunID`, `.Status`, `.Duration`, `.Stage`, `.Step` and `.Error`; a default summary when empty.",
+	"Slack.retries":     "Additional delivery attempts with exponential backoff. Defaults to 3.",
+}
+
+var stepTypeDocs = []struct {
+	Type StepType
+	Doc  string
+}{
+	{StepTypeExec, "Runs the command in `run`."},
+	{StepTypeSleep, "Sleeps for `seconds`."},
+	{StepTypeGoTest, "Runs `go test` for packages affected by changes since `base`."},
+	{StepTypeSnapshot, "Saves the directory in `path`."},
+	{StepTypeRestore, "Restores the directory saved by the `snapshot` step."},
+	{StepTypeSlack, "Posts `slack.message` (or a run summary) to Slack."},
+	{StepTypeApproval, "Waits until the run is approved on the terminal, with an `--approval-token` or through `forge serve`; denied on timeout."},
+	{StepTypeAssert, "Runs `assert.command` and fails unless its exit code and output meet the `expect_*` settings."},
+	{StepTypeGroup, "Runs `steps` in order, shown and reported as one step; stops at the first that fails."},
+}
+
+// referenceTypes are documented in this order
+var referenceTypes = []reflect.Type{
+	reflect.TypeFor[Workflow](),
+	reflect.TypeFor[Stage](),
+	reflect.TypeFor[Step](),
+	reflect.TypeFor[Concurrency](),
+	reflect.TypeFor[Requires](),
+	reflect.TypeFor[Include](),
+	reflect.TypeFor[Defaults](),
+	reflect.TypeFor[Profile](),
+	reflect.TypeFor[RunsOn](),
+	reflect.TypeFor[Kubernetes](),
+	reflect.TypeFor[PodResources](),
+	reflect.TypeFor[Throttle](),
+	reflect.TypeFor[Approval](),
+	reflect.TypeFor[Assert](),
+	reflect.TypeFor[StepHooks](),
+	reflect.TypeFor[Cache](),
+	reflect.TypeFor[matcher.Definition](),
+	reflect.TypeFor[Notifications](),
+	reflect.TypeFor[notify.Webhook](),
+	reflect.TypeFor[notify.Slack](),
+}
+
+// WriteReference renders a Markdown reference of the workflow DSL
+func WriteReference(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# Workflow DSL reference\n")
+
+	for _, t := range referenceTypes {
+		title := t.Name()
+		if t == reflect.TypeFor[matcher.Definition]() {
+			title = "ProblemMatcher"
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n| Key | Type | Description |\n|-----|------|-------------|\n", title)
+		for _, f := range yamlFields(t) {
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", f.key, typeName(f.typ), fieldDocs[t.Name()+"."+f.key])
+		}
+	}
+
+	b.WriteString("\n## Step types\n\n| Type | Description |\n|------|-------------|\n")
+	for _, st := range stepTypeDocs {
+		fmt.Fprintf(&b, "| `%s` | %s |\n", st.Type, st.Doc)
+	}
+	b.WriteString("\nAny other type `<name>` runs the plugin `forge-step-<name>` found on `PATH`, configured with `with`.\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type yamlField struct {
+	key string
+	typ reflect.Type
+}
+
+func yamlFields(t reflect.Type) []yamlField {
+	var fields []yamlField
+	for i := range t.NumField() {
+		f := t.Field(i)
+		key, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		fields = append(fields, yamlField{key: key, typ: f.Type})
+	}
+	return fields
+}
+
+func typeName(t reflect.Type) string {
+	switch t {
+	case reflect.TypeFor[Command]():
+		return "string or list of string"
+	case reflect.TypeFor[*Concurrency]():
+		return "string or Concurrency"
+	case reflect.TypeFor[*RunsOn]():
+		return "string or RunsOn"
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeName(t.Elem())
+	case reflect.Slice:
+		return "list of " + typeName(t.Elem())
+	case reflect.Map:
+		return "map of " + typeName(t.Elem())
+	case reflect.Struct:
+		if t == reflect.TypeFor[matcher.Definition]() {
+			return "ProblemMatcher"
+		}
+		return t.Name()
+	default:
+		return t.Kind().String()
+	}
+}
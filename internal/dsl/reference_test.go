@@ -0,0 +1,37 @@
+package dsl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteReference(t *testing.T) {
+	var b strings.Builder
+	if err := WriteReference(&b); err != nil {
+		t.Fatalf("WriteReference() error: %v", err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"## Workflow",
+		"| `stages` | list of Stage |",
+		"| `ports` | map of string |",
+		"| `problem_matchers` | list of ProblemMatcher |",
+		"| `go-test` |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("reference missing %q", want)
+		}
+	}
+}
+
+// Every YAML key needs a description so the reference does not silently fall behind the DSL
+func TestReferenceDocumentsAllFields(t *testing.T) {
+	for _, typ := range referenceTypes {
+		for _, f := range yamlFields(typ) {
+			if fieldDocs[typ.Name()+"."+f.key] == "" {
+				t.Errorf("no description for %s.%s in fieldDocs", typ.Name(), f.key)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package dsl
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"text/template"
+
+	yaml "github.com/goccy/go-yaml"
+)
+
+// expression matches ${{ }} expressions, which RenderWorkflow leaves for forge to evaluate
+var expression = regexp.MustCompile(`\$\{\{(.*?)\}\}`)
+
+// RenderWorkflow renders workflow data through text/template with values as dot, e.g. to
+// generate a stage per service with {{ range .services }}. ${{ }} expressions are kept as they
+// are and keys missing from values are errors; name identifies the source in messages.
+func RenderWorkflow(data []byte, name string, values map[string]any) ([]byte, error) {
+	// Print ${{ and }} as strings so the template parser does not see the expression as an action
+	text := expression.ReplaceAllString(string(data), `{{"$${{"}}$1{{"}}"}}`)
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ReadTemplateValues reads the YAML mapping a workflow is rendered with, see RenderWorkflow
+func ReadTemplateValues(filename string) (map[string]any, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]any)
+	if err := yaml.Unmarshal(normalizeTabs(data), &values); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return values, nil
+}
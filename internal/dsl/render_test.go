@@ -0,0 +1,68 @@
+package dsl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderWorkflow(t *testing.T) {
+	const workflow = `name: services
+stages:
+{{- range .services }}
+  - name: build-{{ .name }}
+    steps:
+      - name: build
+        type: exec
+        run: ["docker", "build", "-t", "{{ .name }}:${{ vars.tag }}", "{{ .path }}"]
+{{- end }}
+`
+	values := map[string]any{"services": []any{
+		map[string]any{"name": "api", "path": "services/api"},
+		map[string]any{"name": "web", "path": "services/web"},
+	}}
+	out, err := RenderWorkflow([]byte(workflow), "services.yaml", values)
+	if err != nil {
+		t.Fatalf("RenderWorkflow() error: %v", err)
+	}
+	wf, err := ParseWorkflow(out)
+	if err != nil {
+		t.Fatalf("rendered workflow does not parse: %v\n%s", err, out)
+	}
+	if len(wf.Stages) != 2 || wf.Stages[1].Name != "build-web" {
+		t.Fatalf("stages = %+v, want one per service", wf.Stages)
+	}
+	if got, want := strings.Join(wf.Stages[0].Steps[0].Run, " "), "docker build -t api:${{ vars.tag }} services/api"; got != want {
+		t.Errorf("run = %q, want %q", got, want)
+	}
+
+	for _, tc := range []struct{ data, wantErr string }{
+		{"name: {{ .missing }}\n", `map has no entry for key "missing"`},
+		{"name: {{ .name \n", "services.yaml"},
+	} {
+		if _, err := RenderWorkflow([]byte(tc.data), "services.yaml", map[string]any{"name": "x"}); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("RenderWorkflow(%q) error = %v, want %q", tc.data, err, tc.wantErr)
+		}
+	}
+}
+
+func TestReadTemplateValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(path, []byte("replicas: 3\nservices: [api, web]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	values, err := ReadTemplateValues(path)
+	if err != nil {
+		t.Fatalf("ReadTemplateValues() error: %v", err)
+	}
+	if services, ok := values["services"].([]any); !ok || len(services) != 2 {
+		t.Errorf("values = %v, want the services list", values)
+	}
+	if err := os.WriteFile(path, []byte("- a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadTemplateValues(path); err == nil {
+		t.Error("ReadTemplateValues() of a list succeeded, want an error")
+	}
+}
@@ -0,0 +1,166 @@
+package dsl
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// RunsOn is where the steps of a stage run; stages without it run in forge's own process
+type RunsOn struct {
+	// Agent sends the whole stage to a `forge agent` that joined the server running the workflow
+	// and has all of these labels
+	Agent []string `yaml:"agent,omitempty,flow" json:"agent,omitempty"`
+	// SSH runs the commands of the stage's steps on a host, ssh://[user@]host[:port][/dir]
+	SSH string `yaml:"ssh,omitempty" json:"ssh,omitempty"`
+	// Kubernetes runs the commands of the stage's steps in a pod forge starts for the stage
+	Kubernetes *Kubernetes `yaml:"kubernetes,omitempty" json:"kubernetes,omitempty"`
+}
+
+// DefaultPodImage is the image of pods of stages on Kubernetes without an image
+const DefaultPodImage = "alpine:3"
+
+// Kubernetes is the pod the steps of a stage run in; forge deletes it once the stage is done
+type Kubernetes struct {
+	// Image is the container image, DefaultPodImage when empty; it needs sh
+	Image string `yaml:"image,omitempty" json:"image,omitempty"`
+	// Namespace is the namespace of the pod, the one of the kubectl context when empty
+	Namespace string        `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	Resources *PodResources `yaml:"resources,omitempty" json:"resources,omitempty"`
+}
+
+// PodImage returns the image of the pod
+func (k *Kubernetes) PodImage() string {
+	if k.Image == "" {
+		return DefaultPodImage
+	}
+	return k.Image
+}
+
+// PodResources is what the pod of a stage requests, and is limited to
+type PodResources struct {
+	CPU    string `yaml:"cpu,omitempty" json:"cpu,omitempty"`
+	Memory string `yaml:"memory,omitempty" json:"memory,omitempty"`
+}
+
+var (
+	namespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+	quantityPattern  = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(m|k|Ki|M|Mi|G|Gi|T|Ti|P|Pi|E|Ei)?$`)
+)
+
+func (k *Kubernetes) validate() error {
+	if strings.ContainsAny(k.Image, " \t\n") {
+		return fmt.Errorf("invalid image %q", k.Image)
+	}
+	if k.Namespace != "" && !namespacePattern.MatchString(k.Namespace) {
+		return fmt.Errorf("invalid namespace %q, namespaces are lowercase letters, digits and '-'", k.Namespace)
+	}
+	if r := k.Resources; r != nil {
+		for _, q := range []struct{ name, value string }{{"cpu", r.CPU}, {"memory", r.Memory}} {
+			if q.value != "" && !quantityPattern.MatchString(q.value) {
+				return fmt.Errorf("resources: invalid %s %q, e.g. 500m or 2 for cpu and 512Mi or 4Gi for memory", q.name, q.value)
+			}
+		}
+	}
+	return nil
+}
+
+// runsOn has the fields of RunsOn without its methods, so decoding it does not recurse
+type runsOn RunsOn
+
+// UnmarshalYAML accepts a plain string as the ssh:// URL of the host the stage runs on, or
+// "kubernetes" for a pod with the default settings
+func (r *RunsOn) UnmarshalYAML(unmarshal func(any) error) error {
+	var target string
+	if err := unmarshal(&target); err == nil {
+		if target == "kubernetes" {
+			*r = RunsOn{Kubernetes: &Kubernetes{}}
+		} else {
+			*r = RunsOn{SSH: target}
+		}
+		return nil
+	}
+	return unmarshal((*runsOn)(r))
+}
+
+// MarshalYAML writes a stage running on a host as the plain ssh:// URL, and one running in a
+// pod with the default settings as "kubernetes"
+func (r RunsOn) MarshalYAML() (any, error) {
+	switch {
+	case len(r.Agent) > 0:
+	case r.SSH != "" && r.Kubernetes == nil:
+		return r.SSH, nil
+	case r.SSH == "" && r.Kubernetes != nil && *r.Kubernetes == (Kubernetes{}):
+		return "kubernetes", nil
+	}
+	return runsOn(r), nil
+}
+
+func (r *RunsOn) validate() error {
+	targets := 0
+	for _, set := range []bool{len(r.Agent) > 0, r.SSH != "", r.Kubernetes != nil} {
+		if set {
+			targets++
+		}
+	}
+	switch {
+	case targets > 1:
+		return errors.New("'agent', 'ssh' and 'kubernetes' are mutually exclusive")
+	case r.SSH != "":
+		_, err := ParseSSH(r.SSH)
+		return err
+	case r.Kubernetes != nil:
+		if err := r.Kubernetes.validate(); err != nil {
+			return fmt.Errorf("kubernetes: %w", err)
+		}
+		return nil
+	case len(r.Agent) == 0:
+		return errors.New("requires 'agent' with the labels of the agents the stage may run on, an ssh:// URL or kubernetes")
+	}
+	for _, label := range r.Agent {
+		if strings.TrimSpace(label) == "" || strings.ContainsAny(label, ", ") {
+			return fmt.Errorf("agent label %q must be a non-empty word", label)
+		}
+	}
+	return nil
+}
+
+// SSHHost is a host stages run on over ssh
+type SSHHost struct {
+	// User logs in, the one of the ssh configuration when empty
+	User string
+	Host string
+	// Port is the ssh port, the one of the ssh configuration when empty
+	Port string
+	// Dir is the directory commands start in, relative workdirs included; the login directory when empty
+	Dir string
+}
+
+// ParseSSH parses an ssh://[user@]host[:port][/dir] URL
+func ParseSSH(s string) (SSHHost, error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "ssh" || u.Hostname() == "" || u.Opaque != "" {
+		return SSHHost{}, fmt.Errorf("%q must be an ssh://[user@]host[:port][/dir] URL", s)
+	}
+	if _, ok := u.User.Password(); ok || u.RawQuery != "" || u.Fragment != "" {
+		return SSHHost{}, fmt.Errorf("ssh URL %q must not have a password, query or fragment; configure ssh instead", s)
+	}
+	if strings.HasPrefix(u.Hostname(), "-") || strings.HasPrefix(u.User.Username(), "-") {
+		return SSHHost{}, fmt.Errorf("ssh URL %q has an invalid user or host", s)
+	}
+	dir := u.Path
+	if dir == "/" {
+		dir = ""
+	}
+	return SSHHost{User: u.User.Username(), Host: u.Hostname(), Port: u.Port(), Dir: dir}, nil
+}
+
+// String returns the [user@]host the commands run on
+func (h SSHHost) String() string {
+	if h.User == "" {
+		return h.Host
+	}
+	return h.User + "@" + h.Host
+}
@@ -0,0 +1,101 @@
+package dsl
+
+import (
+	"errors"
+	"fmt"
+
+	yaml "github.com/goccy/go-yaml"
+)
+
+// schemaMigrations upgrade workflow documents one schema version at a time: the i-th one turns
+// a document of version i+1 into one of version i+2. Documents are decoded YAML mappings.
+var schemaMigrations = []func(doc map[string]any) error{
+	migrateRequiresForge,
+}
+
+// migrateRequiresForge moves the requires_forge of version 1 to requires.forge
+func migrateRequiresForge(doc map[string]any) error {
+	constraint, ok := doc["requires_forge"]
+	if !ok {
+		return nil
+	}
+	delete(doc, "requires_forge")
+	requires, _ := doc["requires"].(map[string]any)
+	switch {
+	case requires == nil && doc["requires"] != nil:
+		return errors.New("requires must be a mapping")
+	case requires == nil:
+		requires = map[string]any{}
+	}
+	if _, ok := requires["forge"]; ok {
+		return errors.New("requires_forge and requires.forge cannot both be set")
+	}
+	requires["forge"] = constraint
+	doc["requires"] = requires
+	return nil
+}
+
+// CurrentSchemaVersion returns the schema version of workflows this forge writes; older ones
+// are migrated when they are loaded
+func CurrentSchemaVersion() int {
+	return len(schemaMigrations) + 1
+}
+
+// SchemaError reports a workflow written for a newer forge than the running one
+type SchemaError struct {
+	Version   int
+	Supported int
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("workflow uses schema_version %d but this forge supports up to %d; upgrade forge or put a newer binary in $%s",
+		e.Version, e.Supported, ToolcacheEnv)
+}
+
+// checkSchemaVersion reports schema versions this forge cannot read; zero stands for the current one
+func checkSchemaVersion(v int) error {
+	switch {
+	case v < 0:
+		return fmt.Errorf("invalid schema_version %d, versions start at 1", v)
+	case v > CurrentSchemaVersion():
+		return &SchemaError{Version: v, Supported: CurrentSchemaVersion()}
+	}
+	return nil
+}
+
+// migrate upgrades YAML workflow data of an older schema version to the current one and returns
+// it unchanged when it is current; data without schema_version is of version 1
+func migrate(data []byte) ([]byte, error) {
+	var head struct {
+		SchemaVersion *int `yaml:"schema_version"`
+	}
+	if err := yaml.Unmarshal(data, &head); err != nil {
+		return nil, err
+	}
+	v := 1
+	if head.SchemaVersion != nil {
+		v = *head.SchemaVersion
+	}
+	if v < 1 {
+		return nil, fmt.Errorf("invalid schema_version %d, versions start at 1", v)
+	}
+	if err := checkSchemaVersion(v); err != nil {
+		return nil, err
+	}
+	current := CurrentSchemaVersion()
+	if v == current {
+		return data, nil
+	}
+
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	for ; v < current; v++ {
+		if err := schemaMigrations[v-1](doc); err != nil {
+			return nil, fmt.Errorf("migrating schema_version %d to %d: %w", v, v+1, err)
+		}
+	}
+	doc["schema_version"] = current
+	return yaml.Marshal(doc)
+}
@@ -0,0 +1,114 @@
+package dsl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const schemaStages = "stages:\n  - name: build\n    steps:\n      - name: make\n        type: exec\n        run: [make]\n"
+
+func TestParseWorkflow_SchemaVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    int
+		wantErr string
+	}{
+		{name: "unversioned", header: "name: ci\n", want: 2},
+		{name: "older", header: "schema_version: 1\nname: ci\n", want: 2},
+		{name: "current", header: "schema_version: 2\nname: ci\n", want: 2},
+		{name: "newer", header: "schema_version: 3\nname: ci\n", wantErr: "workflow uses schema_version 3 but this forge supports up to 2; upgrade forge"},
+		{name: "zero", header: "schema_version: 0\nname: ci\n", wantErr: "invalid schema_version 0"},
+		{name: "not a number", header: "schema_version: v1\nname: ci\n", wantErr: "schema_version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf, err := LoadWorkflow([]byte(tt.header+schemaStages), "ci.yaml")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadWorkflow() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadWorkflow() error: %v", err)
+			}
+			if wf.SchemaVersion != tt.want {
+				t.Errorf("SchemaVersion = %d, want %d", wf.SchemaVersion, tt.want)
+			}
+		})
+	}
+
+	var schemaErr *SchemaError
+	if _, err := ParseWorkflow([]byte("schema_version: 7\n")); !errors.As(err, &schemaErr) || schemaErr.Version != 7 || schemaErr.Supported != 2 {
+		t.Errorf("ParseWorkflow() error = %#v, want a *SchemaError for version 7", err)
+	}
+	if err := (&Workflow{Name: "ci", SchemaVersion: 3}).Validate(); !errors.As(err, &schemaErr) {
+		t.Errorf("Validate() error = %v, want a *SchemaError", err)
+	}
+}
+
+func TestParseWorkflow_RequiresForge(t *testing.T) {
+	tests := []struct {
+		name, header, want, wantErr string
+	}{
+		{name: "version 1", header: "name: ci\nrequires_forge: \">=0.5\"\n", want: ">=0.5"},
+		{name: "version 1 with tools", header: "schema_version: 1\nname: ci\nrequires_forge: \">=0.5\"\nrequires:\n  tools: [make]\n", want: ">=0.5"},
+		{name: "both", header: "name: ci\nrequires_forge: \">=0.5\"\nrequires:\n  forge: \">=0.4\"\n", wantErr: "requires_forge and requires.forge cannot both be set"},
+		{name: "current", header: "schema_version: 2\nname: ci\nrequires:\n  forge: \">=0.4\"\n", want: ">=0.4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf, err := ParseWorkflow([]byte(tt.header + schemaStages))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseWorkflow() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseWorkflow() error: %v", err)
+			}
+			if got := wf.ForgeConstraint(); got != tt.want {
+				t.Errorf("ForgeConstraint() = %q, want %q", got, tt.want)
+			}
+			if got, err := RequiredForge([]byte(tt.header), "ci.yaml"); err != nil || got != tt.want {
+				t.Errorf("RequiredForge() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWorkflow_Migrations(t *testing.T) {
+	prev := schemaMigrations
+	defer func() { schemaMigrations = prev }()
+	// Version 1 called the description "summary"
+	schemaMigrations = []func(map[string]any) error{func(doc map[string]any) error {
+		if summary, ok := doc["summary"]; ok {
+			doc["description"] = summary
+			delete(doc, "summary")
+		}
+		return nil
+	}}
+
+	for _, header := range []string{"name: ci\nsummary: Build it\n", "schema_version: 1\nname: ci\nsummary: Build it\n"} {
+		wf, err := LoadWorkflow([]byte(header+schemaStages), "ci.yaml")
+		if err != nil {
+			t.Fatalf("LoadWorkflow(%q) error: %v", header, err)
+		}
+		if wf.SchemaVersion != 2 || wf.Description != "Build it" || len(wf.Stages) != 1 {
+			t.Errorf("LoadWorkflow(%q) = version %d, description %q, want the migrated workflow", header, wf.SchemaVersion, wf.Description)
+		}
+	}
+
+	wf, err := ParseWorkflow([]byte("schema_version: 2\nname: ci\ndescription: current\n"))
+	if err != nil || wf.Description != "current" {
+		t.Errorf("ParseWorkflow() = %+v, %v, want the current version unchanged", wf, err)
+	}
+
+	schemaMigrations = []func(map[string]any) error{func(map[string]any) error { return errors.New("stages must be a list") }}
+	if _, err := ParseWorkflow([]byte("name: ci\n")); err == nil || !strings.Contains(err.Error(), "migrating schema_version 1 to 2: stages must be a list") {
+		t.Errorf("ParseWorkflow() error = %v, want the migration error", err)
+	}
+}
@@ -0,0 +1,211 @@
+package dsl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// DefaultTemplate is the template used by forge init when none is requested
+const DefaultTemplate = "hello"
+
+var ErrUnknownTemplate = errors.New("unknown template")
+
+// Template is a starter workflow offered by forge init
+type Template struct {
+	Name        string
+	Description string
+	workflow    func() Workflow
+}
+
+var templates = []Template{
+	{Name: "hello", Description: "Minimal two-stage example", workflow: helloWorkflow},
+	{Name: "go-ci", Description: "Lint, test and build a Go module", workflow: goCIWorkflow},
+	{Name: "docker-build", Description: "Build, smoke-test and push a Docker image", workflow: dockerBuildWorkflow},
+	{Name: "deploy", Description: "Build, deploy and verify a release", workflow: deployWorkflow},
+	{Name: "monorepo", Description: "Test and build several services of one repository", workflow: monorepoWorkflow},
+}
+
+// Templates returns the available templates in catalog order
+func Templates() []Template {
+	return templates
+}
+
+// RenderTemplate returns the YAML of the named template
+func RenderTemplate(name string) (string, error) {
+	for _, t := range templates {
+		if t.Name != name {
+			continue
+		}
+		wf := t.workflow()
+		b, err := wf.MarshalYAML()
+		if err != nil {
+			return "", fmt.Errorf("Failed to generate the %s workflow template: %w", name, err)
+		}
+		return fmt.Sprintf("# %s\n%s", t.Description, b), nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+}
+
+// WriteNamedTemplate creates a workflow file from the named template
+func WriteNamedTemplate(filename, name string) error {
+	tpl, err := RenderTemplate(name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, []byte(tpl), 0644)
+}
+
+func helloWorkflow() Workflow {
+	return Workflow{
+		Name:        "example-forge-workflow",
+		Description: "Generated by forge init",
+		Stages: []Stage{
+			{
+				Name:        "hello-stage",
+				Description: "A very simple Stage with two steps",
+				Steps: []Step{
+					{
+						Name: "hello",
+						Type: StepTypeExec,
+						Run:  []string{"echo", "Hello from Forge"},
+					},
+					{
+						Name:    "pause",
+						Type:    StepTypeSleep,
+						Seconds: 1,
+					},
+				},
+			},
+			{
+				Name:        "goodbye-stage",
+				Description: "A second Stage to say goodbye",
+				Steps: []Step{
+					{
+						Name: "goodbye",
+						Type: StepTypeExec,
+						Run:  []string{"echo", "Goodbye from Forge"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func goCIWorkflow() Workflow {
+	return Workflow{
+		Name:        "go-ci",
+		Description: "Generated by forge init --template go-ci",
+		Stages: []Stage{
+			{
+				Name: "lint",
+				Steps: []Step{
+					{Name: "vet", Type: StepTypeExec, Run: []string{"go", "vet", "./..."}, Matchers: []string{"go"}},
+				},
+			},
+			{
+				Name:      "test",
+				DependsOn: []string{"lint"},
+				Steps: []Step{
+					{Name: "unit", Type: StepTypeExec, Run: []string{"go", "test", "-race", "./..."}, Matchers: []string{"go"}},
+				},
+			},
+			{
+				Name:      "build",
+				DependsOn: []string{"test"},
+				Steps: []Step{
+					{Name: "binary", Type: StepTypeExec, Run: []string{"go", "build", "-o", "bin/", "./..."}},
+				},
+			},
+		},
+	}
+}
+
+func dockerBuildWorkflow() Workflow {
+	image := "example/app:latest"
+	return Workflow{
+		Name:        "docker-build",
+		Description: "Generated by forge init --template docker-build",
+		Stages: []Stage{
+			{
+				Name: "build",
+				Steps: []Step{
+					{Name: "image", Type: StepTypeExec, Run: []string{"docker", "build", "-t", image, "."}},
+				},
+			},
+			{
+				Name:      "smoke-test",
+				DependsOn: []string{"build"},
+				Steps: []Step{
+					{Name: "run", Type: StepTypeExec, Run: []string{"docker", "run", "--rm", image, "--version"}},
+				},
+			},
+			{
+				Name:      "publish",
+				DependsOn: []string{"smoke-test"},
+				Steps: []Step{
+					{Name: "push", Type: StepTypeExec, Run: []string{"docker", "push", image}},
+				},
+			},
+		},
+	}
+}
+
+func deployWorkflow() Workflow {
+	return Workflow{
+		Name:        "deploy",
+		Description: "Generated by forge init --template deploy",
+		Stages: []Stage{
+			{
+				Name: "build",
+				Steps: []Step{
+					{Name: "package", Type: StepTypeExec, Run: []string{"make", "build"}},
+				},
+			},
+			{
+				Name:      "deploy",
+				DependsOn: []string{"build"},
+				Steps: []Step{
+					{Name: "release", Type: StepTypeExec, Run: []string{"./scripts/deploy.sh", "staging"}},
+					{Name: "settle", Type: StepTypeSleep, Seconds: 10},
+				},
+			},
+			{
+				Name:      "verify",
+				DependsOn: []string{"deploy"},
+				Steps: []Step{
+					{Name: "health", Type: StepTypeExec, Run: []string{"curl", "--fail", "--retry", "5", "https://staging.example.com/healthz"}},
+				},
+			},
+		},
+	}
+}
+
+func monorepoWorkflow() Workflow {
+	return Workflow{
+		Name:        "monorepo",
+		Description: "Generated by forge init --template monorepo",
+		Stages: []Stage{
+			{
+				Name: "test",
+				Steps: []Step{
+					{Name: "affected", Type: StepTypeGoTest, Base: "origin/main", Matchers: []string{"go"}},
+				},
+			},
+			{
+				Name:      "build-api",
+				DependsOn: []string{"test"},
+				Steps: []Step{
+					{Name: "build", Type: StepTypeExec, Run: []string{"go", "build", "-o", "bin/api", "./services/api"}},
+				},
+			},
+			{
+				Name:      "build-web",
+				DependsOn: []string{"test"},
+				Steps: []Step{
+					{Name: "build", Type: StepTypeExec, Run: []string{"npm", "--prefix", "services/web", "run", "build"}},
+				},
+			},
+		},
+	}
+}
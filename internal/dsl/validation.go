@@ -3,6 +3,22 @@ package dsl
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/andre-koe/forge/internal/artifact"
+	"github.com/andre-koe/forge/internal/cron"
+	"github.com/andre-koe/forge/internal/expr"
+	"github.com/andre-koe/forge/internal/glob"
+	"github.com/andre-koe/forge/internal/matcher"
+	"github.com/andre-koe/forge/internal/window"
+	"github.com/andre-koe/forge/pkg/version"
 )
 
 func (w *Workflow) Validate() error {
@@ -10,16 +26,196 @@ func (w *Workflow) Validate() error {
 		return errors.New("workflow name is required")
 	}
 
+	if err := checkSchemaVersion(w.SchemaVersion); err != nil {
+		return err
+	}
+
+	if w.Requires != nil {
+		if w.Requires.Forge != "" {
+			if _, err := version.ParseConstraint(w.Requires.Forge); err != nil {
+				return fmt.Errorf("requires.forge: %w", err)
+			}
+		}
+		if _, err := w.RequiredTools(); err != nil {
+			return fmt.Errorf("requires.tools: %w", err)
+		}
+	}
+
+	if _, err := w.Location(); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", w.Timezone, err)
+	}
+
+	if w.Schedule != "" {
+		if _, err := cron.Parse(w.Schedule); err != nil {
+			return fmt.Errorf("schedule: %w", err)
+		}
+	}
+
+	if w.Concurrency != nil && strings.TrimSpace(w.Concurrency.Group) == "" {
+		return errors.New("concurrency: group is required")
+	}
+
+	for name := range w.Vars {
+		if err := ValidateVarName(name); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(w.Profiles)) {
+		if err := w.validateProfile(name); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+	}
+
+	if w.Defaults != nil {
+		if err := w.validateDefaults(); err != nil {
+			return fmt.Errorf("defaults: %w", err)
+		}
+	}
+
+	for _, def := range w.ProblemMatchers {
+		if _, err := matcher.Compile(def); err != nil {
+			return err
+		}
+	}
+
+	if w.Notifications != nil {
+		for i, hook := range w.Notifications.Webhooks {
+			if err := hook.Validate(); err != nil {
+				return fmt.Errorf("notifications: webhook %d: %w", i, err)
+			}
+		}
+		for i, slack := range w.Notifications.Slack {
+			if err := slack.Validate(); err != nil {
+				return fmt.Errorf("notifications: slack %d: %w", i, err)
+			}
+		}
+	}
+
+	if w.StepHooks != nil {
+		if err := validateCommands("step_hooks: before", w.StepHooks.Before); err != nil {
+			return err
+		}
+		if err := validateCommands("step_hooks: after", w.StepHooks.After); err != nil {
+			return err
+		}
+	}
+
 	if len(w.Stages) == 0 {
 		return errors.New("workflow must have at least one stage")
 	}
 
+	declared := make(map[string]bool)
+	finally := make(map[string]bool)
+	snapshots := make(map[string]bool)
+	ids := make(map[string]bool)
+	var checkSteps func(steps []Step) error
+	checkSteps = func(steps []Step) error {
+		for _, step := range steps {
+			if step.ID != "" {
+				if ids[step.ID] {
+					return fmt.Errorf("step %s: id %q is already used by another step", step.Name, step.ID)
+				}
+				ids[step.ID] = true
+			}
+			if _, err := matcher.Resolve(step.Matchers, w.ProblemMatchers); err != nil {
+				return fmt.Errorf("step %s: %w", step.Name, err)
+			}
+			if step.Type == StepTypeExec && step.Run.IsLine() && w.StepShell(&step) == "" {
+				if _, err := splitCommand(step.Run[0], step.ExpandGlobs); err != nil {
+					return fmt.Errorf("step %s: run: %w; set shell, e.g. shell: sh, or write run as a list", step.Name, err)
+				}
+			}
+			if step.ExpandGlobs && w.StepShell(&step) != "" {
+				return fmt.Errorf("step %s: expand_globs is for steps run without a shell, the shell expands globs itself", step.Name)
+			}
+			switch step.Type {
+			case StepTypeSnapshot:
+				if snapshots[step.Name] {
+					return fmt.Errorf("step %s: snapshot name is already used; restore steps refer to snapshots by name, so it must be unique in the workflow", step.Name)
+				}
+				snapshots[step.Name] = true
+			case StepTypeRestore:
+				if !snapshots[step.Snapshot] {
+					return fmt.Errorf("step %s: snapshot %q must name a snapshot step declared before it", step.Name, step.Snapshot)
+				}
+			}
+			if err := checkSteps(step.GroupSteps()); err != nil {
+				return fmt.Errorf("step %s: %w", step.Name, err)
+			}
+		}
+		return nil
+	}
 	for i, stage := range w.Stages {
 		if err := stage.Validate(); err != nil {
 			return fmt.Errorf("stage %d (%s): %w", i, stage.Name, err)
 		}
+		if declared[stage.Name] {
+			return fmt.Errorf("stage %d (%s): name is already used by another stage", i, stage.Name)
+		}
+		for _, steps := range [][]Step{stage.Steps, stage.OnSuccess, stage.OnFailure, stage.Always} {
+			if err := checkSteps(steps); err != nil {
+				return fmt.Errorf("stage %d (%s): %w", i, stage.Name, err)
+			}
+		}
+		for _, dep := range stage.DependsOn {
+			if !declared[dep] {
+				return fmt.Errorf("stage %d (%s): depends_on %q must name a stage declared before it", i, stage.Name, dep)
+			}
+			if finally[dep] && !stage.Finally {
+				return fmt.Errorf("stage %d (%s): depends_on %q names a finally stage, which runs after it", i, stage.Name, dep)
+			}
+		}
+		declared[stage.Name] = true
+		finally[stage.Name] = stage.Finally
+	}
+
+	for i, stage := range w.Stages {
+		for _, name := range stage.Restore {
+			j := slices.IndexFunc(w.Stages, func(s Stage) bool { return s.Name == name })
+			if j < 0 || len(w.Stages[j].Artifacts) == 0 {
+				return fmt.Errorf("stage %d (%s): restore %q must name a stage with artifacts", i, stage.Name, name)
+			}
+		}
+	}
+
+	hooks := w.Hooks()
+	for _, kind := range HookKinds {
+		if err := validateHooks(kind, hooks[kind]); err != nil {
+			return err
+		}
+		if err := checkSteps(hooks[kind]); err != nil {
+			return fmt.Errorf("%s: %w", kind, err)
+		}
+	}
+
+	return nil
+}
+
+func validateHooks(kind string, steps []Step) error {
+	names := make(map[string]bool)
+	for i, step := range steps {
+		if err := step.Validate(); err != nil {
+			return fmt.Errorf("%s step %d (%s): %w", kind, i, step.Name, err)
+		}
+		if names[step.Name] {
+			return fmt.Errorf("%s step %d (%s): name is already used by another %s step", kind, i, step.Name, kind)
+		}
+		names[step.Name] = true
+		if step.IdempotencyKey != "" {
+			return fmt.Errorf("%s step %d (%s): hooks do not accept 'idempotency_key'", kind, i, step.Name)
+		}
 	}
+	return nil
+}
 
+// MaxDescriptionLength is the number of characters a description: of a stage or step may have
+const MaxDescriptionLength = 200
+
+func validateDescription(description string) error {
+	if n := utf8.RuneCountInString(description); n > MaxDescriptionLength {
+		return fmt.Errorf("description has %d characters, at most %d are allowed", n, MaxDescriptionLength)
+	}
 	return nil
 }
 
@@ -29,25 +225,121 @@ func (s *Stage) Validate() error {
 		return errors.New("stage name is required")
 	}
 
+	if err := validateDescription(s.Description); err != nil {
+		return err
+	}
+
 	if len(s.Steps) == 0 {
 		return errors.New("stage must have at least one step")
 	}
 
+	names := make(map[string]bool)
 	for i, step := range s.Steps {
 		if err := step.Validate(); err != nil {
 			return fmt.Errorf("step %d (%s): %w", i, step.Name, err)
 		}
+		if names[step.Name] {
+			return fmt.Errorf("step %d (%s): name is already used by another step of the stage", i, step.Name)
+		}
+		names[step.Name] = true
+	}
+
+	hooks := s.Hooks()
+	for _, kind := range HookKinds {
+		if err := validateHooks(kind, hooks[kind]); err != nil {
+			return err
+		}
+	}
+
+	for _, pattern := range s.Artifacts {
+		if err := artifact.ValidatePattern(pattern); err != nil {
+			return err
+		}
+	}
+
+	for _, pattern := range s.Changes {
+		if err := glob.Validate(pattern); err != nil {
+			return fmt.Errorf("changes %q: %w", pattern, err)
+		}
+	}
+
+	if err := validatePlatforms(s.Platforms); err != nil {
+		return err
+	}
+
+	if err := validateEnv(s.Env); err != nil {
+		return err
+	}
+
+	switch s.OnError {
+	case "", OnErrorAbort, OnErrorContinue, OnErrorRetry:
+	default:
+		return fmt.Errorf("on_error must be %s, %s or %s, got %q", OnErrorAbort, OnErrorContinue, OnErrorRetry, s.OnError)
+	}
+	if s.MaxStageRetries < 0 {
+		return errors.New("max_stage_retries must not be negative")
+	}
+	if s.MaxStageRetries > 0 && s.OnError != OnErrorRetry {
+		return errors.New("max_stage_retries requires on_error: retry")
+	}
+
+	if s.Throttle != nil {
+		if err := s.Throttle.validate(); err != nil {
+			return fmt.Errorf("throttle: %w", err)
+		}
+	}
+
+	if s.RunsOn != nil {
+		if err := s.RunsOn.validate(); err != nil {
+			return fmt.Errorf("runs_on: %w", err)
+		}
+	}
+
+	if s.ScheduleWindow != "" {
+		if _, err := window.Parse(s.ScheduleWindow); err != nil {
+			return err
+		}
+	}
+	switch s.OutsideWindow {
+	case "", WindowWait, WindowSkip, WindowFail:
+	default:
+		return fmt.Errorf("outside_window must be %s, %s or %s, got %q", WindowWait, WindowSkip, WindowFail, s.OutsideWindow)
+	}
+	if s.OutsideWindow != "" && s.ScheduleWindow == "" {
+		return errors.New("outside_window requires schedule_window")
 	}
 
 	return nil
 }
 
+// stepTypeSupported reports whether a step type without built-in support is provided by an executor
+var stepTypeSupported func(StepType) bool
+
+// RegisterStepTypes makes Validate accept the step types for which supported returns true,
+// in addition to the built-in ones
+func RegisterStepTypes(supported func(StepType) bool) {
+	stepTypeSupported = supported
+}
+
+// IsBuiltinStepType reports whether t is one of the step types forge executes itself
+func IsBuiltinStepType(t StepType) bool {
+	switch t {
+	case StepTypeExec, StepTypeSleep, StepTypeGoTest, StepTypeSnapshot, StepTypeRestore, StepTypeSlack, StepTypeApproval, StepTypeAssert, StepTypeGroup:
+		return true
+	}
+	return false
+}
+
 // Validate validates a step
 func (s *Step) Validate() error {
 	if s.Name == "" {
 		return errors.New("step name is required")
 	}
 
+	if err := validateDescription(s.Description); err != nil {
+		return err
+	}
+
 	switch s.Type {
 	case StepTypeExec:
 		if len(s.Run) == 0 {
@@ -57,9 +349,323 @@ func (s *Step) Validate() error {
 		if s.Seconds <= 0 {
 			return errors.New("sleep step requires positive 'seconds' value")
 		}
+	case StepTypeGoTest:
+		if len(s.Run) > 0 {
+			return errors.New("go-test step does not accept 'run', use 'args' for extra flags")
+		}
+	case StepTypeSnapshot:
+		if s.Path == "" {
+			return errors.New("snapshot step requires 'path'")
+		}
+	case StepTypeRestore:
+		if s.Snapshot == "" {
+			return errors.New("restore step requires 'snapshot' naming an earlier snapshot step")
+		}
+	case StepTypeSlack:
+		if s.Slack == nil {
+			return errors.New("slack step requires 'slack'")
+		}
+		if len(s.Slack.On) > 0 {
+			return errors.New("slack step does not accept 'on', it posts whenever it runs")
+		}
+		if err := s.Slack.Validate(); err != nil {
+			return err
+		}
+	case StepTypeApproval:
+		if s.Approval != nil && s.Approval.Timeout != "" {
+			if d, err := time.ParseDuration(s.Approval.Timeout); err != nil || d <= 0 {
+				return fmt.Errorf("approval: invalid timeout %q, use a positive duration such as 30m", s.Approval.Timeout)
+			}
+		}
+	case StepTypeAssert:
+		if s.Assert == nil {
+			return errors.New("assert step requires 'assert'")
+		}
+		if err := s.Assert.validate(); err != nil {
+			return fmt.Errorf("assert: %w", err)
+		}
+	case StepTypeGroup:
+		if len(s.Steps) == 0 {
+			return errors.New("group step requires 'steps'")
+		}
+		if len(s.Matchers) > 0 || len(s.Ports) > 0 {
+			return errors.New("group step does not accept 'matchers' or 'ports', set them on its steps")
+		}
+		if s.Cache != nil || s.IdempotencyKey != "" {
+			return errors.New("group step does not accept 'cache' or 'idempotency_key'")
+		}
+		if err := s.validateGroup(); err != nil {
+			return err
+		}
+	default:
+		if stepTypeSupported == nil || !stepTypeSupported(s.Type) {
+			return fmt.Errorf("unknown step type: %s", s.Type)
+		}
+	}
+
+	if s.ID != "" && !varNamePattern.MatchString(s.ID) {
+		return fmt.Errorf("invalid step id %q: use letters, digits and underscores", s.ID)
+	}
+	if s.When != "" {
+		if err := expr.Check(s.When, true); err != nil {
+			return fmt.Errorf("when: %w", err)
+		}
+	}
+
+	if s.TTY && s.Type != StepTypeExec {
+		return fmt.Errorf("%s step does not accept 'tty'", s.Type)
+	}
+	if (s.User != "" || s.Group != "") && s.Type != StepTypeExec {
+		return fmt.Errorf("%s step does not accept 'user' or 'group'", s.Type)
+	}
+	if len(s.Steps) > 0 && s.Type != StepTypeGroup {
+		return fmt.Errorf("%s step does not accept 'steps'", s.Type)
+	}
+	if s.Approval != nil && s.Type != StepTypeApproval {
+		return fmt.Errorf("%s step does not accept 'approval'", s.Type)
+	}
+	if s.Shell != "" && s.Type != StepTypeExec {
+		return fmt.Errorf("%s step does not accept 'shell'", s.Type)
+	}
+	if s.ExpandGlobs && s.Type != StepTypeExec {
+		return fmt.Errorf("%s step does not accept 'expand_globs'", s.Type)
+	}
+	if s.Redirected() {
+		switch {
+		case s.Type != StepTypeExec:
+			return fmt.Errorf("%s step does not accept 'stdin', 'stdin_file', 'stdout_file' or 'stderr_file'", s.Type)
+		case s.Stdin != "" && s.StdinFile != "":
+			return errors.New("stdin and stdin_file cannot both be set")
+		case s.TTY:
+			return errors.New("tty steps cannot redirect stdin, stdout or stderr")
+		}
+	}
+	if err := validateTimeout(s.Timeout); err != nil {
+		return err
+	}
+	if s.Retries != nil {
+		if *s.Retries < 0 {
+			return errors.New("retries must not be negative")
+		}
+		if *s.Retries > 0 && s.Type == StepTypeApproval {
+			return errors.New("approval step does not accept 'retries', a denial is final")
+		}
+	}
+	if s.Assert != nil && s.Type != StepTypeAssert {
+		return fmt.Errorf("%s step does not accept 'assert'", s.Type)
+	}
+	switch s.DryRun {
+	case "", DryRunSimulate, DryRunSkip:
+	case DryRunExecute:
+		if s.Type != StepTypeExec {
+			return fmt.Errorf("%s step does not accept 'dry_run: %s'", s.Type, DryRunExecute)
+		}
 	default:
-		return fmt.Errorf("unknown step type: %s", s.Type)
+		return fmt.Errorf("dry_run must be %s, %s or %s, got %q", DryRunExecute, DryRunSkip, DryRunSimulate, s.DryRun)
+	}
+	if (len(s.Before) > 0 || len(s.After) > 0) && s.Type != StepTypeExec {
+		return fmt.Errorf("%s step does not accept 'before' or 'after'", s.Type)
+	}
+	if err := validateCommands("before", s.Before); err != nil {
+		return err
+	}
+	if err := validateCommands("after", s.After); err != nil {
+		return err
+	}
+
+	if s.With != nil && IsBuiltinStepType(s.Type) {
+		return fmt.Errorf("%s step does not accept 'with'", s.Type)
+	}
+
+	for name, value := range s.Ports {
+		if err := validatePort(name, value); err != nil {
+			return err
+		}
+	}
+
+	if err := validatePlatforms(s.Platforms); err != nil {
+		return err
+	}
+
+	if err := validateEnv(s.Env); err != nil {
+		return err
+	}
+
+	if s.Cache != nil {
+		if len(s.Cache.KeyFiles) == 0 {
+			return errors.New("cache requires 'key_files'")
+		}
+		for _, pattern := range s.Cache.KeyFiles {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("cache key file %q: %w", pattern, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateGroup checks the steps of a group step, which cannot be groups themselves
+func (s *Step) validateGroup() error {
+	names := make(map[string]bool)
+	for i, step := range s.Steps {
+		switch {
+		case step.Type == StepTypeGroup:
+			return fmt.Errorf("step %d (%s): groups cannot be nested", i, step.Name)
+		case step.Cache != nil || step.IdempotencyKey != "":
+			return fmt.Errorf("step %d (%s): steps of a group do not accept 'cache' or 'idempotency_key'", i, step.Name)
+		}
+		if err := step.Validate(); err != nil {
+			return fmt.Errorf("step %d (%s): %w", i, step.Name, err)
+		}
+		if names[step.Name] {
+			return fmt.Errorf("step %d (%s): name is already used by another step of the group", i, step.Name)
+		}
+		names[step.Name] = true
+	}
+	return nil
+}
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// validateProfile checks that the named profile overrides declared variables only and sets
+// valid environment variables, each either as env or as secret
+func (w *Workflow) validateProfile(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return errors.New("invalid name: use letters, digits, '.', '_' and '-'")
 	}
+	p := w.Profiles[name]
+	for _, v := range slices.Sorted(maps.Keys(p.Vars)) {
+		if _, ok := w.Vars[v]; !ok {
+			return fmt.Errorf("variable %q is not declared in vars", v)
+		}
+	}
+	for _, env := range []map[string]string{p.Env, p.Secrets} {
+		if err := validateEnv(env); err != nil {
+			return err
+		}
+		for _, k := range slices.Sorted(maps.Keys(env)) {
+			if err := expr.Check(env[k], false); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(p.Secrets)) {
+		if _, ok := p.Env[k]; ok {
+			return fmt.Errorf("%s is set both in env and in secrets", k)
+		}
+	}
+	return nil
+}
+
+var varNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateVarName reports names that cannot be referenced as vars.NAME
+func ValidateVarName(name string) error {
+	if !varNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid variable name %q: use letters, digits and underscores", name)
+	}
+	return nil
+}
+
+var envNamePattern = regexp.MustCompile(`^[^=\x00]+$`)
+
+// validateTimeout reports timeouts that are not positive durations
+func validateTimeout(timeout string) error {
+	if timeout == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+		return fmt.Errorf("invalid timeout %q, use a positive duration such as 15m", timeout)
+	}
+	return nil
+}
+
+// validateDefaults checks the settings of the defaults block
+func (w *Workflow) validateDefaults() error {
+	d := w.Defaults
+	if d.Workdir != "" && w.Workdir != "" {
+		return errors.New("workdir and defaults.workdir cannot both be set")
+	}
+	if d.Shell == ShellNone {
+		return fmt.Errorf("shell %q only turns off the default shell of a step", ShellNone)
+	}
+	if err := validateTimeout(d.Timeout); err != nil {
+		return err
+	}
+	if d.Retries < 0 {
+		return errors.New("retries must not be negative")
+	}
+	return validateEnv(d.Env)
+}
+
+// validateEnv reports names that cannot be set as environment variables
+func validateEnv(env map[string]string) error {
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid env name %q", name)
+		}
+	}
+	return nil
+}
+
+var portNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+func validatePort(name, value string) error {
+	if !portNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid port name %q: use letters, digits and underscores", name)
+	}
+	if value == PortAuto {
+		return nil
+	}
+	if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("port %q must be %q or a number between 1 and 65535, got %q", name, PortAuto, value)
+	}
+	return nil
+}
+
+func (t *Throttle) validate() error {
+	if t.Per == "" && t.Jitter == "" {
+		return errors.New("requires 'per' or 'jitter'")
+	}
+	if t.Steps < 0 {
+		return errors.New("steps must not be negative")
+	}
+	if t.Steps > 0 && t.Per == "" {
+		return errors.New("steps requires 'per'")
+	}
+	for _, f := range []struct{ key, value string }{{"per", t.Per}, {"jitter", t.Jitter}} {
+		if f.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(f.value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid %s %q, use a positive duration such as 10s", f.key, f.value)
+		}
+	}
+	return nil
+}
+
+func (a *Assert) validate() error {
+	if len(a.Command) == 0 || a.Command[0] == "" {
+		return errors.New("requires 'command'")
+	}
+	if a.ExpectExitCode < 0 || a.ExpectExitCode > 255 {
+		return fmt.Errorf("expect_exit_code must be between 0 and 255, got %d", a.ExpectExitCode)
+	}
+	for _, pattern := range a.ExpectStdoutMatches {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("expect_stdout_matches: %w", err)
+		}
+	}
+	return nil
+}
 
+// validateCommands checks that each command of a before or after list has a program to run
+func validateCommands(key string, cmds [][]string) error {
+	for i, argv := range cmds {
+		if len(argv) == 0 || argv[0] == "" {
+			return fmt.Errorf("%s: command %d is empty", key, i+1)
+		}
+	}
 	return nil
 }
@@ -1,6 +1,12 @@
 package dsl
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/matcher"
+	"github.com/andre-koe/forge/internal/notify"
+)
 
 func TestValidateSteps(t *testing.T) {
 	tests := []struct {
@@ -17,6 +23,26 @@ func TestValidateSteps(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:    "exec step with redirects",
+			step:    Step{Name: "load", Type: StepTypeExec, Run: Command{"psql"}, StdinFile: "dump.sql", StdoutFile: "psql.log", StderrFile: "psql.log"},
+			wantErr: false,
+		},
+		{
+			name:    "stdin and stdin_file",
+			step:    Step{Name: "load", Type: StepTypeExec, Run: Command{"psql"}, Stdin: "select 1;", StdinFile: "dump.sql"},
+			wantErr: true,
+		},
+		{
+			name:    "redirected tty step",
+			step:    Step{Name: "load", Type: StepTypeExec, Run: Command{"psql"}, TTY: true, StdoutFile: "psql.log"},
+			wantErr: true,
+		},
+		{
+			name:    "redirected sleep step",
+			step:    Step{Name: "wait", Type: StepTypeSleep, Seconds: 1, StdoutFile: "wait.log"},
+			wantErr: true,
+		},
 		{
 			name: "valid sleep step",
 			step: Step{
@@ -50,6 +76,140 @@ func TestValidateSteps(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid go-test step",
+			step: Step{
+				Name: "affected",
+				Type: StepTypeGoTest,
+				Base: "origin/main",
+			},
+			wantErr: false,
+		},
+		{
+			name: "go-test step with run",
+			step: Step{
+				Name: "affected",
+				Type: StepTypeGoTest,
+				Run:  []string{"go", "test"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "exec step with ports",
+			step: Step{
+				Name:  "serve",
+				Type:  StepTypeExec,
+				Run:   []string{"./server"},
+				Ports: map[string]string{"api": PortAuto, "db_main": "5432"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid port name",
+			step: Step{
+				Name:  "serve",
+				Type:  StepTypeExec,
+				Run:   []string{"./server"},
+				Ports: map[string]string{"api-v2": PortAuto},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid port value",
+			step: Step{
+				Name:  "serve",
+				Type:  StepTypeExec,
+				Run:   []string{"./server"},
+				Ports: map[string]string{"api": "70000"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "snapshot step without path",
+			step:    Step{Name: "save", Type: StepTypeSnapshot},
+			wantErr: true,
+		},
+		{
+			name:    "restore step without snapshot",
+			step:    Step{Name: "undo", Type: StepTypeRestore},
+			wantErr: true,
+		},
+		{
+			name:    "slack step",
+			step:    Step{Name: "announce", Type: StepTypeSlack, Slack: &notify.Slack{WebhookURL: "${{ env.SLACK_WEBHOOK }}"}},
+			wantErr: false,
+		},
+		{
+			name:    "slack step without slack",
+			step:    Step{Name: "announce", Type: StepTypeSlack},
+			wantErr: true,
+		},
+		{
+			name:    "slack step with on",
+			step:    Step{Name: "announce", Type: StepTypeSlack, Slack: &notify.Slack{WebhookURL: "https://x", On: []string{notify.OnFailure}}},
+			wantErr: true,
+		},
+		{
+			name:    "exec step with with",
+			step:    Step{Name: "build", Type: StepTypeExec, Run: []string{"make"}, With: map[string]any{"target": "all"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown step type",
+			step:    Step{Name: "deploy", Type: "helm"},
+			wantErr: true,
+		},
+		{
+			name:    "step with id and when",
+			step:    Step{Name: "rollback", ID: "rollback_1", Type: StepTypeExec, Run: []string{"make", "rollback"}, When: "steps.deploy.status == 'failed'"},
+			wantErr: false,
+		},
+		{
+			name:    "description too long",
+			step:    Step{Name: "deploy", Description: strings.Repeat("x", MaxDescriptionLength+1), Type: StepTypeExec, Run: []string{"make"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid step id",
+			step:    Step{Name: "deploy", ID: "deploy-prod", Type: StepTypeExec, Run: []string{"make"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid when",
+			step:    Step{Name: "deploy", Type: StepTypeExec, Run: []string{"make"}, When: "steps.deploy.result"},
+			wantErr: true,
+		},
+		{
+			name: "exec step with before and after",
+			step: Step{
+				Name:   "step6",
+				Type:   StepTypeExec,
+				Run:    []string{"make"},
+				Before: [][]string{{"mkdir", "tmp"}},
+				After:  [][]string{{"rm", "-rf", "tmp"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "exec step with empty before command",
+			step: Step{
+				Name:   "step6",
+				Type:   StepTypeExec,
+				Run:    []string{"make"},
+				Before: [][]string{{}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "sleep step with after",
+			step: Step{
+				Name:    "step6",
+				Type:    StepTypeSleep,
+				Seconds: 1,
+				After:   [][]string{{"true"}},
+			},
+			wantErr: true,
+		},
 		{
 			name: "sleep step with non-positive seconds",
 			step: Step{
@@ -59,6 +219,53 @@ func TestValidateSteps(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name:    "exec step executed in dry runs",
+			step:    Step{Name: "plan", Type: StepTypeExec, Run: []string{"terraform", "plan"}, DryRun: DryRunExecute},
+			wantErr: false,
+		},
+		{
+			name:    "sleep step executed in dry runs",
+			step:    Step{Name: "wait", Type: StepTypeSleep, Seconds: 5, DryRun: DryRunExecute},
+			wantErr: true,
+		},
+		{
+			name:    "unknown dry_run",
+			step:    Step{Name: "plan", Type: StepTypeExec, Run: []string{"terraform", "plan"}, DryRun: "always"},
+			wantErr: true,
+		},
+		{
+			name: "assert step",
+			step: Step{Name: "healthy", Type: StepTypeAssert, Assert: &Assert{
+				Command: []string{"curl", "-s", "localhost/healthz"}, ExpectStdoutContains: []string{"ok"}, ExpectStdoutMatches: []string{`"version":\s*"v\d+`},
+			}},
+			wantErr: false,
+		},
+		{
+			name:    "assert step without assert",
+			step:    Step{Name: "healthy", Type: StepTypeAssert},
+			wantErr: true,
+		},
+		{
+			name:    "assert step without command",
+			step:    Step{Name: "healthy", Type: StepTypeAssert, Assert: &Assert{ExpectStdoutContains: []string{"ok"}}},
+			wantErr: true,
+		},
+		{
+			name:    "assert step with invalid regex",
+			step:    Step{Name: "healthy", Type: StepTypeAssert, Assert: &Assert{Command: []string{"status"}, ExpectStdoutMatches: []string{"v(\\d+"}}},
+			wantErr: true,
+		},
+		{
+			name:    "assert step with exit code out of range",
+			step:    Step{Name: "healthy", Type: StepTypeAssert, Assert: &Assert{Command: []string{"status"}, ExpectExitCode: 256}},
+			wantErr: true,
+		},
+		{
+			name:    "assert on exec step",
+			step:    Step{Name: "build", Type: StepTypeExec, Run: []string{"make"}, Assert: &Assert{Command: []string{"status"}}},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -71,6 +278,18 @@ func TestValidateSteps(t *testing.T) {
 	}
 }
 
+func TestRegisterStepTypes(t *testing.T) {
+	t.Cleanup(func() { RegisterStepTypes(nil) })
+	RegisterStepTypes(func(t StepType) bool { return t == "helm" })
+
+	if err := (&Step{Name: "deploy", Type: "helm", With: map[string]any{"chart": "api"}}).Validate(); err != nil {
+		t.Errorf("Validate() error for a registered type: %v", err)
+	}
+	if err := (&Step{Name: "deploy", Type: "kustomize"}).Validate(); err == nil {
+		t.Error("Validate() accepted an unregistered type")
+	}
+}
+
 func TestValidateStages(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -104,6 +323,61 @@ func TestValidateStages(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name:    "stage with schedule window",
+			stage:   Stage{Name: "deploy", ScheduleWindow: "Mon-Fri 09:00-17:00 UTC", OutsideWindow: WindowWait, Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+			wantErr: false,
+		},
+		{
+			name:    "stage with invalid schedule window",
+			stage:   Stage{Name: "deploy", ScheduleWindow: "weekdays 9-5", Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "stage with unknown outside_window",
+			stage:   Stage{Name: "deploy", ScheduleWindow: "09:00-17:00", OutsideWindow: "queue", Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "stage with outside_window but no schedule window",
+			stage:   Stage{Name: "deploy", OutsideWindow: WindowSkip, Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "throttled stage",
+			stage:   Stage{Name: "deploy", Throttle: &Throttle{Steps: 1, Per: "10s", Jitter: "2s"}, Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+			wantErr: false,
+		},
+		{
+			name:    "throttle without per or jitter",
+			stage:   Stage{Name: "deploy", Throttle: &Throttle{Steps: 1}, Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "throttle with invalid per",
+			stage:   Stage{Name: "deploy", Throttle: &Throttle{Per: "-5s"}, Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "retried stage",
+			stage:   Stage{Name: "deploy", OnError: OnErrorRetry, MaxStageRetries: 3, Steps: []Step{{Name: "push", Type: StepTypeExec, Run: []string{"push"}}}},
+			wantErr: false,
+		},
+		{
+			name:    "unknown on_error",
+			stage:   Stage{Name: "deploy", OnError: "ignore", Steps: []Step{{Name: "push", Type: StepTypeExec, Run: []string{"push"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "max_stage_retries without retry",
+			stage:   Stage{Name: "deploy", OnError: OnErrorContinue, MaxStageRetries: 2, Steps: []Step{{Name: "push", Type: StepTypeExec, Run: []string{"push"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "negative max_stage_retries",
+			stage:   Stage{Name: "deploy", OnError: OnErrorRetry, MaxStageRetries: -1, Steps: []Step{{Name: "push", Type: StepTypeExec, Run: []string{"push"}}}},
+			wantErr: true,
+		},
 		{
 			name: "stage with no steps",
 			stage: Stage{
@@ -125,6 +399,119 @@ func TestValidateStages(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "stage with hooks",
+			stage: Stage{
+				Name:      "stage4",
+				Steps:     []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"make"}}},
+				OnFailure: []Step{{Name: "logs", Type: StepTypeExec, Run: []string{"cat", "build.log"}}},
+				Always:    []Step{{Name: "cleanup", Type: StepTypeExec, Run: []string{"make", "clean"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "stage with invalid hook",
+			stage: Stage{
+				Name:      "stage5",
+				Steps:     []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"make"}}},
+				OnSuccess: []Step{{Name: "announce", Type: StepTypeExec}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "stage with idempotency key",
+			stage: Stage{
+				Name:  "provision",
+				Steps: []Step{{Name: "bucket", Type: StepTypeExec, Run: []string{"mkbucket"}, IdempotencyKey: "bucket-${{ vars.name }}"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "hook with idempotency key",
+			stage: Stage{
+				Name:   "provision",
+				Steps:  []Step{{Name: "bucket", Type: StepTypeExec, Run: []string{"mkbucket"}}},
+				Always: []Step{{Name: "cleanup", Type: StepTypeExec, Run: []string{"rm"}, IdempotencyKey: "cleanup"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate step names",
+			stage: Stage{
+				Name:  "build",
+				Steps: []Step{{Name: "compile", Type: StepTypeExec, Run: []string{"make"}}, {Name: "compile", Type: StepTypeExec, Run: []string{"make", "all"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate hook names",
+			stage: Stage{
+				Name:   "build",
+				Steps:  []Step{{Name: "compile", Type: StepTypeExec, Run: []string{"make"}}},
+				Always: []Step{{Name: "clean", Type: StepTypeExec, Run: []string{"rm"}}, {Name: "clean", Type: StepTypeExec, Run: []string{"rm", "-r"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "same name in steps and hooks",
+			stage: Stage{
+				Name:      "build",
+				Steps:     []Step{{Name: "notify", Type: StepTypeExec, Run: []string{"make"}}},
+				OnSuccess: []Step{{Name: "notify", Type: StepTypeExec, Run: []string{"echo", "ok"}}},
+				OnFailure: []Step{{Name: "notify", Type: StepTypeExec, Run: []string{"echo", "failed"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "stage on an agent",
+			stage:   Stage{Name: "train", RunsOn: &RunsOn{Agent: []string{"linux", "gpu"}}, Steps: []Step{{Name: "fit", Type: StepTypeExec, Run: []string{"train"}}}},
+			wantErr: false,
+		},
+		{
+			name:    "runs_on without agent labels",
+			stage:   Stage{Name: "train", RunsOn: &RunsOn{}, Steps: []Step{{Name: "fit", Type: StepTypeExec, Run: []string{"train"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "runs_on with a blank agent label",
+			stage:   Stage{Name: "train", RunsOn: &RunsOn{Agent: []string{"linux", " "}}, Steps: []Step{{Name: "fit", Type: StepTypeExec, Run: []string{"train"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "stage on an ssh host",
+			stage:   Stage{Name: "deploy", RunsOn: &RunsOn{SSH: "ssh://deploy@web-1:2222/srv/app"}, Steps: []Step{{Name: "restart", Type: StepTypeExec, Run: []string{"systemctl", "restart", "app"}}}},
+			wantErr: false,
+		},
+		{
+			name:    "runs_on with an invalid ssh URL",
+			stage:   Stage{Name: "deploy", RunsOn: &RunsOn{SSH: "deploy@web-1"}, Steps: []Step{{Name: "restart", Type: StepTypeExec, Run: []string{"restart"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "runs_on with both an agent and an ssh host",
+			stage:   Stage{Name: "deploy", RunsOn: &RunsOn{Agent: []string{"linux"}, SSH: "ssh://web-1"}, Steps: []Step{{Name: "restart", Type: StepTypeExec, Run: []string{"restart"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "stage in a pod",
+			stage:   Stage{Name: "test", RunsOn: &RunsOn{Kubernetes: &Kubernetes{Image: "golang:1.24", Namespace: "ci", Resources: &PodResources{CPU: "500m", Memory: "4Gi"}}}, Steps: []Step{{Name: "unit", Type: StepTypeExec, Run: []string{"go", "test", "./..."}}}},
+			wantErr: false,
+		},
+		{
+			name:    "runs_on kubernetes with an invalid namespace",
+			stage:   Stage{Name: "test", RunsOn: &RunsOn{Kubernetes: &Kubernetes{Namespace: "CI_Jobs"}}, Steps: []Step{{Name: "unit", Type: StepTypeExec, Run: []string{"go", "test"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "runs_on kubernetes with an invalid memory",
+			stage:   Stage{Name: "test", RunsOn: &RunsOn{Kubernetes: &Kubernetes{Resources: &PodResources{Memory: "4 GB"}}}, Steps: []Step{{Name: "unit", Type: StepTypeExec, Run: []string{"go", "test"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "runs_on with both an ssh host and kubernetes",
+			stage:   Stage{Name: "test", RunsOn: &RunsOn{SSH: "ssh://web-1", Kubernetes: &Kubernetes{}}, Steps: []Step{{Name: "unit", Type: StepTypeExec, Run: []string{"go", "test"}}}},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -180,6 +567,301 @@ func TestValidateWorkflows(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "workflow with valid timezone",
+			workflow: Workflow{
+				Name:     "workflow-tz",
+				Timezone: "UTC",
+				Stages: []Stage{
+					{
+						Name:  "stage1",
+						Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "workflow with unknown timezone",
+			workflow: Workflow{
+				Name:     "workflow-tz",
+				Timezone: "Mars/Olympus_Mons",
+				Stages: []Stage{
+					{
+						Name:  "stage1",
+						Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with schedule",
+			workflow: Workflow{
+				Name:     "workflow-nightly",
+				Schedule: "0 2 * * mon-fri",
+				Stages: []Stage{
+					{Name: "stage1", Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "workflow with invalid schedule",
+			workflow: Workflow{
+				Name:     "workflow-nightly",
+				Schedule: "every night",
+				Stages: []Stage{
+					{Name: "stage1", Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with invalid requires.forge",
+			workflow: Workflow{
+				Name:     "workflow-pinned",
+				Requires: &Requires{Forge: ">=latest"},
+				Stages: []Stage{
+					{Name: "stage1", Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with required tools",
+			workflow: Workflow{
+				Name:     "workflow-requires",
+				Requires: &Requires{Tools: []string{"docker", "kubectl>=1.28"}, Forge: ">=0.4"},
+				Stages: []Stage{
+					{Name: "stage1", Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "workflow with invalid tool constraint",
+			workflow: Workflow{
+				Name:     "workflow-requires",
+				Requires: &Requires{Tools: []string{"kubectl>=new"}},
+				Stages: []Stage{
+					{Name: "stage1", Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with tty on a sleep step",
+			workflow: Workflow{
+				Name: "workflow-tty",
+				Stages: []Stage{
+					{Name: "stage1", Steps: []Step{{Name: "step1", Type: StepTypeSleep, Seconds: 1, TTY: true}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with invalid env name",
+			workflow: Workflow{
+				Name: "workflow-env",
+				Stages: []Stage{
+					{Name: "stage1", Env: map[string]string{"A=B": "x"}, Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with an empty step_hooks command",
+			workflow: Workflow{
+				Name:      "workflow-step-hooks",
+				StepHooks: &StepHooks{After: [][]string{{""}}},
+				Stages: []Stage{
+					{Name: "stage1", Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with user on a sleep step",
+			workflow: Workflow{
+				Name: "workflow-user",
+				Stages: []Stage{
+					{Name: "stage1", Steps: []Step{{Name: "step1", Type: StepTypeSleep, Seconds: 1, User: "nobody"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with depends_on on earlier stage",
+			workflow: Workflow{
+				Name: "workflow-deps",
+				Stages: []Stage{
+					{Name: "build", Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+					{Name: "test", DependsOn: []string{"build"}, Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "workflow with depends_on on later stage",
+			workflow: Workflow{
+				Name: "workflow-deps",
+				Stages: []Stage{
+					{Name: "test", DependsOn: []string{"build"}, Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+					{Name: "build", Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with depends_on on a finally stage",
+			workflow: Workflow{
+				Name: "workflow-deps",
+				Stages: []Stage{
+					{Name: "cleanup", Finally: true, Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+					{Name: "test", DependsOn: []string{"cleanup"}, Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with restore of a stage's artifacts",
+			workflow: Workflow{
+				Name: "workflow-artifacts",
+				Stages: []Stage{
+					{Name: "build", Artifacts: []string{"dist/*"}, Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"make"}}}},
+					{Name: "package", Restore: []string{"build"}, Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"tar"}}}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "workflow with restore of a stage without artifacts",
+			workflow: Workflow{
+				Name: "workflow-artifacts",
+				Stages: []Stage{
+					{Name: "build", Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"make"}}}},
+					{Name: "package", Restore: []string{"build"}, Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"tar"}}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with artifacts outside the working directory",
+			workflow: Workflow{
+				Name: "workflow-artifacts",
+				Stages: []Stage{
+					{Name: "build", Artifacts: []string{"../out"}, Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"make"}}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with changes filter",
+			workflow: Workflow{
+				Name: "workflow-changes",
+				Stages: []Stage{
+					{Name: "api", Changes: []string{"services/api/**", "go.mod"}, Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"make"}}}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "workflow with malformed changes pattern",
+			workflow: Workflow{
+				Name: "workflow-changes",
+				Stages: []Stage{
+					{Name: "api", Changes: []string{"services/[a-"}, Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"make"}}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with unknown problem matcher",
+			workflow: Workflow{
+				Name: "workflow-matchers",
+				Stages: []Stage{
+					{Name: "lint", Steps: []Step{{Name: "vet", Type: StepTypeExec, Run: []string{"go", "vet"}, Matchers: []string{"nope"}}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with invalid custom problem matcher",
+			workflow: Workflow{
+				Name:            "workflow-matchers",
+				ProblemMatchers: []matcher.Definition{{Name: "broken", Pattern: "("}},
+				Stages: []Stage{
+					{Name: "lint", Steps: []Step{{Name: "vet", Type: StepTypeExec, Run: []string{"go", "vet"}}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with restore of earlier snapshot",
+			workflow: Workflow{
+				Name: "workflow-snapshot",
+				Stages: []Stage{
+					{Name: "prepare", Steps: []Step{{Name: "save", Type: StepTypeSnapshot, Path: "testdata"}}},
+					{Name: "cleanup", Steps: []Step{{Name: "undo", Type: StepTypeRestore, Snapshot: "save"}}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "workflow with snapshots of the same name in different stages",
+			workflow: Workflow{
+				Name: "workflow-snapshot",
+				Stages: []Stage{
+					{Name: "prepare", Steps: []Step{{Name: "save", Type: StepTypeSnapshot, Path: "testdata"}}},
+					{Name: "migrate", Steps: []Step{{Name: "save", Type: StepTypeSnapshot, Path: "db"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with restore of unknown snapshot",
+			workflow: Workflow{
+				Name: "workflow-snapshot",
+				Stages: []Stage{
+					{Name: "cleanup", Steps: []Step{{Name: "undo", Type: StepTypeRestore, Snapshot: "save"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with restore in an on_failure hook",
+			workflow: Workflow{
+				Name: "workflow-hooks",
+				Stages: []Stage{
+					{Name: "prepare", Steps: []Step{{Name: "save", Type: StepTypeSnapshot, Path: "testdata"}}},
+				},
+				OnFailure: []Step{{Name: "undo", Type: StepTypeRestore, Snapshot: "save"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "workflow with invalid always hook",
+			workflow: Workflow{
+				Name: "workflow-hooks",
+				Stages: []Stage{
+					{Name: "stage1", Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+				},
+				Always: []Step{{Name: "cleanup", Type: StepTypeSleep}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow with invalid variable name",
+			workflow: Workflow{
+				Name: "workflow-vars",
+				Vars: map[string]string{"image-tag": "latest"},
+				Stages: []Stage{
+					{Name: "stage1", Steps: []Step{{Name: "step1", Type: StepTypeExec, Run: []string{"true"}}}},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "workflow with no stages",
 			workflow: Workflow{
@@ -188,6 +870,65 @@ func TestValidateWorkflows(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "duplicate step id",
+			workflow: Workflow{
+				Name: "workflow-ids",
+				Stages: []Stage{
+					{
+						Name:      "deploy",
+						Steps:     []Step{{Name: "push", ID: "deploy", Type: StepTypeExec, Run: []string{"true"}}},
+						OnFailure: []Step{{Name: "rollback", ID: "deploy", Type: StepTypeExec, Run: []string{"true"}}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "command line",
+			workflow: Workflow{
+				Name:   "workflow-line",
+				Stages: []Stage{{Name: "test", Steps: []Step{{Name: "unit", Type: StepTypeExec, Run: Command{"go test -run 'Test.*' ./..."}}}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "command line needing a shell",
+			workflow: Workflow{
+				Name:   "workflow-line",
+				Stages: []Stage{{Name: "test", Steps: []Step{{Name: "unit", Type: StepTypeExec, Run: Command{"go test ./... | tee test.log"}}}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "command line run by a shell",
+			workflow: Workflow{
+				Name:     "workflow-line",
+				Defaults: &Defaults{Shell: "bash"},
+				Stages:   []Stage{{Name: "test", Steps: []Step{{Name: "unit", Type: StepTypeExec, Run: Command{"go test ./... | tee test.log"}}}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "duplicate stage names",
+			workflow: Workflow{
+				Name: "workflow-stages",
+				Stages: []Stage{
+					{Name: "test", Steps: []Step{{Name: "unit", Type: StepTypeExec, Run: []string{"go", "test"}}}},
+					{Name: "test", Steps: []Step{{Name: "race", Type: StepTypeExec, Run: []string{"go", "test", "-race"}}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate workflow hook names",
+			workflow: Workflow{
+				Name:   "workflow-hooks",
+				Stages: []Stage{{Name: "test", Steps: []Step{{Name: "unit", Type: StepTypeExec, Run: []string{"go", "test"}}}}},
+				Always: []Step{{Name: "report", Type: StepTypeExec, Run: []string{"true"}}, {Name: "report", Type: StepTypeExec, Run: []string{"false"}}},
+			},
+			wantErr: true,
+		},
 		{
 			name: "workflow with invalid stage",
 			workflow: Workflow{
@@ -212,3 +953,161 @@ func TestValidateWorkflows(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		p       Profile
+		wantErr string
+	}{
+		{name: "valid", profile: "prod", p: Profile{Vars: map[string]string{"replicas": "3"}, Env: map[string]string{"STAGE": "prod"}, Secrets: map[string]string{"TOKEN": "${{ env.PROD_TOKEN }}"}}},
+		{name: "invalid name", profile: "-prod", wantErr: "profile -prod: invalid name"},
+		{name: "undeclared variable", profile: "prod", p: Profile{Vars: map[string]string{"region": "eu"}}, wantErr: `profile prod: variable "region" is not declared in vars`},
+		{name: "invalid env name", profile: "prod", p: Profile{Env: map[string]string{"A=B": "x"}}, wantErr: `invalid env name "A=B"`},
+		{name: "invalid expression", profile: "prod", p: Profile{Secrets: map[string]string{"TOKEN": "${{ env.TOKEN"}}, wantErr: "profile prod: TOKEN:"},
+		{name: "env and secret", profile: "prod", p: Profile{Env: map[string]string{"TOKEN": "a"}, Secrets: map[string]string{"TOKEN": "b"}}, wantErr: "TOKEN is set both in env and in secrets"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := Workflow{
+				Name:     "deploy",
+				Vars:     map[string]string{"replicas": "1"},
+				Profiles: map[string]Profile{tt.profile: tt.p},
+				Stages:   []Stage{{Name: "deploy", Steps: []Step{{Name: "apply", Type: StepTypeExec, Run: []string{"kubectl", "apply"}}}}},
+			}
+			err := wf.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	one, negative := 1, -1
+	tests := []struct {
+		name     string
+		workdir  string
+		defaults Defaults
+		step     Step
+		wantErr  string
+	}{
+		{name: "valid", defaults: Defaults{Shell: "bash -eo pipefail", Workdir: "src", Timeout: "15m", Retries: 2, Env: map[string]string{"CI": "true"}}},
+		{name: "step overrides", defaults: Defaults{Shell: "bash", Retries: 2}, step: Step{Shell: ShellNone, Timeout: "1h", Retries: &one}},
+		{name: "two workdirs", workdir: "build", defaults: Defaults{Workdir: "src"}, wantErr: "defaults: workdir and defaults.workdir cannot both be set"},
+		{name: "shell none", defaults: Defaults{Shell: ShellNone}, wantErr: `defaults: shell "none"`},
+		{name: "timeout", defaults: Defaults{Timeout: "soon"}, wantErr: `defaults: invalid timeout "soon"`},
+		{name: "negative retries", defaults: Defaults{Retries: -1}, wantErr: "defaults: retries must not be negative"},
+		{name: "env name", defaults: Defaults{Env: map[string]string{"A=B": "x"}}, wantErr: `invalid env name "A=B"`},
+		{name: "step timeout", step: Step{Timeout: "0s"}, wantErr: `invalid timeout "0s"`},
+		{name: "step retries", step: Step{Retries: &negative}, wantErr: "retries must not be negative"},
+		{name: "shell on sleep", step: Step{Type: StepTypeSleep, Seconds: 1, Shell: "bash"}, wantErr: "sleep step does not accept 'shell'"},
+		{name: "retried approval", step: Step{Type: StepTypeApproval, Retries: &one}, wantErr: "approval step does not accept 'retries'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := tt.step
+			step.Name = "build"
+			if step.Type == "" {
+				step.Type, step.Run = StepTypeExec, []string{"make"}
+			}
+			wf := Workflow{Name: "ci", Workdir: tt.workdir, Defaults: &tt.defaults, Stages: []Stage{{Name: "build", Steps: []Step{step}}}}
+			err := wf.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateExpandGlobs(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults *Defaults
+		step     Step
+		wantErr  string
+	}{
+		{name: "list", step: Step{Type: StepTypeExec, Run: Command{"tar", "czf", "dist.tgz", "dist/*"}, ExpandGlobs: true}},
+		{name: "command line", step: Step{Type: StepTypeExec, Run: Command{"upload dist/*.tar.gz ~/.config/upload"}, ExpandGlobs: true}},
+		{name: "command line without expand_globs", step: Step{Type: StepTypeExec, Run: Command{"upload dist/*.tar.gz"}}, wantErr: `run: needs a shell for "*"`},
+		{name: "other shell syntax", step: Step{Type: StepTypeExec, Run: Command{"upload dist/* | tee log"}, ExpandGlobs: true}, wantErr: `needs a shell for "|"`},
+		{name: "with a shell", defaults: &Defaults{Shell: "bash"}, step: Step{Type: StepTypeExec, Run: Command{"ls", "*"}, ExpandGlobs: true}, wantErr: "expand_globs is for steps run without a shell"},
+		{name: "sleep step", step: Step{Type: StepTypeSleep, Seconds: 1, ExpandGlobs: true}, wantErr: "sleep step does not accept 'expand_globs'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := tt.step
+			step.Name = "publish"
+			wf := Workflow{Name: "release", Defaults: tt.defaults, Stages: []Stage{{Name: "publish", Steps: []Step{step}}}}
+			err := wf.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateGroups(t *testing.T) {
+	build := Step{Name: "build", Type: StepTypeExec, Run: Command{"make"}}
+	tests := []struct {
+		name    string
+		step    Step
+		wantErr string
+	}{
+		{name: "group", step: Step{Type: StepTypeGroup, Timeout: "10m", Env: map[string]string{"CI": "1"}, Steps: []Step{build, {Name: "test", Type: StepTypeExec, Run: Command{"make test"}}}}},
+		{name: "no steps", step: Step{Type: StepTypeGroup}, wantErr: "group step requires 'steps'"},
+		{name: "invalid step", step: Step{Type: StepTypeGroup, Steps: []Step{{Name: "build", Type: StepTypeExec}}}, wantErr: "step 0 (build): exec step requires 'run' command"},
+		{name: "duplicate names", step: Step{Type: StepTypeGroup, Steps: []Step{build, build}}, wantErr: "step 1 (build): name is already used by another step of the group"},
+		{name: "nested group", step: Step{Type: StepTypeGroup, Steps: []Step{{Name: "inner", Type: StepTypeGroup, Steps: []Step{build}}}}, wantErr: "groups cannot be nested"},
+		{name: "cached step", step: Step{Type: StepTypeGroup, Steps: []Step{{Name: "build", Type: StepTypeExec, Run: Command{"make"}, Cache: &Cache{KeyFiles: []string{"*.go"}}}}}, wantErr: "steps of a group do not accept 'cache' or 'idempotency_key'"},
+		{name: "matchers", step: Step{Type: StepTypeGroup, Matchers: []string{"go"}, Steps: []Step{build}}, wantErr: "group step does not accept 'matchers' or 'ports'"},
+		{name: "unsplittable command line", step: Step{Type: StepTypeGroup, Steps: []Step{{Name: "build", Type: StepTypeExec, Run: Command{"make | tee log"}}}}, wantErr: "step ci: step build: run: needs a shell"},
+		{name: "steps on an exec step", step: Step{Type: StepTypeExec, Run: Command{"make"}, Steps: []Step{build}}, wantErr: "exec step does not accept 'steps'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := tt.step
+			step.Name = "ci"
+			wf := Workflow{Name: "release", Stages: []Stage{{Name: "build", Steps: []Step{step}}}}
+			err := wf.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateGroups_DuplicateIDs(t *testing.T) {
+	wf := Workflow{Name: "release", Stages: []Stage{{Name: "build", Steps: []Step{
+		{Name: "compile", ID: "compile", Type: StepTypeExec, Run: Command{"make"}},
+		{Name: "ci", Type: StepTypeGroup, Steps: []Step{{Name: "again", ID: "compile", Type: StepTypeExec, Run: Command{"make"}}}},
+	}}}}
+	if err := wf.Validate(); err == nil || !strings.Contains(err.Error(), `id "compile" is already used`) {
+		t.Errorf("Validate() error = %v, want the id of the group's step to be checked", err)
+	}
+}
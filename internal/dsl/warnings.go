@@ -0,0 +1,75 @@
+package dsl
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LongSleep is the duration from which sleep steps are reported by Warnings
+const LongSleep = time.Hour
+
+// Warnings returns findings that do not make a valid workflow fail validation but are likely
+// mistakes: very long sleeps, exec steps without a timeout and commands run by a relative path
+// while no workdir pins the directory it is relative to
+func (w *Workflow) Warnings() []string {
+	var warnings []string
+	untimed := 0
+	w.eachStep(func(path string, stage *Stage, s *Step) {
+		if s.Type == StepTypeSleep && time.Duration(s.Seconds)*time.Second >= LongSleep {
+			warnings = append(warnings, fmt.Sprintf("step %s sleeps for %s", path, time.Duration(s.Seconds)*time.Second))
+		}
+		if s.Type == StepTypeExec && w.StepTimeout(s) == 0 {
+			untimed++
+		}
+		if w.hasWorkdir(stage, s) {
+			return
+		}
+		cmd := s.Argv()
+		if s.Type == StepTypeAssert && s.Assert != nil {
+			cmd = s.Assert.Command
+		}
+		if len(cmd) > 0 && strings.Contains(cmd[0], "/") && !strings.Contains(cmd[0], "${{") && !filepath.IsAbs(cmd[0]) {
+			warnings = append(warnings, fmt.Sprintf("step %s runs %s, a path relative to the directory forge is started in; set workdir to make it relative to the workflow file", path, cmd[0]))
+		}
+	})
+	if untimed > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d exec step(s) without a timeout are stopped only after the default of 10 minutes; set timeout or defaults.timeout", untimed))
+	}
+	return warnings
+}
+
+// eachStep calls fn with every step of the workflow and its path, stage.step for steps and
+// stage hooks and kind.step for workflow hooks; stage is nil for workflow hooks. The steps of a
+// group follow it, as they run, with the path stage.group.step.
+func (w *Workflow) eachStep(fn func(path string, stage *Stage, s *Step)) {
+	each := func(prefix string, stage *Stage, steps []Step) {
+		for j := range steps {
+			path := prefix + "." + steps[j].Name
+			fn(path, stage, &steps[j])
+			subs := steps[j].GroupSteps()
+			for k := range subs {
+				fn(path+"."+subs[k].Name, stage, &subs[k])
+			}
+		}
+	}
+	for i := range w.Stages {
+		stage := &w.Stages[i]
+		for _, steps := range [][]Step{stage.Steps, stage.OnSuccess, stage.OnFailure, stage.Always} {
+			each(stage.Name, stage, steps)
+		}
+	}
+	hooks := w.Hooks()
+	for _, kind := range HookKinds {
+		each(kind, nil, hooks[kind])
+	}
+}
+
+// hasWorkdir reports whether the step's commands run in a directory set by the workflow
+func (w *Workflow) hasWorkdir(stage *Stage, s *Step) bool {
+	if s.Workdir != "" || w.Workdir != "" || (stage != nil && stage.Workdir != "") {
+		return true
+	}
+	return w.Defaults != nil && w.Defaults.Workdir != ""
+}
@@ -0,0 +1,74 @@
+package dsl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWorkflow_Warnings(t *testing.T) {
+	tests := []struct {
+		name string
+		wf   Workflow
+		want []string
+	}{
+		{
+			name: "none",
+			wf: Workflow{Defaults: &Defaults{Timeout: "5m"}, Stages: []Stage{{Name: "build", Workdir: "src", Steps: []Step{
+				{Name: "compile", Type: StepTypeExec, Run: []string{"./build.sh"}},
+				{Name: "cool-down", Type: StepTypeSleep, Seconds: 60},
+			}}}},
+		},
+		{
+			name: "long sleep",
+			wf: Workflow{Stages: []Stage{{Name: "deploy", Steps: []Step{
+				{Name: "soak", Type: StepTypeSleep, Seconds: 2 * 3600},
+			}}}},
+			want: []string{"step deploy.soak sleeps for 2h0m0s"},
+		},
+		{
+			name: "no timeout",
+			wf: Workflow{Stages: []Stage{{Name: "test", Steps: []Step{
+				{Name: "unit", Type: StepTypeExec, Run: []string{"go", "test"}},
+				{Name: "race", Type: StepTypeExec, Run: []string{"go", "test", "-race"}, Timeout: "30m"},
+			}}}, Always: []Step{{Name: "report", Type: StepTypeExec, Run: []string{"true"}}}},
+			want: []string{"2 exec step(s) without a timeout are stopped only after the default of 10 minutes; set timeout or defaults.timeout"},
+		},
+		{
+			name: "relative paths",
+			wf: Workflow{Defaults: &Defaults{Timeout: "1m"}, Stages: []Stage{{Name: "verify", Steps: []Step{
+				{Name: "lint", Type: StepTypeExec, Run: []string{"scripts/lint.sh"}},
+				{Name: "abs", Type: StepTypeExec, Run: []string{"/usr/bin/env", "true"}},
+				{Name: "var", Type: StepTypeExec, Run: []string{"${{ vars.bin }}/tool"}},
+				{Name: "pinned", Type: StepTypeExec, Run: []string{"./check.sh"}, Workdir: "ci"},
+				{Name: "health", Type: StepTypeAssert, Assert: &Assert{Command: []string{"./health.sh"}}},
+			}}}},
+			want: []string{
+				"step verify.lint runs scripts/lint.sh, a path relative to the directory forge is started in; set workdir to make it relative to the workflow file",
+				"step verify.health runs ./health.sh, a path relative to the directory forge is started in; set workdir to make it relative to the workflow file",
+			},
+		},
+		{
+			name: "group",
+			wf: Workflow{Defaults: &Defaults{Timeout: "1m"}, Stages: []Stage{{Name: "verify", Steps: []Step{
+				{Name: "checks", Type: StepTypeGroup, Steps: []Step{
+					{Name: "lint", Type: StepTypeExec, Run: []string{"scripts/lint.sh"}},
+					{Name: "wait", Type: StepTypeSleep, Seconds: 3600},
+				}},
+				{Name: "pinned", Type: StepTypeGroup, Workdir: "ci", Steps: []Step{
+					{Name: "lint", Type: StepTypeExec, Run: []string{"scripts/lint.sh"}},
+				}},
+			}}}},
+			want: []string{
+				"step verify.checks.lint runs scripts/lint.sh, a path relative to the directory forge is started in; set workdir to make it relative to the workflow file",
+				"step verify.checks.wait sleeps for 1h0m0s",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.wf.Warnings(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Warnings() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package dsl
+
+import (
+	"os"
+	"strings"
+
+	yaml "github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/printer"
+	"github.com/goccy/go-yaml/token"
+)
+
+// MarshalYAML renders the workflow as YAML in the style of hand-written files: sequences are
+// indented under their keys, commands such as run are kept on one line and multi-line strings
+// use literal blocks. A workflow without a schema version is stamped with the current one.
+func (w *Workflow) MarshalYAML() ([]byte, error) {
+	// workflow has the fields of Workflow without its methods, so marshalling it does not recurse
+	type workflow Workflow
+	wf := workflow(*w)
+	if wf.SchemaVersion == 0 {
+		wf.SchemaVersion = CurrentSchemaVersion()
+	}
+	node, err := yaml.ValueToNode(&wf, yaml.IndentSequence(true), yaml.UseLiteralStyleIfMultiline(true))
+	if err != nil {
+		return nil, err
+	}
+	ast.Walk(flowQuoter{}, node)
+	var p printer.Printer
+	return p.PrintNode(node), nil
+}
+
+// flowQuoter double-quotes multi-line strings in flow sequences, where literal blocks are not allowed
+type flowQuoter struct{}
+
+func (q flowQuoter) Visit(node ast.Node) ast.Visitor {
+	seq, ok := node.(*ast.SequenceNode)
+	if !ok || !seq.IsFlowStyle {
+		return q
+	}
+	for _, v := range seq.Values {
+		if s, ok := v.(*ast.StringNode); ok && strings.ContainsAny(s.Value, "\n\r") {
+			s.Token.Type = token.DoubleQuoteType
+		}
+	}
+	return q
+}
+
+// WriteWorkflow writes the workflow to path as YAML, see MarshalYAML. Workflows loaded from files
+// have their includes merged in; write the result of ParseWorkflow to keep the include list.
+func WriteWorkflow(path string, wf *Workflow) error {
+	data, err := wf.MarshalYAML()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,98 @@
+package dsl
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWorkflow_MarshalYAML(t *testing.T) {
+	retries := 0
+	wf := &Workflow{
+		Name:        "release",
+		Description: "Builds and publishes\na release\n",
+		Vars:        map[string]string{"tag": "dev"},
+		Defaults:    &Defaults{Shell: "bash", Timeout: "5m"},
+		StepHooks: &StepHooks{
+			Before: [][]string{{"echo", "start: ${{ step.name }}"}},
+		},
+		Stages: []Stage{
+			{Name: "build", Steps: []Step{
+				{Name: "compile", Type: StepTypeExec, Run: []string{"go", "build", "-ldflags", "-X main.v=${{ vars.tag }}"}, Matchers: []string{"go"}, Retries: &retries},
+				{Name: "notes", Type: StepTypeExec, Run: []string{"printf", "line one\nline two\n"}},
+			}},
+			{Name: "publish", DependsOn: []string{"build"}, Steps: []Step{
+				{Name: "push", Type: StepTypeExec, Run: []string{"publish", ""}},
+			}},
+		},
+	}
+
+	data, err := wf.MarshalYAML()
+	if err != nil {
+		t.Fatalf("MarshalYAML() error: %v", err)
+	}
+	for _, want := range []string{
+		"schema_version: 2\n",
+		"  - name: build\n",
+		`run: [go, build, -ldflags, "-X main.v=${{ vars.tag }}"]`,
+		"depends_on: [build]",
+		"retries: 0",
+		"description: |\n  Builds and publishes\n",
+		`run: [printf, "line one\nline two\n"]`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("MarshalYAML() missing %q:\n%s", want, data)
+		}
+	}
+	if wf.SchemaVersion != 0 {
+		t.Errorf("MarshalYAML() changed the workflow's schema version to %d", wf.SchemaVersion)
+	}
+
+	got, err := LoadWorkflow(data, "release.yml")
+	if err != nil {
+		t.Fatalf("LoadWorkflow() error: %v\n%s", err, data)
+	}
+	want := *wf
+	want.SchemaVersion = CurrentSchemaVersion()
+	if !reflect.DeepEqual(got, &want) {
+		t.Errorf("round trip = %+v, want %+v\n%s", got, &want, data)
+	}
+}
+
+func TestWorkflow_MarshalYAML_Templates(t *testing.T) {
+	for _, tpl := range Templates() {
+		t.Run(tpl.Name, func(t *testing.T) {
+			wf := tpl.workflow()
+			data, err := wf.MarshalYAML()
+			if err != nil {
+				t.Fatalf("MarshalYAML() error: %v", err)
+			}
+			got, err := ParseWorkflow(data)
+			if err != nil {
+				t.Fatalf("ParseWorkflow() error: %v\n%s", err, data)
+			}
+			wf.SchemaVersion = CurrentSchemaVersion()
+			if !reflect.DeepEqual(got, &wf) {
+				t.Errorf("round trip = %+v, want %+v\n%s", got, &wf, data)
+			}
+		})
+	}
+}
+
+func TestWriteWorkflow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.yml")
+	wf := &Workflow{Name: "hello", Stages: []Stage{{Name: "greet", Steps: []Step{{Name: "hi", Type: StepTypeExec, Run: []string{"echo", "hi"}}}}}}
+	if err := WriteWorkflow(path, wf); err != nil {
+		t.Fatalf("WriteWorkflow() error: %v", err)
+	}
+	got, err := LoadWorkflowFromFile(path)
+	if err != nil {
+		data, _ := os.ReadFile(path)
+		t.Fatalf("LoadWorkflowFromFile() error: %v\n%s", err, data)
+	}
+	if got.Name != "hello" || len(got.Stages) != 1 || !reflect.DeepEqual(got.Stages[0].Steps[0].Run, Command{"echo", "hi"}) {
+		t.Errorf("written workflow = %+v", got)
+	}
+}
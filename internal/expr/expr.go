@@ -0,0 +1,432 @@
+// Package expr expands ${{ ... }} expressions in workflow strings.
+//
+// An expression is a reference of the form env.NAME, vars.NAME or steps.ID.status/output, a quoted
+// string, or a call of one of the built-in Functions, whose arguments are expressions themselves,
+// e.g. upper(default(env.STAGE, "dev")). Values compare with == and != and combine with &&, || and
+// !, which yield "true" or "false"; parentheses group them. Unset environment variables expand to
+// "" while undeclared workflow variables and unknown step ids are an error.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrSyntax is returned for malformed or unsupported expressions
+	ErrSyntax = errors.New("invalid expression")
+	// ErrUndefined is returned for references to variables that are not declared
+	ErrUndefined = errors.New("undefined variable")
+	// ErrFunction is returned when a function cannot compute its value, e.g. file of a missing file
+	ErrFunction = errors.New("function failed")
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Context holds the values expressions can reference
+type Context struct {
+	// Env holds the environment variables referenced as env.NAME
+	Env map[string]string
+	// Vars holds the workflow variables referenced as vars.NAME
+	Vars map[string]string
+	// Dir is the directory relative paths of file and hash are resolved against, the working directory when empty
+	Dir string
+	// Now returns the time used by now, time.Now when nil
+	Now func() time.Time
+	// Steps holds the results of the steps with an id, referenced as steps.ID.status and steps.ID.output
+	Steps map[string]StepResult
+}
+
+// StepResult is what expressions see of a step with an id
+type StepResult struct {
+	// Status is the step's outcome, e.g. "success", "failed" or "skipped", empty until it ran
+	Status string
+	// Output is the standard output of the step without surrounding white space
+	Output string
+}
+
+// Truthy reports whether the value of an expression counts as true: anything but "", "false" and "0"
+func Truthy(value string) bool {
+	return value != "" && value != "false" && value != "0"
+}
+
+// Condition evaluates a condition such as a step's when: either a bare expression or a string with
+// ${{ }} expressions, whose result is tested with Truthy
+func Condition(s string, ctx Context) (bool, error) {
+	var value string
+	var err error
+	if strings.Contains(s, "${{") {
+		value, err = Interpolate(s, ctx)
+	} else {
+		value, err = Eval(s, ctx)
+	}
+	return Truthy(strings.TrimSpace(value)), err
+}
+
+// Check reports syntax errors in the expressions of s, or in s itself when bare is set, without evaluating them
+func Check(s string, bare bool) error {
+	if bare && !strings.Contains(s, "${{") {
+		_, err := parseExpression(s)
+		return err
+	}
+	for {
+		start := strings.Index(s, "${{")
+		if start < 0 {
+			return nil
+		}
+		end := strings.Index(s[start:], "}}")
+		if end < 0 {
+			return fmt.Errorf("%w: unterminated ${{ in %q", ErrSyntax, s)
+		}
+		if _, err := parseExpression(s[start+3 : start+end]); err != nil {
+			return err
+		}
+		s = s[start+end+2:]
+	}
+}
+
+// Interpolate replaces every ${{ expr }} in s with the expression's value
+func Interpolate(s string, ctx Context) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${{")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end := strings.Index(s[start:], "}}")
+		if end < 0 {
+			return "", fmt.Errorf("%w: unterminated ${{ in %q", ErrSyntax, s)
+		}
+		value, err := Eval(s[start+3:start+end], ctx)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s[:start])
+		b.WriteString(value)
+		s = s[start+end+2:]
+	}
+}
+
+// InterpolateAll interpolates each string of ss
+func InterpolateAll(ss []string, ctx Context) ([]string, error) {
+	out := make([]string, len(ss))
+	for i, s := range ss {
+		v, err := Interpolate(s, ctx)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// Eval evaluates a single expression without the surrounding ${{ }}
+func Eval(expression string, ctx Context) (string, error) {
+	n, err := parseExpression(expression)
+	if err != nil {
+		return "", err
+	}
+	return n.eval(ctx)
+}
+
+func parseExpression(expression string) (node, error) {
+	p := &parser{src: expression}
+	n, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %v", ErrSyntax, strings.TrimSpace(expression), err)
+	}
+	return n, nil
+}
+
+// node is a parsed expression
+type node interface {
+	eval(ctx Context) (string, error)
+}
+
+type literal string
+
+func (l literal) eval(Context) (string, error) { return string(l), nil }
+
+type reference struct {
+	scope, name, field string
+}
+
+func (r reference) eval(ctx Context) (string, error) {
+	switch r.scope {
+	case "env":
+		return ctx.Env[r.name], nil
+	case "steps":
+		result, ok := ctx.Steps[r.name]
+		if !ok {
+			return "", fmt.Errorf("%w: %q: no step has id %s", ErrUndefined, "steps."+r.name+"."+r.field, r.name)
+		}
+		if r.field == "status" {
+			return result.Status, nil
+		}
+		return result.Output, nil
+	}
+	v, ok := ctx.Vars[r.name]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUndefined, r.scope+"."+r.name)
+	}
+	return v, nil
+}
+
+// operator is a comparison or logical operator applied to its operands
+type operator struct {
+	op          string
+	left, right node
+}
+
+func (o operator) eval(ctx Context) (string, error) {
+	left, err := o.left.eval(ctx)
+	if err != nil {
+		return "", err
+	}
+	switch o.op {
+	case "!":
+		return strconv.FormatBool(!Truthy(left)), nil
+	case "&&", "||":
+		if Truthy(left) == (o.op == "||") {
+			return strconv.FormatBool(Truthy(left)), nil
+		}
+		right, err := o.right.eval(ctx)
+		return strconv.FormatBool(Truthy(right)), err
+	}
+	right, err := o.right.eval(ctx)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatBool((left == right) == (o.op == "==")), nil
+}
+
+type call struct {
+	fn   *Function
+	args []node
+}
+
+func (c call) eval(ctx Context) (string, error) {
+	return c.fn.call(ctx, c.args)
+}
+
+// parser reads an expression: references, quoted strings and function calls combined with operators
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.src) {
+		return nil, fmt.Errorf("unexpected %q", p.src[p.pos:])
+	}
+	return n, nil
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// consume skips token and reports true when it comes next
+func (p *parser) consume(token string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *parser) or() (node, error) {
+	return p.binary([]string{"||"}, p.and)
+}
+
+func (p *parser) and() (node, error) {
+	return p.binary([]string{"&&"}, p.comparison)
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!="} {
+		if p.consume(op) {
+			right, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return operator{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+// binary reads operands joined by one of ops, left to right
+func (p *parser) binary(ops []string, operand func() (node, error)) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		i := slices.IndexFunc(ops, p.consume)
+		if i < 0 {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = operator{op: ops[i], left: left, right: right}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], "!") && !strings.HasPrefix(p.src[p.pos:], "!=") {
+		p.pos++
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return operator{op: "!", left: operand}, nil
+	}
+	if p.consume("(") {
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, errors.New("missing )")
+		}
+		return n, nil
+	}
+	return p.value()
+}
+
+// value reads a quoted string, a reference or a function call
+func (p *parser) value() (node, error) {
+	p.skipSpace()
+	if p.pos < len(p.src) && (p.src[p.pos] == '"' || p.src[p.pos] == '\'') {
+		return p.str()
+	}
+	ident := p.ident()
+	if ident == "" {
+		if p.pos == len(p.src) {
+			return nil, errors.New("missing value")
+		}
+		return nil, fmt.Errorf("unexpected %q", p.src[p.pos:])
+	}
+	if p.pos < len(p.src) && p.src[p.pos] == '(' {
+		return p.call(ident)
+	}
+	if p.pos < len(p.src) && p.src[p.pos] == '.' && (ident == "env" || ident == "vars" || ident == "steps") {
+		p.pos++
+		name := p.ident()
+		if !namePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid name after %s.", ident)
+		}
+		ref := reference{scope: ident, name: name}
+		if ident == "steps" {
+			if !p.consume(".") {
+				return nil, fmt.Errorf("steps.%s needs .status or .output", name)
+			}
+			if ref.field = p.ident(); ref.field != "status" && ref.field != "output" {
+				return nil, fmt.Errorf("steps.%s has no field %q, use status or output", name, ref.field)
+			}
+		}
+		return ref, nil
+	}
+	return nil, fmt.Errorf("unknown reference %q", ident)
+}
+
+func (p *parser) ident() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9' || p.pos == start) {
+			break
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// str reads a string quoted with " or ', in which a backslash escapes the next character
+func (p *parser) str() (node, error) {
+	quote := p.src[p.pos]
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.src); p.pos++ {
+		c := p.src[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return literal(b.String()), nil
+		case c == '\\' && p.pos+1 < len(p.src):
+			p.pos++
+			b.WriteByte(p.src[p.pos])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return nil, errors.New("unterminated string")
+}
+
+func (p *parser) call(name string) (node, error) {
+	fn := lookupFunction(name)
+	if fn == nil {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	p.pos++
+	var args []node
+	for {
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == ')' && len(args) == 0 {
+			p.pos++
+			break
+		}
+		arg, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, fmt.Errorf("missing ) after arguments of %s", name)
+		}
+		if p.src[p.pos] == ')' {
+			p.pos++
+			break
+		}
+		if p.src[p.pos] != ',' {
+			return nil, fmt.Errorf("unexpected %q in arguments of %s", p.src[p.pos:], name)
+		}
+		p.pos++
+	}
+	if len(args) < fn.minArgs || len(args) > fn.maxArgs {
+		return nil, fmt.Errorf("%s takes %s", fn.Signature, plural(fn.minArgs, fn.maxArgs))
+	}
+	return call{fn: fn, args: args}, nil
+}
+
+func plural(lo, hi int) string {
+	switch {
+	case lo == hi && hi == 1:
+		return "1 argument"
+	case lo == hi:
+		return fmt.Sprintf("%d arguments", hi)
+	default:
+		return fmt.Sprintf("%d to %d arguments", lo, hi)
+	}
+}
@@ -0,0 +1,134 @@
+package expr
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	ctx := Context{
+		Env:  map[string]string{"HOST": "db.local", "PORT": "5432"},
+		Vars: map[string]string{"image": "app", "tag": ""},
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "no expressions", input: "echo $HOME", want: "echo $HOME"},
+		{name: "single reference", input: "${{ env.HOST }}", want: "db.local"},
+		{name: "several references", input: "postgres://${{env.HOST}}:${{ env.PORT }}/app", want: "postgres://db.local:5432/app"},
+		{name: "unset variable", input: "[${{ env.MISSING }}]", want: "[]"},
+		{name: "variables", input: "${{ vars.image }}:${{ vars.tag }}", want: "app:"},
+		{name: "unterminated", input: "${{ env.HOST", wantErr: true},
+		{name: "unknown scope", input: "${{ secrets.TOKEN }}", wantErr: true},
+		{name: "invalid name", input: "${{ env.A-B }}", wantErr: true},
+		{name: "undeclared variable", input: "${{ vars.missing }}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Interpolate(tt.input, ctx)
+			if tt.wantErr {
+				if !errors.Is(err, ErrSyntax) && !errors.Is(err, ErrUndefined) {
+					t.Fatalf("Interpolate() error = %v, want ErrSyntax or ErrUndefined", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Interpolate() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Interpolate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterpolateAll(t *testing.T) {
+	got, err := InterpolateAll([]string{"echo", "${{ env.A }}"}, Context{Env: map[string]string{"A": "x"}})
+	if err != nil || len(got) != 2 || got[1] != "x" {
+		t.Errorf("InterpolateAll() = %q, %v", got, err)
+	}
+	if _, err := InterpolateAll([]string{"${{ nope }}"}, Context{}); err == nil {
+		t.Error("InterpolateAll() accepted an invalid expression")
+	}
+}
+
+func TestEval_Operators(t *testing.T) {
+	ctx := Context{
+		Env:   map[string]string{"CI": "true", "EMPTY": ""},
+		Vars:  map[string]string{"target": "prod"},
+		Steps: map[string]StepResult{"deploy": {Status: "failed", Output: "v1.2.3"}, "later": {}},
+	}
+
+	tests := []struct {
+		input   string
+		want    string
+		wantErr error
+	}{
+		{input: "steps.deploy.status == 'failed'", want: "true"},
+		{input: "steps.deploy.status != 'failed'", want: "false"},
+		{input: "steps.deploy.output", want: "v1.2.3"},
+		{input: "steps.later.status == ''", want: "true"},
+		{input: "env.CI && vars.target == 'prod'", want: "true"},
+		{input: "env.EMPTY || !env.CI", want: "false"},
+		{input: "!(env.EMPTY)", want: "true"},
+		{input: "env.CI == 'true' && (vars.target == 'dev' || vars.target == 'prod')", want: "true"},
+		{input: "upper(steps.deploy.status) == 'FAILED'", want: "true"},
+		{input: "steps.missing.status", wantErr: ErrUndefined},
+		{input: "steps.deploy.result", wantErr: ErrSyntax},
+		{input: "steps.deploy", wantErr: ErrSyntax},
+		{input: "(env.CI", wantErr: ErrSyntax},
+		{input: "env.CI ==", wantErr: ErrSyntax},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Eval(tt.input, ctx)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Eval() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Eval() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCondition(t *testing.T) {
+	ctx := Context{Env: map[string]string{"CI": "1", "DEBUG": "false"}}
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{input: "env.CI", want: true},
+		{input: "${{ env.CI }}", want: true},
+		{input: "${{ env.DEBUG }}", want: false},
+		{input: "env.MISSING", want: false},
+		{input: "env.CI == '0'", want: false},
+	}
+	for _, tt := range tests {
+		if got, err := Condition(tt.input, ctx); err != nil || got != tt.want {
+			t.Errorf("Condition(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check("steps.deploy.status == 'failed'", true); err != nil {
+		t.Errorf("Check() of a valid expression: %v", err)
+	}
+	if err := Check("echo ${{ env.A }} ${{ upper('b') }}", false); err != nil {
+		t.Errorf("Check() of valid interpolation: %v", err)
+	}
+	for _, s := range []string{"${{ nope }}", "${{ env.A", "upper("} {
+		if err := Check(s, true); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Check(%q) error = %v, want %v", s, err, ErrSyntax)
+		}
+	}
+}
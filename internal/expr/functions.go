@@ -0,0 +1,157 @@
+package expr
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Function is a built-in function of expressions
+type Function struct {
+	Name string
+	// Signature shows how the function is called, e.g. default(value, fallback)
+	Signature   string
+	Description string
+
+	minArgs, maxArgs int
+	fn               func(ctx Context, args []node) (string, error)
+}
+
+func (f *Function) call(ctx Context, args []node) (string, error) {
+	return f.fn(ctx, args)
+}
+
+var functions = []Function{
+	{
+		Name: "env", Signature: "env(name)", minArgs: 1, maxArgs: 1,
+		Description: `the environment variable name, "" when unset; like env.NAME for names computed at run time`,
+		fn: func(ctx Context, args []node) (string, error) {
+			name, err := args[0].eval(ctx)
+			return ctx.Env[name], err
+		},
+	},
+	{
+		Name: "file", Signature: "file(path)", minArgs: 1, maxArgs: 1,
+		Description: "the contents of the file at path, without a trailing newline",
+		fn: func(ctx Context, args []node) (string, error) {
+			data, err := readFile(ctx, args[0])
+			return strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r"), err
+		},
+	},
+	{
+		Name: "hash", Signature: "hash(path)", minArgs: 1, maxArgs: 1,
+		Description: "the hex-encoded SHA-256 checksum of the file at path",
+		fn: func(ctx Context, args []node) (string, error) {
+			data, err := readFile(ctx, args[0])
+			if err != nil {
+				return "", err
+			}
+			sum := sha256.Sum256(data)
+			return hex.EncodeToString(sum[:]), nil
+		},
+	},
+	{
+		Name: "now", Signature: "now([layout])", minArgs: 0, maxArgs: 1,
+		Description: "the current time in a Go time layout such as \"2006-01-02\", RFC 3339 by default",
+		fn: func(ctx Context, args []node) (string, error) {
+			layout := time.RFC3339
+			if len(args) > 0 {
+				var err error
+				if layout, err = args[0].eval(ctx); err != nil {
+					return "", err
+				}
+			}
+			now := time.Now
+			if ctx.Now != nil {
+				now = ctx.Now
+			}
+			return now().Format(layout), nil
+		},
+	},
+	{
+		Name: "uuid", Signature: "uuid()", minArgs: 0, maxArgs: 0,
+		Description: "a random UUID (version 4)",
+		fn: func(Context, []node) (string, error) {
+			b := make([]byte, 16)
+			if _, err := rand.Read(b); err != nil {
+				return "", fmt.Errorf("%w: uuid(): %v", ErrFunction, err)
+			}
+			b[6] = b[6]&0x0f | 0x40
+			b[8] = b[8]&0x3f | 0x80
+			return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+		},
+	},
+	{
+		Name: "default", Signature: "default(value, fallback)", minArgs: 2, maxArgs: 2,
+		Description: "value, or fallback when value is empty or an undeclared variable",
+		fn: func(ctx Context, args []node) (string, error) {
+			v, err := args[0].eval(ctx)
+			if err != nil && !errors.Is(err, ErrUndefined) {
+				return "", err
+			}
+			if v != "" {
+				return v, nil
+			}
+			return args[1].eval(ctx)
+		},
+	},
+	{
+		Name: "trim", Signature: "trim(s)", minArgs: 1, maxArgs: 1,
+		Description: "s without leading and trailing white space",
+		fn:          stringFunction(strings.TrimSpace),
+	},
+	{
+		Name: "upper", Signature: "upper(s)", minArgs: 1, maxArgs: 1,
+		Description: "s in upper case",
+		fn:          stringFunction(strings.ToUpper),
+	},
+	{
+		Name: "lower", Signature: "lower(s)", minArgs: 1, maxArgs: 1,
+		Description: "s in lower case",
+		fn:          stringFunction(strings.ToLower),
+	},
+}
+
+// Functions returns the built-in functions of expressions
+func Functions() []Function {
+	return functions
+}
+
+func lookupFunction(name string) *Function {
+	for i := range functions {
+		if functions[i].Name == name {
+			return &functions[i]
+		}
+	}
+	return nil
+}
+
+// stringFunction applies f to the only argument
+func stringFunction(f func(string) string) func(Context, []node) (string, error) {
+	return func(ctx Context, args []node) (string, error) {
+		s, err := args[0].eval(ctx)
+		return f(s), err
+	}
+}
+
+// readFile reads the file named by the argument, relative to ctx.Dir
+func readFile(ctx Context, arg node) ([]byte, error) {
+	path, err := arg.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(path) && ctx.Dir != "" {
+		path = filepath.Join(ctx.Dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFunction, err)
+	}
+	return data, nil
+}
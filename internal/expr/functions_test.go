@@ -0,0 +1,85 @@
+package expr
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestFunctions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "VERSION"), []byte("1.2.3\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	ctx := Context{
+		Env:  map[string]string{"STAGE": "prod", "EMPTY": ""},
+		Vars: map[string]string{"name": "  App  "},
+		Dir:  dir,
+		Now:  func() time.Time { return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC) },
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{name: "env", input: `env("STAGE")`, want: "prod"},
+		{name: "env computed", input: `env(upper("stage"))`, want: "prod"},
+		{name: "file", input: `file("VERSION")`, want: "1.2.3"},
+		{name: "hash", input: `hash('VERSION')`, want: "d82f34ae9aa41bc4a0cb529a1ac0898fed09d6b479fb1cc44cb66c34f15ee84d"},
+		{name: "now", input: `now("2006-01-02")`, want: "2025-01-02"},
+		{name: "now default layout", input: `now()`, want: "2025-01-02T03:04:05Z"},
+		{name: "default of empty", input: `default(env.EMPTY, "dev")`, want: "dev"},
+		{name: "default of set", input: `default(env.STAGE, "dev")`, want: "prod"},
+		{name: "default of undeclared", input: `default(vars.missing, 'x')`, want: "x"},
+		{name: "nested", input: `lower(trim(vars.name))`, want: "app"},
+		{name: "escaped quote", input: `upper("a\"b")`, want: `A"B`},
+		{name: "missing file", input: `file("nope")`, wantErr: ErrFunction},
+		{name: "unknown function", input: `reverse("x")`, wantErr: ErrSyntax},
+		{name: "wrong arity", input: `upper("a", "b")`, wantErr: ErrSyntax},
+		{name: "unterminated call", input: `upper("a"`, wantErr: ErrSyntax},
+		{name: "unterminated string", input: `upper("a)`, wantErr: ErrSyntax},
+		{name: "trailing input", input: `upper("a") x`, wantErr: ErrSyntax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Eval(tt.input, ctx)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Eval() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Eval() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFunctions_UUID(t *testing.T) {
+	a, err := Eval("uuid()", Context{})
+	if err != nil {
+		t.Fatalf("Eval() error: %v", err)
+	}
+	b, _ := Eval("uuid()", Context{})
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(a) || a == b {
+		t.Errorf("uuid() = %q, %q, want two different version 4 UUIDs", a, b)
+	}
+}
+
+func TestFunctions_Documented(t *testing.T) {
+	for _, f := range Functions() {
+		if f.Signature == "" || f.Description == "" || f.fn == nil {
+			t.Errorf("function %s is not documented or has no implementation", f.Name)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"go.mod", "go.mod", true},
+		{"go.mod", "api/go.mod", false},
+		{"src/**", "src/main.go", true},
+		{"src/**", "src/pkg/deep/file.go", true},
+		{"src/**", "src", true},
+		{"src/**", "srcs/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "a/b/c.go", true},
+		{"**/*.go", "a/b/c.txt", false},
+		{"services/*/Dockerfile", "services/api/Dockerfile", true},
+		{"services/*/Dockerfile", "services/api/v2/Dockerfile", false},
+		{"services/**/Dockerfile", "services/api/v2/Dockerfile", true},
+		{"docs/[a-c]*.md", "docs/build.md", true},
+		{"[", "[", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	patterns := []string{"api/**", "go.mod"}
+	if !MatchAny(patterns, "go.mod") || !MatchAny(patterns, "api/handler.go") || MatchAny(patterns, "web/index.ts") {
+		t.Error("MatchAny() does not match any of the patterns")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate("src/**/*.go"); err != nil {
+		t.Errorf("Validate() error: %v", err)
+	}
+	if err := Validate("src/[a-"); err == nil {
+		t.Error("Validate() accepted a malformed pattern")
+	}
+}
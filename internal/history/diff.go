@@ -0,0 +1,120 @@
+package history
+
+import (
+	"maps"
+	"slices"
+)
+
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Change describes how a step differs between two executions
+type Change struct {
+	Kind ChangeKind
+	Path string
+	Old  *StepRecord
+	New  *StepRecord
+}
+
+// Diff compares the steps of two executions by path. Commands are only compared for
+// exec steps, since other step types compute their commands at run time.
+func Diff(old, new []StepRecord) []Change {
+	oldByPath := make(map[string]*StepRecord, len(old))
+	for i := range old {
+		oldByPath[old[i].Path] = &old[i]
+	}
+	newByPath := make(map[string]*StepRecord, len(new))
+	for i := range new {
+		newByPath[new[i].Path] = &new[i]
+	}
+
+	var changes []Change
+	for i := range new {
+		n := &new[i]
+		o, ok := oldByPath[n.Path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: ChangeAdded, Path: n.Path, New: n})
+		case o.Type != n.Type || (n.Type == "exec" && !equalCommands(o.Commands, n.Commands)):
+			changes = append(changes, Change{Kind: ChangeChanged, Path: n.Path, Old: o, New: n})
+		}
+	}
+	for i := range old {
+		if _, ok := newByPath[old[i].Path]; !ok {
+			changes = append(changes, Change{Kind: ChangeRemoved, Path: old[i].Path, Old: &old[i]})
+		}
+	}
+	return changes
+}
+
+func equalCommands(a, b [][]string) bool {
+	return slices.EqualFunc(a, b, func(x, y []string) bool { return slices.Equal(x, y) })
+}
+
+// DriftKind names a property of the host a recorded run can differ in from the current one
+type DriftKind string
+
+const (
+	DriftOS    DriftKind = "os"
+	DriftArch  DriftKind = "arch"
+	DriftHost  DriftKind = "host"
+	DriftForge DriftKind = "forge"
+	// DriftTools covers programs that resolve to another file or not at all
+	DriftTools DriftKind = "tools"
+)
+
+// DriftKinds lists every kind of drift
+var DriftKinds = []DriftKind{DriftOS, DriftArch, DriftHost, DriftForge, DriftTools}
+
+// Drift describes how the current host differs from the one a run was recorded on
+type Drift struct {
+	Kind DriftKind
+	// Name is the program for DriftTools
+	Name     string
+	Old, New string
+}
+
+func (d Drift) String() string {
+	name := string(d.Kind)
+	if d.Name != "" {
+		name += " " + d.Name
+	}
+	return name + ": " + orNone(d.Old) + " -> " + orNone(d.New)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// Drift compares the environment a run was recorded in with cur; tools are compared for the
+// programs the run executed
+func (e *Environment) Drift(cur *Environment) []Drift {
+	var drifts []Drift
+	for _, f := range []struct {
+		kind     DriftKind
+		old, new string
+	}{
+		{DriftOS, e.OS, cur.OS},
+		{DriftArch, e.Arch, cur.Arch},
+		{DriftHost, e.Hostname, cur.Hostname},
+		{DriftForge, e.Forge, cur.Forge},
+	} {
+		if f.old != f.new {
+			drifts = append(drifts, Drift{Kind: f.kind, Old: f.old, New: f.new})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(e.Tools)) {
+		if old, cur := e.Tools[name], cur.Tools[name]; old != cur {
+			drifts = append(drifts, Drift{Kind: DriftTools, Name: name, Old: old, New: cur})
+		}
+	}
+	return drifts
+}
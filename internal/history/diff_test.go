@@ -0,0 +1,65 @@
+package history
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := []StepRecord{
+		{Path: "build/compile", Type: "exec", Commands: [][]string{{"go", "build"}}},
+		{Path: "build/wait", Type: "sleep"},
+		{Path: "test/unit", Type: "go-test", Commands: [][]string{{"go", "test", "m/a"}}},
+		{Path: "deploy/push", Type: "exec", Commands: [][]string{{"docker", "push"}}},
+	}
+	planned := []StepRecord{
+		{Path: "build/compile", Type: "exec", Commands: [][]string{{"go", "build", "-race"}}},
+		{Path: "build/wait", Type: "exec", Commands: [][]string{{"true"}}},
+		{Path: "test/unit", Type: "go-test"},
+		{Path: "test/lint", Type: "exec", Commands: [][]string{{"golangci-lint", "run"}}},
+	}
+
+	changes := Diff(old, planned)
+
+	var got []string
+	for _, c := range changes {
+		got = append(got, string(c.Kind)+" "+c.Path)
+	}
+	want := []string{
+		"changed build/compile",
+		"changed build/wait",
+		"added test/lint",
+		"removed deploy/push",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+}
+
+func TestDiff_NoChanges(t *testing.T) {
+	steps := []StepRecord{{Path: "build/compile", Type: "exec", Commands: [][]string{{"go", "build"}}}}
+	if changes := Diff(steps, steps); len(changes) != 0 {
+		t.Errorf("Diff() = %v, want no changes", changes)
+	}
+}
+
+func TestEnvironment_Drift(t *testing.T) {
+	old := &Environment{OS: "linux", Arch: "amd64", Hostname: "ci-1", Forge: "1.2.0", Tools: map[string]string{"go": "/usr/bin/go", "make": "/usr/bin/make"}}
+	cur := &Environment{OS: "linux", Arch: "amd64", Hostname: "ci-2", Forge: "1.2.0", Tools: map[string]string{"go": "/opt/go/bin/go", "make": "/usr/bin/make"}}
+
+	got := old.Drift(cur)
+	want := []Drift{
+		{Kind: DriftHost, Old: "ci-1", New: "ci-2"},
+		{Kind: DriftTools, Name: "go", Old: "/usr/bin/go", New: "/opt/go/bin/go"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Drift() = %v, want %v", got, want)
+	}
+	if s := (Drift{Kind: DriftTools, Name: "make", Old: "/usr/bin/make"}).String(); s != "tools make: /usr/bin/make -> (none)" {
+		t.Errorf("String() = %q", s)
+	}
+	if got := old.Drift(old); len(got) != 0 {
+		t.Errorf("Drift() of the same environment = %v", got)
+	}
+}
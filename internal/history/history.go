@@ -0,0 +1,218 @@
+// Package history stores records of past workflow runs.
+package history
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andre-koe/forge/internal/matcher"
+)
+
+// DirName is the directory, relative to the workflow file, where run records are kept
+const DirName = ".forge/runs"
+
+// ErrNoRuns is returned when no matching run has been recorded yet
+var ErrNoRuns = errors.New("no recorded runs")
+
+type Status string
+
+const (
+	StatusSuccess Status = "success"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped"
+	// StatusCached marks steps skipped because their inputs were unchanged since the last successful run
+	StatusCached Status = "cached"
+)
+
+// Record describes one execution of a workflow
+type Record struct {
+	ID         string       `json:"id"`
+	Workflow   string       `json:"workflow"`
+	Name       string       `json:"name"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	Status     Status       `json:"status"`
+	Error      string       `json:"error,omitempty"`
+	Steps      []StepRecord `json:"steps"`
+	// Timezone is the zone the run's timestamps were shown in, e.g. Europe/Berlin
+	Timezone string `json:"timezone,omitempty"`
+	// StageDescriptions holds the description: of the workflow's stages that have one, by name
+	StageDescriptions map[string]string `json:"stage_descriptions,omitempty"`
+	// Environment describes the host of runs recorded for replay
+	Environment *Environment `json:"environment,omitempty"`
+}
+
+// Environment describes the host a run was recorded on, to tell whether it can be replayed elsewhere
+type Environment struct {
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	Hostname string `json:"hostname"`
+	Forge    string `json:"forge_version"`
+	// Tools maps the programs the run executed to the files they resolved to
+	Tools map[string]string `json:"tools,omitempty"`
+}
+
+// Execution is a command as a run recorded for replay executed it, after interpolation
+type Execution struct {
+	Argv []string `json:"argv"`
+	Dir  string   `json:"dir"`
+	// Env holds the variables forge set on top of the inherited environment, or the whole
+	// environment with CleanEnv
+	Env      []string `json:"env,omitempty"`
+	CleanEnv bool     `json:"clean_env,omitempty"`
+}
+
+// StepRecord describes one executed step, including every command it ran
+type StepRecord struct {
+	Path  string `json:"path"`
+	Stage string `json:"stage"`
+	Step  string `json:"step"`
+	Type  string `json:"type"`
+	// Group is the name of the group step the step ran in
+	Group string `json:"group,omitempty"`
+	// Description is the step's description: from the workflow
+	Description string     `json:"description,omitempty"`
+	Commands    [][]string `json:"commands,omitempty"`
+	// Executions holds the commands as executed, in runs recorded for replay
+	Executions []Execution `json:"executions,omitempty"`
+	// IdempotencyKey is the step's interpolated idempotency_key
+	IdempotencyKey string        `json:"idempotency_key,omitempty"`
+	Status         Status        `json:"status"`
+	StartedAt      time.Time     `json:"started_at"`
+	Duration       time.Duration `json:"duration"`
+	Error          string        `json:"error,omitempty"`
+	// Findings are problems extracted from the step output by problem matchers
+	Findings []matcher.Finding `json:"findings,omitempty"`
+	// OutputTail holds the last lines of the step's process output when the runner keeps them
+	OutputTail []string `json:"output_tail,omitempty"`
+	// OutputHead holds the first lines of the output when its middle was left out to stay within
+	// the runner's output limit; OutputOmitted is the number of bytes left out
+	OutputHead    []string `json:"output_head,omitempty"`
+	OutputOmitted int64    `json:"output_omitted,omitempty"`
+}
+
+// NewID returns a sortable, unique run identifier
+func NewID(now time.Time) string {
+	b := make([]byte, 3)
+	_, _ = rand.Read(b)
+	return now.UTC().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// Store keeps run records as JSON files in a directory
+type Store struct {
+	Dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// WorkflowsDir is where multi-workflow projects keep their workflow files
+const WorkflowsDir = ".forge/workflows"
+
+// ForWorkflow returns the store that sits next to the given workflow file, or in the project
+// root for workflows kept in WorkflowsDir
+func ForWorkflow(workflow string) *Store {
+	dir := filepath.Dir(workflow)
+	if filepath.Base(dir) == filepath.Base(WorkflowsDir) && filepath.Base(filepath.Dir(dir)) == filepath.Dir(WorkflowsDir) {
+		dir = filepath.Dir(filepath.Dir(dir))
+	}
+	return NewStore(filepath.Join(dir, DirName))
+}
+
+// Save writes the record, replacing an earlier version with the same ID
+func (s *Store) Save(rec *Record) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.Dir, rec.ID+".json"), data, 0644)
+}
+
+// Get loads the record with the given ID
+func (s *Store) Get(id string) (*Record, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("invalid run id %q", id)
+	}
+	data, err := os.ReadFile(filepath.Join(s.Dir, id+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("run %s: %w", id, ErrNoRuns)
+		}
+		return nil, err
+	}
+	var rec Record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("run %s: %w", id, err)
+	}
+	return &rec, nil
+}
+
+// List returns all records, oldest first
+func (s *Store) List() ([]*Record, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var records []*Record
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		rec, err := s.Get(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		records = append(records, rec)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].StartedAt.Before(records[j].StartedAt)
+	})
+	return records, nil
+}
+
+// Last returns the most recent record of the named workflow
+func (s *Store) Last(name string) (*Record, error) {
+	records, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Name == name {
+			return records[i], nil
+		}
+	}
+	return nil, fmt.Errorf("workflow %s: %w", name, ErrNoRuns)
+}
+
+// Succeeded returns the most recent run in which a step with the idempotency key succeeded
+func (s *Store) Succeeded(key string) (*Record, error) {
+	records, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		for _, step := range records[i].Steps {
+			if step.IdempotencyKey == key && step.Status == StatusSuccess {
+				return records[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("idempotency key %s: %w", key, ErrNoRuns)
+}
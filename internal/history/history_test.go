@@ -0,0 +1,105 @@
+package history
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_SaveGetList(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "runs"))
+	base := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	first := &Record{ID: NewID(base), Name: "ci", StartedAt: base, Status: StatusSuccess}
+	second := &Record{ID: NewID(base.Add(time.Minute)), Name: "ci", StartedAt: base.Add(time.Minute), Status: StatusFailed}
+	other := &Record{ID: NewID(base.Add(2 * time.Minute)), Name: "deploy", StartedAt: base.Add(2 * time.Minute)}
+
+	for _, rec := range []*Record{second, first, other} {
+		if err := store.Save(rec); err != nil {
+			t.Fatalf("Save() failed: %v", err)
+		}
+	}
+
+	got, err := store.Get(first.ID)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if got.Name != "ci" || got.Status != StatusSuccess {
+		t.Errorf("Get() = %+v, want %+v", got, first)
+	}
+
+	records, err := store.List()
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(records) != 3 || records[0].ID != first.ID || records[2].ID != other.ID {
+		t.Errorf("List() returned records in wrong order: %v", records)
+	}
+
+	last, err := store.Last("ci")
+	if err != nil {
+		t.Fatalf("Last() failed: %v", err)
+	}
+	if last.ID != second.ID {
+		t.Errorf("Last() = %s, want %s", last.ID, second.ID)
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "missing"))
+
+	if records, err := store.List(); err != nil || len(records) != 0 {
+		t.Errorf("List() on missing dir = %v, %v, want empty", records, err)
+	}
+	if _, err := store.Last("ci"); !errors.Is(err, ErrNoRuns) {
+		t.Errorf("Last() error = %v, want %v", err, ErrNoRuns)
+	}
+	if _, err := store.Get("nope"); !errors.Is(err, ErrNoRuns) {
+		t.Errorf("Get() error = %v, want %v", err, ErrNoRuns)
+	}
+	if _, err := store.Get("../etc/passwd"); err == nil {
+		t.Error("Get() should reject ids containing path separators")
+	}
+}
+
+func TestStore_Succeeded(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "runs"))
+	base := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	step := func(key string, status Status) StepRecord {
+		return StepRecord{Step: "bucket", IdempotencyKey: key, Status: status}
+	}
+	for _, rec := range []*Record{
+		{ID: "1", StartedAt: base, Steps: []StepRecord{step("bucket-a", StatusSuccess)}},
+		{ID: "2", StartedAt: base.Add(time.Minute), Steps: []StepRecord{step("bucket-a", StatusSuccess)}},
+		{ID: "3", StartedAt: base.Add(2 * time.Minute), Steps: []StepRecord{step("bucket-a", StatusSkipped), step("bucket-b", StatusFailed)}},
+	} {
+		if err := store.Save(rec); err != nil {
+			t.Fatalf("Save() failed: %v", err)
+		}
+	}
+
+	rec, err := store.Succeeded("bucket-a")
+	if err != nil || rec.ID != "2" {
+		t.Errorf("Succeeded(bucket-a) = %v, %v, want run 2", rec, err)
+	}
+	if _, err := store.Succeeded("bucket-b"); !errors.Is(err, ErrNoRuns) {
+		t.Errorf("Succeeded(bucket-b) error = %v, want %v", err, ErrNoRuns)
+	}
+}
+
+func TestForWorkflow(t *testing.T) {
+	tests := []struct {
+		workflow string
+		want     string
+	}{
+		{workflow: filepath.Join("project", "ci.yaml"), want: filepath.Join("project", DirName)},
+		{workflow: filepath.Join("project", ".forge", "workflows", "ci.yaml"), want: filepath.Join("project", DirName)},
+		{workflow: filepath.Join("project", "workflows", "ci.yaml"), want: filepath.Join("project", "workflows", DirName)},
+	}
+	for _, tt := range tests {
+		if got := ForWorkflow(tt.workflow).Dir; got != tt.want {
+			t.Errorf("ForWorkflow(%s) dir = %q, want %q", tt.workflow, got, tt.want)
+		}
+	}
+}
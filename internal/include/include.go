@@ -0,0 +1,267 @@
+// Package include fetches workflow includes from https URLs and Git repositories, caches them
+// under the user's cache directory and pins what they resolved to in a lock file next to the workflow.
+package include
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// LockSuffix is appended to the workflow file name to name its lock file
+const LockSuffix = ".lock"
+
+// maxSize limits how much of an included file is read
+const maxSize = 10 << 20
+
+// ErrLockMismatch is returned when a fetched include does not match the digest recorded in the lock file
+var ErrLockMismatch = errors.New("include does not match the lock file")
+
+// Lock records what the remote includes of a workflow resolved to
+type Lock struct {
+	Includes map[string]LockEntry `json:"includes"`
+}
+
+// LockEntry is the resolved state of one include, keyed by its source
+type LockEntry struct {
+	// Commit is the commit a Git ref resolved to
+	Commit string `json:"commit,omitempty"`
+	SHA256 string `json:"sha256"`
+}
+
+// Fetcher fetches remote includes
+type Fetcher struct {
+	// CacheDir keeps fetched files and Git repositories
+	CacheDir   string
+	HTTPClient *http.Client
+	// Git runs git with the given arguments in dir and returns its stdout
+	Git func(dir string, args ...string) ([]byte, error)
+
+	mu sync.Mutex
+}
+
+// DefaultCacheDir returns ~/.cache/forge, or the platform's equivalent
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "forge")
+}
+
+// NewFetcher returns a fetcher caching in DefaultCacheDir
+func NewFetcher() *Fetcher {
+	return &Fetcher{
+		CacheDir:   DefaultCacheDir(),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		Git:        runGit,
+	}
+}
+
+func runGit(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return out, fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return out, err
+}
+
+// LockPath returns the lock file of a workflow file
+func LockPath(workflow string) string {
+	return workflow + LockSuffix
+}
+
+// Fetch returns the content of a remote include of workflow. Git refs are pinned to the
+// commit recorded in the workflow's lock file; new includes are added to it. Workflows
+// read from stdin or a URL have no lock file.
+func (f *Fetcher) Fetch(workflow string, inc dsl.Include) ([]byte, error) {
+	if err := inc.Validate(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	lockPath := ""
+	if fi, err := os.Stat(workflow); err == nil && fi.Mode().IsRegular() {
+		lockPath = LockPath(workflow)
+	}
+	lock, err := ReadLock(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	key := inc.String()
+	locked, isLocked := lock.Includes[key]
+
+	var data []byte
+	var entry LockEntry
+	switch {
+	case inc.URL != "":
+		data, err = f.fetchURL(inc.URL, inc.SHA256)
+	default:
+		data, entry.Commit, err = f.fetchGit(inc, locked.Commit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := inc.CheckSHA256(data); err != nil {
+		return nil, err
+	}
+	entry.SHA256 = digest(data)
+	if isLocked {
+		if locked.SHA256 != entry.SHA256 {
+			return nil, fmt.Errorf("%w: %s: locked %s, got %s; delete its entry in %s to update it", ErrLockMismatch, key, locked.SHA256, entry.SHA256, lockPath)
+		}
+		return data, nil
+	}
+	if lockPath != "" {
+		lock.Includes[key] = entry
+		if err := lock.Write(lockPath); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fetchURL downloads url unless a file with the expected digest is cached
+func (f *Fetcher) fetchURL(url, sum string) ([]byte, error) {
+	cached := filepath.Join(f.CacheDir, "includes", strings.ToLower(sum))
+	if data, err := os.ReadFile(cached); err == nil && digest(data) == strings.ToLower(sum) {
+		return data, nil
+	}
+
+	resp, err := f.HTTPClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize))
+	if err != nil {
+		return nil, err
+	}
+	if digest(data) == strings.ToLower(sum) {
+		if err := writeFile(cached, data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// fetchGit reads the include's file at commit, or at its ref if commit is empty, from a
+// cached clone of the repository and returns it with the commit it was read at
+func (f *Fetcher) fetchGit(inc dsl.Include, commit string) ([]byte, string, error) {
+	if commit != "" && !isCommit(commit) {
+		return nil, "", fmt.Errorf("%w: %q of %s is not a commit hash", ErrLockMismatch, commit, inc)
+	}
+	repo := filepath.Join(f.CacheDir, "git", digest([]byte(inc.Git))[:16])
+	if _, err := os.Stat(filepath.Join(repo, "HEAD")); err != nil {
+		if err := os.MkdirAll(repo, 0o755); err != nil {
+			return nil, "", err
+		}
+		if _, err := f.Git(repo, "init", "--bare", "--quiet"); err != nil {
+			return nil, "", err
+		}
+	}
+
+	switch {
+	case commit == "":
+		if _, err := f.Git(repo, "fetch", "--quiet", "--depth", "1", "--", inc.Git, inc.Ref); err != nil {
+			return nil, "", err
+		}
+		out, err := f.Git(repo, "rev-parse", "FETCH_HEAD")
+		if err != nil {
+			return nil, "", err
+		}
+		commit = strings.TrimSpace(string(out))
+	case !f.hasCommit(repo, commit):
+		// Not every server lets clients fetch a commit by its hash; the ref's history may contain it
+		if _, err := f.Git(repo, "fetch", "--quiet", "--depth", "1", "--", inc.Git, commit); err != nil {
+			if _, err := f.Git(repo, "fetch", "--quiet", "--", inc.Git, inc.Ref); err != nil {
+				return nil, "", err
+			}
+		}
+		if !f.hasCommit(repo, commit) {
+			return nil, "", fmt.Errorf("%w: commit %s of %s is gone", ErrLockMismatch, commit, inc.Git)
+		}
+	}
+
+	data, err := f.Git(repo, "show", commit+":"+filepath.ToSlash(inc.Path))
+	if err != nil {
+		return nil, "", err
+	}
+	return data, commit, nil
+}
+
+// isCommit reports whether s is a full SHA-1 or SHA-256 commit hash
+func isCommit(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+func (f *Fetcher) hasCommit(repo, commit string) bool {
+	_, err := f.Git(repo, "cat-file", "-e", commit+"^{commit}")
+	return err == nil
+}
+
+// ReadLock reads a lock file; a missing file, or an empty path, is an empty lock
+func ReadLock(path string) (*Lock, error) {
+	lock := &Lock{Includes: make(map[string]LockEntry)}
+	if path == "" {
+		return lock, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("lock file %s: %w", path, err)
+	}
+	if lock.Includes == nil {
+		lock.Includes = make(map[string]LockEntry)
+	}
+	return lock, nil
+}
+
+// Write saves the lock file
+func (l *Lock) Write(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
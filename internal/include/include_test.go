@@ -0,0 +1,164 @@
+package include
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+const fragment = "stages: []\n"
+
+// gitRepo creates a repository with fragment committed at lint.yaml and returns its path
+// and a function that commits new content
+func gitRepo(t *testing.T) (string, func(content string)) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=forge", "-c", "user.email=forge@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	commit := func(content string) {
+		if err := os.WriteFile(filepath.Join(dir, "lint.yaml"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", "lint.yaml")
+		git("commit", "--quiet", "-m", "update")
+	}
+	git("init", "--quiet", "--initial-branch", "main")
+	commit(fragment)
+	return dir, commit
+}
+
+func TestFetcher_Git(t *testing.T) {
+	repo, commit := gitRepo(t)
+	workflow := filepath.Join(t.TempDir(), "ci.yaml")
+	if err := os.WriteFile(workflow, []byte("name: ci\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFetcher()
+	f.CacheDir = t.TempDir()
+	inc := dsl.Include{Git: repo, Ref: "main", Path: "lint.yaml"}
+
+	data, err := f.Fetch(workflow, inc)
+	if err != nil || string(data) != fragment {
+		t.Fatalf("Fetch() = %q, %v", data, err)
+	}
+	lock, err := ReadLock(LockPath(workflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := lock.Includes[inc.String()]
+	if len(entry.Commit) != 40 || entry.SHA256 != digest([]byte(fragment)) {
+		t.Errorf("lock entry = %+v, want the resolved commit and digest", entry)
+	}
+
+	// The lock pins the commit even after the branch moved on
+	commit("stages: [changed]\n")
+	if data, err := f.Fetch(workflow, inc); err != nil || string(data) != fragment {
+		t.Errorf("Fetch() after the ref moved = %q, %v, want the locked content", data, err)
+	}
+
+	// A lock entry whose digest no longer matches is rejected
+	lock.Includes[inc.String()] = LockEntry{Commit: entry.Commit, SHA256: digest([]byte("other"))}
+	if err := lock.Write(LockPath(workflow)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Fetch(workflow, inc); !errors.Is(err, ErrLockMismatch) {
+		t.Errorf("Fetch() error = %v, want %v", err, ErrLockMismatch)
+	}
+
+	// Without a lock file the current commit of the ref is used
+	if err := os.Remove(LockPath(workflow)); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := f.Fetch(workflow, inc); err != nil || string(data) != "stages: [changed]\n" {
+		t.Errorf("Fetch() without a lock = %q, %v", data, err)
+	}
+
+	if _, err := f.Fetch(workflow, dsl.Include{Git: repo, Ref: "missing", Path: "lint.yaml"}); err == nil {
+		t.Error("Fetch() of a missing ref succeeded")
+	}
+
+	// A lock entry that is no commit hash is not passed to git
+	lock.Includes[inc.String()] = LockEntry{Commit: "--output=/tmp/x", SHA256: entry.SHA256}
+	if err := lock.Write(LockPath(workflow)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Fetch(workflow, inc); !errors.Is(err, ErrLockMismatch) {
+		t.Errorf("Fetch() with a tampered lock error = %v, want %v", err, ErrLockMismatch)
+	}
+}
+
+func TestFetcher_GitOptions(t *testing.T) {
+	f := NewFetcher()
+	f.CacheDir = t.TempDir()
+	f.Git = func(dir string, args ...string) ([]byte, error) {
+		t.Errorf("git %q ran", args)
+		return nil, nil
+	}
+	for _, inc := range []dsl.Include{
+		{Git: "--upload-pack=touch /tmp/pwned", Ref: "main", Path: "lint.yaml"},
+		{Git: "https://example.com/pipelines.git", Ref: "--upload-pack=id", Path: "lint.yaml"},
+	} {
+		if _, err := f.Fetch(filepath.Join(t.TempDir(), "ci.yaml"), inc); err == nil {
+			t.Errorf("Fetch(%s) succeeded", inc.String())
+		}
+	}
+}
+
+func TestFetcher_URL(t *testing.T) {
+	requests := 0
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/lint.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(fragment))
+	}))
+	defer srv.Close()
+
+	f := NewFetcher()
+	f.CacheDir = t.TempDir()
+	f.HTTPClient = srv.Client()
+	workflow := filepath.Join(t.TempDir(), "ci.yaml")
+	if err := os.WriteFile(workflow, []byte("name: ci\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	inc := dsl.Include{URL: srv.URL + "/lint.yaml", SHA256: digest([]byte(fragment))}
+	for range 2 {
+		if data, err := f.Fetch(workflow, inc); err != nil || string(data) != fragment {
+			t.Fatalf("Fetch() = %q, %v", data, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want the second fetch served from the cache", requests)
+	}
+	if lock, err := ReadLock(LockPath(workflow)); err != nil || lock.Includes[inc.URL].SHA256 != inc.SHA256 {
+		t.Errorf("lock = %+v, %v, want the URL's digest", lock, err)
+	}
+
+	bad := dsl.Include{URL: srv.URL + "/lint.yaml", SHA256: digest([]byte("other"))}
+	if _, err := f.Fetch(workflow, bad); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Fetch() error = %v, want a checksum mismatch", err)
+	}
+	if _, err := f.Fetch("-", dsl.Include{URL: srv.URL + "/missing.yaml", SHA256: bad.SHA256}); err == nil {
+		t.Error("Fetch() of a missing URL succeeded")
+	}
+}
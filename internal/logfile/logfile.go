@@ -0,0 +1,85 @@
+// Package logfile writes the output of runs to timestamped log files and removes old ones.
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// timeLayout is inserted into the log file name; names sort in the order they were created
+const timeLayout = "20060102-150405"
+
+// Retention limits the log files kept next to a new one; zero values keep everything
+type Retention struct {
+	// Keep is the number of log files kept, including the new one
+	Keep int
+	// MaxAge removes log files created longer ago
+	MaxAge time.Duration
+}
+
+// Name returns the log file of a run started at now: path with the time inserted before its
+// extension, e.g. logs/forge.log becomes logs/forge-20250102-150405.log
+func Name(path string, now time.Time) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + now.Format(timeLayout) + ext
+}
+
+// Create opens the log file of a run started at now for appending, creating its directory,
+// and removes the log files of path that fall outside the retention
+func Create(path string, now time.Time, keep Retention) (*os.File, error) {
+	name := Name(path, now)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := Prune(path, now, keep); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Prune removes the log files of path beyond keep.Keep, newest first, and those older than keep.MaxAge
+func Prune(path string, now time.Time, keep Retention) error {
+	ext := filepath.Ext(path)
+	dir, base := filepath.Split(strings.TrimSuffix(path, ext))
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return err
+	}
+
+	type logFile struct {
+		name    string
+		created time.Time
+	}
+	var files []logFile
+	for _, e := range entries {
+		stamp, ok := strings.CutPrefix(e.Name(), base+"-")
+		if !ok || e.IsDir() || !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		created, err := time.ParseInLocation(timeLayout, strings.TrimSuffix(stamp, ext), now.Location())
+		if err == nil {
+			files = append(files, logFile{name: filepath.Join(dir, e.Name()), created: created})
+		}
+	}
+	slices.SortFunc(files, func(a, b logFile) int { return strings.Compare(b.name, a.name) })
+
+	for i, f := range files {
+		tooMany := keep.Keep > 0 && i >= keep.Keep
+		tooOld := keep.MaxAge > 0 && now.Sub(f.created) > keep.MaxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(f.name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
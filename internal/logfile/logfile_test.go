@@ -0,0 +1,89 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestName(t *testing.T) {
+	now := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "logs/forge.log", want: "logs/forge-20250102-150405.log"},
+		{path: "run", want: "run-20250102-150405"},
+	}
+	for _, tt := range tests {
+		if got := Name(tt.path, now); got != tt.want {
+			t.Errorf("Name(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	old := []time.Time{
+		now.Add(-time.Hour),
+		now.Add(-2 * time.Hour),
+		now.Add(-48 * time.Hour),
+		now.Add(-72 * time.Hour),
+	}
+
+	tests := []struct {
+		name string
+		keep Retention
+		want []time.Time
+	}{
+		{name: "keep everything", want: append([]time.Time{now}, old...)},
+		{name: "keep count", keep: Retention{Keep: 2}, want: []time.Time{now, old[0]}},
+		{name: "max age", keep: Retention{MaxAge: 24 * time.Hour}, want: []time.Time{now, old[0], old[1]}},
+		{name: "both", keep: Retention{Keep: 2, MaxAge: 90 * time.Minute}, want: []time.Time{now, old[0]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "logs", "forge.log")
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			for _, created := range old {
+				if err := os.WriteFile(Name(path, created), nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			unrelated := filepath.Join(dir, "logs", "forge-notes.log")
+			if err := os.WriteFile(unrelated, nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := Create(path, now, tt.keep)
+			if err != nil {
+				t.Fatalf("Create() error: %v", err)
+			}
+			f.Close()
+
+			var want []string
+			for _, created := range tt.want {
+				want = append(want, filepath.Base(Name(path, created)))
+			}
+			want = append(want, filepath.Base(unrelated))
+			entries, err := os.ReadDir(filepath.Dir(path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Name())
+			}
+			slices.Sort(got)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("log files = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+// Package matcher extracts structured findings (errors and warnings) from tool output.
+package matcher
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNotice  Severity = "notice"
+)
+
+// Definition describes a problem matcher: a regular expression with the named groups
+// file, line, column, severity and message. Severity is used when the pattern has no
+// severity group or it does not match a known severity.
+type Definition struct {
+	Name     string   `yaml:"name" json:"name"`
+	Pattern  string   `yaml:"pattern" json:"pattern"`
+	Severity Severity `yaml:"severity,omitempty" json:"severity,omitempty"`
+}
+
+// Builtins are matchers available without a definition in the workflow
+var Builtins = map[string]Definition{
+	"go": {
+		Name:     "go",
+		Pattern:  `^\s*(?:vet: )?(?P<file>[^\s:]+\.go):(?P<line>\d+)(?::(?P<column>\d+))?:\s+(?P<message>.+)$`,
+		Severity: SeverityError,
+	},
+	"eslint": {
+		// eslint --format unix
+		Name:     "eslint",
+		Pattern:  `^(?P<file>[^\s:]+):(?P<line>\d+):(?P<column>\d+):\s+(?P<message>.+?)\s+\[(?P<severity>Error|Warning)(?:/[^\]]+)?\]$`,
+		Severity: SeverityError,
+	},
+	"tsc": {
+		Name:     "tsc",
+		Pattern:  `^(?P<file>[^\s(]+)\((?P<line>\d+),(?P<column>\d+)\):\s+(?P<severity>error|warning)\s+(?P<message>TS\d+:.+)$`,
+		Severity: SeverityError,
+	},
+}
+
+// Finding is a single problem extracted from step output
+type Finding struct {
+	Matcher  string   `json:"matcher"`
+	File     string   `json:"file,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (f Finding) String() string {
+	loc := f.File
+	if f.Line > 0 {
+		loc += ":" + strconv.Itoa(f.Line)
+		if f.Column > 0 {
+			loc += ":" + strconv.Itoa(f.Column)
+		}
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", loc, f.Severity, f.Message, f.Matcher)
+}
+
+// Annotation formats the finding as a GitHub Actions workflow command
+func (f Finding) Annotation() string {
+	kind := string(f.Severity)
+	var props []string
+	if f.File != "" {
+		props = append(props, "file="+f.File)
+	}
+	if f.Line > 0 {
+		props = append(props, "line="+strconv.Itoa(f.Line))
+	}
+	if f.Column > 0 {
+		props = append(props, "col="+strconv.Itoa(f.Column))
+	}
+	msg := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(f.Message)
+	if len(props) == 0 {
+		return fmt.Sprintf("::%s::%s", kind, msg)
+	}
+	return fmt.Sprintf("::%s %s::%s", kind, strings.Join(props, ","), msg)
+}
+
+// Matcher is a compiled Definition
+type Matcher struct {
+	def Definition
+	re  *regexp.Regexp
+}
+
+// Compile validates a definition and compiles its pattern
+func Compile(def Definition) (*Matcher, error) {
+	if def.Name == "" {
+		return nil, fmt.Errorf("problem matcher name is required")
+	}
+	re, err := regexp.Compile(def.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("problem matcher %s: invalid pattern: %w", def.Name, err)
+	}
+	if re.SubexpIndex("message") < 0 {
+		return nil, fmt.Errorf("problem matcher %s: pattern requires a named group 'message'", def.Name)
+	}
+	switch def.Severity {
+	case "":
+		def.Severity = SeverityError
+	case SeverityError, SeverityWarning, SeverityNotice:
+	default:
+		return nil, fmt.Errorf("problem matcher %s: unknown severity %q", def.Name, def.Severity)
+	}
+	return &Matcher{def: def, re: re}, nil
+}
+
+// Resolve compiles the named matchers, looking them up in custom definitions first and then in Builtins
+func Resolve(names []string, custom []Definition) ([]*Matcher, error) {
+	byName := make(map[string]Definition, len(custom))
+	for _, d := range custom {
+		byName[d.Name] = d
+	}
+
+	matchers := make([]*Matcher, 0, len(names))
+	for _, name := range names {
+		def, ok := byName[name]
+		if !ok {
+			def, ok = Builtins[name]
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown problem matcher %q (built-in: %s)", name, strings.Join(BuiltinNames(), ", "))
+		}
+		m, err := Compile(def)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+// BuiltinNames returns the names of all built-in matchers, sorted
+func BuiltinNames() []string {
+	names := make([]string, 0, len(Builtins))
+	for name := range Builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Match applies the matcher to a single line of output
+func (m *Matcher) Match(line string) (Finding, bool) {
+	sub := m.re.FindStringSubmatch(line)
+	if sub == nil {
+		return Finding{}, false
+	}
+
+	group := func(name string) string {
+		if i := m.re.SubexpIndex(name); i >= 0 {
+			return sub[i]
+		}
+		return ""
+	}
+
+	f := Finding{
+		Matcher:  m.def.Name,
+		File:     group("file"),
+		Severity: m.def.Severity,
+		Message:  strings.TrimSpace(group("message")),
+	}
+	f.Line, _ = strconv.Atoi(group("line"))
+	f.Column, _ = strconv.Atoi(group("column"))
+	switch s := Severity(strings.ToLower(group("severity"))); s {
+	case SeverityError, SeverityWarning, SeverityNotice:
+		f.Severity = s
+	}
+	return f, true
+}
+
+// Writer scans everything written to it line by line and collects findings.
+// The first matcher that matches a line wins.
+type Writer struct {
+	matchers []*Matcher
+
+	mu       sync.Mutex
+	partial  []byte
+	findings []Finding
+}
+
+func NewWriter(matchers []*Matcher) *Writer {
+	return &Writer{matchers: matchers}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.scan(string(bytes.TrimRight(w.partial[:i], "\r")))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Findings flushes a trailing unterminated line and returns all findings so far
+func (w *Writer) Findings() []Finding {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) > 0 {
+		w.scan(string(w.partial))
+		w.partial = nil
+	}
+	return w.findings
+}
+
+func (w *Writer) scan(line string) {
+	for _, m := range w.matchers {
+		if f, ok := m.Match(line); ok {
+			w.findings = append(w.findings, f)
+			return
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package matcher
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBuiltins(t *testing.T) {
+	tests := []struct {
+		matcher string
+		line    string
+		want    Finding
+		noMatch bool
+	}{
+		{
+			matcher: "go",
+			line:    "./main.go:12:5: undefined: foo",
+			want:    Finding{Matcher: "go", File: "./main.go", Line: 12, Column: 5, Severity: SeverityError, Message: "undefined: foo"},
+		},
+		{
+			matcher: "go",
+			line:    "    runner_test.go:42: expected 2, got 3",
+			want:    Finding{Matcher: "go", File: "runner_test.go", Line: 42, Severity: SeverityError, Message: "expected 2, got 3"},
+		},
+		{
+			matcher: "eslint",
+			line:    "src/app.js:3:10: 'x' is defined but never used. [Warning/no-unused-vars]",
+			want:    Finding{Matcher: "eslint", File: "src/app.js", Line: 3, Column: 10, Severity: SeverityWarning, Message: "'x' is defined but never used."},
+		},
+		{
+			matcher: "tsc",
+			line:    "src/index.ts(4,7): error TS2322: Type 'string' is not assignable to type 'number'.",
+			want:    Finding{Matcher: "tsc", File: "src/index.ts", Line: 4, Column: 7, Severity: SeverityError, Message: "TS2322: Type 'string' is not assignable to type 'number'."},
+		},
+		{matcher: "go", line: "ok  	github.com/andre-koe/forge/cmd	0.024s", noMatch: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.matcher+"/"+tt.line, func(t *testing.T) {
+			m, err := Compile(Builtins[tt.matcher])
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, ok := m.Match(tt.line)
+			if ok == tt.noMatch {
+				t.Fatalf("Match() ok = %v, want %v", ok, !tt.noMatch)
+			}
+			if !tt.noMatch && got != tt.want {
+				t.Errorf("Match() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		def  Definition
+	}{
+		{name: "missing name", def: Definition{Pattern: `(?P<message>.*)`}},
+		{name: "invalid regex", def: Definition{Name: "x", Pattern: `(`}},
+		{name: "missing message group", def: Definition{Name: "x", Pattern: `.*`}},
+		{name: "unknown severity", def: Definition{Name: "x", Pattern: `(?P<message>.*)`, Severity: "fatal"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile(tt.def); err == nil {
+				t.Error("Compile() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	custom := []Definition{{Name: "go", Pattern: `^CUSTOM (?P<message>.*)$`, Severity: SeverityNotice}}
+
+	matchers, err := Resolve([]string{"go", "tsc"}, custom)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if f, ok := matchers[0].Match("CUSTOM hello"); !ok || f.Severity != SeverityNotice {
+		t.Errorf("custom definitions should shadow built-ins, got %+v, %v", f, ok)
+	}
+
+	if _, err := Resolve([]string{"nope"}, nil); err == nil {
+		t.Error("Resolve() expected error for unknown matcher")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	m, _ := Compile(Builtins["go"])
+	w := NewWriter([]*Matcher{m})
+
+	fmt.Fprint(w, "# pkg\nmain.go:1:2: first\r\nnoise\nmain")
+	fmt.Fprint(w, ".go:3:4: second")
+
+	findings := w.Findings()
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %+v", findings)
+	}
+	if findings[0].Message != "first" || findings[1].Line != 3 {
+		t.Errorf("unexpected findings %+v", findings)
+	}
+}
+
+func TestFinding_Annotation(t *testing.T) {
+	tests := []struct {
+		f    Finding
+		want string
+	}{
+		{
+			f:    Finding{File: "a.go", Line: 1, Column: 2, Severity: SeverityError, Message: "bad\nthing"},
+			want: "::error file=a.go,line=1,col=2::bad%0Athing",
+		},
+		{
+			f:    Finding{Severity: SeverityWarning, Message: "100% odd"},
+			want: "::warning::100%25 odd",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.f.Annotation(); got != tt.want {
+			t.Errorf("Annotation() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Package metrics collects run metrics and exposes them in the Prometheus text format,
+// either served on /metrics by long-lived modes or pushed to a Pushgateway after one-shot runs.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andre-koe/forge/internal/history"
+)
+
+// ContentType is the media type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// durationBuckets are the histogram upper bounds in seconds, sized for build and deploy steps
+var durationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}
+
+// Collector aggregates metrics over the runs it observes; it is safe for concurrent use
+type Collector struct {
+	mu            sync.Mutex
+	runs          *family
+	runDuration   *family
+	stepDuration  *family
+	stageFailures *family
+	activeRuns    *family
+}
+
+// New returns an empty collector
+func New() *Collector {
+	c := &Collector{
+		runs:          newFamily("forge_runs_total", "Completed workflow runs.", "counter", nil, "workflow", "status"),
+		runDuration:   newFamily("forge_run_duration_seconds", "Duration of completed workflow runs.", "histogram", durationBuckets, "workflow"),
+		stepDuration:  newFamily("forge_step_duration_seconds", "Duration of executed steps.", "histogram", durationBuckets, "workflow", "stage", "step"),
+		stageFailures: newFamily("forge_stage_failures_total", "Failed steps per stage.", "counter", nil, "workflow", "stage"),
+		activeRuns:    newFamily("forge_active_runs", "Workflow runs in progress.", "gauge", nil),
+	}
+	c.activeRuns.get() // report 0 before the first run
+	return c
+}
+
+// Observe records a completed run
+func (c *Collector) Observe(rec *history.Record) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.runs.get(rec.Name, string(rec.Status)).add(1)
+	c.runDuration.get(rec.Name).observe(rec.FinishedAt.Sub(rec.StartedAt).Seconds())
+	for _, step := range rec.Steps {
+		if step.Status == history.StatusSkipped || step.Status == history.StatusCached {
+			continue
+		}
+		c.stepDuration.get(rec.Name, step.Stage, step.Step).observe(step.Duration.Seconds())
+		if step.Status == history.StatusFailed && step.Group == "" {
+			c.stageFailures.get(rec.Name, step.Stage).add(1)
+		}
+	}
+}
+
+// RunStarted counts a run as active until RunEnded is called
+func (c *Collector) RunStarted() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.activeRuns.get().add(1)
+}
+
+// RunEnded removes a run started with RunStarted from the active runs
+func (c *Collector) RunEnded() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.activeRuns.get().add(-1)
+}
+
+// Write writes all metrics in the Prometheus text exposition format
+func (c *Collector) Write(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var b bytes.Buffer
+	for _, f := range []*family{c.runs, c.runDuration, c.stepDuration, c.stageFailures, c.activeRuns} {
+		f.write(&b)
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// ServeHTTP serves the metrics, typically on /metrics
+func (c *Collector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_ = c.Write(w)
+}
+
+// Push replaces the metrics of the group identified by job and labels on the Pushgateway at gatewayURL
+func (c *Collector) Push(client *http.Client, gatewayURL, job string, labels map[string]string) error {
+	target := strings.TrimSuffix(gatewayURL, "/") + "/metrics/job/" + url.PathEscape(job)
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		target += "/" + url.PathEscape(name) + "/" + url.PathEscape(labels[name])
+	}
+
+	var body bytes.Buffer
+	if err := c.Write(&body); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, target, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("pushgateway returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// family is a metric with all its labelled series
+type family struct {
+	name, help, kind string
+	labels           []string
+	buckets          []float64
+	series           map[string]*series
+}
+
+// series holds the value of one label combination; histograms use counts, sum and count
+type series struct {
+	labelValues []string
+	value       float64
+	buckets     []float64
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+func newFamily(name, help, kind string, buckets []float64, labels ...string) *family {
+	return &family{name: name, help: help, kind: kind, labels: labels, buckets: buckets, series: make(map[string]*series)}
+}
+
+func (f *family) get(labelValues ...string) *series {
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: labelValues, buckets: f.buckets, counts: make([]uint64, len(f.buckets))}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (s *series) add(v float64) {
+	s.value += v
+}
+
+// observe adds v to a histogram series; bucket counts are cumulative
+func (s *series) observe(v float64) {
+	s.sum += v
+	s.count++
+	for i, le := range s.buckets {
+		if v <= le {
+			s.counts[i]++
+		}
+	}
+}
+
+func (f *family) write(b *bytes.Buffer) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+	for _, key := range slices.Sorted(maps.Keys(f.series)) {
+		s := f.series[key]
+		labels := f.labelPairs(s.labelValues)
+		if f.kind != "histogram" {
+			fmt.Fprintf(b, "%s%s %s\n", f.name, braces(labels), formatFloat(s.value))
+			continue
+		}
+		for i, le := range f.buckets {
+			fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, braces(append(slices.Clone(labels), `le="`+formatFloat(le)+`"`)), s.counts[i])
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, braces(append(slices.Clone(labels), `le="+Inf"`)), s.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", f.name, braces(labels), formatFloat(s.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", f.name, braces(labels), s.count)
+	}
+}
+
+func (f *family) labelPairs(values []string) []string {
+	pairs := make([]string, len(values))
+	for i, v := range values {
+		pairs[i] = f.labels[i] + `="` + escapeLabel(v) + `"`
+	}
+	return pairs
+}
+
+func braces(pairs []string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
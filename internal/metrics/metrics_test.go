@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func testRecord(status history.Status) *history.Record {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	rec := &history.Record{
+		Name:       "ci",
+		StartedAt:  start,
+		FinishedAt: start.Add(20 * time.Second),
+		Status:     status,
+		Steps: []history.StepRecord{
+			{Stage: "build", Step: "compile", Status: history.StatusSuccess, Duration: 12 * time.Second},
+			{Stage: "test", Step: "unit", Status: status, Duration: 8 * time.Second},
+			{Stage: "deploy", Step: "push", Status: history.StatusSkipped},
+		},
+	}
+	return rec
+}
+
+func TestCollector_Write(t *testing.T) {
+	c := New()
+	c.Observe(testRecord(history.StatusSuccess))
+	c.Observe(testRecord(history.StatusFailed))
+	c.RunStarted()
+
+	var out strings.Builder
+	if err := c.Write(&out); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	text := out.String()
+
+	for _, want := range []string{
+		"# TYPE forge_runs_total counter\n",
+		`forge_runs_total{workflow="ci",status="failed"} 1` + "\n",
+		`forge_runs_total{workflow="ci",status="success"} 1` + "\n",
+		`forge_run_duration_seconds_bucket{workflow="ci",le="15"} 0` + "\n",
+		`forge_run_duration_seconds_bucket{workflow="ci",le="30"} 2` + "\n",
+		`forge_run_duration_seconds_bucket{workflow="ci",le="+Inf"} 2` + "\n",
+		`forge_run_duration_seconds_sum{workflow="ci"} 40` + "\n",
+		`forge_step_duration_seconds_count{workflow="ci",stage="build",step="compile"} 2` + "\n",
+		`forge_stage_failures_total{workflow="ci",stage="test"} 1` + "\n",
+		"forge_active_runs 1\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("exposition missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, `step="push"`) {
+		t.Error("skipped steps must not be observed")
+	}
+
+	c.RunEnded()
+	out.Reset()
+	_ = c.Write(&out)
+	if !strings.Contains(out.String(), "forge_active_runs 0\n") {
+		t.Errorf("active runs not decremented:\n%s", out.String())
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if got, want := escapeLabel("a\"b\\c\nd"), `a\"b\\c\nd`; got != want {
+		t.Errorf("escapeLabel() = %q, want %q", got, want)
+	}
+}
+
+func TestCollector_ServeHTTP(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, ContentType)
+	}
+	if !strings.Contains(rec.Body.String(), "forge_active_runs 0") {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+}
+
+func TestCollector_Push(t *testing.T) {
+	var gotMethod, gotPath, gotBody string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.EscapedPath()
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	c := New()
+	c.Observe(testRecord(history.StatusSuccess))
+	if err := c.Push(srv.Client(), srv.URL+"/", "forge", map[string]string{"workflow": "ci nightly"}); err != nil {
+		t.Fatalf("Push() error: %v", err)
+	}
+	if gotMethod != http.MethodPut || gotPath != "/metrics/job/forge/workflow/ci%20nightly" {
+		t.Errorf("Push() sent %s %s", gotMethod, gotPath)
+	}
+	if !strings.Contains(gotBody, "forge_runs_total") {
+		t.Errorf("Push() body = %q", gotBody)
+	}
+
+	status = http.StatusBadRequest
+	if err := c.Push(srv.Client(), srv.URL, "forge", nil); err == nil {
+		t.Error("Push() ignored an error status")
+	}
+}
@@ -0,0 +1,172 @@
+// Package notify delivers run lifecycle events to external services such as webhooks.
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Event names
+const (
+	EventRunStarted  = "run_started"
+	EventStepFailed  = "step_failed"
+	EventRunFinished = "run_finished"
+)
+
+// Events lists the event names a webhook can subscribe to
+var Events = []string{EventRunStarted, EventStepFailed, EventRunFinished}
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body as "sha256=<hex>" when a secret is set
+const SignatureHeader = "X-Forge-Signature-256"
+
+// DefaultRetries is the number of retries for a webhook without an explicit retries setting
+const DefaultRetries = 3
+
+// Webhook is a URL that receives events as JSON POST requests
+type Webhook struct {
+	URL string `yaml:"url" json:"url"`
+	// Events limits the delivered events; all events are delivered when empty
+	Events []string `yaml:"events,omitempty" json:"events,omitempty"`
+	// Secret signs each request body with HMAC-SHA256
+	Secret string `yaml:"secret,omitempty" json:"secret,omitempty"`
+	// Retries is the number of additional attempts after a failed delivery
+	Retries *int `yaml:"retries,omitempty" json:"retries,omitempty"`
+}
+
+// Validate checks the webhook definition
+func (h *Webhook) Validate() error {
+	if h.URL == "" {
+		return errors.New("webhook url is required")
+	}
+	for _, e := range h.Events {
+		if !slices.Contains(Events, e) {
+			return fmt.Errorf("unknown webhook event %q, use one of %s", e, strings.Join(Events, ", "))
+		}
+	}
+	if h.Retries != nil && *h.Retries < 0 {
+		return errors.New("webhook retries must not be negative")
+	}
+	return nil
+}
+
+// Wants reports whether the webhook subscribes to the named event
+func (h *Webhook) Wants(event string) bool {
+	return len(h.Events) == 0 || slices.Contains(h.Events, event)
+}
+
+// Event is the JSON payload delivered for a lifecycle change of a run
+type Event struct {
+	Event    string    `json:"event"`
+	RunID    string    `json:"run_id"`
+	Workflow string    `json:"workflow"`
+	Time     time.Time `json:"time"`
+	Status   string    `json:"status,omitempty"`
+	Stage    string    `json:"stage,omitempty"`
+	Step     string    `json:"step,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	// Duration is set for run_finished
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// Sender posts events to webhooks, retrying failed deliveries with exponential backoff
+type Sender struct {
+	Client *http.Client
+	Sleep  func(time.Duration)
+	// Backoff is the delay before the first retry; it doubles for every further retry
+	Backoff time.Duration
+	// SlackAPI overrides SlackAPIURL
+	SlackAPI string
+}
+
+// Send delivers ev to the webhook unless it does not subscribe to the event
+func (s *Sender) Send(h Webhook, ev Event) error {
+	if !h.Wants(ev.Event) {
+		return nil
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	if err := s.retry(h.Retries, func() error { return s.post(h, body) }); err != nil {
+		return fmt.Errorf("webhook %s: %w", redact(h.URL), err)
+	}
+	return nil
+}
+
+// retry calls fn until it succeeds or the retries (DefaultRetries when nil) are used up
+func (s *Sender) retry(retries *int, fn func() error) error {
+	n := DefaultRetries
+	if retries != nil {
+		n = *retries
+	}
+	delay := s.Backoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= n {
+			return err
+		}
+		s.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (s *Sender) post(h Webhook, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "forge")
+	if h.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(h.Secret, body))
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return withoutURL(err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the signature header value for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// withoutURL drops the request URL from client errors so tokens in it are not printed
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// redact strips the path and query of a URL for messages, since webhook URLs often embed tokens
+func redact(rawURL string) string {
+	scheme, rest, ok := strings.Cut(rawURL, "://")
+	if !ok {
+		return "<invalid url>"
+	}
+	host, _, _ := strings.Cut(rest, "/")
+	return scheme + "://" + host
+}
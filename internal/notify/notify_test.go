@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func intPtr(i int) *int { return &i }
+
+func TestWebhook_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    Webhook
+		wantErr bool
+	}{
+		{name: "url only", hook: Webhook{URL: "https://example.com/hook"}},
+		{name: "events and retries", hook: Webhook{URL: "https://example.com/hook", Events: []string{EventStepFailed}, Retries: intPtr(0)}},
+		{name: "missing url", hook: Webhook{}, wantErr: true},
+		{name: "unknown event", hook: Webhook{URL: "https://example.com/hook", Events: []string{"step_started"}}, wantErr: true},
+		{name: "negative retries", hook: Webhook{URL: "https://example.com/hook", Retries: intPtr(-1)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.hook.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSender_Send(t *testing.T) {
+	var attempts int
+	var got Event
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		if Sign("s3cret", body) != signature {
+			t.Errorf("signature %q does not match the body", signature)
+		}
+		_ = json.Unmarshal(body, &got)
+	}))
+	defer srv.Close()
+
+	var sleeps []time.Duration
+	s := &Sender{Client: srv.Client(), Sleep: func(d time.Duration) { sleeps = append(sleeps, d) }, Backoff: time.Second}
+	ev := Event{Event: EventStepFailed, RunID: "r1", Workflow: "ci", Stage: "test", Step: "unit", Error: "exit status 1"}
+
+	if err := s.Send(Webhook{URL: srv.URL, Secret: "s3cret"}, ev); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if attempts != 3 || got.Step != "unit" || got.Event != EventStepFailed {
+		t.Errorf("attempts = %d, event = %+v", attempts, got)
+	}
+	if len(sleeps) != 2 || sleeps[0] != time.Second || sleeps[1] != 2*time.Second {
+		t.Errorf("backoff = %v, want [1s 2s]", sleeps)
+	}
+
+	attempts = 0
+	err := s.Send(Webhook{URL: srv.URL + "/token123", Retries: intPtr(1)}, ev)
+	if err == nil || attempts != 2 {
+		t.Fatalf("Send() with one retry: err = %v, attempts = %d", err, attempts)
+	}
+	if strings.Contains(err.Error(), "token123") {
+		t.Errorf("error %q leaks the webhook path", err)
+	}
+
+	attempts = 0
+	if err := s.Send(Webhook{URL: srv.URL, Events: []string{EventRunFinished}}, ev); err != nil || attempts != 0 {
+		t.Errorf("Send() of an unsubscribed event: err = %v, attempts = %d", err, attempts)
+	}
+}
@@ -0,0 +1,169 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// SlackAPIURL is the Slack Web API method used with bot tokens
+const SlackAPIURL = "https://slack.com/api/chat.postMessage"
+
+// Run outcomes a Slack notification can be limited to
+const (
+	OnSuccess = "success"
+	OnFailure = "failure"
+)
+
+// DefaultSlackMessage is the message template used when a Slack notification has no message
+const DefaultSlackMessage = `{{if eq .Status "failed"}}:x: *{{.Workflow}}* failed after {{.Duration}}` +
+	`{{if .Step}} in {{.Stage}}/{{.Step}}{{end}}{{if .Error}}: {{.Error}}{{end}}` +
+	`{{else}}:white_check_mark: *{{.Workflow}}* {{.Status}} in {{.Duration}}{{end}} (run {{.RunID}})`
+
+// Slack posts a message to Slack through an incoming webhook or, with a bot token, to a channel
+type Slack struct {
+	// WebhookURL is an incoming webhook URL; use ${{ env.NAME }} to keep it out of the workflow
+	WebhookURL string `yaml:"webhook_url,omitempty" json:"webhook_url,omitempty"`
+	// Token is a bot token used with Channel instead of a webhook
+	Token   string `yaml:"token,omitempty" json:"token,omitempty"`
+	Channel string `yaml:"channel,omitempty" json:"channel,omitempty"`
+	// On limits a notifications block to runs that end in success or failure; both when empty
+	On []string `yaml:"on,omitempty" json:"on,omitempty"`
+	// Message is a text/template rendered with a Summary; DefaultSlackMessage when empty
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+	Retries *int   `yaml:"retries,omitempty" json:"retries,omitempty"`
+}
+
+// Summary describes the state of a run for notification messages
+type Summary struct {
+	Workflow string
+	RunID    string
+	// Status is success, failed or running
+	Status   string
+	Duration time.Duration
+	// Stage, Step and Error describe the first failed step, if any
+	Stage string
+	Step  string
+	Error string
+}
+
+// Validate checks the Slack definition
+func (s *Slack) Validate() error {
+	switch {
+	case s.WebhookURL == "" && s.Token == "":
+		return errors.New("slack needs webhook_url or token")
+	case s.WebhookURL != "" && s.Token != "":
+		return errors.New("slack takes either webhook_url or token, not both")
+	case s.Token != "" && s.Channel == "":
+		return errors.New("slack token requires a channel")
+	}
+	for _, on := range s.On {
+		if on != OnSuccess && on != OnFailure {
+			return fmt.Errorf("unknown slack trigger %q, use %s or %s", on, OnSuccess, OnFailure)
+		}
+	}
+	if s.Retries != nil && *s.Retries < 0 {
+		return errors.New("slack retries must not be negative")
+	}
+	_, err := s.template()
+	return err
+}
+
+// Wants reports whether a notifications block fires for a run that ended with the given status
+func (s *Slack) Wants(status string) bool {
+	on := OnSuccess
+	if status == "failed" {
+		on = OnFailure
+	}
+	return len(s.On) == 0 || slices.Contains(s.On, on)
+}
+
+func (s *Slack) template() (*template.Template, error) {
+	text := s.Message
+	if text == "" {
+		text = DefaultSlackMessage
+	}
+	tmpl, err := template.New("slack").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("slack message: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Render returns the message for the summary
+func (s *Slack) Render(sum Summary) (string, error) {
+	tmpl, err := s.template()
+	if err != nil {
+		return "", err
+	}
+	sum.Duration = sum.Duration.Round(time.Millisecond)
+	var b strings.Builder
+	if err := tmpl.Execute(&b, sum); err != nil {
+		return "", fmt.Errorf("slack message: %w", err)
+	}
+	return b.String(), nil
+}
+
+// SendSlack renders the message for sum and posts it
+func (s *Sender) SendSlack(sl Slack, sum Summary) error {
+	text, err := sl.Render(sum)
+	if err != nil {
+		return err
+	}
+	return s.retry(sl.Retries, func() error { return s.postSlack(sl, text) })
+}
+
+func (s *Sender) postSlack(sl Slack, text string) error {
+	target := sl.WebhookURL
+	payload := map[string]string{"text": text}
+	if sl.Token != "" {
+		target = s.SlackAPI
+		if target == "" {
+			target = SlackAPIURL
+		}
+		payload["channel"] = sl.Channel
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("slack: %w", withoutURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if sl.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+sl.Token)
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack: %w", withoutURL(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("slack: unexpected status %s", resp.Status)
+	}
+	if sl.Token == "" {
+		return nil
+	}
+
+	// The Web API reports errors in the body of a 200 response
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("slack: %w", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("slack: %s", result.Error)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlack_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		slack   Slack
+		wantErr bool
+	}{
+		{name: "webhook", slack: Slack{WebhookURL: "https://hooks.slack.com/services/x", On: []string{OnFailure}}},
+		{name: "token and channel", slack: Slack{Token: "xoxb-1", Channel: "#ci"}},
+		{name: "neither", slack: Slack{}, wantErr: true},
+		{name: "both", slack: Slack{WebhookURL: "https://x", Token: "xoxb-1", Channel: "#ci"}, wantErr: true},
+		{name: "token without channel", slack: Slack{Token: "xoxb-1"}, wantErr: true},
+		{name: "unknown trigger", slack: Slack{WebhookURL: "https://x", On: []string{"always"}}, wantErr: true},
+		{name: "invalid template", slack: Slack{WebhookURL: "https://x", Message: "{{.Workflow"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.slack.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSlack_Render(t *testing.T) {
+	failed := Summary{Workflow: "ci", RunID: "r1", Status: "failed", Duration: 1234567 * time.Microsecond, Stage: "test", Step: "unit", Error: "exit status 1"}
+	tests := []struct {
+		name    string
+		message string
+		sum     Summary
+		want    string
+	}{
+		{
+			name: "default failure",
+			sum:  failed,
+			want: ":x: *ci* failed after 1.235s in test/unit: exit status 1 (run r1)",
+		},
+		{
+			name: "default success",
+			sum:  Summary{Workflow: "ci", RunID: "r2", Status: "success", Duration: 2 * time.Second},
+			want: ":white_check_mark: *ci* success in 2s (run r2)",
+		},
+		{
+			name:    "custom",
+			message: "{{.Workflow}} {{.Status}} at {{.Step}}",
+			sum:     failed,
+			want:    "ci failed at unit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&Slack{Message: tt.message}).Render(tt.sum)
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSender_SendSlack(t *testing.T) {
+	var payload map[string]string
+	var auth string
+	ok := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		if r.URL.Path == "/api" {
+			if ok {
+				_, _ = w.Write([]byte(`{"ok":true}`))
+			} else {
+				_, _ = w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+			}
+		}
+	}))
+	defer srv.Close()
+
+	s := &Sender{Client: srv.Client(), Sleep: func(time.Duration) {}, SlackAPI: srv.URL + "/api"}
+	sum := Summary{Workflow: "ci", RunID: "r1", Status: "success"}
+
+	if err := s.SendSlack(Slack{WebhookURL: srv.URL + "/hook", Message: "hello {{.Workflow}}"}, sum); err != nil {
+		t.Fatalf("SendSlack(webhook) error: %v", err)
+	}
+	if payload["text"] != "hello ci" || auth != "" {
+		t.Errorf("webhook payload = %v, auth = %q", payload, auth)
+	}
+
+	if err := s.SendSlack(Slack{Token: "xoxb-1", Channel: "#ci"}, sum); err != nil {
+		t.Fatalf("SendSlack(token) error: %v", err)
+	}
+	if payload["channel"] != "#ci" || auth != "Bearer xoxb-1" {
+		t.Errorf("API payload = %v, auth = %q", payload, auth)
+	}
+
+	ok = false
+	retries := 0
+	err := s.SendSlack(Slack{Token: "xoxb-1", Channel: "#nope", Retries: &retries}, sum)
+	if err == nil || !strings.Contains(err.Error(), "channel_not_found") {
+		t.Errorf("SendSlack() error = %v, want the API error", err)
+	}
+}
@@ -0,0 +1,148 @@
+// Package policy admits or denies workflows with Open Policy Agent Rego policies, evaluated
+// by the opa binary before a run starts.
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultQuery collects the deny messages of policies in package forge, e.g.
+//
+//	package forge
+//
+//	deny contains msg if {
+//		some stage in input.workflow.stages
+//		startswith(stage.name, "deploy")
+//		not stage.on_failure
+//		msg := sprintf("stage %s must have an on_failure hook", [stage.name])
+//	}
+const DefaultQuery = "data.forge.deny"
+
+// ErrDenied is returned when a policy denies the workflow
+var ErrDenied = errors.New("workflow denied by policy")
+
+// Input is the document policies see as input
+type Input struct {
+	// Workflow is the workflow after includes, in its JSON form
+	Workflow any `json:"workflow"`
+	// Vars holds the workflow variables after overrides
+	Vars map[string]string `json:"vars,omitempty"`
+	// Stages holds the stages selected to run, all when empty
+	Stages []string `json:"stages,omitempty"`
+}
+
+// Engine evaluates policies with opa eval
+type Engine struct {
+	// Binary is the opa executable
+	Binary string
+	// Query collects the deny messages, DefaultQuery when empty
+	Query string
+	// Output runs a command with stdin and returns its stdout
+	Output func(argv []string, stdin []byte) ([]byte, error)
+}
+
+// NewEngine returns an engine running opa from PATH
+func NewEngine() *Engine {
+	return &Engine{Binary: "opa", Query: DefaultQuery, Output: output}
+}
+
+func output(argv []string, stdin []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return out, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return out, err
+}
+
+// evalResult is the JSON output of opa eval
+type evalResult struct {
+	Result []struct {
+		Expressions []struct {
+			Value any `json:"value"`
+		} `json:"expressions"`
+	} `json:"result"`
+}
+
+// Deny evaluates the policy files or directories against input and returns the deny messages
+func (e *Engine) Deny(policies []string, input Input) ([]string, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	argv := []string{e.Binary, "eval", "--format", "json", "--stdin-input"}
+	for _, p := range policies {
+		argv = append(argv, "--data", p)
+	}
+	query := e.Query
+	if query == "" {
+		query = DefaultQuery
+	}
+	out, err := e.Output(append(argv, query), data)
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, fmt.Errorf("policies need the opa binary on PATH: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opa eval: %w", err)
+	}
+
+	var res evalResult
+	if err := json.Unmarshal(out, &res); err != nil {
+		return nil, fmt.Errorf("opa eval: %w", err)
+	}
+	var msgs []string
+	for _, r := range res.Result {
+		for _, expr := range r.Expressions {
+			msgs = append(msgs, messages(expr.Value)...)
+		}
+	}
+	return msgs, nil
+}
+
+// messages turns a deny value, a set of strings or a single one, into messages; other values
+// are shown as JSON
+func messages(v any) []string {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{v}
+	case []any:
+		var msgs []string
+		for _, item := range v {
+			msgs = append(msgs, messages(item)...)
+		}
+		return msgs
+	case bool:
+		if v {
+			return []string{"denied"}
+		}
+		return nil
+	default:
+		b, _ := json.Marshal(v)
+		return []string{string(b)}
+	}
+}
+
+// Check returns an error wrapping ErrDenied with every deny message, nil if the policies admit input
+func (e *Engine) Check(policies []string, input Input) error {
+	msgs, err := e.Deny(policies, input)
+	if err != nil {
+		return err
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w:\n  - %s", ErrDenied, strings.Join(msgs, "\n  - "))
+}
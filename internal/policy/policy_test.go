@@ -0,0 +1,49 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEngine_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		err     error
+		wantErr error
+		want    string
+	}{
+		{name: "admitted", out: `{"result":[{"expressions":[{"value":[]}]}]}`},
+		{name: "undefined", out: `{}`},
+		{name: "denied", out: `{"result":[{"expressions":[{"value":["deploy needs approval","no tests"]}]}]}`, wantErr: ErrDenied, want: "  - deploy needs approval\n  - no tests"},
+		{name: "object messages", out: `{"result":[{"expressions":[{"value":[{"msg":"x"}]}]}]}`, wantErr: ErrDenied, want: `{"msg":"x"}`},
+		{name: "opa missing", err: exec.ErrNotFound, wantErr: exec.ErrNotFound, want: "opa binary on PATH"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var argv []string
+			var stdin []byte
+			e := &Engine{Binary: "opa", Output: func(a []string, in []byte) ([]byte, error) {
+				argv, stdin = a, in
+				return []byte(tt.out), tt.err
+			}}
+			err := e.Check([]string{"policies/", "extra.rego"}, Input{Workflow: map[string]any{"name": "deploy"}, Vars: map[string]string{"env": "prod"}})
+			if !errors.Is(err, tt.wantErr) || (err != nil && !strings.Contains(err.Error(), tt.want)) {
+				t.Fatalf("Check() error = %v, want %v containing %q", err, tt.wantErr, tt.want)
+			}
+
+			wantArgv := []string{"opa", "eval", "--format", "json", "--stdin-input", "--data", "policies/", "--data", "extra.rego", DefaultQuery}
+			if !reflect.DeepEqual(argv, wantArgv) {
+				t.Errorf("argv = %q, want %q", argv, wantArgv)
+			}
+			var input map[string]any
+			if err := json.Unmarshal(stdin, &input); err != nil || input["workflow"].(map[string]any)["name"] != "deploy" {
+				t.Errorf("input = %s, want the workflow", stdin)
+			}
+		})
+	}
+}
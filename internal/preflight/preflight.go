@@ -0,0 +1,114 @@
+// Package preflight checks that the tools a workflow requires are installed before it runs.
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/andre-koe/forge/pkg/version"
+)
+
+// Tool is a required executable, optionally with a version constraint
+type Tool struct {
+	Name       string
+	Constraint string
+}
+
+func (t Tool) String() string {
+	return t.Name + t.Constraint
+}
+
+// ParseTool parses a tool requirement such as "docker" or "kubectl>=1.28, <2"
+func ParseTool(spec string) (Tool, error) {
+	spec = strings.TrimSpace(spec)
+	i := strings.IndexAny(spec, "<>=!")
+	if i < 0 {
+		i = len(spec)
+	}
+	t := Tool{Name: strings.TrimSpace(spec[:i]), Constraint: strings.TrimSpace(spec[i:])}
+	if t.Name == "" || strings.ContainsAny(t.Name, " \t") {
+		return Tool{}, fmt.Errorf("invalid tool requirement %q", spec)
+	}
+	if t.Constraint != "" {
+		if _, err := version.ParseConstraint(t.Constraint); err != nil {
+			return Tool{}, fmt.Errorf("tool %s: %w", t.Name, err)
+		}
+	}
+	return t, nil
+}
+
+// versionPattern finds the first version number in the output of a version command
+var versionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?`)
+
+// versionArgs are tried in order to make a tool print its version
+var versionArgs = [][]string{{"--version"}, {"version"}}
+
+// Checker looks up required tools
+type Checker struct {
+	LookPath func(file string) (string, error)
+	// Output runs a command and returns its combined output, which may hold a version even if it failed
+	Output func(argv []string) ([]byte, error)
+}
+
+// NewChecker returns a checker that looks tools up on PATH
+func NewChecker() *Checker {
+	return &Checker{LookPath: exec.LookPath, Output: combinedOutput}
+}
+
+func combinedOutput(argv []string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return exec.CommandContext(ctx, argv[0], argv[1:]...).CombinedOutput()
+}
+
+// Check returns an error listing every tool that is missing or does not satisfy its constraint
+func (c *Checker) Check(tools []Tool) error {
+	var problems []string
+	for _, t := range tools {
+		if err := c.check(t); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New("required tools are missing:\n  - " + strings.Join(problems, "\n  - "))
+}
+
+func (c *Checker) check(t Tool) error {
+	path, err := c.LookPath(t.Name)
+	if err != nil {
+		return fmt.Errorf("%s: not found on PATH", t.Name)
+	}
+	if t.Constraint == "" {
+		return nil
+	}
+	constraint, err := version.ParseConstraint(t.Constraint)
+	if err != nil {
+		return fmt.Errorf("%s: %v", t.Name, err)
+	}
+	v, err := c.version(path)
+	if err != nil {
+		return fmt.Errorf("%s: %v", t.Name, err)
+	}
+	if !constraint.Check(v) {
+		return fmt.Errorf("%s: version %s does not satisfy %s", t.Name, v, t.Constraint)
+	}
+	return nil
+}
+
+// version runs the tool's version command and parses the first version number it prints
+func (c *Checker) version(path string) (version.Semver, error) {
+	for _, args := range versionArgs {
+		out, _ := c.Output(append([]string{path}, args...))
+		if m := versionPattern.Find(out); m != nil {
+			return version.Parse(string(m))
+		}
+	}
+	return version.Semver{}, errors.New("cannot determine its version")
+}
@@ -0,0 +1,89 @@
+package preflight
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTool(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Tool
+		wantErr bool
+	}{
+		{spec: "docker", want: Tool{Name: "docker"}},
+		{spec: "kubectl>=1.28", want: Tool{Name: "kubectl", Constraint: ">=1.28"}},
+		{spec: "terraform >= 1.5, <2", want: Tool{Name: "terraform", Constraint: ">= 1.5, <2"}},
+		{spec: ">=1.0", wantErr: true},
+		{spec: "kubectl>=one", wantErr: true},
+		{spec: "my tool", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTool(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTool(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseTool(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestChecker_Check(t *testing.T) {
+	outputs := map[string]string{
+		"/bin/docker --version":  "Docker version 24.0.7, build afdd53b",
+		"/bin/kubectl --version": "error: unknown flag: --version",
+		"/bin/kubectl version":   "Client Version: v1.27.3\nKustomize Version: v5.0.1",
+	}
+	var ran []string
+	c := &Checker{
+		LookPath: func(file string) (string, error) {
+			if file == "terraform" {
+				return "", errors.New("not found")
+			}
+			return "/bin/" + file, nil
+		},
+		Output: func(argv []string) ([]byte, error) {
+			cmd := strings.Join(argv, " ")
+			ran = append(ran, cmd)
+			return []byte(outputs[cmd]), nil
+		},
+	}
+
+	parse := func(specs ...string) []Tool {
+		var tools []Tool
+		for _, s := range specs {
+			tool, err := ParseTool(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tools = append(tools, tool)
+		}
+		return tools
+	}
+
+	if err := c.Check(parse("docker>=24", "make")); err != nil {
+		t.Errorf("Check() error: %v", err)
+	}
+	if !slices.Equal(ran, []string{"/bin/docker --version"}) {
+		t.Errorf("ran %q, want only the version of the constrained tool", ran)
+	}
+
+	err := c.Check(parse("kubectl>=1.28", "terraform", "docker<20", "jq>=1.6"))
+	if err == nil {
+		t.Fatal("Check() succeeded with unmet requirements")
+	}
+	for _, want := range []string{
+		"kubectl: version 1.27.3 does not satisfy >=1.28",
+		"terraform: not found on PATH",
+		"docker: version 24.0.7 does not satisfy <20",
+		"jq: cannot determine its version",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Check() error = %q, want it to mention %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Package preprocess evaluates workflows written in CUE or Jsonnet to JSON with the cue and
+// jsonnet binaries, so they can generate stages with loops, imports and their own validation.
+package preprocess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// ErrEvaluate is returned when a workflow source cannot be evaluated
+var ErrEvaluate = errors.New("workflow preprocessing failed")
+
+// Evaluator runs cue export and jsonnet
+type Evaluator struct {
+	// CUE and Jsonnet are the executables
+	CUE, Jsonnet string
+	// Output runs a command and returns its stdout
+	Output func(argv []string) ([]byte, error)
+}
+
+// NewEvaluator returns an evaluator running cue and jsonnet from PATH
+func NewEvaluator() *Evaluator {
+	return &Evaluator{CUE: "cue", Jsonnet: "jsonnet", Output: output}
+}
+
+func output(argv []string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, argv[0], argv[1:]...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return out, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return out, err
+}
+
+// Evaluate evaluates the workflow file at path to JSON, see dsl.Preprocessor
+func (e *Evaluator) Evaluate(format dsl.Format, path string) ([]byte, error) {
+	var argv []string
+	var site string
+	switch format {
+	case dsl.FormatCUE:
+		argv, site = []string{e.CUE, "export", "--out", "json", path}, "https://cuelang.org"
+	case dsl.FormatJsonnet:
+		argv, site = []string{e.Jsonnet, path}, "https://jsonnet.org"
+	default:
+		return nil, fmt.Errorf("%w: %s: %s is not a preprocessed format", ErrEvaluate, path, format)
+	}
+	out, err := e.Output(argv)
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		return nil, fmt.Errorf("%w: %s: %s not found in PATH, install it from %s", ErrEvaluate, path, argv[0], site)
+	case err != nil:
+		return nil, fmt.Errorf("%w: %s: %s: %v", ErrEvaluate, path, argv[0], err)
+	}
+	return out, nil
+}
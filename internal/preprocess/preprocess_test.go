@@ -0,0 +1,51 @@
+package preprocess
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestEvaluator_Evaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   dsl.Format
+		err      error
+		wantArgv []string
+		wantErr  string
+	}{
+		{name: "cue", format: dsl.FormatCUE, wantArgv: []string{"cue", "export", "--out", "json", "ci.cue"}},
+		{name: "jsonnet", format: dsl.FormatJsonnet, wantArgv: []string{"jsonnet", "ci.cue"}},
+		{name: "not installed", format: dsl.FormatCUE, err: &exec.Error{Name: "cue", Err: exec.ErrNotFound}, wantErr: "ci.cue: cue not found in PATH, install it from https://cuelang.org"},
+		{name: "evaluation error", format: dsl.FormatJsonnet, err: fmt.Errorf("exit status 1: RUNTIME ERROR: x"), wantErr: "ci.cue: jsonnet: exit status 1: RUNTIME ERROR: x"},
+		{name: "other format", format: dsl.FormatYAML, wantErr: "yaml is not a preprocessed format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var argv []string
+			e := NewEvaluator()
+			e.Output = func(got []string) ([]byte, error) {
+				argv = got
+				return []byte(`{"name": "ci"}`), tt.err
+			}
+			out, err := e.Evaluate(tt.format, "ci.cue")
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrEvaluate) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Evaluate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Evaluate() error: %v", err)
+			}
+			if !reflect.DeepEqual(argv, tt.wantArgv) || string(out) != `{"name": "ci"}` {
+				t.Errorf("Evaluate() ran %q and returned %q", argv, out)
+			}
+		})
+	}
+}
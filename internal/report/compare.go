@@ -0,0 +1,119 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/andre-koe/forge/internal/history"
+)
+
+// Read loads a JSON report written by WriteFile
+func Read(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &r, nil
+}
+
+type DeltaKind string
+
+const (
+	DeltaAdded     DeltaKind = "added"
+	DeltaRemoved   DeltaKind = "removed"
+	DeltaFailing   DeltaKind = "newly failing"
+	DeltaFixed     DeltaKind = "fixed"
+	DeltaSlower    DeltaKind = "slower"
+	DeltaFaster    DeltaKind = "faster"
+	DeltaUnchanged DeltaKind = "unchanged"
+)
+
+// Threshold decides when a step's duration counts as changed: the difference must be at
+// least Min and more than Ratio of the old duration
+type Threshold struct {
+	Ratio float64
+	Min   time.Duration
+}
+
+func (t Threshold) exceeded(old, diff time.Duration) bool {
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff >= t.Min && float64(diff) > t.Ratio*float64(old)
+}
+
+// Delta describes how a step differs between two reports; Old is nil for added steps and
+// New for removed ones
+type Delta struct {
+	Kind DeltaKind
+	Path string
+	Old  *history.StepRecord
+	New  *history.StepRecord
+}
+
+// Change returns how much longer the step took in the new report
+func (d Delta) Change() time.Duration {
+	if d.Old == nil || d.New == nil {
+		return 0
+	}
+	return d.New.Duration - d.Old.Duration
+}
+
+// Regression reports whether the step got slower or started failing
+func (d Delta) Regression() bool {
+	return d.Kind == DeltaSlower || d.Kind == DeltaFailing
+}
+
+// Compare matches the steps of two reports by path, in the order of the new report followed by
+// the steps only the old one ran. Steps that failed in the new report but not in the old one,
+// including added steps, are newly failing; durations are only compared when both succeeded.
+func Compare(old, new *Report, t Threshold) []Delta {
+	oldByPath := make(map[string]*history.StepRecord, len(old.Steps))
+	for i := range old.Steps {
+		oldByPath[old.Steps[i].Path] = &old.Steps[i]
+	}
+	newByPath := make(map[string]*history.StepRecord, len(new.Steps))
+	for i := range new.Steps {
+		newByPath[new.Steps[i].Path] = &new.Steps[i]
+	}
+
+	var deltas []Delta
+	for i := range new.Steps {
+		n := &new.Steps[i]
+		if newByPath[n.Path] != n {
+			// A retried step is compared by its last attempt
+			continue
+		}
+		o := oldByPath[n.Path]
+		d := Delta{Path: n.Path, Old: o, New: n}
+		switch {
+		case n.Status == history.StatusFailed && (o == nil || o.Status != history.StatusFailed):
+			d.Kind = DeltaFailing
+		case o == nil:
+			d.Kind = DeltaAdded
+		case o.Status == history.StatusFailed && n.Status != history.StatusFailed:
+			d.Kind = DeltaFixed
+		case o.Status == history.StatusSuccess && n.Status == history.StatusSuccess && t.exceeded(o.Duration, d.Change()):
+			d.Kind = DeltaFaster
+			if d.Change() > 0 {
+				d.Kind = DeltaSlower
+			}
+		default:
+			d.Kind = DeltaUnchanged
+		}
+		deltas = append(deltas, d)
+	}
+	for i := range old.Steps {
+		o := &old.Steps[i]
+		if _, ok := newByPath[o.Path]; !ok && oldByPath[o.Path] == o {
+			deltas = append(deltas, Delta{Kind: DeltaRemoved, Path: o.Path, Old: o})
+		}
+	}
+	return deltas
+}
@@ -0,0 +1,82 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func TestCompare(t *testing.T) {
+	step := func(path string, status history.Status, d time.Duration) history.StepRecord {
+		return history.StepRecord{Path: path, Status: status, Duration: d}
+	}
+	old := &Report{Record: history.Record{Steps: []history.StepRecord{
+		step("build/compile", history.StatusSuccess, time.Second),
+		step("build/lint", history.StatusSuccess, time.Second),
+		step("test/unit", history.StatusSuccess, 2*time.Second),
+		step("test/e2e", history.StatusFailed, time.Second),
+		step("test/vet", history.StatusSuccess, time.Second),
+		step("deploy/old", history.StatusSuccess, time.Second),
+	}}}
+	new := &Report{Record: history.Record{Steps: []history.StepRecord{
+		step("build/compile", history.StatusSuccess, 2*time.Second),
+		step("build/lint", history.StatusSuccess, 1050*time.Millisecond),
+		step("test/unit", history.StatusFailed, time.Second),
+		step("test/unit", history.StatusSuccess, time.Second),
+		step("test/e2e", history.StatusSuccess, time.Second),
+		step("test/vet", history.StatusSuccess, 100*time.Millisecond),
+		step("deploy/new", history.StatusFailed, time.Second),
+	}}}
+
+	deltas := Compare(old, new, Threshold{Ratio: 0.1, Min: 100 * time.Millisecond})
+	want := []struct {
+		path string
+		kind DeltaKind
+	}{
+		{"build/compile", DeltaSlower},
+		{"build/lint", DeltaUnchanged},
+		{"test/unit", DeltaFaster},
+		{"test/e2e", DeltaFixed},
+		{"test/vet", DeltaFaster},
+		{"deploy/new", DeltaFailing},
+		{"deploy/old", DeltaRemoved},
+	}
+	if len(deltas) != len(want) {
+		t.Fatalf("Compare() = %+v, want %d deltas", deltas, len(want))
+	}
+	for i, w := range want {
+		if deltas[i].Path != w.path || deltas[i].Kind != w.kind {
+			t.Errorf("delta %d = %s %s, want %s %s", i, deltas[i].Path, deltas[i].Kind, w.path, w.kind)
+		}
+	}
+	if got := deltas[0].Change(); got != time.Second {
+		t.Errorf("Change() = %v, want 1s", got)
+	}
+	if !deltas[0].Regression() || !deltas[5].Regression() || deltas[2].Regression() {
+		t.Errorf("Regression() does not match slower and newly failing steps")
+	}
+}
+
+func TestRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	if err := New(testRecord()).WriteFile(Spec{Format: "json", Path: path}); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	r, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() error: %v", err)
+	}
+	if r.Duration != 3*time.Second || len(r.Steps) != 2 || r.Steps[1].Status != history.StatusFailed {
+		t.Errorf("Read() = %+v", r)
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	if _, err := Read(path); err == nil {
+		t.Error("Read() of invalid JSON succeeded")
+	}
+}
@@ -0,0 +1,71 @@
+package report
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/andre-koe/forge/internal/history"
+)
+
+//go:embed report.html.tmpl
+var htmlSource string
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration":  func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+	"timestamp": func(t time.Time) string { return t.Format("2006-01-02 15:04:05 MST") },
+	"join":      func(argv []string) string { return strings.Join(argv, " ") },
+}).Parse(htmlSource))
+
+// stageSummary groups the steps of one stage for the HTML report
+type stageSummary struct {
+	Name        string
+	Description string
+	Status      history.Status
+	Duration    time.Duration
+	Steps       []*stepSummary
+}
+
+// stepSummary is a step of the HTML report with, for a group, the steps it ran
+type stepSummary struct {
+	history.StepRecord
+	Steps []*stepSummary
+}
+
+// stages groups the report's steps by stage in execution order, and the steps of groups below
+// their group. A stage failed if any of its steps failed and is skipped if all of them were
+// skipped; cached steps count as successful.
+func (r *Report) stages() []*stageSummary {
+	var stages []*stageSummary
+	byName := make(map[string]*stageSummary)
+	for _, step := range r.Steps {
+		s, ok := byName[step.Stage]
+		if !ok {
+			s = &stageSummary{Name: step.Stage, Description: r.StageDescriptions[step.Stage], Status: history.StatusSkipped}
+			byName[step.Stage] = s
+			stages = append(stages, s)
+		}
+		if n := len(s.Steps); step.Group != "" && n > 0 && s.Steps[n-1].Step == step.Group {
+			s.Steps[n-1].Steps = append(s.Steps[n-1].Steps, &stepSummary{StepRecord: step})
+			continue
+		}
+		s.Steps = append(s.Steps, &stepSummary{StepRecord: step})
+		s.Duration += step.Duration
+		switch {
+		case step.Status == history.StatusFailed:
+			s.Status = history.StatusFailed
+		case (step.Status == history.StatusSuccess || step.Status == history.StatusCached) && s.Status == history.StatusSkipped:
+			s.Status = history.StatusSuccess
+		}
+	}
+	return stages
+}
+
+func writeHTML(w io.Writer, r *Report) error {
+	return htmlTemplate.Execute(w, struct {
+		*Report
+		Stages []*stageSummary
+	}{r, r.stages()})
+}
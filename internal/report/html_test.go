@@ -0,0 +1,93 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func TestReport_Stages(t *testing.T) {
+	rep := New(testRecord())
+	rep.Steps = append(rep.Steps, history.StepRecord{Stage: "deploy", Step: "push", Status: history.StatusSkipped})
+
+	stages := rep.stages()
+	if len(stages) != 3 {
+		t.Fatalf("stages() returned %d stages, want 3", len(stages))
+	}
+	want := []struct {
+		name   string
+		status history.Status
+	}{{"build", history.StatusSuccess}, {"test", history.StatusFailed}, {"deploy", history.StatusSkipped}}
+	for i, w := range want {
+		if stages[i].Name != w.name || stages[i].Status != w.status {
+			t.Errorf("stage %d = %s (%s), want %s (%s)", i, stages[i].Name, stages[i].Status, w.name, w.status)
+		}
+	}
+	if stages[1].Duration != 2*time.Second {
+		t.Errorf("test stage duration = %s, want 2s", stages[1].Duration)
+	}
+}
+
+func TestReport_Write_HTML(t *testing.T) {
+	rec := testRecord()
+	rec.Steps[1].OutputTail = append(rec.Steps[1].OutputTail, "<script>alert(1)</script>")
+	rec.Steps[0].Description = "Build the binary"
+	rec.StageDescriptions = map[string]string{"test": "Unit tests"}
+
+	var out strings.Builder
+	if err := New(rec).Write(&out, "html"); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	page := out.String()
+
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"20250102-030405-abcdef",
+		`<details class="failed" open>`,
+		`<details class="success">`,
+		"--- FAIL: TestX",
+		"&lt;script&gt;",
+		`<p class="description">Build the binary</p>`,
+		`<p class="description">Unit tests</p>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("HTML report missing %q", want)
+		}
+	}
+	if strings.Contains(page, "<script>") {
+		t.Error("HTML report does not escape step output")
+	}
+}
+
+func TestReport_Stages_Groups(t *testing.T) {
+	rep := New(testRecord())
+	rep.Steps = []history.StepRecord{
+		{Stage: "ci", Step: "checks", Type: "group", Status: history.StatusFailed, Duration: 3 * time.Second},
+		{Stage: "ci", Step: "lint", Type: "exec", Group: "checks", Status: history.StatusSuccess, Duration: time.Second},
+		{Stage: "ci", Step: "test", Type: "exec", Group: "checks", Status: history.StatusFailed, Duration: 2 * time.Second},
+	}
+
+	stages := rep.stages()
+	if len(stages) != 1 || len(stages[0].Steps) != 1 {
+		t.Fatalf("stages() = %+v, want one stage with the group as its only step", stages)
+	}
+	if group := stages[0].Steps[0]; len(group.Steps) != 2 || group.Steps[1].Step != "test" {
+		t.Errorf("group steps = %+v, want lint and test", group.Steps)
+	}
+	if stages[0].Duration != 3*time.Second || stages[0].Status != history.StatusFailed {
+		t.Errorf("stage = %s (%s), want 3s (failed) without counting the group's steps twice", stages[0].Duration, stages[0].Status)
+	}
+
+	var out strings.Builder
+	if err := rep.Write(&out, "html"); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	page := out.String()
+	group := strings.Index(page, "checks (group)")
+	lint := strings.Index(page, "lint (exec)")
+	if group < 0 || lint < group || strings.Contains(page[group:lint], "</details>") {
+		t.Errorf("HTML report does not nest the group's steps in the group:\n%s", page)
+	}
+}
@@ -0,0 +1,116 @@
+// Package report renders completed workflow runs into files for tools and people outside the terminal.
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/pkg/version"
+)
+
+// ErrUnknownFormat is returned for report formats forge cannot write
+var ErrUnknownFormat = errors.New("unknown report format")
+
+// Report is a run record together with its total duration and the host it ran on
+type Report struct {
+	history.Record
+	Duration time.Duration `json:"duration"`
+	Host     Host          `json:"host"`
+}
+
+// Host describes the machine and forge build that executed a run
+type Host struct {
+	Hostname  string `json:"hostname"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	CPUs      int    `json:"cpus"`
+	Forge     string `json:"forge_version"`
+	GoVersion string `json:"go_version"`
+}
+
+// New builds a report for a completed run on the current host
+func New(rec *history.Record) *Report {
+	hostname, _ := os.Hostname()
+	return &Report{
+		Record:   *rec,
+		Duration: rec.FinishedAt.Sub(rec.StartedAt),
+		Host: Host{
+			Hostname:  hostname,
+			OS:        runtime.GOOS,
+			Arch:      runtime.GOARCH,
+			CPUs:      runtime.NumCPU(),
+			Forge:     version.Version,
+			GoVersion: runtime.Version(),
+		},
+	}
+}
+
+// writers renders a report in each supported format
+var writers = map[string]func(io.Writer, *Report) error{
+	"json": writeJSON,
+	"html": writeHTML,
+}
+
+// Formats returns the supported report formats
+func Formats() []string {
+	formats := make([]string, 0, len(writers))
+	for f := range writers {
+		formats = append(formats, f)
+	}
+	slices.Sort(formats)
+	return formats
+}
+
+// Spec names a report format and the file it is written to, given as "format=path"
+type Spec struct {
+	Format string
+	Path   string
+}
+
+// ParseSpec parses a "format=path" report specification
+func ParseSpec(s string) (Spec, error) {
+	format, path, ok := strings.Cut(s, "=")
+	if !ok || path == "" {
+		return Spec{}, fmt.Errorf("report %q: expected format=path", s)
+	}
+	if _, ok := writers[format]; !ok {
+		return Spec{}, fmt.Errorf("%w %q, use one of %s", ErrUnknownFormat, format, strings.Join(Formats(), ", "))
+	}
+	return Spec{Format: format, Path: path}, nil
+}
+
+// Write renders the report to w in the given format
+func (r *Report) Write(w io.Writer, format string) error {
+	write, ok := writers[format]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+	return write(w, r)
+}
+
+// WriteFile renders the report into the file named by spec
+func (r *Report) WriteFile(spec Spec) error {
+	f, err := os.Create(spec.Path)
+	if err != nil {
+		return err
+	}
+	if err := r.Write(f, spec.Format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
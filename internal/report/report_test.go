@@ -0,0 +1,99 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func testRecord() *history.Record {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &history.Record{
+		ID:         "20250102-030405-abcdef",
+		Workflow:   "workflow.yaml",
+		Name:       "ci",
+		StartedAt:  start,
+		FinishedAt: start.Add(3 * time.Second),
+		Status:     history.StatusFailed,
+		Error:      "stage 'test', step 'unit': exit status 1",
+		Steps: []history.StepRecord{
+			{Path: "build/compile", Stage: "build", Step: "compile", Type: "exec", Status: history.StatusSuccess, Duration: time.Second},
+			{Path: "test/unit", Stage: "test", Step: "unit", Type: "exec", Status: history.StatusFailed, Duration: 2 * time.Second,
+				Error: "exit status 1", OutputTail: []string{"--- FAIL: TestX", "FAIL"}},
+		},
+	}
+}
+
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Spec
+		wantErr bool
+	}{
+		{input: "json=run.json", want: Spec{Format: "json", Path: "run.json"}},
+		{input: "json=out/a=b.json", want: Spec{Format: "json", Path: "out/a=b.json"}},
+		{input: "run.json", wantErr: true},
+		{input: "json=", wantErr: true},
+		{input: "xml=run.xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSpec(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSpec() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReport_WriteFile_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	if err := New(testRecord()).WriteFile(Spec{Format: "json", Path: path}); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		ID       string        `json:"id"`
+		Name     string        `json:"name"`
+		Status   string        `json:"status"`
+		Duration time.Duration `json:"duration"`
+		Host     Host          `json:"host"`
+		Steps    []struct {
+			Path       string   `json:"path"`
+			Status     string   `json:"status"`
+			OutputTail []string `json:"output_tail"`
+		} `json:"steps"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	if got.ID != "20250102-030405-abcdef" || got.Name != "ci" || got.Status != "failed" || got.Duration != 3*time.Second {
+		t.Errorf("report header = %+v", got)
+	}
+	if got.Host.OS == "" || got.Host.Forge == "" {
+		t.Errorf("report host = %+v, want OS and forge version", got.Host)
+	}
+	if len(got.Steps) != 2 || got.Steps[1].Status != "failed" || len(got.Steps[1].OutputTail) != 2 {
+		t.Errorf("report steps = %+v", got.Steps)
+	}
+}
+
+func TestReport_Write_UnknownFormat(t *testing.T) {
+	if err := New(testRecord()).Write(io.Discard, "xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Write() error = %v, want ErrUnknownFormat", err)
+	}
+}
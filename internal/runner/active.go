@@ -0,0 +1,43 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/andre-koe/forge/internal/active"
+)
+
+// WithActive registers runs in progress so `forge status` lists them and `forge cancel` can stop them;
+// a nil registry disables tracking
+func WithActive(reg *active.Registry) Option {
+	return func(r *Runner) { r.Active = reg }
+}
+
+// WithCancelURL records that the run is cancelled with a DELETE request to url, for runs managed by a server
+func WithCancelURL(url string) Option {
+	return func(r *Runner) { r.CancelURL = url }
+}
+
+// WithRunID sets the ID of the run instead of generating one, e.g. to match the ID a server reported
+func WithRunID(id string) Option {
+	return func(r *Runner) { r.ID = id }
+}
+
+func (r *Runner) registerActive() {
+	if r.Active == nil {
+		return
+	}
+	err := r.Active.Add(active.Entry{ID: r.record.ID, Workflow: r.path, PID: os.Getpid(), StartedAt: r.record.StartedAt, CancelURL: r.CancelURL, Group: r.concurrency})
+	if err != nil {
+		fmt.Fprintf(r.Out, "Warning: failed to register run %s: %v\n", r.record.ID, err)
+	}
+}
+
+func (r *Runner) unregisterActive() {
+	if r.Active == nil {
+		return
+	}
+	if err := r.Active.Remove(r.record.ID); err != nil {
+		fmt.Fprintf(r.Out, "Warning: failed to unregister run %s: %v\n", r.record.ID, err)
+	}
+}
@@ -0,0 +1,38 @@
+package runner
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/active"
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_Active(t *testing.T) {
+	reg := active.NewRegistry(filepath.Join(t.TempDir(), "active"))
+	var during []*active.Entry
+	runCmd := func(argv []string) error {
+		var err error
+		during, err = reg.List()
+		return err
+	}
+	stages := []dsl.Stage{{Name: "build", Steps: []dsl.Step{{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"make"}}}}}
+	r, err := NewRunner("ci.yaml", WithOut(new(bytes.Buffer)), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(runCmd), WithActive(reg), WithRunID("run-1"))
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	if len(during) != 1 || during[0].ID != "run-1" || during[0].Workflow != "ci.yaml" {
+		t.Errorf("runs in progress during the run = %+v, want run-1", during)
+	}
+	if r.RunID() != "run-1" {
+		t.Errorf("RunID() = %s, want run-1", r.RunID())
+	}
+	if after, _ := reg.List(); len(after) != 0 {
+		t.Errorf("runs in progress after the run = %+v, want none", after)
+	}
+}
@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// StageRequest asks for a stage of the current run to be run on an agent
+type StageRequest struct {
+	RunID string
+	// Workflow is the path of the workflow file the run was started with
+	Workflow string
+	Stage    string
+	// Labels are the labels an agent needs to run the stage
+	Labels []string
+	// Vars and Profile are the overrides the run was started with
+	Vars    map[string]string
+	Profile string
+}
+
+// Dispatcher runs the requested stage on an agent with all of its labels and returns once it
+// ran there, copying the agent's output to out
+type Dispatcher func(ctx context.Context, req StageRequest, out io.Writer) error
+
+// WithDispatcher sends stages with runs_on: agent to agents through d, as `forge serve` does
+func WithDispatcher(d Dispatcher) Option {
+	return func(r *Runner) { r.Dispatch = d }
+}
+
+// WithOnAgent runs stages with runs_on: agent in this process, as the agent they were sent to.
+// The run that sent them keeps the concurrency group and runs the workflow hooks and
+// notifications, so agents leave them out.
+func WithOnAgent() Option {
+	return func(r *Runner) { r.OnAgent = true }
+}
+
+// dispatched reports whether the stage runs on an agent rather than in this process
+func (r *Runner) dispatched(stage *dsl.Stage) bool {
+	return stage.RunsOn != nil && len(stage.RunsOn.Agent) > 0 && !r.OnAgent
+}
+
+// runOnAgent sends the stage to an agent and waits until it ran there; its steps are recorded
+// in the agent's history. done counts them as executed.
+func (r *Runner) runOnAgent(stageIdx int, stage *dsl.Stage, done *int) error {
+	stagePath := JoinPath(r.rootPath, stage.Name)
+	fmt.Fprintf(r.Out, "\n=== STAGE %d: %s (on an agent with %s) ===\n", stageIdx+1, stage.Name, strings.Join(stage.RunsOn.Agent, ", "))
+	printDescription(r.Out, stage.Description)
+	r.emit(EventStageStart, stagePath, stage.Name, nil)
+
+	var err error
+	switch {
+	case r.Dispatch == nil:
+		err = fmt.Errorf("stage '%s' runs on an agent, which only `forge serve` sends stages to", stage.Name)
+	default:
+		req := StageRequest{RunID: r.record.ID, Workflow: r.path, Stage: stage.Name, Labels: stage.RunsOn.Agent, Vars: r.Vars, Profile: r.Profile}
+		if err = r.Dispatch(r.ctx, req, r.Out); err != nil && r.ctx.Err() != nil {
+			err = fmt.Errorf("stage '%s': %w", stage.Name, ErrCancelled)
+		} else if err != nil {
+			err = fmt.Errorf("stage '%s': %w", stage.Name, err)
+		}
+	}
+	*done += len(stage.Steps)
+
+	r.emit(EventStageEnd, stagePath, stage.Name, err)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(r.Out, "=== STAGE %d COMPLETED ===\n", stageIdx+1)
+	return nil
+}
+
+// dryRunAgent prints the agent a planned stage would be sent to
+func (r *Runner) dryRunAgent(runsOn *dsl.RunsOn) {
+	if runsOn != nil && len(runsOn.Agent) > 0 && !r.OnAgent {
+		fmt.Fprintf(r.Out, "[DRY-RUN] Would run on an agent with %s\n", strings.Join(runsOn.Agent, ", "))
+	}
+}
@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func loadAgentWorkflow(string) (*dsl.Workflow, error) {
+	return &dsl.Workflow{Name: "ml", Vars: map[string]string{"model": "small"}, Stages: agentStages()}, nil
+}
+
+func agentStages() []dsl.Stage {
+	return []dsl.Stage{
+		{Name: "build", Steps: []dsl.Step{{Name: "compile", Type: dsl.StepTypeExec, Run: dsl.Command{"make"}}}},
+		{Name: "train", RunsOn: &dsl.RunsOn{Agent: []string{"linux", "gpu"}}, Steps: []dsl.Step{
+			{Name: "fit", Type: dsl.StepTypeExec, Run: dsl.Command{"train"}},
+			{Name: "eval", Type: dsl.StepTypeExec, Run: dsl.Command{"eval"}},
+		}},
+	}
+}
+
+func TestRunner_RunsOnAgent(t *testing.T) {
+	tests := []struct {
+		name      string
+		dispatch  func(ctx context.Context, req StageRequest, out io.Writer) error
+		onAgent   bool
+		wantCalls []string
+		wantErr   string
+		wantOut   string
+	}{
+		{
+			name: "sends the stage to an agent",
+			dispatch: func(ctx context.Context, req StageRequest, out io.Writer) error {
+				fmt.Fprintf(out, "agent ran %s of %s with %v and %s\n", req.Stage, req.Workflow, req.Labels, req.Vars["model"])
+				return nil
+			},
+			wantCalls: []string{"make"},
+			wantOut:   "agent ran train of ci.yaml with [linux gpu] and large\n",
+		},
+		{
+			name: "fails with the agent",
+			dispatch: func(ctx context.Context, req StageRequest, out io.Writer) error {
+				return errors.New("on agent: out of memory")
+			},
+			wantCalls: []string{"make"},
+			wantErr:   "stage 'train': on agent: out of memory",
+		},
+		{
+			name:      "fails without agents",
+			wantCalls: []string{"make"},
+			wantErr:   "stage 'train' runs on an agent, which only `forge serve` sends stages to",
+		},
+		{
+			name:      "runs the stage on the agent",
+			onAgent:   true,
+			wantCalls: []string{"make", "train", "eval"},
+			wantOut:   "STEP 2.1: fit (exec)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			run := func(argv []string) error {
+				calls = append(calls, argv[0])
+				return nil
+			}
+			var out bytes.Buffer
+			opts := []Option{WithOut(&out), WithLoadWorkflow(loadAgentWorkflow), WithRunCmd(run), WithVars(map[string]string{"model": "large"})}
+			if tt.dispatch != nil {
+				opts = append(opts, WithDispatcher(tt.dispatch))
+			}
+			if tt.onAgent {
+				opts = append(opts, WithOnAgent())
+			}
+			r, err := NewRunner("ci.yaml", opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = r.Run()
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+			if !slices.Equal(calls, tt.wantCalls) {
+				t.Errorf("commands = %q, want %q", calls, tt.wantCalls)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output missing %q:\n%s", tt.wantOut, out.String())
+			}
+			sent := strings.Contains(out.String(), "=== STAGE 2: train (on an agent with linux, gpu) ===")
+			if sent == tt.onAgent {
+				t.Errorf("stage sent to an agent = %v, want %v:\n%s", sent, !tt.onAgent, out.String())
+			}
+		})
+	}
+}
+
+func TestRunner_RunsOnAgent_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	dispatch := func(ctx context.Context, req StageRequest, out io.Writer) error {
+		cancel()
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	r, err := NewRunner("ci.yaml", WithOut(new(bytes.Buffer)), WithLoadWorkflow(mockLoadWorkflow(agentStages())), WithRunCmd(func([]string) error { return nil }),
+		WithDispatcher(dispatch), WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); !errors.Is(err, ErrCancelled) {
+		t.Errorf("Run() error = %v, want %v", err, ErrCancelled)
+	}
+}
+
+func TestRunner_RunsOnAgent_DryRun(t *testing.T) {
+	var out bytes.Buffer
+	r, err := NewRunner("ci.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(agentStages())), WithMode(ModeDryRun))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+	if want := "[DRY-RUN] === STAGE 2: train ===\n[DRY-RUN] Would run on an agent with linux, gpu\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("dry-run output missing %q:\n%s", want, out.String())
+	}
+}
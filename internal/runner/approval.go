@@ -0,0 +1,267 @@
+package runner
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/expr"
+)
+
+// DefaultApprovalTimeout is how long an approval step without a timeout waits for a decision
+const DefaultApprovalTimeout = time.Hour
+
+// ErrApprovalDenied is returned by approval steps that were denied or got no decision in time
+var ErrApprovalDenied = errors.New("approval denied")
+
+// ApprovalRequest describes an approval step waiting for a decision
+type ApprovalRequest struct {
+	RunID    string `json:"run_id"`
+	Workflow string `json:"workflow"`
+	Stage    string `json:"stage"`
+	Step     string `json:"step"`
+	Message  string `json:"message,omitempty"`
+	// Deadline is when the step is denied without a decision
+	Deadline time.Time `json:"deadline"`
+}
+
+// Decision is the answer of an Approver
+type Decision struct {
+	Approved bool
+	// By tells who or what decided, e.g. "terminal" or "token"
+	By string
+}
+
+// Approver decides about an approval step. An approver that cannot decide, e.g. because it has
+// no token for the step, waits until ctx is done and returns its error.
+type Approver func(ctx context.Context, req ApprovalRequest) (Decision, error)
+
+// WithApprovers adds approvers asked in parallel for every approval step; the first decision wins
+func WithApprovers(approvers ...Approver) Option {
+	return func(r *Runner) { r.Approvers = append(r.Approvers, approvers...) }
+}
+
+// awaitApproval blocks until one of the runner's approvers decides about an approval step,
+// the step's timeout expires or the run is cancelled; anything but an approval fails the step
+func (r *Runner) awaitApproval(step *dsl.Step) error {
+	timeout := DefaultApprovalTimeout
+	var message string
+	if a := step.Approval; a != nil {
+		if a.Timeout != "" {
+			// Validation ensures a positive duration
+			timeout, _ = time.ParseDuration(a.Timeout)
+		}
+		var err error
+		if message, err = expr.Interpolate(a.Message, r.exprContext(false)); err != nil {
+			return err
+		}
+	}
+	if len(r.Approvers) == 0 {
+		return fmt.Errorf("%w: no approver is available", ErrApprovalDenied)
+	}
+
+	req := ApprovalRequest{
+		RunID:    r.RunID(),
+		Workflow: r.wf.Name,
+		Stage:    r.current.stage,
+		Step:     step.Name,
+		Message:  message,
+		Deadline: r.Clock.Now().Add(timeout),
+	}
+	fmt.Fprintf(r.Out, "  Waiting up to %s for approval", timeout)
+	if message != "" {
+		fmt.Fprintf(r.Out, ": %s", message)
+	}
+	fmt.Fprintln(r.Out)
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	defer cancel()
+	type answer struct {
+		Decision
+		err error
+	}
+	answers := make(chan answer, len(r.Approvers))
+	for _, approve := range r.Approvers {
+		go func() {
+			d, err := approve(ctx, req)
+			answers <- answer{d, err}
+		}()
+	}
+
+	decide := func(d Decision) error {
+		if !d.Approved {
+			return fmt.Errorf("%w by %s", ErrApprovalDenied, d.By)
+		}
+		fmt.Fprintf(r.Out, "  Approved by %s\n", d.By)
+		return nil
+	}
+	expired := r.Clock.After(timeout)
+	for pending := len(r.Approvers); pending > 0; {
+		select {
+		case a := <-answers:
+			pending--
+			if a.err != nil {
+				if ctx.Err() == nil {
+					fmt.Fprintf(r.Out, "  Approver failed: %v\n", a.err)
+				}
+				continue
+			}
+			return decide(a.Decision)
+		case <-expired:
+			// Approvers return once ctx is done; a decision one of them already made still counts
+			cancel()
+			for ; pending > 0; pending-- {
+				if a := <-answers; a.err == nil {
+					return decide(a.Decision)
+				}
+			}
+			return fmt.Errorf("%w: no decision within %s", ErrApprovalDenied, timeout)
+		case <-r.ctx.Done():
+			return r.ctx.Err()
+		}
+	}
+	return fmt.Errorf("%w: no approver could decide", ErrApprovalDenied)
+}
+
+// TerminalApprover asks on out whether to approve and reads the answer from in; anything but
+// yes, and the end of in, denies. A read still waiting when another approver decided is used
+// for the next question.
+func TerminalApprover(in io.Reader, out io.Writer) Approver {
+	reader := bufio.NewReader(in)
+	var mu sync.Mutex
+	var pending chan string
+	readLine := func() chan string {
+		mu.Lock()
+		defer mu.Unlock()
+		if pending == nil {
+			ch := make(chan string, 1)
+			go func() {
+				s, _ := reader.ReadString('\n')
+				ch <- s
+			}()
+			pending = ch
+		}
+		return pending
+	}
+
+	return func(ctx context.Context, req ApprovalRequest) (Decision, error) {
+		question := req.Message
+		if question == "" {
+			question = fmt.Sprintf("Approve %s.%s?", req.Stage, req.Step)
+		}
+		fmt.Fprintf(out, "%s [y/N] ", question)
+
+		select {
+		case s := <-readLine():
+			mu.Lock()
+			pending = nil
+			mu.Unlock()
+			switch strings.ToLower(strings.TrimSpace(s)) {
+			case "y", "yes":
+				return Decision{Approved: true, By: "terminal"}, nil
+			}
+			return Decision{By: "terminal"}, nil
+		case <-ctx.Done():
+			fmt.Fprintln(out)
+			return Decision{}, ctx.Err()
+		}
+	}
+}
+
+// approvalClaims is the signed content of an approval token
+type approvalClaims struct {
+	Workflow string    `json:"workflow"`
+	Step     string    `json:"step"`
+	Expires  time.Time `json:"expires"`
+	// By names who the token was issued to, the approver recorded for decisions made with it
+	By string `json:"by,omitempty"`
+}
+
+// SignApprovalToken returns a token approving the step "stage.step" of the named workflow until
+// expires on behalf of by, signed with secret for TokenApprover and CheckApprovalToken
+func SignApprovalToken(secret []byte, workflow, step, by string, expires time.Time) string {
+	payload, _ := json.Marshal(approvalClaims{Workflow: workflow, Step: step, Expires: expires.UTC(), By: by})
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+// CheckApprovalToken checks that token, signed with secret, is for the step of req and has not
+// expired at now, and returns who it was issued to, "token" if it names no one
+func CheckApprovalToken(secret []byte, token string, req ApprovalRequest, now time.Time) (string, error) {
+	claims, err := verifyApprovalToken(secret, token)
+	switch {
+	case err != nil:
+		return "", err
+	case claims.Workflow != req.Workflow || claims.Step != req.Stage+"."+req.Step:
+		return "", fmt.Errorf("approval token is for %s of %s, not %s.%s of %s", claims.Step, claims.Workflow, req.Stage, req.Step, req.Workflow)
+	case !now.Before(claims.Expires):
+		return "", fmt.Errorf("approval token for %s expired at %s", claims.Step, claims.Expires.Format(time.RFC3339))
+	}
+	return cmp.Or(claims.By, "token"), nil
+}
+
+// verifyApprovalToken returns the claims of a token signed with secret
+func verifyApprovalToken(secret []byte, token string) (approvalClaims, error) {
+	var claims approvalClaims
+	enc := base64.RawURLEncoding
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return claims, errors.New("malformed approval token")
+	}
+	data, err := enc.DecodeString(payload)
+	if err != nil {
+		return claims, errors.New("malformed approval token")
+	}
+	want, err := enc.DecodeString(sig)
+	if err != nil {
+		return claims, errors.New("malformed approval token")
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	if !hmac.Equal(mac.Sum(nil), want) {
+		return claims, errors.New("approval token has an invalid signature")
+	}
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return claims, errors.New("malformed approval token")
+	}
+	return claims, nil
+}
+
+// TokenApprover approves steps for which one of tokens, signed with secret by SignApprovalToken,
+// names the workflow and step and has not expired at now(); it cannot decide about other steps
+func TokenApprover(secret []byte, tokens []string, now func() time.Time) Approver {
+	return func(ctx context.Context, req ApprovalRequest) (Decision, error) {
+		var problems []string
+		for _, token := range tokens {
+			claims, err := verifyApprovalToken(secret, token)
+			switch {
+			case err != nil:
+				problems = append(problems, err.Error())
+			case claims.Workflow != req.Workflow || claims.Step != req.Stage+"."+req.Step:
+				continue
+			case !now().Before(claims.Expires):
+				problems = append(problems, fmt.Sprintf("approval token for %s expired at %s", claims.Step, claims.Expires.Format(time.RFC3339)))
+			default:
+				return Decision{Approved: true, By: cmp.Or(claims.By, "token")}, nil
+			}
+		}
+		if len(problems) > 0 {
+			return Decision{}, errors.New(strings.Join(problems, "; "))
+		}
+		<-ctx.Done()
+		return Decision{}, ctx.Err()
+	}
+}
@@ -0,0 +1,184 @@
+package runner
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/clock"
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// decide is an approver that always gives d
+func decide(d Decision) Approver {
+	return func(context.Context, ApprovalRequest) (Decision, error) { return d, nil }
+}
+
+// abstain is an approver that never decides
+func abstain(ctx context.Context, _ ApprovalRequest) (Decision, error) {
+	<-ctx.Done()
+	return Decision{}, ctx.Err()
+}
+
+func TestRunner_Approval(t *testing.T) {
+	tests := []struct {
+		name      string
+		approvers []Approver
+		wantErr   string
+		wantOut   string
+		// expire makes the clock run out the timeout at once
+		expire bool
+	}{
+		{name: "approved", approvers: []Approver{abstain, decide(Decision{Approved: true, By: "alice"})}, wantOut: "Approved by alice"},
+		{name: "denied", approvers: []Approver{decide(Decision{By: "bob"})}, wantErr: "approval denied by bob"},
+		{name: "no approvers", wantErr: "no approver is available"},
+		{name: "timeout", approvers: []Approver{abstain}, wantErr: "no decision within 5m0s", expire: true},
+		{name: "decided as the timeout expires", approvers: []Approver{abstain, decide(Decision{Approved: true, By: "carol"})}, wantOut: "Approved by carol", expire: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stages := []dsl.Stage{{Name: "deploy", Steps: []dsl.Step{
+				{Name: "gate", Type: dsl.StepTypeApproval, Approval: &dsl.Approval{Message: "Deploy ${{ vars.tag }}?", Timeout: "5m"}},
+				{Name: "push", Type: dsl.StepTypeExec, Run: []string{"push"}},
+			}}}
+			load := func(string) (*dsl.Workflow, error) {
+				return &dsl.Workflow{Name: "release", Vars: map[string]string{"tag": "v1"}, Stages: stages}, nil
+			}
+			var out bytes.Buffer
+			var calls [][]string
+			clk := WithClock(clock.NewFake(time.Now()))
+			if tt.expire {
+				clk = WithSleep(func(time.Duration) {})
+			}
+			r, err := NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(load), WithRunCmd(mockRunCmd(&calls)),
+				clk, WithApprovers(tt.approvers...))
+			if err != nil {
+				t.Fatalf("NewRunner() error: %v", err)
+			}
+
+			err = r.Run()
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrApprovalDenied) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+				if len(calls) > 0 {
+					t.Errorf("denied run executed %v", calls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			if !reflect.DeepEqual(calls, [][]string{{"push"}}) {
+				t.Errorf("calls = %v, want [[push]]", calls)
+			}
+			for _, want := range []string{"Waiting up to 5m0s for approval: Deploy v1?", tt.wantOut} {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestTerminalApprover(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+	}
+	req := ApprovalRequest{Stage: "deploy", Step: "gate"}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		d, err := TerminalApprover(strings.NewReader(tt.input), &out)(context.Background(), req)
+		if err != nil || d.Approved != tt.want || d.By != "terminal" {
+			t.Errorf("answer %q: got %+v, %v, want approved %v", tt.input, d, err, tt.want)
+		}
+		if out.String() != "Approve deploy.gate? [y/N] " {
+			t.Errorf("prompt = %q", out.String())
+		}
+	}
+}
+
+func TestTokenApprover(t *testing.T) {
+	secret := []byte("s3cret")
+	now := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+	at := func() time.Time { return now }
+	req := ApprovalRequest{Workflow: "release", Stage: "deploy", Step: "gate"}
+	valid := SignApprovalToken(secret, "release", "deploy.gate", "", now.Add(time.Hour))
+
+	tests := []struct {
+		name    string
+		tokens  []string
+		want    bool
+		wantBy  string
+		wantErr string
+	}{
+		{name: "valid", tokens: []string{SignApprovalToken(secret, "release", "other.step", "", now.Add(time.Hour)), valid}, want: true},
+		{name: "other step", tokens: []string{SignApprovalToken(secret, "release", "deploy.other", "", now.Add(time.Hour))}, wantErr: context.Canceled.Error()},
+		{name: "expired", tokens: []string{SignApprovalToken(secret, "release", "deploy.gate", "", now)}, wantErr: "expired"},
+		{name: "wrong secret", tokens: []string{SignApprovalToken([]byte("other"), "release", "deploy.gate", "", now.Add(time.Hour))}, wantErr: "invalid signature"},
+		{name: "malformed", tokens: []string{"not-a-token"}, wantErr: "malformed"},
+		{name: "issued to someone", tokens: []string{SignApprovalToken(secret, "release", "deploy.gate", "alice", now.Add(time.Hour))}, want: true, wantBy: "alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			d, err := TokenApprover(secret, tt.tokens, at)(ctx, req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || d.Approved != tt.want || d.By != cmp.Or(tt.wantBy, "token") {
+				t.Errorf("got %+v, %v, want approved %v", d, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckApprovalToken(t *testing.T) {
+	secret := []byte("s3cret")
+	now := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+	req := ApprovalRequest{Workflow: "release", Stage: "deploy", Step: "gate"}
+	tests := []struct {
+		name    string
+		token   string
+		wantBy  string
+		wantErr string
+	}{
+		{name: "issued to someone", token: SignApprovalToken(secret, "release", "deploy.gate", "alice", now.Add(time.Hour)), wantBy: "alice"},
+		{name: "issued to no one", token: SignApprovalToken(secret, "release", "deploy.gate", "", now.Add(time.Hour)), wantBy: "token"},
+		{name: "other step", token: SignApprovalToken(secret, "release", "deploy.other", "alice", now.Add(time.Hour)), wantErr: "is for deploy.other of release"},
+		{name: "other workflow", token: SignApprovalToken(secret, "build", "deploy.gate", "alice", now.Add(time.Hour)), wantErr: "is for deploy.gate of build"},
+		{name: "expired", token: SignApprovalToken(secret, "release", "deploy.gate", "alice", now), wantErr: "expired"},
+		{name: "wrong secret", token: SignApprovalToken([]byte("other"), "release", "deploy.gate", "alice", now.Add(time.Hour)), wantErr: "invalid signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			by, err := CheckApprovalToken(secret, tt.token, req, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("CheckApprovalToken() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || by != tt.wantBy {
+				t.Errorf("CheckApprovalToken() = %q, %v, want %q", by, err, tt.wantBy)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/artifact"
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// errNoArtifactStore is returned for stages using artifacts when the runner has no store
+var errNoArtifactStore = errors.New("artifacts: no artifact store configured")
+
+// WithArtifacts keeps the artifacts of stages in the store
+func WithArtifacts(s *artifact.Store) Option {
+	return func(r *Runner) { r.Artifacts = s }
+}
+
+// restoreArtifacts copies the artifacts of the stages named in stage.Restore into the stage's
+// working directory, preferring those kept by the current run
+func (r *Runner) restoreArtifacts(stage *dsl.Stage) error {
+	if len(stage.Restore) > 0 && r.Artifacts == nil {
+		return errNoArtifactStore
+	}
+	for _, name := range stage.Restore {
+		runID, err := r.Artifacts.Find(name, r.record.ID)
+		if err != nil {
+			return fmt.Errorf("restore: %w", err)
+		}
+		stats, err := r.Artifacts.Restore(runID, name, cmp.Or(r.stageDir(stage), "."))
+		if err != nil {
+			return fmt.Errorf("restore: %w", err)
+		}
+		from := "this run"
+		if runID != r.record.ID {
+			from = "run " + runID
+		}
+		fmt.Fprintf(r.Out, "Restored artifacts of %s from %s: %s\n", name, from, stats)
+	}
+	return nil
+}
+
+// collectArtifacts keeps the files matching stage.Artifacts, relative to the stage's working
+// directory, for the current run
+func (r *Runner) collectArtifacts(stage *dsl.Stage) error {
+	if len(stage.Artifacts) == 0 {
+		return nil
+	}
+	if r.Artifacts == nil {
+		return errNoArtifactStore
+	}
+	stats, err := r.Artifacts.Collect(r.record.ID, stage.Name, r.stageDir(stage), stage.Artifacts)
+	if err != nil {
+		return fmt.Errorf("artifacts: %w", err)
+	}
+	if stats.Files == 0 {
+		fmt.Fprintf(r.Out, "Warning: no files match the artifacts of %s (%s)\n", stage.Name, strings.Join(stage.Artifacts, ", "))
+		return nil
+	}
+	fmt.Fprintf(r.Out, "Collected artifacts: %s\n", stats)
+	return nil
+}
@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/artifact"
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_Artifacts(t *testing.T) {
+	t.Chdir(t.TempDir())
+	store := artifact.NewStore(filepath.Join(t.TempDir(), "artifacts"))
+
+	stages := []dsl.Stage{
+		{Name: "build", Artifacts: []string{"dist"}, Steps: []dsl.Step{{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"compile"}}}},
+		{Name: "package", Restore: []string{"build"}, Steps: []dsl.Step{{Name: "tar", Type: dsl.StepTypeExec, Run: []string{"package"}}}},
+	}
+	var packaged string
+	runCmd := func(argv []string) error {
+		switch argv[0] {
+		case "compile":
+			if err := os.MkdirAll("dist", 0o755); err != nil {
+				return err
+			}
+			return os.WriteFile("dist/app", []byte("v1"), 0o644)
+		case "package":
+			data, err := os.ReadFile("dist/app")
+			packaged = string(data)
+			return err
+		}
+		return errors.New("unexpected command")
+	}
+
+	// The first run builds and packages; the build output is removed in between to prove package restores it
+	var out bytes.Buffer
+	r, err := NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)), WithArtifacts(store),
+		WithRunCmd(func(argv []string) error {
+			if argv[0] == "package" {
+				if err := os.RemoveAll("dist"); err != nil {
+					return err
+				}
+			}
+			return runCmd(argv)
+		}))
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := r.Run(); err == nil {
+		t.Fatal("Run() succeeded although package ran without dist")
+	}
+
+	out.Reset()
+	r, err = NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)), WithArtifacts(store), WithRunCmd(runCmd))
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v\n%s", err, out.String())
+	}
+	if packaged != "v1" || !strings.Contains(out.String(), "Restored artifacts of build from this run") {
+		t.Errorf("packaged %q, output:\n%s", packaged, out.String())
+	}
+	list, err := store.List(r.RunID())
+	if err != nil || len(list) != 1 || list[0].Path != "dist/app" {
+		t.Errorf("List() = %+v, %v", list, err)
+	}
+
+	// A later run of only the package stage restores the build artifacts of the previous run
+	if err := os.RemoveAll("dist"); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	packaged = ""
+	r, err = NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)), WithArtifacts(store), WithRunCmd(runCmd), WithStages("package"))
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v\n%s", err, out.String())
+	}
+	if packaged != "v1" || !strings.Contains(out.String(), "Restored artifacts of build from run ") {
+		t.Errorf("packaged %q, output:\n%s", packaged, out.String())
+	}
+}
+
+func TestRunner_Artifacts_Workdir(t *testing.T) {
+	project := filepath.Join(t.TempDir(), "project")
+	if err := os.MkdirAll(project, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	workflow := filepath.Join(project, "ci.yaml")
+	if err := os.WriteFile(workflow, []byte("name: ci\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// forge runs from elsewhere; artifacts are relative to the stages' workdir, the workflow's directory
+	t.Chdir(t.TempDir())
+	store := artifact.NewStore(filepath.Join(t.TempDir(), "artifacts"))
+	stages := []dsl.Stage{
+		{Name: "build", Workdir: ".", Artifacts: []string{"dist"}, Steps: []dsl.Step{{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"compile"}}}},
+		{Name: "package", Workdir: "pkg", Restore: []string{"build"}, Steps: []dsl.Step{{Name: "tar", Type: dsl.StepTypeExec, Run: []string{"package"}}}},
+	}
+	runCmd := func(argv []string) error {
+		if argv[0] == "compile" {
+			if err := os.MkdirAll(filepath.Join(project, "dist"), 0o755); err != nil {
+				return err
+			}
+			return os.WriteFile(filepath.Join(project, "dist", "app"), []byte("v1"), 0o644)
+		}
+		return nil
+	}
+	var out bytes.Buffer
+	r, err := NewRunner(workflow, WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)), WithArtifacts(store), WithRunCmd(runCmd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v\n%s", err, out.String())
+	}
+	if list, err := store.List(r.RunID()); err != nil || len(list) != 1 || list[0].Path != "dist/app" {
+		t.Errorf("List() = %+v, %v, want dist/app", list, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(project, "pkg", "dist", "app")); err != nil || string(data) != "v1" {
+		t.Errorf("restored artifact = %q, %v, want it in the package stage's workdir", data, err)
+	}
+}
@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/ansi"
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// ErrAssertionFailed is returned by assert steps whose command did not meet the expectations
+var ErrAssertionFailed = errors.New("assertion failed")
+
+// exitCode returns the exit code of a command that ran and failed; exec.ExitError and errors of
+// command stubs tell it with an ExitCode method
+func exitCode(err error) (int, bool) {
+	var coder interface{ ExitCode() int }
+	if errors.As(err, &coder) {
+		return coder.ExitCode(), true
+	}
+	return 0, false
+}
+
+// runAssert runs the command of an assert step and fails the step unless its exit code and
+// output meet the step's expectations; a command that cannot be started fails it as well
+func (r *Runner) runAssert(step *dsl.Step) error {
+	a := step.Assert
+	limit := cmp.Or(r.OutputLimit, DefaultOutputLimit)
+	stdout, stderr := &captureBuffer{limit: limit}, &captureBuffer{limit: limit}
+	prevOut, prevErr := r.stdout, r.stderr
+	r.stdout = io.MultiWriter(r.processStdout(), stdout)
+	r.stderr = io.MultiWriter(r.processStderr(), stderr)
+	err := r.exec(a.Command)
+	r.stdout, r.stderr = prevOut, prevErr
+
+	code := 0
+	if err != nil {
+		var ok bool
+		if code, ok = exitCode(err); !ok {
+			return fmt.Errorf("command execution failed: %w", err)
+		}
+	}
+
+	var failures []string
+	if code != a.ExpectExitCode {
+		failures = append(failures, fmt.Sprintf("exit code %d, want %d", code, a.ExpectExitCode))
+	}
+	checks := []struct {
+		stream string
+		output string
+		want   []string
+	}{
+		{"stdout", ansi.Strip(stdout.buf.String()), a.ExpectStdoutContains},
+		{"stderr", ansi.Strip(stderr.buf.String()), a.ExpectStderrContains},
+	}
+	for _, c := range checks {
+		want, err := r.interpolate(c.want)
+		if err != nil {
+			return err
+		}
+		for _, s := range want {
+			if !strings.Contains(c.output, s) {
+				failures = append(failures, fmt.Sprintf("%s does not contain %q", c.stream, s))
+			}
+		}
+	}
+	for _, pattern := range a.ExpectStdoutMatches {
+		// Validation ensures the pattern compiles
+		if !regexp.MustCompile(pattern).MatchString(checks[0].output) {
+			failures = append(failures, fmt.Sprintf("stdout does not match %q", pattern))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%w: %s", ErrAssertionFailed, strings.Join(failures, "; "))
+	}
+	fmt.Fprintf(r.Out, "  Assertion passed\n")
+	return nil
+}
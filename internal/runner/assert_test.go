@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// exitStatus is a command error with an exit code, like exec.ExitError
+type exitStatus int
+
+func (e exitStatus) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+func (e exitStatus) ExitCode() int { return int(e) }
+
+func TestRunner_Assert(t *testing.T) {
+	tests := []struct {
+		name    string
+		assert  dsl.Assert
+		stdout  string
+		stderr  string
+		err     error
+		wantErr string
+	}{
+		{
+			name:   "passes",
+			assert: dsl.Assert{ExpectStdoutContains: []string{`"status": "ok"`, "${{ vars.tag }}"}, ExpectStdoutMatches: []string{`"version": "v\d+\.\d+"`}},
+			stdout: `{"status": "ok", "version": "v1.2"}`,
+		},
+		{
+			name:   "expected exit code",
+			assert: dsl.Assert{ExpectExitCode: 2, ExpectStderrContains: []string{"not found"}},
+			stderr: "not found\n",
+			err:    exitStatus(2),
+		},
+		{
+			name:    "unexpected exit code",
+			err:     exitStatus(7),
+			wantErr: "assertion failed: exit code 7, want 0",
+		},
+		{
+			name:    "output mismatch",
+			assert:  dsl.Assert{ExpectStdoutContains: []string{"ok", "${{ vars.tag }}"}, ExpectStdoutMatches: []string{`^ready$`}, ExpectStderrContains: []string{"done"}},
+			stdout:  "\x1b[31mdegraded\x1b[0m",
+			wantErr: `assertion failed: stdout does not contain "ok"; stdout does not contain "v1.2"; stderr does not contain "done"; stdout does not match "^ready$"`,
+		},
+		{
+			name:    "command not started",
+			err:     errors.New(`exec: "status": executable file not found in $PATH`),
+			wantErr: "command execution failed: exec: ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.assert
+			a.Command = []string{"status", "--tag", "${{ vars.tag }}"}
+			stages := []dsl.Stage{{Name: "verify", Steps: []dsl.Step{{Name: "healthy", Type: dsl.StepTypeAssert, Assert: &a}}}}
+			load := func(string) (*dsl.Workflow, error) {
+				return &dsl.Workflow{Name: "verify", Vars: map[string]string{"tag": "v1.2"}, Stages: stages}, nil
+			}
+			var out bytes.Buffer
+			var argv []string
+			r, err := NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(load),
+				WithCommandFunc(func(got []string, stdout, stderr io.Writer) error {
+					argv = got
+					io.WriteString(stdout, tt.stdout)
+					io.WriteString(stderr, tt.stderr)
+					return tt.err
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = r.Run()
+			if strings.Join(argv, " ") != "status --tag v1.2" {
+				t.Errorf("command = %q, want the interpolated assert command", argv)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Run() error: %v", err)
+				}
+				if !strings.Contains(out.String(), "Assertion passed") {
+					t.Errorf("output missing the passed assertion:\n%s", out.String())
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+			if got := errors.Is(err, ErrAssertionFailed); got != strings.HasPrefix(tt.wantErr, "assertion failed") {
+				t.Errorf("errors.Is(%v, ErrAssertionFailed) = %v", err, got)
+			}
+			if got := r.Results().Record.Steps[0].Commands; len(got) != 1 {
+				t.Errorf("recorded commands = %q, want the assert command", got)
+			}
+		})
+	}
+}
+
+func TestRunner_DryRun_Assert(t *testing.T) {
+	stages := []dsl.Stage{{Name: "verify", Steps: []dsl.Step{
+		{Name: "healthy", Type: dsl.StepTypeAssert, Assert: &dsl.Assert{Command: []string{"curl", "-s", "localhost/healthz"}}},
+	}}}
+	var out bytes.Buffer
+	var calls [][]string
+	r, err := NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(mockRunCmd(&calls)), WithMode(ModeDryRun))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if len(calls) > 0 {
+		t.Errorf("dry run executed %v", calls)
+	}
+	if want := "[DRY-RUN]   Would assert on command: [curl -s localhost/healthz]"; !strings.Contains(out.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, out.String())
+	}
+}
@@ -0,0 +1,37 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/andre-koe/forge/internal/cache"
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// WithCache skips steps with a cache whose key matches the key of their last successful run;
+// a nil store disables caching
+func WithCache(s *cache.Store) Option {
+	return func(r *Runner) { r.Cache = s }
+}
+
+// cacheKey returns the id and the current key of a cached step, or empty strings when the step is not cached.
+// The key covers the interpolated command so changed variables invalidate it; key files are
+// relative to the step's directory.
+func (r *Runner) cacheKey(stage *dsl.Stage, stagePath string, step *dsl.Step) (id, key string) {
+	if step.Cache == nil || r.Cache == nil {
+		return "", ""
+	}
+	run, err := r.interpolate(runCommandLine(r.wf, step))
+	if err != nil {
+		return "", ""
+	}
+	def := struct {
+		Step *dsl.Step
+		Run  []string
+	}{step, run}
+	key, err = cache.Key(def, r.stepDir(r.wf, stage, step), step.Cache.KeyFiles)
+	if err != nil {
+		fmt.Fprintf(r.Out, "Warning: not caching step %s: %v\n", step.Name, err)
+		return "", ""
+	}
+	return r.wf.Name + "/" + JoinPath(stagePath, step.Name), key
+}
@@ -0,0 +1,132 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/cache"
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func TestRunner_Cache(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("go.sum", []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store := cache.NewStore(filepath.Join(t.TempDir(), "cache"))
+	stages := []dsl.Stage{{Name: "build", Steps: []dsl.Step{
+		{Name: "deps", Type: dsl.StepTypeExec, Run: []string{"deps"}, Cache: &dsl.Cache{KeyFiles: []string{"go.sum"}}},
+		{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"compile"}},
+	}}}
+
+	run := func(fail bool, opts ...Option) ([]string, []history.Status) {
+		t.Helper()
+		var calls []string
+		runCmd := func(argv []string) error {
+			calls = append(calls, argv[0])
+			if fail {
+				return errors.New("exit status 1")
+			}
+			return nil
+		}
+		r, err := NewRunner("test.yaml", append([]Option{WithOut(new(bytes.Buffer)), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(runCmd)}, opts...)...)
+		if err != nil {
+			t.Fatalf("NewRunner() failed: %v", err)
+		}
+		_ = r.Run()
+		var statuses []history.Status
+		for _, step := range r.Record().Steps {
+			statuses = append(statuses, step.Status)
+		}
+		return calls, statuses
+	}
+
+	tests := []struct {
+		name       string
+		change     string
+		fail       bool
+		opts       []Option
+		wantCalls  []string
+		wantStatus []history.Status
+	}{
+		{name: "failed runs are not cached", fail: true, opts: []Option{WithCache(store)}, wantCalls: []string{"deps"}, wantStatus: []history.Status{history.StatusFailed}},
+		{name: "first successful run", opts: []Option{WithCache(store)}, wantCalls: []string{"deps", "compile"}, wantStatus: []history.Status{history.StatusSuccess, history.StatusSuccess}},
+		{name: "unchanged inputs", opts: []Option{WithCache(store)}, wantCalls: []string{"compile"}, wantStatus: []history.Status{history.StatusCached, history.StatusSuccess}},
+		{name: "caching disabled", wantCalls: []string{"deps", "compile"}, wantStatus: []history.Status{history.StatusSuccess, history.StatusSuccess}},
+		{name: "changed key file", change: "v2", opts: []Option{WithCache(store)}, wantCalls: []string{"deps", "compile"}, wantStatus: []history.Status{history.StatusSuccess, history.StatusSuccess}},
+		{name: "unchanged again", opts: []Option{WithCache(store)}, wantCalls: []string{"compile"}, wantStatus: []history.Status{history.StatusCached, history.StatusSuccess}},
+	}
+	// The cases build on each other and run in order
+	for _, tt := range tests {
+		if tt.change != "" {
+			if err := os.WriteFile("go.sum", []byte(tt.change), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		calls, statuses := run(tt.fail, tt.opts...)
+		if !reflect.DeepEqual(calls, tt.wantCalls) || !reflect.DeepEqual(statuses, tt.wantStatus) {
+			t.Errorf("%s: commands = %v, statuses = %v; want %v, %v", tt.name, calls, statuses, tt.wantCalls, tt.wantStatus)
+		}
+	}
+}
+
+func TestRunner_Cache_Workdir(t *testing.T) {
+	project := filepath.Join(t.TempDir(), "project")
+	if err := os.MkdirAll(project, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	workflow := filepath.Join(project, "ci.yaml")
+	for name, content := range map[string]string{workflow: "name: ci\n", filepath.Join(project, "go.sum"): "v1"} {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// forge runs from elsewhere; the key files are relative to the step's workdir
+	t.Chdir(t.TempDir())
+	store := cache.NewStore(filepath.Join(t.TempDir(), "cache"))
+	stages := []dsl.Stage{{Name: "build", Steps: []dsl.Step{
+		{Name: "deps", Type: dsl.StepTypeExec, Workdir: ".", Run: []string{"deps"}, Cache: &dsl.Cache{KeyFiles: []string{"go.sum", "*.lock"}}},
+	}}}
+	lock := filepath.Join(project, "deps.lock")
+
+	tests := []struct {
+		name      string
+		write     map[string]string
+		wantCalls int
+		wantOut   string
+	}{
+		{name: "pattern matching nothing", wantCalls: 1, wantOut: `Warning: not caching step deps: key file "*.lock" matches no files`},
+		{name: "first run", write: map[string]string{lock: "a"}, wantCalls: 1},
+		{name: "unchanged", wantCalls: 0},
+		{name: "changed key file", write: map[string]string{filepath.Join(project, "go.sum"): "v2"}, wantCalls: 1},
+		{name: "unchanged again", wantCalls: 0},
+	}
+	for _, tt := range tests {
+		for name, content := range tt.write {
+			if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		calls := 0
+		var out bytes.Buffer
+		r, err := NewRunner(workflow, WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)), WithCache(store),
+			WithRunCmd(func([]string) error { calls++; return nil }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Run(); err != nil {
+			t.Fatalf("%s: Run() error: %v", tt.name, err)
+		}
+		if calls != tt.wantCalls {
+			t.Errorf("%s: commands run = %d, want %d", tt.name, calls, tt.wantCalls)
+		}
+		if !bytes.Contains(out.Bytes(), []byte(tt.wantOut)) {
+			t.Errorf("%s: output missing %q:\n%s", tt.name, tt.wantOut, out.String())
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/glob"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+const defaultChangesBase = "HEAD"
+
+// WithChangesBase sets the git ref stages with changes filters are compared against, overriding the workflow's changes_base
+func WithChangesBase(ref string) Option {
+	return func(r *Runner) { r.ChangesBase = ref }
+}
+
+// WithChangedFiles makes stages with changes filters match against the given slash-separated
+// paths instead of asking git, e.g. for files reported by a file watcher
+func WithChangedFiles(files []string) Option {
+	return func(r *Runner) { r.ChangedFiles = files }
+}
+
+func (r *Runner) changesBase(wf *dsl.Workflow) string {
+	switch {
+	case r.ChangesBase != "":
+		return r.ChangesBase
+	case wf.ChangesBase != "":
+		return wf.ChangesBase
+	}
+	return defaultChangesBase
+}
+
+// changedFiles lists the files changed since base plus untracked files, once per run
+func (r *Runner) changedFiles(base string) ([]string, error) {
+	if r.ChangedFiles != nil {
+		return r.ChangedFiles, nil
+	}
+	if r.changed != nil {
+		return *r.changed, nil
+	}
+	diff, err := r.CmdOutput([]string{"git", "diff", "--name-only", "--relative", base})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changes since %s: %w", base, err)
+	}
+	untracked, err := r.CmdOutput([]string{"git", "ls-files", "--others", "--exclude-standard"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list untracked files: %w", err)
+	}
+	files := append(splitLines(string(diff)), splitLines(string(untracked))...)
+	r.changed = &files
+	return files, nil
+}
+
+// stageChanged reports whether a stage has to run because of its changes filter. Stages without
+// a filter or selected explicitly with WithStages always run, as do all stages when git fails.
+func (r *Runner) stageChanged(stage *dsl.Stage) bool {
+	if len(stage.Changes) == 0 || len(r.Stages) > 0 {
+		return true
+	}
+	files, err := r.changedFiles(r.changesBase(r.wf))
+	if err != nil {
+		fmt.Fprintf(r.Out, "Warning: running stage '%s' regardless of its changes filter: %v\n", stage.Name, err)
+		return true
+	}
+	for _, f := range files {
+		if glob.MatchAny(stage.Changes, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipUnchangedStage records the steps of a stage skipped by its changes filter; done counts them
+func (r *Runner) skipUnchangedStage(stageIdx int, stage *dsl.Stage, done *int) {
+	since := "since " + r.changesBase(r.wf)
+	if r.ChangedFiles != nil {
+		since = "among the changed files"
+	}
+	fmt.Fprintf(r.Out, "\n=== STAGE %d: %s (skipped, no changes %s) ===\n", stageIdx+1, stage.Name, since)
+	stagePath := JoinPath(r.rootPath, stage.Name)
+	for _, step := range stage.Steps {
+		r.startStepRecord(stage.Name, &step, JoinPath(stagePath, step.Name))
+		r.skipStepRecord(history.StatusSkipped)
+		*done++
+	}
+}
+
+func (r *Runner) dryRunChanges(wf *dsl.Workflow, stage *dsl.Stage) {
+	if len(stage.Changes) > 0 {
+		fmt.Fprintf(r.Out, "[DRY-RUN] Would run only if paths matching %s changed since %s\n", strings.Join(stage.Changes, ", "), r.changesBase(wf))
+	}
+}
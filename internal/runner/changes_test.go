@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func TestRunner_Changes(t *testing.T) {
+	stages := []dsl.Stage{
+		{Name: "api", Changes: []string{"services/api/**", "go.mod"}, Steps: []dsl.Step{{Name: "build-api", Type: dsl.StepTypeExec, Run: []string{"api"}}}},
+		{Name: "web", Changes: []string{"services/web/**"}, Steps: []dsl.Step{{Name: "build-web", Type: dsl.StepTypeExec, Run: []string{"web"}}}},
+		{Name: "lint", Steps: []dsl.Step{{Name: "lint", Type: dsl.StepTypeExec, Run: []string{"lint"}}}},
+	}
+
+	tests := []struct {
+		name       string
+		diff       string
+		untracked  string
+		gitErr     error
+		opts       []Option
+		wantBase   string
+		wantCalls  []string
+		wantStatus []history.Status
+	}{
+		{name: "changed service only", diff: "services/api/main.go\n", wantBase: "HEAD", wantCalls: []string{"api", "lint"}, wantStatus: []history.Status{history.StatusSuccess, history.StatusSkipped, history.StatusSuccess}},
+		{name: "untracked files count", untracked: "services/web/new.ts\n", wantBase: "HEAD", wantCalls: []string{"web", "lint"}, wantStatus: []history.Status{history.StatusSkipped, history.StatusSuccess, history.StatusSuccess}},
+		{name: "base override", diff: "go.mod\n", opts: []Option{WithChangesBase("origin/main")}, wantBase: "origin/main", wantCalls: []string{"api", "lint"}, wantStatus: []history.Status{history.StatusSuccess, history.StatusSkipped, history.StatusSuccess}},
+		{name: "changed files given", diff: "services/api/main.go\n", opts: []Option{WithChangedFiles([]string{"services/web/app.ts"})}, wantCalls: []string{"web", "lint"}, wantStatus: []history.Status{history.StatusSkipped, history.StatusSuccess, history.StatusSuccess}},
+		{name: "explicit stage selection", opts: []Option{WithStages("web")}, wantCalls: []string{"web"}, wantStatus: []history.Status{history.StatusSuccess}},
+		{name: "git failure runs everything", gitErr: errors.New("not a git repository"), wantBase: "HEAD", wantCalls: []string{"api", "web", "lint"}, wantStatus: []history.Status{history.StatusSuccess, history.StatusSuccess, history.StatusSuccess}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var base string
+			cmdOutput := func(argv []string) ([]byte, error) {
+				if tt.gitErr != nil {
+					return nil, tt.gitErr
+				}
+				if argv[1] == "diff" {
+					base = argv[len(argv)-1]
+					return []byte(tt.diff), nil
+				}
+				return []byte(tt.untracked), nil
+			}
+			var calls []string
+			runCmd := func(argv []string) error {
+				calls = append(calls, argv[0])
+				return nil
+			}
+			r, err := NewRunner("test.yaml", append([]Option{WithOut(new(bytes.Buffer)), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(runCmd), WithCmdOutput(cmdOutput)}, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("NewRunner() failed: %v", err)
+			}
+			if err := r.Run(); err != nil {
+				t.Fatalf("Run() failed: %v", err)
+			}
+			var statuses []history.Status
+			for _, step := range r.Record().Steps {
+				statuses = append(statuses, step.Status)
+			}
+			if tt.gitErr == nil && base != tt.wantBase {
+				t.Errorf("compared against %q, want %q", base, tt.wantBase)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) || !reflect.DeepEqual(statuses, tt.wantStatus) {
+				t.Errorf("commands = %v, statuses = %v; want %v, %v", calls, statuses, tt.wantCalls, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// ErrChaosInjected is returned for steps failed on purpose by a chaos rule
+var ErrChaosInjected = errors.New("chaos: injected failure")
+
+type ChaosKind string
+
+const (
+	ChaosFail  ChaosKind = "fail-step"
+	ChaosDelay ChaosKind = "delay"
+)
+
+// ChaosRule injects a failure or delay into steps whose "stage.step" id matches Pattern
+type ChaosRule struct {
+	Kind        ChaosKind
+	Pattern     string
+	Probability float64
+	Delay       time.Duration
+}
+
+// ParseChaos parses a comma-separated chaos specification such as
+// "fail-step=deploy.push:0.3,delay=test.*:5s". Patterns use path.Match syntax
+// against "stage.step", or "stage.group.step" inside groups. Delays accept an optional trailing probability ("test.*:5s:0.5").
+func ParseChaos(spec string) ([]ChaosRule, error) {
+	var rules []ChaosRule
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		kind, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid chaos rule %q: expected kind=pattern:value", part)
+		}
+
+		fields := strings.Split(value, ":")
+		if len(fields) < 2 || fields[0] == "" {
+			return nil, fmt.Errorf("invalid chaos rule %q: expected kind=pattern:value", part)
+		}
+		if _, err := path.Match(fields[0], ""); err != nil {
+			return nil, fmt.Errorf("invalid chaos pattern %q: %w", fields[0], err)
+		}
+
+		rule := ChaosRule{Kind: ChaosKind(kind), Pattern: fields[0], Probability: 1}
+		var err error
+		switch rule.Kind {
+		case ChaosFail:
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("invalid chaos rule %q: expected fail-step=pattern:probability", part)
+			}
+			rule.Probability, err = parseProbability(fields[1])
+		case ChaosDelay:
+			if len(fields) > 3 {
+				return nil, fmt.Errorf("invalid chaos rule %q: expected delay=pattern:duration[:probability]", part)
+			}
+			rule.Delay, err = time.ParseDuration(fields[1])
+			if err == nil && len(fields) == 3 {
+				rule.Probability, err = parseProbability(fields[2])
+			}
+		default:
+			return nil, fmt.Errorf("unknown chaos kind %q", kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid chaos rule %q: %w", part, err)
+		}
+
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseProbability(s string) (float64, error) {
+	p, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if p < 0 || p > 1 {
+		return 0, fmt.Errorf("probability %v must be between 0 and 1", p)
+	}
+	return p, nil
+}
+
+// withChaos applies the chaos rules matching a step before running fn, an attempt at the step,
+// so that an injected failure is retried like any other
+func (r *Runner) withChaos(stage string, step *dsl.Step, fn func() error) error {
+	if err := r.injectChaos(stage, step.Name); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// injectChaos applies the matching chaos rules to a step before it executes, in the order of
+// the specification; an injected failure skips the rules after it and prevents the step from running.
+func (r *Runner) injectChaos(stage, step string) error {
+	id := r.stepID(stage, step)
+	for _, rule := range r.Chaos {
+		if ok, _ := path.Match(rule.Pattern, id); !ok {
+			continue
+		}
+		if r.Random() >= rule.Probability {
+			continue
+		}
+
+		switch rule.Kind {
+		case ChaosDelay:
+			fmt.Fprintf(r.Out, "  [CHAOS] Injecting delay of %s\n", rule.Delay)
+			if err := r.sleep(rule.Delay); err != nil {
+				return err
+			}
+		case ChaosFail:
+			fmt.Fprintf(r.Out, "  [CHAOS] Injecting failure\n")
+			return ErrChaosInjected
+		}
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestParseChaos(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []ChaosRule
+		wantErr bool
+	}{
+		{
+			name: "fail and delay rules",
+			spec: "fail-step=deploy.push:0.3,delay=test.*:5s",
+			want: []ChaosRule{
+				{Kind: ChaosFail, Pattern: "deploy.push", Probability: 0.3},
+				{Kind: ChaosDelay, Pattern: "test.*", Probability: 1, Delay: 5 * time.Second},
+			},
+		},
+		{
+			name: "delay with probability",
+			spec: "delay=*.*:1m:0.5",
+			want: []ChaosRule{{Kind: ChaosDelay, Pattern: "*.*", Probability: 0.5, Delay: time.Minute}},
+		},
+		{name: "empty spec", spec: "", want: nil},
+		{name: "unknown kind", spec: "explode=a.b:1", wantErr: true},
+		{name: "missing value", spec: "fail-step=a.b", wantErr: true},
+		{name: "probability out of range", spec: "fail-step=a.b:1.5", wantErr: true},
+		{name: "invalid duration", spec: "delay=a.b:soon", wantErr: true},
+		{name: "invalid pattern", spec: "fail-step=[:0.5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseChaos(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseChaos() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseChaos() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunner_Run_Chaos(t *testing.T) {
+	workflow := []dsl.Stage{
+		{Name: "test", Steps: []dsl.Step{{Name: "unit", Type: dsl.StepTypeExec, Run: []string{"go", "test"}}}},
+		{Name: "deploy", Steps: []dsl.Step{{Name: "push", Type: dsl.StepTypeExec, Run: []string{"docker", "push"}}}},
+	}
+
+	tests := []struct {
+		name       string
+		spec       string
+		random     float64
+		wantErr    error
+		wantCalls  int
+		wantSleeps []time.Duration
+	}{
+		{
+			name:       "delay and failure triggered",
+			spec:       "delay=test.*:5s,fail-step=deploy.push:0.3",
+			random:     0.1,
+			wantErr:    ErrChaosInjected,
+			wantCalls:  1,
+			wantSleeps: []time.Duration{5 * time.Second},
+		},
+		{
+			name:      "rules apply in order, a failure skips the delay after it",
+			spec:      "fail-step=test.unit:1,delay=test.*:5s",
+			random:    0.1,
+			wantErr:   ErrChaosInjected,
+			wantCalls: 0,
+		},
+		{
+			name:      "failure not triggered above probability",
+			spec:      "fail-step=deploy.push:0.3",
+			random:    0.5,
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cmdCalls [][]string
+			var sleepCalls []time.Duration
+
+			rules, err := ParseChaos(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseChaos() failed: %v", err)
+			}
+
+			runner, err := NewRunner("test.yaml",
+				WithOut(new(bytes.Buffer)),
+				WithLoadWorkflow(mockLoadWorkflow(workflow)),
+				WithRunCmd(mockRunCmd(&cmdCalls)),
+				WithSleep(mockSleep(&sleepCalls)),
+				WithChaos(rules),
+				WithRandom(func() float64 { return tt.random }),
+			)
+			if err != nil {
+				t.Fatalf("NewRunner() failed: %v", err)
+			}
+
+			err = runner.Run()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(cmdCalls) != tt.wantCalls {
+				t.Errorf("expected %d command calls, got %d", tt.wantCalls, len(cmdCalls))
+			}
+			if !slices.Equal(sleepCalls, tt.wantSleeps) {
+				t.Errorf("sleep calls = %v, want %v", sleepCalls, tt.wantSleeps)
+			}
+		})
+	}
+}
+
+func TestRunner_Run_ChaosRetried(t *testing.T) {
+	retries := 1
+	workflow := []dsl.Stage{{Name: "deploy", Steps: []dsl.Step{
+		{Name: "push", Type: dsl.StepTypeExec, Run: []string{"docker", "push"}, Retries: &retries},
+	}}}
+	rules, err := ParseChaos("fail-step=deploy.push:0.5")
+	if err != nil {
+		t.Fatalf("ParseChaos() failed: %v", err)
+	}
+	// the first attempt draws below the probability and fails, the retry draws above it
+	draws := []float64{0.1, 0.9}
+	var cmdCalls [][]string
+	out := new(bytes.Buffer)
+	runner, err := NewRunner("test.yaml",
+		WithOut(out),
+		WithLoadWorkflow(mockLoadWorkflow(workflow)),
+		WithRunCmd(mockRunCmd(&cmdCalls)),
+		WithChaos(rules),
+		WithRandom(func() float64 {
+			d := draws[0]
+			draws = draws[1:]
+			return d
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+
+	if err := runner.Run(); err != nil {
+		t.Fatalf("Run() error: %v, want the retry to recover from the injected failure", err)
+	}
+	if len(cmdCalls) != 1 {
+		t.Errorf("expected 1 command call, got %d", len(cmdCalls))
+	}
+	if want := "Step 'push' failed (attempt 1/2), retrying: " + ErrChaosInjected.Error(); !strings.Contains(out.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, out.String())
+	}
+}
+
+func TestRunner_Run_ChaosInGroup(t *testing.T) {
+	workflow := []dsl.Stage{{Name: "build", Steps: []dsl.Step{{Name: "checks", Type: dsl.StepTypeGroup, Steps: []dsl.Step{
+		{Name: "lint", Type: dsl.StepTypeExec, Run: []string{"lint"}},
+		{Name: "vet", Type: dsl.StepTypeExec, Run: []string{"vet"}},
+	}}}}}
+	tests := []struct {
+		spec      string
+		wantErr   error
+		wantCalls int
+	}{
+		{spec: "fail-step=build.checks.lint:1", wantErr: ErrChaosInjected},
+		{spec: "fail-step=build.lint:1", wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			rules, err := ParseChaos(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseChaos() failed: %v", err)
+			}
+			var cmdCalls [][]string
+			runner, err := NewRunner("test.yaml", WithOut(new(bytes.Buffer)), WithLoadWorkflow(mockLoadWorkflow(workflow)),
+				WithRunCmd(mockRunCmd(&cmdCalls)), WithChaos(rules), WithRandom(func() float64 { return 0 }))
+			if err != nil {
+				t.Fatalf("NewRunner() failed: %v", err)
+			}
+			if err := runner.Run(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(cmdCalls) != tt.wantCalls {
+				t.Errorf("expected %d command calls, got %d", tt.wantCalls, len(cmdCalls))
+			}
+		})
+	}
+}
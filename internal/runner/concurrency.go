@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/andre-koe/forge/internal/active"
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// concurrencyPoll is how often a run queued in its concurrency group checks whether the runs
+// ahead of it finished
+const concurrencyPoll = time.Second
+
+// resolveConcurrency interpolates the concurrency group of the workflow for the current run
+func (r *Runner) resolveConcurrency(wf *dsl.Workflow) error {
+	r.concurrency = ""
+	if wf.Concurrency == nil || r.OnAgent {
+		return nil
+	}
+	group, err := r.interpolate([]string{wf.Concurrency.Group})
+	if err != nil {
+		return fmt.Errorf("concurrency: %w", err)
+	}
+	r.concurrency = group[0]
+	return nil
+}
+
+// awaitConcurrency waits until the runs of the current run's concurrency group that started
+// before it finished, after cancelling them with cancel_in_progress. Runs find each other in
+// the registry of runs in progress; without one the group has no effect.
+func (r *Runner) awaitConcurrency(wf *dsl.Workflow) error {
+	if r.concurrency == "" || r.Active == nil {
+		return nil
+	}
+	cancelled := make(map[string]bool)
+	waiting := ""
+	for {
+		ahead, err := r.runsAhead()
+		if err != nil {
+			return fmt.Errorf("concurrency group %s: %w", r.concurrency, err)
+		}
+		if len(ahead) == 0 {
+			return nil
+		}
+		for _, e := range ahead {
+			// Runs of this process without a server API would only be cancelled with the process itself
+			if !wf.Concurrency.CancelInProgress || cancelled[e.ID] || (e.CancelURL == "" && e.PID == os.Getpid()) {
+				continue
+			}
+			cancelled[e.ID] = true
+			fmt.Fprintf(r.Out, "Cancelling run %s of concurrency group %s\n", e.ID, r.concurrency)
+			if err := e.Cancel(r.ctx, r.HTTPClient); err != nil {
+				fmt.Fprintf(r.Out, "Warning: failed to cancel run %s: %v\n", e.ID, err)
+			}
+		}
+		if last := ahead[len(ahead)-1]; last.ID != waiting {
+			waiting = last.ID
+			fmt.Fprintf(r.Out, "Waiting for run %s of concurrency group %s to finish\n", last.ID, r.concurrency)
+		}
+		if r.sleep(concurrencyPoll) != nil {
+			return fmt.Errorf("concurrency group %s: %w", r.concurrency, ErrCancelled)
+		}
+	}
+}
+
+// runsAhead returns the runs in progress of the current run's concurrency group that started
+// before it, oldest first
+func (r *Runner) runsAhead() ([]*active.Entry, error) {
+	entries, err := r.Active.List()
+	if err != nil {
+		return nil, err
+	}
+	var ahead []*active.Entry
+	for _, e := range entries {
+		if e.Group != r.concurrency || e.ID == r.record.ID {
+			continue
+		}
+		if e.StartedAt.Before(r.record.StartedAt) || (e.StartedAt.Equal(r.record.StartedAt) && e.ID < r.record.ID) {
+			ahead = append(ahead, e)
+		}
+	}
+	return ahead, nil
+}
@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/active"
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_Concurrency(t *testing.T) {
+	stages := []dsl.Stage{{Name: "deploy", Steps: []dsl.Step{{Name: "push", Type: dsl.StepTypeExec, Run: dsl.Command{"push"}}}}}
+	load := func(c *dsl.Concurrency) func(string) (*dsl.Workflow, error) {
+		return func(string) (*dsl.Workflow, error) {
+			return &dsl.Workflow{Name: "deploy", Vars: map[string]string{"env": "staging"}, Concurrency: c, Stages: stages}, nil
+		}
+	}
+	started := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name        string
+		concurrency *dsl.Concurrency
+		group       string
+		cancel      bool
+		wantWaits   int
+		wantOut     []string
+	}{
+		{
+			name:        "queues behind a run of its group",
+			concurrency: &dsl.Concurrency{Group: "deploy-${{ vars.env }}"},
+			group:       "deploy-staging",
+			wantWaits:   1,
+			wantOut:     []string{"Waiting for run earlier of concurrency group deploy-staging to finish\n"},
+		},
+		{
+			name:        "cancels a run of its group",
+			concurrency: &dsl.Concurrency{Group: "deploy-${{ vars.env }}", CancelInProgress: true},
+			group:       "deploy-staging",
+			cancel:      true,
+			wantWaits:   1,
+			wantOut:     []string{"Cancelling run earlier of concurrency group deploy-staging\n", "Waiting for run earlier"},
+		},
+		{
+			name:        "ignores runs of other groups",
+			concurrency: &dsl.Concurrency{Group: "deploy-${{ vars.env }}"},
+			group:       "deploy-prod",
+		},
+		{
+			name:  "ignores runs without a concurrency group",
+			group: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := active.NewRegistry(filepath.Join(t.TempDir(), "active"))
+			var cancelled bool
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				cancelled = req.Method == http.MethodDelete && req.URL.Path == "/runs/earlier"
+				reg.Remove("earlier")
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer srv.Close()
+
+			earlier := active.Entry{ID: "earlier", Workflow: "deploy.yaml", PID: os.Getpid(), StartedAt: started, Group: tt.group}
+			if tt.cancel {
+				earlier.CancelURL = srv.URL + "/runs/earlier"
+			}
+			if err := reg.Add(earlier); err != nil {
+				t.Fatal(err)
+			}
+
+			waits := 0
+			sleep := func(time.Duration) {
+				// The run ahead finishes while the new run waits for it
+				waits++
+				reg.Remove("earlier")
+			}
+			var ahead []*active.Entry
+			runCmd := func([]string) error {
+				var err error
+				ahead, err = reg.List()
+				return err
+			}
+			var out bytes.Buffer
+			r, err := NewRunner("deploy.yaml", WithOut(&out), WithLoadWorkflow(load(tt.concurrency)), WithRunCmd(runCmd),
+				WithActive(reg), WithRunID("later"), WithSleep(sleep), WithHTTPClient(srv.Client()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Run(); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+
+			if waits != tt.wantWaits {
+				t.Errorf("waited %d time(s), want %d", waits, tt.wantWaits)
+			}
+			if cancelled != tt.cancel {
+				t.Errorf("cancelled the run ahead = %v, want %v", cancelled, tt.cancel)
+			}
+			if tt.wantWaits > 0 {
+				for _, e := range ahead {
+					if e.ID == "earlier" {
+						t.Error("the step ran while the run ahead was in progress")
+					}
+				}
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package runner
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// setCredential fails if a user or group is set; this platform cannot switch users for a process
+func setCredential(cmd *exec.Cmd, userName, groupName string) error {
+	if userName == "" && groupName == "" {
+		return nil
+	}
+	return errors.New("user and group are only supported on Unix-like systems")
+}
@@ -0,0 +1,88 @@
+//go:build unix
+
+package runner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// setCredential makes cmd run as the named user and group, given as names or numeric ids. A
+// user alone also selects its primary and supplementary groups; a group alone keeps forge's user.
+func setCredential(cmd *exec.Cmd, userName, groupName string) error {
+	if userName == "" && groupName == "" {
+		return nil
+	}
+	cred, err := lookupCredential(userName, groupName)
+	if err != nil {
+		return err
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = cred
+	return nil
+}
+
+func lookupCredential(userName, groupName string) (*syscall.Credential, error) {
+	cred := &syscall.Credential{Uid: uint32(os.Getuid()), Gid: uint32(os.Getgid())}
+	if userName != "" {
+		u, err := lookupUser(userName)
+		if err != nil {
+			return nil, err
+		}
+		cred.Uid, err = parseID(u.Uid)
+		if err != nil {
+			return nil, fmt.Errorf("user %s: %w", userName, err)
+		}
+		if cred.Gid, err = parseID(u.Gid); err != nil {
+			return nil, fmt.Errorf("user %s: %w", userName, err)
+		}
+		ids, _ := u.GroupIds()
+		for _, id := range ids {
+			if gid, err := parseID(id); err == nil {
+				cred.Groups = append(cred.Groups, gid)
+			}
+		}
+	}
+	if groupName != "" {
+		g, err := user.LookupGroup(groupName)
+		if err != nil {
+			if g, err = user.LookupGroupId(groupName); err != nil {
+				return nil, fmt.Errorf("unknown group %q", groupName)
+			}
+		}
+		if cred.Gid, err = parseID(g.Gid); err != nil {
+			return nil, fmt.Errorf("group %s: %w", groupName, err)
+		}
+		cred.Groups = nil
+	}
+	if userName == "" {
+		// Only the group changes; the supplementary groups stay forge's
+		cred.NoSetGroups = true
+	}
+	return cred, nil
+}
+
+// lookupUser finds a user by name or numeric id; ids without a passwd entry run with forge's group
+func lookupUser(name string) (*user.User, error) {
+	if u, err := user.Lookup(name); err == nil {
+		return u, nil
+	}
+	if u, err := user.LookupId(name); err == nil {
+		return u, nil
+	}
+	if _, err := parseID(name); err == nil {
+		return &user.User{Uid: name, Gid: strconv.Itoa(os.Getgid())}, nil
+	}
+	return nil, fmt.Errorf("unknown user %q", name)
+}
+
+func parseID(s string) (uint32, error) {
+	id, err := strconv.ParseUint(s, 10, 32)
+	return uint32(id), err
+}
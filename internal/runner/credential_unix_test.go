@@ -0,0 +1,76 @@
+//go:build unix
+
+package runner
+
+import (
+	"bytes"
+	"os"
+	"os/user"
+	"strconv"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestLookupCredential(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+	uid, _ := strconv.Atoi(current.Uid)
+	gid, _ := strconv.Atoi(current.Gid)
+
+	tests := []struct {
+		name        string
+		user, group string
+		wantUID     int
+		wantGID     int
+		wantErr     bool
+	}{
+		{name: "user by name", user: current.Username, wantUID: uid, wantGID: gid},
+		{name: "user by id", user: current.Uid, wantUID: uid, wantGID: gid},
+		{name: "group only", group: current.Gid, wantUID: os.Getuid(), wantGID: gid},
+		{name: "unknown user", user: "forge-no-such-user", wantErr: true},
+		{name: "unknown group", group: "forge-no-such-group", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cred, err := lookupCredential(tt.user, tt.group)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lookupCredential() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if int(cred.Uid) != tt.wantUID || int(cred.Gid) != tt.wantGID {
+				t.Errorf("lookupCredential() = %d:%d, want %d:%d", cred.Uid, cred.Gid, tt.wantUID, tt.wantGID)
+			}
+			if tt.user == "" && !cred.NoSetGroups {
+				t.Error("lookupCredential() with a group only replaces the supplementary groups")
+			}
+		})
+	}
+}
+
+func TestRunner_Run_User(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("switching users needs root")
+	}
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	var out bytes.Buffer
+	stages := []dsl.Stage{{Name: "s", Steps: []dsl.Step{{Name: "id", Type: dsl.StepTypeExec, Run: []string{"id", "-u"}, User: "nobody"}}}}
+	r, err := NewRunner("test.yaml", WithOut(new(bytes.Buffer)), WithProcessOutput(&out, &out), WithLoadWorkflow(mockLoadWorkflow(stages)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if got := out.String(); got != nobody.Uid+"\n" {
+		t.Errorf("id -u = %q, want %q", got, nobody.Uid+"\n")
+	}
+}
@@ -0,0 +1,40 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// runCommandLine returns the command an exec step runs: its run split into arguments, or its
+// run joined into a script for its shell
+func runCommandLine(wf *dsl.Workflow, step *dsl.Step) []string {
+	shell := wf.StepShell(step)
+	if shell == "" {
+		return step.Argv()
+	}
+	return append(strings.Fields(shell), "-c", strings.Join(step.Run, " "))
+}
+
+// withStepTimeout runs fn with the step's timeout bounding each of its commands
+func (r *Runner) withStepTimeout(step *dsl.Step, fn func() error) error {
+	prev := r.timeout
+	r.timeout = r.wf.StepTimeout(step)
+	defer func() { r.timeout = prev }()
+	return fn()
+}
+
+// withRetries runs fn, and again after it failed as often as the step's retries allow; a
+// cancelled run is not retried
+func (r *Runner) withRetries(step *dsl.Step, fn func() error) error {
+	retries := r.wf.StepRetries(step)
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt > retries || errors.Is(err, ErrCancelled) || r.ctx.Err() != nil {
+			return err
+		}
+		fmt.Fprintf(r.Out, "  Step '%s' failed (attempt %d/%d), retrying: %v\n", step.Name, attempt, retries+1, err)
+	}
+}
@@ -0,0 +1,164 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_Defaults(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "ci.yaml")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	zero := 0
+	load := func(string) (*dsl.Workflow, error) {
+		return &dsl.Workflow{
+			Name:     "ci",
+			Defaults: &dsl.Defaults{Shell: "sh -e", Workdir: "src", Env: map[string]string{"TIER": "default", "REGION": "eu"}},
+			Stages: []dsl.Stage{{Name: "build", Env: map[string]string{"REGION": "us"}, Steps: []dsl.Step{
+				{Name: "script", Type: dsl.StepTypeExec, Run: []string{"echo", "$TIER", "$REGION", "$(basename", "$PWD)"}},
+				{Name: "direct", Type: dsl.StepTypeExec, Shell: dsl.ShellNone, Workdir: ".", Env: map[string]string{"TIER": "step"}, Retries: &zero,
+					Run: []string{"sh", "-c", `echo "$TIER $REGION $(basename "$PWD")"`}},
+			}}},
+		}, nil
+	}
+	var out bytes.Buffer
+	r, err := NewRunner(path, WithOut(new(bytes.Buffer)), WithProcessOutput(&out, &out), WithLoadWorkflow(load))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	want := "default us src\nstep us " + filepath.Base(dir) + "\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	p, err := r.ResolvePlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.Stages[0].Steps[0].Argv, []string{"sh", "-e", "-c", "echo $TIER $REGION $(basename $PWD)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("planned argv = %q, want %q", got, want)
+	}
+}
+
+func TestRunner_StepRetries(t *testing.T) {
+	two := 2
+	tests := []struct {
+		name      string
+		defaults  *dsl.Defaults
+		retries   *int
+		failures  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "no retries", failures: 1, wantCalls: 1, wantErr: true},
+		{name: "default retries", defaults: &dsl.Defaults{Retries: 3}, failures: 2, wantCalls: 3},
+		{name: "step overrides", defaults: &dsl.Defaults{Retries: 5}, retries: &two, failures: 5, wantCalls: 3, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			load := func(string) (*dsl.Workflow, error) {
+				return &dsl.Workflow{Name: "flaky", Defaults: tt.defaults, Stages: []dsl.Stage{{Name: "test", Steps: []dsl.Step{
+					{Name: "e2e", Type: dsl.StepTypeExec, Run: []string{"make", "e2e"}, Retries: tt.retries},
+				}}}}, nil
+			}
+			var out bytes.Buffer
+			calls := 0
+			r, err := NewRunner("flaky.yaml", WithOut(&out), WithLoadWorkflow(load),
+				WithCommandFunc(func([]string, io.Writer, io.Writer) error {
+					calls++
+					if calls <= tt.failures {
+						return errors.New("exit status 1")
+					}
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = r.Run()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantCalls > 1 && !strings.Contains(out.String(), "Step 'e2e' failed (attempt 1/") {
+				t.Errorf("output missing the retry:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestRunner_StepTimeout(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	stages := []dsl.Stage{{Name: "slow", Steps: []dsl.Step{
+		{Name: "hang", Type: dsl.StepTypeExec, Timeout: "100ms", Run: []string{"sleep", "5"}},
+	}}}
+	r, err := NewRunner("test.yaml", WithOut(new(bytes.Buffer)), WithLoadWorkflow(mockLoadWorkflow(stages)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := r.Run(); err == nil {
+		t.Fatal("Run() succeeded, want the timeout to stop the command")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Run() took %s, want the command stopped after 100ms", elapsed)
+	}
+
+	var out bytes.Buffer
+	r, err = NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)), WithMode(ModeDryRun))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if want := "[DRY-RUN]   Would time out each command after 100ms\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, out.String())
+	}
+}
+
+func TestRunCommandLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		shell string
+		run   dsl.Command
+		want  []string
+	}{
+		{name: "list", run: dsl.Command{"go", "test", "./..."}, want: []string{"go", "test", "./..."}},
+		{name: "line", run: dsl.Command{`git commit -m "fix the build"`}, want: []string{"git", "commit", "-m", "fix the build"}},
+		{name: "line in a shell", shell: "bash", run: dsl.Command{`make 2>&1 | tee "build log"`}, want: []string{"bash", "-c", `make 2>&1 | tee "build log"`}},
+		{name: "list in a shell", shell: "sh -e", run: dsl.Command{"echo", "$HOME"}, want: []string{"sh", "-e", "-c", "echo $HOME"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := &dsl.Workflow{Defaults: &dsl.Defaults{Shell: tt.shell}}
+			step := &dsl.Step{Name: "cmd", Type: dsl.StepTypeExec, Run: tt.run}
+			if got := runCommandLine(wf, step); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("runCommandLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package runner
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// ExecutionMode selects what Run does with the resolved workflow
+type ExecutionMode int
+
+const (
+	// ModeExecute runs the workflow's steps
+	ModeExecute ExecutionMode = iota
+	// ModeDryRun prints what the steps would do without running anything
+	ModeDryRun
+)
+
+// WithMode sets the execution mode of Run
+func WithMode(mode ExecutionMode) Option {
+	return func(r *Runner) { r.Mode = mode }
+}
+
+// prefix starts the lines Run prints in the mode
+func (m ExecutionMode) prefix() string {
+	if m == ModeDryRun {
+		return "[DRY-RUN] "
+	}
+	return ""
+}
+
+// DryRun simulates Workflow execution; it is Run in ModeDryRun
+func (r *Runner) DryRun() error {
+	mode := r.Mode
+	r.Mode = ModeDryRun
+	defer func() { r.Mode = mode }()
+	return r.Run()
+}
+
+// simulate prints the plan of the prepared workflow
+func (r *Runner) simulate(wf *dsl.Workflow, loc *time.Location) error {
+	p, err := r.plan(wf, loc)
+	if err != nil {
+		return err
+	}
+	r.ctx = r.Context
+	for _, w := range wf.Warnings() {
+		fmt.Fprintf(r.Out, "[DRY-RUN] Warning: %s\n", w)
+	}
+	if len(r.Policies) > 0 {
+		fmt.Fprintf(r.Out, "[DRY-RUN] Would check the workflow against policies: %s\n", strings.Join(r.Policies, ", "))
+	}
+	if tools, _ := wf.RequiredTools(); len(tools) > 0 {
+		fmt.Fprintf(r.Out, "[DRY-RUN] Would check required tools: %s\n", strings.Join(wf.Requires.Tools, ", "))
+	}
+	if n := wf.Notifications; n != nil {
+		if len(n.Webhooks) > 0 {
+			fmt.Fprintf(r.Out, "[DRY-RUN] Would notify %d webhook(s)\n", len(n.Webhooks))
+		}
+		if len(n.Slack) > 0 {
+			fmt.Fprintf(r.Out, "[DRY-RUN] Would post %d Slack notification(s)\n", len(n.Slack))
+		}
+	}
+
+	for i := range p.Stages {
+		if err := r.dryRunStage(wf, &p.Stages[i]); err != nil {
+			return err
+		}
+	}
+	if err := r.dryRunHooks("", p.Hooks, wf.Hooks()); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(r.Out, "\n[DRY-RUN] ✓ Workflow simulation completed.\n")
+	return nil
+}
+
+// dryRunStage prints a planned stage and runs its steps marked dry_run: execute; like Run, it
+// leaves out stages that are not selected
+func (r *Runner) dryRunStage(wf *dsl.Workflow, ps *PlanStage) error {
+	stage := ps.stage
+	switch {
+	case !r.stageSelected(stage.Name):
+		return nil
+	case ps.Skip != "":
+		fmt.Fprintf(r.Out, "\n[DRY-RUN] === STAGE %d: %s (%s) ===\n", ps.number, ps.Name, ps.Skip)
+		r.dryRunPlatforms(ps.Platforms, "")
+		return nil
+	case ps.Finally:
+		fmt.Fprintf(r.Out, "\n[DRY-RUN] === STAGE %d: %s (finally) ===\n", ps.number, ps.Name)
+	default:
+		fmt.Fprintf(r.Out, "\n[DRY-RUN] === STAGE %d: %s ===\n", ps.number, ps.Name)
+	}
+	r.dryRunPlatforms(ps.Platforms, "")
+	r.dryRunAgent(ps.RunsOn)
+	r.dryRunRemote(ps.RunsOn)
+	r.dryRunChanges(wf, stage)
+	r.dryRunWindow(ps)
+	r.dryRunThrottle(ps.Throttle)
+	switch ps.OnError {
+	case dsl.OnErrorContinue:
+		fmt.Fprintf(r.Out, "[DRY-RUN] Would continue with the next stage if this one fails\n")
+	case dsl.OnErrorRetry:
+		fmt.Fprintf(r.Out, "[DRY-RUN] Would retry the stage up to %d time(s) if it fails\n", ps.Retries)
+	}
+	for _, name := range ps.Restore {
+		fmt.Fprintf(r.Out, "[DRY-RUN] Would restore artifacts of %s\n", name)
+	}
+
+	for i := range ps.Steps {
+		step := &ps.Steps[i]
+		fmt.Fprintf(r.Out, "[DRY-RUN] STEP %d.%d: %s (%s)\n", ps.number, i+1, step.Name, step.dryRunLabel())
+		r.dryRunPlatforms(step.Platforms, "  ")
+		if step.Skip != "" || step.DryRun == dsl.DryRunSkip {
+			continue
+		}
+		if step.Dir != "" {
+			fmt.Fprintf(r.Out, "[DRY-RUN]   Would run in %s\n", step.Dir)
+		}
+		if step.CleanEnv {
+			fmt.Fprintf(r.Out, "[DRY-RUN]   Would start from a clean environment\n")
+		}
+		if err := r.dryRunStep(stage.Name, i+1, step, &stage.Steps[i]); err != nil {
+			return fmt.Errorf("stage '%s', step '%s': %w", stage.Name, step.Name, err)
+		}
+	}
+	if len(ps.Artifacts) > 0 {
+		fmt.Fprintf(r.Out, "[DRY-RUN] Would collect artifacts: %s\n", strings.Join(ps.Artifacts, ", "))
+	}
+	if err := r.dryRunHooks(stage.Name, ps.Hooks, stage.Hooks()); err != nil {
+		return fmt.Errorf("stage '%s', %w", stage.Name, err)
+	}
+
+	fmt.Fprintf(r.Out, "[DRY-RUN] === STAGE %d COMPLETED ===\n", ps.number)
+	return nil
+}
+
+// dryRunHooks prints the planned hook steps of the named stage, or of the workflow, that would
+// run for either outcome
+func (r *Runner) dryRunHooks(stage string, planned map[string][]PlanStep, hooks map[string][]dsl.Step) error {
+	for _, kind := range dsl.HookKinds {
+		for i := range planned[kind] {
+			step := &planned[kind][i]
+			fmt.Fprintf(r.Out, "[DRY-RUN] HOOK %s: %s (%s)\n", kind, step.Name, step.dryRunLabel())
+			r.dryRunPlatforms(step.Platforms, "  ")
+			if step.Skip != "" || step.DryRun == dsl.DryRunSkip {
+				continue
+			}
+			if err := r.dryRunStep(stage, i+1, step, &hooks[kind][i]); err != nil {
+				return fmt.Errorf("%s hook '%s': %w", kind, step.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// dryRunLabel is shown next to the name of a step in dry runs
+func (ps *PlanStep) dryRunLabel() string {
+	switch {
+	case ps.Skip != "":
+		return ps.Skip
+	case ps.DryRun == dsl.DryRunSkip:
+		return "skipped in dry runs"
+	}
+	return string(ps.Type)
+}
+
+// dryRunStep prints what the planned step of the named stage, at 1-based position index among
+// its steps or hooks, would do; steps marked dry_run: execute are run instead
+func (r *Runner) dryRunStep(stage string, index int, ps *PlanStep, step *dsl.Step) error {
+	if ps.When != "" {
+		fmt.Fprintf(r.Out, "[DRY-RUN]   Would only run when %s\n", ps.When)
+	}
+	if ps.DryRun == dsl.DryRunExecute {
+		return r.dryRunExecute(stage, index, step)
+	}
+	if step.Type == dsl.StepTypeGroup {
+		return r.dryRunGroup(stage, ps, step)
+	}
+	switch step.Type {
+	case dsl.StepTypeExec:
+		for _, argv := range ps.Before {
+			fmt.Fprintf(r.Out, "[DRY-RUN]   Would run before: %v\n", argv)
+		}
+		fmt.Fprintf(r.Out, "[DRY-RUN]   Would execute command: %v\n", ps.Argv)
+		switch {
+		case ps.Stdin != "":
+			fmt.Fprintf(r.Out, "[DRY-RUN]   Would pass stdin: %q\n", ps.Stdin)
+		case ps.StdinFile != "":
+			fmt.Fprintf(r.Out, "[DRY-RUN]   Would read stdin from %s\n", ps.StdinFile)
+		}
+		if ps.StdoutFile != "" {
+			fmt.Fprintf(r.Out, "[DRY-RUN]   Would write stdout to %s\n", ps.StdoutFile)
+		}
+		if ps.StderrFile != "" {
+			fmt.Fprintf(r.Out, "[DRY-RUN]   Would write stderr to %s\n", ps.StderrFile)
+		}
+		if ps.TTY {
+			fmt.Fprintf(r.Out, "[DRY-RUN]   Would run it in a pseudo-terminal\n")
+		}
+		if ps.User != "" || ps.Group != "" {
+			fmt.Fprintf(r.Out, "[DRY-RUN]   Would run it as %s\n", strings.Trim(ps.User+":"+ps.Group, ":"))
+		}
+		for _, argv := range ps.After {
+			fmt.Fprintf(r.Out, "[DRY-RUN]   Would run after: %v\n", argv)
+		}
+		if ps.Timeout != commandTimeout.String() {
+			fmt.Fprintf(r.Out, "[DRY-RUN]   Would time out each command after %s\n", ps.Timeout)
+		}
+	case dsl.StepTypeSleep:
+		fmt.Fprintf(r.Out, "[DRY-RUN]   Would sleep for %d seconds\n", step.Seconds)
+	case dsl.StepTypeGoTest:
+		fmt.Fprintf(r.Out, "[DRY-RUN]   Would run go test for packages changed since %s\n", goTestBase(step))
+	case dsl.StepTypeSnapshot:
+		fmt.Fprintf(r.Out, "[DRY-RUN]   Would snapshot %s\n", step.Path)
+	case dsl.StepTypeRestore:
+		fmt.Fprintf(r.Out, "[DRY-RUN]   Would restore snapshot %s\n", step.Snapshot)
+	case dsl.StepTypeSlack:
+		fmt.Fprintf(r.Out, "[DRY-RUN]   Would post to Slack\n")
+	case dsl.StepTypeApproval:
+		timeout := DefaultApprovalTimeout.String()
+		if step.Approval != nil && step.Approval.Timeout != "" {
+			timeout = step.Approval.Timeout
+		}
+		fmt.Fprintf(r.Out, "[DRY-RUN]   Would wait up to %s for approval\n", timeout)
+	case dsl.StepTypeAssert:
+		fmt.Fprintf(r.Out, "[DRY-RUN]   Would assert on command: %v\n", ps.Argv)
+	default:
+		fmt.Fprintf(r.Out, "[DRY-RUN]   Would run a %s step\n", step.Type)
+	}
+	if ps.Retries > 0 {
+		fmt.Fprintf(r.Out, "[DRY-RUN]   Would retry the step up to %d time(s) if it fails\n", ps.Retries)
+	}
+	for _, name := range slices.Sorted(maps.Keys(ps.Ports)) {
+		fmt.Fprintf(r.Out, "[DRY-RUN]   Would allocate port %s (%s) as %s\n", name, ps.Ports[name], PortEnv(name))
+	}
+	if ps.Cache != nil {
+		fmt.Fprintf(r.Out, "[DRY-RUN]   Would skip if unchanged since its last successful run: %s\n", strings.Join(ps.Cache.KeyFiles, ", "))
+	}
+	return nil
+}
+
+// dryRunExecute runs an exec step marked dry_run: execute as a run would, its before and after
+// commands included; when clauses are not evaluated
+func (r *Runner) dryRunExecute(stage string, index int, step *dsl.Step) error {
+	fmt.Fprintf(r.Out, "[DRY-RUN]   Executing, marked dry_run: %s\n", dsl.DryRunExecute)
+	r.current = currentStep{stage: stage, step: step.Name, index: index}
+	defer func() { r.current = currentStep{} }()
+	return r.withStepDir(stage, step, func() error {
+		return r.withStepEnv(stage, step, func() error {
+			return r.withStepOutput(stage, step.Name, func() error {
+				return r.withStepTimeout(step, func() error { return r.runExec(step) })
+			})
+		})
+	})
+}
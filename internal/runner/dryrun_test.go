@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_Run_DryRunMode(t *testing.T) {
+	stages := []dsl.Stage{
+		{Name: "build", Steps: []dsl.Step{
+			{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"go", "build", "${{ env.FORGE_STEP }}"}},
+			{Name: "lint", Type: dsl.StepTypeExec, Run: []string{"golangci-lint", "run"}},
+		}},
+		{Name: "deploy", Steps: []dsl.Step{{Name: "push", Type: dsl.StepTypeExec, Run: []string{"push"}}}},
+	}
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "all stages",
+			want: []string{"[DRY-RUN] Would execute workflow: test.yaml", "[DRY-RUN] STEP 1.1: compile (exec)", "Would execute command: [go build compile]", "=== STAGE 2: deploy ===", "Workflow simulation completed"},
+		},
+		{
+			name:    "selected stages and skipped steps",
+			opts:    []Option{WithStages("build"), WithSkipSteps("build.lint")},
+			want:    []string{"STEP 1.2: lint (skipped with --skip-step)"},
+			notWant: []string{"deploy", "golangci-lint"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls [][]string
+			var out bytes.Buffer
+			opts := append([]Option{WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(mockRunCmd(&calls)), WithMode(ModeDryRun)}, tt.opts...)
+			r, err := NewRunner("test.yaml", opts...)
+			if err != nil {
+				t.Fatalf("NewRunner() failed: %v", err)
+			}
+			if err := r.Run(); err != nil {
+				t.Fatalf("Run() failed: %v", err)
+			}
+			if len(calls) != 0 || r.Record() != nil {
+				t.Errorf("Run() in ModeDryRun ran commands %v or recorded the run", calls)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output does not contain %q:\n%s", want, out.String())
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out.String(), notWant) {
+					t.Errorf("output contains %q:\n%s", notWant, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRunner_DryRunMarkers(t *testing.T) {
+	stages := []dsl.Stage{{Name: "infra", Steps: []dsl.Step{
+		{Name: "plan", Type: dsl.StepTypeExec, DryRun: dsl.DryRunExecute, Run: []string{"terraform", "plan", "${{ env.FORGE_DRY_RUN }}"}},
+		{Name: "notify", Type: dsl.StepTypeExec, DryRun: dsl.DryRunSkip, Run: []string{"notify"}},
+		{Name: "apply", Type: dsl.StepTypeExec, Run: []string{"terraform", "apply"}},
+	}}}
+
+	tests := []struct {
+		name    string
+		runErr  error
+		wantErr bool
+	}{
+		{name: "executed step succeeds"},
+		{name: "executed step fails", runErr: errors.New("exit status 1"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			var calls [][]string
+			runCmd := func(argv []string) error {
+				calls = append(calls, argv)
+				return tt.runErr
+			}
+			r, err := NewRunner("test.yaml", WithOut(&out), WithMode(ModeDryRun), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(runCmd))
+			if err != nil {
+				t.Fatalf("NewRunner() error: %v", err)
+			}
+			err = r.Run()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if want := [][]string{{"terraform", "plan", "true"}}; !reflect.DeepEqual(calls, want) {
+				t.Errorf("calls = %v, want %v", calls, want)
+			}
+			if tt.wantErr {
+				return
+			}
+			for _, want := range []string{
+				"Executing, marked dry_run: execute",
+				"STEP 1.2: notify (skipped in dry runs)",
+				"Would execute command: [terraform apply]",
+			} {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+			if strings.Contains(out.String(), "[notify]") {
+				t.Errorf("output describes the skipped step:\n%s", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package runner
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dotenv"
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/expr"
+)
+
+// WithEnvFiles loads the given dotenv files after the workflow's env_file; later files win
+func WithEnvFiles(paths ...string) Option {
+	return func(r *Runner) { r.EnvFiles = paths }
+}
+
+// loadEnvFiles reads the workflow's env_file and the runner's EnvFiles into fileEnv
+func (r *Runner) loadEnvFiles(wf *dsl.Workflow, prefix string) error {
+	r.fileEnv = nil
+	paths := r.EnvFiles
+	if wf.EnvFile != "" {
+		paths = append([]string{wf.EnvFile}, paths...)
+	}
+	for _, p := range paths {
+		vars, err := dotenv.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("env file: %w", err)
+		}
+		if r.fileEnv == nil {
+			r.fileEnv = make(map[string]string)
+		}
+		maps.Copy(r.fileEnv, vars)
+		fmt.Fprintf(r.Out, "%sLoaded %d variable(s) from %s\n", prefix, len(vars), p)
+	}
+	return nil
+}
+
+// Variables describing the current step, set in the environment of its processes
+const (
+	EnvRunID     = "FORGE_RUN_ID"
+	EnvWorkflow  = "FORGE_WORKFLOW"
+	EnvStage     = "FORGE_STAGE"
+	EnvStep      = "FORGE_STEP"
+	EnvStepIndex = "FORGE_STEP_INDEX"
+	EnvDryRun    = "FORGE_DRY_RUN"
+	EnvWorkdir   = "FORGE_WORKDIR"
+)
+
+// currentStep identifies the step being executed; stage is empty for workflow hooks
+type currentStep struct {
+	stage, step string
+	// index is the 1-based position of the step among the steps or hooks of its kind
+	index int
+}
+
+// contextEnv returns the FORGE_* variables describing the current step
+func (r *Runner) contextEnv() []string {
+	if r.current.step == "" {
+		return nil
+	}
+	workflow := ""
+	if r.wf != nil {
+		workflow = r.wf.Name
+	}
+	workdir := r.processDir()
+	if abs, err := filepath.Abs(cmp.Or(workdir, ".")); err == nil {
+		workdir = abs
+	}
+	return []string{
+		EnvRunID + "=" + r.RunID(),
+		EnvWorkflow + "=" + workflow,
+		EnvStage + "=" + r.current.stage,
+		EnvStep + "=" + r.current.step,
+		EnvStepIndex + "=" + strconv.Itoa(r.current.index),
+		EnvDryRun + "=" + strconv.FormatBool(r.Mode == ModeDryRun),
+		EnvWorkdir + "=" + workdir,
+	}
+}
+
+// commandEnv returns the variables added to the inherited environment of processes started by
+// the current step, starting with the FORGE_* context variables; later entries win. With
+// clean_env they are the whole environment.
+func (r *Runner) commandEnv() []string {
+	env := r.contextEnv()
+	for _, vars := range []map[string]string{r.profileEnv, r.Env, r.fileEnv, r.stepEnv} {
+		for _, k := range slices.Sorted(maps.Keys(vars)) {
+			env = append(env, k+"="+vars[k])
+		}
+	}
+	return append(env, r.env...)
+}
+
+// withStepEnv runs fn with the env: entries of the defaults, the named stage and the step, the
+// step's winning, and with clean_env of the stage or step in effect. Values are interpolated
+// against the environment the step would inherit, so ${{ env.PATH }} passes a variable through
+// a clean environment.
+func (r *Runner) withStepEnv(stage string, step *dsl.Step, fn func() error) error {
+	vars := make(map[string]string)
+	if r.wf != nil && r.wf.Defaults != nil {
+		maps.Copy(vars, r.wf.Defaults.Env)
+	}
+	clean := step.CleanEnv
+	if s := r.findStage(stage); s != nil {
+		clean = clean || s.CleanEnv
+		maps.Copy(vars, s.Env)
+	}
+	maps.Copy(vars, step.Env)
+	if len(vars) == 0 && !clean {
+		return fn()
+	}
+
+	ctx := r.exprContext(false)
+	for k, v := range vars {
+		expanded, err := expr.Interpolate(v, ctx)
+		if err != nil {
+			return fmt.Errorf("env %s: %w", k, err)
+		}
+		vars[k] = expanded
+	}
+	prevEnv, prevClean := r.stepEnv, r.cleanEnv
+	r.stepEnv, r.cleanEnv = vars, clean
+	defer func() { r.stepEnv, r.cleanEnv = prevEnv, prevClean }()
+	return fn()
+}
+
+// exprContext returns what ${{ }} expressions see: the workflow variables and the environment of
+// the current step's processes, without the inherited environment when clean is set. Files named
+// in expressions are relative to the directory the processes start in.
+func (r *Runner) exprContext(clean bool) expr.Context {
+	env := make(map[string]string)
+	inherited := os.Environ()
+	if clean {
+		inherited = nil
+	}
+	for _, kv := range slices.Concat(inherited, r.commandEnv()) {
+		k, v, _ := strings.Cut(kv, "=")
+		env[k] = v
+	}
+	ctx := expr.Context{Env: env, Vars: r.vars, Dir: r.processDir(), Steps: r.stepResults}
+	if r.Clock != nil {
+		now, loc := r.Clock.Now, r.loc
+		ctx.Now = now
+		if loc != nil {
+			ctx.Now = func() time.Time { return now().In(loc) }
+		}
+	}
+	return ctx
+}
+
+// interpolate expands ${{ }} expressions in argv against the workflow variables and the environment the current step's processes see
+func (r *Runner) interpolate(argv []string) ([]string, error) {
+	return expr.InterpolateAll(argv, r.exprContext(r.cleanEnv))
+}
@@ -0,0 +1,236 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/clock"
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_EnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	wfEnv := filepath.Join(dir, ".env")
+	cliEnv := filepath.Join(dir, "override.env")
+	if err := os.WriteFile(wfEnv, []byte("HOST=db.local\nPORT=5432\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cliEnv, []byte("PORT=6543\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	load := func(string) (*dsl.Workflow, error) {
+		return &dsl.Workflow{Name: "env", EnvFile: wfEnv, Stages: []dsl.Stage{
+			{Name: "db", Steps: []dsl.Step{
+				{Name: "connect", Type: dsl.StepTypeExec, Run: []string{"psql", "${{ env.HOST }}:${{ env.PORT }}"}},
+			}},
+		}}, nil
+	}
+
+	var calls, envs [][]string
+	var r *Runner
+	out := new(bytes.Buffer)
+	r, err := NewRunner("test.yaml",
+		WithOut(out),
+		WithLoadWorkflow(load),
+		WithEnvFiles(cliEnv),
+		WithRunCmd(func(argv []string) error {
+			calls = append(calls, argv)
+			envs = append(envs, r.commandEnv()[len(r.contextEnv()):])
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	if want := [][]string{{"psql", "db.local:6543"}}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("commands = %v, want %v", calls, want)
+	}
+	if want := [][]string{{"HOST=db.local", "PORT=6543"}}; !reflect.DeepEqual(envs, want) {
+		t.Errorf("env = %v, want %v", envs, want)
+	}
+	if !strings.Contains(out.String(), "Loaded 1 variable(s) from "+cliEnv) {
+		t.Errorf("output does not report the loaded env file:\n%s", out.String())
+	}
+
+	r.EnvFiles = []string{filepath.Join(dir, "missing.env")}
+	if err := r.Run(); err == nil {
+		t.Error("Run() with a missing env file succeeded")
+	}
+}
+
+func TestRunner_Interpolate(t *testing.T) {
+	t.Setenv("FORGE_TEST_USER", "alice")
+	r := &Runner{
+		fileEnv: map[string]string{"FORGE_TEST_USER": "bob", "REGION": "eu"},
+		env:     []string{"FORGE_PORT_API=8080"},
+	}
+
+	got, err := r.interpolate([]string{"${{ env.FORGE_TEST_USER }}", "${{ env.REGION }}-${{ env.FORGE_PORT_API }}", "$HOME"})
+	if err != nil {
+		t.Fatalf("interpolate() error: %v", err)
+	}
+	if want := []string{"bob", "eu-8080", "$HOME"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("interpolate() = %q, want %q", got, want)
+	}
+	if _, err := r.interpolate([]string{"${{ env.REGION"}); err == nil {
+		t.Error("interpolate() accepted an unterminated expression")
+	}
+}
+
+func TestRunner_ProcessContext(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("FORGE_TEST_LEVEL", "inherited")
+	envFile := filepath.Join(dir, ".env")
+	if err := os.WriteFile(envFile, []byte("REGION=from-file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stages := []dsl.Stage{{Name: "ctx", Steps: []dsl.Step{
+		{Name: "show", Type: dsl.StepTypeExec, Run: []string{"sh", "-c", `echo "$FORGE_TEST_LEVEL $REGION $TIER $(basename "$PWD") $(cat)"`}},
+	}}}
+	var out bytes.Buffer
+	r, err := NewRunner("test.yaml",
+		WithOut(new(bytes.Buffer)),
+		WithProcessOutput(&out, &out),
+		WithLoadWorkflow(mockLoadWorkflow(stages)),
+		WithEnvFiles(envFile),
+		WithEnv(map[string]string{"FORGE_TEST_LEVEL": "base", "REGION": "base", "TIER": "gold"}),
+		WithWorkdir(dir),
+		WithStdin(strings.NewReader("from-stdin")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	want := "base from-file gold " + filepath.Base(dir) + " from-stdin\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestRunner_StepEnv(t *testing.T) {
+	t.Setenv("FORGE_TEST_SECRET", "s3cret")
+	sh := "/bin/sh"
+	if _, err := os.Stat(sh); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	show := []string{sh, "-c", `echo "${FORGE_TEST_SECRET:-unset} ${TIER:-unset} ${REGION:-unset} ${KEPT:-unset}"`}
+
+	tests := []struct {
+		name  string
+		stage dsl.Stage
+		want  string
+	}{
+		{
+			name:  "env adds to the inherited environment",
+			stage: dsl.Stage{Name: "s", Env: map[string]string{"TIER": "stage", "REGION": "eu"}, Steps: []dsl.Step{{Name: "show", Type: dsl.StepTypeExec, Run: show, Env: map[string]string{"TIER": "step"}}}},
+			want:  "s3cret step eu unset\n",
+		},
+		{
+			name:  "clean step",
+			stage: dsl.Stage{Name: "s", Steps: []dsl.Step{{Name: "show", Type: dsl.StepTypeExec, Run: show, CleanEnv: true, Env: map[string]string{"KEPT": "${{ env.FORGE_TEST_SECRET }}"}}}},
+			want:  "unset unset unset s3cret\n",
+		},
+		{
+			name:  "clean stage",
+			stage: dsl.Stage{Name: "s", CleanEnv: true, Env: map[string]string{"TIER": "stage"}, Steps: []dsl.Step{{Name: "show", Type: dsl.StepTypeExec, Run: show}}},
+			want:  "unset stage unset unset\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r, err := NewRunner("test.yaml",
+				WithOut(new(bytes.Buffer)),
+				WithProcessOutput(&out, &out),
+				WithLoadWorkflow(mockLoadWorkflow([]dsl.Stage{tt.stage})),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Run(); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRunner_ContextEnv(t *testing.T) {
+	dir := t.TempDir()
+	stages := []dsl.Stage{{Name: "build", Steps: []dsl.Step{
+		{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"compile"}},
+		{Name: "package", Type: dsl.StepTypeExec, Run: []string{"package", "${{ env.FORGE_STAGE }}/${{ env.FORGE_STEP }}"}},
+	}}}
+
+	var r *Runner
+	var calls, envs [][]string
+	r, err := NewRunner("test.yaml",
+		WithOut(new(bytes.Buffer)),
+		WithLoadWorkflow(mockLoadWorkflow(stages)),
+		WithWorkdir(dir),
+		WithRunCmd(func(argv []string) error {
+			calls = append(calls, argv)
+			envs = append(envs, r.contextEnv())
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	want := []string{
+		"FORGE_RUN_ID=" + r.RunID(),
+		"FORGE_WORKFLOW=mock-workflow",
+		"FORGE_STAGE=build",
+		"FORGE_STEP=package",
+		"FORGE_STEP_INDEX=2",
+		"FORGE_DRY_RUN=false",
+		"FORGE_WORKDIR=" + dir,
+	}
+	if len(envs) != 2 || !reflect.DeepEqual(envs[1], want) {
+		t.Errorf("context env = %q, want %q", envs, want)
+	}
+	if got := calls[1][1]; got != "build/package" {
+		t.Errorf("interpolated argument = %q, want %q", got, "build/package")
+	}
+	if env := r.contextEnv(); env != nil {
+		t.Errorf("context env after the run = %q, want none", env)
+	}
+}
+
+func TestRunner_NowInTimezone(t *testing.T) {
+	stages := []dsl.Stage{{Name: "build", Steps: []dsl.Step{
+		{Name: "stamp", Type: dsl.StepTypeExec, Run: []string{"echo", `${{ now("2006-01-02 15h") }}`}},
+	}}}
+	var calls [][]string
+	r, err := NewRunner("test.yaml", WithOut(&bytes.Buffer{}), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(mockRunCmd(&calls)),
+		WithClock(clock.NewFake(time.Date(2025, 1, 10, 20, 0, 0, 0, time.UTC))), WithTimezone(time.FixedZone("AEST", 10*60*60)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if want := [][]string{{"echo", "2025-01-11 06h"}}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+}
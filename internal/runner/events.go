@@ -0,0 +1,142 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type EventKind string
+
+const (
+	EventWorkflowStart EventKind = "workflow_start"
+	EventWorkflowEnd   EventKind = "workflow_end"
+	EventStageStart    EventKind = "stage_start"
+	EventStageEnd      EventKind = "stage_end"
+	EventStepStart     EventKind = "step_start"
+	EventStepEnd       EventKind = "step_end"
+)
+
+// Event describes a lifecycle change of a unit of execution. Units form a tree:
+// the workflow is the root (empty path), stages are its children and steps are
+// children of their stage. Nested units such as sub-workflows or matrix instances
+// extend the path of their parent, e.g. "deploy/matrix[go=1.22]/build".
+type Event struct {
+	Kind   EventKind `json:"kind"`
+	Path   string    `json:"path"`
+	Parent string    `json:"parent"`
+	Depth  int       `json:"depth"`
+	Name   string    `json:"name"`
+	Time   time.Time `json:"time"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// JoinPath appends a segment to a parent path. Slashes and percent signs inside the
+// segment are escaped so that paths can always be split back into their segments.
+func JoinPath(parent, segment string) string {
+	segment = strings.NewReplacer("%", "%25", "/", "%2F").Replace(segment)
+	if parent == "" {
+		return segment
+	}
+	return parent + "/" + segment
+}
+
+// InstanceSegment names one instance of a repeated unit, e.g. InstanceSegment("matrix",
+// map[string]string{"go": "1.22"}) returns "matrix[go=1.22]". Keys are sorted so the
+// identifier is stable across runs.
+func InstanceSegment(name string, params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, params[k]))
+	}
+	return name + "[" + strings.Join(pairs, ",") + "]"
+}
+
+// SplitPath returns the unescaped segments of a path
+func SplitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	unescape := strings.NewReplacer("%2F", "/", "%25", "%")
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = unescape.Replace(s)
+	}
+	return segments
+}
+
+// parentPath returns the path of the enclosing unit
+func parentPath(path string) string {
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// WithOnStageStart registers a function called with the event of every stage that starts
+func WithOnStageStart(f func(Event)) Option {
+	return func(r *Runner) { r.OnStageStart = append(r.OnStageStart, f) }
+}
+
+// WithOnStageEnd registers a function called with the event of every stage that ends, failed or not
+func WithOnStageEnd(f func(Event)) Option {
+	return func(r *Runner) { r.OnStageEnd = append(r.OnStageEnd, f) }
+}
+
+// WithOnStepStart registers a function called with the event of every step that starts
+func WithOnStepStart(f func(Event)) Option {
+	return func(r *Runner) { r.OnStepStart = append(r.OnStepStart, f) }
+}
+
+// WithOnStepEnd registers a function called with the event of every step that ends, failed or not
+func WithOnStepEnd(f func(Event)) Option {
+	return func(r *Runner) { r.OnStepEnd = append(r.OnStepEnd, f) }
+}
+
+// handlers returns the functions registered for events of the given kind
+func (r *Runner) handlers(kind EventKind) []func(Event) {
+	switch kind {
+	case EventStageStart:
+		return r.OnStageStart
+	case EventStageEnd:
+		return r.OnStageEnd
+	case EventStepStart:
+		return r.OnStepStart
+	case EventStepEnd:
+		return r.OnStepEnd
+	}
+	return nil
+}
+
+// emit delivers an event for the unit at path to the configured event handlers
+func (r *Runner) emit(kind EventKind, path, name string, err error) {
+	handlers := r.handlers(kind)
+	if r.OnEvent == nil && len(handlers) == 0 {
+		return
+	}
+
+	ev := Event{
+		Kind:   kind,
+		Path:   path,
+		Parent: parentPath(path),
+		Depth:  len(SplitPath(path)),
+		Name:   name,
+		Time:   r.Clock.Now(),
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	if r.OnEvent != nil {
+		r.OnEvent(ev)
+	}
+	for _, f := range handlers {
+		f(ev)
+	}
+}
@@ -0,0 +1,161 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestJoinAndSplitPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []string
+		want     string
+	}{
+		{name: "single segment", segments: []string{"deploy"}, want: "deploy"},
+		{name: "nested", segments: []string{"deploy", "matrix[go=1.22]", "build"}, want: "deploy/matrix[go=1.22]/build"},
+		{name: "escaped slash", segments: []string{"ci/cd", "100%"}, want: "ci%2Fcd/100%25"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := ""
+			for _, s := range tt.segments {
+				path = JoinPath(path, s)
+			}
+			if path != tt.want {
+				t.Errorf("JoinPath() = %q, want %q", path, tt.want)
+			}
+			if got := SplitPath(path); !slices.Equal(got, tt.segments) {
+				t.Errorf("SplitPath(%q) = %v, want %v", path, got, tt.segments)
+			}
+		})
+	}
+}
+
+func TestInstanceSegment(t *testing.T) {
+	got := InstanceSegment("matrix", map[string]string{"os": "linux", "go": "1.22"})
+	if want := "matrix[go=1.22,os=linux]"; got != want {
+		t.Errorf("InstanceSegment() = %q, want %q", got, want)
+	}
+}
+
+func TestRunner_Run_Events(t *testing.T) {
+	var events []Event
+	var cmdCalls [][]string
+
+	workflow := []dsl.Stage{
+		{Name: "build", Steps: []dsl.Step{{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"go", "build"}}}},
+	}
+
+	runner, err := NewRunner("test.yaml",
+		WithOut(new(bytes.Buffer)),
+		WithLoadWorkflow(mockLoadWorkflow(workflow)),
+		WithRunCmd(mockRunCmd(&cmdCalls)),
+		WithRootPath("ci"),
+		WithEvents(func(e Event) { events = append(events, e) }),
+	)
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := runner.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	want := []Event{
+		{Kind: EventWorkflowStart, Path: "ci", Parent: "", Depth: 1, Name: "mock-workflow"},
+		{Kind: EventStageStart, Path: "ci/build", Parent: "ci", Depth: 2, Name: "build"},
+		{Kind: EventStepStart, Path: "ci/build/compile", Parent: "ci/build", Depth: 3, Name: "compile"},
+		{Kind: EventStepEnd, Path: "ci/build/compile", Parent: "ci/build", Depth: 3, Name: "compile"},
+		{Kind: EventStageEnd, Path: "ci/build", Parent: "ci", Depth: 2, Name: "build"},
+		{Kind: EventWorkflowEnd, Path: "ci", Parent: "", Depth: 1, Name: "mock-workflow"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, e := range events {
+		e.Time = want[i].Time
+		if e != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, e, want[i])
+		}
+	}
+}
+
+func TestRunner_Run_EventsOnFailure(t *testing.T) {
+	var events []Event
+
+	workflow := []dsl.Stage{
+		{Name: "build", Steps: []dsl.Step{{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"go", "build"}}}},
+	}
+
+	runner, err := NewRunner("test.yaml",
+		WithOut(new(bytes.Buffer)),
+		WithLoadWorkflow(mockLoadWorkflow(workflow)),
+		WithRunCmd(mockRunCmdError(errors.New("boom"))),
+		WithEvents(func(e Event) { events = append(events, e) }),
+	)
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := runner.Run(); err == nil {
+		t.Fatal("Run() should have failed")
+	}
+
+	for _, e := range events {
+		if e.Kind == EventStepStart || e.Kind == EventStageStart || e.Kind == EventWorkflowStart {
+			continue
+		}
+		if e.Error == "" {
+			t.Errorf("%s event for %q should carry the error", e.Kind, e.Path)
+		}
+	}
+}
+
+func TestRunner_Run_EventHooks(t *testing.T) {
+	var calls []string
+	record := func(name string) func(Event) {
+		return func(e Event) { calls = append(calls, name+" "+e.Path) }
+	}
+
+	workflow := []dsl.Stage{
+		{Name: "build", Steps: []dsl.Step{
+			{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"go", "build"}},
+			{Name: "vet", Type: dsl.StepTypeExec, Run: []string{"go", "vet"}},
+		}},
+	}
+
+	var cmdCalls [][]string
+	runner, err := NewRunner("test.yaml",
+		WithOut(new(bytes.Buffer)),
+		WithLoadWorkflow(mockLoadWorkflow(workflow)),
+		WithRunCmd(mockRunCmd(&cmdCalls)),
+		WithOnStageStart(record("stage start")),
+		WithOnStageEnd(record("stage end")),
+		WithOnStepStart(record("step start")),
+		WithOnStepEnd(record("step end")),
+		WithOnStepEnd(record("step end again")),
+	)
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := runner.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	want := []string{
+		"stage start build",
+		"step start build/compile",
+		"step end build/compile",
+		"step end again build/compile",
+		"step start build/vet",
+		"step end build/vet",
+		"step end again build/vet",
+		"stage end build",
+	}
+	if !slices.Equal(calls, want) {
+		t.Errorf("hook calls = %q, want %q", calls, want)
+	}
+}
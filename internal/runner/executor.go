@@ -0,0 +1,135 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// StepExecutor executes steps of a type forge has no built-in support for
+type StepExecutor interface {
+	// Supports reports whether the executor handles steps of type t
+	Supports(t dsl.StepType) bool
+	// Execute runs the step until it is done or ctx is cancelled
+	Execute(ctx context.Context, step *dsl.Step, env StepEnv) error
+}
+
+// StepEnv is what the runner hands an executor for one step
+type StepEnv struct {
+	// RunID and Workflow identify the run the step belongs to
+	RunID    string
+	Workflow string
+	// Env holds KEY=VALUE pairs the step adds to the inherited environment, e.g. from env files and ports
+	Env []string
+	// CleanEnv makes Env the whole environment of the step's processes, see dsl.Step.CleanEnv
+	CleanEnv bool
+	// Dir is the directory the step runs in, forge's working directory when empty
+	Dir string
+	// Vars holds the workflow variables of the run
+	Vars map[string]string
+	// Stdout and Stderr receive the step's output
+	Stdout, Stderr io.Writer
+	// SetOutput exposes a value of the step, see Runner.Outputs
+	SetOutput func(key, value string)
+}
+
+var (
+	executorsMu sync.RWMutex
+	executors   []StepExecutor
+)
+
+func init() {
+	dsl.RegisterStepTypes(func(t dsl.StepType) bool { return findExecutor(t) != nil })
+}
+
+// RegisterStepExecutor makes steps of the types e supports valid in workflows and executes
+// them with e. Programs embedding forge call it from an init function; executors registered
+// earlier win, and exec plugins found on PATH are only used for types no executor supports.
+func RegisterStepExecutor(e StepExecutor) {
+	executorsMu.Lock()
+	defer executorsMu.Unlock()
+	executors = append(executors, e)
+}
+
+// findExecutor returns the executor for steps of type t, nil if there is none
+func findExecutor(t dsl.StepType) StepExecutor {
+	if dsl.IsBuiltinStepType(t) {
+		return nil
+	}
+	executorsMu.RLock()
+	defer executorsMu.RUnlock()
+	for _, e := range executors {
+		if e.Supports(t) {
+			return e
+		}
+	}
+	if plugins.Supports(t) {
+		return plugins
+	}
+	return nil
+}
+
+// runExecutor runs a step of a type without built-in support with its executor;
+// string values in the step's with are interpolated first
+func (r *Runner) runExecutor(step *dsl.Step) error {
+	e := findExecutor(step.Type)
+	if e == nil {
+		return fmt.Errorf("unknown step type: %s", step.Type)
+	}
+	resolved := *step
+	if step.With != nil {
+		with, err := r.interpolateValue(step.With)
+		if err != nil {
+			return err
+		}
+		resolved.With = with.(map[string]any)
+	}
+	return e.Execute(r.ctx, &resolved, StepEnv{
+		RunID:     r.RunID(),
+		Workflow:  r.wf.Name,
+		Env:       r.commandEnv(),
+		CleanEnv:  r.cleanEnv,
+		Dir:       r.processDir(),
+		Vars:      r.vars,
+		Stdout:    r.processStdout(),
+		Stderr:    r.processStderr(),
+		SetOutput: func(key, value string) { r.setOutput(step.Name, key, value) },
+	})
+}
+
+// interpolateValue expands ${{ }} expressions in the strings of a decoded YAML value
+func (r *Runner) interpolateValue(v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		s, err := r.interpolate([]string{v})
+		if err != nil {
+			return nil, err
+		}
+		return s[0], nil
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			x, err := r.interpolateValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = x
+		}
+		return out, nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			x, err := r.interpolateValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = x
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
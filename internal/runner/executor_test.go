@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// fakeExecutor records the steps it executes and fails those named "fail"
+type fakeExecutor struct {
+	typ   dsl.StepType
+	steps []*dsl.Step
+}
+
+func (e *fakeExecutor) Supports(t dsl.StepType) bool { return t == e.typ }
+
+func (e *fakeExecutor) Execute(ctx context.Context, step *dsl.Step, env StepEnv) error {
+	e.steps = append(e.steps, step)
+	if step.Name == "fail" {
+		return errors.New("deploy rejected")
+	}
+	fmt.Fprintf(env.Stdout, "deployed %s\n", step.With["chart"])
+	env.SetOutput("revision", "3")
+	return nil
+}
+
+func TestRegisterStepExecutor(t *testing.T) {
+	exec := &fakeExecutor{typ: "test-helm"}
+	RegisterStepExecutor(exec)
+	t.Setenv("FORGE_TEST_CHART", "api")
+
+	step := dsl.Step{Name: "deploy", Type: "test-helm", With: map[string]any{
+		"chart":  "${{ env.FORGE_TEST_CHART }}",
+		"values": []any{"prod.yaml", 2},
+	}}
+	if err := step.Validate(); err != nil {
+		t.Fatalf("Validate() error for a registered type: %v", err)
+	}
+
+	var out bytes.Buffer
+	r, err := NewRunner("test.yaml",
+		WithOut(&out),
+		WithProcessOutput(&out, &out),
+		WithLoadWorkflow(mockLoadWorkflow([]dsl.Stage{{Name: "release", Steps: []dsl.Step{step}}})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if len(exec.steps) != 1 {
+		t.Fatalf("executor ran %d steps, want 1", len(exec.steps))
+	}
+	want := map[string]any{"chart": "api", "values": []any{"prod.yaml", 2}}
+	if !reflect.DeepEqual(exec.steps[0].With, want) {
+		t.Errorf("with = %v, want %v", exec.steps[0].With, want)
+	}
+	if step.With["chart"] != "${{ env.FORGE_TEST_CHART }}" {
+		t.Error("interpolation modified the workflow's step")
+	}
+	if !bytes.Contains(out.Bytes(), []byte("deployed api")) {
+		t.Errorf("output = %q, want the executor's output", out.String())
+	}
+	if got := r.Outputs("deploy")["revision"]; got != "3" {
+		t.Errorf("Outputs()[revision] = %q, want 3", got)
+	}
+
+	fail := dsl.Step{Name: "fail", Type: "test-helm"}
+	r, err = NewRunner("test.yaml",
+		WithOut(new(bytes.Buffer)),
+		WithLoadWorkflow(mockLoadWorkflow([]dsl.Stage{{Name: "release", Steps: []dsl.Step{fail}}})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err == nil {
+		t.Error("Run() succeeded although the executor failed")
+	}
+}
@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// expandGlobs expands a leading ~ to the home directory and glob patterns to the files they
+// match in dir, in the arguments of a step with expand_globs. Relative patterns expand to
+// relative paths. A pattern matching no files is an error unless keep is set, which keeps it as
+// it is, e.g. for dry runs before the files exist.
+func expandGlobs(argv []string, dir string, keep bool) ([]string, error) {
+	var out []string
+	for _, arg := range argv {
+		if arg == "~" || strings.HasPrefix(arg, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("expand_globs: %w", err)
+			}
+			arg = home + arg[1:]
+		}
+		if !strings.ContainsAny(arg, "*?[") {
+			out = append(out, arg)
+			continue
+		}
+		pattern := arg
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("expand_globs: %q: %w", arg, err)
+		}
+		if len(matches) == 0 {
+			if !keep {
+				return nil, fmt.Errorf("expand_globs: %q matches no files", arg)
+			}
+			out = append(out, arg)
+			continue
+		}
+		for _, m := range matches {
+			if !filepath.IsAbs(arg) {
+				if rel, err := filepath.Rel(cmp.Or(dir, "."), m); err == nil {
+					m = rel
+				}
+			}
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestExpandGlobs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "dist"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"dist/b.tar.gz", "dist/a.tar.gz", "dist/notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("HOME", "/home/dev")
+
+	tests := []struct {
+		name    string
+		argv    []string
+		keep    bool
+		want    []string
+		wantErr string
+	}{
+		{name: "relative", argv: []string{"upload", "dist/*.tar.gz", "--to", "s3"}, want: []string{"upload", "dist/a.tar.gz", "dist/b.tar.gz", "--to", "s3"}},
+		{name: "absolute", argv: []string{"ls", filepath.Join(dir, "dist", "?.tar.gz")}, want: []string{"ls", filepath.Join(dir, "dist", "a.tar.gz"), filepath.Join(dir, "dist", "b.tar.gz")}},
+		{name: "home", argv: []string{"~/bin/tool", "~", "x~y"}, want: []string{"/home/dev/bin/tool", "/home/dev", "x~y"}},
+		{name: "no match", argv: []string{"rm", "build/*.o"}, wantErr: `expand_globs: "build/*.o" matches no files`},
+		{name: "no match kept", argv: []string{"rm", "build/*.o"}, keep: true, want: []string{"rm", "build/*.o"}},
+		{name: "bad pattern", argv: []string{"ls", "dist/[a"}, wantErr: "syntax error in pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandGlobs(tt.argv, dir, tt.keep)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expandGlobs() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandGlobs() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunner_ExpandGlobs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "release.yaml")
+	for _, name := range []string{"release.yaml", "app.tar.gz"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load := func(string) (*dsl.Workflow, error) {
+		return &dsl.Workflow{Name: "release", Workdir: ".", Stages: []dsl.Stage{{Name: "publish", Steps: []dsl.Step{
+			{Name: "upload", Type: dsl.StepTypeExec, Run: dsl.Command{"upload *.tar.gz"}, ExpandGlobs: true},
+			{Name: "literal", Type: dsl.StepTypeExec, Run: dsl.Command{"echo", "*.tar.gz"}},
+		}}}}, nil
+	}
+
+	var calls [][]string
+	r, err := NewRunner(path, WithOut(new(bytes.Buffer)), WithLoadWorkflow(load), WithRunCmd(mockRunCmd(&calls)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	want := [][]string{{"upload", "app.tar.gz"}, {"echo", "*.tar.gz"}}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("commands = %q, want %q", calls, want)
+	}
+
+	var out bytes.Buffer
+	r, err = NewRunner(path, WithOut(&out), WithLoadWorkflow(load), WithMode(ModeDryRun))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+	if want := "Would execute command: [upload app.tar.gz]"; !strings.Contains(out.String(), want) {
+		t.Errorf("dry-run output missing %q:\n%s", want, out.String())
+	}
+}
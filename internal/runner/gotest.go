@@ -0,0 +1,163 @@
+package runner
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+const defaultGoTestBase = "HEAD"
+
+// goListFormat prints one package per line: import path, directory, transitive deps, test imports
+const goListFormat = `{{.ImportPath}}	{{.Dir}}	{{join .Deps ","}}	{{join .TestImports ","}},{{join .XTestImports ","}}`
+
+type goPackage struct {
+	ImportPath  string
+	Dir         string
+	Deps        []string
+	TestImports []string
+}
+
+func goTestBase(step *dsl.Step) string {
+	if step.Base == "" {
+		return defaultGoTestBase
+	}
+	return step.Base
+}
+
+// runGoTest runs `go test` for the packages affected by changes since the step's base ref.
+// The number of selected and skipped packages is exposed as step outputs.
+func (r *Runner) runGoTest(step *dsl.Step) error {
+	base := goTestBase(step)
+
+	diff, err := r.CmdOutput([]string{"git", "diff", "--name-only", "--relative", base})
+	if err != nil {
+		return fmt.Errorf("failed to list changes since %s: %w", base, err)
+	}
+
+	list, err := r.CmdOutput([]string{"go", "list", "-f", goListFormat, "./..."})
+	if err != nil {
+		return fmt.Errorf("failed to list go packages: %w", err)
+	}
+
+	changed, err := absPaths(r.processDir(), splitLines(string(diff)))
+	if err != nil {
+		return err
+	}
+	selected, skipped := selectAffectedPackages(parseGoList(string(list)), changed)
+
+	r.setOutput(step.Name, "selected", strconv.Itoa(len(selected)))
+	r.setOutput(step.Name, "skipped", strconv.Itoa(len(skipped)))
+	fmt.Fprintf(r.Out, "  Selected %d package(s), skipped %d since %s\n", len(selected), len(skipped), base)
+
+	if len(selected) == 0 {
+		return nil
+	}
+
+	argv := append([]string{"go", "test"}, step.Args...)
+	argv = append(argv, selected...)
+	if err := r.exec(argv); err != nil {
+		return fmt.Errorf("command execution failed: %w", err)
+	}
+	return nil
+}
+
+// selectAffectedPackages splits pkgs into packages whose own files, dependencies or
+// test imports contain one of the changed files, and the remaining packages.
+// A change to go.mod or go.sum selects every package.
+func selectAffectedPackages(pkgs []goPackage, changed []string) (selected, skipped []string) {
+	changedDirs := make(map[string]bool)
+	all := false
+	for _, file := range changed {
+		switch filepath.Base(file) {
+		case "go.mod", "go.sum":
+			all = true
+		}
+		if strings.HasSuffix(file, ".go") {
+			changedDirs[filepath.Dir(file)] = true
+		}
+	}
+
+	changedPkgs := make(map[string]bool)
+	for _, p := range pkgs {
+		if changedDirs[p.Dir] {
+			changedPkgs[p.ImportPath] = true
+		}
+	}
+
+	// affected reports whether a package or its transitive dependencies changed
+	affected := make(map[string]bool)
+	for _, p := range pkgs {
+		affected[p.ImportPath] = changedPkgs[p.ImportPath] || slices.ContainsFunc(p.Deps, func(d string) bool {
+			return changedPkgs[d]
+		})
+	}
+
+	for _, p := range pkgs {
+		hit := all || affected[p.ImportPath] || slices.ContainsFunc(p.TestImports, func(d string) bool {
+			return changedPkgs[d] || affected[d]
+		})
+		if hit {
+			selected = append(selected, p.ImportPath)
+		} else {
+			skipped = append(skipped, p.ImportPath)
+		}
+	}
+	return selected, skipped
+}
+
+func parseGoList(out string) []goPackage {
+	var pkgs []goPackage
+	for _, line := range splitLines(out) {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		p := goPackage{ImportPath: fields[0], Dir: fields[1]}
+		if len(fields) > 2 {
+			p.Deps = splitList(fields[2])
+		}
+		if len(fields) > 3 {
+			p.TestImports = splitList(fields[3])
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs
+}
+
+// absPaths returns the absolute paths of paths relative to dir, the directory git ran in
+func absPaths(dir string, paths []string) ([]string, error) {
+	abs := make([]string, 0, len(paths))
+	for _, p := range paths {
+		a, err := filepath.Abs(filepath.Join(dir, p))
+		if err != nil {
+			return nil, err
+		}
+		abs = append(abs, a)
+	}
+	return abs, nil
+}
+
+func splitLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,168 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestSelectAffectedPackages(t *testing.T) {
+	pkgs := []goPackage{
+		{ImportPath: "m/a", Dir: "/src/a"},
+		{ImportPath: "m/b", Dir: "/src/b", Deps: []string{"fmt", "m/a"}},
+		{ImportPath: "m/c", Dir: "/src/c", TestImports: []string{"m/b"}},
+		{ImportPath: "m/d", Dir: "/src/d"},
+	}
+
+	tests := []struct {
+		name         string
+		changed      []string
+		wantSelected []string
+		wantSkipped  []string
+	}{
+		{
+			name:         "no changes",
+			changed:      nil,
+			wantSelected: nil,
+			wantSkipped:  []string{"m/a", "m/b", "m/c", "m/d"},
+		},
+		{
+			name:         "dependency change propagates to dependents and test importers",
+			changed:      []string{"/src/a/a.go"},
+			wantSelected: []string{"m/a", "m/b", "m/c"},
+			wantSkipped:  []string{"m/d"},
+		},
+		{
+			name:         "leaf change",
+			changed:      []string{"/src/d/d_test.go"},
+			wantSelected: []string{"m/d"},
+			wantSkipped:  []string{"m/a", "m/b", "m/c"},
+		},
+		{
+			name:         "non-go files are ignored",
+			changed:      []string{"/src/a/README.md"},
+			wantSelected: nil,
+			wantSkipped:  []string{"m/a", "m/b", "m/c", "m/d"},
+		},
+		{
+			name:         "go.mod change selects everything",
+			changed:      []string{"/src/go.mod"},
+			wantSelected: []string{"m/a", "m/b", "m/c", "m/d"},
+			wantSkipped:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, skipped := selectAffectedPackages(pkgs, tt.changed)
+			if !slices.Equal(selected, tt.wantSelected) {
+				t.Errorf("selected = %v, want %v", selected, tt.wantSelected)
+			}
+			if !slices.Equal(skipped, tt.wantSkipped) {
+				t.Errorf("skipped = %v, want %v", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestRunner_Run_GoTestStep(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd() failed: %v", err)
+	}
+
+	var cmdCalls [][]string
+	out := new(bytes.Buffer)
+	cmdOutput := func(argv []string) ([]byte, error) {
+		switch argv[0] {
+		case "git":
+			return []byte("a/a.go\n"), nil
+		case "go":
+			return []byte(strings.Join([]string{
+				"m/a\t" + filepath.Join(wd, "a") + "\t\t,",
+				"m/b\t" + filepath.Join(wd, "b") + "\t\t,",
+			}, "\n")), nil
+		}
+		t.Fatalf("unexpected command %v", argv)
+		return nil, nil
+	}
+
+	workflow := []dsl.Stage{
+		{
+			Name: "test",
+			Steps: []dsl.Step{
+				{Name: "affected", Type: dsl.StepTypeGoTest, Base: "origin/main", Args: []string{"-race"}},
+			},
+		},
+	}
+
+	r, err := NewRunner("test.yaml",
+		WithOut(out),
+		WithLoadWorkflow(mockLoadWorkflow(workflow)),
+		WithRunCmd(mockRunCmd(&cmdCalls)),
+		WithCmdOutput(cmdOutput),
+	)
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	if len(cmdCalls) != 1 || !slices.Equal(cmdCalls[0], []string{"go", "test", "-race", "m/a"}) {
+		t.Errorf("expected [go test -race m/a], got %v", cmdCalls)
+	}
+	outputs := r.Outputs("affected")
+	if outputs["selected"] != "1" || outputs["skipped"] != "1" {
+		t.Errorf("unexpected outputs %v", outputs)
+	}
+	if !strings.Contains(out.String(), "Selected 1 package(s), skipped 1 since origin/main") {
+		t.Errorf("output missing selection summary, got:\n%s", out.String())
+	}
+}
+
+func TestRunner_Run_GoTestStep_Workdir(t *testing.T) {
+	root := t.TempDir()
+	module := filepath.Join(root, "module")
+	t.Chdir(root)
+
+	var cmdCalls [][]string
+	cmdOutput := func(argv []string) ([]byte, error) {
+		switch argv[0] {
+		case "git":
+			// --relative paths are relative to the step's workdir, where git runs
+			return []byte("a/a.go\n"), nil
+		case "go":
+			return []byte(strings.Join([]string{
+				"m/a\t" + filepath.Join(module, "a") + "\t\t,",
+				"m/b\t" + filepath.Join(module, "b") + "\t\t,",
+			}, "\n")), nil
+		}
+		t.Fatalf("unexpected command %v", argv)
+		return nil, nil
+	}
+	workflow := []dsl.Stage{{Name: "test", Steps: []dsl.Step{
+		{Name: "affected", Type: dsl.StepTypeGoTest, Workdir: "module"},
+	}}}
+	r, err := NewRunner("test.yaml",
+		WithOut(new(bytes.Buffer)),
+		WithLoadWorkflow(mockLoadWorkflow(workflow)),
+		WithRunCmd(mockRunCmd(&cmdCalls)),
+		WithCmdOutput(cmdOutput),
+	)
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if len(cmdCalls) != 1 || !slices.Equal(cmdCalls[0], []string{"go", "test", "m/a"}) {
+		t.Errorf("expected [go test m/a], got %v", cmdCalls)
+	}
+}
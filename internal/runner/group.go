@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+// runGroup runs the steps of a group step of the named stage in order until one fails, each
+// recorded as a step of its own below the group; the group's timeout bounds all of them
+func (r *Runner) runGroup(stage, groupPath string, group *dsl.Step) error {
+	parent := r.ctx
+	timeout := r.wf.StepTimeout(group)
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		r.ctx = ctx
+		defer func() {
+			cancel()
+			r.ctx = parent
+		}()
+	}
+	prev := r.group
+	r.group = group.Name
+	defer func() { r.group = prev }()
+
+	steps := group.GroupSteps()
+	for i := range steps {
+		step := &steps[i]
+		label := fmt.Sprintf("  STEP %s.%d: %s", group.Name, i+1, step.Name)
+		switch {
+		case !r.onPlatform(step.Platforms):
+			fmt.Fprintf(r.Out, "%s (%s)\n", label, r.notForPlatform())
+			r.skipStep(stage, groupPath, step, history.StatusSkipped)
+			continue
+		case r.stepSkipped(stage, step.Name):
+			fmt.Fprintf(r.Out, "%s (skipped)\n", label)
+			r.skipStep(stage, groupPath, step, history.StatusSkipped)
+			continue
+		}
+		run, err := r.stepCondition(step)
+		if err != nil {
+			return fmt.Errorf("step '%s': %w", step.Name, err)
+		}
+		if !run {
+			fmt.Fprintf(r.Out, "%s (skipped, when is false)\n", label)
+			r.skipStep(stage, groupPath, step, history.StatusSkipped)
+			continue
+		}
+		fmt.Fprintf(r.Out, "%s (%s)\n", label, step.Type)
+		printDescription(r.Out, step.Description)
+		if err := r.runStep(stage, groupPath, i+1, step); err != nil {
+			if r.ctx.Err() != nil && parent.Err() == nil {
+				// Not wrapped, so the group is retried like a step whose command timed out
+				return fmt.Errorf("step '%s': group timed out after %s: %v", step.Name, timeout, err)
+			}
+			return fmt.Errorf("step '%s': %w", step.Name, err)
+		}
+	}
+	return nil
+}
+
+// dryRunGroup prints what the planned group step of the named stage would do, its steps included
+func (r *Runner) dryRunGroup(stage string, ps *PlanStep, group *dsl.Step) error {
+	if ps.Timeout != "" {
+		fmt.Fprintf(r.Out, "[DRY-RUN]   Would time out the group after %s\n", ps.Timeout)
+	}
+	if ps.Retries > 0 {
+		fmt.Fprintf(r.Out, "[DRY-RUN]   Would retry the group up to %d time(s) if it fails\n", ps.Retries)
+	}
+	steps := group.GroupSteps()
+	for i := range ps.Steps {
+		step := &ps.Steps[i]
+		fmt.Fprintf(r.Out, "[DRY-RUN]   STEP %s.%d: %s (%s)\n", group.Name, i+1, step.Name, step.dryRunLabel())
+		r.dryRunPlatforms(step.Platforms, "    ")
+		if step.Skip != "" || step.DryRun == dsl.DryRunSkip {
+			continue
+		}
+		if step.Dir != ps.Dir {
+			fmt.Fprintf(r.Out, "[DRY-RUN]   Would run in %s\n", step.Dir)
+		}
+		if step.CleanEnv {
+			fmt.Fprintf(r.Out, "[DRY-RUN]   Would start from a clean environment\n")
+		}
+		if err := r.dryRunStep(stage, i+1, step, &steps[i]); err != nil {
+			return fmt.Errorf("step '%s': %w", step.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func TestRunner_Group(t *testing.T) {
+	stages := []dsl.Stage{{Name: "ci", Steps: []dsl.Step{
+		{Name: "checks", Type: dsl.StepTypeGroup, Workdir: "/src", Env: map[string]string{"MODE": "fast"}, Steps: []dsl.Step{
+			{Name: "lint", ID: "lint", Type: dsl.StepTypeExec, Run: dsl.Command{"lint"}},
+			{Name: "vet", Type: dsl.StepTypeExec, Run: dsl.Command{"vet"}},
+			{Name: "docs", Type: dsl.StepTypeExec, Run: dsl.Command{"docs"}, Workdir: "/docs", Env: map[string]string{"MODE": "full"}},
+			{Name: "never", Type: dsl.StepTypeExec, Run: dsl.Command{"never"}, When: "steps.lint.status == 'failed'"},
+		}},
+		{Name: "vet", Type: dsl.StepTypeExec, Run: dsl.Command{"vet", "./..."}},
+	}}}
+
+	var r *Runner
+	var calls []string
+	run := func(argv []string) error {
+		calls = append(calls, strings.Join(argv, " ")+" in "+r.processDir()+" with "+r.stepEnv["MODE"])
+		return nil
+	}
+	var out bytes.Buffer
+	r, err := NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(run), WithSkipSteps("ci.checks.vet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	want := []string{"lint in /src with fast", "docs in /docs with full", "vet ./... in  with "}
+	if !slices.Equal(calls, want) {
+		t.Errorf("commands = %q, want %q", calls, want)
+	}
+	for _, line := range []string{
+		"STEP 1.1: checks (group)\n",
+		"  STEP checks.1: lint (exec)\n",
+		"  STEP checks.2: vet (skipped)\n",
+		"  STEP checks.4: never (skipped, when is false)\n",
+		"STEP 1.2: vet (exec)\n",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("output missing %q:\n%s", line, out.String())
+		}
+	}
+
+	type step struct {
+		path, group string
+		status      history.Status
+	}
+	var got []step
+	for _, s := range r.Record().Steps {
+		got = append(got, step{s.Path, s.Group, s.Status})
+	}
+	wantSteps := []step{
+		{"ci/checks", "", history.StatusSuccess},
+		{"ci/checks/lint", "checks", history.StatusSuccess},
+		{"ci/checks/vet", "checks", history.StatusSkipped},
+		{"ci/checks/docs", "checks", history.StatusSuccess},
+		{"ci/checks/never", "checks", history.StatusSkipped},
+		{"ci/vet", "", history.StatusSuccess},
+	}
+	if !slices.Equal(got, wantSteps) {
+		t.Errorf("recorded steps = %v, want %v", got, wantSteps)
+	}
+}
+
+func TestRunner_Group_Failure(t *testing.T) {
+	retries := 1
+	stages := []dsl.Stage{{Name: "ci", Steps: []dsl.Step{
+		{Name: "checks", Type: dsl.StepTypeGroup, Retries: &retries, Steps: []dsl.Step{
+			{Name: "lint", Type: dsl.StepTypeExec, Run: dsl.Command{"lint"}},
+			{Name: "test", Type: dsl.StepTypeExec, Run: dsl.Command{"test"}},
+			{Name: "docs", Type: dsl.StepTypeExec, Run: dsl.Command{"docs"}},
+		}},
+	}}}
+	var calls []string
+	run := func(argv []string) error {
+		calls = append(calls, argv[0])
+		if argv[0] == "test" {
+			return errors.New("exit status 1")
+		}
+		return nil
+	}
+	var out bytes.Buffer
+	r, err := NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(run))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Run()
+	if err == nil || !strings.Contains(err.Error(), "step 'checks': step 'test': command execution failed: exit status 1") {
+		t.Fatalf("Run() error = %v, want the failing step of the group", err)
+	}
+	if want := []string{"lint", "test", "lint", "test"}; !slices.Equal(calls, want) {
+		t.Errorf("commands = %q, want the group to be retried as a whole: %q", calls, want)
+	}
+	if !strings.Contains(out.String(), "Step 'checks' failed (attempt 1/2), retrying") {
+		t.Errorf("output does not report the retry of the group:\n%s", out.String())
+	}
+	if rec := r.Record().Steps[0]; rec.Path != "ci/checks" || rec.Status != history.StatusFailed {
+		t.Errorf("group record = %s (%s), want ci/checks (failed)", rec.Path, rec.Status)
+	}
+}
+
+func TestRunner_Group_Timeout(t *testing.T) {
+	retries := 1
+	stages := []dsl.Stage{{Name: "ci", Steps: []dsl.Step{
+		{Name: "checks", Type: dsl.StepTypeGroup, Timeout: "50ms", Retries: &retries, Steps: []dsl.Step{
+			{Name: "wait", Type: dsl.StepTypeSleep, Seconds: 60},
+		}},
+	}}}
+	var out bytes.Buffer
+	r, err := NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Run()
+	if err == nil || !strings.Contains(err.Error(), "group timed out after 50ms") || errors.Is(err, ErrCancelled) {
+		t.Fatalf("Run() error = %v, want the group to time out", err)
+	}
+	if !strings.Contains(out.String(), "Step 'checks' failed (attempt 1/2), retrying") {
+		t.Errorf("a group that timed out is not retried:\n%s", out.String())
+	}
+}
+
+func TestRunner_Group_DryRun(t *testing.T) {
+	dir := t.TempDir()
+	stages := []dsl.Stage{{Name: "ci", Steps: []dsl.Step{
+		{Name: "checks", Type: dsl.StepTypeGroup, Workdir: dir, Timeout: "5m", Steps: []dsl.Step{
+			{Name: "lint", Type: dsl.StepTypeExec, Run: dsl.Command{"golangci-lint run"}},
+			{Name: "docs", Type: dsl.StepTypeExec, Run: dsl.Command{"make", "docs"}, Workdir: filepath.Join(dir, "docs")},
+			{Name: "windows", Type: dsl.StepTypeExec, Run: dsl.Command{"lint.bat"}, Platforms: []string{"plan9"}},
+		}},
+	}}}
+	var out bytes.Buffer
+	r, err := NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)), WithMode(ModeDryRun))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+	for _, want := range []string{
+		"[DRY-RUN] STEP 1.1: checks (group)\n",
+		"[DRY-RUN]   Would run in " + dir + "\n",
+		"[DRY-RUN]   Would time out the group after 5m0s\n",
+		"[DRY-RUN]   STEP checks.1: lint (exec)\n",
+		"[DRY-RUN]   Would execute command: [golangci-lint run]\n",
+		"[DRY-RUN]   STEP checks.2: docs (exec)\n",
+		"[DRY-RUN]   Would run in " + filepath.Join(dir, "docs") + "\n",
+		"[DRY-RUN]   STEP checks.3: windows (skipped, not for ",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("dry-run output missing %q:\n%s", want, out.String())
+		}
+	}
+}
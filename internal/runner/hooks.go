@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+// ErrCancelled is returned for runs stopped because the runner's context was done
+var ErrCancelled = errors.New("run cancelled")
+
+// WithContext stops the run before the next step, and kills running commands, when ctx is done
+func WithContext(ctx context.Context) Option {
+	return func(r *Runner) { r.Context = ctx }
+}
+
+// runHooks runs the on_success or on_failure hooks for outcome, then the always hooks.
+// Hooks run even after cancellation, with commands that are no longer killed by it, and
+// every hook runs even if an earlier one failed; the first failure is returned.
+func (r *Runner) runHooks(stage, stagePath string, outcome error, hooks map[string][]dsl.Step) error {
+	kinds := []string{dsl.HookOnSuccess, dsl.HookAlways}
+	if outcome != nil {
+		kinds[0] = dsl.HookOnFailure
+	}
+	if len(hooks[kinds[0]])+len(hooks[kinds[1]]) == 0 {
+		return nil
+	}
+
+	return r.withoutCancel(func() error {
+		var first error
+		for _, kind := range kinds {
+			for i, step := range hooks[kind] {
+				if !r.onPlatform(step.Platforms) {
+					fmt.Fprintf(r.Out, "HOOK %s: %s (%s)\n", kind, step.Name, r.notForPlatform())
+					continue
+				}
+				run, err := r.stepCondition(&step)
+				if err == nil && !run {
+					fmt.Fprintf(r.Out, "HOOK %s: %s (skipped, when is false)\n", kind, step.Name)
+					r.skipStep(stage, stagePath, &step, history.StatusSkipped)
+					continue
+				}
+				fmt.Fprintf(r.Out, "HOOK %s: %s (%s)\n", kind, step.Name, step.Type)
+				if err == nil {
+					err = r.runStep(stage, stagePath, i+1, &step)
+				}
+				if err != nil {
+					fmt.Fprintf(r.Out, "  Hook failed: %v\n", err)
+					if first == nil {
+						first = fmt.Errorf("%s hook '%s': %w", kind, step.Name, err)
+					}
+				}
+			}
+		}
+		return first
+	})
+}
+
+// withoutCancel calls fn with a step context that is not cancelled with the runner's context
+func (r *Runner) withoutCancel(fn func() error) error {
+	ctx := r.ctx
+	r.ctx = context.WithoutCancel(r.Context)
+	defer func() { r.ctx = ctx }()
+	return fn()
+}
@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+var errCommand = errors.New("exit status 1")
+
+func execStep(name string) dsl.Step {
+	return dsl.Step{Name: name, Type: dsl.StepTypeExec, Run: []string{name}}
+}
+
+func TestRunner_Hooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		fail    string
+		cancel  string
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "success",
+			want: []string{"build", "test", "stage-ok", "stage-cleanup", "wf-ok", "wf-cleanup"},
+		},
+		{
+			name:    "failing step",
+			fail:    "build",
+			want:    []string{"build", "stage-failed", "stage-cleanup", "wf-failed", "wf-cleanup"},
+			wantErr: errCommand,
+		},
+		{
+			name:    "failing success hook",
+			fail:    "stage-ok",
+			want:    []string{"build", "test", "stage-ok", "stage-cleanup", "wf-failed", "wf-cleanup"},
+			wantErr: errCommand,
+		},
+		{
+			name:    "failing always hook runs the remaining hooks",
+			fail:    "stage-cleanup",
+			want:    []string{"build", "test", "stage-ok", "stage-cleanup", "wf-failed", "wf-cleanup"},
+			wantErr: errCommand,
+		},
+		{
+			name:    "cancelled",
+			cancel:  "build",
+			want:    []string{"build", "stage-failed", "stage-cleanup", "wf-failed", "wf-cleanup"},
+			wantErr: ErrCancelled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var calls []string
+			runCmd := func(argv []string) error {
+				calls = append(calls, argv[0])
+				if argv[0] == tt.cancel {
+					cancel()
+				}
+				if argv[0] == tt.fail {
+					return errCommand
+				}
+				return nil
+			}
+			load := func(string) (*dsl.Workflow, error) {
+				return &dsl.Workflow{
+					Name: "hooked",
+					Stages: []dsl.Stage{{
+						Name:      "ci",
+						Steps:     []dsl.Step{execStep("build"), execStep("test")},
+						OnSuccess: []dsl.Step{execStep("stage-ok")},
+						OnFailure: []dsl.Step{execStep("stage-failed")},
+						Always:    []dsl.Step{execStep("stage-cleanup")},
+					}},
+					OnSuccess: []dsl.Step{execStep("wf-ok")},
+					OnFailure: []dsl.Step{execStep("wf-failed")},
+					Always:    []dsl.Step{execStep("wf-cleanup")},
+				}, nil
+			}
+
+			r, err := NewRunner("test.yaml", WithOut(new(bytes.Buffer)), WithLoadWorkflow(load), WithRunCmd(runCmd), WithContext(ctx))
+			if err != nil {
+				t.Fatalf("NewRunner() failed: %v", err)
+			}
+			err = r.Run()
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Errorf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(calls, tt.want) {
+				t.Errorf("commands = %v, want %v", calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunner_DryRun_Hooks(t *testing.T) {
+	load := func(string) (*dsl.Workflow, error) {
+		return &dsl.Workflow{
+			Name:      "hooked",
+			Stages:    []dsl.Stage{{Name: "ci", Steps: []dsl.Step{execStep("build")}, Always: []dsl.Step{execStep("stage-cleanup")}}},
+			OnFailure: []dsl.Step{execStep("wf-failed")},
+		}, nil
+	}
+	var out bytes.Buffer
+	r, err := NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(load))
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := r.DryRun(); err != nil {
+		t.Fatalf("DryRun() error: %v", err)
+	}
+	for _, want := range []string{
+		"[DRY-RUN] HOOK always: stage-cleanup (exec)\n[DRY-RUN]   Would execute command: [stage-cleanup]",
+		"[DRY-RUN] HOOK on_failure: wf-failed (exec)",
+	} {
+		if !bytes.Contains(out.Bytes(), []byte(want)) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/expr"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+// WithForce runs steps whose idempotency key already succeeded in a recorded run
+func WithForce(force bool) Option {
+	return func(r *Runner) { r.Force = force }
+}
+
+// idempotencyKey returns the interpolated idempotency key of a step and the id of the latest
+// recorded run in which a step with that key succeeded; the id is empty when none did, the
+// runner keeps no history or is forced
+func (r *Runner) idempotencyKey(step *dsl.Step) (key, runID string, err error) {
+	if step.IdempotencyKey == "" {
+		return "", "", nil
+	}
+	if key, err = expr.Interpolate(step.IdempotencyKey, r.exprContext(false)); err != nil {
+		return "", "", fmt.Errorf("idempotency_key: %w", err)
+	}
+	if r.History == nil || r.Force {
+		return key, "", nil
+	}
+	rec, err := r.History.Succeeded(key)
+	if errors.Is(err, history.ErrNoRuns) {
+		return key, "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("idempotency_key: %w", err)
+	}
+	return key, rec.ID, nil
+}
+
+// setIdempotencyKey stores the idempotency key in the record of the step that just ran or was skipped
+func (r *Runner) setIdempotencyKey(key string) {
+	if rec := r.currentStepRecord(); rec != nil && key != "" {
+		rec.IdempotencyKey = key
+	}
+}
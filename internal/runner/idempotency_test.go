@@ -0,0 +1,70 @@
+package runner
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func TestRunner_IdempotencyKey(t *testing.T) {
+	stages := []dsl.Stage{{Name: "provision", Steps: []dsl.Step{
+		{Name: "bucket", Type: dsl.StepTypeExec, Run: []string{"mkbucket"}, IdempotencyKey: "bucket-${{ vars.bucket }}"},
+		{Name: "upload", Type: dsl.StepTypeExec, Run: []string{"upload"}},
+	}}}
+	load := func(string) (*dsl.Workflow, error) {
+		return &dsl.Workflow{Name: "provision", Vars: map[string]string{"bucket": "assets"}, Stages: stages}, nil
+	}
+	store := history.NewStore(filepath.Join(t.TempDir(), "runs"))
+	run := func(opts ...Option) ([][]string, string, *Runner) {
+		t.Helper()
+		var out bytes.Buffer
+		var calls [][]string
+		opts = append([]Option{WithOut(&out), WithLoadWorkflow(load), WithRunCmd(mockRunCmd(&calls)), WithHistory(store)}, opts...)
+		r, err := NewRunner("test.yaml", opts...)
+		if err != nil {
+			t.Fatalf("NewRunner() error: %v", err)
+		}
+		if err := r.Run(); err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+		return calls, out.String(), r
+	}
+
+	calls, _, first := run()
+	if want := [][]string{{"mkbucket"}, {"upload"}}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("first run calls = %v, want %v", calls, want)
+	}
+	if key := first.Record().Steps[0].IdempotencyKey; key != "bucket-assets" {
+		t.Errorf("recorded key = %q, want bucket-assets", key)
+	}
+
+	calls, out, _ := run()
+	if want := [][]string{{"upload"}}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("second run calls = %v, want %v", calls, want)
+	}
+	if want := "(skipped, idempotency key bucket-assets succeeded in run " + first.RunID() + ")"; !strings.Contains(out, want) {
+		t.Errorf("output missing %q:\n%s", want, out)
+	}
+
+	calls, _, _ = run(WithForce(true))
+	if want := [][]string{{"mkbucket"}, {"upload"}}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("forced run calls = %v, want %v", calls, want)
+	}
+
+	var out2 bytes.Buffer
+	r, err := NewRunner("test.yaml", WithOut(&out2), WithLoadWorkflow(load), WithHistory(store), WithMode(ModeDryRun))
+	if err != nil {
+		t.Fatalf("NewRunner() error: %v", err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if want := "STEP 1.1: bucket (skipped, idempotency key succeeded in run"; !strings.Contains(out2.String(), want) {
+		t.Errorf("dry-run output missing %q:\n%s", want, out2.String())
+	}
+}
@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"maps"
+
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/matcher"
+)
+
+// Interface is what code starting runs needs of a Runner, so it can be tested with the fakes of
+// package runnertest instead of executing workflows
+type Interface interface {
+	// Run executes the workflow, or simulates it in ModeDryRun
+	Run() error
+	// DryRun simulates the workflow
+	DryRun() error
+	// Results returns what the last run produced
+	Results() Results
+}
+
+// Factory creates a runner for the workflow at path; New is the one that executes workflows
+type Factory func(path string, opts ...Option) (Interface, error)
+
+// New is NewRunner as a Factory
+func New(path string, opts ...Option) (Interface, error) {
+	return NewRunner(path, opts...)
+}
+
+// Results is what a run produced
+type Results struct {
+	// Record is the run record, nil before the first run and for dry runs
+	Record *history.Record
+	// Saved tells whether the runner records its runs in a run history
+	Saved bool
+	// Outputs holds the outputs of the run's steps, keyed by step name
+	Outputs map[string]map[string]string
+	// Findings are the problems the run's problem matchers found
+	Findings []matcher.Finding
+}
+
+// Results returns what the last run produced
+func (r *Runner) Results() Results {
+	outputs := make(map[string]map[string]string, len(r.outputs))
+	for step, values := range r.outputs {
+		outputs[step] = maps.Clone(values)
+	}
+	return Results{
+		Record:   r.record,
+		Saved:    r.record != nil && r.History != nil,
+		Outputs:  outputs,
+		Findings: r.Findings(),
+	}
+}
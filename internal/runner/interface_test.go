@@ -0,0 +1,37 @@
+package runner
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func TestRunner_Results(t *testing.T) {
+	stages := []dsl.Stage{{Name: "serve", Steps: []dsl.Step{
+		{Name: "api", Type: dsl.StepTypeExec, Run: []string{"serve"}, Ports: map[string]string{"api": "8080"}},
+	}}}
+	var calls [][]string
+	var r Interface
+	r, err := New("test.yaml", WithOut(&bytes.Buffer{}), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(mockRunCmd(&calls)),
+		WithHistory(history.NewStore(filepath.Join(t.TempDir(), "runs"))))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if res := r.Results(); res.Record != nil {
+		t.Errorf("Results() before the first run = %+v", res)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	res := r.Results()
+	if res.Record == nil || res.Record.Status != history.StatusSuccess || !res.Saved {
+		t.Errorf("Results() record = %+v, saved %v", res.Record, res.Saved)
+	}
+	if got := res.Outputs["api"]["port_api"]; got != "8080" {
+		t.Errorf("Results() outputs = %v, want port_api 8080", res.Outputs)
+	}
+}
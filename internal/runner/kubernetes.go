@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// podReadyTimeout bounds how long a stage waits for its pod, pulling the image included
+const podReadyTimeout = "5m"
+
+// stagePod is the pod the commands of a stage on Kubernetes run in, see dsl.Kubernetes
+type stagePod struct {
+	name      string
+	namespace string
+}
+
+// kubectl returns the kubectl command with args for the pod's namespace
+func (p *stagePod) kubectl(args ...string) []string {
+	cmd := []string{"kubectl"}
+	if p.namespace != "" {
+		cmd = append(cmd, "--namespace", p.namespace)
+	}
+	return append(cmd, args...)
+}
+
+// exec returns the command running a shell script in the pod, its stdin attached
+func (p *stagePod) exec(script string, tty bool) []string {
+	args := []string{"exec", "-i"}
+	if tty {
+		args = append(args, "-t")
+	}
+	return p.kubectl(append(args, p.name, "--", "sh", "-c", script)...)
+}
+
+// podName returns the name of the pod of a stage of a run
+func podName(runID string, stageIdx int) string {
+	suffix := fmt.Sprintf("-%d", stageIdx+1)
+	return "forge-" + dnsLabel(runID, 63-len("forge-")-len(suffix)) + suffix
+}
+
+// dnsLabel turns s into at most n lowercase letters, digits and '-' that neither start nor end
+// with '-', as Kubernetes requires of names and label values
+func dnsLabel(s string, n int) string {
+	s = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(s))
+	return strings.Trim(s[:min(len(s), n)], "-")
+}
+
+// podOverrides returns the container of a stage's pod, in the form of kubectl run --overrides;
+// it idles until the pod is deleted
+func podOverrides(k *dsl.Kubernetes) ([]byte, error) {
+	container := map[string]any{
+		"name":    "stage",
+		"image":   k.PodImage(),
+		"command": []string{"tail", "-f", "/dev/null"},
+	}
+	if res := k.Resources; res != nil {
+		quantities := map[string]string{}
+		if res.CPU != "" {
+			quantities["cpu"] = res.CPU
+		}
+		if res.Memory != "" {
+			quantities["memory"] = res.Memory
+		}
+		container["resources"] = map[string]any{"requests": quantities, "limits": quantities}
+	}
+	return json.Marshal(map[string]any{
+		"apiVersion": "v1",
+		"spec": map[string]any{
+			"containers":                    []any{container},
+			"terminationGracePeriodSeconds": 0,
+		},
+	})
+}
+
+// startPod starts the pod of a stage on Kubernetes and waits until it is ready; once it is
+// created stopPod deletes it again, even if it never got ready
+func (r *Runner) startPod(stageIdx int, stage *dsl.Stage) error {
+	if stage.RunsOn == nil || stage.RunsOn.Kubernetes == nil {
+		return nil
+	}
+	k := stage.RunsOn.Kubernetes
+	pod := &stagePod{name: podName(r.RunID(), stageIdx), namespace: k.Namespace}
+	overrides, err := podOverrides(k)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(r.Out, "  Starting pod %s of %s%s\n", pod.name, k.PodImage(), inNamespace(k.Namespace))
+	labels := "app.kubernetes.io/managed-by=forge,forge-run=" + dnsLabel(r.RunID(), 63)
+	err = r.RunCmd(pod.kubectl("run", pod.name, "--image="+k.PodImage(), "--restart=Never", "--labels="+labels, "--overrides="+string(overrides)))
+	if err != nil {
+		return fmt.Errorf("starting pod %s: %w", pod.name, err)
+	}
+	r.pod = pod
+	if err := r.RunCmd(pod.kubectl("wait", "--for=condition=Ready", "pod/"+pod.name, "--timeout="+podReadyTimeout)); err != nil {
+		return fmt.Errorf("pod %s did not get ready: %w", pod.name, err)
+	}
+	return nil
+}
+
+// stopPod deletes the pod of the current stage, if it has one, even if the run was cancelled
+func (r *Runner) stopPod() {
+	pod := r.pod
+	if pod == nil {
+		return
+	}
+	r.pod = nil
+	fmt.Fprintf(r.Out, "  Deleting pod %s\n", pod.name)
+	err := r.withoutCancel(func() error {
+		return r.RunCmd(pod.kubectl("delete", "pod", pod.name, "--ignore-not-found"))
+	})
+	if err != nil {
+		fmt.Fprintf(r.Out, "Warning: failed to delete pod %s: %v\n", pod.name, err)
+	}
+}
+
+// inNamespace describes the namespace of a pod, the kubectl context's when empty
+func inNamespace(namespace string) string {
+	if namespace == "" {
+		return ""
+	}
+	return " in namespace " + namespace
+}
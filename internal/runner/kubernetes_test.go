@@ -0,0 +1,146 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestPodName(t *testing.T) {
+	tests := []struct {
+		runID    string
+		stageIdx int
+		want     string
+	}{
+		{runID: "20261016-163644-a1b2c3", stageIdx: 1, want: "forge-20261016-163644-a1b2c3-2"},
+		{runID: "Nightly_Build.7", stageIdx: 0, want: "forge-nightly-build-7-1"},
+		{runID: strings.Repeat("a", 80) + "-", stageIdx: 11, want: "forge-" + strings.Repeat("a", 54) + "-12"},
+	}
+	for _, tt := range tests {
+		got := podName(tt.runID, tt.stageIdx)
+		if got != tt.want || len(got) > 63 {
+			t.Errorf("podName(%q, %d) = %q, want %q", tt.runID, tt.stageIdx, got, tt.want)
+		}
+	}
+}
+
+func TestRunner_RunsOnKubernetes(t *testing.T) {
+	const pod = "forge-20261016-163644-a1b2c3-2"
+	kubectl := []string{"kubectl", "--namespace", "ci"}
+	tests := []struct {
+		name    string
+		fail    string
+		wantErr string
+		// wantCalls are the kubectl subcommands run after the local make
+		wantCalls []string
+	}{
+		{name: "runs the steps in a pod", wantCalls: []string{"run", "wait", "exec", "exec", "delete"}},
+		{name: "fails to start the pod", fail: "run", wantErr: "starting pod " + pod, wantCalls: []string{"run"}},
+		{name: "deletes a pod that did not get ready", fail: "wait", wantErr: "pod " + pod + " did not get ready", wantCalls: []string{"run", "wait", "delete"}},
+		{name: "deletes the pod after a failed step", fail: "exec", wantErr: "step 'unit'", wantCalls: []string{"run", "wait", "exec", "delete"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls [][]string
+			run := func(argv []string) error {
+				calls = append(calls, argv)
+				if len(argv) > 3 && argv[3] == tt.fail {
+					return errors.New("exit status 1")
+				}
+				return nil
+			}
+			load := func(string) (*dsl.Workflow, error) {
+				return &dsl.Workflow{Name: "ci", Stages: []dsl.Stage{
+					{Name: "build", Steps: []dsl.Step{{Name: "compile", Type: dsl.StepTypeExec, Run: dsl.Command{"make"}}}},
+					{
+						Name:   "test",
+						RunsOn: &dsl.RunsOn{Kubernetes: &dsl.Kubernetes{Image: "golang:1.24", Namespace: "ci", Resources: &dsl.PodResources{CPU: "2", Memory: "4Gi"}}},
+						Env:    map[string]string{"CGO_ENABLED": "0"},
+						Steps: []dsl.Step{
+							{Name: "unit", Type: dsl.StepTypeExec, Workdir: "/src", Run: dsl.Command{"go", "test", "./..."}},
+							{Name: "vet", Type: dsl.StepTypeExec, Run: dsl.Command{"go", "vet", "./..."}},
+						},
+					},
+				}}, nil
+			}
+			var out bytes.Buffer
+			r, err := NewRunner("ci.yaml", WithOut(&out), WithLoadWorkflow(load), WithRunCmd(run), WithRunID("20261016-163644-a1b2c3"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = r.Run()
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+			if len(calls) == 0 || !slices.Equal(calls[0], []string{"make"}) {
+				t.Fatalf("commands = %q, want make to run here first", calls)
+			}
+			var got []string
+			for _, argv := range calls[1:] {
+				if len(argv) < 4 || !slices.Equal(argv[:3], kubectl) {
+					t.Fatalf("command = %q, want kubectl in namespace ci", argv)
+				}
+				got = append(got, argv[3])
+			}
+			if !slices.Equal(got, tt.wantCalls) {
+				t.Fatalf("kubectl commands = %q, want %q", got, tt.wantCalls)
+			}
+
+			create := calls[1]
+			for _, want := range []string{pod, "--image=golang:1.24", "--restart=Never", "--labels=app.kubernetes.io/managed-by=forge,forge-run=20261016-163644-a1b2c3",
+				`--overrides={"apiVersion":"v1","spec":{"containers":[{"command":["tail","-f","/dev/null"],"image":"golang:1.24","name":"stage","resources":{"limits":{"cpu":"2","memory":"4Gi"},"requests":{"cpu":"2","memory":"4Gi"}}}],"terminationGracePeriodSeconds":0}}`} {
+				if !slices.Contains(create, want) {
+					t.Errorf("kubectl run = %q, missing %q", create, want)
+				}
+			}
+			if tt.fail == "run" {
+				return
+			}
+			if wait := calls[2]; !slices.Equal(wait[4:], []string{"--for=condition=Ready", "pod/" + pod, "--timeout=5m"}) {
+				t.Errorf("kubectl wait = %q", wait)
+			}
+			if del := calls[len(calls)-1]; !slices.Equal(del[4:], []string{"pod", pod, "--ignore-not-found"}) {
+				t.Errorf("kubectl delete = %q", del)
+			}
+			if tt.fail == "wait" {
+				return
+			}
+			exec := calls[3]
+			if !slices.Equal(exec[3:8], []string{"exec", "-i", pod, "--", "sh"}) || exec[8] != "-c" {
+				t.Fatalf("kubectl exec = %q", exec)
+			}
+			script := exec[9]
+			if !strings.HasPrefix(script, "cd /src && exec env FORGE_RUN_ID=20261016-163644-a1b2c3 ") || !strings.Contains(script, " CGO_ENABLED=0 ") || !strings.HasSuffix(script, " go test ./...") {
+				t.Errorf("script = %q, want go test in /src with the stage's env", script)
+			}
+			if !strings.Contains(out.String(), "  Starting pod "+pod+" of golang:1.24 in namespace ci\n") || !strings.Contains(out.String(), "  Deleting pod "+pod+"\n") {
+				t.Errorf("output missing the pod's start and deletion:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestRunner_RunsOnKubernetes_DryRun(t *testing.T) {
+	stages := []dsl.Stage{{Name: "test", RunsOn: &dsl.RunsOn{Kubernetes: &dsl.Kubernetes{}}, Steps: []dsl.Step{
+		{Name: "unit", Type: dsl.StepTypeExec, Run: dsl.Command{"go", "test"}},
+	}}}
+	var out bytes.Buffer
+	r, err := NewRunner("ci.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)), WithMode(ModeDryRun))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+	if want := "[DRY-RUN] Would run its commands in a pod of alpine:3\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("dry-run output missing %q:\n%s", want, out.String())
+	}
+}
@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/matcher"
+)
+
+// WithGitHubAnnotations prints findings as GitHub Actions workflow commands so they show up as annotations
+func WithGitHubAnnotations(enabled bool) Option {
+	return func(r *Runner) { r.GitHubAnnotations = enabled }
+}
+
+// Findings returns the problems extracted by problem matchers during the last run
+func (r *Runner) Findings() []matcher.Finding {
+	return r.findings
+}
+
+// processStdout returns the writer for the stdout of the current step's processes
+func (r *Runner) processStdout() io.Writer {
+	if r.stdout == nil {
+		return r.Out
+	}
+	return r.stdout
+}
+
+// processStderr returns the writer for the stderr of the current step's processes
+func (r *Runner) processStderr() io.Writer {
+	if r.stderr == nil {
+		return r.Out
+	}
+	return r.stderr
+}
+
+// withMatchers runs fn while scanning the step's process output with its problem matchers
+func (r *Runner) withMatchers(step *dsl.Step, fn func() error) error {
+	if len(step.Matchers) == 0 {
+		return fn()
+	}
+
+	var custom []matcher.Definition
+	if r.wf != nil {
+		custom = r.wf.ProblemMatchers
+	}
+	matchers, err := matcher.Resolve(step.Matchers, custom)
+	if err != nil {
+		return err
+	}
+
+	w := matcher.NewWriter(matchers)
+	prevOut, prevErr := r.stdout, r.stderr
+	r.stdout = io.MultiWriter(r.processStdout(), w)
+	r.stderr = io.MultiWriter(r.processStderr(), w)
+	defer func() { r.stdout, r.stderr = prevOut, prevErr }()
+
+	err = fn()
+
+	found := w.Findings()
+	if rec := r.currentStepRecord(); rec != nil {
+		rec.Findings = found
+	}
+	r.findings = append(r.findings, found...)
+	if len(found) > 0 {
+		fmt.Fprintf(r.Out, "  Found %d problem(s)\n", len(found))
+	}
+	return err
+}
+
+// printFindings writes the summary of all findings and, if enabled, GitHub annotations
+func (r *Runner) printFindings() {
+	if len(r.findings) == 0 {
+		return
+	}
+
+	counts := make(map[matcher.Severity]int)
+	for _, f := range r.findings {
+		counts[f.Severity]++
+	}
+	fmt.Fprintf(r.Out, "\nProblems: %d error(s), %d warning(s), %d notice(s)\n",
+		counts[matcher.SeverityError], counts[matcher.SeverityWarning], counts[matcher.SeverityNotice])
+	for _, f := range r.findings {
+		fmt.Fprintf(r.Out, "  %s\n", f)
+	}
+
+	if r.GitHubAnnotations {
+		for _, f := range r.findings {
+			fmt.Fprintln(r.Out, f.Annotation())
+		}
+	}
+}
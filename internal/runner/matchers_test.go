@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/matcher"
+)
+
+func TestRunner_Run_ProblemMatchers(t *testing.T) {
+	out := new(bytes.Buffer)
+	load := func(path string) (*dsl.Workflow, error) {
+		return &dsl.Workflow{
+			Name: "lint",
+			ProblemMatchers: []matcher.Definition{
+				{Name: "custom", Pattern: `^WARN (?P<file>\S+): (?P<message>.+)$`, Severity: matcher.SeverityWarning},
+			},
+			Stages: []dsl.Stage{{Name: "check", Steps: []dsl.Step{
+				{Name: "vet", Type: dsl.StepTypeExec, Run: []string{"go", "vet"}, Matchers: []string{"go", "custom"}},
+				{Name: "plain", Type: dsl.StepTypeExec, Run: []string{"echo"}},
+			}}},
+		}, nil
+	}
+
+	r, err := NewRunner("test.yaml", WithOut(out), WithLoadWorkflow(load), WithGitHubAnnotations(true))
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	r.RunCmd = func(argv []string) error {
+		fmt.Fprintln(r.processStdout(), "main.go:3:1: unreachable code")
+		fmt.Fprintln(r.processStderr(), "WARN docs.md: stale")
+		if argv[0] == "go" {
+			return errors.New("exit status 1")
+		}
+		return nil
+	}
+
+	if err := r.Run(); err == nil {
+		t.Fatal("Run() should have failed")
+	}
+
+	findings := r.Findings()
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %+v", findings)
+	}
+	if findings[0].File != "main.go" || findings[1].Severity != matcher.SeverityWarning {
+		t.Errorf("unexpected findings %+v", findings)
+	}
+	if rec := r.Record().Steps[0]; len(rec.Findings) != 2 {
+		t.Errorf("findings not recorded in history: %+v", rec)
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"Problems: 1 error(s), 1 warning(s)",
+		"main.go:3:1: error: unreachable code [go]",
+		"::error file=main.go,line=3,col=1::unreachable code",
+		"::warning file=docs.md::stale",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q, got:\n%s", want, output)
+		}
+	}
+}
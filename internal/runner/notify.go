@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/notify"
+)
+
+// webhookBackoff is the delay before the first retry of a failed webhook delivery
+const webhookBackoff = time.Second
+
+// WithHTTPClient sets the client used to deliver notifications
+func WithHTTPClient(c *http.Client) Option {
+	return func(r *Runner) { r.HTTPClient = c }
+}
+
+// resolveNotifications interpolates the URLs and secrets of the workflow's notifications for the current run
+func (r *Runner) resolveNotifications(wf *dsl.Workflow) error {
+	r.webhooks, r.slack = nil, nil
+	if wf.Notifications == nil || r.OnAgent {
+		return nil
+	}
+	for _, hook := range wf.Notifications.Webhooks {
+		resolved, err := r.interpolate([]string{hook.URL, hook.Secret})
+		if err != nil {
+			return fmt.Errorf("notifications: %w", err)
+		}
+		hook.URL, hook.Secret = resolved[0], resolved[1]
+		r.webhooks = append(r.webhooks, hook)
+	}
+	for _, sl := range wf.Notifications.Slack {
+		if err := r.resolveSlack(&sl); err != nil {
+			return fmt.Errorf("notifications: %w", err)
+		}
+		r.slack = append(r.slack, sl)
+	}
+	return nil
+}
+
+func (r *Runner) resolveSlack(sl *notify.Slack) error {
+	resolved, err := r.interpolate([]string{sl.WebhookURL, sl.Token, sl.Channel})
+	if err != nil {
+		return err
+	}
+	sl.WebhookURL, sl.Token, sl.Channel = resolved[0], resolved[1], resolved[2]
+	return nil
+}
+
+func (r *Runner) sender() *notify.Sender {
+	return &notify.Sender{Client: r.HTTPClient, Sleep: r.Clock.Sleep, Backoff: webhookBackoff}
+}
+
+// summary describes the current run for notification messages
+func (r *Runner) summary() notify.Summary {
+	rec := r.record
+	sum := notify.Summary{Workflow: rec.Name, RunID: rec.ID, Status: "running", Duration: time.Since(rec.StartedAt)}
+	if !rec.FinishedAt.IsZero() {
+		sum.Status = string(rec.Status)
+		sum.Duration = rec.FinishedAt.Sub(rec.StartedAt)
+	}
+	for _, step := range rec.Steps {
+		if step.Status == history.StatusFailed {
+			sum.Status = string(history.StatusFailed)
+			sum.Stage, sum.Step, sum.Error = step.Stage, step.Step, step.Error
+			break
+		}
+	}
+	return sum
+}
+
+// notifySlack posts the workflow's Slack notifications that want the finished run's outcome
+func (r *Runner) notifySlack() {
+	sum := r.summary()
+	for _, sl := range r.slack {
+		if !sl.Wants(sum.Status) {
+			continue
+		}
+		if err := r.sender().SendSlack(sl, sum); err != nil {
+			fmt.Fprintf(r.Out, "Warning: failed to post Slack notification: %v\n", err)
+		}
+	}
+}
+
+// postSlack runs a slack step
+func (r *Runner) postSlack(step *dsl.Step) error {
+	sl := *step.Slack
+	if err := r.resolveSlack(&sl); err != nil {
+		return err
+	}
+	fmt.Fprintf(r.Out, "  Posting to Slack\n")
+	return r.sender().SendSlack(sl, r.summary())
+}
+
+// notify delivers ev to the workflow's webhooks; failed deliveries only print a warning
+func (r *Runner) notify(ev notify.Event) {
+	if len(r.webhooks) == 0 {
+		return
+	}
+	ev.RunID = r.RunID()
+	ev.Workflow = r.wf.Name
+	ev.Time = r.Clock.Now()
+
+	sender := r.sender()
+	for _, hook := range r.webhooks {
+		if err := sender.Send(hook, ev); err != nil {
+			fmt.Fprintf(r.Out, "Warning: failed to deliver %s notification: %v\n", ev.Event, err)
+		}
+	}
+}
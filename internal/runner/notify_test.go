@@ -0,0 +1,129 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/notify"
+)
+
+func TestRunner_Webhooks(t *testing.T) {
+	var mu sync.Mutex
+	var received []notify.Event
+	var signed bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var ev notify.Event
+		_ = json.Unmarshal(body, &ev)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, ev)
+		signed = r.Header.Get(notify.SignatureHeader) == notify.Sign("hook-secret", body)
+	}))
+	defer srv.Close()
+	t.Setenv("FORGE_TEST_WEBHOOK_SECRET", "hook-secret")
+
+	load := func(string) (*dsl.Workflow, error) {
+		return &dsl.Workflow{
+			Name: "notified",
+			Notifications: &dsl.Notifications{Webhooks: []notify.Webhook{
+				{URL: srv.URL, Secret: "${{ env.FORGE_TEST_WEBHOOK_SECRET }}"},
+			}},
+			Stages: []dsl.Stage{
+				{Name: "test", Steps: []dsl.Step{{Name: "unit", Type: dsl.StepTypeExec, Run: []string{"go", "test"}}}},
+			},
+		}, nil
+	}
+
+	r, err := NewRunner("test.yaml",
+		WithOut(new(bytes.Buffer)),
+		WithLoadWorkflow(load),
+		WithRunCmd(func([]string) error { return errors.New("exit status 1") }),
+		WithHTTPClient(srv.Client()),
+		WithSleep(func(time.Duration) {}),
+	)
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := r.Run(); err == nil {
+		t.Fatal("Run() succeeded, want failure")
+	}
+
+	var kinds []string
+	for _, ev := range received {
+		kinds = append(kinds, ev.Event)
+		if ev.RunID != r.RunID() || ev.Workflow != "notified" {
+			t.Errorf("event %s = %+v, want run id and workflow", ev.Event, ev)
+		}
+	}
+	if want := []string{notify.EventRunStarted, notify.EventStepFailed, notify.EventRunFinished}; !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("events = %v, want %v", kinds, want)
+	}
+	if received[1].Step != "unit" || received[2].Status != "failed" {
+		t.Errorf("step_failed = %+v, run_finished = %+v", received[1], received[2])
+	}
+	if !signed {
+		t.Error("requests are not signed with the interpolated secret")
+	}
+}
+
+func TestRunner_Slack(t *testing.T) {
+	var mu sync.Mutex
+	var messages []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct{ Text string }
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		defer mu.Unlock()
+		messages = append(messages, r.URL.Path+": "+payload.Text)
+	}))
+	defer srv.Close()
+	t.Setenv("FORGE_TEST_SLACK_URL", srv.URL)
+
+	load := func(string) (*dsl.Workflow, error) {
+		return &dsl.Workflow{
+			Name: "chatty",
+			Notifications: &dsl.Notifications{Slack: []notify.Slack{
+				{WebhookURL: "${{ env.FORGE_TEST_SLACK_URL }}/failure", On: []string{notify.OnFailure}, Message: "{{.Status}} at {{.Step}}: {{.Error}}"},
+				{WebhookURL: srv.URL + "/success", On: []string{notify.OnSuccess}},
+			}},
+			Stages: []dsl.Stage{
+				{Name: "deploy", Steps: []dsl.Step{
+					{Name: "announce", Type: dsl.StepTypeSlack, Slack: &notify.Slack{WebhookURL: srv.URL + "/step", Message: "deploying {{.Workflow}} ({{.Status}})"}},
+					{Name: "push", Type: dsl.StepTypeExec, Run: []string{"false"}},
+				}},
+			},
+		}, nil
+	}
+
+	r, err := NewRunner("test.yaml",
+		WithOut(new(bytes.Buffer)),
+		WithLoadWorkflow(load),
+		WithRunCmd(func([]string) error { return errors.New("exit status 1") }),
+		WithHTTPClient(srv.Client()),
+		WithSleep(func(time.Duration) {}),
+	)
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := r.Run(); err == nil {
+		t.Fatal("Run() succeeded, want failure")
+	}
+
+	want := []string{
+		"/step: deploying chatty (running)",
+		"/failure: failed at push: command execution failed: exit status 1",
+	}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("messages = %q, want %q", messages, want)
+	}
+}
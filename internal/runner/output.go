@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// WithStepWriter sends the process output of each step to the writers f returns for its stage
+// and step, e.g. a file or UI pane per step. A nil writer keeps the default destination.
+func WithStepWriter(f func(stage, step string) (stdout, stderr io.Writer)) Option {
+	return func(r *Runner) { r.StepWriter = f }
+}
+
+// withStepOutput runs fn with the step's process output routed to the writers of StepWriter or
+// WithProcessOutput; output without a destination goes to Out, each line prefixed with the step
+func (r *Runner) withStepOutput(stage, step string, fn func() error) error {
+	prevOut, prevErr := r.stdout, r.stderr
+	defer func() { r.stdout, r.stderr = prevOut, prevErr }()
+
+	if r.StepWriter != nil {
+		stdout, stderr := r.StepWriter(stage, step)
+		if stdout != nil {
+			r.stdout = stdout
+		}
+		if stderr != nil {
+			r.stderr = stderr
+		}
+	}
+	if r.stdout != nil && r.stderr != nil {
+		return fn()
+	}
+
+	prefix := "[" + step + "] "
+	if stage != "" {
+		prefix = "[" + stage + "/" + step + "] "
+	}
+	mu := new(sync.Mutex)
+	var writers []*prefixWriter
+	for _, w := range []*io.Writer{&r.stdout, &r.stderr} {
+		if *w == nil {
+			pw := &prefixWriter{w: r.Out, prefix: prefix, mu: mu}
+			writers = append(writers, pw)
+			*w = pw
+		}
+	}
+	defer func() {
+		for _, pw := range writers {
+			pw.Flush()
+		}
+	}()
+	return fn()
+}
+
+// prefixWriter writes complete lines to w with a prefix; writers sharing mu never interleave
+// within a line. A trailing partial line is held back until it is completed or flushed.
+type prefixWriter struct {
+	w       io.Writer
+	prefix  string
+	mu      *sync.Mutex
+	partial []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := len(b)
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			p.partial = append(p.partial, b...)
+			break
+		}
+		line := append(p.partial, b[:i+1]...)
+		p.partial = nil
+		if _, err := io.WriteString(p.w, p.prefix+string(line)); err != nil {
+			return n - len(b), err
+		}
+		b = b[i+1:]
+	}
+	return n, nil
+}
+
+// Flush writes a held back partial line, terminated by a newline
+func (p *prefixWriter) Flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.partial) > 0 {
+		io.WriteString(p.w, p.prefix+string(p.partial)+"\n")
+		p.partial = nil
+	}
+}
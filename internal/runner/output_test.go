@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_StepWriter(t *testing.T) {
+	stages := []dsl.Stage{
+		{Name: "build", Steps: []dsl.Step{
+			{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"compile"}},
+			{Name: "lint", Type: dsl.StepTypeExec, Run: []string{"lint"}},
+		}},
+	}
+
+	buffers := make(map[string]*bytes.Buffer)
+	var defaultErr bytes.Buffer
+	var r *Runner
+	r, err := NewRunner("test.yaml",
+		WithOut(new(bytes.Buffer)),
+		WithLoadWorkflow(mockLoadWorkflow(stages)),
+		WithProcessOutput(new(bytes.Buffer), &defaultErr),
+		WithOutputTail(5),
+		WithStepWriter(func(stage, step string) (stdout, stderr io.Writer) {
+			b := new(bytes.Buffer)
+			buffers[stage+"/"+step] = b
+			if step == "lint" {
+				// Only stdout is redirected; stderr keeps going to the default writer
+				return b, nil
+			}
+			return b, b
+		}),
+		WithRunCmd(func(argv []string) error {
+			fmt.Fprintf(r.processStdout(), "%s out\n", argv[0])
+			fmt.Fprintf(r.processStderr(), "%s err\n", argv[0])
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if got := buffers["build/compile"].String(); got != "compile out\ncompile err\n" {
+		t.Errorf("compile output = %q", got)
+	}
+	if got := buffers["build/lint"].String(); got != "lint out\n" {
+		t.Errorf("lint output = %q", got)
+	}
+	if got := defaultErr.String(); got != "lint err\n" {
+		t.Errorf("default stderr = %q, want only the output lint did not redirect", got)
+	}
+	if tail := r.record.Steps[0].OutputTail; !strings.Contains(strings.Join(tail, "\n"), "compile out") {
+		t.Errorf("output tail = %q, want the redirected output", tail)
+	}
+}
+
+func TestRunner_StepOutputThroughOut(t *testing.T) {
+	stages := []dsl.Stage{
+		{Name: "build", Steps: []dsl.Step{{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"compile"}}}},
+	}
+
+	var out bytes.Buffer
+	var r *Runner
+	r, err := NewRunner("test.yaml",
+		WithOut(&out),
+		WithLoadWorkflow(mockLoadWorkflow(stages)),
+		WithRunCmd(func(argv []string) error {
+			fmt.Fprint(r.processStdout(), "line one\nline ")
+			fmt.Fprint(r.processStderr(), "warning\n")
+			fmt.Fprint(r.processStdout(), "two\nno newline")
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	want := "[build/compile] line one\n[build/compile] warning\n[build/compile] line two\n[build/compile] no newline\n"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("Out = %q, want the step's output prefixed with %q", out.String(), want)
+	}
+}
+
+func TestWithCommandFunc(t *testing.T) {
+	stages := []dsl.Stage{
+		{Name: "build", Steps: []dsl.Step{{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"compile", "${{ vars.target }}"}}}},
+	}
+
+	var out bytes.Buffer
+	var got []string
+	r, err := NewRunner("test.yaml",
+		WithOut(&out),
+		WithLoadWorkflow(func(string) (*dsl.Workflow, error) {
+			return &dsl.Workflow{Name: "build", Vars: map[string]string{"target": "linux"}, Stages: stages}, nil
+		}),
+		WithCommandFunc(func(argv []string, stdout, stderr io.Writer) error {
+			got = argv
+			fmt.Fprintln(stdout, "built")
+			fmt.Fprintln(stderr, "warning")
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if !slices.Equal(got, []string{"compile", "linux"}) {
+		t.Errorf("argv = %q, want [compile linux]", got)
+	}
+	if want := "[build/compile] built\n[build/compile] warning\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("Out = %q, want %q", out.String(), want)
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var b bytes.Buffer
+	w := &prefixWriter{w: &b, prefix: "> ", mu: new(sync.Mutex)}
+	for _, s := range []string{"a", "b\nc\n", "", "d"} {
+		if n, err := w.Write([]byte(s)); err != nil || n != len(s) {
+			t.Fatalf("Write(%q) = %d, %v", s, n, err)
+		}
+	}
+	if b.String() != "> ab\n> c\n" {
+		t.Errorf("before Flush() = %q", b.String())
+	}
+	w.Flush()
+	w.Flush()
+	if b.String() != "> ab\n> c\n> d\n" {
+		t.Errorf("after Flush() = %q", b.String())
+	}
+}
@@ -0,0 +1,422 @@
+package runner
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/include"
+	"github.com/andre-koe/forge/pkg/version"
+)
+
+// ErrPlanStale is returned when the workflow file, or another file it was resolved from, changed
+// after the plan was created
+var ErrPlanStale = errors.New("workflow file changed since the plan was created")
+
+// Plan is a serialized, fully-resolved execution plan that can be reviewed and applied later
+type Plan struct {
+	Workflow     string        `json:"workflow"`
+	SHA256       string        `json:"sha256"`
+	CreatedAt    time.Time     `json:"created_at"`
+	Timezone     string        `json:"timezone"`
+	ForgeVersion string        `json:"forge_version"`
+	Resolved     *dsl.Workflow `json:"resolved"`
+	// Inputs holds the SHA-256 of the other files the plan was resolved from, by path: local
+	// includes, the lock file of remote includes and env files
+	Inputs map[string]string `json:"inputs,omitempty"`
+	// Vars, EnvFiles, Stages and Hooks describe what a run would do; see Runner.ResolvePlan
+	Vars     map[string]string     `json:"vars,omitempty"`
+	Profile  string                `json:"profile,omitempty"`
+	EnvFiles []string              `json:"env_files,omitempty"`
+	Stages   []PlanStage           `json:"stages,omitempty"`
+	Hooks    map[string][]PlanStep `json:"hooks,omitempty"`
+}
+
+// PlanStage is a stage as it would run, in execution order
+type PlanStage struct {
+	Name      string      `json:"name"`
+	Finally   bool        `json:"finally,omitempty"`
+	DependsOn []string    `json:"depends_on,omitempty"`
+	OnError   dsl.OnError `json:"on_error,omitempty"`
+	// Retries is how often the stage is run again after failing
+	Retries int `json:"retries,omitempty"`
+	// Changes, Platforms and ScheduleWindow are the conditions the stage runs under
+	Changes        []string         `json:"changes,omitempty"`
+	Platforms      []string         `json:"platforms,omitempty"`
+	ScheduleWindow string           `json:"schedule_window,omitempty"`
+	OutsideWindow  dsl.WindowPolicy `json:"outside_window,omitempty"`
+	Throttle       *dsl.Throttle    `json:"throttle,omitempty"`
+	RunsOn         *dsl.RunsOn      `json:"runs_on,omitempty"`
+	// Skip tells why the stage would not run, empty if it would
+	Skip      string                `json:"skip,omitempty"`
+	Restore   []string              `json:"restore,omitempty"`
+	Artifacts []string              `json:"artifacts,omitempty"`
+	Steps     []PlanStep            `json:"steps"`
+	Hooks     map[string][]PlanStep `json:"hooks,omitempty"`
+
+	// number is the 1-based position of the stage in the workflow file
+	number int
+	stage  *dsl.Stage
+}
+
+// PlanStep is a step as it would run, with its expressions interpolated
+type PlanStep struct {
+	Name string       `json:"name"`
+	Type dsl.StepType `json:"type"`
+	// Argv is the command of an exec or assert step
+	Argv []string `json:"argv,omitempty"`
+	Dir  string   `json:"dir,omitempty"`
+	// Before and After are the commands run around Argv, step_hooks included
+	Before [][]string `json:"before,omitempty"`
+	After  [][]string `json:"after,omitempty"`
+	// Env holds the variables the step sets on top of the inherited environment, from --env
+	// and env: of its stage and itself; variables of env files are not shown
+	Env      map[string]string `json:"env,omitempty"`
+	CleanEnv bool              `json:"clean_env,omitempty"`
+	TTY      bool              `json:"tty,omitempty"`
+	User     string            `json:"user,omitempty"`
+	Group    string            `json:"group,omitempty"`
+	// Stdin is the text passed to the standard input of Argv, StdinFile the file it is read
+	// from; StdoutFile and StderrFile receive its output
+	Stdin      string `json:"stdin,omitempty"`
+	StdinFile  string `json:"stdin_file,omitempty"`
+	StdoutFile string `json:"stdout_file,omitempty"`
+	StderrFile string `json:"stderr_file,omitempty"`
+	// Timeout bounds each command the step runs
+	Timeout string `json:"timeout,omitempty"`
+	// Retries is how often the step is run again after failing
+	Retries   int               `json:"retries,omitempty"`
+	Platforms []string          `json:"platforms,omitempty"`
+	Ports     map[string]string `json:"ports,omitempty"`
+	Cache     *dsl.Cache        `json:"cache,omitempty"`
+	// IdempotencyKey is the interpolated idempotency_key of the step
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// DryRun is what dry runs do with the step
+	DryRun dsl.DryRunMode `json:"dry_run,omitempty"`
+	With   map[string]any `json:"with,omitempty"`
+	// When is the condition the step runs under, evaluated when the run gets to it
+	When string `json:"when,omitempty"`
+	// Skip tells why the step would not run, empty if it would
+	Skip string `json:"skip,omitempty"`
+	// Steps are the steps of a group step
+	Steps []PlanStep `json:"steps,omitempty"`
+}
+
+// NewPlan loads and resolves the workflow at path with opts, see Runner.ResolvePlan
+func NewPlan(path string, load func(path string) (*dsl.Workflow, error), opts ...Option) (*Plan, error) {
+	r, err := NewRunner(path, append([]Option{WithOut(io.Discard), WithLoadWorkflow(load)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return r.ResolvePlan()
+}
+
+// ReadPlan reads a plan previously written with Plan.Write
+func ReadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid plan file: %w", err)
+	}
+	if p.Resolved == nil {
+		return nil, errors.New("invalid plan file: missing resolved workflow")
+	}
+	return &p, nil
+}
+
+// Write serializes the plan as indented JSON
+func (p *Plan) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// Verify checks that the workflow file and the other inputs of the plan still match the hashes
+// recorded in it
+func (p *Plan) Verify() error {
+	sum, err := fileSHA256(p.Workflow)
+	if err != nil {
+		return err
+	}
+	if sum != p.SHA256 {
+		return fmt.Errorf("%w: %s", ErrPlanStale, p.Workflow)
+	}
+	for _, path := range slices.Sorted(maps.Keys(p.Inputs)) {
+		if sum, err := fileSHA256(path); err != nil || sum != p.Inputs[path] {
+			return fmt.Errorf("%w: %s changed", ErrPlanStale, path)
+		}
+	}
+	return nil
+}
+
+// Options returns the runner options that apply the plan: its resolved workflow, with the
+// variables, profile and env files it was resolved with
+func (p *Plan) Options() []Option {
+	envFiles := p.EnvFiles
+	if p.Resolved.EnvFile != "" && len(envFiles) > 0 && envFiles[0] == p.Resolved.EnvFile {
+		// The runner loads the workflow's env file itself
+		envFiles = envFiles[1:]
+	}
+	return []Option{WithLoadWorkflow(p.Load), WithVars(p.Vars), WithProfile(p.Profile), WithEnvFiles(envFiles...)}
+}
+
+// Load returns the resolved workflow, for use with WithLoadWorkflow
+func (p *Plan) Load(string) (*dsl.Workflow, error) {
+	return p.Resolved, nil
+}
+
+// ResolvePlan loads the workflow and resolves what a run would do without running anything.
+// Changes filters are listed but not evaluated.
+func (r *Runner) ResolvePlan() (*Plan, error) {
+	mode := r.Mode
+	r.Mode = ModeDryRun
+	defer func() { r.Mode = mode }()
+	wf, loc, err := r.prepare()
+	if err != nil {
+		return nil, err
+	}
+	return r.plan(wf, loc)
+}
+
+// plan resolves what a run of the prepared workflow would do
+func (r *Runner) plan(wf *dsl.Workflow, loc *time.Location) (*Plan, error) {
+	r.wf = wf
+	sum, _ := fileSHA256(r.path)
+	p := &Plan{
+		Workflow:     r.path,
+		SHA256:       sum,
+		CreatedAt:    r.Clock.Now().In(loc),
+		Timezone:     loc.String(),
+		ForgeVersion: version.Version,
+		Resolved:     wf,
+		Vars:         r.vars,
+		Profile:      r.Profile,
+		EnvFiles:     r.EnvFiles,
+		Hooks:        make(map[string][]PlanStep),
+	}
+	if wf.EnvFile != "" {
+		p.EnvFiles = append([]string{wf.EnvFile}, p.EnvFiles...)
+	}
+	if sum != "" {
+		inputs, err := planInputs(r.path, wf.Included(), p.EnvFiles)
+		if err != nil {
+			return nil, err
+		}
+		p.Inputs = inputs
+	}
+	for _, stageIdx := range wf.StageOrder() {
+		stage := &wf.Stages[stageIdx]
+		ps := PlanStage{
+			Name:           stage.Name,
+			Finally:        stage.Finally,
+			DependsOn:      stage.DependsOn,
+			OnError:        stage.OnError,
+			Retries:        stage.StageRetries(),
+			Changes:        stage.Changes,
+			Platforms:      stage.Platforms,
+			Restore:        stage.Restore,
+			ScheduleWindow: stage.ScheduleWindow,
+			OutsideWindow:  stage.OutsideWindow,
+			Throttle:       stage.Throttle,
+			RunsOn:         stage.RunsOn,
+			Artifacts:      stage.Artifacts,
+			Hooks:          make(map[string][]PlanStep),
+			number:         stageIdx + 1,
+			stage:          stage,
+		}
+		switch {
+		case !r.stageSelected(stage.Name):
+			ps.Skip = "not selected"
+		case !r.onPlatform(stage.Platforms):
+			ps.Skip = r.notForPlatform()
+		}
+		for i := range stage.Steps {
+			step, err := r.planStep(stage.Name, i+1, &stage.Steps[i])
+			if err != nil {
+				return nil, fmt.Errorf("stage '%s', step '%s': %w", stage.Name, stage.Steps[i].Name, err)
+			}
+			ps.Steps = append(ps.Steps, step)
+		}
+		if err := r.planHooks(stage.Name, stage.Hooks(), ps.Hooks); err != nil {
+			return nil, fmt.Errorf("stage '%s', %w", stage.Name, err)
+		}
+		p.Stages = append(p.Stages, ps)
+	}
+	if err := r.planHooks("", wf.Hooks(), p.Hooks); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *Runner) planHooks(stage string, hooks map[string][]dsl.Step, dst map[string][]PlanStep) error {
+	for _, kind := range dsl.HookKinds {
+		for i := range hooks[kind] {
+			step, err := r.planStep(stage, i+1, &hooks[kind][i])
+			if err != nil {
+				return fmt.Errorf("%s hook '%s': %w", kind, hooks[kind][i].Name, err)
+			}
+			dst[kind] = append(dst[kind], step)
+		}
+	}
+	return nil
+}
+
+// planStep resolves a step of the named stage the way runStep would set it up
+func (r *Runner) planStep(stage string, index int, step *dsl.Step) (PlanStep, error) {
+	ps := PlanStep{
+		Name:      step.Name,
+		Type:      step.Type,
+		TTY:       step.TTY || (step.Type == dsl.StepTypeExec && r.Interactive && !step.Redirected()),
+		User:      step.User,
+		Group:     step.Group,
+		Platforms: step.Platforms,
+		Ports:     step.Ports,
+		Cache:     step.Cache,
+		With:      step.With,
+		When:      step.When,
+		DryRun:    step.DryRun,
+		Retries:   r.wf.StepRetries(step),
+	}
+	switch {
+	case !r.onPlatform(step.Platforms):
+		ps.Skip = r.notForPlatform()
+	case stage != "" && r.stepSkipped(stage, step.Name):
+		ps.Skip = "skipped with --skip-step"
+	}
+	key, succeededIn, err := r.idempotencyKey(step)
+	if err != nil {
+		return ps, err
+	}
+	ps.IdempotencyKey = key
+	if ps.Skip == "" && succeededIn != "" {
+		ps.Skip = fmt.Sprintf("skipped, idempotency key succeeded in run %s", succeededIn)
+	}
+
+	prev := r.current
+	r.current = currentStep{stage: stage, step: step.Name, index: index}
+	defer func() { r.current = prev }()
+	if step.Type == dsl.StepTypeGroup {
+		return ps, r.planGroup(stage, &ps, step)
+	}
+	err = r.withStepDir(stage, step, func() error {
+		return r.withStepEnv(stage, step, func() error {
+			ps.Dir = r.processDir()
+			ps.CleanEnv = r.cleanEnv
+			if len(r.profileEnv)+len(r.Env)+len(r.stepEnv) > 0 {
+				ps.Env = make(map[string]string)
+				for _, vars := range []map[string]string{r.profileEnv, r.Env, r.stepEnv} {
+					for k, v := range vars {
+						ps.Env[k] = r.maskSecrets(v)
+					}
+				}
+			}
+			if step.Type == dsl.StepTypeAssert {
+				argv, err := r.interpolate(step.Assert.Command)
+				ps.Argv = r.maskSecretsAll(argv)
+				return err
+			}
+			if step.Type != dsl.StepTypeExec {
+				return nil
+			}
+			argv, err := r.interpolate(runCommandLine(r.wf, step))
+			if err != nil {
+				return err
+			}
+			if step.ExpandGlobs {
+				if argv, err = expandGlobs(argv, r.processDir(), true); err != nil {
+					return err
+				}
+			}
+			ps.Argv = r.maskSecretsAll(argv)
+			red, err := r.stepRedirects(step)
+			if err != nil {
+				return err
+			}
+			ps.Stdin, ps.StdinFile = r.maskSecrets(red.Stdin), red.StdinFile
+			ps.StdoutFile, ps.StderrFile = red.StdoutFile, red.StderrFile
+			ps.Timeout = cmp.Or(r.wf.StepTimeout(step), commandTimeout).String()
+			before, after := stepCommands(r.wf, step)
+			if ps.Before, err = r.interpolateCommands(before); err != nil {
+				return err
+			}
+			ps.After, err = r.interpolateCommands(after)
+			return err
+		})
+	})
+	return ps, err
+}
+
+// planGroup resolves the steps of a group step of the named stage into ps
+func (r *Runner) planGroup(stage string, ps *PlanStep, group *dsl.Step) error {
+	ps.Dir = r.stepDir(r.wf, r.findStage(stage), group)
+	if d := r.wf.StepTimeout(group); d > 0 {
+		ps.Timeout = d.String()
+	}
+	prev := r.group
+	r.group = group.Name
+	defer func() { r.group = prev }()
+	steps := group.GroupSteps()
+	for i := range steps {
+		step, err := r.planStep(stage, i+1, &steps[i])
+		if err != nil {
+			return fmt.Errorf("step '%s': %w", steps[i].Name, err)
+		}
+		ps.Steps = append(ps.Steps, step)
+	}
+	return nil
+}
+
+// planInputs returns the SHA-256 of the files other than the workflow file at path a run of it
+// reads: its local includes, the lock file pinning its remote includes, and envFiles
+func planInputs(path string, includes []dsl.Include, envFiles []string) (map[string]string, error) {
+	files := slices.Clone(envFiles)
+	for _, inc := range includes {
+		switch {
+		case inc.Remote():
+			if lock := include.LockPath(path); !slices.Contains(files, lock) {
+				if _, err := os.Stat(lock); err == nil {
+					files = append(files, lock)
+				}
+			}
+		case filepath.IsAbs(inc.Path):
+			files = append(files, inc.Path)
+		default:
+			files = append(files, filepath.Join(filepath.Dir(path), inc.Path))
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	inputs := make(map[string]string, len(files))
+	for _, f := range files {
+		sum, err := fileSHA256(f)
+		if err != nil {
+			return nil, err
+		}
+		inputs[f] = sum
+	}
+	return inputs, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
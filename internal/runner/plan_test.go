@@ -0,0 +1,184 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestPlan_RoundTrip(t *testing.T) {
+	path := writeTestFile(t, "workflow.yaml", "name: test\n")
+	stages := []dsl.Stage{
+		{Name: "build", Steps: []dsl.Step{{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"go", "build"}}}},
+	}
+
+	p, err := NewPlan(path, mockLoadWorkflow(stages))
+	if err != nil {
+		t.Fatalf("NewPlan() failed: %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := p.Write(buf); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	planPath := writeTestFile(t, "plan.json", buf.String())
+
+	got, err := ReadPlan(planPath)
+	if err != nil {
+		t.Fatalf("ReadPlan() failed: %v", err)
+	}
+	if got.SHA256 != p.SHA256 || got.Workflow != path {
+		t.Errorf("ReadPlan() = %+v, want %+v", got, p)
+	}
+	if len(got.Resolved.Stages) != 1 || got.Resolved.Stages[0].Steps[0].Run[1] != "build" {
+		t.Errorf("resolved workflow not preserved: %+v", got.Resolved)
+	}
+	if err := got.Verify(); err != nil {
+		t.Errorf("Verify() unexpected error: %v", err)
+	}
+}
+
+func TestPlan_VerifyDetectsChanges(t *testing.T) {
+	path := writeTestFile(t, "workflow.yaml", "name: test\n")
+
+	p, err := NewPlan(path, mockLoadWorkflow(nil))
+	if err != nil {
+		t.Fatalf("NewPlan() failed: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("name: changed\n"), 0644); err != nil {
+		t.Fatalf("failed to modify workflow: %v", err)
+	}
+
+	if err := p.Verify(); !errors.Is(err, ErrPlanStale) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrPlanStale)
+	}
+}
+
+func TestPlan_VerifyDetectsInputChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		change string
+	}{
+		{name: "included file", change: "common.yaml"},
+		{name: "env file of the workflow", change: "ci.env"},
+		{name: "env file of the run", change: "local.env"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Chdir(dir)
+			files := map[string]string{
+				"workflow.yaml": "name: test\nenv_file: ci.env\ninclude:\n  - path: common.yaml\nstages:\n  - name: build\n    steps:\n      - {name: compile, type: exec, run: [go, build]}\n",
+				"common.yaml":   "stages:\n  - name: lint\n    steps:\n      - {name: vet, type: exec, run: [go, vet]}\n",
+				"ci.env":        "CGO_ENABLED=0\n",
+				"local.env":     "REGION=eu\n",
+			}
+			for name, content := range files {
+				if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			p, err := NewPlan(filepath.Join(dir, "workflow.yaml"), dsl.LoadWorkflowFromFile, WithEnvFiles("local.env"))
+			if err != nil {
+				t.Fatalf("NewPlan() failed: %v", err)
+			}
+			if len(p.Inputs) != 3 {
+				t.Errorf("Inputs = %v, want the include and both env files", p.Inputs)
+			}
+			if err := p.Verify(); err != nil {
+				t.Fatalf("Verify() before the change: %v", err)
+			}
+
+			if err := os.WriteFile(tt.change, []byte(files[tt.change]+"# edited\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Verify(); !errors.Is(err, ErrPlanStale) {
+				t.Errorf("Verify() error = %v, want %v", err, ErrPlanStale)
+			}
+		})
+	}
+}
+
+func TestReadPlan_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "not json", content: "not json"},
+		{name: "missing workflow", content: `{"workflow": "w.yaml"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadPlan(writeTestFile(t, "plan.json", tt.content)); err == nil {
+				t.Error("ReadPlan() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestRunner_ResolvePlan(t *testing.T) {
+	path := writeTestFile(t, "workflow.yaml", "name: test\n")
+	load := func(string) (*dsl.Workflow, error) {
+		return &dsl.Workflow{
+			Name: "deploy",
+			Vars: map[string]string{"tag": "dev"},
+			Stages: []dsl.Stage{
+				{Name: "push", Env: map[string]string{"REGION": "eu"}, OnError: dsl.OnErrorRetry, Steps: []dsl.Step{
+					{Name: "image", Type: dsl.StepTypeExec, Run: []string{"docker", "push", "app:${{ vars.tag }}", "${{ env.FORGE_STEP_INDEX }}"}, Env: map[string]string{"TIER": "gold"}, Workdir: "app"},
+					{Name: "mac-only", Type: dsl.StepTypeExec, Run: []string{"true"}, Platforms: []string{"darwin"}},
+				}, Always: []dsl.Step{{Name: "cleanup", Type: dsl.StepTypeSleep, Seconds: 1}}},
+				{Name: "docs", Steps: []dsl.Step{{Name: "publish", Type: dsl.StepTypeSleep, Seconds: 1}}},
+			},
+		}, nil
+	}
+
+	r, err := NewRunner(path, WithLoadWorkflow(load), WithVars(map[string]string{"tag": "v1"}), WithStages("push"), WithPlatform("linux", "amd64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := r.ResolvePlan()
+	if err != nil {
+		t.Fatalf("ResolvePlan() error: %v", err)
+	}
+
+	if p.SHA256 == "" || p.Vars["tag"] != "v1" || len(p.Stages) != 2 {
+		t.Fatalf("ResolvePlan() = %+v", p)
+	}
+	push := p.Stages[0]
+	wantImage := PlanStep{
+		Name:    "image",
+		Type:    dsl.StepTypeExec,
+		Argv:    []string{"docker", "push", "app:v1", "1"},
+		Dir:     filepath.Join(filepath.Dir(path), "app"),
+		Env:     map[string]string{"REGION": "eu", "TIER": "gold"},
+		Timeout: commandTimeout.String(),
+	}
+	if !reflect.DeepEqual(push.Steps[0], wantImage) {
+		t.Errorf("step = %+v, want %+v", push.Steps[0], wantImage)
+	}
+	if push.Retries != 1 || push.Skip != "" || push.Steps[1].Skip != "skipped, not for linux/amd64" {
+		t.Errorf("stage push = %+v", push)
+	}
+	if hooks := push.Hooks[dsl.HookAlways]; len(hooks) != 1 || hooks[0].Name != "cleanup" {
+		t.Errorf("hooks = %+v, want the always hook", push.Hooks)
+	}
+	if p.Stages[1].Skip != "not selected" {
+		t.Errorf("stage docs skip = %q, want it not selected", p.Stages[1].Skip)
+	}
+}
@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+// WithPlatform sets the "os/arch" platform matched against platforms of stages and steps
+// instead of the one forge runs on
+func WithPlatform(goos, goarch string) Option {
+	return func(r *Runner) { r.GOOS, r.GOARCH = goos, goarch }
+}
+
+// platform returns the OS and architecture stages and steps are matched against
+func (r *Runner) platform() (goos, goarch string) {
+	goos, goarch = r.GOOS, r.GOARCH
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+	return goos, goarch
+}
+
+// onPlatform reports whether a stage or step limited to platforms runs here
+func (r *Runner) onPlatform(platforms []string) bool {
+	goos, goarch := r.platform()
+	return dsl.MatchPlatform(platforms, goos, goarch)
+}
+
+// notForPlatform describes why a stage or step was skipped
+func (r *Runner) notForPlatform() string {
+	goos, goarch := r.platform()
+	return "skipped, not for " + goos + "/" + goarch
+}
+
+// skipStageForPlatform records the steps of a stage skipped by its platforms; done counts them
+func (r *Runner) skipStageForPlatform(stageIdx int, stage *dsl.Stage, done *int) {
+	fmt.Fprintf(r.Out, "\n=== STAGE %d: %s (%s) ===\n", stageIdx+1, stage.Name, r.notForPlatform())
+	stagePath := JoinPath(r.rootPath, stage.Name)
+	for _, step := range stage.Steps {
+		r.startStepRecord(stage.Name, &step, JoinPath(stagePath, step.Name))
+		r.skipStepRecord(history.StatusSkipped)
+		*done++
+	}
+}
+
+func (r *Runner) dryRunPlatforms(platforms []string, indent string) {
+	if len(platforms) == 0 {
+		return
+	}
+	fmt.Fprintf(r.Out, "[DRY-RUN] %sWould run only on %s\n", indent, strings.Join(platforms, ", "))
+}
@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func TestRunner_Platforms(t *testing.T) {
+	stages := []dsl.Stage{
+		{Name: "mac", Platforms: []string{"darwin"}, Steps: []dsl.Step{{Name: "brew", Type: dsl.StepTypeExec, Run: []string{"brew"}}}},
+		{Name: "setup", Steps: []dsl.Step{
+			{Name: "apt", Type: dsl.StepTypeExec, Run: []string{"apt"}, Platforms: []string{"linux"}},
+			{Name: "rosetta", Type: dsl.StepTypeExec, Run: []string{"rosetta"}, Platforms: []string{"darwin/arm64"}},
+			{Name: "build", Type: dsl.StepTypeExec, Run: []string{"build"}, Platforms: []string{"amd64", "arm64"}},
+		}},
+	}
+
+	tests := []struct {
+		name       string
+		goos       string
+		goarch     string
+		wantCalls  []string
+		wantStatus []history.Status
+		wantOut    string
+	}{
+		{
+			name: "linux", goos: "linux", goarch: "amd64",
+			wantCalls:  []string{"apt", "build"},
+			wantStatus: []history.Status{history.StatusSkipped, history.StatusSuccess, history.StatusSkipped, history.StatusSuccess},
+			wantOut:    "STEP 2.2: rosetta (skipped, not for linux/amd64)",
+		},
+		{
+			name: "apple silicon", goos: "darwin", goarch: "arm64",
+			wantCalls:  []string{"brew", "rosetta", "build"},
+			wantStatus: []history.Status{history.StatusSuccess, history.StatusSkipped, history.StatusSuccess, history.StatusSuccess},
+			wantOut:    "STEP 2.1: apt (skipped, not for darwin/arm64)",
+		},
+		{
+			name: "windows", goos: "windows", goarch: "386",
+			wantCalls:  nil,
+			wantStatus: []history.Status{history.StatusSkipped, history.StatusSkipped, history.StatusSkipped, history.StatusSkipped},
+			wantOut:    "=== STAGE 1: mac (skipped, not for windows/386) ===",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			runCmd := func(argv []string) error {
+				calls = append(calls, argv[0])
+				return nil
+			}
+			var out bytes.Buffer
+			r, err := NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(runCmd), WithPlatform(tt.goos, tt.goarch))
+			if err != nil {
+				t.Fatalf("NewRunner() failed: %v", err)
+			}
+			if err := r.Run(); err != nil {
+				t.Fatalf("Run() failed: %v", err)
+			}
+			var statuses []history.Status
+			for _, step := range r.Record().Steps {
+				statuses = append(statuses, step.Status)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) || !reflect.DeepEqual(statuses, tt.wantStatus) {
+				t.Errorf("commands = %v, statuses = %v; want %v, %v", calls, statuses, tt.wantCalls, tt.wantStatus)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output does not contain %q:\n%s", tt.wantOut, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// PluginPrefix is prepended to a step type to find the executable of an exec plugin on PATH
+const PluginPrefix = "forge-step-"
+
+// pluginTypePattern restricts the step types that can name an executable
+var pluginTypePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// PluginRequest is written as JSON to the stdin of an exec plugin, which is closed afterwards
+type PluginRequest struct {
+	RunID    string            `json:"run_id"`
+	Workflow string            `json:"workflow"`
+	Step     *dsl.Step         `json:"step"`
+	Vars     map[string]string `json:"vars,omitempty"`
+}
+
+// PluginMessage is one line of JSON an exec plugin writes to stdout. Log lines are written
+// to the step's output, outputs are exposed as the step's outputs and error describes why
+// the step failed when the plugin exits non-zero. Lines that are not JSON are passed through.
+type PluginMessage struct {
+	Log     string            `json:"log,omitempty"`
+	Outputs map[string]string `json:"outputs,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// ExecPlugins runs steps of type <name> with the executable forge-step-<name> found on PATH.
+// The plugin gets a PluginRequest on stdin and the step's environment, answers with
+// PluginMessage lines on stdout, writes diagnostics to stderr and reports failure by exit status.
+type ExecPlugins struct {
+	// LookPath finds plugin executables, exec.LookPath when nil
+	LookPath func(file string) (string, error)
+}
+
+// plugins is consulted for types no registered executor supports
+var plugins = &ExecPlugins{}
+
+func (p *ExecPlugins) lookPath(t dsl.StepType) (string, error) {
+	if !pluginTypePattern.MatchString(string(t)) {
+		return "", fmt.Errorf("no plugin for step type %q", t)
+	}
+	lookPath := p.LookPath
+	if lookPath == nil {
+		lookPath = exec.LookPath
+	}
+	return lookPath(PluginPrefix + string(t))
+}
+
+// Supports reports whether the plugin executable for t is on PATH
+func (p *ExecPlugins) Supports(t dsl.StepType) bool {
+	_, err := p.lookPath(t)
+	return err == nil
+}
+
+// Execute runs the plugin for the step
+func (p *ExecPlugins) Execute(ctx context.Context, step *dsl.Step, env StepEnv) error {
+	bin, err := p.lookPath(step.Type)
+	if err != nil {
+		return err
+	}
+	req, err := json.Marshal(PluginRequest{RunID: env.RunID, Workflow: env.Workflow, Step: step, Vars: env.Vars})
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, bin)
+	stopOnCancel(cmd)
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stderr = env.Stderr
+	cmd.Env = append(os.Environ(), env.Env...)
+	if env.CleanEnv {
+		cmd.Env = append([]string{}, env.Env...)
+	}
+	cmd.Dir = env.Dir
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	var failure string
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var msg PluginMessage
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &msg) != nil {
+			fmt.Fprintf(env.Stdout, "%s\n", line)
+			continue
+		}
+		if msg.Log != "" {
+			fmt.Fprintln(env.Stdout, msg.Log)
+		}
+		for k, v := range msg.Outputs {
+			if env.SetOutput != nil {
+				env.SetOutput(k, v)
+			}
+		}
+		if msg.Error != "" {
+			failure = msg.Error
+		}
+	}
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		// Drain the rest so the plugin is not blocked writing to a full pipe
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+
+	err = cmd.Wait()
+	if err != nil && failure != "" {
+		return fmt.Errorf("%s: %s", PluginPrefix+string(step.Type), failure)
+	}
+	return errors.Join(err, scanErr)
+}
@@ -0,0 +1,84 @@
+//go:build unix
+
+package runner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// writePlugin installs an exec plugin for step type typ that runs script with sh
+func writePlugin(t *testing.T, typ, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, PluginPrefix+typ), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestExecPlugins(t *testing.T) {
+	writePlugin(t, "greet", `req=$(cat)
+case "$req" in
+*'"who":"world"'*) ;;
+*) echo '{"error":"unexpected request"}'; exit 1 ;;
+esac
+echo '{"log":"hello world"}'
+echo "plain line"
+echo '{"outputs":{"greeted":"world"}}'
+echo "note from $FORGE_PLUGIN_TEST" >&2
+`)
+	writePlugin(t, "broken", `echo '{"error":"no cluster configured"}'; exit 3`)
+	t.Setenv("FORGE_PLUGIN_TEST", "greet")
+
+	p := &ExecPlugins{}
+	if !p.Supports("greet") || p.Supports("missing") || p.Supports("../greet") {
+		t.Error("Supports() does not match the plugins on PATH")
+	}
+
+	stages := []dsl.Stage{{Name: "hello", Steps: []dsl.Step{
+		{Name: "greet", Type: "greet", With: map[string]any{"who": "world"}},
+	}}}
+	if err := stages[0].Steps[0].Validate(); err != nil {
+		t.Fatalf("Validate() error for a plugin type: %v", err)
+	}
+	var out, stderr bytes.Buffer
+	r, err := NewRunner("test.yaml",
+		WithOut(new(bytes.Buffer)),
+		WithProcessOutput(&out, &stderr),
+		WithLoadWorkflow(mockLoadWorkflow(stages)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if out.String() != "hello world\nplain line\n" {
+		t.Errorf("stdout = %q, want the log and plain lines", out.String())
+	}
+	if stderr.String() != "note from greet\n" {
+		t.Errorf("stderr = %q, want the plugin's stderr with the environment", stderr.String())
+	}
+	if got := r.Outputs("greet")["greeted"]; got != "world" {
+		t.Errorf("Outputs()[greeted] = %q, want world", got)
+	}
+
+	stages = []dsl.Stage{{Name: "deploy", Steps: []dsl.Step{{Name: "broken", Type: "broken"}}}}
+	r, err = NewRunner("test.yaml",
+		WithOut(new(bytes.Buffer)),
+		WithProcessOutput(new(bytes.Buffer), new(bytes.Buffer)),
+		WithLoadWorkflow(mockLoadWorkflow(stages)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err == nil || !strings.Contains(err.Error(), "no cluster configured") {
+		t.Errorf("Run() error = %v, want the plugin's error", err)
+	}
+}
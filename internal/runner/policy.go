@@ -0,0 +1,29 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/policy"
+)
+
+// WithPolicies makes runs start only if the Rego policies in the given files or directories admit the workflow
+func WithPolicies(paths ...string) Option {
+	return func(r *Runner) { r.Policies = paths }
+}
+
+// WithPolicyEngine sets how policies are evaluated
+func WithPolicyEngine(e *policy.Engine) Option {
+	return func(r *Runner) { r.PolicyEngine = e }
+}
+
+// checkPolicies evaluates the runner's policies against the workflow before anything runs
+func (r *Runner) checkPolicies(wf *dsl.Workflow) error {
+	if len(r.Policies) == 0 {
+		return nil
+	}
+	if r.PolicyEngine == nil {
+		return fmt.Errorf("no policy engine to evaluate %d policy path(s)", len(r.Policies))
+	}
+	return r.PolicyEngine.Check(r.Policies, policy.Input{Workflow: wf, Vars: r.vars, Stages: r.Stages})
+}
@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/policy"
+)
+
+func TestRunner_Run_Policies(t *testing.T) {
+	stages := []dsl.Stage{{Name: "deploy", Steps: []dsl.Step{{Name: "push", Type: dsl.StepTypeExec, Run: []string{"push"}}}}}
+
+	tests := []struct {
+		name      string
+		policies  []string
+		deny      string
+		wantErr   error
+		wantCalls int
+	}{
+		{name: "no policies", wantCalls: 1},
+		{name: "admitted", policies: []string{"policies"}, deny: `[]`, wantCalls: 1},
+		{name: "denied", policies: []string{"policies"}, deny: `["deploy stages must have an approval step"]`, wantErr: policy.ErrDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input policy.Input
+			engine := &policy.Engine{Binary: "opa", Output: func(argv []string, stdin []byte) ([]byte, error) {
+				if err := json.Unmarshal(stdin, &input); err != nil {
+					t.Fatal(err)
+				}
+				return []byte(`{"result":[{"expressions":[{"value":` + tt.deny + `}]}]}`), nil
+			}}
+			var calls [][]string
+			r, err := NewRunner("test.yaml",
+				WithOut(new(bytes.Buffer)),
+				WithLoadWorkflow(mockLoadWorkflow(stages)),
+				WithRunCmd(mockRunCmd(&calls)),
+				WithPolicies(tt.policies...),
+				WithPolicyEngine(engine),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Run(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(calls) != tt.wantCalls {
+				t.Errorf("ran %d command(s), want %d", len(calls), tt.wantCalls)
+			}
+			if len(tt.policies) > 0 && input.Workflow.(map[string]any)["name"] != "mock-workflow" {
+				t.Errorf("policy input = %+v, want the workflow", input)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// portEnvPrefix prefixes the environment variables that carry allocated ports, e.g. FORGE_PORT_API
+const portEnvPrefix = "FORGE_PORT_"
+
+// WithPortAllocator sets the function that finds a free TCP port for "auto" port requests
+func WithPortAllocator(f func() (int, error)) Option {
+	return func(r *Runner) { r.FreePort = f }
+}
+
+// freePort asks the OS for a free TCP port on the loopback interface
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// PortEnv returns the environment variable name for the named port
+func PortEnv(name string) string {
+	return portEnvPrefix + strings.ToUpper(name)
+}
+
+// Ports returns the ports allocated during the current or last run, keyed by name
+func (r *Runner) Ports() map[string]int {
+	return r.ports
+}
+
+// allocatePort returns the port for name, allocating it on first request. Ports are shared by all
+// steps of a run that request the same name and are never handed out twice within a run.
+func (r *Runner) allocatePort(name, value string) (int, error) {
+	if port, ok := r.ports[name]; ok {
+		return port, nil
+	}
+
+	var port int
+	if value == dsl.PortAuto {
+		var err error
+		for attempt := 0; ; attempt++ {
+			if port, err = r.FreePort(); err != nil {
+				return 0, err
+			}
+			if !r.portInUse(port) {
+				break
+			}
+			if attempt == 10 {
+				return 0, fmt.Errorf("no free port found for %q", name)
+			}
+		}
+	} else {
+		port, _ = strconv.Atoi(value)
+	}
+
+	if r.ports == nil {
+		r.ports = make(map[string]int)
+	}
+	r.ports[name] = port
+	return port, nil
+}
+
+func (r *Runner) portInUse(port int) bool {
+	for _, p := range r.ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+// withPorts allocates the step's ports, exposes them as outputs and environment variables and runs fn
+func (r *Runner) withPorts(step *dsl.Step, fn func() error) error {
+	if len(step.Ports) == 0 {
+		return fn()
+	}
+
+	names := slices.Sorted(maps.Keys(step.Ports))
+	env := make([]string, 0, len(names))
+	for _, name := range names {
+		port, err := r.allocatePort(name, step.Ports[name])
+		if err != nil {
+			return fmt.Errorf("allocating port %q: %w", name, err)
+		}
+		fmt.Fprintf(r.Out, "  Port %s: %d\n", name, port)
+		r.setOutput(step.Name, "port_"+name, strconv.Itoa(port))
+		env = append(env, fmt.Sprintf("%s=%d", PortEnv(name), port))
+	}
+
+	prev := r.env
+	r.env = append(slices.Clone(prev), env...)
+	defer func() { r.env = prev }()
+	return fn()
+}
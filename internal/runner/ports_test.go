@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_Ports(t *testing.T) {
+	stages := []dsl.Stage{
+		{Name: "services", Steps: []dsl.Step{
+			{Name: "api", Type: dsl.StepTypeExec, Run: []string{"serve"}, Ports: map[string]string{"api": dsl.PortAuto, "db": "5432"}},
+			{Name: "e2e", Type: dsl.StepTypeExec, Run: []string{"test"}, Ports: map[string]string{"api": dsl.PortAuto, "metrics": dsl.PortAuto}},
+		}},
+	}
+
+	// The allocator hands out 9000 twice to check that a port is never reused within a run
+	free := []int{9000, 9000, 9001}
+	allocator := func() (int, error) {
+		p := free[0]
+		free = free[1:]
+		return p, nil
+	}
+
+	var envs [][]string
+	var r *Runner
+	r, err := NewRunner("test.yaml",
+		WithOut(new(bytes.Buffer)),
+		WithLoadWorkflow(mockLoadWorkflow(stages)),
+		WithPortAllocator(allocator),
+		WithRunCmd(func(argv []string) error {
+			envs = append(envs, r.env)
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	wantEnvs := [][]string{
+		{"FORGE_PORT_API=9000", "FORGE_PORT_DB=5432"},
+		{"FORGE_PORT_API=9000", "FORGE_PORT_METRICS=9001"},
+	}
+	if !reflect.DeepEqual(envs, wantEnvs) {
+		t.Errorf("env = %v, want %v", envs, wantEnvs)
+	}
+	if got := r.Outputs("e2e")["port_metrics"]; got != "9001" {
+		t.Errorf("Outputs(e2e)[port_metrics] = %q, want %q", got, "9001")
+	}
+	if want := map[string]int{"api": 9000, "db": 5432, "metrics": 9001}; !reflect.DeepEqual(r.Ports(), want) {
+		t.Errorf("Ports() = %v, want %v", r.Ports(), want)
+	}
+	if r.env != nil {
+		t.Errorf("env after run = %v, want nil", r.env)
+	}
+}
+
+func TestFreePort(t *testing.T) {
+	port, err := freePort()
+	if err != nil {
+		t.Fatalf("freePort() error: %v", err)
+	}
+	if port <= 0 || port > 65535 {
+		t.Errorf("freePort() = %d, want a valid port", port)
+	}
+}
+
+func TestRunCommand_Env(t *testing.T) {
+	var out bytes.Buffer
+	err := runCommand(context.Background(), []string{"sh", "-c", "echo $FORGE_PORT_API"}, procAttr{Env: []string{"FORGE_PORT_API=1234"}, Stdout: &out, Stderr: &out})
+	if err != nil {
+		t.Fatalf("runCommand() error: %v", err)
+	}
+	if got := strings.TrimSpace(out.String()); got != "1234" {
+		t.Errorf("output = %q, want %q", got, "1234")
+	}
+}
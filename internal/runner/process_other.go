@@ -0,0 +1,10 @@
+//go:build !unix
+
+package runner
+
+import "os/exec"
+
+// stopOnCancel kills the command when it is cancelled; this platform has no process groups to signal
+func stopOnCancel(cmd *exec.Cmd) {
+	cmd.WaitDelay = killGrace
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// stopOnCancel makes cancelling the command send SIGTERM to its process group and SIGKILL after
+// killGrace, so processes started by the step stop too. Commands reading from a terminal stay in
+// forge's process group, which the terminal signals as a whole on Ctrl-C, and only the command
+// itself is signalled.
+func stopOnCancel(cmd *exec.Cmd) {
+	cmd.WaitDelay = killGrace
+	if f, ok := cmd.Stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+		return
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	stopGroupOnCancel(cmd)
+}
+
+// stopGroupOnCancel signals the process group led by the command when it is cancelled
+func stopGroupOnCancel(cmd *exec.Cmd) {
+	cmd.WaitDelay = killGrace
+	cmd.Cancel = func() error {
+		pgid := cmd.Process.Pid
+		// Children may outlive the step's process; the group is killed once the grace period ends
+		time.AfterFunc(killGrace, func() { _ = syscall.Kill(-pgid, syscall.SIGKILL) })
+		return syscall.Kill(-pgid, syscall.SIGTERM)
+	}
+}
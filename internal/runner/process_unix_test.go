@@ -0,0 +1,33 @@
+//go:build unix
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"golang.org/x/term"
+)
+
+func TestRunCommand_CancelStopsProcessGroup(t *testing.T) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		t.Skip("commands reading from a terminal are not started in their own process group")
+	}
+
+	// The background sleep keeps the output pipe open; only signalling the group ends it before killGrace
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	start := time.Now()
+	err := runCommand(ctx, []string{"sh", "-c", "sleep 30 & sleep 30"}, procAttr{Stdout: io.Discard, Stderr: io.Discard})
+	if err == nil || errors.Is(err, exec.ErrWaitDelay) {
+		t.Fatalf("runCommand() error = %v, want the command to be terminated", err)
+	}
+	if elapsed := time.Since(start); elapsed >= killGrace {
+		t.Errorf("runCommand() took %s after cancellation, want the process group to stop on SIGTERM", elapsed)
+	}
+}
@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/expr"
+)
+
+// secretMask replaces the values of profile secrets in dry runs and plans
+const secretMask = "***"
+
+// WithProfile selects the named profile of the workflow, see dsl.Profile
+func WithProfile(name string) Option {
+	return func(r *Runner) { r.Profile = name }
+}
+
+// profile returns the selected profile of wf, nil when none is selected
+func (r *Runner) profile(wf *dsl.Workflow) (*dsl.Profile, error) {
+	if r.Profile == "" {
+		return nil, nil
+	}
+	p, ok := wf.Profiles[r.Profile]
+	if !ok {
+		if len(wf.Profiles) == 0 {
+			return nil, fmt.Errorf("unknown profile %q: the workflow declares no profiles", r.Profile)
+		}
+		return nil, fmt.Errorf("unknown profile %q, use one of %s", r.Profile, strings.Join(slices.Sorted(maps.Keys(wf.Profiles)), ", "))
+	}
+	return &p, nil
+}
+
+// resolveProfile interpolates the env and secrets of the selected profile, after the variables
+// and env files are resolved, and prints the profile; dry runs list its values without secrets
+func (r *Runner) resolveProfile(wf *dsl.Workflow, prefix string) error {
+	r.profileEnv, r.secrets = nil, nil
+	p, err := r.profile(wf)
+	if err != nil || p == nil {
+		return err
+	}
+
+	ctx := r.exprContext(false)
+	r.profileEnv = make(map[string]string, len(p.Env)+len(p.Secrets))
+	for _, env := range []map[string]string{p.Env, p.Secrets} {
+		for k, v := range env {
+			expanded, err := expr.Interpolate(v, ctx)
+			if err != nil {
+				return fmt.Errorf("profile %s: %s: %w", r.Profile, k, err)
+			}
+			r.profileEnv[k] = expanded
+		}
+	}
+	for k := range p.Secrets {
+		if v := r.profileEnv[k]; v != "" {
+			r.secrets = append(r.secrets, v)
+		}
+	}
+	// Longer secrets first, so one containing another is masked whole
+	slices.SortFunc(r.secrets, func(a, b string) int { return len(b) - len(a) })
+
+	fmt.Fprintf(r.Out, "%sUsing profile %s\n", prefix, r.Profile)
+	if r.Mode != ModeDryRun {
+		return nil
+	}
+	for _, k := range slices.Sorted(maps.Keys(p.Vars)) {
+		fmt.Fprintf(r.Out, "%s  vars.%s = %s\n", prefix, k, r.vars[k])
+	}
+	for _, k := range slices.Sorted(maps.Keys(p.Env)) {
+		fmt.Fprintf(r.Out, "%s  env %s = %s\n", prefix, k, r.maskSecrets(r.profileEnv[k]))
+	}
+	for _, k := range slices.Sorted(maps.Keys(p.Secrets)) {
+		fmt.Fprintf(r.Out, "%s  secret %s = %s\n", prefix, k, secretMask)
+	}
+	return nil
+}
+
+// maskSecrets replaces the values of the profile's secrets in s
+func (r *Runner) maskSecrets(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, secretMask)
+	}
+	return s
+}
+
+// maskSecretsAll replaces the values of the profile's secrets in every element of list
+func (r *Runner) maskSecretsAll(list []string) []string {
+	if len(r.secrets) == 0 {
+		return list
+	}
+	masked := make([]string, len(list))
+	for i, s := range list {
+		masked[i] = r.maskSecrets(s)
+	}
+	return masked
+}
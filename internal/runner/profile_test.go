@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func profileWorkflow(string) (*dsl.Workflow, error) {
+	return &dsl.Workflow{
+		Name: "deploy",
+		Vars: map[string]string{"replicas": "1", "region": "eu"},
+		Profiles: map[string]dsl.Profile{
+			"prod": {
+				Vars:    map[string]string{"replicas": "3", "region": "us"},
+				Env:     map[string]string{"STAGE": "prod-${{ vars.region }}"},
+				Secrets: map[string]string{"TOKEN": "${{ env.FORGE_TEST_PROD_TOKEN }}"},
+			},
+			"dev": {},
+		},
+		Stages: []dsl.Stage{{Name: "deploy", Steps: []dsl.Step{
+			{Name: "apply", Type: dsl.StepTypeExec, Run: []string{"deploy", "--replicas", "${{ vars.replicas }}", "--region", "${{ vars.region }}", "--token", "${{ env.TOKEN }}", "--stage", "${{ env.STAGE }}"}},
+		}}},
+	}, nil
+}
+
+func TestRunner_Profile(t *testing.T) {
+	t.Setenv("FORGE_TEST_PROD_TOKEN", "s3cret")
+	tests := []struct {
+		name     string
+		opts     []Option
+		wantArgv []string
+		wantErr  string
+	}{
+		{
+			name:     "no profile",
+			wantArgv: []string{"deploy", "--replicas", "1", "--region", "eu", "--token", "", "--stage", ""},
+		},
+		{
+			name:     "prod",
+			opts:     []Option{WithProfile("prod")},
+			wantArgv: []string{"deploy", "--replicas", "3", "--region", "us", "--token", "s3cret", "--stage", "prod-us"},
+		},
+		{
+			name:     "flags win over the profile",
+			opts:     []Option{WithProfile("prod"), WithVars(map[string]string{"replicas": "5"}), WithEnv(map[string]string{"STAGE": "canary"})},
+			wantArgv: []string{"deploy", "--replicas", "5", "--region", "us", "--token", "s3cret", "--stage", "canary"},
+		},
+		{
+			name:    "unknown profile",
+			opts:    []Option{WithProfile("staging")},
+			wantErr: `unknown profile "staging", use one of dev, prod`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			var calls [][]string
+			opts := append([]Option{WithOut(&out), WithLoadWorkflow(profileWorkflow), WithRunCmd(mockRunCmd(&calls))}, tt.opts...)
+			r, err := NewRunner("deploy.yml", opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = r.Run()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			if !reflect.DeepEqual(calls, [][]string{tt.wantArgv}) {
+				t.Errorf("calls = %q, want %q", calls, tt.wantArgv)
+			}
+		})
+	}
+}
+
+func TestRunner_Profile_DryRun(t *testing.T) {
+	t.Setenv("FORGE_TEST_PROD_TOKEN", "s3cret")
+	var out bytes.Buffer
+	r, err := NewRunner("deploy.yml", WithOut(&out), WithLoadWorkflow(profileWorkflow), WithMode(ModeDryRun), WithProfile("prod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	for _, want := range []string{
+		"[DRY-RUN] Using profile prod\n",
+		"[DRY-RUN]   vars.region = us\n",
+		"[DRY-RUN]   vars.replicas = 3\n",
+		"[DRY-RUN]   env STAGE = prod-us\n",
+		"[DRY-RUN]   secret TOKEN = ***\n",
+		"Would execute command: [deploy --replicas 3 --region us --token *** --stage prod-us]",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "s3cret") {
+		t.Errorf("dry run shows the secret:\n%s", out.String())
+	}
+
+	p, err := r.ResolvePlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Profile != "prod" || p.Stages[0].Steps[0].Env["TOKEN"] != secretMask || p.Stages[0].Steps[0].Env["STAGE"] != "prod-us" {
+		t.Errorf("plan profile %q, env %v, want prod with the secret masked", p.Profile, p.Stages[0].Steps[0].Env)
+	}
+}
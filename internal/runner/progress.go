@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// progressDelay is how long a step runs before its progress line appears, so quick steps do not flicker
+const progressDelay = 500 * time.Millisecond
+
+// progressRefresh is how often the progress line is redrawn
+const progressRefresh = 100 * time.Millisecond
+
+// WithProgress shows a spinner with the elapsed time of the running step on w, which should be
+// a terminal; sleep steps count down instead. The line is cleared before anything else is written
+// to Out or the step's output.
+func WithProgress(w io.Writer) Option {
+	return func(r *Runner) { r.Progress = w }
+}
+
+// progressLine is the spinner line drawn below the output
+type progressLine struct {
+	w     io.Writer
+	mu    sync.Mutex
+	shown bool
+	frame int
+}
+
+// draw replaces the line with text
+func (p *progressLine) draw(text string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, "\r\x1b[K%s %s", spinnerFrames[p.frame%len(spinnerFrames)], text)
+	p.frame++
+	p.shown = true
+}
+
+// clear removes the line; the caller holds mu
+func (p *progressLine) clear() {
+	if p.shown {
+		io.WriteString(p.w, "\r\x1b[K")
+		p.shown = false
+	}
+}
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// clearingWriter clears the progress line before writing to w
+type clearingWriter struct {
+	line *progressLine
+	w    io.Writer
+}
+
+func (c clearingWriter) Write(b []byte) (int, error) {
+	c.line.mu.Lock()
+	defer c.line.mu.Unlock()
+	c.line.clear()
+	return c.w.Write(b)
+}
+
+// progressText describes how long the step has been running at now, or how long a sleep step has left
+func progressText(step *dsl.Step, elapsed time.Duration) string {
+	if step.Type == dsl.StepTypeSleep {
+		left := max(0, time.Duration(step.Seconds)*time.Second-elapsed)
+		return fmt.Sprintf("%s: %s left", step.Name, left.Round(time.Second))
+	}
+	return fmt.Sprintf("%s: %s", step.Name, elapsed.Round(time.Second))
+}
+
+// withProgress runs fn while a progress line for the step is kept up to date; steps in a
+// pseudo-terminal own the terminal and get none
+func (r *Runner) withProgress(step *dsl.Step, fn func() error) error {
+	if r.Progress == nil || step.TTY || (step.Type == dsl.StepTypeExec && r.Interactive) {
+		return fn()
+	}
+
+	line := &progressLine{w: r.Progress}
+	prevOut, prevStdout, prevStderr := r.Out, r.stdout, r.stderr
+	r.Out = clearingWriter{line: line, w: r.Out}
+	if r.stdout != nil {
+		r.stdout = clearingWriter{line: line, w: r.stdout}
+	}
+	if r.stderr != nil {
+		r.stderr = clearingWriter{line: line, w: r.stderr}
+	}
+
+	start := r.Clock.Now()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(progressRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if elapsed := r.Clock.Now().Sub(start); elapsed >= progressDelay {
+					line.draw(progressText(step, elapsed))
+				}
+			}
+		}
+	}()
+
+	err := fn()
+	close(done)
+	<-stopped
+	line.mu.Lock()
+	line.clear()
+	line.mu.Unlock()
+	r.Out, r.stdout, r.stderr = prevOut, prevStdout, prevStderr
+	return err
+}
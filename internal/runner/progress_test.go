@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/clock"
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestProgressText(t *testing.T) {
+	tests := []struct {
+		name    string
+		step    dsl.Step
+		elapsed time.Duration
+		want    string
+	}{
+		{name: "exec", step: dsl.Step{Name: "build", Type: dsl.StepTypeExec}, elapsed: 61400 * time.Millisecond, want: "build: 1m1s"},
+		{name: "sleep", step: dsl.Step{Name: "wait", Type: dsl.StepTypeSleep, Seconds: 10}, elapsed: 3 * time.Second, want: "wait: 7s left"},
+		{name: "sleep over", step: dsl.Step{Name: "wait", Type: dsl.StepTypeSleep, Seconds: 1}, elapsed: 2 * time.Second, want: "wait: 0s left"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := progressText(&tt.step, tt.elapsed); got != tt.want {
+				t.Errorf("progressText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe for the progress goroutine and the test to share
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRunner_Progress(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	stages := []dsl.Stage{{Name: "build", Steps: []dsl.Step{{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"go", "build"}}}}}
+	out := new(lockedBuffer)
+	var r *Runner
+	runCmd := func(argv []string) error {
+		fake.Advance(3 * time.Second)
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(out.String(), "compile: 3s") {
+			if time.Now().After(deadline) {
+				t.Fatal("progress line not drawn")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		r.Out.Write([]byte("compiled\n"))
+		return nil
+	}
+	r, err := NewRunner("test.yaml", WithOut(out), WithProgress(out), WithClock(fake), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(runCmd))
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "compile: 3s\r\x1b[Kcompiled\n") {
+		t.Errorf("output is not written over the progress line: %q", out.String())
+	}
+	if _, ok := r.Out.(clearingWriter); ok {
+		t.Error("Out is still wrapped after the step")
+	}
+}
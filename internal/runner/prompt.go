@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// StepAction is what the runner does with a step it paused before, see WithStepPrompt
+type StepAction int
+
+const (
+	// StepContinue runs the step
+	StepContinue StepAction = iota
+	// StepSkip skips the step and pauses before the next one
+	StepSkip
+	// StepAbort stops the run like a cancellation; hooks and finally stages still run
+	StepAbort
+)
+
+// StepPrompt decides about a step of the named stage before it runs
+type StepPrompt func(stage string, step PlanStep) StepAction
+
+// WithStepPrompt pauses before every step of a stage and asks prompt whether to run it
+func WithStepPrompt(prompt StepPrompt) Option {
+	return func(r *Runner) { r.StepPrompt = prompt }
+}
+
+// TerminalStepPrompt shows the command, directory and environment of each step on out and
+// reads the answer from in; the end of in aborts the run
+func TerminalStepPrompt(in io.Reader, out io.Writer) StepPrompt {
+	reader := bufio.NewReader(in)
+	return func(stage string, step PlanStep) StepAction {
+		fmt.Fprintf(out, "\nNext step: %s.%s (%s)\n", stage, step.Name, step.Type)
+		if len(step.Argv) > 0 {
+			fmt.Fprintf(out, "  command: %s\n", strings.Join(step.Argv, " "))
+		}
+		if step.Dir != "" {
+			fmt.Fprintf(out, "  dir:     %s\n", step.Dir)
+		}
+		for _, name := range slices.Sorted(maps.Keys(step.Env)) {
+			fmt.Fprintf(out, "  env:     %s=%s\n", name, step.Env[name])
+		}
+		for {
+			fmt.Fprint(out, "[c]ontinue, [s]kip or [a]bort? ")
+			line, err := reader.ReadString('\n')
+			if err != nil && line == "" {
+				fmt.Fprintln(out)
+				return StepAbort
+			}
+			switch strings.ToLower(strings.TrimSpace(line)) {
+			case "", "c", "continue":
+				return StepContinue
+			case "s", "skip":
+				return StepSkip
+			case "a", "abort":
+				return StepAbort
+			}
+			if err != nil {
+				fmt.Fprintln(out)
+				return StepAbort
+			}
+		}
+	}
+}
+
+// promptStep asks the StepPrompt, if any, about a step of the stage at 1-based index
+func (r *Runner) promptStep(stage string, index int, step *dsl.Step) (StepAction, error) {
+	if r.StepPrompt == nil {
+		return StepContinue, nil
+	}
+	ps, err := r.planStep(stage, index, step)
+	if err != nil {
+		return StepAbort, err
+	}
+	return r.StepPrompt(stage, ps), nil
+}
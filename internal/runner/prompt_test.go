@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func TestRunner_StepPrompt(t *testing.T) {
+	stages := []dsl.Stage{{Name: "build", Steps: []dsl.Step{
+		{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"go", "build", "${{ env.FORGE_STAGE }}"}},
+		{Name: "lint", Type: dsl.StepTypeExec, Run: []string{"golangci-lint", "run"}},
+		{Name: "test", Type: dsl.StepTypeExec, Run: []string{"go", "test"}},
+	}}}
+
+	tests := []struct {
+		name       string
+		answers    map[string]StepAction
+		wantCalls  [][]string
+		wantStatus []history.Status
+		wantErr    error
+	}{
+		{
+			name:       "continue",
+			wantCalls:  [][]string{{"go", "build", "build"}, {"golangci-lint", "run"}, {"go", "test"}},
+			wantStatus: []history.Status{history.StatusSuccess, history.StatusSuccess, history.StatusSuccess},
+		},
+		{
+			name:       "skip",
+			answers:    map[string]StepAction{"lint": StepSkip},
+			wantCalls:  [][]string{{"go", "build", "build"}, {"go", "test"}},
+			wantStatus: []history.Status{history.StatusSuccess, history.StatusSkipped, history.StatusSuccess},
+		},
+		{
+			name:       "abort",
+			answers:    map[string]StepAction{"lint": StepAbort},
+			wantCalls:  [][]string{{"go", "build", "build"}},
+			wantStatus: []history.Status{history.StatusSuccess},
+			wantErr:    ErrCancelled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls [][]string
+			var asked []PlanStep
+			prompt := func(stage string, step PlanStep) StepAction {
+				asked = append(asked, step)
+				return tt.answers[step.Name]
+			}
+			r, err := NewRunner("test.yaml", WithOut(new(bytes.Buffer)), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(mockRunCmd(&calls)), WithStepPrompt(prompt))
+			if err != nil {
+				t.Fatalf("NewRunner() failed: %v", err)
+			}
+			err = r.Run()
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("commands = %v, want %v", calls, tt.wantCalls)
+			}
+			var statuses []history.Status
+			for _, step := range r.Record().Steps {
+				statuses = append(statuses, step.Status)
+			}
+			if !reflect.DeepEqual(statuses, tt.wantStatus) {
+				t.Errorf("statuses = %v, want %v", statuses, tt.wantStatus)
+			}
+			if len(asked) == 0 || !reflect.DeepEqual(asked[0].Argv, []string{"go", "build", "build"}) {
+				t.Errorf("first prompt = %+v, want the interpolated command", asked)
+			}
+		})
+	}
+}
+
+func TestTerminalStepPrompt(t *testing.T) {
+	step := PlanStep{Name: "deploy", Type: dsl.StepTypeExec, Argv: []string{"kubectl", "apply"}, Dir: "/src", Env: map[string]string{"REGION": "eu"}}
+
+	tests := []struct {
+		input string
+		want  StepAction
+	}{
+		{input: "\n", want: StepContinue},
+		{input: "c\n", want: StepContinue},
+		{input: "S\n", want: StepSkip},
+		{input: "maybe\nabort\n", want: StepAbort},
+		{input: "skip", want: StepSkip},
+		{input: "", want: StepAbort},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var out bytes.Buffer
+			got := TerminalStepPrompt(strings.NewReader(tt.input), &out)("release", step)
+			if got != tt.want {
+				t.Errorf("answer %q = %v, want %v", tt.input, got, tt.want)
+			}
+			for _, want := range []string{"release.deploy (exec)", "command: kubectl apply", "dir:     /src", "env:     REGION=eu"} {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output does not contain %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package runner
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/andre-koe/forge/internal/ansi"
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+// WithHistory saves a record of every run, including the commands each step executed, to the store
+func WithHistory(s *history.Store) Option {
+	return func(r *Runner) { r.History = s }
+}
+
+// WithOnFinish registers a function that receives the completed run record after every run, failed or not.
+// Functions are called in the order they were registered.
+func WithOnFinish(f func(*history.Record)) Option {
+	return func(r *Runner) { r.OnFinish = append(r.OnFinish, f) }
+}
+
+// RunID returns the identifier of the current or last run
+func (r *Runner) RunID() string {
+	if r.record == nil {
+		return ""
+	}
+	return r.record.ID
+}
+
+// Record returns the record of the current or last run
+func (r *Runner) Record() *history.Record {
+	return r.record
+}
+
+func (r *Runner) startRecord(wf *dsl.Workflow, loc *time.Location) {
+	now := r.Clock.Now()
+	r.record = &history.Record{
+		ID:        cmp.Or(r.ID, history.NewID(now)),
+		Workflow:  r.path,
+		Name:      wf.Name,
+		StartedAt: now,
+		Timezone:  loc.String(),
+	}
+	for _, stage := range wf.Stages {
+		if stage.Description != "" {
+			if r.record.StageDescriptions == nil {
+				r.record.StageDescriptions = make(map[string]string)
+			}
+			r.record.StageDescriptions[stage.Name] = stage.Description
+		}
+	}
+}
+
+func (r *Runner) startStepRecord(stage string, step *dsl.Step, path string) {
+	r.record.Steps = append(r.record.Steps, history.StepRecord{
+		Path:        path,
+		Stage:       stage,
+		Step:        step.Name,
+		Type:        string(step.Type),
+		Description: step.Description,
+		Group:       r.group,
+		StartedAt:   r.Clock.Now(),
+	})
+}
+
+// endStepRecord completes the step record at index i, which the records of the steps of a
+// group follow
+func (r *Runner) endStepRecord(i int, err error) {
+	rec := &r.record.Steps[i]
+	rec.Duration = time.Since(rec.StartedAt)
+	rec.Status = statusOf(err)
+	if err != nil {
+		rec.Error = ansi.Sanitize(err.Error())
+	}
+}
+
+// finishRecord completes the run record and saves it when a history store is configured
+func (r *Runner) finishRecord(err error) error {
+	r.record.FinishedAt = r.Clock.Now()
+	r.record.Status = statusOf(err)
+	if err != nil {
+		r.record.Error = ansi.Sanitize(err.Error())
+	}
+	if r.Replayable {
+		r.record.Environment = HostEnvironment(executions(r.record))
+	}
+	if r.History == nil {
+		return nil
+	}
+	return r.History.Save(r.record)
+}
+
+func (r *Runner) currentStepRecord() *history.StepRecord {
+	if r.record == nil || len(r.record.Steps) == 0 {
+		return nil
+	}
+	return &r.record.Steps[len(r.record.Steps)-1]
+}
+
+// exec runs a command for the current step and records it in the run record.
+// The command is recorded before interpolation so values from the environment are not written to history.
+func (r *Runner) exec(argv []string) error {
+	if rec := r.currentStepRecord(); rec != nil {
+		rec.Commands = append(rec.Commands, argv)
+	}
+	expanded, err := r.interpolate(argv)
+	if err != nil {
+		return err
+	}
+	if r.remote != nil {
+		return r.runRemote(expanded)
+	}
+	if r.globs {
+		if expanded, err = expandGlobs(expanded, r.processDir(), false); err != nil {
+			return err
+		}
+	}
+	r.recordExecution(expanded)
+	return r.RunCmd(expanded)
+}
+
+// skipStepRecord marks the current step as not executed, either skipped or cached
+func (r *Runner) skipStepRecord(status history.Status) {
+	if rec := r.currentStepRecord(); rec != nil {
+		rec.Status = status
+	}
+}
+
+func statusOf(err error) history.Status {
+	if err != nil {
+		return history.StatusFailed
+	}
+	return history.StatusSuccess
+}
+
+// PlannedSteps returns the steps of a workflow in the shape of a run record, with the
+// commands known before execution. It is used to compare a plan against recorded runs.
+func PlannedSteps(wf *dsl.Workflow) []history.StepRecord {
+	var steps []history.StepRecord
+	for _, i := range wf.StageOrder() {
+		stage := wf.Stages[i]
+		stagePath := JoinPath("", stage.Name)
+		for _, step := range stage.Steps {
+			path := JoinPath(stagePath, step.Name)
+			steps = append(steps, plannedStep(wf, stage.Name, path, "", &step))
+			for _, sub := range step.GroupSteps() {
+				steps = append(steps, plannedStep(wf, stage.Name, JoinPath(path, sub.Name), step.Name, &sub))
+			}
+		}
+	}
+	return steps
+}
+
+// plannedStep returns the record of a step of the named stage for PlannedSteps
+func plannedStep(wf *dsl.Workflow, stage, path, group string, step *dsl.Step) history.StepRecord {
+	rec := history.StepRecord{
+		Path:  path,
+		Stage: stage,
+		Step:  step.Name,
+		Type:  string(step.Type),
+		Group: group,
+	}
+	switch step.Type {
+	case dsl.StepTypeExec:
+		before, after := stepCommands(wf, step)
+		rec.Commands = slices.Concat(before, [][]string{runCommandLine(wf, step)}, after)
+	case dsl.StepTypeAssert:
+		rec.Commands = [][]string{step.Assert.Command}
+	}
+	return rec
+}
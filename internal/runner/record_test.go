@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func TestRunner_Run_RecordsHistory(t *testing.T) {
+	store := history.NewStore(filepath.Join(t.TempDir(), "runs"))
+	workflow := []dsl.Stage{
+		{
+			Name: "build",
+			Steps: []dsl.Step{
+				{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"go", "build"}},
+				{Name: "deploy", Type: dsl.StepTypeExec, Run: []string{"deploy"}},
+			},
+		},
+	}
+	runCmd := func(argv []string) error {
+		if argv[0] == "deploy" {
+			return errors.New("deploy failed")
+		}
+		return nil
+	}
+
+	runner, err := NewRunner("test.yaml",
+		WithOut(new(bytes.Buffer)),
+		WithLoadWorkflow(mockLoadWorkflow(workflow)),
+		WithRunCmd(runCmd),
+		WithHistory(store),
+	)
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := runner.Run(); err == nil {
+		t.Fatal("Run() should have failed")
+	}
+
+	rec, err := store.Get(runner.RunID())
+	if err != nil {
+		t.Fatalf("run was not recorded: %v", err)
+	}
+	if rec.Status != history.StatusFailed || rec.Name != "mock-workflow" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	if len(rec.Steps) != 2 {
+		t.Fatalf("expected 2 step records, got %d", len(rec.Steps))
+	}
+	if rec.Steps[0].Path != "build/compile" || rec.Steps[0].Status != history.StatusSuccess {
+		t.Errorf("unexpected first step record %+v", rec.Steps[0])
+	}
+	if !slices.Equal(rec.Steps[0].Commands[0], []string{"go", "build"}) {
+		t.Errorf("expected recorded command [go build], got %v", rec.Steps[0].Commands)
+	}
+	if rec.Steps[1].Status != history.StatusFailed || rec.Steps[1].Error == "" {
+		t.Errorf("unexpected second step record %+v", rec.Steps[1])
+	}
+}
+
+func TestRunner_Run_Descriptions(t *testing.T) {
+	workflow := []dsl.Stage{{
+		Name:        "build",
+		Description: "Compile the service",
+		Steps:       []dsl.Step{{Name: "compile", Description: "Static binary for linux", Type: dsl.StepTypeExec, Run: []string{"go", "build"}}},
+	}}
+	var out bytes.Buffer
+	var calls [][]string
+	runner, err := NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(workflow)), WithRunCmd(mockRunCmd(&calls)))
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := runner.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	for _, want := range []string{"=== STAGE 1: build ===\n  Compile the service\n", "STEP 1.1: compile (exec)\n  Static binary for linux\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	rec := runner.Record()
+	if rec.StageDescriptions["build"] != "Compile the service" || rec.Steps[0].Description != "Static binary for linux" {
+		t.Errorf("record does not keep the descriptions: %+v", rec)
+	}
+}
+
+func TestPlannedSteps(t *testing.T) {
+	wf := &dsl.Workflow{Stages: []dsl.Stage{
+		{
+			Name: "build",
+			Steps: []dsl.Step{
+				{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"go", "build"}},
+				{Name: "wait", Type: dsl.StepTypeSleep, Seconds: 1},
+			},
+		},
+	}}
+
+	steps := PlannedSteps(wf)
+	if len(steps) != 2 {
+		t.Fatalf("expected 2 planned steps, got %d", len(steps))
+	}
+	if steps[0].Path != "build/compile" || len(steps[0].Commands) != 1 {
+		t.Errorf("unexpected planned exec step %+v", steps[0])
+	}
+	if steps[1].Type != "sleep" || steps[1].Commands != nil {
+		t.Errorf("unexpected planned sleep step %+v", steps[1])
+	}
+}
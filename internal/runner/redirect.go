@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// redirects are the interpolated stdin and output files of an exec step
+type redirects struct {
+	Stdin, StdinFile, StdoutFile, StderrFile string
+}
+
+// stepRedirects interpolates the stdin and output files of the step
+func (r *Runner) stepRedirects(step *dsl.Step) (redirects, error) {
+	v, err := r.interpolate([]string{step.Stdin, step.StdinFile, step.StdoutFile, step.StderrFile})
+	if err != nil {
+		return redirects{}, err
+	}
+	return redirects{Stdin: v[0], StdinFile: v[1], StdoutFile: v[2], StderrFile: v[3]}, nil
+}
+
+// withRedirects runs fn, the run of an exec step, with its stdin read from the step's stdin or
+// stdin_file and its stdout and stderr written to stdout_file and stderr_file instead of the
+// step's output
+func (r *Runner) withRedirects(step *dsl.Step, fn func() error) error {
+	if !step.Redirected() {
+		return fn()
+	}
+	red, err := r.stepRedirects(step)
+	if err != nil {
+		return err
+	}
+	path := func(name string) string {
+		if filepath.IsAbs(name) {
+			return name
+		}
+		return filepath.Join(r.processDir(), name)
+	}
+
+	prevIn, prevOut, prevErr := r.Stdin, r.stdout, r.stderr
+	defer func() { r.Stdin, r.stdout, r.stderr = prevIn, prevOut, prevErr }()
+	switch {
+	case step.Stdin != "":
+		r.Stdin = strings.NewReader(red.Stdin)
+	case step.StdinFile != "":
+		f, err := os.Open(path(red.StdinFile))
+		if err != nil {
+			return fmt.Errorf("stdin_file: %w", err)
+		}
+		defer f.Close()
+		r.Stdin = f
+	}
+	var stdout io.Writer
+	if step.StdoutFile != "" {
+		f, err := os.Create(path(red.StdoutFile))
+		if err != nil {
+			return fmt.Errorf("stdout_file: %w", err)
+		}
+		defer f.Close()
+		r.stdout, stdout = f, f
+	}
+	if step.StderrFile != "" {
+		if stdout != nil && path(red.StderrFile) == path(red.StdoutFile) {
+			r.stderr = stdout
+		} else {
+			f, err := os.Create(path(red.StderrFile))
+			if err != nil {
+				return fmt.Errorf("stderr_file: %w", err)
+			}
+			defer f.Close()
+			r.stderr = f
+		}
+	}
+	return fn()
+}
@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_Redirects(t *testing.T) {
+	for _, tool := range []string{"sh", "tr", "cat"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skip("no " + tool)
+		}
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.yaml")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	load := func(string) (*dsl.Workflow, error) {
+		return &dsl.Workflow{Name: "data", Workdir: ".", Vars: map[string]string{"name": "forge"}, Stages: []dsl.Stage{{Name: "transform", Steps: []dsl.Step{
+			{Name: "upper", Type: dsl.StepTypeExec, Run: dsl.Command{"tr", "a-z", "A-Z"}, Stdin: "hello ${{ vars.name }}\n", StdoutFile: "upper.txt"},
+			{Name: "copy", Type: dsl.StepTypeExec, Run: dsl.Command{"cat"}, StdinFile: "upper.txt", StdoutFile: "${{ vars.name }}.txt"},
+			{Name: "both", Type: dsl.StepTypeExec, Run: dsl.Command{"sh", "-c", "echo out; echo err >&2"}, StdoutFile: "both.log", StderrFile: "./both.log"},
+			{Name: "errors", Type: dsl.StepTypeExec, Run: dsl.Command{"sh", "-c", "echo out; echo err >&2"}, StderrFile: "err.log"},
+		}}}}, nil
+	}
+
+	var out bytes.Buffer
+	r, err := NewRunner(path, WithOut(new(bytes.Buffer)), WithProcessOutput(&out, &out), WithLoadWorkflow(load))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	for name, want := range map[string]string{"upper.txt": "HELLO FORGE\n", "forge.txt": "HELLO FORGE\n", "both.log": "out\nerr\n", "err.log": "err\n"} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if out.String() != "out\n" {
+		t.Errorf("step output = %q, want only the stdout of the step without stdout_file", out.String())
+	}
+
+	var dry bytes.Buffer
+	r, err = NewRunner(path, WithOut(&dry), WithLoadWorkflow(load), WithMode(ModeDryRun))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+	for _, want := range []string{
+		`[DRY-RUN]   Would pass stdin: "hello forge\n"`,
+		"[DRY-RUN]   Would write stdout to upper.txt",
+		"[DRY-RUN]   Would read stdin from upper.txt",
+		"[DRY-RUN]   Would write stdout to forge.txt",
+		"[DRY-RUN]   Would write stderr to err.log",
+	} {
+		if !strings.Contains(dry.String(), want) {
+			t.Errorf("dry-run output missing %q:\n%s", want, dry.String())
+		}
+	}
+}
+
+func TestRunner_Redirects_MissingStdinFile(t *testing.T) {
+	stages := []dsl.Stage{{Name: "load", Steps: []dsl.Step{{Name: "import", Type: dsl.StepTypeExec, Run: dsl.Command{"psql"}, StdinFile: filepath.Join(t.TempDir(), "dump.sql")}}}}
+	var calls [][]string
+	r, err := NewRunner("test.yaml", WithOut(new(bytes.Buffer)), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(mockRunCmd(&calls)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err == nil || !strings.Contains(err.Error(), "stdin_file: open") {
+		t.Errorf("Run() error = %v, want the missing stdin_file", err)
+	}
+	if len(calls) > 0 {
+		t.Errorf("commands = %q, want none", calls)
+	}
+}
@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/shell"
+)
+
+// remoteHost is where the commands of a step run when its stage has runs_on: on a host over ssh
+// or in the stage's pod, see dsl.RunsOn
+type remoteHost struct {
+	dsl.SSHHost
+	// pod is the pod of a stage on Kubernetes, nil for hosts
+	pod *stagePod
+	// dir is the directory the commands start in, the login or image's one when empty
+	dir string
+	// err is why the commands cannot run there; they fail instead of running here
+	err error
+}
+
+// remoteOf returns where the commands of a step of stage run, nil when they run here
+func (r *Runner) remoteOf(stage *dsl.Stage, step *dsl.Step) *remoteHost {
+	if stage == nil || stage.RunsOn == nil {
+		return nil
+	}
+	dir := workdirOf(r.wf, stage, step)
+	switch {
+	case stage.RunsOn.Kubernetes != nil:
+		if r.pod == nil {
+			return &remoteHost{err: fmt.Errorf("the pod of stage '%s' is not running", stage.Name)}
+		}
+		return &remoteHost{pod: r.pod, dir: dir}
+	case stage.RunsOn.SSH != "":
+		host, err := dsl.ParseSSH(stage.RunsOn.SSH)
+		switch {
+		case dir == "":
+			dir = host.Dir
+		case !path.IsAbs(dir) && host.Dir != "":
+			dir = path.Join(host.Dir, dir)
+		}
+		return &remoteHost{SSHHost: host, dir: dir, err: err}
+	}
+	return nil
+}
+
+// String returns the [user@]host or pod the commands run on
+func (h *remoteHost) String() string {
+	if h.pod != nil {
+		return "pod " + h.pod.name
+	}
+	return h.SSHHost.String()
+}
+
+// command returns the ssh or kubectl command running argv in the directory, with env added to
+// the environment of the login or container or, with clean, as the whole environment
+func (h *remoteHost) command(argv, env []string, clean, tty bool) []string {
+	script := "exec env "
+	if clean {
+		script += "-i "
+	}
+	script += shell.Join(slices.Concat(env, argv))
+	if h.dir != "" {
+		script = "cd " + shell.Quote(h.dir) + " && " + script
+	}
+	if h.pod != nil {
+		return h.pod.exec(script, tty)
+	}
+	cmd := []string{"ssh", "-o", "BatchMode=yes"}
+	if h.Port != "" {
+		cmd = append(cmd, "-p", h.Port)
+	}
+	if tty {
+		cmd = append(cmd, "-tt")
+	}
+	return append(cmd, "--", h.String(), script)
+}
+
+// runRemote runs a command of the current step on its host or in its pod, with the step's environment
+func (r *Runner) runRemote(argv []string) error {
+	if r.remote.err != nil {
+		return r.remote.err
+	}
+	if r.proc.User != "" || r.proc.Group != "" {
+		return fmt.Errorf("user and group are not supported on %s, run the commands as the user there instead", r.remote)
+	}
+	env := slices.DeleteFunc(r.commandEnv(), func(kv string) bool { return strings.HasPrefix(kv, EnvWorkdir+"=") })
+	if r.remote.dir != "" {
+		env = append(env, EnvWorkdir+"="+r.remote.dir)
+	}
+	cmd := r.remote.command(argv, env, r.cleanEnv, r.proc.TTY)
+	r.recordExecution(cmd)
+	return r.RunCmd(cmd)
+}
+
+// printRemote tells where the commands of a stage running on a host over ssh run
+func (r *Runner) printRemote(stage *dsl.Stage) {
+	if stage.RunsOn != nil && stage.RunsOn.SSH != "" {
+		fmt.Fprintf(r.Out, "  Running its commands on %s\n", stage.RunsOn.SSH)
+	}
+}
+
+// dryRunRemote prints the host or pod the commands of a planned stage would run on
+func (r *Runner) dryRunRemote(runsOn *dsl.RunsOn) {
+	switch {
+	case runsOn == nil:
+	case runsOn.SSH != "":
+		fmt.Fprintf(r.Out, "[DRY-RUN] Would run its commands on %s\n", runsOn.SSH)
+	case runsOn.Kubernetes != nil:
+		fmt.Fprintf(r.Out, "[DRY-RUN] Would run its commands in a pod of %s%s\n", runsOn.Kubernetes.PodImage(), inNamespace(runsOn.Kubernetes.Namespace))
+	}
+}
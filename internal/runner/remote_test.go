@@ -0,0 +1,158 @@
+package runner
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRemoteHost_Command(t *testing.T) {
+	tests := []struct {
+		name  string
+		host  remoteHost
+		env   []string
+		clean bool
+		tty   bool
+		want  []string
+	}{
+		{
+			name: "login directory",
+			host: remoteHost{SSHHost: dsl.SSHHost{Host: "web-1"}},
+			want: []string{"ssh", "-o", "BatchMode=yes", "--", "web-1", "exec env systemctl restart 'my app'"},
+		},
+		{
+			name: "directory, port and environment",
+			host: remoteHost{SSHHost: dsl.SSHHost{User: "deploy", Host: "web-1", Port: "2222"}, dir: "/srv/my app"},
+			env:  []string{"GREETING=hello world"},
+			want: []string{"ssh", "-o", "BatchMode=yes", "-p", "2222", "--", "deploy@web-1", "cd '/srv/my app' && exec env 'GREETING=hello world' systemctl restart 'my app'"},
+		},
+		{
+			name:  "clean environment in a terminal",
+			host:  remoteHost{SSHHost: dsl.SSHHost{Host: "web-1"}},
+			env:   []string{"PATH=/usr/bin"},
+			clean: true,
+			tty:   true,
+			want:  []string{"ssh", "-o", "BatchMode=yes", "-tt", "--", "web-1", "exec env -i PATH=/usr/bin systemctl restart 'my app'"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.host.command([]string{"systemctl", "restart", "my app"}, tt.env, tt.clean, tt.tty)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("command() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunner_RunsOnSSH(t *testing.T) {
+	tests := []struct {
+		name     string
+		stage    dsl.Stage
+		wantHost string
+		wantCmd  string
+		wantErr  string
+	}{
+		{
+			name: "runs the commands on the host",
+			stage: dsl.Stage{Name: "deploy", RunsOn: &dsl.RunsOn{SSH: "ssh://deploy@web-1/srv/app"}, Env: map[string]string{"RELEASE": "v1"}, Steps: []dsl.Step{
+				{Name: "restart", Type: dsl.StepTypeExec, Workdir: "current", Run: dsl.Command{"./restart", "${{ vars.mode }}"}},
+			}},
+			wantHost: "deploy@web-1",
+			wantCmd:  "cd /srv/app/current && exec env FORGE_RUN_ID=",
+		},
+		{
+			name: "keeps absolute workdirs",
+			stage: dsl.Stage{Name: "deploy", RunsOn: &dsl.RunsOn{SSH: "ssh://web-1/srv/app"}, Workdir: "/opt/app", Steps: []dsl.Step{
+				{Name: "restart", Type: dsl.StepTypeExec, Run: dsl.Command{"./restart", "${{ vars.mode }}"}},
+			}},
+			wantHost: "web-1",
+			wantCmd:  "cd /opt/app && exec env ",
+		},
+		{
+			name: "cannot switch users",
+			stage: dsl.Stage{Name: "deploy", RunsOn: &dsl.RunsOn{SSH: "ssh://web-1"}, Steps: []dsl.Step{
+				{Name: "restart", Type: dsl.StepTypeExec, User: "root", Run: dsl.Command{"./restart"}},
+			}},
+			wantErr: "user and group are not supported on web-1",
+		},
+		{
+			name: "fails with an invalid URL",
+			stage: dsl.Stage{Name: "deploy", RunsOn: &dsl.RunsOn{SSH: "web-1"}, Steps: []dsl.Step{
+				{Name: "restart", Type: dsl.StepTypeExec, Run: dsl.Command{"./restart"}},
+			}},
+			wantErr: "must be an ssh://[user@]host[:port][/dir] URL",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls [][]string
+			run := func(argv []string) error {
+				calls = append(calls, argv)
+				return nil
+			}
+			load := func(string) (*dsl.Workflow, error) {
+				return &dsl.Workflow{Name: "deploy", Vars: map[string]string{"mode": "graceful"}, Stages: []dsl.Stage{
+					{Name: "build", Steps: []dsl.Step{{Name: "package", Type: dsl.StepTypeExec, Run: dsl.Command{"make", "dist"}}}},
+					tt.stage,
+				}}, nil
+			}
+			var out bytes.Buffer
+			r, err := NewRunner("deploy.yaml", WithOut(&out), WithLoadWorkflow(load), WithRunCmd(run))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = r.Run()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+				}
+				if len(calls) != 1 {
+					t.Errorf("commands = %q, want only the local one", calls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			if len(calls) != 2 || !slices.Equal(calls[0], []string{"make", "dist"}) {
+				t.Fatalf("commands = %q, want make dist and the ssh command", calls)
+			}
+			ssh := calls[1]
+			if len(ssh) < 3 || ssh[0] != "ssh" || ssh[len(ssh)-2] != tt.wantHost {
+				t.Fatalf("command = %q, want ssh to %s", ssh, tt.wantHost)
+			}
+			script := ssh[len(ssh)-1]
+			if !strings.HasPrefix(script, tt.wantCmd) || !strings.HasSuffix(script, " ./restart graceful") {
+				t.Errorf("remote command = %q, want it to start with %q and run ./restart graceful", script, tt.wantCmd)
+			}
+			if tt.stage.Env != nil && !strings.Contains(script, " RELEASE=v1 ") {
+				t.Errorf("remote command = %q, want the stage's env passed along", script)
+			}
+			if !strings.Contains(out.String(), "  Running its commands on "+tt.stage.RunsOn.SSH+"\n") {
+				t.Errorf("output does not tell where the stage runs:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestRunner_RunsOnSSH_DryRun(t *testing.T) {
+	stages := []dsl.Stage{{Name: "deploy", RunsOn: &dsl.RunsOn{SSH: "ssh://deploy@web-1"}, Steps: []dsl.Step{
+		{Name: "restart", Type: dsl.StepTypeExec, Run: dsl.Command{"./restart"}},
+	}}}
+	var out bytes.Buffer
+	r, err := NewRunner("deploy.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)), WithMode(ModeDryRun))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+	if want := "[DRY-RUN] Would run its commands on ssh://deploy@web-1\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("dry-run output missing %q:\n%s", want, out.String())
+	}
+}
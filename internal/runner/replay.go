@@ -0,0 +1,122 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/pkg/version"
+)
+
+var (
+	// ErrNotReplayable is returned for runs that were not recorded with WithReplayable
+	ErrNotReplayable = errors.New("run was not recorded for replay")
+	// ErrEnvironmentDrift is returned when the host differs from the one a run was recorded on
+	// in more than the tolerated ways
+	ErrEnvironmentDrift = errors.New("environment drifted")
+)
+
+// WithReplayable records every command as executed, after interpolation, and the host in the run
+// record so Replay can repeat them. The record then holds the values of variables and of the
+// environment the commands were given.
+func WithReplayable(replayable bool) Option {
+	return func(r *Runner) { r.Replayable = replayable }
+}
+
+// recordExecution adds a command as executed to the current step record of a replayable run
+func (r *Runner) recordExecution(argv []string) {
+	rec := r.currentStepRecord()
+	if !r.Replayable || rec == nil {
+		return
+	}
+	dir, err := filepath.Abs(r.processDir())
+	if err != nil {
+		dir = r.processDir()
+	}
+	rec.Executions = append(rec.Executions, history.Execution{
+		Argv:     argv,
+		Dir:      dir,
+		Env:      r.commandEnv(),
+		CleanEnv: r.cleanEnv,
+	})
+}
+
+// HostEnvironment describes the current host, with the files the programs of execs resolve to
+func HostEnvironment(execs []history.Execution) *history.Environment {
+	hostname, _ := os.Hostname()
+	env := &history.Environment{
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		Hostname: hostname,
+		Forge:    version.Version,
+	}
+	for _, ex := range execs {
+		if env.Tools == nil {
+			env.Tools = make(map[string]string)
+		}
+		env.Tools[ex.Argv[0]] = resolveProgram(ex.Argv[0], ex.Dir)
+	}
+	return env
+}
+
+// resolveProgram returns the file a program name runs in dir, empty if there is none
+func resolveProgram(name, dir string) string {
+	if strings.ContainsRune(name, filepath.Separator) && !filepath.IsAbs(name) {
+		name = filepath.Join(dir, name)
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// executions returns the commands recorded for replay, in the order they ran
+func executions(rec *history.Record) []history.Execution {
+	var execs []history.Execution
+	for _, step := range rec.Steps {
+		execs = append(execs, step.Executions...)
+	}
+	return execs
+}
+
+// Replay executes the commands of a run recorded with WithReplayable again, in the order and with
+// the directories and environment they had, writing their output to out. It stops at the first
+// failing command, so replaying a failed run reproduces its failure. Before anything runs, the
+// host is compared with the recorded one; differences other than those of the tolerated kinds
+// fail the replay.
+func Replay(ctx context.Context, rec *history.Record, tolerate []history.DriftKind, out io.Writer) error {
+	if rec.Environment == nil {
+		return fmt.Errorf("run %s: %w", rec.ID, ErrNotReplayable)
+	}
+	execs := executions(rec)
+	var drifts []string
+	for _, d := range rec.Environment.Drift(HostEnvironment(execs)) {
+		if !slices.Contains(tolerate, d.Kind) {
+			drifts = append(drifts, d.String())
+		}
+	}
+	if len(drifts) > 0 {
+		return fmt.Errorf("%w since run %s: %s", ErrEnvironmentDrift, rec.ID, strings.Join(drifts, "; "))
+	}
+
+	for _, step := range rec.Steps {
+		for _, ex := range step.Executions {
+			fmt.Fprintf(out, "REPLAY %s: %v\n", step.Path, ex.Argv)
+			attr := procAttr{Env: ex.Env, Dir: ex.Dir, CleanEnv: ex.CleanEnv, Stdout: out, Stderr: out}
+			if err := runCommand(ctx, ex.Argv, attr); err != nil {
+				return fmt.Errorf("%s: command %v failed: %w", step.Path, ex.Argv, err)
+			}
+		}
+	}
+	fmt.Fprintf(out, "Replayed %d command(s) of run %s\n", len(execs), rec.ID)
+	return nil
+}
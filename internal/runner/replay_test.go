@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func TestRunner_Replay(t *testing.T) {
+	dir := t.TempDir()
+	stages := []dsl.Stage{{Name: "build", Steps: []dsl.Step{
+		{Name: "write", Type: dsl.StepTypeExec, Run: []string{"sh", "-c", "echo ${{ vars.msg }}-$REGION >> out.txt"}},
+	}}}
+	load := func(string) (*dsl.Workflow, error) {
+		return &dsl.Workflow{Name: "ci", Vars: map[string]string{"msg": "hello"}, Stages: stages}, nil
+	}
+	var out bytes.Buffer
+	r, err := NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(load), WithWorkdir(dir),
+		WithEnv(map[string]string{"REGION": "eu"}), WithReplayable(true))
+	if err != nil {
+		t.Fatalf("NewRunner() error: %v", err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	rec := r.Record()
+	execs := rec.Steps[0].Executions
+	if len(execs) != 1 || !reflect.DeepEqual(execs[0].Argv, []string{"sh", "-c", "echo hello-$REGION >> out.txt"}) || execs[0].Dir != dir {
+		t.Fatalf("executions = %+v", execs)
+	}
+	if rec.Environment == nil || rec.Environment.Tools["sh"] == "" {
+		t.Fatalf("environment = %+v, want the path of sh", rec.Environment)
+	}
+
+	// Replaying runs the interpolated command with the recorded environment, not the current one
+	out.Reset()
+	if err := Replay(context.Background(), rec, nil, &out); err != nil {
+		t.Fatalf("Replay() error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "out.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello-eu\nhello-eu\n" {
+		t.Errorf("out.txt = %q, want the line written twice", data)
+	}
+	if !strings.Contains(out.String(), "REPLAY build/write: [sh -c echo hello-$REGION >> out.txt]") {
+		t.Errorf("output = %q", out.String())
+	}
+
+	rec.Environment.Hostname = "elsewhere"
+	if err := Replay(context.Background(), rec, nil, &out); !errors.Is(err, ErrEnvironmentDrift) || !strings.Contains(err.Error(), "host: elsewhere") {
+		t.Errorf("Replay() on another host error = %v, want %v", err, ErrEnvironmentDrift)
+	}
+	if err := Replay(context.Background(), rec, []history.DriftKind{history.DriftHost}, &out); err != nil {
+		t.Errorf("Replay() tolerating the host error: %v", err)
+	}
+
+	if err := Replay(context.Background(), &history.Record{ID: "old"}, nil, &out); !errors.Is(err, ErrNotReplayable) {
+		t.Errorf("Replay() of a run not recorded for replay error = %v, want %v", err, ErrNotReplayable)
+	}
+}
+
+func TestRunner_NotReplayable(t *testing.T) {
+	stages := []dsl.Stage{{Name: "build", Steps: []dsl.Step{{Name: "make", Type: dsl.StepTypeExec, Run: []string{"make"}}}}}
+	var calls [][]string
+	r, err := NewRunner("test.yaml", WithOut(&bytes.Buffer{}), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(mockRunCmd(&calls)))
+	if err != nil {
+		t.Fatalf("NewRunner() error: %v", err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if rec := r.Record(); rec.Environment != nil || rec.Steps[0].Executions != nil {
+		t.Errorf("run without WithReplayable recorded %+v", rec)
+	}
+}
@@ -0,0 +1,23 @@
+package runner
+
+import (
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/preflight"
+)
+
+// WithPreflight sets how required tools are checked; nil skips the check
+func WithPreflight(c *preflight.Checker) Option {
+	return func(r *Runner) { r.Preflight = c }
+}
+
+// checkRequires fails before anything runs when a tool the workflow requires is missing
+func (r *Runner) checkRequires(wf *dsl.Workflow) error {
+	if r.Preflight == nil {
+		return nil
+	}
+	tools, err := wf.RequiredTools()
+	if err != nil || len(tools) == 0 {
+		return err
+	}
+	return r.Preflight.Check(tools)
+}
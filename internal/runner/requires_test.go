@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/preflight"
+)
+
+func TestRunner_Run_Requires(t *testing.T) {
+	checker := &preflight.Checker{
+		LookPath: func(file string) (string, error) {
+			if file == "docker" {
+				return "/usr/bin/docker", nil
+			}
+			return "", errors.New("not found")
+		},
+		Output: func(argv []string) ([]byte, error) { return []byte("Docker version 24.0.7"), nil },
+	}
+
+	tests := []struct {
+		name    string
+		tools   []string
+		wantErr string
+	}{
+		{name: "satisfied", tools: []string{"docker>=24"}},
+		{name: "missing", tools: []string{"docker", "kubectl>=1.28"}, wantErr: "kubectl: not found on PATH"},
+		{name: "too old", tools: []string{"docker>=25"}, wantErr: "docker: version 24.0.7 does not satisfy >=25"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls [][]string
+			r, err := NewRunner("test.yaml",
+				WithLoadWorkflow(func(path string) (*dsl.Workflow, error) {
+					return &dsl.Workflow{
+						Name:     "requires",
+						Requires: &dsl.Requires{Tools: tt.tools},
+						Stages:   []dsl.Stage{{Name: "build", Steps: []dsl.Step{{Name: "s", Type: dsl.StepTypeExec, Run: []string{"true"}}}}},
+					}, nil
+				}),
+				WithRunCmd(mockRunCmd(&calls)),
+				WithPreflight(checker),
+				WithOut(&bytes.Buffer{}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = r.Run()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Run() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+			if len(calls) != 0 {
+				t.Errorf("Run() ran %v before failing the preflight check", calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/andre-koe/forge/internal/ansi"
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/expr"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+// resetStepResults declares the ids of all steps of wf, including hooks and the steps of groups,
+// with empty results
+func (r *Runner) resetStepResults(wf *dsl.Workflow) {
+	r.stepResults = make(map[string]expr.StepResult)
+	var declare func(steps []dsl.Step)
+	declare = func(steps []dsl.Step) {
+		for _, step := range steps {
+			if step.ID != "" {
+				r.stepResults[step.ID] = expr.StepResult{}
+			}
+			declare(step.Steps)
+		}
+	}
+	for _, stage := range wf.Stages {
+		declare(stage.Steps)
+		for _, kind := range dsl.HookKinds {
+			declare(stage.Hooks()[kind])
+		}
+	}
+	for _, kind := range dsl.HookKinds {
+		declare(wf.Hooks()[kind])
+	}
+}
+
+// setStepStatus records the outcome of a step for steps.<id>.status
+func (r *Runner) setStepStatus(step *dsl.Step, status history.Status) {
+	if step.ID == "" || r.stepResults == nil {
+		return
+	}
+	result := r.stepResults[step.ID]
+	result.Status = string(status)
+	r.stepResults[step.ID] = result
+}
+
+// skipStep records a step that is not executed, with the status telling why
+func (r *Runner) skipStep(stage, stagePath string, step *dsl.Step, status history.Status) {
+	r.startStepRecord(stage, step, JoinPath(stagePath, step.Name))
+	r.skipStepRecord(status)
+	r.setStepStatus(step, status)
+}
+
+// stepCondition evaluates the when: of a step; steps without one always run
+func (r *Runner) stepCondition(step *dsl.Step) (bool, error) {
+	if step.When == "" {
+		return true, nil
+	}
+	ok, err := expr.Condition(step.When, r.exprContext(false))
+	if err != nil {
+		return false, fmt.Errorf("when: %w", err)
+	}
+	return ok, nil
+}
+
+// captureBuffer keeps the first limit bytes written to it
+type captureBuffer struct {
+	mu    sync.Mutex
+	limit int
+	buf   bytes.Buffer
+}
+
+func (c *captureBuffer) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if room := c.limit - c.buf.Len(); room > 0 {
+		c.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+// withStepCapture runs fn while keeping the standard output of a step with an id, up to the output
+// limit, for steps.<id>.output
+func (r *Runner) withStepCapture(step *dsl.Step, fn func() error) error {
+	if step.ID == "" || r.stepResults == nil {
+		return fn()
+	}
+
+	w := &captureBuffer{limit: cmp.Or(r.OutputLimit, DefaultOutputLimit)}
+	prev := r.stdout
+	r.stdout = io.MultiWriter(r.processStdout(), w)
+	defer func() { r.stdout = prev }()
+
+	err := fn()
+	result := r.stepResults[step.ID]
+	result.Output = strings.TrimSpace(ansi.Strip(w.buf.String()))
+	r.stepResults[step.ID] = result
+	return err
+}
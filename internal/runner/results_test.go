@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func TestRunner_StepReferences(t *testing.T) {
+	stages := []dsl.Stage{
+		{
+			Name: "release",
+			Steps: []dsl.Step{
+				{Name: "version", ID: "version", Type: dsl.StepTypeExec, Run: []string{"git", "describe"}},
+				{Name: "tag", Type: dsl.StepTypeExec, Run: []string{"docker", "tag", "app:${{ steps.version.output }}"}},
+				{Name: "notes", Type: dsl.StepTypeExec, Run: []string{"notes"}, When: "steps.version.output == 'v0'"},
+				{Name: "deploy", ID: "deploy", Type: dsl.StepTypeExec, Run: []string{"deploy"}},
+			},
+			OnFailure: []dsl.Step{
+				{Name: "rollback", Type: dsl.StepTypeExec, Run: []string{"rollback"}, When: "steps.deploy.status == 'failed'"},
+				{Name: "page", Type: dsl.StepTypeExec, Run: []string{"page"}, When: "${{ steps.version.status != 'success' }}"},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	var calls [][]string
+	r, err := NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)))
+	if err != nil {
+		t.Fatalf("NewRunner() error: %v", err)
+	}
+	r.RunCmd = func(argv []string) error {
+		calls = append(calls, argv)
+		switch argv[0] {
+		case "git":
+			fmt.Fprintln(r.procAttr().Stdout, "\x1b[1mv1.2.3\x1b[0m")
+		case "deploy":
+			return errors.New("exit status 1")
+		}
+		return nil
+	}
+
+	if err := r.Run(); err == nil {
+		t.Fatal("Run() succeeded, want the deploy failure")
+	}
+	want := [][]string{{"git", "describe"}, {"docker", "tag", "app:v1.2.3"}, {"deploy"}, {"rollback"}}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	for _, line := range []string{"STEP 1.3: notes (skipped, when is false)", "HOOK on_failure: page (skipped, when is false)"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("output missing %q:\n%s", line, out.String())
+		}
+	}
+	var statuses []history.Status
+	for _, s := range r.Record().Steps {
+		statuses = append(statuses, s.Status)
+	}
+	wantStatuses := []history.Status{history.StatusSuccess, history.StatusSuccess, history.StatusSkipped, history.StatusFailed, history.StatusSuccess, history.StatusSkipped}
+	if !reflect.DeepEqual(statuses, wantStatuses) {
+		t.Errorf("statuses = %v, want %v", statuses, wantStatuses)
+	}
+}
+
+func TestRunner_WhenError(t *testing.T) {
+	stages := []dsl.Stage{{Name: "build", Steps: []dsl.Step{
+		{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"make"}, When: "steps.unknown.status == 'success'"},
+	}}}
+	var calls [][]string
+	r, err := NewRunner("test.yaml", WithOut(new(bytes.Buffer)), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(mockRunCmd(&calls)))
+	if err != nil {
+		t.Fatalf("NewRunner() error: %v", err)
+	}
+	if err := r.Run(); err == nil || !strings.Contains(err.Error(), "when:") {
+		t.Errorf("Run() error = %v, want a when error", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("calls = %v, want none", calls)
+	}
+}
@@ -1,16 +1,35 @@
 package runner
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
+	"net/http"
 	"os"
 	"os/exec"
+	"path"
+	"slices"
 	"time"
 
+	"github.com/andre-koe/forge/internal/active"
+	"github.com/andre-koe/forge/internal/artifact"
+	"github.com/andre-koe/forge/internal/cache"
+	"github.com/andre-koe/forge/internal/clock"
 	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/expr"
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/matcher"
+	"github.com/andre-koe/forge/internal/notify"
+	"github.com/andre-koe/forge/internal/policy"
+	"github.com/andre-koe/forge/internal/preflight"
 )
 
+// timestampLayout is used for all timestamps the runner prints
+const timestampLayout = "2006-01-02 15:04:05 MST"
+
 // Options for configuring the Runner
 
 type Option func(*Runner)
@@ -33,8 +52,106 @@ func WithRunCmd(f func(argv []string) error) Option {
 	}
 }
 
+// WithCommandFunc runs the commands of steps with f instead of executing them, passing the
+// writers the step's process output goes to
+func WithCommandFunc(f func(argv []string, stdout, stderr io.Writer) error) Option {
+	return func(r *Runner) {
+		r.RunCmd = func(argv []string) error {
+			return f(argv, r.processStdout(), r.processStderr())
+		}
+	}
+}
+
+// WithClock sets the clock used for timestamps, sleep steps, delays and retry backoff
+func WithClock(c clock.Clock) Option {
+	return func(r *Runner) { r.Clock = c }
+}
+
+// WithSleep replaces how the runner waits while it keeps telling the time with the system clock
 func WithSleep(f func(d time.Duration)) Option {
-	return func(r *Runner) { r.Sleep = f }
+	return func(r *Runner) { r.Clock = sleepClock{Clock: clock.Real{}, sleep: f} }
+}
+
+// sleepClock waits with a sleep function; After returns once it did
+type sleepClock struct {
+	clock.Clock
+	sleep func(d time.Duration)
+}
+
+func (c sleepClock) Sleep(d time.Duration) { c.sleep(d) }
+
+func (c sleepClock) After(d time.Duration) <-chan time.Time {
+	c.sleep(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
+
+// WithTimezone overrides the workflow's timezone for displayed timestamps
+func WithTimezone(loc *time.Location) Option {
+	return func(r *Runner) { r.Location = loc }
+}
+
+// WithChaos injects failures and delays into matching steps
+func WithChaos(rules []ChaosRule) Option {
+	return func(r *Runner) { r.Chaos = rules }
+}
+
+// WithRandom sets the source of random numbers in [0, 1) used by chaos rules and throttle jitter
+func WithRandom(f func() float64) Option {
+	return func(r *Runner) { r.Random = f }
+}
+
+// WithEvents registers a handler that receives lifecycle events for the workflow, its stages and steps
+func WithEvents(f func(Event)) Option {
+	return func(r *Runner) { r.OnEvent = f }
+}
+
+// WithRootPath places all event paths below the given path, used when the runner executes a nested workflow
+func WithRootPath(path string) Option {
+	return func(r *Runner) { r.rootPath = path }
+}
+
+// WithCmdOutput sets the function used to run commands whose stdout forge itself consumes
+func WithCmdOutput(f func(argv []string) ([]byte, error)) Option {
+	return func(r *Runner) { r.CmdOutput = f }
+}
+
+// WithStages restricts execution to the named stages; all stages run when none are given
+func WithStages(names ...string) Option {
+	return func(r *Runner) { r.Stages = names }
+}
+
+// WithSkipSteps skips steps whose "stage.step" id matches one of the path.Match patterns
+func WithSkipSteps(patterns ...string) Option {
+	return func(r *Runner) { r.SkipSteps = patterns }
+}
+
+// WithEnv adds variables to the environment of every process started by steps; env files and
+// ports override them
+func WithEnv(env map[string]string) Option {
+	return func(r *Runner) { r.Env = env }
+}
+
+// WithWorkdir starts the processes of steps in dir
+func WithWorkdir(dir string) Option {
+	return func(r *Runner) { r.Workdir = dir }
+}
+
+// WithStdin connects the processes of steps to stdin instead of forge's stdin
+func WithStdin(stdin io.Reader) Option {
+	return func(r *Runner) { r.Stdin = stdin }
+}
+
+// WithInteractive runs the commands of every exec step in a pseudo-terminal
+func WithInteractive(interactive bool) Option {
+	return func(r *Runner) { r.Interactive = interactive }
+}
+
+// WithProcessOutput sends the stdout and stderr of processes started by steps to the given writers
+// instead of Out, without prefixes
+func WithProcessOutput(stdout, stderr io.Writer) Option {
+	return func(r *Runner) { r.stdout, r.stderr = stdout, stderr }
 }
 
 // Runner implements Runner
@@ -42,8 +159,160 @@ type Runner struct {
 	path         string
 	LoadWorkflow func(path string) (*dsl.Workflow, error)
 	RunCmd       func(argv []string) error
-	Sleep        func(d time.Duration)
-	Out          io.Writer
+	CmdOutput    func(argv []string) ([]byte, error)
+	// Clock tells the time and waits for sleep steps, delays and retry backoff
+	Clock clock.Clock
+	Out   io.Writer
+	// Location overrides the workflow timezone when set
+	Location *time.Location
+	Chaos    []ChaosRule
+	Random   func() float64
+	// FreePort finds a free TCP port for steps requesting "auto" ports
+	FreePort func() (int, error)
+	OnEvent  func(Event)
+	// OnStageStart, OnStageEnd, OnStepStart and OnStepEnd are called with the events of their kind
+	OnStageStart, OnStageEnd []func(Event)
+	OnStepStart, OnStepEnd   []func(Event)
+	History                  *history.Store
+	// Artifacts keeps the artifacts of stages
+	Artifacts *artifact.Store
+	// Cache keeps the keys of cached steps; steps always run when it is nil
+	Cache *cache.Store
+	// Replayable records the commands as executed and the host for Replay
+	Replayable bool
+	// Force runs steps whose idempotency key already succeeded in a run recorded in History
+	Force bool
+	// Active registers the run while it is in progress when set
+	Active *active.Registry
+	// ID is the ID of the next run when set; otherwise one is generated
+	ID string
+	// CancelURL is registered with the run when a server manages it
+	CancelURL string
+	// HTTPClient delivers notifications such as webhooks
+	HTTPClient *http.Client
+	// Context cancels the run when done; hooks still run after cancellation
+	Context context.Context
+	// Stages limits execution to the named stages when non-empty
+	Stages []string
+	// SkipSteps holds "stage.step" patterns of steps that are not executed
+	SkipSteps []string
+	// Vars overrides the values of the workflow's variables
+	Vars map[string]string
+	// Profile names the workflow profile whose overrides apply, none when empty
+	Profile string
+	// EnvFiles lists dotenv files loaded after the workflow's env_file
+	EnvFiles []string
+	// TerminalStatus receives terminal title and progress escape sequences when set
+	TerminalStatus io.Writer
+	// Progress receives a spinner line for the running step when set, see WithProgress
+	Progress io.Writer
+	// Env holds variables added to the environment of every process, above the profile's and
+	// below env files and ports
+	Env map[string]string
+	// Workdir is the directory processes start in, forge's working directory when empty
+	Workdir string
+	// Stdin is read by processes instead of forge's stdin when set; a reader other than a
+	// file is consumed by the first process that reads it
+	Stdin io.Reader
+	// StepPrompt is asked before every step of a stage whether to run it, see WithStepPrompt
+	StepPrompt StepPrompt
+	// Approvers decide about approval steps, see WithApprovers
+	Approvers []Approver
+	// Mode selects whether Run executes the workflow or only prints what it would do
+	Mode ExecutionMode
+	// Interactive runs the commands of every exec step in a pseudo-terminal, as if they set tty
+	Interactive bool
+	// StepWriter returns the writers for the process output of a step when set
+	StepWriter func(stage, step string) (stdout, stderr io.Writer)
+	// OutputTail is the number of output lines kept per step in the run record
+	OutputTail int
+	// OutputLimit bounds the bytes of output kept per step, see WithOutputLimit
+	OutputLimit int
+	// OnFinish holds the functions called with the completed record after every run
+	OnFinish []func(*history.Record)
+	// GitHubAnnotations prints problem matcher findings as GitHub Actions annotations
+	GitHubAnnotations bool
+	// ChangesBase overrides the workflow's changes_base when set
+	ChangesBase string
+	// ChangedFiles replaces the files listed by git for changes filters when non-nil
+	ChangedFiles []string
+	// GOOS and GOARCH are the platform stages and steps with platforms are matched against,
+	// the one forge runs on when empty
+	GOOS, GOARCH string
+	// Policies are Rego files or directories that have to admit the workflow before it runs
+	Policies []string
+	// PolicyEngine evaluates Policies
+	PolicyEngine *policy.Engine
+	// Preflight checks the tools the workflow requires before it runs; they are not checked when nil
+	Preflight *preflight.Checker
+	// StrictWarnings fails runs of workflows with warnings instead of only printing them in dry runs
+	StrictWarnings bool
+	// Dispatch sends stages with runs_on: agent to agents; without it such stages fail
+	Dispatch Dispatcher
+	// OnAgent runs stages with runs_on: agent in this process, see WithOnAgent
+	OnAgent bool
+
+	// rootPath is the event path of the workflow itself, empty for top-level runs
+	rootPath string
+	// ctx bounds the commands of the current step; it is not cancelled while hooks run
+	ctx    context.Context
+	record *history.Record
+	wf     *dsl.Workflow
+	// loc is the timezone of the current run, see location
+	loc *time.Location
+
+	// stdout and stderr receive the output of processes started for the current step
+	stdout, stderr io.Writer
+	findings       []matcher.Finding
+
+	// env holds extra environment variables for processes started by the current step
+	env []string
+	// fileEnv holds the variables loaded from env files for the current run
+	fileEnv map[string]string
+	// profileEnv holds the interpolated env and secrets of the selected profile
+	profileEnv map[string]string
+	// secrets holds the values of the profile's secrets, longest first, for masking
+	secrets []string
+	// vars holds the workflow variables of the current run after overrides
+	vars map[string]string
+	// webhooks and slack hold the workflow's notifications with interpolated URLs and secrets
+	webhooks []notify.Webhook
+	slack    []notify.Slack
+	// snapshots holds the directories saved by snapshot steps of the current run, keyed by step name
+	snapshots map[string]*savedSnapshot
+	// totalSteps is the number of steps the current run executes
+	totalSteps int
+	// dir is the directory of the current step's processes, Workdir when empty
+	dir string
+	// remote is the host or pod the current step's commands run on, nil when they run here
+	remote *remoteHost
+	// pod is the pod of the current stage when it runs on Kubernetes
+	pod *stagePod
+	// timeout bounds each command of the current step, commandTimeout when zero
+	timeout time.Duration
+	// current identifies the step being executed
+	current currentStep
+	// proc holds how the processes of the current exec step are started
+	proc execAttr
+	// globs expands ~ and glob patterns in the command being run, for steps with expand_globs
+	globs bool
+	// group is the name of the group step whose steps are being run, empty outside groups
+	group string
+	// concurrency is the interpolated concurrency group of the current run
+	concurrency string
+	// stepEnv holds the env: entries of the current step and its stage
+	stepEnv map[string]string
+	// cleanEnv starts the current step's processes without the inherited environment
+	cleanEnv bool
+	// ports holds the ports allocated during the current run, keyed by name
+	ports map[string]int
+	// changed holds the files changed since the changes base, nil until listed in the current run
+	changed *[]string
+
+	// outputs holds values exposed by steps, keyed by step name
+	outputs map[string]map[string]string
+	// stepResults holds the status and output of the current run's steps with an id, keyed by id
+	stepResults map[string]expr.StepResult
 }
 
 // NewRunner creates a new Runner for the specified workflow
@@ -51,109 +320,569 @@ func NewRunner(path string, opts ...Option) (*Runner, error) {
 	r := &Runner{
 		path:         path,
 		LoadWorkflow: dsl.LoadWorkflowFromFile,
-		RunCmd:       runCommand,
-		Sleep:        time.Sleep,
+		Clock:        clock.Real{},
 		Out:          os.Stdout,
+		Random:       rand.Float64,
+		FreePort:     freePort,
+		HTTPClient:   &http.Client{Timeout: 10 * time.Second},
+		Context:      context.Background(),
+		Preflight:    preflight.NewChecker(),
+		PolicyEngine: policy.NewEngine(),
+	}
+
+	r.RunCmd = func(argv []string) error {
+		return runCommand(r.ctx, argv, r.procAttr())
+	}
+	r.CmdOutput = func(argv []string) ([]byte, error) {
+		return commandOutput(argv, r.processDir())
 	}
 
 	for _, opt := range opts {
 		opt(r)
 	}
 
-	if r.LoadWorkflow == nil || r.RunCmd == nil || r.Sleep == nil || r.Out == nil {
+	if r.LoadWorkflow == nil || r.RunCmd == nil || r.CmdOutput == nil || r.Clock == nil || r.Out == nil || r.Random == nil || r.FreePort == nil || r.HTTPClient == nil || r.Context == nil {
 		return nil, fmt.Errorf("runner not properly configured")
 	}
+	r.ctx = r.Context
 	return r, nil
 }
 
+// Run executes the workflow, or prints what it would do in ModeDryRun. Both modes load and
+// resolve the workflow the same way, see prepare.
 func (r *Runner) Run() error {
-	fmt.Fprintf(r.Out, "Executing workflow: %s\n", r.path)
+	if r.Mode == ModeDryRun {
+		fmt.Fprintf(r.Out, "[DRY-RUN] Would execute workflow: %s\n", r.path)
+	} else {
+		fmt.Fprintf(r.Out, "Executing workflow: %s\n", r.path)
+	}
 
-	wf, err := r.LoadWorkflow(r.path)
+	wf, loc, err := r.prepare()
 	if err != nil {
 		return err
 	}
+	if r.Mode == ModeDryRun {
+		return r.simulate(wf, loc)
+	}
+	if err := r.resolveNotifications(wf); err != nil {
+		return err
+	}
+	if err := r.checkPolicies(wf); err != nil {
+		return err
+	}
+	if err := r.checkRequires(wf); err != nil {
+		return err
+	}
 
-	// Iterate through stages
-	// TODO: Allow for parallel stage and or step execution in the future
-	for stageIdx, stage := range wf.Stages {
-		fmt.Fprintf(r.Out, "\n=== STAGE %d: %s ===\n", stageIdx+1, stage.Name)
-
-		// Execute each step in the stage
-		for stepIdx, step := range stage.Steps {
-			fmt.Fprintf(r.Out, "STEP %d.%d: %s (%s)\n", stageIdx+1, stepIdx+1, step.Name, step.Type)
+	if err := r.resolveConcurrency(wf); err != nil {
+		return err
+	}
 
-			if err := r.executeStep(&step); err != nil {
-				return fmt.Errorf("stage '%s', step '%s': %w", stage.Name, step.Name, err)
-			}
+	r.wf = wf
+	r.ctx = r.Context
+	r.resetStepResults(wf)
+	r.findings = nil
+	r.ports = nil
+	r.changed = nil
+	r.totalSteps = r.countSteps(wf)
+	r.startRecord(wf, loc)
+	r.registerActive()
+	if err := r.awaitConcurrency(wf); err != nil {
+		if saveErr := r.finishRecord(err); saveErr != nil {
+			fmt.Fprintf(r.Out, "Warning: failed to save run history: %v\n", saveErr)
 		}
-
-		fmt.Fprintf(r.Out, "=== STAGE %d COMPLETED ===\n", stageIdx+1)
+		r.unregisterActive()
+		return err
+	}
+	r.emit(EventWorkflowStart, r.rootPath, wf.Name, nil)
+	r.notify(notify.Event{Event: notify.EventRunStarted})
+	err = r.runStages(wf)
+	hooks := wf.Hooks()
+	if r.OnAgent {
+		hooks = nil
+	}
+	if hookErr := r.runHooks("", r.rootPath, err, hooks); err == nil {
+		err = hookErr
+	}
+	r.cleanupSnapshots(err)
+	r.emit(EventWorkflowEnd, r.rootPath, wf.Name, err)
+	r.finishTerminalStatus(err)
+	if err != nil {
+		r.printFindings()
+	}
+	if saveErr := r.finishRecord(err); saveErr != nil {
+		fmt.Fprintf(r.Out, "Warning: failed to save run history: %v\n", saveErr)
+	}
+	r.unregisterActive()
+	r.notify(notify.Event{
+		Event:    notify.EventRunFinished,
+		Status:   string(r.record.Status),
+		Error:    r.record.Error,
+		Duration: r.record.FinishedAt.Sub(r.record.StartedAt),
+	})
+	r.notifySlack()
+	for _, f := range r.OnFinish {
+		f(r.record)
+	}
+	if err != nil {
+		return err
 	}
 
+	r.printFindings()
 	fmt.Fprintf(r.Out, "\n✓ Workflow execution completed.\n")
+	fmt.Fprintf(r.Out, "Finished at %s\n", r.Clock.Now().In(loc).Format(timestampLayout))
 	return nil
 }
 
-// DryRun simulates Workflow execution
-func (r *Runner) DryRun() error {
-	fmt.Fprintf(r.Out, "[DRY-RUN] Would execute workflow: %s\n", r.path)
+// checkStages reports selected stages the workflow does not declare
+func (r *Runner) checkStages(wf *dsl.Workflow) error {
+	for _, name := range r.Stages {
+		if !slices.ContainsFunc(wf.Stages, func(s dsl.Stage) bool { return s.Name == name }) {
+			return fmt.Errorf("unknown stage %q", name)
+		}
+	}
+	return nil
+}
 
-	wf, err := r.LoadWorkflow(r.path)
-	if err != nil {
-		return err
+func (r *Runner) stageSelected(name string) bool {
+	return len(r.Stages) == 0 || slices.Contains(r.Stages, name)
+}
+
+// findStage returns the named stage of the current run, nil for workflow hooks
+func (r *Runner) findStage(name string) *dsl.Stage {
+	if r.wf == nil || name == "" {
+		return nil
+	}
+	for i := range r.wf.Stages {
+		if r.wf.Stages[i].Name == name {
+			return &r.wf.Stages[i]
+		}
 	}
+	return nil
+}
 
-	// Iterate through stages
-	// TODO: Allow for parallel stage and or step "simulation" in the future
-	for stageIdx, stage := range wf.Stages {
-		fmt.Fprintf(r.Out, "\n[DRY-RUN] === STAGE %d: %s ===\n", stageIdx+1, stage.Name)
+// stepSkipped reports whether --skip-step skips the step of the named stage, matched as
+// stage.step, or as stage.group.step inside a group
+func (r *Runner) stepSkipped(stage, step string) bool {
+	id := r.stepID(stage, step)
+	return slices.ContainsFunc(r.SkipSteps, func(pattern string) bool {
+		ok, _ := path.Match(pattern, id)
+		return ok
+	})
+}
 
-		// Simulate each step in the stage
-		for stepIdx, step := range stage.Steps {
-			fmt.Fprintf(r.Out, "[DRY-RUN] STEP %d.%d: %s (%s)\n", stageIdx+1, stepIdx+1, step.Name, step.Type)
+// stepID returns the "stage.step" id --skip-step and --chaos patterns match, "stage.group.step"
+// for steps of the current group
+func (r *Runner) stepID(stage, step string) string {
+	if r.group != "" {
+		return stage + "." + r.group + "." + step
+	}
+	return stage + "." + step
+}
 
-			switch step.Type {
-			case dsl.StepTypeExec:
-				fmt.Fprintf(r.Out, "[DRY-RUN]   Would execute command: %v\n", step.Run)
-			case dsl.StepTypeSleep:
-				fmt.Fprintf(r.Out, "[DRY-RUN]   Would sleep for %d seconds\n", step.Seconds)
-			}
+// runStages runs the selected stages in order until one fails, then the finally stages.
+// A failing finally stage only becomes the run's error if no earlier stage failed.
+func (r *Runner) runStages(wf *dsl.Workflow) error {
+	// TODO: Allow for parallel stage and or step execution in the future
+	done := 0
+	var err error
+	for _, stageIdx := range wf.StageOrder() {
+		stage := &wf.Stages[stageIdx]
+		if !r.stageSelected(stage.Name) || (err != nil && !stage.Finally) {
+			continue
+		}
+		if !r.onPlatform(stage.Platforms) {
+			r.skipStageForPlatform(stageIdx, stage, &done)
+			continue
+		}
+		if !r.stageChanged(stage) {
+			r.skipUnchangedStage(stageIdx, stage, &done)
+			continue
+		}
+		var stageErr error
+		switch run, windowErr := r.scheduleWindow(stageIdx, stage, &done); {
+		case windowErr != nil:
+			stageErr = windowErr
+		case !run:
+			continue
+		case stage.Finally:
+			stageErr = r.withoutCancel(func() error { return r.runStageAttempts(stageIdx, stage, &done) })
+		default:
+			stageErr = r.runStageAttempts(stageIdx, stage, &done)
+		}
+		if stageErr != nil && stage.OnError == dsl.OnErrorContinue && !errors.Is(stageErr, ErrCancelled) {
+			fmt.Fprintf(r.Out, "Warning: stage '%s' failed, continuing (on_error: continue): %v\n", stage.Name, stageErr)
+			stageErr = nil
+		}
+		switch {
+		case stageErr == nil:
+		case err == nil:
+			err = stageErr
+		default:
+			fmt.Fprintf(r.Out, "Warning: finally stage '%s' failed: %v\n", stage.Name, stageErr)
+		}
+	}
+	return err
+}
+
+// runStageAttempts runs a stage, and runs it again after a failure as often as its on_error
+// strategy allows; cancelled stages are not retried
+func (r *Runner) runStageAttempts(stageIdx int, stage *dsl.Stage, done *int) error {
+	retries := stage.StageRetries()
+	start := *done
+	for attempt := 1; ; attempt++ {
+		err := r.runStage(stageIdx, stage, done)
+		if err == nil || attempt > retries || errors.Is(err, ErrCancelled) || r.ctx.Err() != nil {
+			return err
 		}
+		fmt.Fprintf(r.Out, "Stage '%s' failed (attempt %d/%d), retrying: %v\n", stage.Name, attempt, retries+1, err)
+		*done = start
+	}
+}
 
-		fmt.Fprintf(r.Out, "[DRY-RUN] === STAGE %d COMPLETED ===\n", stageIdx+1)
+// runStage runs the steps of a stage until one fails, then the stage's hooks; done counts the executed steps
+func (r *Runner) runStage(stageIdx int, stage *dsl.Stage, done *int) error {
+	if r.dispatched(stage) {
+		return r.runOnAgent(stageIdx, stage, done)
 	}
+	stagePath := JoinPath(r.rootPath, stage.Name)
+	if stage.Finally {
+		fmt.Fprintf(r.Out, "\n=== STAGE %d: %s (finally) ===\n", stageIdx+1, stage.Name)
+	} else {
+		fmt.Fprintf(r.Out, "\n=== STAGE %d: %s ===\n", stageIdx+1, stage.Name)
+	}
+	printDescription(r.Out, stage.Description)
+	r.printRemote(stage)
+	r.emit(EventStageStart, stagePath, stage.Name, nil)
 
-	fmt.Fprintf(r.Out, "\n[DRY-RUN] ✓ Workflow simulation completed.\n")
+	// Start the stage's pod and restore artifacts, then execute each step in the stage
+	err := r.startPod(stageIdx, stage)
+	if err == nil {
+		err = r.restoreArtifacts(stage)
+	}
+	if err != nil {
+		err = fmt.Errorf("stage '%s': %w", stage.Name, err)
+	}
+	var starts []time.Time
+	for stepIdx, step := range stage.Steps {
+		if err != nil {
+			break
+		}
+		if r.ctx.Err() != nil {
+			err = fmt.Errorf("stage '%s': %w", stage.Name, ErrCancelled)
+			break
+		}
+		if !r.onPlatform(step.Platforms) {
+			fmt.Fprintf(r.Out, "STEP %d.%d: %s (%s)\n", stageIdx+1, stepIdx+1, step.Name, r.notForPlatform())
+			r.skipStep(stage.Name, stagePath, &step, history.StatusSkipped)
+			*done++
+			continue
+		}
+		if r.stepSkipped(stage.Name, step.Name) {
+			fmt.Fprintf(r.Out, "STEP %d.%d: %s (skipped)\n", stageIdx+1, stepIdx+1, step.Name)
+			r.skipStep(stage.Name, stagePath, &step, history.StatusSkipped)
+			*done++
+			continue
+		}
+		var run bool
+		if run, err = r.stepCondition(&step); err != nil {
+			err = fmt.Errorf("stage '%s', step '%s': %w", stage.Name, step.Name, err)
+			break
+		}
+		if !run {
+			fmt.Fprintf(r.Out, "STEP %d.%d: %s (skipped, when is false)\n", stageIdx+1, stepIdx+1, step.Name)
+			r.skipStep(stage.Name, stagePath, &step, history.StatusSkipped)
+			*done++
+			continue
+		}
+		cacheID, cacheKey := r.cacheKey(stage, stagePath, &step)
+		if cacheKey != "" && r.Cache.Hit(cacheID, cacheKey) {
+			fmt.Fprintf(r.Out, "STEP %d.%d: %s (cached)\n", stageIdx+1, stepIdx+1, step.Name)
+			r.skipStep(stage.Name, stagePath, &step, history.StatusCached)
+			*done++
+			continue
+		}
+		key, succeededIn, keyErr := r.idempotencyKey(&step)
+		if keyErr != nil {
+			err = fmt.Errorf("stage '%s', step '%s': %w", stage.Name, step.Name, keyErr)
+			break
+		}
+		if succeededIn != "" {
+			fmt.Fprintf(r.Out, "STEP %d.%d: %s (skipped, idempotency key %s succeeded in run %s)\n", stageIdx+1, stepIdx+1, step.Name, key, succeededIn)
+			r.skipStep(stage.Name, stagePath, &step, history.StatusSkipped)
+			r.setIdempotencyKey(key)
+			*done++
+			continue
+		}
+		var action StepAction
+		if action, err = r.promptStep(stage.Name, stepIdx+1, &step); err != nil {
+			err = fmt.Errorf("stage '%s', step '%s': %w", stage.Name, step.Name, err)
+			break
+		}
+		if action == StepAbort {
+			err = fmt.Errorf("stage '%s', step '%s': %w: aborted", stage.Name, step.Name, ErrCancelled)
+			break
+		}
+		if action == StepSkip {
+			fmt.Fprintf(r.Out, "STEP %d.%d: %s (skipped)\n", stageIdx+1, stepIdx+1, step.Name)
+			r.skipStep(stage.Name, stagePath, &step, history.StatusSkipped)
+			*done++
+			continue
+		}
+		if r.throttle(stage, &starts) != nil {
+			err = fmt.Errorf("stage '%s': %w", stage.Name, ErrCancelled)
+			break
+		}
+		fmt.Fprintf(r.Out, "STEP %d.%d: %s (%s)\n", stageIdx+1, stepIdx+1, step.Name, step.Type)
+		printDescription(r.Out, step.Description)
+		r.updateTerminalStatus(stage.Name, step.Name, *done)
+		err = r.runStep(stage.Name, stagePath, stepIdx+1, &step)
+		r.setIdempotencyKey(key)
+		*done++
+		if err == nil && cacheKey != "" {
+			if cacheErr := r.Cache.Put(cacheID, cacheKey); cacheErr != nil {
+				fmt.Fprintf(r.Out, "Warning: failed to cache step %s: %v\n", step.Name, cacheErr)
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("stage '%s', step '%s': %w", stage.Name, step.Name, err)
+			break
+		}
+	}
+	if collectErr := r.collectArtifacts(stage); err == nil && collectErr != nil {
+		err = fmt.Errorf("stage '%s': %w", stage.Name, collectErr)
+	}
+	if hookErr := r.runHooks(stage.Name, stagePath, err, stage.Hooks()); err == nil && hookErr != nil {
+		err = fmt.Errorf("stage '%s', %w", stage.Name, hookErr)
+	}
+	r.stopPod()
+
+	r.emit(EventStageEnd, stagePath, stage.Name, err)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(r.Out, "=== STAGE %d COMPLETED ===\n", stageIdx+1)
 	return nil
 }
 
+// printDescription prints the description of a stage or step below its header
+func printDescription(w io.Writer, description string) {
+	if description != "" {
+		fmt.Fprintf(w, "  %s\n", description)
+	}
+}
+
+// runStep executes a step of the named stage, at 1-based position index among its steps or
+// hooks, and records its outcome
+func (r *Runner) runStep(stage, stagePath string, index int, step *dsl.Step) error {
+	stepPath := JoinPath(stagePath, step.Name)
+	prev := r.current
+	r.current = currentStep{stage: stage, step: step.Name, index: index}
+	defer func() { r.current = prev }()
+	r.emit(EventStepStart, stepPath, step.Name, nil)
+	r.startStepRecord(stage, step, stepPath)
+	rec := len(r.record.Steps) - 1
+
+	var err error
+	if step.Type == dsl.StepTypeGroup {
+		// The steps of a group set up their processes themselves
+		err = r.withRetries(step, func() error {
+			return r.withChaos(stage, step, func() error { return r.runGroup(stage, stepPath, step) })
+		})
+	} else {
+		err = r.withStepDir(stage, step, func() error {
+			return r.withStepEnv(stage, step, func() error {
+				return r.withPorts(step, func() error {
+					return r.withStepOutput(stage, step.Name, func() error {
+						return r.withProgress(step, func() error {
+							return r.withOutputTail(func() error {
+								return r.withStepCapture(step, func() error {
+									return r.withMatchers(step, func() error {
+										return r.withStepTimeout(step, func() error {
+											return r.withRetries(step, func() error {
+												return r.withChaos(stage, step, func() error { return r.executeStep(step) })
+											})
+										})
+									})
+								})
+							})
+						})
+					})
+				})
+			})
+		})
+	}
+	if err != nil && r.ctx.Err() != nil {
+		err = fmt.Errorf("%w: %v", ErrCancelled, err)
+	}
+	r.endStepRecord(rec, err)
+	r.setStepStatus(step, statusOf(err))
+	r.emit(EventStepEnd, stepPath, step.Name, err)
+
+	if err != nil {
+		r.notify(notify.Event{Event: notify.EventStepFailed, Stage: stage, Step: step.Name, Error: err.Error()})
+	}
+	return err
+}
+
+// prepare loads the workflow and resolves its timezone, stage selection, variables and env files
+func (r *Runner) prepare() (*dsl.Workflow, *time.Location, error) {
+	wf, err := r.LoadWorkflow(r.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := r.checkWarnings(wf); err != nil {
+		return nil, nil, err
+	}
+
+	loc, err := r.location(wf)
+	if err != nil {
+		return nil, nil, err
+	}
+	r.loc = loc
+	if r.Mode == ModeExecute {
+		fmt.Fprintf(r.Out, "Started at %s\n", r.Clock.Now().In(loc).Format(timestampLayout))
+	}
+
+	if err := r.checkStages(wf); err != nil {
+		return nil, nil, err
+	}
+	if err := r.resolveVars(wf); err != nil {
+		return nil, nil, err
+	}
+	if err := r.loadEnvFiles(wf, r.Mode.prefix()); err != nil {
+		return nil, nil, err
+	}
+	if err := r.resolveProfile(wf, r.Mode.prefix()); err != nil {
+		return nil, nil, err
+	}
+	return wf, loc, nil
+}
+
+// location returns the effective timezone: the runner override, then the workflow setting, then local
+func (r *Runner) location(wf *dsl.Workflow) (*time.Location, error) {
+	if r.Location != nil {
+		return r.Location, nil
+	}
+	loc, err := wf.Location()
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", wf.Timezone, err)
+	}
+	return loc, nil
+}
+
 // executeStep executes a single step (extracted for reusability)
 func (r *Runner) executeStep(step *dsl.Step) error {
 	switch step.Type {
 	case dsl.StepTypeExec:
-		if err := r.RunCmd(step.Run); err != nil {
-			return fmt.Errorf("command execution failed: %w", err)
-		}
+		return r.runExec(step)
 	case dsl.StepTypeSleep:
 		fmt.Fprintf(r.Out, "  Sleeping for %d seconds...\n", step.Seconds)
-		r.Sleep(time.Duration(step.Seconds) * time.Second)
+		return r.sleep(time.Duration(step.Seconds) * time.Second)
+	case dsl.StepTypeGoTest:
+		return r.runGoTest(step)
+	case dsl.StepTypeSnapshot:
+		return r.takeSnapshot(step)
+	case dsl.StepTypeRestore:
+		return r.restoreSnapshot(step)
+	case dsl.StepTypeSlack:
+		return r.postSlack(step)
+	case dsl.StepTypeApproval:
+		return r.awaitApproval(step)
+	case dsl.StepTypeAssert:
+		return r.runAssert(step)
 	default:
-		// This should never happen due to validation in LoadWorkflowFromFile
-		return fmt.Errorf("unknown step type: %s", step.Type)
+		// Validation in LoadWorkflowFromFile only lets through types an executor supports
+		return r.runExecutor(step)
 	}
-	return nil
 }
 
-// runCommand executes a command with arguments
-func runCommand(argv []string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+// sleep waits for d on the runner's clock or until the run is cancelled
+func (r *Runner) sleep(d time.Duration) error {
+	select {
+	case <-r.ctx.Done():
+		return r.ctx.Err()
+	case <-r.Clock.After(d):
+		return nil
+	}
+}
+
+// commandTimeout bounds every command of steps without a timeout
+const commandTimeout = 10 * time.Minute
+
+// killGrace is how long the processes of a cancelled step get to exit after SIGTERM before they are killed
+const killGrace = 5 * time.Second
+
+// procAttr is the context processes of a step are started in
+type procAttr struct {
+	// Env is added to the inherited environment
+	Env            []string
+	Dir            string
+	Stdin          io.Reader
+	Stdout, Stderr io.Writer
+	// CleanEnv makes Env the whole environment instead of adding to the inherited one
+	CleanEnv bool
+	// Timeout bounds the process, commandTimeout when zero
+	Timeout time.Duration
+	execAttr
+}
+
+// execAttr holds the settings of exec steps for their processes
+type execAttr struct {
+	// TTY runs the process in a pseudo-terminal whose output, stderr included, goes to Stdout
+	TTY bool
+	// User and Group run the process as another user or group, by name or numeric id
+	User, Group string
+}
+
+// procAttr returns the context for processes started by the current step
+func (r *Runner) procAttr() procAttr {
+	if r.remote != nil {
+		// The step's environment and directory are set on the host, see remoteHost.command
+		return procAttr{Stdin: r.Stdin, Stdout: r.processStdout(), Stderr: r.processStderr(), Timeout: r.timeout, execAttr: r.proc}
+	}
+	return procAttr{
+		Env:      r.commandEnv(),
+		Dir:      r.processDir(),
+		Stdin:    r.Stdin,
+		Stdout:   r.processStdout(),
+		Stderr:   r.processStderr(),
+		CleanEnv: r.cleanEnv,
+		Timeout:  r.timeout,
+		execAttr: r.proc,
+	}
+}
+
+// runCommand executes a command with arguments until it exits or ctx is done
+func runCommand(ctx context.Context, argv []string, attr procAttr) error {
+	ctx, cancel := context.WithTimeout(ctx, cmp.Or(attr.Timeout, commandTimeout))
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
+	cmd.Dir = attr.Dir
+	switch {
+	case attr.CleanEnv:
+		cmd.Env = append([]string{}, attr.Env...)
+	case len(attr.Env) > 0:
+		cmd.Env = append(os.Environ(), attr.Env...)
+	}
+	stdin := attr.Stdin
+	if stdin == nil {
+		stdin = os.Stdin
+	}
+	if attr.TTY {
+		if err := setCredential(cmd, attr.User, attr.Group); err != nil {
+			return err
+		}
+		return runInTerminal(cmd, stdin, attr.Stdout)
+	}
+	cmd.Stdout = attr.Stdout
+	cmd.Stderr = attr.Stderr
+	cmd.Stdin = stdin
+	stopOnCancel(cmd)
+	if err := setCredential(cmd, attr.User, attr.Group); err != nil {
+		return err
+	}
 
 	if err := cmd.Run(); err != nil {
 		return err
@@ -161,3 +890,29 @@ func runCommand(argv []string) error {
 
 	return nil
 }
+
+// commandOutput executes a command in dir and returns its stdout
+func commandOutput(argv []string, dir string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	return cmd.Output()
+}
+
+// Outputs returns the values exposed by the named step during the last run
+func (r *Runner) Outputs(step string) map[string]string {
+	return r.outputs[step]
+}
+
+func (r *Runner) setOutput(step, key, value string) {
+	if r.outputs == nil {
+		r.outputs = make(map[string]map[string]string)
+	}
+	if r.outputs[step] == nil {
+		r.outputs[step] = make(map[string]string)
+	}
+	r.outputs[step][key] = value
+}
@@ -2,13 +2,17 @@ package runner
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"reflect"
 	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/andre-koe/forge/internal/clock"
 	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
 )
 
 func mockLoadWorkflow(stages []dsl.Stage) func(path string) (*dsl.Workflow, error) {
@@ -403,3 +407,328 @@ func TestRunner_EmptyWorkflow(t *testing.T) {
 		t.Error("output missing completion message for empty workflow")
 	}
 }
+
+func TestRunner_Run_Timezone(t *testing.T) {
+	workflow := []dsl.Stage{
+		{Name: "build", Steps: []dsl.Step{{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"go", "build"}}}},
+	}
+	fixed := time.FixedZone("FRG", 3*60*60)
+
+	tests := []struct {
+		name     string
+		timezone string
+		opts     []Option
+		wantZone string
+		wantErr  bool
+	}{
+		{name: "workflow timezone", timezone: "UTC", wantZone: "UTC"},
+		{name: "runner override wins", timezone: "UTC", opts: []Option{WithTimezone(fixed)}, wantZone: "FRG"},
+		{name: "invalid workflow timezone", timezone: "Nowhere/Nothing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cmdCalls [][]string
+			out := new(bytes.Buffer)
+			load := func(path string) (*dsl.Workflow, error) {
+				return &dsl.Workflow{Name: "tz", Timezone: tt.timezone, Stages: workflow}, nil
+			}
+
+			runner, err := NewRunner("test.yaml", append([]Option{
+				WithOut(out),
+				WithLoadWorkflow(load),
+				WithRunCmd(mockRunCmd(&cmdCalls)),
+			}, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("NewRunner() failed: %v", err)
+			}
+
+			err = runner.Run()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !strings.Contains(out.String(), "Started at ") || !strings.Contains(out.String(), " "+tt.wantZone+"\n") {
+				t.Errorf("output missing %s timestamps, got:\n%s", tt.wantZone, out.String())
+			}
+			if got := runner.Record().Timezone; got != tt.wantZone {
+				t.Errorf("record timezone = %q, want %q", got, tt.wantZone)
+			}
+		})
+	}
+}
+
+func TestRunner_Run_Stages(t *testing.T) {
+	stages := []dsl.Stage{
+		{Name: "build", Steps: []dsl.Step{{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"make"}}}},
+		{Name: "test", Steps: []dsl.Step{{Name: "unit", Type: dsl.StepTypeExec, Run: []string{"go", "test"}}}},
+	}
+
+	tests := []struct {
+		name      string
+		stages    []string
+		wantCalls [][]string
+		wantErr   bool
+	}{
+		{name: "all stages", wantCalls: [][]string{{"make"}, {"go", "test"}}},
+		{name: "selected stage", stages: []string{"test"}, wantCalls: [][]string{{"go", "test"}}},
+		{name: "unknown stage", stages: []string{"deploy"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls [][]string
+			r, err := NewRunner("test.yaml",
+				WithOut(new(bytes.Buffer)),
+				WithLoadWorkflow(mockLoadWorkflow(stages)),
+				WithRunCmd(mockRunCmd(&calls)),
+				WithStages(tt.stages...),
+			)
+			if err != nil {
+				t.Fatalf("NewRunner() failed: %v", err)
+			}
+
+			err = r.Run()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("commands = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRunner_Run_SkipSteps(t *testing.T) {
+	stages := []dsl.Stage{
+		{Name: "build", Steps: []dsl.Step{
+			{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"make"}},
+			{Name: "lint", Type: dsl.StepTypeExec, Run: []string{"lint"}},
+		}},
+		{Name: "deploy", Steps: []dsl.Step{{Name: "push", Type: dsl.StepTypeExec, Run: []string{"push"}}}},
+	}
+
+	var calls [][]string
+	out := new(bytes.Buffer)
+	r, err := NewRunner("test.yaml",
+		WithOut(out),
+		WithLoadWorkflow(mockLoadWorkflow(stages)),
+		WithRunCmd(mockRunCmd(&calls)),
+		WithSkipSteps("build.lint", "deploy.*"),
+	)
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	if want := [][]string{{"make"}}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("commands = %v, want %v", calls, want)
+	}
+	if !strings.Contains(out.String(), "STEP 1.2: lint (skipped)") {
+		t.Errorf("output does not report skipped step:\n%s", out.String())
+	}
+	if got := r.Record().Steps[2].Status; got != history.StatusSkipped {
+		t.Errorf("deploy.push status = %q, want %q", got, history.StatusSkipped)
+	}
+}
+
+func TestRunner_FinallyStages(t *testing.T) {
+	tests := []struct {
+		name      string
+		fail      []string
+		want      []string
+		wantErrIn string
+	}{
+		{
+			name: "success",
+			want: []string{"build", "deploy", "teardown"},
+		},
+		{
+			name:      "failing stage",
+			fail:      []string{"build"},
+			want:      []string{"build", "teardown"},
+			wantErrIn: "stage 'build'",
+		},
+		{
+			name:      "failing finally stage",
+			fail:      []string{"teardown"},
+			want:      []string{"build", "deploy", "teardown"},
+			wantErrIn: "stage 'teardown'",
+		},
+		{
+			name:      "failing finally stage keeps the original error",
+			fail:      []string{"deploy", "teardown"},
+			want:      []string{"build", "deploy", "teardown"},
+			wantErrIn: "stage 'deploy'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// teardown is declared first but runs last
+			stages := []dsl.Stage{
+				{Name: "teardown", Finally: true, Steps: []dsl.Step{execStep("teardown")}},
+				{Name: "build", Steps: []dsl.Step{execStep("build")}},
+				{Name: "deploy", Steps: []dsl.Step{execStep("deploy")}},
+			}
+			var calls []string
+			runCmd := func(argv []string) error {
+				calls = append(calls, argv[0])
+				if slices.Contains(tt.fail, argv[0]) {
+					return errCommand
+				}
+				return nil
+			}
+			var out bytes.Buffer
+			r, err := NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(runCmd))
+			if err != nil {
+				t.Fatalf("NewRunner() failed: %v", err)
+			}
+
+			err = r.Run()
+			if tt.wantErrIn == "" && err != nil {
+				t.Errorf("Run() error = %v", err)
+			}
+			if tt.wantErrIn != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErrIn)) {
+				t.Errorf("Run() error = %v, want it to mention %s", err, tt.wantErrIn)
+			}
+			if !reflect.DeepEqual(calls, tt.want) {
+				t.Errorf("commands = %v, want %v", calls, tt.want)
+			}
+			if !strings.Contains(out.String(), "=== STAGE 1: teardown (finally) ===") {
+				t.Errorf("output does not mark the finally stage:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestRunner_Clock(t *testing.T) {
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	stages := []dsl.Stage{{Name: "wait", Steps: []dsl.Step{
+		{Name: "pause", Type: dsl.StepTypeSleep, Seconds: 30},
+	}}}
+
+	var events []Event
+	out := new(bytes.Buffer)
+	r, err := NewRunner("test.yaml",
+		WithOut(out),
+		WithLoadWorkflow(mockLoadWorkflow(stages)),
+		WithClock(clk),
+		WithEvents(func(e Event) { events = append(events, e) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() { done <- r.Run() }()
+	clk.BlockUntil(1)
+	clk.Advance(30 * time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "Started at 2025-03-01 09:00:00") || !strings.Contains(out.String(), "Finished at 2025-03-01 09:00:30") {
+		t.Errorf("output does not use the clock's time:\n%s", out)
+	}
+	if first, last := events[0].Time, events[len(events)-1].Time; !first.Equal(start) || last.Sub(first) != 30*time.Second {
+		t.Errorf("events span %s to %s, want 30s from %s", first, last, start)
+	}
+}
+
+func TestRunner_SleepCancelled(t *testing.T) {
+	stages := []dsl.Stage{{Name: "wait", Steps: []dsl.Step{
+		{Name: "pause", Type: dsl.StepTypeSleep, Seconds: 3600},
+	}}}
+	clk := clock.NewFake(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	r, err := NewRunner("test.yaml",
+		WithOut(new(bytes.Buffer)),
+		WithLoadWorkflow(mockLoadWorkflow(stages)),
+		WithClock(clk),
+		WithContext(ctx),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() { done <- r.Run() }()
+	clk.BlockUntil(1)
+	cancel()
+	if err := <-done; !errors.Is(err, ErrCancelled) {
+		t.Errorf("Run() error = %v, want %v", err, ErrCancelled)
+	}
+}
+
+func TestRunner_Run_OnError(t *testing.T) {
+	tests := []struct {
+		name      string
+		onError   dsl.OnError
+		retries   int
+		failures  int // how often the flaky command fails before it succeeds
+		wantErr   bool
+		wantCalls []string
+	}{
+		{
+			name: "abort", failures: 1, wantErr: true,
+			wantCalls: []string{"flaky"},
+		},
+		{
+			name: "continue", onError: dsl.OnErrorContinue, failures: 1,
+			wantCalls: []string{"flaky", "after"},
+		},
+		{
+			name: "retry once by default", onError: dsl.OnErrorRetry, failures: 1,
+			wantCalls: []string{"flaky", "setup", "flaky", "verify", "after"},
+		},
+		{
+			name: "retries exhausted", onError: dsl.OnErrorRetry, retries: 2, failures: 3, wantErr: true,
+			wantCalls: []string{"flaky", "setup", "flaky", "setup", "flaky"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stages := []dsl.Stage{
+				{Name: "cloud", OnError: tt.onError, MaxStageRetries: tt.retries, Steps: []dsl.Step{
+					{Name: "setup", Type: dsl.StepTypeExec, Run: []string{"setup"}},
+					{Name: "flaky", Type: dsl.StepTypeExec, Run: []string{"flaky"}},
+					{Name: "verify", Type: dsl.StepTypeExec, Run: []string{"verify"}},
+				}},
+				{Name: "next", Steps: []dsl.Step{{Name: "after", Type: dsl.StepTypeExec, Run: []string{"after"}}}},
+			}
+
+			var calls []string
+			failures := tt.failures
+			r, err := NewRunner("test.yaml",
+				WithOut(new(bytes.Buffer)),
+				WithLoadWorkflow(mockLoadWorkflow(stages)),
+				WithRunCmd(func(argv []string) error {
+					calls = append(calls, argv[0])
+					if argv[0] == "flaky" && failures > 0 {
+						failures--
+						return errors.New("throttled")
+					}
+					return nil
+				}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = r.Run()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// The first setup always runs; compare what follows
+			if got := calls[1:]; !slices.Equal(got, tt.wantCalls) {
+				t.Errorf("commands = %q, want setup followed by %q", calls, tt.wantCalls)
+			}
+		})
+	}
+}
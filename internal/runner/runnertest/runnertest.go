@@ -0,0 +1,115 @@
+// Package runnertest provides fake runners for testing code that starts workflow runs through
+// runner.Interface, without executing workflows or spawning processes.
+package runnertest
+
+import (
+	"io"
+	"sync"
+
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+// Runner is a fake runner.Interface. Its zero value succeeds without doing anything.
+type Runner struct {
+	// Path is the workflow the runner was created for
+	Path string
+	// Config is a real runner with the options the fake was created with, to inspect them, e.g.
+	// Config.Mode or Config.Context; it never runs
+	Config *runner.Runner
+	// Output is written to Config.Out by every run
+	Output string
+	// RunFunc is called by Run and DryRun, with dryRun telling which, and its error returned; nil succeeds
+	RunFunc func(r *Runner, dryRun bool) error
+	// Result is returned by Results
+	Result runner.Results
+
+	mu            sync.Mutex
+	runs, dryRuns int
+}
+
+var _ runner.Interface = (*Runner)(nil)
+
+// Run counts the run, writes Output and calls RunFunc
+func (r *Runner) Run() error {
+	dryRun := r.Config != nil && r.Config.Mode == runner.ModeDryRun
+	return r.run(dryRun)
+}
+
+// DryRun counts the dry run, writes Output and calls RunFunc
+func (r *Runner) DryRun() error {
+	return r.run(true)
+}
+
+func (r *Runner) run(dryRun bool) error {
+	r.mu.Lock()
+	if dryRun {
+		r.dryRuns++
+	} else {
+		r.runs++
+	}
+	r.mu.Unlock()
+	if r.Output != "" && r.Config != nil {
+		_, _ = io.WriteString(r.Config.Out, r.Output)
+	}
+	if r.RunFunc == nil {
+		return nil
+	}
+	return r.RunFunc(r, dryRun)
+}
+
+// Results returns Result
+func (r *Runner) Results() runner.Results {
+	return r.Result
+}
+
+// Runs returns how often the runner ran, dry runs not included
+func (r *Runner) Runs() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.runs
+}
+
+// DryRuns returns how often the runner simulated a run
+func (r *Runner) DryRuns() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dryRuns
+}
+
+// Factory creates fake runners and keeps them for inspection. Its zero value creates runners
+// that succeed without doing anything.
+type Factory struct {
+	// Configure is called with every new runner, e.g. to set its RunFunc
+	Configure func(*Runner)
+	// Err makes New fail when set
+	Err error
+
+	mu      sync.Mutex
+	runners []*Runner
+}
+
+// New creates a fake for the workflow at path; it is a runner.Factory
+func (f *Factory) New(path string, opts ...runner.Option) (runner.Interface, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	config, err := runner.NewRunner(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	r := &Runner{Path: path, Config: config}
+	if f.Configure != nil {
+		f.Configure(r)
+	}
+	f.mu.Lock()
+	f.runners = append(f.runners, r)
+	f.mu.Unlock()
+	return r, nil
+}
+
+// Runners returns the runners created so far, in order
+func (f *Factory) Runners() []*Runner {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*Runner(nil), f.runners...)
+}
@@ -0,0 +1,69 @@
+package runnertest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+func TestFactory(t *testing.T) {
+	failed := errors.New("failed")
+	f := &Factory{Configure: func(r *Runner) {
+		r.Output = "ran " + r.Path + "\n"
+		r.RunFunc = func(r *Runner, dryRun bool) error {
+			if r.Path == "bad.yaml" && !dryRun {
+				return failed
+			}
+			return nil
+		}
+	}}
+	var newRunner runner.Factory = f.New
+
+	var out bytes.Buffer
+	good, err := newRunner("good.yaml", runner.WithOut(&out))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	bad, err := newRunner("bad.yaml", runner.WithOut(&out), runner.WithMode(runner.ModeDryRun))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := good.Run(); err != nil {
+		t.Errorf("good.Run() error: %v", err)
+	}
+	if err := bad.Run(); err != nil {
+		t.Errorf("bad.Run() in dry-run mode error: %v", err)
+	}
+	bad.(*Runner).Config.Mode = runner.ModeExecute
+	if err := bad.Run(); !errors.Is(err, failed) {
+		t.Errorf("bad.Run() error = %v, want %v", err, failed)
+	}
+
+	runners := f.Runners()
+	if len(runners) != 2 || runners[0].Runs() != 1 || runners[1].Runs() != 1 || runners[1].DryRuns() != 1 {
+		t.Errorf("runners = %+v", runners)
+	}
+	if want := "ran good.yaml\nran bad.yaml\nran bad.yaml\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	f.Err = failed
+	if _, err := f.New("good.yaml"); !errors.Is(err, failed) {
+		t.Errorf("New() error = %v, want %v", err, failed)
+	}
+}
+
+func TestRunner_ZeroValue(t *testing.T) {
+	var r Runner
+	if err := r.Run(); err != nil {
+		t.Errorf("Run() error: %v", err)
+	}
+	if err := r.DryRun(); err != nil {
+		t.Errorf("DryRun() error: %v", err)
+	}
+	if r.Runs() != 1 || r.DryRuns() != 1 || r.Results().Record != nil {
+		t.Errorf("zero runner after two runs = %+v", &r)
+	}
+}
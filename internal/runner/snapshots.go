@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/snapshot"
+)
+
+// savedSnapshot is a directory saved by a snapshot step during the current run
+type savedSnapshot struct {
+	path             string
+	dir              string
+	restoreOnFailure bool
+}
+
+// takeSnapshot saves the directory of a snapshot step; relative paths are relative to the step's
+// directory, and the snapshot is restored to the same directory wherever it is restored from
+func (r *Runner) takeSnapshot(step *dsl.Step) error {
+	dir, err := os.MkdirTemp("", "forge-snapshot-")
+	if err != nil {
+		return err
+	}
+	path := step.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.processDir(), path)
+	}
+	stats, err := snapshot.Take(path, filepath.Join(dir, "tree"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("snapshot of %s failed: %w", step.Path, err)
+	}
+
+	if r.snapshots == nil {
+		r.snapshots = make(map[string]*savedSnapshot)
+	}
+	// Names are unique in valid workflows; a step taking its snapshot again, e.g. on a retry,
+	// replaces the older one
+	if old, ok := r.snapshots[step.Name]; ok {
+		os.RemoveAll(old.dir)
+	}
+	r.snapshots[step.Name] = &savedSnapshot{path: path, dir: dir, restoreOnFailure: step.RestoreOnFailure}
+	fmt.Fprintf(r.Out, "  Snapshot of %s: %s\n", step.Path, stats)
+	return nil
+}
+
+func (r *Runner) restoreSnapshot(step *dsl.Step) error {
+	s, ok := r.snapshots[step.Snapshot]
+	if !ok {
+		return fmt.Errorf("snapshot %q was not taken", step.Snapshot)
+	}
+	stats, err := snapshot.Restore(filepath.Join(s.dir, "tree"), s.path)
+	if err != nil {
+		return fmt.Errorf("restore of %s failed: %w", s.path, err)
+	}
+	fmt.Fprintf(r.Out, "  Restored %s: %s\n", s.path, stats)
+	return nil
+}
+
+// cleanupSnapshots restores snapshots marked restore_on_failure if the run failed and
+// removes all snapshots taken during the run
+func (r *Runner) cleanupSnapshots(runErr error) {
+	for name, s := range r.snapshots {
+		if runErr != nil && s.restoreOnFailure {
+			if _, err := snapshot.Restore(filepath.Join(s.dir, "tree"), s.path); err != nil {
+				fmt.Fprintf(r.Out, "Warning: failed to restore snapshot %s of %s: %v\n", name, s.path, err)
+			} else {
+				fmt.Fprintf(r.Out, "Restored %s from snapshot %s after failure\n", s.path, name)
+			}
+		}
+		if err := os.RemoveAll(s.dir); err != nil {
+			fmt.Fprintf(r.Out, "Warning: failed to remove snapshot %s: %v\n", name, err)
+		}
+	}
+	r.snapshots = nil
+}
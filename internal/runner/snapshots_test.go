@@ -0,0 +1,165 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_Snapshots(t *testing.T) {
+	tests := []struct {
+		name             string
+		restoreStep      bool
+		restoreOnFailure bool
+		failAfter        bool
+		wantContent      string
+		wantErr          bool
+	}{
+		{name: "restore step", restoreStep: true, wantContent: "original"},
+		{name: "no restore keeps mutation", wantContent: "mutated"},
+		{name: "restore on failure", restoreOnFailure: true, failAfter: true, wantContent: "original", wantErr: true},
+		{name: "failure without restore_on_failure", failAfter: true, wantContent: "mutated", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			work := filepath.Join(t.TempDir(), "work")
+			if err := os.MkdirAll(work, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			file := filepath.Join(work, "data.txt")
+			if err := os.WriteFile(file, []byte("original"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			steps := []dsl.Step{
+				{Name: "save", Type: dsl.StepTypeSnapshot, Path: work, RestoreOnFailure: tt.restoreOnFailure},
+				{Name: "mutate", Type: dsl.StepTypeExec, Run: []string{"mutate"}},
+			}
+			if tt.failAfter {
+				steps = append(steps, dsl.Step{Name: "break", Type: dsl.StepTypeExec, Run: []string{"fail"}})
+			}
+			if tt.restoreStep {
+				steps = append(steps, dsl.Step{Name: "undo", Type: dsl.StepTypeRestore, Snapshot: "save"})
+			}
+
+			r, err := NewRunner("test.yaml",
+				WithOut(new(bytes.Buffer)),
+				WithLoadWorkflow(mockLoadWorkflow([]dsl.Stage{{Name: "destructive", Steps: steps}})),
+				WithRunCmd(func(argv []string) error {
+					if argv[0] == "fail" {
+						return errors.New("boom")
+					}
+					return os.WriteFile(file, []byte("mutated"), 0o644)
+				}),
+			)
+			if err != nil {
+				t.Fatalf("NewRunner() failed: %v", err)
+			}
+
+			if err := r.Run(); (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			got, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("ReadFile() error: %v", err)
+			}
+			if string(got) != tt.wantContent {
+				t.Errorf("content = %q, want %q", got, tt.wantContent)
+			}
+			if r.snapshots != nil {
+				t.Errorf("snapshots not cleaned up: %v", r.snapshots)
+			}
+		})
+	}
+}
+
+func TestRunner_Snapshots_Workdir(t *testing.T) {
+	project := filepath.Join(t.TempDir(), "project")
+	if err := os.MkdirAll(project, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	workflow := filepath.Join(project, "ci.yaml")
+	file := filepath.Join(project, "data.txt")
+	for name, content := range map[string]string{workflow: "name: ci\n", file: "original"} {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// forge runs from elsewhere; path: . is the step's workdir, the workflow's directory
+	t.Chdir(t.TempDir())
+	steps := []dsl.Step{
+		{Name: "save", Type: dsl.StepTypeSnapshot, Path: ".", RestoreOnFailure: true},
+		{Name: "mutate", Type: dsl.StepTypeExec, Run: []string{"mutate"}},
+		{Name: "break", Type: dsl.StepTypeExec, Run: []string{"fail"}},
+	}
+	var out bytes.Buffer
+	r, err := NewRunner(workflow,
+		WithOut(&out),
+		WithLoadWorkflow(mockLoadWorkflow([]dsl.Stage{{Name: "destructive", Workdir: ".", Steps: steps}})),
+		WithRunCmd(func(argv []string) error {
+			if argv[0] == "fail" {
+				return errors.New("boom")
+			}
+			if err := os.WriteFile(file, []byte("mutated"), 0o644); err != nil {
+				return err
+			}
+			return os.WriteFile(filepath.Join(project, "junk.txt"), []byte("junk"), 0o644)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err == nil {
+		t.Fatal("Run() succeeded, want the break step to fail")
+	}
+	if got, err := os.ReadFile(file); err != nil || string(got) != "original" {
+		t.Errorf("content = %q, %v, want original restored:\n%s", got, err, out.String())
+	}
+	if _, err := os.Stat(filepath.Join(project, "junk.txt")); !os.IsNotExist(err) {
+		t.Error("restoring kept a file created after the snapshot")
+	}
+}
+
+func TestRunner_Snapshots_TakenAgain(t *testing.T) {
+	retries := 1
+	steps := []dsl.Step{{Name: "prepare", Type: dsl.StepTypeGroup, Retries: &retries, Steps: []dsl.Step{
+		{Name: "save", Type: dsl.StepTypeSnapshot, Path: t.TempDir()},
+		{Name: "flaky", Type: dsl.StepTypeExec, Run: []string{"flaky"}},
+	}}}
+	var r *Runner
+	var dirs []string
+	r, err := NewRunner("test.yaml",
+		WithOut(new(bytes.Buffer)),
+		WithLoadWorkflow(mockLoadWorkflow([]dsl.Stage{{Name: "build", Steps: steps}})),
+		WithRunCmd(func([]string) error {
+			dirs = append(dirs, r.snapshots["save"].dir)
+			if len(dirs) == 1 {
+				return errors.New("boom")
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	var leaked []string
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if len(dirs) != 2 || dirs[0] == dirs[1] {
+		t.Fatalf("snapshot dirs = %q, want one per attempt", dirs)
+	}
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); err == nil {
+			leaked = append(leaked, dir)
+		}
+	}
+	if len(leaked) > 0 {
+		t.Errorf("snapshot dirs left behind: %q", leaked)
+	}
+}
@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// stepCommands returns the before and after commands of an exec step of wf, the workflow's
+// step_hooks wrapped around the step's own
+func stepCommands(wf *dsl.Workflow, step *dsl.Step) (before, after [][]string) {
+	before, after = step.Before, step.After
+	if wf != nil && wf.StepHooks != nil {
+		before = append(slices.Clone(wf.StepHooks.Before), before...)
+		after = append(slices.Clone(after), wf.StepHooks.After...)
+	}
+	return before, after
+}
+
+// runExec runs the command of an exec step between its before and after commands. A failed before
+// command fails the step without running it; the after commands run regardless, even after
+// cancellation, and all of them run even if one fails.
+func (r *Runner) runExec(step *dsl.Step) error {
+	before, after := stepCommands(r.wf, step)
+
+	var err error
+	for _, argv := range before {
+		if err = r.exec(argv); err != nil {
+			err = fmt.Errorf("before command %v failed: %w", argv, err)
+			break
+		}
+	}
+	if err == nil {
+		r.proc = execAttr{TTY: step.TTY || r.Interactive && !step.Redirected(), User: step.User, Group: step.Group}
+		r.globs = step.ExpandGlobs
+		err = r.withRedirects(step, func() error { return r.exec(runCommandLine(r.wf, step)) })
+		r.proc, r.globs = execAttr{}, false
+		if err != nil {
+			err = fmt.Errorf("command execution failed: %w", err)
+		}
+	}
+	if len(after) == 0 {
+		return err
+	}
+
+	afterErr := r.withoutCancel(func() error {
+		var first error
+		for _, argv := range after {
+			if err := r.exec(argv); err != nil && first == nil {
+				first = fmt.Errorf("after command %v failed: %w", argv, err)
+			}
+		}
+		return first
+	})
+	if afterErr != nil {
+		if err == nil {
+			return afterErr
+		}
+		fmt.Fprintf(r.Out, "  %v\n", afterErr)
+	}
+	return err
+}
+
+// interpolateCommands interpolates each command of cmds for a plan, with secrets masked
+func (r *Runner) interpolateCommands(cmds [][]string) ([][]string, error) {
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+	out := make([][]string, len(cmds))
+	for i, argv := range cmds {
+		expanded, err := r.interpolate(argv)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = r.maskSecretsAll(expanded)
+	}
+	return out, nil
+}
@@ -0,0 +1,110 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_StepHooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		fail    string
+		wantErr string
+		want    [][]string
+	}{
+		{
+			name: "success",
+			want: [][]string{{"creds"}, {"mkdir", "tmp"}, {"build"}, {"rm", "tmp"}, {"timer", "stop"}},
+		},
+		{
+			name:    "step fails",
+			fail:    "build",
+			wantErr: "command execution failed",
+			want:    [][]string{{"creds"}, {"mkdir", "tmp"}, {"build"}, {"rm", "tmp"}, {"timer", "stop"}},
+		},
+		{
+			name:    "before fails",
+			fail:    "creds",
+			wantErr: "before command [creds] failed",
+			want:    [][]string{{"creds"}, {"rm", "tmp"}, {"timer", "stop"}},
+		},
+		{
+			name:    "after fails",
+			fail:    "rm",
+			wantErr: "after command [rm ${{ vars.DIR }}] failed",
+			want:    [][]string{{"creds"}, {"mkdir", "tmp"}, {"build"}, {"rm", "tmp"}, {"timer", "stop"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			load := func(string) (*dsl.Workflow, error) {
+				return &dsl.Workflow{
+					Name:      "hooks",
+					StepHooks: &dsl.StepHooks{Before: [][]string{{"creds"}}, After: [][]string{{"timer", "stop"}}},
+					Stages: []dsl.Stage{{Name: "build", Steps: []dsl.Step{{
+						Name: "build", Type: dsl.StepTypeExec, Run: []string{"build"},
+						Before: [][]string{{"mkdir", "${{ vars.DIR }}"}}, After: [][]string{{"rm", "${{ vars.DIR }}"}},
+					}}}},
+					Vars: map[string]string{"DIR": "tmp"},
+				}, nil
+			}
+			var calls [][]string
+			r, err := NewRunner("test.yaml", WithOut(&bytes.Buffer{}), WithLoadWorkflow(load))
+			if err != nil {
+				t.Fatalf("NewRunner() error: %v", err)
+			}
+			r.RunCmd = func(argv []string) error {
+				calls = append(calls, argv)
+				if argv[0] == tt.fail {
+					return errors.New("exit status 1")
+				}
+				return nil
+			}
+
+			err = r.Run()
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Run() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(calls, tt.want) {
+				t.Errorf("calls = %v, want %v", calls, tt.want)
+			}
+			if got := r.Record().Steps[0].Commands; len(got) != len(tt.want) {
+				t.Errorf("recorded commands = %v, want %d", got, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestRunner_StepHooksDryRun(t *testing.T) {
+	stages := []dsl.Stage{{Name: "build", Steps: []dsl.Step{{
+		Name: "build", Type: dsl.StepTypeExec, Run: []string{"build"},
+		Before: [][]string{{"setup"}}, After: [][]string{{"teardown"}},
+	}}}}
+	var out bytes.Buffer
+	var calls [][]string
+	r, err := NewRunner("test.yaml", WithOut(&out), WithMode(ModeDryRun), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(mockRunCmd(&calls)))
+	if err != nil {
+		t.Fatalf("NewRunner() error: %v", err)
+	}
+
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if len(calls) > 0 {
+		t.Errorf("dry run executed %v", calls)
+	}
+	for _, line := range []string{"Would run before: [setup]", "Would execute command: [build]", "Would run after: [teardown]"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("output missing %q:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/andre-koe/forge/internal/ansi"
+)
+
+// WithOutputTail keeps the last lines of each step's process output in its run record
+func WithOutputTail(lines int) Option {
+	return func(r *Runner) { r.OutputTail = lines }
+}
+
+// WithOutputLimit bounds the process output kept per step to about limit bytes: when a step
+// prints more, only the lines from its first and last limit/2 bytes are kept, with a count of
+// the bytes left out in between. The default is DefaultOutputLimit.
+func WithOutputLimit(limit int) Option {
+	return func(r *Runner) { r.OutputLimit = limit }
+}
+
+// DefaultOutputLimit is the output kept per step when no limit is set
+const DefaultOutputLimit = 64 << 10
+
+// tailWriter keeps the first and last limit bytes written to it and hands out the last max
+// lines, plus the first max lines when the middle had to be left out. Memory stays bounded
+// however much is written.
+type tailWriter struct {
+	mu    sync.Mutex
+	max   int
+	limit int
+	head  []byte
+	tail  []byte
+	total int64
+}
+
+func (w *tailWriter) bytesLimit() int {
+	if w.limit <= 0 {
+		return DefaultOutputLimit / 2
+	}
+	return w.limit
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := len(p)
+	limit := w.bytesLimit()
+	w.total += int64(n)
+	if room := limit - len(w.head); room > 0 {
+		k := min(room, len(p))
+		w.head = append(w.head, p[:k]...)
+		p = p[k:]
+	}
+	if len(p) >= limit {
+		w.tail = append(w.tail[:0], p[len(p)-limit:]...)
+		return n, nil
+	}
+	w.tail = append(w.tail, p...)
+	if len(w.tail) > 2*limit {
+		w.tail = w.tail[:copy(w.tail, w.tail[len(w.tail)-limit:])]
+	}
+	return n, nil
+}
+
+// parts returns the kept head and tail trimmed to whole lines, or the whole output as tail
+// when nothing was left out
+func (w *tailWriter) parts() (head, tail []byte, truncated bool) {
+	tail = w.tail
+	if limit := w.bytesLimit(); len(tail) > limit {
+		tail = tail[len(tail)-limit:]
+	}
+	if w.total == int64(len(w.head)+len(tail)) {
+		return nil, append(bytes.Clone(w.head), tail...), false
+	}
+	head = w.head
+	if i := bytes.LastIndexByte(head, '\n'); i >= 0 {
+		head = head[:i+1]
+	}
+	if i := bytes.IndexByte(tail, '\n'); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return head, tail, true
+}
+
+// Omitted returns the number of bytes left out between Head and Lines
+func (w *tailWriter) Omitted() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	head, tail, truncated := w.parts()
+	if !truncated {
+		return 0
+	}
+	return w.total - int64(len(head)+len(tail))
+}
+
+// Head returns the first kept lines when the middle of the output was left out, nil otherwise
+func (w *tailWriter) Head() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	head, _, _ := w.parts()
+	lines := outputLines(head)
+	if len(lines) > w.max {
+		lines = lines[:w.max]
+	}
+	return lines
+}
+
+// Lines returns the last kept lines, including a final line without newline
+func (w *tailWriter) Lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, tail, _ := w.parts()
+	lines := outputLines(tail)
+	if len(lines) > w.max {
+		lines = lines[len(lines)-w.max:]
+	}
+	return lines
+}
+
+// outputLines splits output into lines without their line endings
+func outputLines(b []byte) []string {
+	s := strings.TrimSuffix(string(b), "\n")
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// withOutputTail runs fn while keeping the head and tail of the step's process output for the run
+// record, without escape sequences
+func (r *Runner) withOutputTail(fn func() error) error {
+	if r.OutputTail <= 0 {
+		return fn()
+	}
+
+	w := &tailWriter{max: r.OutputTail, limit: r.OutputLimit / 2}
+	prevOut, prevErr := r.stdout, r.stderr
+	r.stdout = io.MultiWriter(r.processStdout(), w)
+	r.stderr = io.MultiWriter(r.processStderr(), w)
+	defer func() { r.stdout, r.stderr = prevOut, prevErr }()
+
+	err := fn()
+	if rec := r.currentStepRecord(); rec != nil {
+		rec.OutputHead = ansi.SanitizeLines(w.Head())
+		rec.OutputTail = ansi.SanitizeLines(w.Lines())
+		rec.OutputOmitted = w.Omitted()
+	}
+	return err
+}
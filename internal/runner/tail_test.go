@@ -0,0 +1,110 @@
+package runner
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func TestTailWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   []string
+	}{
+		{name: "fewer lines than max", writes: []string{"a\nb\n"}, want: []string{"a", "b"}},
+		{name: "keeps last lines", writes: []string{"1\n2\n", "3\n4\n"}, want: []string{"2", "3", "4"}},
+		{name: "line split across writes", writes: []string{"he", "llo\r\nwor", "ld"}, want: []string{"hello", "world"}},
+		{name: "unterminated line counts", writes: []string{"1\n2\n3\n4"}, want: []string{"2", "3", "4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &tailWriter{max: 3}
+			for _, s := range tt.writes {
+				if _, err := w.Write([]byte(s)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := w.Lines(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTailWriter_Limit(t *testing.T) {
+	output := "a1\na2\na3\n" + strings.Repeat("x", 1000) + "\nz1\nz2\n"
+	tests := []struct {
+		name  string
+		chunk int
+	}{
+		{name: "one write", chunk: len(output)},
+		{name: "small writes", chunk: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &tailWriter{max: 5, limit: 8}
+			for rest := output; rest != ""; {
+				n := min(tt.chunk, len(rest))
+				if _, err := w.Write([]byte(rest[:n])); err != nil {
+					t.Fatal(err)
+				}
+				rest = rest[n:]
+			}
+			if got, want := w.Head(), []string{"a1", "a2"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Head() = %q, want %q", got, want)
+			}
+			if got, want := w.Lines(), []string{"z1", "z2"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Lines() = %q, want %q", got, want)
+			}
+			if got, want := w.Omitted(), int64(len(output)-12); got != want {
+				t.Errorf("Omitted() = %d, want %d", got, want)
+			}
+			if len(w.tail) > 2*w.limit {
+				t.Errorf("kept %d bytes of tail, want at most %d", len(w.tail), 2*w.limit)
+			}
+		})
+	}
+
+	w := &tailWriter{max: 5, limit: 8}
+	w.Write([]byte("short\n"))
+	if w.Head() != nil || w.Omitted() != 0 {
+		t.Errorf("Head() = %q, Omitted() = %d for output within the limit", w.Head(), w.Omitted())
+	}
+}
+
+func TestRunner_OutputTail(t *testing.T) {
+	stages := []dsl.Stage{
+		{Name: "build", Steps: []dsl.Step{
+			{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"sh", "-c", `echo one; echo two; printf '\033[32mthree\033[0m\n'`}},
+		}},
+	}
+
+	var finished *history.Record
+	var out bytes.Buffer
+	r, err := NewRunner("test.yaml",
+		WithOut(&out),
+		WithLoadWorkflow(mockLoadWorkflow(stages)),
+		WithProcessOutput(&out, &out),
+		WithOutputTail(2),
+		WithOnFinish(func(rec *history.Record) { finished = rec }),
+	)
+	if err != nil {
+		t.Fatalf("NewRunner() failed: %v", err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	if finished == nil || finished.Status != history.StatusSuccess {
+		t.Fatalf("OnFinish record = %+v, want a successful run", finished)
+	}
+	if got, want := finished.Steps[0].OutputTail, []string{"two", "three"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OutputTail = %q, want %q", got, want)
+	}
+}
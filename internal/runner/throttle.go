@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// throttle waits until the stage's throttle lets its next step start; starts holds the start
+// times of the steps of the stage so far
+func (r *Runner) throttle(stage *dsl.Stage, starts *[]time.Time) error {
+	if stage.Throttle == nil {
+		return nil
+	}
+	if n := len(*starts); n > 0 {
+		steps, per, jitter := stage.Throttle.Limit()
+		var wait time.Duration
+		if per > 0 && n >= steps {
+			wait = max((*starts)[n-steps].Add(per).Sub(r.Clock.Now()), 0)
+		}
+		wait += time.Duration(r.Random() * float64(jitter))
+		if wait > 0 {
+			fmt.Fprintf(r.Out, "Throttling: waiting %s before the next step\n", wait.Round(time.Millisecond))
+			if err := r.sleep(wait); err != nil {
+				return err
+			}
+		}
+	}
+	*starts = append(*starts, r.Clock.Now())
+	return nil
+}
+
+func (r *Runner) dryRunThrottle(t *dsl.Throttle) {
+	if t == nil {
+		return
+	}
+	steps, per, jitter := t.Limit()
+	if per > 0 {
+		fmt.Fprintf(r.Out, "[DRY-RUN] Would start at most %d step(s) per %s\n", steps, per)
+	}
+	if jitter > 0 {
+		fmt.Fprintf(r.Out, "[DRY-RUN] Would wait a random delay of up to %s before every step but the first\n", jitter)
+	}
+}
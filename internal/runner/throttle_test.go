@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/clock"
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_Throttle(t *testing.T) {
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	stages := []dsl.Stage{{Name: "deploy", Throttle: &dsl.Throttle{Steps: 2, Per: "10s", Jitter: "4s"}, Steps: []dsl.Step{
+		{Name: "one", Type: dsl.StepTypeExec, Run: []string{"one"}},
+		{Name: "two", Type: dsl.StepTypeExec, Run: []string{"two"}},
+		{Name: "three", Type: dsl.StepTypeExec, Run: []string{"three"}},
+	}}}
+	fake := clock.NewFake(start)
+	var out bytes.Buffer
+	var calls [][]string
+	r, err := NewRunner("test.yaml", WithOut(&out), WithClock(fake), WithRandom(func() float64 { return 0.5 }),
+		WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(mockRunCmd(&calls)))
+	if err != nil {
+		t.Fatalf("NewRunner() error: %v", err)
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- r.Run() }()
+	// The second step only waits for its jitter, the third until 10s after the first started
+	fake.BlockUntil(1)
+	fake.Advance(2 * time.Second)
+	fake.BlockUntil(1)
+	fake.Advance(10 * time.Second)
+	if err := <-errc; err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if want := [][]string{{"one"}, {"two"}, {"three"}}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	for _, want := range []string{"Throttling: waiting 2s before the next step", "Throttling: waiting 10s before the next step"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if got := fake.Now().Sub(start); got != 12*time.Second {
+		t.Errorf("run took %s, want 12s", got)
+	}
+}
+
+func TestRunner_ThrottleDryRun(t *testing.T) {
+	stages := []dsl.Stage{{Name: "deploy", Throttle: &dsl.Throttle{Per: "10s", Jitter: "2s"}, Steps: []dsl.Step{
+		{Name: "one", Type: dsl.StepTypeExec, Run: []string{"one"}},
+	}}}
+	var out bytes.Buffer
+	r, err := NewRunner("test.yaml", WithOut(&out), WithMode(ModeDryRun), WithLoadWorkflow(mockLoadWorkflow(stages)))
+	if err != nil {
+		t.Fatalf("NewRunner() error: %v", err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	for _, want := range []string{"Would start at most 1 step(s) per 10s", "random delay of up to 2s"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// OSC 9;4 progress states
+const (
+	progressClear  = 0
+	progressNormal = 1
+	progressError  = 2
+)
+
+// WithTerminalStatus writes the current stage, step and progress to w as terminal title
+// and OSC 9;4 progress escape sequences; w should be a terminal
+func WithTerminalStatus(w io.Writer) Option {
+	return func(r *Runner) { r.TerminalStatus = w }
+}
+
+// countSteps returns the number of steps the runner will execute for wf
+func (r *Runner) countSteps(wf *dsl.Workflow) int {
+	n := 0
+	for _, stage := range wf.Stages {
+		if r.stageSelected(stage.Name) {
+			n += len(stage.Steps)
+		}
+	}
+	return n
+}
+
+// updateTerminalStatus shows which step is running and how many of the run's steps have finished
+func (r *Runner) updateTerminalStatus(stage, step string, done int) {
+	if r.TerminalStatus == nil {
+		return
+	}
+	pct := 0
+	if r.totalSteps > 0 {
+		pct = done * 100 / r.totalSteps
+	}
+	title := fmt.Sprintf("forge: %s › %s › %s (%d%%)", r.wf.Name, stage, step, pct)
+	fmt.Fprintf(r.TerminalStatus, "\x1b]0;%s\x07\x1b]9;4;%d;%d\x07", sanitizeTitle(title), progressNormal, pct)
+}
+
+// finishTerminalStatus leaves a final title and clears or flags the progress indicator
+func (r *Runner) finishTerminalStatus(err error) {
+	if r.TerminalStatus == nil {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(r.TerminalStatus, "\x1b]0;%s\x07\x1b]9;4;%d;100\x07", sanitizeTitle("forge: "+r.wf.Name+" failed"), progressError)
+		return
+	}
+	fmt.Fprintf(r.TerminalStatus, "\x1b]0;%s\x07\x1b]9;4;%d;0\x07", sanitizeTitle("forge: "+r.wf.Name+" done"), progressClear)
+}
+
+// sanitizeTitle drops control characters that would end or corrupt the escape sequence
+func sanitizeTitle(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_TerminalStatus(t *testing.T) {
+	stages := []dsl.Stage{
+		{Name: "build", Steps: []dsl.Step{
+			{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"make"}},
+			{Name: "vet", Type: dsl.StepTypeExec, Run: []string{"vet"}},
+		}},
+		{Name: "test", Steps: []dsl.Step{
+			{Name: "unit", Type: dsl.StepTypeExec, Run: []string{"go", "test"}},
+			{Name: "e2e", Type: dsl.StepTypeExec, Run: []string{"e2e"}},
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		runCmd  func([]string) error
+		wantErr bool
+		want    []string
+	}{
+		{
+			name:   "success",
+			runCmd: func([]string) error { return nil },
+			want: []string{
+				"\x1b]0;forge: mock-workflow › build › compile (0%)\x07\x1b]9;4;1;0\x07",
+				"\x1b]0;forge: mock-workflow › test › unit (50%)\x07\x1b]9;4;1;50\x07",
+				"\x1b]0;forge: mock-workflow › test › e2e (75%)\x07",
+				"\x1b]0;forge: mock-workflow done\x07\x1b]9;4;0;0\x07",
+			},
+		},
+		{
+			name: "failure",
+			runCmd: func(argv []string) error {
+				if argv[0] == "vet" {
+					return errors.New("vet failed")
+				}
+				return nil
+			},
+			wantErr: true,
+			want: []string{
+				"build › vet (25%)",
+				"\x1b]0;forge: mock-workflow failed\x07\x1b]9;4;2;100\x07",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := new(bytes.Buffer)
+			r, err := NewRunner("test.yaml",
+				WithOut(new(bytes.Buffer)),
+				WithLoadWorkflow(mockLoadWorkflow(stages)),
+				WithRunCmd(tt.runCmd),
+				WithTerminalStatus(status),
+			)
+			if err != nil {
+				t.Fatalf("NewRunner() failed: %v", err)
+			}
+			if err := r.Run(); (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(status.String(), want) {
+					t.Errorf("terminal status missing %q in %q", want, status.String())
+				}
+			}
+		})
+	}
+}
+
+func TestSanitizeTitle(t *testing.T) {
+	if got := sanitizeTitle("build\x07\x1b]0;evil\n"); got != "build]0;evil" {
+		t.Errorf("sanitizeTitle() = %q, want %q", got, "build]0;evil")
+	}
+}
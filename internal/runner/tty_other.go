@@ -0,0 +1,14 @@
+//go:build !unix
+
+package runner
+
+import (
+	"errors"
+	"io"
+	"os/exec"
+)
+
+// runInTerminal fails; pseudo-terminals are only supported on Unix-like systems
+func runInTerminal(cmd *exec.Cmd, stdin io.Reader, stdout io.Writer) error {
+	return errors.New("tty: pseudo-terminals are not supported on this platform")
+}
@@ -0,0 +1,64 @@
+//go:build unix
+
+package runner
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+	"golang.org/x/term"
+)
+
+// runInTerminal runs cmd in a new session whose controlling terminal is a pseudo-terminal.
+// Everything the command writes to the terminal, its stderr included, is copied to stdout.
+// When stdin is forge's terminal it is switched to raw mode and follows the window size, so
+// keys, passwords and escape sequences reach the command unchanged.
+func runInTerminal(cmd *exec.Cmd, stdin io.Reader, stdout io.Writer) error {
+	// The session leader leads its process group too, which is signalled on cancel
+	stopGroupOnCancel(cmd)
+	attrs := &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	if cmd.SysProcAttr != nil {
+		attrs.Credential = cmd.SysProcAttr.Credential
+	}
+	ptmx, err := pty.StartWithAttrs(cmd, nil, attrs)
+	if err != nil {
+		return err
+	}
+	defer ptmx.Close()
+
+	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		_ = pty.InheritSize(f, ptmx)
+		resize := make(chan os.Signal, 1)
+		signal.Notify(resize, syscall.SIGWINCH)
+		defer signal.Stop(resize)
+		go func() {
+			for range resize {
+				_ = pty.InheritSize(f, ptmx)
+			}
+		}()
+		if state, err := term.MakeRaw(int(f.Fd())); err == nil {
+			defer func() { _ = term.Restore(int(f.Fd()), state) }()
+		}
+	}
+	// Reading stdin blocks until the next key, which the copy then writes to the closed terminal
+	go func() { _, _ = io.Copy(ptmx, stdin) }()
+
+	copied := make(chan struct{})
+	go func() {
+		// Reading fails with EIO once the command and its children closed the terminal
+		_, _ = io.Copy(stdout, ptmx)
+		close(copied)
+	}()
+	err = cmd.Wait()
+	select {
+	case <-copied:
+	case <-time.After(time.Second):
+		// A background process still holds the terminal; its output is not waited for
+	}
+	return err
+}
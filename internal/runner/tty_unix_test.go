@@ -0,0 +1,62 @@
+//go:build unix
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_Run_TTY(t *testing.T) {
+	script := []string{"sh", "-c", "if test -t 0 && test -t 1; then echo terminal; else echo pipe; fi; echo oops >&2"}
+
+	tests := []struct {
+		name        string
+		tty         bool
+		interactive bool
+		want        string
+	}{
+		{name: "pipes by default", want: "pipe"},
+		{name: "tty step", tty: true, want: "terminal"},
+		{name: "interactive run", interactive: true, want: "terminal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			stages := []dsl.Stage{{Name: "s", Steps: []dsl.Step{{Name: "check", Type: dsl.StepTypeExec, Run: script, TTY: tt.tty}}}}
+			r, err := NewRunner("test.yaml",
+				WithLoadWorkflow(mockLoadWorkflow(stages)),
+				WithOut(&out),
+				WithStdin(strings.NewReader("")),
+				WithInteractive(tt.interactive),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Run(); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			if !strings.Contains(out.String(), "[s/check] "+tt.want) {
+				t.Errorf("output = %q, want the command to see a %s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCommand_TTYCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	start := time.Now()
+	err := runCommand(ctx, []string{"sh", "-c", "sleep 30 & sleep 30"}, procAttr{Stdin: strings.NewReader(""), Stdout: &bytes.Buffer{}, execAttr: execAttr{TTY: true}})
+	if err == nil {
+		t.Fatal("runCommand() error = nil, want the command to be terminated")
+	}
+	if elapsed := time.Since(start); elapsed >= killGrace {
+		t.Errorf("runCommand() took %s after cancellation, want the session to stop on SIGTERM", elapsed)
+	}
+}
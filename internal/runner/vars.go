@@ -0,0 +1,38 @@
+package runner
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// WithVars overrides the values of variables the workflow declares in vars
+func WithVars(vars map[string]string) Option {
+	return func(r *Runner) { r.Vars = vars }
+}
+
+// resolveVars merges the selected profile's values and then the runner's overrides into the
+// workflow's variable defaults
+func (r *Runner) resolveVars(wf *dsl.Workflow) error {
+	r.vars = maps.Clone(wf.Vars)
+	p, err := r.profile(wf)
+	if err != nil {
+		return err
+	}
+	if p != nil {
+		// Validation ensures the profile only sets declared variables
+		if r.vars == nil {
+			r.vars = make(map[string]string)
+		}
+		maps.Copy(r.vars, p.Vars)
+	}
+	for _, name := range slices.Sorted(maps.Keys(r.Vars)) {
+		if _, ok := wf.Vars[name]; !ok {
+			return fmt.Errorf("unknown variable %q: the workflow does not declare it in vars", name)
+		}
+		r.vars[name] = r.Vars[name]
+	}
+	return nil
+}
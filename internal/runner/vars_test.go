@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_Vars(t *testing.T) {
+	load := func(string) (*dsl.Workflow, error) {
+		return &dsl.Workflow{Name: "vars", Vars: map[string]string{"image": "app", "tag": "latest"}, Stages: []dsl.Stage{
+			{Name: "build", Steps: []dsl.Step{
+				{Name: "docker", Type: dsl.StepTypeExec, Run: []string{"docker", "build", "-t", "${{ vars.image }}:${{ vars.tag }}"}},
+			}},
+		}}, nil
+	}
+
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		want      [][]string
+		wantErr   bool
+	}{
+		{
+			name: "defaults",
+			want: [][]string{{"docker", "build", "-t", "app:latest"}},
+		},
+		{
+			name:      "override",
+			overrides: map[string]string{"tag": "v1.2.3"},
+			want:      [][]string{{"docker", "build", "-t", "app:v1.2.3"}},
+		},
+		{
+			name:      "undeclared override",
+			overrides: map[string]string{"tga": "v1"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls [][]string
+			r, err := NewRunner("test.yaml",
+				WithOut(new(bytes.Buffer)),
+				WithLoadWorkflow(load),
+				WithRunCmd(mockRunCmd(&calls)),
+				WithVars(tt.overrides),
+			)
+			if err != nil {
+				t.Fatalf("NewRunner() failed: %v", err)
+			}
+			err = r.Run()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(calls, tt.want) {
+				t.Errorf("commands = %v, want %v", calls, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// ErrWarnings is returned for workflows with warnings when StrictWarnings is set
+var ErrWarnings = errors.New("workflow has warnings")
+
+// WithStrictWarnings fails runs of workflows with warnings, see dsl.Workflow.Warnings
+func WithStrictWarnings(strict bool) Option {
+	return func(r *Runner) { r.StrictWarnings = strict }
+}
+
+// checkWarnings fails the run on warnings of the workflow with StrictWarnings
+func (r *Runner) checkWarnings(wf *dsl.Workflow) error {
+	if warnings := wf.Warnings(); r.StrictWarnings && len(warnings) > 0 {
+		return fmt.Errorf("%w: %s", ErrWarnings, strings.Join(warnings, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_Warnings(t *testing.T) {
+	stages := []dsl.Stage{{Name: "deploy", Steps: []dsl.Step{
+		{Name: "soak", Type: dsl.StepTypeSleep, Seconds: 7200},
+	}}}
+	tests := []struct {
+		name    string
+		mode    ExecutionMode
+		strict  bool
+		wantOut string
+		wantErr bool
+	}{
+		{name: "dry run prints", mode: ModeDryRun, wantOut: "[DRY-RUN] Warning: step deploy.soak sleeps for 2h0m0s\n"},
+		{name: "dry run strict", mode: ModeDryRun, strict: true, wantErr: true},
+		{name: "run strict", mode: ModeExecute, strict: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			var calls [][]string
+			r, err := NewRunner("test.yaml", WithOut(&out), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(mockRunCmd(&calls)), WithMode(tt.mode), WithStrictWarnings(tt.strict))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = r.Run()
+			if tt.wantErr {
+				if !errors.Is(err, ErrWarnings) || !strings.Contains(err.Error(), "sleeps for 2h0m0s") {
+					t.Fatalf("Run() error = %v, want %v", err, ErrWarnings)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output missing %q:\n%s", tt.wantOut, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/window"
+)
+
+// ErrOutsideWindow is returned for stages due outside their schedule window with outside_window: fail
+var ErrOutsideWindow = errors.New("outside the schedule window")
+
+// scheduleWindow reports whether a stage may run now according to its schedule window and
+// outside_window policy, waiting for the window to open with WindowWait; done counts the steps of
+// a skipped stage
+func (r *Runner) scheduleWindow(stageIdx int, stage *dsl.Stage, done *int) (bool, error) {
+	if stage.ScheduleWindow == "" {
+		return true, nil
+	}
+	w, err := window.Parse(stage.ScheduleWindow)
+	if err != nil {
+		return false, fmt.Errorf("stage '%s': %w", stage.Name, err)
+	}
+	loc, err := r.location(r.wf)
+	if err != nil {
+		return false, err
+	}
+	now := r.Clock.Now().In(loc)
+	if w.Contains(now) {
+		return true, nil
+	}
+
+	switch stage.OutsideWindow {
+	case dsl.WindowWait:
+		next := w.Next(now)
+		fmt.Fprintf(r.Out, "\nStage '%s' waits until %s for its schedule window %s\n", stage.Name, next.Format(timestampLayout), w)
+		if err := r.sleep(next.Sub(now)); err != nil {
+			return false, fmt.Errorf("stage '%s': %w", stage.Name, ErrCancelled)
+		}
+		return true, nil
+	case dsl.WindowSkip:
+		fmt.Fprintf(r.Out, "\n=== STAGE %d: %s (skipped, outside schedule window %s) ===\n", stageIdx+1, stage.Name, w)
+		stagePath := JoinPath(r.rootPath, stage.Name)
+		for _, step := range stage.Steps {
+			r.skipStep(stage.Name, stagePath, &step, history.StatusSkipped)
+			*done++
+		}
+		return false, nil
+	default:
+		fmt.Fprintf(r.Out, "\n=== STAGE %d: %s (outside schedule window %s) ===\n", stageIdx+1, stage.Name, w)
+		return false, fmt.Errorf("stage '%s': %w %s", stage.Name, ErrOutsideWindow, w)
+	}
+}
+
+func (r *Runner) dryRunWindow(ps *PlanStage) {
+	if ps.ScheduleWindow == "" {
+		return
+	}
+	policy := ps.OutsideWindow
+	if policy == "" {
+		policy = dsl.WindowFail
+	}
+	fmt.Fprintf(r.Out, "[DRY-RUN] Would run only within %s, otherwise %s\n", ps.ScheduleWindow, policy)
+}
@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/clock"
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/history"
+)
+
+func TestRunner_ScheduleWindow(t *testing.T) {
+	// A Saturday night, outside the window
+	saturday := time.Date(2025, 1, 11, 3, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		policy    dsl.WindowPolicy
+		wantErr   error
+		wantCalls [][]string
+		wantOut   string
+	}{
+		{name: "fail by default", wantErr: ErrOutsideWindow, wantCalls: [][]string{{"build"}}, wantOut: "STAGE 2: deploy (outside schedule window Mon-Fri 09:00-17:00 UTC)"},
+		{name: "skip", policy: dsl.WindowSkip, wantCalls: [][]string{{"build"}, {"notify"}}, wantOut: "STAGE 2: deploy (skipped, outside schedule window Mon-Fri 09:00-17:00 UTC)"},
+		{name: "wait", policy: dsl.WindowWait, wantCalls: [][]string{{"build"}, {"deploy"}, {"notify"}}, wantOut: "Stage 'deploy' waits until 2025-01-13"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stages := []dsl.Stage{
+				{Name: "build", Steps: []dsl.Step{{Name: "build", Type: dsl.StepTypeExec, Run: []string{"build"}}}},
+				{Name: "deploy", ScheduleWindow: "Mon-Fri 09:00-17:00 UTC", OutsideWindow: tt.policy, Steps: []dsl.Step{
+					{Name: "deploy", ID: "deploy", Type: dsl.StepTypeExec, Run: []string{"deploy"}},
+				}},
+				{Name: "notify", Steps: []dsl.Step{{Name: "notify", Type: dsl.StepTypeExec, Run: []string{"notify"}}}},
+			}
+			fake := clock.NewFake(saturday)
+			var out bytes.Buffer
+			var calls [][]string
+			r, err := NewRunner("test.yaml", WithOut(&out), WithClock(fake), WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(mockRunCmd(&calls)))
+			if err != nil {
+				t.Fatalf("NewRunner() error: %v", err)
+			}
+
+			errc := make(chan error, 1)
+			go func() { errc <- r.Run() }()
+			if tt.policy == dsl.WindowWait {
+				fake.BlockUntil(1)
+				fake.Advance(54 * time.Hour)
+			}
+			err = <-errc
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output missing %q:\n%s", tt.wantOut, out.String())
+			}
+			if tt.policy == dsl.WindowSkip {
+				if status := r.Record().Steps[1].Status; status != history.StatusSkipped {
+					t.Errorf("deploy status = %s, want %s", status, history.StatusSkipped)
+				}
+			}
+		})
+	}
+}
+
+func TestRunner_ScheduleWindow_Timezone(t *testing.T) {
+	// Saturday 03:00 UTC is 13:00 in the runner's zone
+	saturday := time.Date(2025, 1, 11, 3, 0, 0, 0, time.UTC)
+	stages := []dsl.Stage{{Name: "deploy", ScheduleWindow: "Sat 09:00-17:00", Steps: []dsl.Step{
+		{Name: "deploy", Type: dsl.StepTypeExec, Run: []string{"deploy"}},
+	}}}
+	var calls [][]string
+	r, err := NewRunner("test.yaml", WithOut(&bytes.Buffer{}), WithClock(clock.NewFake(saturday)), WithTimezone(time.FixedZone("AEST", 10*60*60)),
+		WithLoadWorkflow(mockLoadWorkflow(stages)), WithRunCmd(mockRunCmd(&calls)))
+	if err != nil {
+		t.Fatalf("NewRunner() error: %v", err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if !reflect.DeepEqual(calls, [][]string{{"deploy"}}) {
+		t.Errorf("calls = %v, want deploy to run inside the window in the runner's zone", calls)
+	}
+}
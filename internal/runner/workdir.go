@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"cmp"
+	"os"
+	"path/filepath"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+// stepDir returns the directory the processes of a step start in: the workdir of the step, its
+// stage or the workflow (or its defaults), relative to the workflow file, and the runner's
+// Workdir if none is set.
+// stage is nil for workflow hooks.
+func (r *Runner) stepDir(wf *dsl.Workflow, stage *dsl.Stage, step *dsl.Step) string {
+	dir := workdirOf(wf, stage, step)
+	if dir == "" {
+		return r.Workdir
+	}
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(r.workflowDir(), dir)
+}
+
+// stageDir returns the directory of a stage's steps without a workdir of their own, where its
+// artifacts are collected and restored
+func (r *Runner) stageDir(stage *dsl.Stage) string {
+	return r.stepDir(r.wf, stage, &dsl.Step{})
+}
+
+// workdirOf returns the workdir set for a step: its own, its stage's or the workflow's
+func workdirOf(wf *dsl.Workflow, stage *dsl.Stage, step *dsl.Step) string {
+	dir := step.Workdir
+	if dir == "" && stage != nil {
+		dir = stage.Workdir
+	}
+	if dir == "" && wf != nil {
+		dir = wf.Workdir
+		if dir == "" && wf.Defaults != nil {
+			dir = wf.Defaults.Workdir
+		}
+	}
+	return dir
+}
+
+// workflowDir returns the directory of the workflow file, the working directory for workflows
+// read from stdin or a URL
+func (r *Runner) workflowDir() string {
+	if fi, err := os.Stat(r.path); err == nil && fi.Mode().IsRegular() {
+		return filepath.Dir(r.path)
+	}
+	return "."
+}
+
+// withStepDir runs fn with the processes of the named stage's step starting in its directory
+func (r *Runner) withStepDir(stage string, step *dsl.Step, fn func() error) error {
+	prev, prevRemote := r.dir, r.remote
+	s := r.findStage(stage)
+	r.dir = r.stepDir(r.wf, s, step)
+	r.remote = r.remoteOf(s, step)
+	defer func() { r.dir, r.remote = prev, prevRemote }()
+	return fn()
+}
+
+// processDir returns the directory processes of the current step start in
+func (r *Runner) processDir() string {
+	return cmp.Or(r.dir, r.Workdir)
+}
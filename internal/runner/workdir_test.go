@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/dsl"
+)
+
+func TestRunner_StepDir(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "forge.yaml")
+	if err := os.WriteFile(path, []byte("name: x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	abs := filepath.Join(dir, "abs")
+
+	tests := []struct {
+		name     string
+		path     string
+		override string
+		wf       string
+		stage    string
+		step     string
+		want     string
+	}{
+		{name: "none", path: path},
+		{name: "runner override", path: path, override: "/srv", want: "/srv"},
+		{name: "workflow", path: path, override: "/srv", wf: "app", want: filepath.Join(dir, "app")},
+		{name: "stage wins", path: path, wf: "app", stage: "api", want: filepath.Join(dir, "api")},
+		{name: "step wins", path: path, wf: "app", stage: "api", step: "../web", want: filepath.Join(filepath.Dir(dir), "web")},
+		{name: "absolute", path: path, step: abs, want: abs},
+		{name: "workflow from stdin", path: "-", step: "app", want: "app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Runner{path: tt.path, Workdir: tt.override}
+			wf := &dsl.Workflow{Workdir: tt.wf}
+			got := r.stepDir(wf, &dsl.Stage{Workdir: tt.stage}, &dsl.Step{Workdir: tt.step})
+			if got != tt.want {
+				t.Errorf("stepDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunner_Run_Workdir(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"services/api", "web"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "forge.yaml")
+	if err := os.WriteFile(path, []byte("name: x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pwd := []string{"sh", "-c", `basename "$PWD"`}
+	wf := &dsl.Workflow{Name: "dirs", Workdir: "web", Stages: []dsl.Stage{
+		{Name: "api", Workdir: "services/api", Steps: []dsl.Step{
+			{Name: "stage-dir", Type: dsl.StepTypeExec, Run: pwd},
+			{Name: "step-dir", Type: dsl.StepTypeExec, Run: pwd, Workdir: "services"},
+		}, Always: []dsl.Step{{Name: "hook", Type: dsl.StepTypeExec, Run: pwd}}},
+		{Name: "web", Steps: []dsl.Step{{Name: "workflow-dir", Type: dsl.StepTypeExec, Run: pwd}}},
+	}}
+
+	var out bytes.Buffer
+	r, err := NewRunner(path,
+		WithOut(new(bytes.Buffer)),
+		WithProcessOutput(&out, &out),
+		WithLoadWorkflow(func(string) (*dsl.Workflow, error) { return wf, nil }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if want := "api\nservices\napi\nweb\n"; out.String() != want {
+		t.Errorf("directories = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,412 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+const (
+	// agentTimeout is how long an agent may go without asking for work, or reporting on the stage
+	// it runs, before the server considers it gone
+	agentTimeout = time.Minute
+	// agentCheck is how often a run waiting for an agent checks that the agent is still there
+	agentCheck = 5 * time.Second
+	// maxAgentWait bounds how long GET /agents/{id}/assignment waits for a stage to run
+	maxAgentWait = 30 * time.Second
+)
+
+var (
+	// ErrAgentNotFound is returned for ids of agents or assignments the server does not know
+	ErrAgentNotFound = errors.New("agent not found")
+	// ErrNoAgent is returned when a stage is sent to agents while none with its labels joined
+	ErrNoAgent = errors.New("no agent joined")
+)
+
+// Agent is a `forge agent` that joined the server to run the stages sent to it
+type Agent struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Labels   []string  `json:"labels"`
+	JoinedAt time.Time `json:"joined_at"`
+	LastSeen time.Time `json:"last_seen"`
+	// Assignment is the ID of the assignment the agent runs, empty while it is idle
+	Assignment string `json:"assignment,omitempty"`
+}
+
+// AgentRequest is the body of POST /agents
+type AgentRequest struct {
+	Name string `json:"name"`
+	// Labels select the stages the agent runs, see dsl.RunsOn
+	Labels []string `json:"labels"`
+}
+
+// Assignment is a stage of a run sent to an agent
+type Assignment struct {
+	ID    string `json:"id"`
+	RunID string `json:"run_id"`
+	// Workflow is the path of the workflow file relative to the server's directory, and the agent's
+	Workflow string            `json:"workflow"`
+	Stage    string            `json:"stage"`
+	Vars     map[string]string `json:"vars,omitempty"`
+	Profile  string            `json:"profile,omitempty"`
+
+	labels []string
+	agent  string
+	result chan error
+
+	// mu guards out, the output of the run, which is nil once the run stopped waiting
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// AssignmentResult is the body of POST /agents/{id}/assignments/{assignment}/result
+type AssignmentResult struct {
+	// Error is why the stage failed, empty if it succeeded
+	Error string `json:"error,omitempty"`
+}
+
+// Write copies the output of the agent to the run, until the run stopped waiting for it
+func (a *Assignment) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.out == nil {
+		return len(p), nil
+	}
+	return a.out.Write(p)
+}
+
+// agents holds the agents that joined the server and the stages waiting for one
+type agents struct {
+	byID        map[string]*Agent
+	pending     []*Assignment
+	assignments map[string]*Assignment
+	// changed is closed and replaced whenever agents join or stages wait for one
+	changed chan struct{}
+}
+
+// wake tells agents waiting for a stage that one may be there; s.mu must be held
+func (s *Server) wake() {
+	close(s.agents.changed)
+	s.agents.changed = make(chan struct{})
+}
+
+// Join registers an agent
+func (s *Server) Join(req AgentRequest) (*Agent, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, errors.New("agent name is required")
+	}
+	now := time.Now()
+	agent := &Agent{ID: history.NewID(now), Name: req.Name, Labels: req.Labels, JoinedAt: now, LastSeen: now}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agents.byID[agent.ID] = agent
+	s.wake()
+	c := *agent
+	return &c, nil
+}
+
+// Leave unregisters an agent; the stage it runs fails
+func (s *Server) Leave(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	agent, ok := s.agents.byID[id]
+	if ok {
+		s.dropAgent(agent, fmt.Errorf("agent %s left", agent.Name))
+	}
+	return ok
+}
+
+// Agents returns copies of the agents that joined the server, oldest first
+func (s *Server) Agents() []*Agent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneAgents()
+	agents := make([]*Agent, 0, len(s.agents.byID))
+	for _, agent := range s.agents.byID {
+		c := *agent
+		agents = append(agents, &c)
+	}
+	slices.SortFunc(agents, func(a, b *Agent) int { return a.JoinedAt.Compare(b.JoinedAt) })
+	return agents
+}
+
+// dropAgent removes agent and fails the stage it runs with err; s.mu must be held
+func (s *Server) dropAgent(agent *Agent, err error) {
+	delete(s.agents.byID, agent.ID)
+	if a, ok := s.agents.assignments[agent.Assignment]; ok {
+		select {
+		case a.result <- err:
+		default:
+		}
+	}
+}
+
+// pruneAgents drops the agents that were not seen for agentTimeout; s.mu must be held
+func (s *Server) pruneAgents() {
+	for _, agent := range s.agents.byID {
+		if time.Since(agent.LastSeen) > agentTimeout {
+			s.dropAgent(agent, fmt.Errorf("agent %s stopped responding", agent.Name))
+		}
+	}
+}
+
+// dispatchStage sends a stage of a run to an agent with its labels and waits until it ran there
+func (s *Server) dispatchStage(ctx context.Context, req runner.StageRequest, out io.Writer) error {
+	workflow, err := filepath.Rel(s.Root, req.Workflow)
+	if err != nil {
+		return err
+	}
+	a := &Assignment{
+		ID:       history.NewID(time.Now()),
+		RunID:    req.RunID,
+		Workflow: filepath.ToSlash(workflow),
+		Stage:    req.Stage,
+		Vars:     req.Vars,
+		Profile:  req.Profile,
+		labels:   req.Labels,
+		result:   make(chan error, 1),
+		out:      out,
+	}
+
+	s.mu.Lock()
+	s.pruneAgents()
+	if !s.hasAgent(a.labels) {
+		s.mu.Unlock()
+		return fmt.Errorf("%w with %s", ErrNoAgent, strings.Join(a.labels, ", "))
+	}
+	s.agents.pending = append(s.agents.pending, a)
+	s.agents.assignments[a.ID] = a
+	s.wake()
+	s.mu.Unlock()
+	fmt.Fprintf(a, "Waiting for an agent with %s\n", strings.Join(a.labels, ", "))
+
+	defer func() {
+		s.mu.Lock()
+		s.agents.pending = slices.DeleteFunc(s.agents.pending, func(p *Assignment) bool { return p == a })
+		delete(s.agents.assignments, a.ID)
+		if agent, ok := s.agents.byID[a.agent]; ok && agent.Assignment == a.ID {
+			agent.Assignment = ""
+			s.wake()
+		}
+		s.mu.Unlock()
+		a.mu.Lock()
+		a.out = nil
+		a.mu.Unlock()
+	}()
+
+	check := time.NewTicker(agentCheck)
+	defer check.Stop()
+	for {
+		select {
+		case err := <-a.result:
+			return err
+		case <-ctx.Done():
+			// The agent stops the stage once it finds the assignment gone
+			return ctx.Err()
+		case <-check.C:
+			s.mu.Lock()
+			s.pruneAgents()
+			gone := a.agent == "" && !s.hasAgent(a.labels)
+			s.mu.Unlock()
+			if gone {
+				return fmt.Errorf("%w with %s", ErrNoAgent, strings.Join(a.labels, ", "))
+			}
+		}
+	}
+}
+
+// hasLabels reports whether agent has all labels
+func hasLabels(agent *Agent, labels []string) bool {
+	for _, label := range labels {
+		if !slices.Contains(agent.Labels, label) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasAgent reports whether an agent with all labels joined; s.mu must be held
+func (s *Server) hasAgent(labels []string) bool {
+	for _, agent := range s.agents.byID {
+		if hasLabels(agent, labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextAssignment waits up to wait for a stage the agent with the given ID can run and assigns it
+// to the agent; it returns nil when none came up
+func (s *Server) NextAssignment(ctx context.Context, id string, wait time.Duration) (*Assignment, error) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		agent, ok := s.agents.byID[id]
+		if !ok {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, id)
+		}
+		agent.LastSeen = time.Now()
+		i := slices.IndexFunc(s.agents.pending, func(a *Assignment) bool { return hasLabels(agent, a.labels) })
+		if agent.Assignment == "" && i >= 0 {
+			a := s.agents.pending[i]
+			s.agents.pending = slices.Delete(s.agents.pending, i, i+1)
+			a.agent, agent.Assignment = agent.ID, a.ID
+			s.mu.Unlock()
+			fmt.Fprintf(a, "Running stage '%s' on agent %s\n", a.Stage, agent.Name)
+			return a, nil
+		}
+		changed := s.agents.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, nil
+		}
+	}
+}
+
+// assignment returns the assignment with the given ID of the agent with the given ID and marks
+// the agent as seen
+func (s *Server) assignment(agentID, id string) (*Assignment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	agent, ok := s.agents.byID[agentID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	agent.LastSeen = time.Now()
+	a, ok := s.agents.assignments[id]
+	if !ok || a.agent != agentID {
+		return nil, fmt.Errorf("%w: no assignment %s", ErrAgentNotFound, id)
+	}
+	return a, nil
+}
+
+// Finish reports the outcome of an assignment to the run that sent it; the agent is free for
+// the next stage
+func (s *Server) Finish(agentID, id string, result AssignmentResult) error {
+	a, err := s.assignment(agentID, id)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if agent, ok := s.agents.byID[agentID]; ok && agent.Assignment == id {
+		agent.Assignment = ""
+		s.wake()
+	}
+	s.mu.Unlock()
+	var stageErr error
+	if result.Error != "" {
+		stageErr = fmt.Errorf("on agent: %s", result.Error)
+	}
+	select {
+	case a.result <- stageErr:
+	default:
+	}
+	return nil
+}
+
+func (s *Server) handleJoin(w http.ResponseWriter, req *http.Request) {
+	var body AgentRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	agent, err := s.Join(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, agent)
+}
+
+func (s *Server) handleAgents(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, s.Agents())
+}
+
+func (s *Server) handleLeave(w http.ResponseWriter, req *http.Request) {
+	if !s.Leave(req.PathValue("id")) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", ErrAgentNotFound, req.PathValue("id")))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleNextAssignment answers with the next stage the agent runs, or 204 No Content when none
+// came up within ?wait=, 30s at most
+func (s *Server) handleNextAssignment(w http.ResponseWriter, req *http.Request) {
+	wait := maxAgentWait
+	if v := req.URL.Query().Get("wait"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid wait %q", v))
+			return
+		}
+		wait = min(d, maxAgentWait)
+	}
+	a, err := s.NextAssignment(req.Context(), req.PathValue("id"), wait)
+	switch {
+	case err != nil:
+		writeError(w, http.StatusNotFound, err)
+	case a == nil:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeJSON(w, http.StatusOK, a)
+	}
+}
+
+// handleAssignment answers with an assignment the agent runs; agents ask for it to tell the
+// server they are still there, and stop the stage once it is gone
+func (s *Server) handleAssignment(w http.ResponseWriter, req *http.Request) {
+	a, err := s.assignment(req.PathValue("id"), req.PathValue("assignment"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, a)
+}
+
+// handleAssignmentLogs copies the request body, the output of the stage as the agent runs it, to
+// the output of the run
+func (s *Server) handleAssignmentLogs(w http.ResponseWriter, req *http.Request) {
+	a, err := s.assignment(req.PathValue("id"), req.PathValue("assignment"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if _, err := io.Copy(a, req.Body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleAssignmentResult(w http.ResponseWriter, req *http.Request) {
+	var body AssignmentResult
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if err := s.Finish(req.PathValue("id"), req.PathValue("assignment"), body); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
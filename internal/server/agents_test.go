@@ -0,0 +1,137 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+// syncBuffer is a bytes.Buffer safe to write from the agent's requests while the test reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestServer_Agents(t *testing.T) {
+	s, ts, _ := testServer(t, false)
+	client := NewClient(ts.URL)
+	ctx := context.Background()
+	req := runner.StageRequest{RunID: "run-1", Workflow: filepath.Join(s.Root, "ml", "train.yaml"), Stage: "train", Labels: []string{"gpu"}, Vars: map[string]string{"model": "large"}}
+
+	if err := s.dispatchStage(ctx, req, new(syncBuffer)); !errors.Is(err, ErrNoAgent) {
+		t.Fatalf("dispatchStage() without agents = %v, want %v", err, ErrNoAgent)
+	}
+
+	cpu, err := client.Join(ctx, AgentRequest{Name: "cpu", Labels: []string{"linux"}})
+	if err != nil {
+		t.Fatalf("Join() error: %v", err)
+	}
+	gpu, err := client.Join(ctx, AgentRequest{Name: "gpu", Labels: []string{"linux", "gpu"}})
+	if err != nil {
+		t.Fatalf("Join() error: %v", err)
+	}
+	var agents []Agent
+	if code := do(t, http.MethodGet, ts.URL+"/agents", "", &agents); code != http.StatusOK || len(agents) != 2 || agents[0].Name != "cpu" {
+		t.Errorf("GET /agents = %d, %+v", code, agents)
+	}
+
+	var out syncBuffer
+	done := make(chan error, 1)
+	go func() { done <- s.dispatchStage(ctx, req, &out) }()
+
+	if as, err := client.NextAssignment(ctx, cpu.ID, 50*time.Millisecond); err != nil || as != nil {
+		t.Errorf("NextAssignment() of an agent without the labels = %+v, %v, want nothing", as, err)
+	}
+	as, err := client.NextAssignment(ctx, gpu.ID, time.Second)
+	if err != nil || as == nil {
+		t.Fatalf("NextAssignment() = %+v, %v", as, err)
+	}
+	if as.RunID != "run-1" || as.Workflow != "ml/train.yaml" || as.Stage != "train" || as.Vars["model"] != "large" {
+		t.Errorf("NextAssignment() = %+v", as)
+	}
+	if err := client.SendLogs(ctx, gpu.ID, as.ID, strings.NewReader("STEP 1.1: fit (exec)\n")); err != nil {
+		t.Errorf("SendLogs() error: %v", err)
+	}
+	if err := client.Finish(ctx, gpu.ID, as.ID, AssignmentResult{Error: "out of memory"}); err != nil {
+		t.Errorf("Finish() error: %v", err)
+	}
+	if err := <-done; err == nil || err.Error() != "on agent: out of memory" {
+		t.Errorf("dispatchStage() = %v, want the agent's error", err)
+	}
+	for _, want := range []string{"Waiting for an agent with gpu\n", "Running stage 'train' on agent gpu\n", "STEP 1.1: fit (exec)\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if err := client.Assignment(ctx, gpu.ID, as.ID); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Assignment() once finished = %v, want %v", err, ErrAgentNotFound)
+	}
+}
+
+func TestServer_AgentsCancel(t *testing.T) {
+	s, ts, _ := testServer(t, false)
+	client := NewClient(ts.URL)
+	ctx := context.Background()
+	agent, err := client.Join(ctx, AgentRequest{Name: "gpu", Labels: []string{"gpu"}})
+	if err != nil {
+		t.Fatalf("Join() error: %v", err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.dispatchStage(runCtx, runner.StageRequest{Workflow: filepath.Join(s.Root, "train.yaml"), Stage: "train", Labels: []string{"gpu"}}, new(syncBuffer))
+	}()
+	as, err := client.NextAssignment(ctx, agent.ID, time.Second)
+	if err != nil || as == nil {
+		t.Fatalf("NextAssignment() = %+v, %v", as, err)
+	}
+	if err := client.Assignment(ctx, agent.ID, as.ID); err != nil {
+		t.Errorf("Assignment() while running = %v", err)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("dispatchStage() = %v, want %v", err, context.Canceled)
+	}
+	if err := client.Assignment(ctx, agent.ID, as.ID); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Assignment() once cancelled = %v, want %v", err, ErrAgentNotFound)
+	}
+
+	// An agent that leaves fails the stage it runs
+	done2 := make(chan error, 1)
+	go func() {
+		done2 <- s.dispatchStage(ctx, runner.StageRequest{Workflow: filepath.Join(s.Root, "train.yaml"), Stage: "train", Labels: []string{"gpu"}}, new(syncBuffer))
+	}()
+	if as, err = client.NextAssignment(ctx, agent.ID, time.Second); err != nil || as == nil {
+		t.Fatalf("NextAssignment() = %+v, %v", as, err)
+	}
+	if err := client.Leave(ctx, agent.ID); err != nil {
+		t.Errorf("Leave() error: %v", err)
+	}
+	if err := <-done2; err == nil || err.Error() != "agent gpu left" {
+		t.Errorf("dispatchStage() = %v, want the agent to have left", err)
+	}
+	if _, err := client.NextAssignment(ctx, agent.ID, 0); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("NextAssignment() after leaving = %v, want %v", err, ErrAgentNotFound)
+	}
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client talks to the REST API of a forge server
+type Client struct {
+	// BaseURL is the address of the server, e.g. http://localhost:8080
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+// Logs copies the output of the run so far to w
+func (c *Client) Logs(ctx context.Context, id string, w io.Writer) error {
+	resp, err := c.get(ctx, "/runs/"+url.PathEscape(id)+"/logs?follow=false", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Follow calls fn for every event of the run, starting with the first, until the run ends,
+// ctx is done or fn fails
+func (c *Client) Follow(ctx context.Context, id string, fn func(Event) error) error {
+	resp, err := c.get(ctx, "/runs/"+url.PathEscape(id)+"/events", http.Header{"Accept": {"text/event-stream"}})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var ev Event
+	var data []string
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		field, value, _ := strings.Cut(scanner.Text(), ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.Type = value
+		case "data":
+			data = append(data, value)
+		case "":
+			if ev.Type == "" && data == nil {
+				continue
+			}
+			ev.Data = json.RawMessage(strings.Join(data, "\n"))
+			if err := fn(ev); err != nil {
+				return err
+			}
+			if ev.Type == "end" {
+				return nil
+			}
+			ev, data = Event{}, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("event stream of run %s ended before the run", id)
+}
+
+func (c *Client) get(ctx context.Context, path string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+// responseError returns the error the server answered with
+func responseError(resp *http.Response) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error != "" {
+		return fmt.Errorf("%s: %s", resp.Status, body.Error)
+	}
+	return fmt.Errorf("%s", resp.Status)
+}
+
+// Join registers an agent with the server
+func (c *Client) Join(ctx context.Context, req AgentRequest) (*Agent, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var agent Agent
+	if _, err := c.agentCall(ctx, http.MethodPost, "/agents", bytes.NewReader(body), &agent); err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// Leave unregisters the agent with the given ID
+func (c *Client) Leave(ctx context.Context, id string) error {
+	_, err := c.agentCall(ctx, http.MethodDelete, "/agents/"+url.PathEscape(id), nil, nil)
+	return err
+}
+
+// NextAssignment waits up to wait for a stage the agent runs; it returns nil when none came up
+func (c *Client) NextAssignment(ctx context.Context, id string, wait time.Duration) (*Assignment, error) {
+	var a Assignment
+	code, err := c.agentCall(ctx, http.MethodGet, "/agents/"+url.PathEscape(id)+"/assignment?wait="+wait.String(), nil, &a)
+	if err != nil || code == http.StatusNoContent {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Assignment tells the server the agent still runs the assignment; it returns ErrAgentNotFound
+// once the run no longer waits for it
+func (c *Client) Assignment(ctx context.Context, agentID, id string) error {
+	_, err := c.agentCall(ctx, http.MethodGet, c.assignmentPath(agentID, id), nil, nil)
+	return err
+}
+
+// SendLogs sends what is read from r as the output of the assignment, until r ends
+func (c *Client) SendLogs(ctx context.Context, agentID, id string, r io.Reader) error {
+	_, err := c.agentCall(ctx, http.MethodPost, c.assignmentPath(agentID, id)+"/logs", r, nil)
+	return err
+}
+
+// Finish reports the outcome of the assignment
+func (c *Client) Finish(ctx context.Context, agentID, id string, result AssignmentResult) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = c.agentCall(ctx, http.MethodPost, c.assignmentPath(agentID, id)+"/result", bytes.NewReader(body), nil)
+	return err
+}
+
+func (c *Client) assignmentPath(agentID, id string) string {
+	return "/agents/" + url.PathEscape(agentID) + "/assignments/" + url.PathEscape(id)
+}
+
+// agentCall sends a request of the agent API and decodes the response into v, if any; a 404
+// returns ErrAgentNotFound
+func (c *Client) agentCall(ctx context.Context, method, path string, body io.Reader, v any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return resp.StatusCode, fmt.Errorf("%w: %v", ErrAgentNotFound, responseError(resp))
+	case resp.StatusCode >= 300:
+		return resp.StatusCode, responseError(resp)
+	case v != nil && resp.StatusCode != http.StatusNoContent:
+		return resp.StatusCode, json.NewDecoder(resp.Body).Decode(v)
+	}
+	return resp.StatusCode, nil
+}
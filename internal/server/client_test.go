@@ -0,0 +1,86 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	s, ts, _ := testServer(t, false)
+	run, err := s.Start(RunRequest{Workflow: "release.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(ts.URL + "/")
+
+	var types []string
+	var output strings.Builder
+	var end Run
+	err = c.Follow(context.Background(), run.ID, func(ev Event) error {
+		types = append(types, ev.Type)
+		switch ev.Type {
+		case "output":
+			var text string
+			if err := json.Unmarshal(ev.Data, &text); err != nil {
+				return err
+			}
+			output.WriteString(text)
+		case "end":
+			return json.Unmarshal(ev.Data, &end)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Follow() error: %v", err)
+	}
+	for _, want := range []string{"workflow_start", "stage_start", "step_start", "step_end", "output", "workflow_end"} {
+		if !slices.Contains(types, want) {
+			t.Errorf("events %v missing %s", types, want)
+		}
+	}
+	if types[len(types)-1] != "end" || end.Status != StatusSucceeded {
+		t.Errorf("last event = %s with %+v, want end of a succeeded run", types[len(types)-1], end)
+	}
+	if !strings.Contains(output.String(), "STEP 1.1: push (exec)") {
+		t.Errorf("output events missing step output:\n%s", output.String())
+	}
+
+	var logs bytes.Buffer
+	if err := c.Logs(context.Background(), run.ID, &logs); err != nil {
+		t.Fatalf("Logs() error: %v", err)
+	}
+	if logs.String() != output.String() {
+		t.Errorf("Logs() = %q, want the output of the events %q", logs.String(), output.String())
+	}
+
+	if err := c.Logs(context.Background(), "nope", io.Discard); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Logs() of an unknown run error = %v", err)
+	}
+}
+
+func TestServer_EventsResume(t *testing.T) {
+	s, ts, _ := testServer(t, false)
+	run, err := s.Start(RunRequest{Workflow: "release.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Wait()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/runs/"+run.ID+"/events", nil)
+	req.Header.Set("Last-Event-ID", "2")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.HasPrefix(string(body), "id: 3\n") || strings.Contains(string(body), "id: 2\n") {
+		t.Errorf("resumed stream does not start after event 2:\n%s", body)
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/andre-koe/forge/api/forgev1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// RegisterGRPC registers the gRPC API of the server, the ForgeService of api/forgev1, with gs
+func (s *Server) RegisterGRPC(gs grpc.ServiceRegistrar) {
+	forgev1.RegisterForgeServiceServer(gs, &grpcService{s: s})
+}
+
+// grpcService serves the runs of a Server over gRPC, as the REST API does over HTTP
+type grpcService struct {
+	forgev1.UnimplementedForgeServiceServer
+	s *Server
+}
+
+func (g *grpcService) StartRun(ctx context.Context, req *forgev1.StartRunRequest) (*forgev1.Run, error) {
+	run, err := g.s.Start(RunRequest{Workflow: req.Workflow, Inputs: req.Inputs, Stages: req.Stages, Priority: int(req.Priority)})
+	switch {
+	case errors.Is(err, ErrQueueFull):
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return runProto(run), nil
+}
+
+func (g *grpcService) GetRun(ctx context.Context, req *forgev1.GetRunRequest) (*forgev1.Run, error) {
+	run, ok := g.s.Get(req.Id)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "run %s not found", req.Id)
+	}
+	return runProto(run), nil
+}
+
+func (g *grpcService) ListRuns(ctx context.Context, req *forgev1.ListRunsRequest) (*forgev1.ListRunsResponse, error) {
+	resp := &forgev1.ListRunsResponse{}
+	for _, run := range g.s.List() {
+		resp.Runs = append(resp.Runs, runProto(run))
+	}
+	return resp, nil
+}
+
+func (g *grpcService) StreamLogs(req *forgev1.StreamLogsRequest, stream grpc.ServerStreamingServer[forgev1.LogChunk]) error {
+	g.s.mu.Lock()
+	run, ok := g.s.runs[req.Id]
+	g.s.mu.Unlock()
+	if !ok {
+		return status.Errorf(codes.NotFound, "run %s not found", req.Id)
+	}
+	ctx := stream.Context()
+	if !req.Follow {
+		ctx = closedContext()
+	}
+	err := run.log.text.Follow(ctx, 0, func(p []byte) error {
+		return stream.Send(&forgev1.LogChunk{Data: p})
+	})
+	if err != nil && stream.Context().Err() != nil {
+		return status.FromContextError(stream.Context().Err()).Err()
+	}
+	return err
+}
+
+func (g *grpcService) CancelRun(ctx context.Context, req *forgev1.CancelRunRequest) (*forgev1.Run, error) {
+	if !g.s.Cancel(req.Id) {
+		return nil, status.Errorf(codes.NotFound, "run %s not found", req.Id)
+	}
+	run, _ := g.s.Get(req.Id)
+	return runProto(run), nil
+}
+
+var runStatuses = map[Status]forgev1.RunStatus{
+	StatusQueued:    forgev1.RunStatus_RUN_STATUS_QUEUED,
+	StatusRunning:   forgev1.RunStatus_RUN_STATUS_RUNNING,
+	StatusSucceeded: forgev1.RunStatus_RUN_STATUS_SUCCEEDED,
+	StatusFailed:    forgev1.RunStatus_RUN_STATUS_FAILED,
+	StatusCancelled: forgev1.RunStatus_RUN_STATUS_CANCELLED,
+}
+
+// runProto converts a snapshot of a run to its gRPC message
+func runProto(run *Run) *forgev1.Run {
+	pb := &forgev1.Run{
+		Id:        run.ID,
+		Workflow:  run.Workflow,
+		Status:    runStatuses[run.Status],
+		Priority:  int32(run.Priority),
+		QueuedAt:  timestamp(run.QueuedAt),
+		StartedAt: timestamp(run.StartedAt),
+		Error:     run.Error,
+		Position:  int32(run.Position),
+	}
+	if run.FinishedAt != nil {
+		pb.FinishedAt = timestamp(*run.FinishedAt)
+	}
+	if a := run.Approval; a != nil {
+		pb.Approval = &forgev1.Approval{Stage: a.Stage, Step: a.Step, Message: a.Message, Deadline: timestamp(a.Deadline)}
+	}
+	return pb
+}
+
+// timestamp converts t, leaving the zero time unset
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
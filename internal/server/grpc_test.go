@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/api/forgev1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// grpcClient serves the gRPC API of s in memory and returns a client of it
+func grpcClient(t *testing.T, s *Server) forgev1.ForgeServiceClient {
+	t.Helper()
+	ln := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	s.RegisterGRPC(gs)
+	go gs.Serve(ln)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///forge",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return forgev1.NewForgeServiceClient(conn)
+}
+
+func TestGRPC_Run(t *testing.T) {
+	s, _, calls := testServer(t, false)
+	client := grpcClient(t, s)
+	ctx := context.Background()
+
+	run, err := client.StartRun(ctx, &forgev1.StartRunRequest{Workflow: "release.yaml", Inputs: map[string]string{"tag": "v1.2.0"}})
+	if err != nil {
+		t.Fatalf("StartRun() error: %v", err)
+	}
+	if run.Id == "" || run.Status != forgev1.RunStatus_RUN_STATUS_RUNNING || run.StartedAt == nil {
+		t.Errorf("StartRun() = %v", run)
+	}
+
+	stream, err := client.StreamLogs(ctx, &forgev1.StreamLogsRequest{Id: run.Id, Follow: true})
+	if err != nil {
+		t.Fatalf("StreamLogs() error: %v", err)
+	}
+	var logs strings.Builder
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("StreamLogs() error: %v", err)
+		}
+		logs.Write(chunk.Data)
+	}
+	if !strings.Contains(logs.String(), "STEP 1.1: push (exec)") || !strings.Contains(logs.String(), "Workflow execution completed") {
+		t.Errorf("logs missing the run's output:\n%s", logs.String())
+	}
+	s.Wait()
+
+	got, err := client.GetRun(ctx, &forgev1.GetRunRequest{Id: run.Id})
+	if err != nil || got.Status != forgev1.RunStatus_RUN_STATUS_SUCCEEDED || got.FinishedAt == nil {
+		t.Errorf("GetRun() = %v, %v, want succeeded", got, err)
+	}
+	if got := calls(); len(got) != 1 || !slices.Equal(got[0], []string{"deploy", "v1.2.0"}) {
+		t.Errorf("commands = %v, want [[deploy v1.2.0]]", got)
+	}
+	list, err := client.ListRuns(ctx, &forgev1.ListRunsRequest{})
+	if err != nil || len(list.Runs) != 1 || list.Runs[0].Id != run.Id {
+		t.Errorf("ListRuns() = %v, %v", list, err)
+	}
+}
+
+func TestGRPC_Cancel(t *testing.T) {
+	s, _, _ := testServer(t, true, WithMaxRuns(1), WithMaxQueued(1))
+	client := grpcClient(t, s)
+	ctx := context.Background()
+
+	running, err := client.StartRun(ctx, &forgev1.StartRunRequest{Workflow: "release.yaml"})
+	if err != nil {
+		t.Fatalf("StartRun() error: %v", err)
+	}
+	queued, err := client.StartRun(ctx, &forgev1.StartRunRequest{Workflow: "release.yaml"})
+	if err != nil || queued.Status != forgev1.RunStatus_RUN_STATUS_QUEUED || queued.Position != 1 || queued.StartedAt != nil {
+		t.Errorf("StartRun() while a run is in progress = %v, %v, want it queued", queued, err)
+	}
+	for _, id := range []string{queued.Id, running.Id} {
+		if _, err := client.CancelRun(ctx, &forgev1.CancelRunRequest{Id: id}); err != nil {
+			t.Errorf("CancelRun(%s) error: %v", id, err)
+		}
+	}
+	s.Wait()
+	for _, id := range []string{queued.Id, running.Id} {
+		if got, _ := client.GetRun(ctx, &forgev1.GetRunRequest{Id: id}); got.GetStatus() != forgev1.RunStatus_RUN_STATUS_CANCELLED {
+			t.Errorf("status of %s = %s, want cancelled", id, got.GetStatus())
+		}
+	}
+}
+
+func TestGRPC_Errors(t *testing.T) {
+	s, _, _ := testServer(t, true, WithMaxRuns(1), WithMaxQueued(1))
+	client := grpcClient(t, s)
+	ctx := context.Background()
+	for range 2 {
+		if _, err := client.StartRun(ctx, &forgev1.StartRunRequest{Workflow: "release.yaml"}); err != nil {
+			t.Fatalf("StartRun() error: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		for _, run := range s.List() {
+			s.Cancel(run.ID)
+		}
+		s.Wait()
+	})
+
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"start outside the directory", func() error {
+			_, err := client.StartRun(ctx, &forgev1.StartRunRequest{Workflow: "../outside.yaml"})
+			return err
+		}, codes.InvalidArgument},
+		{"start with a full queue", func() error {
+			_, err := client.StartRun(ctx, &forgev1.StartRunRequest{Workflow: "release.yaml"})
+			return err
+		}, codes.ResourceExhausted},
+		{"get an unknown run", func() error {
+			_, err := client.GetRun(ctx, &forgev1.GetRunRequest{Id: "nope"})
+			return err
+		}, codes.NotFound},
+		{"cancel an unknown run", func() error {
+			_, err := client.CancelRun(ctx, &forgev1.CancelRunRequest{Id: "nope"})
+			return err
+		}, codes.NotFound},
+		{"stream the logs of an unknown run", func() error {
+			stream, err := client.StreamLogs(ctx, &forgev1.StreamLogsRequest{Id: "nope"})
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		}, codes.NotFound},
+	}
+	for _, tt := range tests {
+		if got := status.Code(tt.call()); got != tt.want {
+			t.Errorf("%s: code = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// feed is an append-only sequence that any number of readers can follow while it grows
+type feed[T any] struct {
+	mu     sync.Mutex
+	items  []T
+	closed bool
+	// changed is closed and replaced whenever items are appended or the feed is closed
+	changed chan struct{}
+}
+
+func newFeed[T any]() *feed[T] {
+	return &feed[T]{changed: make(chan struct{})}
+}
+
+func (f *feed[T]) Append(items ...T) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.items = append(f.items, items...)
+	if !f.closed {
+		close(f.changed)
+		f.changed = make(chan struct{})
+	}
+}
+
+// Close marks the end of the feed
+func (f *feed[T]) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.closed {
+		f.closed = true
+		close(f.changed)
+	}
+}
+
+// Len returns the number of items appended so far
+func (f *feed[T]) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.items)
+}
+
+// Follow calls emit with the items from offset on and then with every new batch until the
+// feed is closed, ctx is done or emit fails
+func (f *feed[T]) Follow(ctx context.Context, offset int, emit func([]T) error) error {
+	for {
+		f.mu.Lock()
+		batch, closed, changed := f.items[min(offset, len(f.items)):], f.closed, f.changed
+		f.mu.Unlock()
+		if len(batch) > 0 {
+			if err := emit(batch); err != nil {
+				return err
+			}
+			offset += len(batch)
+			continue
+		}
+		if closed {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Event is a message of the event stream of a run
+type Event struct {
+	// Type is "output" for process and runner output, "end" for the final status of the run,
+	// or the kind of a runner lifecycle event such as "step_start"
+	Type string `json:"type"`
+	// Data is the output text, the runner.Event or the final Run
+	Data json.RawMessage `json:"data"`
+}
+
+// runLog keeps the output of a run as plain text and, together with lifecycle events, as events
+type runLog struct {
+	text   *feed[byte]
+	events *feed[Event]
+}
+
+func newRunLog() *runLog {
+	return &runLog{text: newFeed[byte](), events: newFeed[Event]()}
+}
+
+func (l *runLog) Write(p []byte) (int, error) {
+	l.text.Append(p...)
+	l.event("output", string(p))
+	return len(p), nil
+}
+
+func (l *runLog) event(typ string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	l.events.Append(Event{Type: typ, Data: data})
+}
+
+// Close ends the output; end is sent as the final event
+func (l *runLog) Close(end *Run) {
+	l.event("end", end)
+	l.text.Close()
+	l.events.Close()
+}
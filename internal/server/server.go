@@ -0,0 +1,595 @@
+// Package server exposes workflow runs over a small REST API so runs can be triggered,
+// inspected, followed and cancelled remotely.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+// Status is the state of a run started by the server
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Run is a workflow run started by the server
+type Run struct {
+	ID         string     `json:"id"`
+	Workflow   string     `json:"workflow"`
+	Status     Status     `json:"status"`
+	Priority   int        `json:"priority,omitempty"`
+	QueuedAt   time.Time  `json:"queued_at"`
+	StartedAt  time.Time  `json:"started_at,omitzero"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	// Position is the place of a queued run in the run queue, 1 for the next run to start
+	Position int `json:"position,omitempty"`
+	// Approval is the approval step the run waits for, decided with POST /runs/{id}/approve or /deny
+	// and an approval token for it
+	Approval *runner.ApprovalRequest `json:"approval,omitempty"`
+
+	decide chan runner.Decision
+	ready  chan struct{}
+	cancel context.CancelFunc
+	log    *runLog
+	done   chan struct{}
+}
+
+var (
+	// ErrRunNotFound is returned for ids of runs the server does not know
+	ErrRunNotFound = errors.New("run not found")
+	// ErrNoApproval is returned when deciding about a run that does not wait for an approval
+	ErrNoApproval = errors.New("run is not waiting for an approval")
+	// ErrQueueFull is returned when starting a run while the run queue holds as many runs as allowed
+	ErrQueueFull = errors.New("run queue is full")
+	// ErrApprovalsDisabled is returned when deciding about a run on a server without an approval secret
+	ErrApprovalsDisabled = errors.New("approvals over the API are disabled, the server has no approval secret")
+	// ErrApprovalToken is returned when deciding about a run without a valid approval token for its step
+	ErrApprovalToken = errors.New("invalid approval token")
+)
+
+// RunRequest is the body of POST /runs
+type RunRequest struct {
+	// Workflow is the path of the workflow file relative to the server's root directory
+	Workflow string `json:"workflow"`
+	// Inputs override workflow variables
+	Inputs map[string]string `json:"inputs,omitempty"`
+	// Stages limits the run to the named stages
+	Stages []string `json:"stages,omitempty"`
+	// Priority orders the run queue: runs with a higher priority start first, runs with the same
+	// priority in the order they were requested
+	Priority int `json:"priority,omitempty"`
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithRunOptions sets the function returning the runner options used for every run of a workflow,
+// e.g. its history store
+func WithRunOptions(f func(workflow string) ([]runner.Option, error)) Option {
+	return func(s *Server) { s.RunOptions = f }
+}
+
+// WithBaseURL sets the address clients reach the server at; runs are registered with it so
+// `forge cancel` can cancel them through the API
+func WithBaseURL(url string) Option {
+	return func(s *Server) { s.BaseURL = strings.TrimSuffix(url, "/") }
+}
+
+// WithMaxRuns limits how many runs are in progress at once; further runs wait in the run queue.
+// 0, the default, runs every run right away.
+func WithMaxRuns(n int) Option {
+	return func(s *Server) { s.MaxRuns = n }
+}
+
+// WithMaxRunsPerWorkflow limits how many runs of the same workflow are in progress at once;
+// further runs of it wait in the run queue while runs of other workflows may pass them
+func WithMaxRunsPerWorkflow(n int) Option {
+	return func(s *Server) { s.MaxRunsPerWorkflow = n }
+}
+
+// WithMaxQueued limits how many runs wait in the run queue; starting a run while it is full
+// fails with ErrQueueFull. 0, the default, does not limit the queue.
+func WithMaxQueued(n int) Option {
+	return func(s *Server) { s.MaxQueued = n }
+}
+
+// WithApprovalSecret sets the key the approval tokens of POST /runs/{id}/approve and /deny
+// are signed with, see runner.SignApprovalToken; without it runs cannot be decided over the API
+func WithApprovalSecret(secret []byte) Option {
+	return func(s *Server) { s.ApprovalSecret = secret }
+}
+
+// Server starts and tracks runs of the workflows below Root
+type Server struct {
+	Root               string
+	NewRunner          runner.Factory
+	RunOptions         func(workflow string) ([]runner.Option, error)
+	BaseURL            string
+	MaxRuns            int
+	MaxRunsPerWorkflow int
+	MaxQueued          int
+	ApprovalSecret     []byte
+
+	ctx  context.Context
+	mu   sync.Mutex
+	runs map[string]*Run
+	ids  []string
+	wg   sync.WaitGroup
+
+	// queue holds the runs waiting for a worker, in the order they start
+	queue   []*Run
+	running int
+	// workflowRuns counts the runs in progress per workflow
+	workflowRuns map[string]int
+
+	agents agents
+}
+
+// New creates a server for the workflows below root; runs are cancelled when ctx is done
+func New(ctx context.Context, root string, newRunner runner.Factory, opts ...Option) *Server {
+	s := &Server{
+		Root:         root,
+		NewRunner:    newRunner,
+		RunOptions:   func(string) ([]runner.Option, error) { return nil, nil },
+		ctx:          ctx,
+		runs:         map[string]*Run{},
+		workflowRuns: map[string]int{},
+		agents: agents{
+			byID:        map[string]*Agent{},
+			assignments: map[string]*Assignment{},
+			changed:     make(chan struct{}),
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Handler returns the HTTP handler of the REST API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /runs", s.handleStart)
+	mux.HandleFunc("GET /runs", s.handleList)
+	mux.HandleFunc("GET /runs/{id}", s.handleGet)
+	mux.HandleFunc("GET /runs/{id}/logs", s.handleLogs)
+	mux.HandleFunc("GET /runs/{id}/events", s.handleEvents)
+	mux.HandleFunc("DELETE /runs/{id}", s.handleCancel)
+	mux.HandleFunc("POST /runs/{id}/approve", s.handleDecide(true))
+	mux.HandleFunc("POST /runs/{id}/deny", s.handleDecide(false))
+	mux.HandleFunc("POST /agents", s.handleJoin)
+	mux.HandleFunc("GET /agents", s.handleAgents)
+	mux.HandleFunc("DELETE /agents/{id}", s.handleLeave)
+	mux.HandleFunc("GET /agents/{id}/assignment", s.handleNextAssignment)
+	mux.HandleFunc("GET /agents/{id}/assignments/{assignment}", s.handleAssignment)
+	mux.HandleFunc("POST /agents/{id}/assignments/{assignment}/logs", s.handleAssignmentLogs)
+	mux.HandleFunc("POST /agents/{id}/assignments/{assignment}/result", s.handleAssignmentResult)
+	return mux
+}
+
+// Wait blocks until all runs have finished
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+// Start starts a run of the workflow described by req, or queues it while the server runs as
+// many runs as it may
+func (s *Server) Start(req RunRequest) (*Run, error) {
+	if req.Workflow == "" || !filepath.IsLocal(req.Workflow) {
+		return nil, fmt.Errorf("workflow must be a relative path inside the server's directory, got %q", req.Workflow)
+	}
+	workflow := filepath.Join(s.Root, req.Workflow)
+	opts, err := s.RunOptions(workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	run := &Run{
+		ID:       history.NewID(time.Now()),
+		Workflow: req.Workflow,
+		Status:   StatusQueued,
+		Priority: req.Priority,
+		QueuedAt: time.Now(),
+		ready:    make(chan struct{}),
+		cancel:   cancel,
+		log:      newRunLog(),
+		done:     make(chan struct{}),
+	}
+	opts = append(opts,
+		runner.WithOut(run.log),
+		runner.WithProcessOutput(run.log, run.log),
+		runner.WithEvents(func(ev runner.Event) { run.log.event(string(ev.Kind), ev) }),
+		runner.WithContext(ctx),
+		runner.WithRunID(run.ID),
+		runner.WithApprovers(s.approver(run)),
+		runner.WithDispatcher(s.dispatchStage),
+	)
+	if s.BaseURL != "" {
+		opts = append(opts, runner.WithCancelURL(s.BaseURL+"/runs/"+run.ID))
+	}
+	if len(req.Inputs) > 0 {
+		opts = append(opts, runner.WithVars(req.Inputs))
+	}
+	if len(req.Stages) > 0 {
+		opts = append(opts, runner.WithStages(req.Stages...))
+	}
+	r, err := s.NewRunner(workflow, opts...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.MaxQueued > 0 && len(s.queue) >= s.MaxQueued {
+		s.mu.Unlock()
+		cancel()
+		return nil, fmt.Errorf("%w: %d runs are waiting", ErrQueueFull, len(s.queue))
+	}
+	s.runs[run.ID] = run
+	s.ids = append(s.ids, run.ID)
+	s.enqueue(run)
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.acquire(ctx, run); err != nil {
+			s.finish(run, err)
+			return
+		}
+		err := r.Run()
+		s.release(run)
+		s.finish(run, err)
+	}()
+	return s.snapshot(run), nil
+}
+
+// enqueue adds run to the queue behind the runs of the same or a higher priority and starts
+// what may start; s.mu must be held
+func (s *Server) enqueue(run *Run) {
+	i := slices.IndexFunc(s.queue, func(q *Run) bool { return q.Priority < run.Priority })
+	if i < 0 {
+		i = len(s.queue)
+	}
+	s.queue = slices.Insert(s.queue, i, run)
+	s.dispatch()
+}
+
+// dispatch starts the queued runs that fit the limits on runs in progress, in queue order; a run
+// held back by the limit of its workflow lets the runs of other workflows pass. s.mu must be held.
+func (s *Server) dispatch() {
+	for i := 0; i < len(s.queue); {
+		if s.MaxRuns > 0 && s.running >= s.MaxRuns {
+			return
+		}
+		run := s.queue[i]
+		if s.MaxRunsPerWorkflow > 0 && s.workflowRuns[run.Workflow] >= s.MaxRunsPerWorkflow {
+			i++
+			continue
+		}
+		s.queue = slices.Delete(s.queue, i, i+1)
+		s.running++
+		s.workflowRuns[run.Workflow]++
+		run.Status = StatusRunning
+		run.StartedAt = time.Now()
+		close(run.ready)
+	}
+}
+
+// acquire waits until run leaves the queue; a run cancelled while queued is removed from it and
+// never starts
+func (s *Server) acquire(ctx context.Context, run *Run) error {
+	select {
+	case <-run.ready:
+		return nil
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.Index(s.queue, run)
+	if i < 0 {
+		// Started while it was cancelled; the run stops on its own
+		return nil
+	}
+	s.queue = slices.Delete(s.queue, i, i+1)
+	return fmt.Errorf("%w while queued", runner.ErrCancelled)
+}
+
+// release frees the worker of a finished run for the queue
+func (s *Server) release(run *Run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running--
+	if s.workflowRuns[run.Workflow]--; s.workflowRuns[run.Workflow] == 0 {
+		delete(s.workflowRuns, run.Workflow)
+	}
+	s.dispatch()
+}
+
+func (s *Server) finish(run *Run, err error) {
+	defer close(run.done)
+	defer run.cancel()
+
+	s.mu.Lock()
+	now := time.Now()
+	run.FinishedAt = &now
+	switch {
+	case errors.Is(err, runner.ErrCancelled):
+		run.Status = StatusCancelled
+	case err != nil:
+		run.Status = StatusFailed
+	default:
+		run.Status = StatusSucceeded
+	}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	s.mu.Unlock()
+	run.log.Close(s.snapshot(run))
+}
+
+// Get returns a copy of the run with the given ID
+func (s *Server) Get(id string) (*Run, bool) {
+	s.mu.Lock()
+	run, ok := s.runs[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	return s.snapshot(run), true
+}
+
+// List returns copies of all runs, oldest first
+func (s *Server) List() []*Run {
+	s.mu.Lock()
+	ids := slices.Clone(s.ids)
+	s.mu.Unlock()
+	runs := make([]*Run, 0, len(ids))
+	for _, id := range ids {
+		run, _ := s.Get(id)
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// Cancel cancels the run with the given ID; it reports false for unknown runs
+func (s *Server) Cancel(id string) bool {
+	s.mu.Lock()
+	run, ok := s.runs[id]
+	s.mu.Unlock()
+	if ok {
+		run.cancel()
+	}
+	return ok
+}
+
+// approver lets API clients decide about the approval steps of run
+func (s *Server) approver(run *Run) runner.Approver {
+	return func(ctx context.Context, req runner.ApprovalRequest) (runner.Decision, error) {
+		decide := make(chan runner.Decision, 1)
+		s.mu.Lock()
+		run.Approval, run.decide = &req, decide
+		s.mu.Unlock()
+		defer func() {
+			s.mu.Lock()
+			run.Approval, run.decide = nil, nil
+			s.mu.Unlock()
+		}()
+
+		select {
+		case d := <-decide:
+			return d, nil
+		case <-ctx.Done():
+			return runner.Decision{}, ctx.Err()
+		}
+	}
+}
+
+// Decide approves or denies the approval step the run with the given ID waits for on behalf of
+// whom token, an approval token for the step, was issued to
+func (s *Server) Decide(id string, approved bool, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	if run.decide == nil {
+		return fmt.Errorf("%w: %s", ErrNoApproval, id)
+	}
+	if len(s.ApprovalSecret) == 0 {
+		return ErrApprovalsDisabled
+	}
+	by, err := runner.CheckApprovalToken(s.ApprovalSecret, token, *run.Approval, time.Now())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrApprovalToken, err)
+	}
+	run.decide <- runner.Decision{Approved: approved, By: by}
+	run.decide = nil
+	return nil
+}
+
+// snapshot copies the exported fields of run under the lock
+func (s *Server) snapshot(run *Run) *Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *run
+	if run.Status == StatusQueued {
+		c.Position = slices.Index(s.queue, run) + 1
+	}
+	return &c
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, req *http.Request) {
+	var body RunRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	run, err := s.Start(body)
+	switch {
+	case errors.Is(err, ErrQueueFull):
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Location", "/runs/"+run.ID)
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+func (s *Server) handleList(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, s.List())
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, req *http.Request) {
+	run, ok := s.Get(req.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run %s not found", req.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// run returns the run with the id of the request's path or writes a 404
+func (s *Server) run(w http.ResponseWriter, req *http.Request) (*Run, bool) {
+	s.mu.Lock()
+	run, ok := s.runs[req.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run %s not found", req.PathValue("id")))
+	}
+	return run, ok
+}
+
+// handleLogs streams the output of a run from its start until it finishes or the client
+// disconnects; with ?follow=false only the output so far is returned
+func (s *Server) handleLogs(w http.ResponseWriter, req *http.Request) {
+	run, ok := s.run(w, req)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if req.URL.Query().Get("follow") == "false" {
+		_ = run.log.text.Follow(closedContext(), 0, func(p []byte) error {
+			_, err := w.Write(p)
+			return err
+		})
+		return
+	}
+	flusher, _ := w.(http.Flusher)
+	_ = run.log.text.Follow(req.Context(), 0, func(p []byte) error {
+		if _, err := w.Write(p); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+}
+
+// handleEvents streams the output and lifecycle events of a run as server-sent events. Events are
+// numbered from 1 so clients can resume after the last event they received with Last-Event-ID.
+func (s *Server) handleEvents(w http.ResponseWriter, req *http.Request) {
+	run, ok := s.run(w, req)
+	if !ok {
+		return
+	}
+	offset := 0
+	if last := req.Header.Get("Last-Event-ID"); last != "" {
+		n, err := strconv.Atoi(last)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid Last-Event-ID %q", last))
+			return
+		}
+		offset = n
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	_ = run.log.events.Follow(req.Context(), offset, func(events []Event) error {
+		for _, ev := range events {
+			offset++
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", offset, ev.Type, ev.Data); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, req *http.Request) {
+	if !s.Cancel(req.PathValue("id")) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run %s not found", req.PathValue("id")))
+		return
+	}
+	run, _ := s.Get(req.PathValue("id"))
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+func (s *Server) handleDecide(approved bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		token, _ := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		err := s.Decide(req.PathValue("id"), approved, token)
+		switch {
+		case errors.Is(err, ErrRunNotFound):
+			writeError(w, http.StatusNotFound, err)
+			return
+		case errors.Is(err, ErrApprovalsDisabled):
+			writeError(w, http.StatusForbidden, err)
+			return
+		case errors.Is(err, ErrApprovalToken):
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		case err != nil:
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		run, _ := s.Get(req.PathValue("id"))
+		writeJSON(w, http.StatusAccepted, run)
+	}
+}
+
+// closedContext returns a done context, for reading a feed without waiting for more items
+func closedContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
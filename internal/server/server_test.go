@@ -0,0 +1,326 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+// testServer serves a workflow whose single step runs `deploy ${{ vars.tag }}`; block makes the step wait for cancellation
+func testServer(t *testing.T, block bool, opts ...Option) (*Server, *httptest.Server, func() [][]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var calls [][]string
+	newRunner := func(workflow string, opts ...runner.Option) (runner.Interface, error) {
+		r, err := runner.NewRunner(workflow, opts...)
+		if err != nil {
+			return nil, err
+		}
+		r.LoadWorkflow = func(string) (*dsl.Workflow, error) {
+			return &dsl.Workflow{Name: "release", Vars: map[string]string{"tag": "latest"}, Stages: []dsl.Stage{
+				{Name: "deploy", Steps: []dsl.Step{{Name: "push", Type: dsl.StepTypeExec, Run: []string{"deploy", "${{ vars.tag }}"}}}},
+			}}, nil
+		}
+		r.RunCmd = func(argv []string) error {
+			mu.Lock()
+			calls = append(calls, argv)
+			mu.Unlock()
+			if block {
+				<-r.Context.Done()
+				return r.Context.Err()
+			}
+			return nil
+		}
+		return r, nil
+	}
+	s := New(context.Background(), t.TempDir(), newRunner, opts...)
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return s, ts, func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(calls)
+	}
+}
+
+func do(t *testing.T, method, url, body string, v any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s: decoding response: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestServer_Run(t *testing.T) {
+	s, ts, calls := testServer(t, false)
+
+	var run Run
+	if code := do(t, http.MethodPost, ts.URL+"/runs", `{"workflow": "release.yaml", "inputs": {"tag": "v1.2.0"}}`, &run); code != http.StatusAccepted {
+		t.Fatalf("POST /runs = %d, want %d", code, http.StatusAccepted)
+	}
+	if run.ID == "" || run.Status != StatusRunning {
+		t.Errorf("POST /runs returned %+v", run)
+	}
+
+	resp, err := http.Get(ts.URL + "/runs/" + run.ID + "/logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(logs), "STEP 1.1: push (exec)") || !strings.Contains(string(logs), "Workflow execution completed") {
+		t.Errorf("logs missing the run's output:\n%s", logs)
+	}
+	s.Wait()
+
+	if code := do(t, http.MethodGet, ts.URL+"/runs/"+run.ID, "", &run); code != http.StatusOK {
+		t.Fatalf("GET /runs/{id} = %d", code)
+	}
+	if run.Status != StatusSucceeded || run.FinishedAt == nil {
+		t.Errorf("run = %+v, want succeeded", run)
+	}
+	if got := calls(); len(got) != 1 || !slices.Equal(got[0], []string{"deploy", "v1.2.0"}) {
+		t.Errorf("commands = %v, want [[deploy v1.2.0]]", got)
+	}
+
+	var runs []Run
+	if do(t, http.MethodGet, ts.URL+"/runs", "", &runs); len(runs) != 1 || runs[0].ID != run.ID {
+		t.Errorf("GET /runs = %+v", runs)
+	}
+}
+
+func TestServer_Cancel(t *testing.T) {
+	s, ts, _ := testServer(t, true)
+
+	var run Run
+	do(t, http.MethodPost, ts.URL+"/runs", `{"workflow": "release.yaml"}`, &run)
+	if code := do(t, http.MethodDelete, ts.URL+"/runs/"+run.ID, "", nil); code != http.StatusAccepted {
+		t.Fatalf("DELETE /runs/{id} = %d, want %d", code, http.StatusAccepted)
+	}
+	s.Wait()
+	if got, _ := s.Get(run.ID); got.Status != StatusCancelled {
+		t.Errorf("status = %s, want %s", got.Status, StatusCancelled)
+	}
+}
+
+func TestServer_Queue(t *testing.T) {
+	s, ts, calls := testServer(t, true, WithMaxRuns(2), WithMaxRunsPerWorkflow(1), WithMaxQueued(2))
+	start := func(body string, want Status, position int) *Run {
+		t.Helper()
+		var run Run
+		if code := do(t, http.MethodPost, ts.URL+"/runs", body, &run); code != http.StatusAccepted {
+			t.Fatalf("POST /runs %s = %d, want %d", body, code, http.StatusAccepted)
+		}
+		if run.Status != want || run.Position != position {
+			t.Errorf("POST /runs %s = %s at position %d, want %s at %d", body, run.Status, run.Position, want, position)
+		}
+		return &run
+	}
+	position := func(id string) int {
+		t.Helper()
+		var run Run
+		do(t, http.MethodGet, ts.URL+"/runs/"+id, "", &run)
+		return run.Position
+	}
+
+	first := start(`{"workflow": "release.yaml"}`, StatusRunning, 0)
+	queued := start(`{"workflow": "release.yaml"}`, StatusQueued, 1)
+	// Another workflow passes the run held back by the limit of its workflow
+	start(`{"workflow": "docs.yaml"}`, StatusRunning, 0)
+	urgent := start(`{"workflow": "release.yaml", "priority": 5}`, StatusQueued, 1)
+	if got := position(queued.ID); got != 2 {
+		t.Errorf("position of the run queued first = %d, want 2 behind the one with a higher priority", got)
+	}
+	if code := do(t, http.MethodPost, ts.URL+"/runs", `{"workflow": "lint.yaml"}`, nil); code != http.StatusServiceUnavailable {
+		t.Errorf("POST /runs with a full queue = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	do(t, http.MethodDelete, ts.URL+"/runs/"+queued.ID, "", nil)
+	do(t, http.MethodDelete, ts.URL+"/runs/"+first.ID, "", nil)
+	for deadline := time.Now().Add(5 * time.Second); len(calls()) < 3; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("the queued run did not start after a run finished, commands = %v", calls())
+		}
+	}
+	if got, _ := s.Get(urgent.ID); got.Status != StatusRunning || got.Position != 0 || got.StartedAt.IsZero() {
+		t.Errorf("run with a higher priority = %+v, want it running", got)
+	}
+	if got, _ := s.Get(queued.ID); got.Status != StatusCancelled || !got.StartedAt.IsZero() {
+		t.Errorf("run cancelled while queued = %+v, want cancelled without starting", got)
+	}
+
+	for _, run := range s.List() {
+		s.Cancel(run.ID)
+	}
+	s.Wait()
+	if got := len(calls()); got != 3 {
+		t.Errorf("%d runs ran a command, want 3", got)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	_, ts, _ := testServer(t, false)
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, "/runs", `{"workflow": "../outside.yaml"}`, http.StatusBadRequest},
+		{http.MethodPost, "/runs", `{"workflow": "/etc/forge.yaml"}`, http.StatusBadRequest},
+		{http.MethodPost, "/runs", `not json`, http.StatusBadRequest},
+		{http.MethodGet, "/runs/nope", "", http.StatusNotFound},
+		{http.MethodGet, "/runs/nope/logs", "", http.StatusNotFound},
+		{http.MethodDelete, "/runs/nope", "", http.StatusNotFound},
+		{http.MethodPost, "/runs/nope/approve", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if code := do(t, tt.method, ts.URL+tt.path, tt.body, nil); code != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, code, tt.want)
+		}
+	}
+}
+
+// decide posts to an approve or deny url with token as the bearer token
+func decide(t *testing.T, url, token string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestServer_Approve(t *testing.T) {
+	for _, approve := range []bool{true, false} {
+		var mu sync.Mutex
+		var calls [][]string
+		newRunner := func(workflow string, opts ...runner.Option) (runner.Interface, error) {
+			r, err := runner.NewRunner(workflow, opts...)
+			if err != nil {
+				return nil, err
+			}
+			r.LoadWorkflow = func(string) (*dsl.Workflow, error) {
+				return &dsl.Workflow{Name: "release", Stages: []dsl.Stage{{Name: "deploy", Steps: []dsl.Step{
+					{Name: "gate", Type: dsl.StepTypeApproval, Approval: &dsl.Approval{Message: "Ship it?"}},
+					{Name: "push", Type: dsl.StepTypeExec, Run: []string{"deploy"}},
+				}}}}, nil
+			}
+			r.RunCmd = func(argv []string) error {
+				mu.Lock()
+				calls = append(calls, argv)
+				mu.Unlock()
+				return nil
+			}
+			return r, nil
+		}
+		secret := []byte("s3cret")
+		s := New(context.Background(), t.TempDir(), newRunner, WithApprovalSecret(secret))
+		ts := httptest.NewServer(s.Handler())
+
+		var run Run
+		do(t, http.MethodPost, ts.URL+"/runs", `{"workflow": "release.yaml"}`, &run)
+		for run.Approval == nil {
+			if run.Status != StatusRunning {
+				t.Fatalf("run = %+v, want it to wait for approval", run)
+			}
+			time.Sleep(5 * time.Millisecond)
+			do(t, http.MethodGet, ts.URL+"/runs/"+run.ID, "", &run)
+		}
+		if run.Approval.Step != "gate" || run.Approval.Message != "Ship it?" {
+			t.Errorf("approval = %+v", run.Approval)
+		}
+
+		path, want, wantCalls := "/approve", StatusSucceeded, 1
+		if !approve {
+			path, want, wantCalls = "/deny", StatusFailed, 0
+		}
+		expires := time.Now().Add(time.Hour)
+		for name, token := range map[string]string{
+			"no token":          "",
+			"a wrong signature": runner.SignApprovalToken([]byte("other"), "release", "deploy.gate", "alice", expires),
+			"another step":      runner.SignApprovalToken(secret, "release", "deploy.push", "alice", expires),
+			"an expired token":  runner.SignApprovalToken(secret, "release", "deploy.gate", "alice", time.Now().Add(-time.Minute)),
+		} {
+			if code := decide(t, ts.URL+"/runs/"+run.ID+path, token); code != http.StatusUnauthorized {
+				t.Errorf("POST /runs/{id}%s with %s = %d, want %d", path, name, code, http.StatusUnauthorized)
+			}
+		}
+		token := runner.SignApprovalToken(secret, "release", "deploy.gate", "alice", expires)
+		if code := decide(t, ts.URL+"/runs/"+run.ID+path, token); code != http.StatusAccepted {
+			t.Fatalf("POST /runs/{id}%s = %d, want %d", path, code, http.StatusAccepted)
+		}
+		s.Wait()
+		got, _ := s.Get(run.ID)
+		if got.Status != want || got.Approval != nil {
+			t.Errorf("run after %s = %+v, want %s", path, got, want)
+		}
+		if !approve && !strings.Contains(got.Error, "denied by alice") {
+			t.Errorf("run error = %q, want it denied by alice", got.Error)
+		}
+		if approve {
+			resp, err := http.Get(ts.URL + "/runs/" + run.ID + "/logs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			logs, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if !strings.Contains(string(logs), "Approved by alice") {
+				t.Errorf("logs missing the approver:\n%s", logs)
+			}
+		}
+		if len(calls) != wantCalls {
+			t.Errorf("commands after %s = %v", path, calls)
+		}
+		if code := decide(t, ts.URL+"/runs/"+run.ID+path, token); code != http.StatusConflict {
+			t.Errorf("POST %s on a finished run = %d, want %d", path, code, http.StatusConflict)
+		}
+		ts.Close()
+	}
+}
+
+func TestServer_Decide_WithoutSecret(t *testing.T) {
+	s := New(context.Background(), t.TempDir(), nil)
+	s.runs["r1"] = &Run{
+		ID:       "r1",
+		Approval: &runner.ApprovalRequest{Workflow: "release", Stage: "deploy", Step: "gate"},
+		decide:   make(chan runner.Decision, 1),
+	}
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	token := runner.SignApprovalToken([]byte("s3cret"), "release", "deploy.gate", "alice", time.Now().Add(time.Hour))
+	if code := decide(t, ts.URL+"/runs/r1/approve", token); code != http.StatusForbidden {
+		t.Errorf("POST /runs/{id}/approve without a secret = %d, want %d", code, http.StatusForbidden)
+	}
+	if len(s.runs["r1"].decide) != 0 {
+		t.Error("run was decided without a secret")
+	}
+}
@@ -0,0 +1,22 @@
+package shell
+
+import "testing"
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		argv []string
+		want string
+	}{
+		{[]string{"go", "test", "./..."}, "go test ./..."},
+		{[]string{"echo", ""}, "echo ''"},
+		{[]string{"echo", "hello world"}, "echo 'hello world'"},
+		{[]string{"echo", "it's"}, `echo 'it'"'"'s'`},
+		{[]string{"sh", "-c", "echo $HOME; ls *"}, "sh -c 'echo $HOME; ls *'"},
+		{[]string{"FOO=bar", "deploy", "user@host:/srv"}, "FOO=bar deploy user@host:/srv"},
+	}
+	for _, tt := range tests {
+		if got := Join(tt.argv); got != tt.want {
+			t.Errorf("Join(%q) = %s, want %s", tt.argv, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package snapshot
+
+import "os"
+
+// cloneFile is not supported on this platform; files are copied instead
+func cloneFile(dst, src *os.File) bool {
+	return false
+}
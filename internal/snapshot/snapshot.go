@@ -0,0 +1,182 @@
+// Package snapshot copies directory trees aside and back so destructive steps can be undone.
+// Files are cloned with copy-on-write where the file system supports it and copied otherwise;
+// hardlinks are not used because in-place writes to the workspace would change the snapshot too.
+package snapshot
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Stats describes a copied tree
+type Stats struct {
+	Files  int
+	Bytes  int64
+	Cloned int // files copied with copy-on-write
+}
+
+func (s Stats) String() string {
+	msg := fmt.Sprintf("%d file(s), %s", s.Files, FormatBytes(s.Bytes))
+	if s.Cloned > 0 {
+		msg += fmt.Sprintf(", %d copy-on-write", s.Cloned)
+	}
+	return msg
+}
+
+// Take copies the tree at src into dst, which must not exist yet
+func Take(src, dst string) (Stats, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return Stats{}, err
+	}
+	if !info.IsDir() {
+		return Stats{}, fmt.Errorf("%s is not a directory", src)
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return Stats{}, fmt.Errorf("%s already exists", dst)
+	}
+	return copyTree(src, dst)
+}
+
+// Restore replaces the tree at dst with the snapshot in snap; a directory at dst is emptied
+// rather than removed, so dst may be "." or another process's working directory
+func Restore(snap, dst string) (Stats, error) {
+	if _, err := os.Stat(snap); err != nil {
+		return Stats{}, err
+	}
+	if err := clearTree(dst); err != nil {
+		return Stats{}, err
+	}
+	return copyTree(snap, dst)
+}
+
+// clearTree removes the contents of the directory at path, or the file at path, if it exists
+func clearTree(path string) error {
+	info, err := os.Lstat(path)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return err
+	case !info.IsDir():
+		return os.Remove(path)
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(path, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Copy copies the file or tree at src to dst, creating missing parent directories
+// and replacing files that already exist in dst
+func Copy(src, dst string) (Stats, error) {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return Stats{}, err
+	}
+	return copyTree(src, dst)
+}
+
+// copyTree copies the file or tree at src to dst, replacing existing files
+func copyTree(src, dst string) (Stats, error) {
+	var stats Stats
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0o700)
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := removeFile(target); err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			if err := removeFile(target); err != nil {
+				return err
+			}
+			cloned, err := copyFile(path, target, info.Mode().Perm())
+			if err != nil {
+				return err
+			}
+			stats.Files++
+			stats.Bytes += info.Size()
+			if cloned {
+				stats.Cloned++
+			}
+			return nil
+		default:
+			// Sockets, devices and pipes cannot be meaningfully restored
+			return nil
+		}
+	})
+	return stats, err
+}
+
+// removeFile removes the file or symlink at path if it exists
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// copyFile copies src to dst and reports whether the data was cloned copy-on-write
+func copyFile(src, dst string, perm fs.FileMode) (bool, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return false, err
+	}
+
+	cloned := cloneFile(out, in)
+	if !cloned {
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return false, err
+		}
+	}
+	return cloned, out.Close()
+}
+
+// FormatBytes renders a byte count with a binary unit
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
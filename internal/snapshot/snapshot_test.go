@@ -0,0 +1,138 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll() error: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile() error: %v", err)
+		}
+	}
+}
+
+func TestTakeAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	work := filepath.Join(dir, "work")
+	snap := filepath.Join(dir, "snap")
+	writeTree(t, work, map[string]string{"a.txt": "alpha", "sub/b.txt": "beta"})
+	if err := os.Symlink("a.txt", filepath.Join(work, "link")); err != nil {
+		t.Fatalf("Symlink() error: %v", err)
+	}
+
+	stats, err := Take(work, snap)
+	if err != nil {
+		t.Fatalf("Take() error: %v", err)
+	}
+	if stats.Files != 2 || stats.Bytes != 9 {
+		t.Errorf("Take() stats = %+v, want 2 files and 9 bytes", stats)
+	}
+
+	// Mutate the workspace destructively
+	if err := os.WriteFile(filepath.Join(work, "a.txt"), []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(work, "sub")); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, work, map[string]string{"junk.txt": "junk"})
+
+	if _, err := Restore(snap, work); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+
+	for name, want := range map[string]string{"a.txt": "alpha", "sub/b.txt": "beta", "link": "alpha"} {
+		got, err := os.ReadFile(filepath.Join(work, name))
+		if err != nil {
+			t.Errorf("ReadFile(%s) error: %v", name, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(work, "junk.txt")); !os.IsNotExist(err) {
+		t.Error("Restore() kept a file created after the snapshot")
+	}
+}
+
+func TestRestore_WorkingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	work := filepath.Join(dir, "work")
+	snap := filepath.Join(dir, "snap")
+	writeTree(t, work, map[string]string{"a.txt": "alpha"})
+	t.Chdir(work)
+	if _, err := Take(".", snap); err != nil {
+		t.Fatalf("Take() error: %v", err)
+	}
+	writeTree(t, ".", map[string]string{"a.txt": "changed", "junk/b.txt": "junk"})
+
+	if _, err := Restore(snap, "."); err != nil {
+		t.Fatalf("Restore(.) error: %v", err)
+	}
+	if got, err := os.ReadFile("a.txt"); err != nil || string(got) != "alpha" {
+		t.Errorf("a.txt = %q, %v, want alpha", got, err)
+	}
+	if _, err := os.Stat("junk"); !os.IsNotExist(err) {
+		t.Error("Restore(.) kept a directory created after the snapshot")
+	}
+}
+
+func TestCopy(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, filepath.Join(dir, "src"), map[string]string{"a.txt": "new", "sub/b.txt": "beta"})
+	writeTree(t, filepath.Join(dir, "dst"), map[string]string{"a.txt": "old", "keep.txt": "kept"})
+
+	stats, err := Copy(filepath.Join(dir, "src"), filepath.Join(dir, "dst"))
+	if err != nil {
+		t.Fatalf("Copy() error: %v", err)
+	}
+	if stats.Files != 2 {
+		t.Errorf("Copy() copied %d files, want 2", stats.Files)
+	}
+	for name, want := range map[string]string{"a.txt": "new", "sub/b.txt": "beta", "keep.txt": "kept"} {
+		if got, _ := os.ReadFile(filepath.Join(dir, "dst", name)); string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	if _, err := Copy(filepath.Join(dir, "src", "a.txt"), filepath.Join(dir, "single", "deep", "a.txt")); err != nil {
+		t.Fatalf("Copy() of a file error: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "single", "deep", "a.txt")); string(got) != "new" {
+		t.Errorf("copied file = %q, want new", got)
+	}
+}
+
+func TestTake_Errors(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	writeTree(t, dir, map[string]string{"file": "x"})
+
+	if _, err := Take(filepath.Join(dir, "missing"), filepath.Join(dir, "s1")); err == nil {
+		t.Error("Take() of a missing directory succeeded")
+	}
+	if _, err := Take(file, filepath.Join(dir, "s2")); err == nil {
+		t.Error("Take() of a file succeeded")
+	}
+	if _, err := Take(dir, file); err == nil {
+		t.Error("Take() into an existing path succeeded")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 5 << 20: "5.0 MiB"}
+	for n, want := range tests {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
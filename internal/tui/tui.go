@@ -0,0 +1,249 @@
+// Package tui renders a run as a live terminal dashboard: its stages and steps with spinners and
+// elapsed times, a scrolling pane with the output of the running step and a final summary.
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+// refresh is how often the dashboard is redrawn while the run goes on
+const refresh = 100 * time.Millisecond
+
+var spinner = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// unit is a stage, step or nested unit of the run, see runner.Event
+type unit struct {
+	path    string
+	name    string
+	depth   int
+	step    bool
+	started time.Time
+	ended   time.Time
+	done    bool
+	err     string
+}
+
+// Dashboard draws the run on a terminal. It receives the runner's events and output through
+// Options and is an io.Writer for that output.
+type Dashboard struct {
+	// Width is the number of columns of the terminal; longer lines are cut
+	Width int
+	// OutputLines is the height of the output pane
+	OutputLines int
+	// Now returns the current time, time.Now when nil
+	Now func() time.Time
+
+	out      io.Writer
+	mu       sync.Mutex
+	workflow *unit
+	units    []*unit
+	byPath   map[string]*unit
+	active   *unit
+	lines    []string
+	partial  []byte
+	frame    int
+	drawn    int
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// New returns a dashboard drawing on out, which should be a terminal
+func New(out io.Writer) *Dashboard {
+	return &Dashboard{Width: 80, OutputLines: 10, out: out, byPath: make(map[string]*unit)}
+}
+
+// Options routes the events and all output of a runner into the dashboard
+func (d *Dashboard) Options() []runner.Option {
+	return []runner.Option{runner.WithEvents(d.Event), runner.WithOut(d), runner.WithProcessOutput(d, d)}
+}
+
+func (d *Dashboard) now() time.Time {
+	if d.Now != nil {
+		return d.Now()
+	}
+	return time.Now()
+}
+
+// Start hides the cursor and redraws the dashboard until Stop is called
+func (d *Dashboard) Start() {
+	d.stop, d.stopped = make(chan struct{}), make(chan struct{})
+	io.WriteString(d.out, "\x1b[?25l")
+	go func() {
+		defer close(d.stopped)
+		ticker := time.NewTicker(refresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+				d.mu.Lock()
+				d.frame++
+				d.render(false)
+				d.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// Stop draws the final state of the run with a summary and shows the cursor again
+func (d *Dashboard) Stop() {
+	if d.stop != nil {
+		close(d.stop)
+		<-d.stopped
+		d.stop = nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.render(true)
+	io.WriteString(d.out, "\x1b[?25h")
+}
+
+// Event records a lifecycle event of the run
+func (d *Dashboard) Event(ev runner.Event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch ev.Kind {
+	case runner.EventWorkflowStart, runner.EventStageStart, runner.EventStepStart:
+		u := &unit{path: ev.Path, name: ev.Name, depth: ev.Depth, step: ev.Kind == runner.EventStepStart, started: ev.Time}
+		switch {
+		case ev.Kind == runner.EventWorkflowStart && ev.Depth == 0:
+			d.workflow = u
+			return
+		case d.byPath[ev.Path] != nil:
+			// A retried stage or step starts over in place
+			*d.byPath[ev.Path] = *u
+			u = d.byPath[ev.Path]
+		default:
+			d.byPath[ev.Path] = u
+			d.units = append(d.units, u)
+		}
+		if u.step {
+			d.active, d.lines, d.partial = u, nil, nil
+		}
+	case runner.EventWorkflowEnd, runner.EventStageEnd, runner.EventStepEnd:
+		u := d.byPath[ev.Path]
+		if ev.Kind == runner.EventWorkflowEnd && ev.Depth == 0 {
+			u = d.workflow
+		}
+		if u != nil {
+			u.ended, u.done, u.err = ev.Time, true, ev.Error
+		}
+	}
+}
+
+// Write adds output of the run to the pane, which keeps the last OutputLines lines of the running step
+func (d *Dashboard) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.partial = append(d.partial, p...)
+	for {
+		i := bytes.IndexByte(d.partial, '\n')
+		if i < 0 {
+			break
+		}
+		d.lines = append(d.lines, strings.TrimRight(string(d.partial[:i]), "\r"))
+		d.partial = d.partial[i+1:]
+	}
+	if n := len(d.lines) - d.OutputLines; n > 0 {
+		d.lines = d.lines[n:]
+	}
+	return len(p), nil
+}
+
+// render redraws the dashboard over the previous frame; the final frame has a summary instead of the output pane
+func (d *Dashboard) render(final bool) {
+	var b strings.Builder
+	if d.drawn > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", d.drawn)
+	}
+	b.WriteString("\r\x1b[J")
+	lines := d.frameLines(final)
+	for _, line := range lines {
+		b.WriteString(truncate(line, d.Width))
+		b.WriteByte('\n')
+	}
+	d.drawn = len(lines)
+	io.WriteString(d.out, b.String())
+}
+
+func (d *Dashboard) frameLines(final bool) []string {
+	var lines []string
+	if d.workflow != nil {
+		lines = append(lines, fmt.Sprintf("%s forge › %s  %s", d.icon(d.workflow, final), d.workflow.name, d.elapsed(d.workflow)))
+	}
+	for _, u := range d.units {
+		lines = append(lines, fmt.Sprintf("%s%s %s  %s", strings.Repeat("  ", u.depth), d.icon(u, final), u.name, d.elapsed(u)))
+	}
+
+	if final && d.workflow != nil {
+		return append(lines, "", d.summary())
+	}
+	if !final && d.active != nil && !d.active.done {
+		title := strings.Join(runner.SplitPath(d.active.path), " › ")
+		lines = append(lines, "── output: "+title+" "+strings.Repeat("─", max(0, d.Width-len(title)-12)))
+		for _, line := range d.lines {
+			lines = append(lines, "│ "+line)
+		}
+		if len(d.partial) > 0 && len(d.lines) < d.OutputLines {
+			lines = append(lines, "│ "+string(d.partial))
+		}
+	}
+	return lines
+}
+
+func (d *Dashboard) icon(u *unit, final bool) string {
+	switch {
+	case u.done && u.err != "":
+		return "✗"
+	case u.done:
+		return "✓"
+	case final:
+		return "-"
+	default:
+		return spinner[d.frame%len(spinner)]
+	}
+}
+
+func (d *Dashboard) elapsed(u *unit) string {
+	end := u.ended
+	if !u.done {
+		end = d.now()
+	}
+	return end.Sub(u.started).Round(100 * time.Millisecond).String()
+}
+
+// summary counts the steps of the run by outcome
+func (d *Dashboard) summary() string {
+	var succeeded, failed int
+	for _, u := range d.units {
+		switch {
+		case !u.step || !u.done:
+		case u.err != "":
+			failed++
+		default:
+			succeeded++
+		}
+	}
+	status := "Run succeeded"
+	if d.workflow.err != "" {
+		status = "Run failed"
+	}
+	return fmt.Sprintf("%s: %d step(s) succeeded, %d failed in %s", status, succeeded, failed, d.elapsed(d.workflow))
+}
+
+// truncate cuts s to width runes
+func truncate(s string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width-1]) + "…"
+}
@@ -0,0 +1,96 @@
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andre-koe/forge/internal/dsl"
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+func TestDashboard(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	var out bytes.Buffer
+	d := New(&out)
+	d.Now = func() time.Time { return now }
+	at := func(seconds int) time.Time { return now.Add(-time.Duration(seconds) * time.Second) }
+
+	d.Event(runner.Event{Kind: runner.EventWorkflowStart, Name: "ci", Time: at(5)})
+	d.Event(runner.Event{Kind: runner.EventStageStart, Path: "build", Depth: 1, Name: "build", Time: at(5)})
+	d.Event(runner.Event{Kind: runner.EventStepStart, Path: "build/compile", Depth: 2, Name: "compile", Time: at(5)})
+	fmt.Fprint(d, "old line\n")
+	d.Event(runner.Event{Kind: runner.EventStepEnd, Path: "build/compile", Depth: 2, Name: "compile", Time: at(3)})
+	d.Event(runner.Event{Kind: runner.EventStepStart, Path: "build/test", Depth: 2, Name: "test", Time: at(3)})
+	fmt.Fprint(d, "=== RUN TestA\r\n--- PASS: TestA\nok  ")
+
+	d.render(false)
+	for _, want := range []string{"forge › ci  5s", "  ⠋ build  5s", "    ✓ compile  2s", "    ⠋ test  3s", "── output: build › test", "│ --- PASS: TestA", "│ ok  "} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("frame does not contain %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "old line") {
+		t.Errorf("output pane shows the output of a finished step:\n%s", out.String())
+	}
+
+	out.Reset()
+	d.Event(runner.Event{Kind: runner.EventStepEnd, Path: "build/test", Depth: 2, Name: "test", Time: now, Error: "exit status 1"})
+	d.Event(runner.Event{Kind: runner.EventStageEnd, Path: "build", Depth: 1, Name: "build", Time: now, Error: "exit status 1"})
+	d.Event(runner.Event{Kind: runner.EventWorkflowEnd, Name: "ci", Time: now, Error: "exit status 1"})
+	d.Stop()
+	if !strings.HasPrefix(out.String(), "\x1b[8A\r\x1b[J") {
+		t.Errorf("final frame does not replace the previous one: %q", out.String())
+	}
+	for _, want := range []string{"✗ forge › ci", "    ✗ test  3s", "Run failed: 1 step(s) succeeded, 1 failed in 5s"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("final frame does not contain %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "output:") {
+		t.Errorf("final frame shows the output pane:\n%s", out.String())
+	}
+}
+
+func TestDashboard_Run(t *testing.T) {
+	stages := []dsl.Stage{{Name: "build", Steps: []dsl.Step{{Name: "compile", Type: dsl.StepTypeExec, Run: []string{"go", "build"}}}}}
+	load := func(string) (*dsl.Workflow, error) { return &dsl.Workflow{Name: "ci", Stages: stages}, nil }
+	var out bytes.Buffer
+	d := New(&out)
+	runCmd := func(argv []string) error {
+		fmt.Fprintln(d, "compiling")
+		return nil
+	}
+	r, err := runner.NewRunner("ci.yaml", append(d.Options(), runner.WithLoadWorkflow(load), runner.WithRunCmd(runCmd))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	d.Stop()
+	for _, want := range []string{"✓ forge › ci", "    ✓ compile", "Run succeeded: 1 step(s) succeeded, 0 failed"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("final frame does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{s: "short", width: 10, want: "short"},
+		{s: "› longer text", width: 6, want: "› lon…"},
+		{s: "any", width: 0, want: "any"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.width); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Package watch reports batches of files changed below a directory, debounced so that
+// one save or checkout results in one batch.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/andre-koe/forge/internal/glob"
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is how long a watcher waits for further changes before reporting a batch
+const DefaultDebounce = 300 * time.Millisecond
+
+// ignoredDirs are never watched; forge itself writes its run history, artifacts and cache below .forge
+var ignoredDirs = []string{".git", ".forge"}
+
+// Watcher watches a directory tree recursively, including directories created later
+type Watcher struct {
+	root     string
+	patterns []string
+	debounce time.Duration
+	fs       *fsnotify.Watcher
+}
+
+// New watches root for changes to files matching one of the glob patterns, relative to root;
+// all files match when no patterns are given
+func New(root string, patterns []string, debounce time.Duration) (*Watcher, error) {
+	for _, p := range patterns {
+		if err := glob.Validate(p); err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", p, err)
+		}
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{root: root, patterns: patterns, debounce: debounce, fs: fsw}
+	if err := w.addTree(root); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Close stops watching
+func (w *Watcher) Close() error {
+	return w.fs.Close()
+}
+
+// Run sends the sorted, slash-separated paths relative to the root of the files changed within
+// one debounce interval until ctx is done or watching fails
+func (w *Watcher) Run(ctx context.Context, changes chan<- []string) error {
+	pending := map[string]bool{}
+	var flush <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case ev, ok := <-w.fs.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+			rel, ok := w.relevant(ev.Name)
+			if ev.Has(fsnotify.Create) {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() && !ignored(rel) {
+					// Files created together with the directory may be missed; the directory itself is reported
+					if err := w.addTree(ev.Name); err != nil {
+						return err
+					}
+				}
+			}
+			if ok {
+				pending[rel] = true
+				flush = time.After(w.debounce)
+			}
+		case <-flush:
+			batch := make([]string, 0, len(pending))
+			for name := range pending {
+				batch = append(batch, name)
+			}
+			slices.Sort(batch)
+			clear(pending)
+			flush = nil
+			select {
+			case changes <- batch:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// relevant returns the path of name relative to the root and whether changes to it are reported
+func (w *Watcher) relevant(name string) (string, bool) {
+	rel, err := filepath.Rel(w.root, name)
+	if err != nil {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	if ignored(rel) {
+		return rel, false
+	}
+	return rel, len(w.patterns) == 0 || glob.MatchAny(w.patterns, rel)
+}
+
+func (w *Watcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != w.root && slices.Contains(ignoredDirs, d.Name()) {
+			return filepath.SkipDir
+		}
+		return w.fs.Add(path)
+	})
+}
+
+// ignored reports whether the slash-separated relative path lies in an ignored directory
+func ignored(rel string) bool {
+	first, _, _ := strings.Cut(rel, "/")
+	return slices.Contains(ignoredDirs, first)
+}
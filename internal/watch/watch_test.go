@@ -0,0 +1,71 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"src", ".forge/history"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := New(root, []string{"src/**", "go.mod"}, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan []string)
+	go w.Run(ctx, changes)
+
+	write := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, name), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("src/a.go")
+	write("src/b.go")
+	write("README.md")
+	write(".forge/history/run.json")
+
+	select {
+	case batch := <-changes:
+		if !slices.Equal(batch, []string{"src/a.go", "src/b.go"}) {
+			t.Errorf("batch = %v, want [src/a.go src/b.go]", batch)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no changes reported")
+	}
+
+	// Directories created after the watcher started are watched too
+	if err := os.Mkdir(filepath.Join(root, "src", "pkg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	<-changes
+	write("src/pkg/c.go")
+	select {
+	case batch := <-changes:
+		if !slices.Contains(batch, "src/pkg/c.go") {
+			t.Errorf("batch = %v, want src/pkg/c.go", batch)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("change in new directory not reported")
+	}
+}
+
+func TestNew_InvalidPattern(t *testing.T) {
+	if _, err := New(t.TempDir(), []string{"src/[a-"}, DefaultDebounce); err == nil {
+		t.Error("New() accepted a malformed pattern")
+	}
+}
@@ -0,0 +1,309 @@
+// Package wftest tests workflows against fixtures: the commands of a run are answered by stubs
+// instead of being executed, and the run is checked against what a test case expects of it.
+package wftest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/runner"
+	yaml "github.com/goccy/go-yaml"
+)
+
+// ErrFixtures is returned for fixtures files that cannot be used
+var ErrFixtures = errors.New("invalid test fixtures")
+
+// Start is the time of the virtual clock runs under test start at
+var Start = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Suite holds the test cases of a workflow
+type Suite struct {
+	Tests []Case `yaml:"tests"`
+}
+
+// Case runs the workflow once and describes what the run has to do
+type Case struct {
+	Name string `yaml:"name"`
+	// Vars and Env are passed to the run like --var and --env
+	Vars map[string]string `yaml:"vars,omitempty"`
+	Env  map[string]string `yaml:"env,omitempty"`
+	// Profile selects a workflow profile like --profile
+	Profile string `yaml:"profile,omitempty"`
+	// Changed lists the files stages with changes filters see as changed
+	Changed []string `yaml:"changed,omitempty"`
+	// Commands are the commands the run has to execute, in order, with the answers of their stubs
+	Commands []Command `yaml:"commands,omitempty"`
+	// Skipped lists steps, as stage.step, the run has to skip
+	Skipped []string `yaml:"skipped,omitempty"`
+	// Outputs holds step outputs the run has to produce, keyed by step name
+	Outputs map[string]map[string]string `yaml:"outputs,omitempty"`
+	// Status is the outcome the run has to have, success when empty
+	Status history.Status `yaml:"status,omitempty"`
+}
+
+// Command is an expected command and the answer of its stub
+type Command struct {
+	// Run is the command line after interpolation
+	Run      []string `yaml:"run"`
+	Stdout   string   `yaml:"stdout,omitempty"`
+	Stderr   string   `yaml:"stderr,omitempty"`
+	ExitCode int      `yaml:"exit_code,omitempty"`
+}
+
+// FixturesPath returns the fixtures file next to a workflow, e.g. deploy.test.yml for deploy.yml
+func FixturesPath(workflow string) string {
+	ext := filepath.Ext(workflow)
+	return strings.TrimSuffix(workflow, ext) + ".test" + ext
+}
+
+// Load reads a fixtures file
+func Load(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Suite
+	if err := yaml.UnmarshalWithOptions(data, &s, yaml.Strict()); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrFixtures, path, err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Validate checks that the suite has uniquely named tests with well-formed expectations
+func (s *Suite) Validate() error {
+	if len(s.Tests) == 0 {
+		return fmt.Errorf("%w: no tests", ErrFixtures)
+	}
+	seen := make(map[string]bool)
+	for i, c := range s.Tests {
+		switch {
+		case c.Name == "":
+			return fmt.Errorf("%w: test %d has no name", ErrFixtures, i+1)
+		case seen[c.Name]:
+			return fmt.Errorf("%w: duplicate test %q", ErrFixtures, c.Name)
+		case c.Status != "" && c.Status != history.StatusSuccess && c.Status != history.StatusFailed:
+			return fmt.Errorf("%w: test %q: status must be success or failed, got %q", ErrFixtures, c.Name, c.Status)
+		}
+		seen[c.Name] = true
+		for j, cmd := range c.Commands {
+			if len(cmd.Run) == 0 {
+				return fmt.Errorf("%w: test %q: command %d has no run", ErrFixtures, c.Name, j+1)
+			}
+			if cmd.ExitCode < 0 {
+				return fmt.Errorf("%w: test %q: command %d has a negative exit_code", ErrFixtures, c.Name, j+1)
+			}
+		}
+		for _, step := range c.Skipped {
+			if stage, name, ok := strings.Cut(step, "."); !ok || stage == "" || name == "" {
+				return fmt.Errorf("%w: test %q: skipped step %q must be stage.step", ErrFixtures, c.Name, step)
+			}
+		}
+	}
+	return nil
+}
+
+// Result is the outcome of a test case
+type Result struct {
+	Name string
+	// Failures tell how the run differed from the expectations; none means the case passed
+	Failures []string
+	// Output is what the run printed
+	Output string
+}
+
+// Passed reports whether the run met all expectations
+func (r *Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+func (r *Result) fail(format string, args ...any) {
+	r.Failures = append(r.Failures, fmt.Sprintf(format, args...))
+}
+
+// Run runs the workflow with newRunner and checks the run against the case; opts come after
+// the harness's own options. Commands are answered by the stubs, waits pass at once on a
+// virtual clock starting at Start, notifications are not sent and approval steps are approved.
+// Other steps, e.g. snapshots, run as usual. The error is for runs that could not start.
+func (c *Case) Run(workflow string, newRunner runner.Factory, opts ...runner.Option) (*Result, error) {
+	res := &Result{Name: c.Name}
+	var mu sync.Mutex
+	next := 0
+	// unexpected is set when a command had no stub, which fails the run for a reason already reported
+	unexpected := false
+	stub := func(argv []string, stdout, stderr io.Writer) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if next == len(c.Commands) {
+			unexpected = true
+			res.fail("unexpected command %q", argv)
+			return fmt.Errorf("no stub for command %q", argv)
+		}
+		want := c.Commands[next]
+		next++
+		if !slices.Equal(argv, want.Run) {
+			unexpected = true
+			res.fail("command %d is %q, want %q", next, argv, want.Run)
+			return fmt.Errorf("no stub for command %q", argv)
+		}
+		io.WriteString(stdout, want.Stdout)
+		io.WriteString(stderr, want.Stderr)
+		if want.ExitCode != 0 {
+			return exitError(want.ExitCode)
+		}
+		return nil
+	}
+
+	var out bytes.Buffer
+	all := []runner.Option{
+		runner.WithOut(&out),
+		runner.WithCommandFunc(stub),
+		runner.WithCmdOutput(func(argv []string) ([]byte, error) {
+			var stdout bytes.Buffer
+			err := stub(argv, &stdout, io.Discard)
+			return stdout.Bytes(), err
+		}),
+		runner.WithClock(&virtualClock{now: Start}),
+		runner.WithRandom(func() float64 { return 0 }),
+		runner.WithHTTPClient(&http.Client{Transport: discardTransport{}}),
+		runner.WithApprovers(approve),
+		runner.WithPreflight(nil),
+		runner.WithHistory(nil),
+		runner.WithCache(nil),
+		runner.WithArtifacts(nil),
+		runner.WithActive(nil),
+		runner.WithVars(c.Vars),
+		runner.WithEnv(c.Env),
+	}
+	if c.Changed != nil {
+		all = append(all, runner.WithChangedFiles(c.Changed))
+	}
+	if c.Profile != "" {
+		all = append(all, runner.WithProfile(c.Profile))
+	}
+	r, err := newRunner(workflow, append(all, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	runErr := r.Run()
+	results := r.Results()
+	res.Output = out.String()
+	if results.Record == nil {
+		return nil, runErr
+	}
+
+	status := history.StatusSuccess
+	if c.Status != "" {
+		status = c.Status
+	}
+	if results.Record.Status != status && !unexpected {
+		msg := fmt.Sprintf("run %s, want %s", results.Record.Status, status)
+		if runErr != nil {
+			msg += ": " + runErr.Error()
+		}
+		res.fail("%s", msg)
+	}
+	for _, cmd := range c.Commands[next:] {
+		res.fail("command %q did not run", cmd.Run)
+	}
+	for _, want := range c.Skipped {
+		i := slices.IndexFunc(results.Record.Steps, func(s history.StepRecord) bool { return s.Stage+"."+s.Step == want })
+		switch {
+		case i < 0:
+			res.fail("step %s was not reached", want)
+		case results.Record.Steps[i].Status != history.StatusSkipped:
+			res.fail("step %s %s, want skipped", want, results.Record.Steps[i].Status)
+		}
+	}
+	for _, step := range slices.Sorted(maps.Keys(c.Outputs)) {
+		for _, key := range slices.Sorted(maps.Keys(c.Outputs[step])) {
+			got, ok := results.Outputs[step][key]
+			want := c.Outputs[step][key]
+			switch {
+			case !ok:
+				res.fail("step %s has no output %s, want %q", step, key, want)
+			case got != want:
+				res.fail("output %s of step %s is %q, want %q", key, step, got, want)
+			}
+		}
+	}
+	return res, nil
+}
+
+// Run runs all test cases of the suite in order
+func (s *Suite) Run(workflow string, newRunner runner.Factory, opts ...runner.Option) ([]*Result, error) {
+	var results []*Result
+	for _, c := range s.Tests {
+		res, err := c.Run(workflow, newRunner, opts...)
+		if err != nil {
+			return results, fmt.Errorf("test %q: %w", c.Name, err)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// exitError is the error of a stubbed command with a non-zero exit code
+type exitError int
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+
+// ExitCode tells the exit code to assert steps, like exec.ExitError
+func (e exitError) ExitCode() int { return int(e) }
+
+// approve approves every approval step at once
+func approve(context.Context, runner.ApprovalRequest) (runner.Decision, error) {
+	return runner.Decision{Approved: true, By: "forge test"}, nil
+}
+
+// discardTransport answers every request with an empty 200 response without sending it
+type discardTransport struct{}
+
+func (discardTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Body:       io.NopCloser(strings.NewReader("")),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+// virtualClock passes waits at once and moves its time forward by what was waited for
+type virtualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *virtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *virtualClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(max(d, 0))
+}
+
+func (c *virtualClock) After(d time.Duration) <-chan time.Time {
+	c.Sleep(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
@@ -0,0 +1,188 @@
+package wftest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andre-koe/forge/internal/history"
+	"github.com/andre-koe/forge/internal/runner"
+)
+
+const workflow = `name: release
+vars:
+  tag: dev
+stages:
+  - name: build
+    steps:
+      - name: version
+        id: version
+        type: exec
+        run: ["git", "describe"]
+      - name: compile
+        type: exec
+        run: ["go", "build", "-ldflags", "-X main.version=${{ steps.version.output }}"]
+      - name: cool-down
+        type: sleep
+        seconds: 3600
+      - name: gate
+        type: approval
+      - name: publish
+        type: exec
+        when: vars.tag != 'dev'
+        run: ["publish", "${{ vars.tag }}"]
+`
+
+func writeWorkflow(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "release.yml")
+	if err := os.WriteFile(path, []byte(workflow), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFixturesPath(t *testing.T) {
+	tests := map[string]string{
+		"deploy.yml":        "deploy.test.yml",
+		"ci/release.yaml":   "ci/release.test.yaml",
+		"workflows/no-ext":  "workflows/no-ext.test",
+		"dir.v2/build.yaml": "dir.v2/build.test.yaml",
+	}
+	for in, want := range tests {
+		if got := FixturesPath(in); got != want {
+			t.Errorf("FixturesPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "valid", content: "tests:\n  - name: ok\n    commands:\n      - run: [make]\n        exit_code: 2\n    status: failed\n"},
+		{name: "empty", content: "tests: []\n", wantErr: "no tests"},
+		{name: "unknown key", content: "tests:\n  - name: ok\n    stdout: x\n", wantErr: "unknown field"},
+		{name: "no name", content: "tests:\n  - status: success\n", wantErr: "test 1 has no name"},
+		{name: "duplicate", content: "tests:\n  - name: a\n  - name: a\n", wantErr: `duplicate test "a"`},
+		{name: "status", content: "tests:\n  - name: a\n    status: skipped\n", wantErr: "status must be success or failed"},
+		{name: "no run", content: "tests:\n  - name: a\n    commands:\n      - stdout: x\n", wantErr: "command 1 has no run"},
+		{name: "exit code", content: "tests:\n  - name: a\n    commands:\n      - run: [x]\n        exit_code: -1\n", wantErr: "negative exit_code"},
+		{name: "skipped", content: "tests:\n  - name: a\n    skipped: [publish]\n", wantErr: "must be stage.step"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "release.test.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load() error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrFixtures) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCase_Run(t *testing.T) {
+	version := Command{Run: []string{"git", "describe"}, Stdout: "v1.2.0\n"}
+	compile := Command{Run: []string{"go", "build", "-ldflags", "-X main.version=v1.2.0"}}
+
+	tests := []struct {
+		name         string
+		c            Case
+		wantFailures []string
+	}{
+		{
+			name: "dev build skips publish",
+			c:    Case{Commands: []Command{version, compile}, Skipped: []string{"build.publish"}},
+		},
+		{
+			name: "release publishes the tag",
+			c: Case{
+				Vars:     map[string]string{"tag": "v1.2.0"},
+				Commands: []Command{version, compile, {Run: []string{"publish", "v1.2.0"}}},
+			},
+		},
+		{
+			name: "failing compile",
+			c: Case{
+				Commands: []Command{version, {Run: compile.Run, Stderr: "syntax error\n", ExitCode: 1}},
+				Skipped:  []string{"build.publish"},
+				Status:   history.StatusFailed,
+			},
+			wantFailures: []string{"step build.publish was not reached"},
+		},
+		{
+			name: "wrong command",
+			c:    Case{Commands: []Command{version, {Run: []string{"go", "build"}}}},
+			wantFailures: []string{
+				`command 2 is ["go" "build" "-ldflags" "-X main.version=v1.2.0"], want ["go" "build"]`,
+			},
+		},
+		{
+			name: "missing and unexpected commands",
+			c:    Case{Commands: []Command{version}, Outputs: map[string]map[string]string{"compile": {"binary": "forge"}}},
+			wantFailures: []string{
+				`unexpected command ["go" "build" "-ldflags" "-X main.version=v1.2.0"]`,
+				`step compile has no output binary, want "forge"`,
+			},
+		},
+		{
+			name: "publish expected but skipped",
+			c: Case{
+				Commands: []Command{version, compile, {Run: []string{"publish", "dev"}}},
+			},
+			wantFailures: []string{`command ["publish" "dev"] did not run`},
+		},
+	}
+
+	path := writeWorkflow(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.c.Name = tt.name
+			res, err := tt.c.Run(path, runner.New)
+			if err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			if !reflect.DeepEqual(res.Failures, tt.wantFailures) {
+				t.Errorf("failures = %q, want %q\noutput:\n%s", res.Failures, tt.wantFailures, res.Output)
+			}
+			if res.Passed() != (len(tt.wantFailures) == 0) {
+				t.Errorf("Passed() = %v with failures %q", res.Passed(), res.Failures)
+			}
+		})
+	}
+}
+
+func TestCase_Run_CannotStart(t *testing.T) {
+	c := Case{Name: "unknown var", Vars: map[string]string{"missing": "x"}}
+	if _, err := c.Run(writeWorkflow(t), runner.New); err == nil || !strings.Contains(err.Error(), `unknown variable "missing"`) {
+		t.Errorf("Run() error = %v, want the unknown variable", err)
+	}
+}
+
+func TestSuite_Run(t *testing.T) {
+	s := &Suite{Tests: []Case{
+		{Name: "first", Commands: []Command{{Run: []string{"git", "describe"}, Stdout: "v1"}, {Run: []string{"go", "build", "-ldflags", "-X main.version=v1"}}}},
+		{Name: "second"},
+	}}
+	results, err := s.Run(writeWorkflow(t), runner.New)
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if len(results) != 2 || !results[0].Passed() || results[1].Passed() {
+		t.Errorf("results = %+v, want the first to pass and the second to fail", results)
+	}
+}
@@ -0,0 +1,160 @@
+// Package window parses schedule windows such as "Mon-Fri 09:00-17:00 Europe/Berlin" and tells
+// whether a time falls into one and when the next one opens.
+package window
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrSyntax is returned for malformed schedule windows
+var ErrSyntax = errors.New("invalid schedule window")
+
+var dayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Window is a daily time range on some days of the week, in a timezone
+type Window struct {
+	// days holds the weekdays the window opens on, indexed by time.Weekday
+	days [7]bool
+	// start and end are minutes since midnight; a window with end <= start closes the next day
+	start, end int
+	// loc is the timezone of the window, the timezone of the time asked about when nil
+	loc  *time.Location
+	spec string
+}
+
+// Parse parses a window of the form "[days] HH:MM-HH:MM [timezone]". Days are names such as Mon
+// or Monday, ranges such as Mon-Fri and lists of both, e.g. "Mon-Wed,Fri"; every day when left
+// out. A range ending before it starts, e.g. 22:00-06:00, spans midnight.
+func Parse(spec string) (*Window, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 3 {
+		return nil, fmt.Errorf("%w: %q: want \"[days] HH:MM-HH:MM [timezone]\"", ErrSyntax, spec)
+	}
+	w := &Window{spec: strings.Join(fields, " ")}
+
+	i := 0
+	if !strings.Contains(fields[0], ":") {
+		if err := w.parseDays(fields[0]); err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrSyntax, spec, err)
+		}
+		i++
+	} else {
+		w.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	if i == len(fields) {
+		return nil, fmt.Errorf("%w: %q: missing time range HH:MM-HH:MM", ErrSyntax, spec)
+	}
+
+	from, to, ok := strings.Cut(fields[i], "-")
+	if !ok {
+		return nil, fmt.Errorf("%w: %q: time range %q must be HH:MM-HH:MM", ErrSyntax, spec, fields[i])
+	}
+	var err error
+	if w.start, err = parseClock(from); err != nil {
+		return nil, fmt.Errorf("%w: %q: %v", ErrSyntax, spec, err)
+	}
+	if w.end, err = parseClock(to); err != nil {
+		return nil, fmt.Errorf("%w: %q: %v", ErrSyntax, spec, err)
+	}
+	if w.start == w.end {
+		return nil, fmt.Errorf("%w: %q: time range %q is empty", ErrSyntax, spec, fields[i])
+	}
+	i++
+
+	if i < len(fields) {
+		if w.loc, err = time.LoadLocation(fields[i]); err != nil {
+			return nil, fmt.Errorf("%w: %q: unknown timezone %q", ErrSyntax, spec, fields[i])
+		}
+		i++
+	}
+	if i < len(fields) {
+		return nil, fmt.Errorf("%w: %q: unexpected %q", ErrSyntax, spec, fields[i])
+	}
+	return w, nil
+}
+
+func (w *Window) parseDays(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := parseDay(from)
+		if err != nil {
+			return err
+		}
+		last := first
+		if isRange {
+			if last, err = parseDay(to); err != nil {
+				return err
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			w.days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func parseDay(s string) (int, error) {
+	name := strings.ToLower(s)
+	for i, day := range dayNames {
+		full := strings.ToLower(time.Weekday(i).String())
+		if name == day || name == full {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown day %q", s)
+}
+
+// parseClock returns the minutes since midnight of a time of day HH:MM; 24:00 is the end of the day
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(s, ":")
+	hour, errH := strconv.Atoi(h)
+	minute, errM := strconv.Atoi(m)
+	if !ok || errH != nil || errM != nil || len(m) != 2 || hour < 0 || minute < 0 || minute > 59 || hour*60+minute > 24*60 {
+		return 0, fmt.Errorf("invalid time of day %q, use HH:MM", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// String returns the window as it was parsed
+func (w *Window) String() string {
+	return w.spec
+}
+
+// Contains reports whether t falls into the window
+func (w *Window) Contains(t time.Time) bool {
+	if w.loc != nil {
+		t = t.In(w.loc)
+	}
+	minute := t.Hour()*60 + t.Minute()
+	day := int(t.Weekday())
+	if w.start < w.end {
+		return w.days[day] && minute >= w.start && minute < w.end
+	}
+	return (w.days[day] && minute >= w.start) || (w.days[(day+6)%7] && minute < w.end)
+}
+
+// Next returns t if it falls into the window, otherwise the time the window opens next
+func (w *Window) Next(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	local := t
+	if w.loc != nil {
+		local = t.In(w.loc)
+	}
+	for d := 0; d <= 7; d++ {
+		open := time.Date(local.Year(), local.Month(), local.Day()+d, w.start/60, w.start%60, 0, 0, local.Location())
+		if open.After(t) && w.days[open.Weekday()] {
+			return open
+		}
+	}
+	// Unreachable: Parse accepts no windows without days
+	return t
+}
@@ -0,0 +1,86 @@
+package window
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse_Errors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"Mon-Fri",
+		"Mon-Fri 9-17",
+		"Mon-Fri 09:00",
+		"Mon-Fri 09:00-09:00",
+		"Mon-Fri 25:00-26:00",
+		"Mon-Fri 09:5-17:00",
+		"Mon-Fry 09:00-17:00",
+		"Mon-Fri 09:00-17:00 Mars/Olympus",
+		"Mon-Fri 09:00-17:00 UTC extra",
+	} {
+		if _, err := Parse(spec); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Parse(%q) error = %v, want %v", spec, err, ErrSyntax)
+		}
+	}
+}
+
+func TestWindow_Contains(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no timezone database:", err)
+	}
+	// 2025-01-06 is a Monday
+	at := func(day, hour, minute int) time.Time { return time.Date(2025, 1, day, hour, minute, 0, 0, berlin) }
+
+	tests := []struct {
+		spec string
+		t    time.Time
+		want bool
+	}{
+		{"Mon-Fri 09:00-17:00 Europe/Berlin", at(6, 9, 0), true},
+		{"Mon-Fri 09:00-17:00 Europe/Berlin", at(6, 16, 59), true},
+		{"Mon-Fri 09:00-17:00 Europe/Berlin", at(6, 17, 0), false},
+		{"Mon-Fri 09:00-17:00 Europe/Berlin", at(6, 3, 0), false},
+		{"Mon-Fri 09:00-17:00 Europe/Berlin", at(11, 10, 0), false},
+		{"Mon-Fri 09:00-17:00 UTC", at(6, 9, 30), false},
+		{"Mon-Fri 09:00-17:00 UTC", at(6, 10, 30), true},
+		{"sat,Sunday 00:00-24:00", at(12, 23, 59), true},
+		{"Fri-Mon 10:00-12:00", at(8, 11, 0), false},
+		{"Fri-Mon 10:00-12:00", at(5, 11, 0), true},
+		{"22:00-06:00", at(7, 23, 0), true},
+		{"Fri 22:00-06:00", at(11, 5, 0), true},
+		{"Fri 22:00-06:00", at(10, 5, 0), false},
+	}
+	for _, tt := range tests {
+		w, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", tt.spec, err)
+		}
+		if got := w.Contains(tt.t); got != tt.want {
+			t.Errorf("%q.Contains(%s) = %v, want %v", tt.spec, tt.t.Format(time.RFC1123), got, tt.want)
+		}
+	}
+}
+
+func TestWindow_Next(t *testing.T) {
+	w, err := Parse("Mon-Fri 09:00-17:00 UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		t, want time.Time
+	}{
+		// Monday morning opens the same day
+		{time.Date(2025, 1, 6, 3, 0, 0, 0, time.UTC), time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)},
+		// inside the window
+		{time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC), time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)},
+		// Friday evening waits for Monday
+		{time.Date(2025, 1, 10, 18, 0, 0, 0, time.UTC), time.Date(2025, 1, 13, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := w.Next(tt.t); !got.Equal(tt.want) {
+			t.Errorf("Next(%s) = %s, want %s", tt.t, got, tt.want)
+		}
+	}
+}
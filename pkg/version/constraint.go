@@ -0,0 +1,133 @@
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Semver is a parsed major.minor.patch version; pre-release and build suffixes are ignored
+type Semver struct {
+	Major, Minor, Patch int
+}
+
+func (v Semver) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or 1 if v is lower than, equal to or higher than o
+func (v Semver) Compare(o Semver) int {
+	for _, d := range [][2]int{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if d[0] != d[1] {
+			if d[0] < d[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Parse parses versions such as "0.5", "v0.5.1" or "v0.5.1-3-gabcdef-dirty"
+func Parse(s string) (Semver, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return Semver{}, fmt.Errorf("invalid version %q", s)
+	}
+	var nums [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return Semver{}, fmt.Errorf("invalid version %q", s)
+		}
+		nums[i] = n
+	}
+	return Semver{Major: nums[0], Minor: nums[1], Patch: nums[2]}, nil
+}
+
+type comparison struct {
+	op string
+	v  Semver
+}
+
+// Constraint is a comma-separated list of comparisons that must all hold, e.g. ">=0.5, <1.0"
+type Constraint []comparison
+
+var operators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// ParseConstraint parses a constraint; a bare version means "=version"
+func ParseConstraint(s string) (Constraint, error) {
+	var c Constraint
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("invalid version constraint %q", s)
+		}
+
+		op := "="
+		for _, candidate := range operators {
+			if strings.HasPrefix(part, candidate) {
+				op = candidate
+				part = strings.TrimSpace(part[len(candidate):])
+				break
+			}
+		}
+		v, err := Parse(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %w", s, err)
+		}
+		c = append(c, comparison{op: op, v: v})
+	}
+	return c, nil
+}
+
+// Check reports whether v satisfies every comparison of the constraint
+func (c Constraint) Check(v Semver) bool {
+	for _, cmp := range c {
+		r := v.Compare(cmp.v)
+		var ok bool
+		switch cmp.op {
+		case ">=":
+			ok = r >= 0
+		case "<=":
+			ok = r <= 0
+		case ">":
+			ok = r > 0
+		case "<":
+			ok = r < 0
+		case "!=":
+			ok = r != 0
+		default:
+			ok = r == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// IsDev reports whether the running binary is an unversioned development build,
+// which satisfies every constraint
+func IsDev() bool {
+	_, err := Parse(Version)
+	return err != nil || strings.HasPrefix(Version, "v0.0.0-dev")
+}
+
+// Satisfies reports whether the running forge version satisfies the constraint
+func Satisfies(constraint string) (bool, error) {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return false, err
+	}
+	if IsDev() {
+		return true, nil
+	}
+	v, _ := Parse(Version)
+	return c.Check(v), nil
+}
@@ -0,0 +1,97 @@
+package version
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Semver
+		wantErr bool
+	}{
+		{in: "0.5", want: Semver{0, 5, 0}},
+		{in: "v1.2.3", want: Semver{1, 2, 3}},
+		{in: "v0.5.1-3-gabcdef-dirty", want: Semver{0, 5, 1}},
+		{in: "1.0.0+build", want: Semver{1, 0, 0}},
+		{in: "dev", wantErr: true},
+		{in: "1.2.3.4", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConstraint_Check(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+		wantErr    bool
+	}{
+		{constraint: ">=0.5", version: "0.5.0", want: true},
+		{constraint: ">=0.5", version: "v0.4.9", want: false},
+		{constraint: ">=0.5, <1.0", version: "0.9.3", want: true},
+		{constraint: ">=0.5, <1.0", version: "1.0.0", want: false},
+		{constraint: "0.6.1", version: "0.6.1", want: true},
+		{constraint: "!=0.6.1", version: "0.6.1", want: false},
+		{constraint: ">0.6", version: "0.6.1", want: true},
+		{constraint: "<=0.6", version: "0.6.1", want: false},
+		{constraint: ">=banana", wantErr: true},
+		{constraint: ">=0.5,", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint+" "+tt.version, func(t *testing.T) {
+			c, err := ParseConstraint(tt.constraint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseConstraint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			v, err := Parse(tt.version)
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			if got := c.Check(v); got != tt.want {
+				t.Errorf("Check(%s) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	orig := Version
+	defer func() { Version = orig }()
+
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{version: "dev", want: true},
+		{version: "v0.0.0-dev", want: true},
+		{version: "v0.4.0", want: false},
+		{version: "v0.5.2", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			Version = tt.version
+			got, err := Satisfies(">=0.5")
+			if err != nil {
+				t.Fatalf("Satisfies() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Satisfies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}